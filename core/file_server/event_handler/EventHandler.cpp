@@ -549,6 +549,10 @@ void ModifyHandler::Handle(const Event& event) {
                         reader->CloseFilePtr();
                     }
                 }
+                if (reader->IsReadToEnd() && reader->CompleteCollection()) {
+                    PushCollectionCompleteEvent(reader);
+                    reader->ApplyCompletionAction();
+                }
             }
         }
     } else if (event.IsModify()) {
@@ -799,6 +803,10 @@ void ModifyHandler::Handle(const Event& event) {
                             "file inode", reader->GetDevInode().inode)("file size", reader->GetFileSize()));
                     ForceReadLogAndPush(reader);
                     reader->CloseFilePtr();
+                    if (reader->CompleteCollection()) {
+                        PushCollectionCompleteEvent(reader);
+                        reader->ApplyCompletionAction();
+                    }
                 }
                 break;
             }
@@ -1076,6 +1084,20 @@ void ModifyHandler::ForceReadLogAndPush(LogFileReaderPtr reader) {
     PushLogToProcessor(reader, logBuffer.get());
 }
 
+void ModifyHandler::PushCollectionCompleteEvent(LogFileReaderPtr reader) {
+    if (!reader->IsCompletionEventEnabled()) {
+        return;
+    }
+    PipelineEventGroup group = LogFileReader::GenerateCollectionCompleteEventGroup(reader);
+    int32_t pushRetry = 0;
+    while (!ProcessorRunner::GetInstance()->PushQueue(reader->GetQueueKey(), 0, std::move(group))) // 10ms
+    {
+        ++pushRetry;
+        if (pushRetry % 10 == 0)
+            LogInput::GetInstance()->TryReadEvents(false);
+    }
+}
+
 int32_t ModifyHandler::PushLogToProcessor(LogFileReaderPtr reader, LogBuffer* logBuffer) {
     int32_t pushRetry = 0;
     if (!logBuffer->rawBuffer.empty()) {
@@ -103,6 +103,9 @@ private:
 
     void ForceReadLogAndPush(LogFileReaderPtr reader);
 
+    // pushes the collection complete event of the reader if the input enables it
+    void PushCollectionCompleteEvent(LogFileReaderPtr reader);
+
     // no copy
     ModifyHandler(const ModifyHandler&);
     ModifyHandler& operator=(const ModifyHandler&);
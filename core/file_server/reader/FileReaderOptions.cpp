@@ -184,6 +184,71 @@ bool FileReaderOptions::Init(const Json::Value& config,
                               ctx.GetRegion());
     }
 
+    // EnableCompletionEvent
+    if (!GetOptionalBoolParam(config, "EnableCompletionEvent", mEnableCompletionEvent, errorMsg)) {
+        PARAM_WARNING_DEFAULT(ctx.GetLogger(),
+                              ctx.GetAlarm(),
+                              errorMsg,
+                              mEnableCompletionEvent,
+                              pluginType,
+                              ctx.GetConfigName(),
+                              ctx.GetProjectName(),
+                              ctx.GetLogstoreName(),
+                              ctx.GetRegion());
+    }
+
+    // CompletionArchiveDir
+    if (!GetOptionalStringParam(config, "CompletionArchiveDir", mCompletionArchiveDir, errorMsg)) {
+        PARAM_WARNING_IGNORE(ctx.GetLogger(),
+                             ctx.GetAlarm(),
+                             errorMsg,
+                             pluginType,
+                             ctx.GetConfigName(),
+                             ctx.GetProjectName(),
+                             ctx.GetLogstoreName(),
+                             ctx.GetRegion());
+    }
+
+    // CompletionAction
+    string completionAction;
+    if (!GetOptionalStringParam(config, "CompletionAction", completionAction, errorMsg)) {
+        PARAM_WARNING_DEFAULT(ctx.GetLogger(),
+                              ctx.GetAlarm(),
+                              errorMsg,
+                              "none",
+                              pluginType,
+                              ctx.GetConfigName(),
+                              ctx.GetProjectName(),
+                              ctx.GetLogstoreName(),
+                              ctx.GetRegion());
+    } else if (completionAction == "delete") {
+        mCompletionAction = CompletionAction::REMOVE;
+    } else if (completionAction == "archive") {
+        if (mCompletionArchiveDir.empty()) {
+            PARAM_WARNING_DEFAULT(ctx.GetLogger(),
+                                  ctx.GetAlarm(),
+                                  "string param CompletionArchiveDir is required by the archive completion action",
+                                  "none",
+                                  pluginType,
+                                  ctx.GetConfigName(),
+                                  ctx.GetProjectName(),
+                                  ctx.GetLogstoreName(),
+                                  ctx.GetRegion());
+        } else {
+            mCompletionAction = CompletionAction::ARCHIVE;
+        }
+    } else if (!completionAction.empty() && completionAction != "none") {
+        PARAM_WARNING_DEFAULT(ctx.GetLogger(),
+                              ctx.GetAlarm(),
+                              "string param CompletionAction is not valid",
+                              "none",
+                              pluginType,
+                              ctx.GetConfigName(),
+                              ctx.GetProjectName(),
+                              ctx.GetLogstoreName(),
+                              ctx.GetRegion());
+    }
+
     return true;
 }
 
@@ -29,6 +29,8 @@ namespace logtail {
 struct FileReaderOptions {
     enum class Encoding { UTF8, UTF16, GBK };
     enum class InputType { Unknown, InputFile, InputContainerStdio };
+    // the action on the file of the stopped container once it has been read to the end
+    enum class CompletionAction { NONE, REMOVE, ARCHIVE };
 
     InputType mInputType = InputType::Unknown;
     Encoding mFileEncoding = Encoding::UTF8;
@@ -39,6 +41,9 @@ struct FileReaderOptions {
     uint32_t mReadDelayAlertThresholdBytes;
     uint32_t mCloseUnusedReaderIntervalSec;
     uint32_t mRotatorQueueSize;
+    bool mEnableCompletionEvent = false;
+    CompletionAction mCompletionAction = CompletionAction::NONE;
+    std::string mCompletionArchiveDir;
 
     FileReaderOptions();
 
@@ -26,6 +26,7 @@
 #include <time.h>
 
 #include <algorithm>
+#include <filesystem>
 #include <limits>
 #include <numeric>
 #include <random>
@@ -96,6 +97,7 @@ namespace logtail {
 size_t LogFileReader::BUFFER_SIZE = 1024 * 512; // 512KB
 
 const int64_t kFirstHashKeySeqID = 1;
+const string COLLECTION_COMPLETE_KEY = "_collection_complete_";
 
 LogFileReader* LogFileReader::CreateLogFileReader(const string& hostLogPathDir,
                                                   const string& hostLogPathFile,
@@ -291,6 +293,51 @@ void LogFileReader::SetContainerStopped() {
     }
 }
 
+bool LogFileReader::CompleteCollection() {
+    if (mCollectionCompleted) {
+        return false;
+    }
+    mCollectionCompleted = true;
+    LOG_INFO(sLogger,
+             ("collection complete", "the container has been stopped, and the file has been read to the end")(
+                 "project", GetProject())("logstore", GetLogstore())("config", GetConfigName())(
+                 "file", mHostLogPath)("stopped time", mContainerStoppedTime)("file size", mLastFileSize));
+    return true;
+}
+
+void LogFileReader::ApplyCompletionAction() {
+    error_code ec;
+    switch (mReaderConfig.first->mCompletionAction) {
+        case FileReaderOptions::CompletionAction::REMOVE:
+            std::filesystem::remove(mHostLogPath, ec);
+            break;
+        case FileReaderOptions::CompletionAction::ARCHIVE: {
+            const std::filesystem::path archiveDir(mReaderConfig.first->mCompletionArchiveDir);
+            std::filesystem::create_directories(archiveDir, ec);
+            if (!ec) {
+                // the inode prefix avoids the conflict of the files with the same name from different containers
+                const auto archivePath = archiveDir
+                    / (ToString(mDevInode.inode) + "-" + std::filesystem::path(mHostLogPath).filename().string());
+                std::filesystem::rename(mHostLogPath, archivePath, ec);
+            }
+            break;
+        }
+        default:
+            return;
+    }
+    if (ec && ec != errc::no_such_file_or_directory) {
+        LOG_WARNING(sLogger,
+                    ("failed to apply the completion action", ec.message())("project", GetProject())(
+                        "logstore", GetLogstore())("config", GetConfigName())("file", mHostLogPath));
+        AlarmManager::GetInstance()->SendAlarm(LOG_FILE_COMPLETION_ALARM,
+                                               "failed to apply the completion action: " + ec.message()
+                                                   + ", file: " + mHostLogPath,
+                                               GetProject(),
+                                               GetLogstore(),
+                                               GetRegion());
+    }
+}
+
 bool LogFileReader::ShouldForceReleaseDeletedFileFd() {
     time_t now = time(NULL);
     return INT32_FLAG(force_release_deleted_file_fd_timeout) >= 0
@@ -475,7 +522,7 @@ bool LogFileReader::validatePrimaryCheckpoint(const PrimaryCheckpointPB& cpt) {
                 return false;
             }
 
-            auto dirPath = boost::filesystem::path(filePath).parent_path();
+            auto dirPath = boost::std::filesystem::path(filePath).parent_path();
             const auto searchResult = SearchFilePathByDevInodeInDirectory(dirPath.string(), 0, mDevInode, nullptr);
             if (!searchResult) {
                 LOG_WARNING(sLogger, METHOD_LOG_PATTERN("can not find file with dev inode", mDevInode.inode));
@@ -2534,6 +2581,24 @@ PipelineEventGroup LogFileReader::GenerateEventGroup(LogFileReaderPtr reader, Lo
     return group;
 }
 
+PipelineEventGroup LogFileReader::GenerateCollectionCompleteEventGroup(LogFileReaderPtr reader) {
+    PipelineEventGroup group{std::make_shared<SourceBuffer>()};
+    reader->SetEventGroupMetaAndTag(group);
+    group.SetMetadataNoCopy(EventGroupMetaKey::COLLECTION_COMPLETE, "true");
+
+    LogEvent* event = group.AddLogEvent();
+    time_t logtime = time(nullptr);
+    if (AppConfig::GetInstance()->EnableLogTimeAutoAdjust()) {
+        logtime += GetTimeDelta();
+    }
+    event->SetTimestamp(logtime);
+    event->SetContent(COLLECTION_COMPLETE_KEY, string("true"));
+    event->SetContent(string("_path_"), reader->GetConvertedPath());
+    event->SetContent(string("_offset_"), ToString(reader->GetLastFilePos()));
+
+    return group;
+}
+
 const std::string& LogFileReader::GetConvertedPath() const {
     const std::string& path = mDockerPath.empty() ? mHostLogPath : mDockerPath;
 #if defined(_MSC_VER)
@@ -196,6 +196,8 @@ public:
 
     static PipelineEventGroup GenerateEventGroup(LogFileReaderPtr reader, LogBuffer* logBuffer);
 
+    static PipelineEventGroup GenerateCollectionCompleteEventGroup(LogFileReaderPtr reader);
+
     LogFileReader(const std::string& hostLogPathDir,
                   const std::string& hostLogPathFile,
                   const DevInode& devInode,
@@ -296,6 +298,15 @@ public:
 
     time_t GetContainerStoppedTime() const { return mContainerStoppedTime; }
 
+    // marks the collection of the stopped container file complete once it has been read to the end,
+    // returns false if it has been completed before
+    bool CompleteCollection();
+
+    bool IsCompletionEventEnabled() const { return mReaderConfig.first->mEnableCompletionEvent; }
+
+    // deletes or archives the completed file according to the CompletionAction of the input
+    void ApplyCompletionAction();
+
     bool IsFileOpened() const { return mLogFileOp.IsOpen(); }
 
     bool ShouldForceReleaseDeletedFileFd();
@@ -518,6 +529,7 @@ protected:
     bool mContainerStopped = false;
     time_t mContainerStoppedTime = 0;
     time_t mReadStoppedContainerAlarmTime = 0;
+    bool mCollectionCompleted = false;
     int32_t mReadDelayTime = 0;
     bool mSkipFirstModify = false;
     // int64_t mReadDelayAlarmBytes;
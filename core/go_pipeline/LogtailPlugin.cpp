@@ -473,6 +473,11 @@ bool LogtailPlugin::LoadPluginBase() {
 
     GoInt initRst = 0;
     if (initBaseV2) {
+        // the global vars referenced by the go pipelines as ${vars.name}
+        const Json::Value& localConfig = AppConfig::GetInstance()->GetConfig();
+        if (localConfig.isMember("Vars") && localConfig["Vars"].isObject()) {
+            mPluginCfg["Vars"] = localConfig["Vars"];
+        }
         std::string cfgStr = mPluginCfg.toStyledString();
         GoString goCfgStr;
         goCfgStr.p = cfgStr.c_str();
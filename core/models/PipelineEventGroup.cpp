@@ -346,6 +346,7 @@ const string EVENT_GROUP_META_CONTAINER_IMAGE_ID = "container.image.id";
 const string EVENT_GROUP_META_CONTAINERD_TEXT = "containerd_text";
 const string EVENT_GROUP_META_DOCKER_JSON_FILE = "docker_json-file";
 const string EVENT_GROUP_META_SOURCE_ID = "source.id";
+const string EVENT_GROUP_META_COLLECTION_COMPLETE = "collection.complete";
 
 const string& EventGroupMetaKeyToString(EventGroupMetaKey key) {
     switch (key) {
@@ -359,6 +360,8 @@ const string& EventGroupMetaKeyToString(EventGroupMetaKey key) {
             return EVENT_GROUP_META_HAS_PART_LOG;
         case EventGroupMetaKey::LOG_FILE_OFFSET_KEY:
             return EVENT_GROUP_META_LOG_FILE_OFFSET;
+        case EventGroupMetaKey::COLLECTION_COMPLETE:
+            return EVENT_GROUP_META_COLLECTION_COMPLETE;
         default:
             static string sEmpty = "unknown";
             return sEmpty;
@@ -378,7 +381,8 @@ EventGroupMetaKey StringToEventGroupMetaKey(const string& key) {
     static unordered_map<string, EventGroupMetaKey> sStringToEnum{
         {EVENT_GROUP_META_LOG_FILE_PATH_RESOLVED, EventGroupMetaKey::LOG_FILE_PATH_RESOLVED},
         {EVENT_GROUP_META_SOURCE_ID, EventGroupMetaKey::SOURCE_ID},
-        {EVENT_GROUP_META_HAS_PART_LOG, EventGroupMetaKey::HAS_PART_LOG}};
+        {EVENT_GROUP_META_HAS_PART_LOG, EventGroupMetaKey::HAS_PART_LOG},
+        {EVENT_GROUP_META_COLLECTION_COMPLETE, EventGroupMetaKey::COLLECTION_COMPLETE}};
     auto it = sStringToEnum.find(key);
     if (it != sStringToEnum.end()) {
         return it->second;
@@ -63,7 +63,9 @@ enum class EventGroupMetaKey {
     INTERNAL_DATA_TARGET_REGION,
     INTERNAL_DATA_TYPE,
 
-    SOURCE_ID
+    SOURCE_ID,
+    // the group carries the collection complete event of a file, which is not split or parsed as a container log
+    COLLECTION_COMPLETE
 };
 
 using GroupMetadata = std::map<EventGroupMetaKey, StringView>;
@@ -97,7 +97,8 @@ enum AlarmType {
     SERIALIZE_FAIL_ALARM = 66,
     RELABEL_METRIC_FAIL_ALARM = 67,
     REGISTER_HANDLERS_TOO_SLOW_ALARM = 68,
-    ALL_LOGTAIL_ALARM_NUM = 69
+    LOG_FILE_COMPLETION_ALARM = 69,
+    ALL_LOGTAIL_ALARM_NUM = 70
 };
 
 struct AlarmMessage {
@@ -28,6 +28,9 @@ namespace logtail {
 const string METRIC_GO_KEY_LABELS = "labels";
 const string METRIC_GO_KEY_COUNTERS = "counters";
 const string METRIC_GO_KEY_GAUGES = "gauges";
+const string METRIC_GO_KEY_METADATA = "metadata";
+// type, unit and help text of the go plugin metrics, exported as a json object keyed by the metric name
+const string METRIC_TAG_METADATA = "metric_metadata";
 
 SelfMonitorMetricEvent::SelfMonitorMetricEvent() {
 }
@@ -80,6 +83,16 @@ SelfMonitorMetricEvent::SelfMonitorMetricEvent(const std::map<std::string, std::
     if (!errMsg.empty()) {
         LOG_ERROR(sLogger, ("parse go metric", "gauges")("err", errMsg));
     }
+    auto metadata = metricRecord.find(METRIC_GO_KEY_METADATA);
+    if (metadata != metricRecord.end()) {
+        Json::Value descriptions;
+        string metadataErrMsg;
+        if (ParseJsonTable(metadata->second, descriptions, metadataErrMsg) && descriptions.isObject()) {
+            mMetadata = metadata->second;
+        } else {
+            LOG_ERROR(sLogger, ("parse go metric", "metadata")("err", metadataErrMsg));
+        }
+    }
     // category
     if (labels.isMember("metric_category")) {
         mCategory = labels["metric_category"].asString();
@@ -149,6 +162,9 @@ void SelfMonitorMetricEvent::Merge(SelfMonitorMetricEvent& event) {
     for (auto gauge = event.mGauges.begin(); gauge != event.mGauges.end(); gauge++) {
         mGauges[gauge->first] = gauge->second;
     }
+    if (!event.mMetadata.empty()) {
+        mMetadata = event.mMetadata;
+    }
     mUpdatedFlag = true;
 }
 
@@ -168,6 +184,9 @@ void SelfMonitorMetricEvent::ReadAsMetricEvent(MetricEvent* metricEventPtr) {
     for (auto label = mLabels.begin(); label != mLabels.end(); label++) {
         metricEventPtr->SetTag(label->first, label->second);
     }
+    if (!mMetadata.empty()) {
+        metricEventPtr->SetTag(METRIC_TAG_METADATA, mMetadata);
+    }
     // name
     metricEventPtr->SetName(mCategory);
     // values
@@ -56,6 +56,7 @@ private:
     std::unordered_map<std::string, std::string> mLabels;
     std::unordered_map<std::string, uint64_t> mCounters;
     std::unordered_map<std::string, double> mGauges;
+    std::string mMetadata; // descriptions of the go plugin metrics, not a part of the key
     int32_t mSendInterval;
     int32_t mLastSendInterval;
     bool mUpdatedFlag;
@@ -75,6 +75,18 @@ bool InputContainerStdio::Init(const Json::Value& config, Json::Value& optionalG
         return false;
     }
     mFileReader.mInputType = FileReaderOptions::InputType::InputContainerStdio;
+    // the stdio files are owned by the container runtime, which must not be deleted or moved
+    if (mFileReader.mCompletionAction != FileReaderOptions::CompletionAction::NONE) {
+        mFileReader.mCompletionAction = FileReaderOptions::CompletionAction::NONE;
+        PARAM_WARNING_IGNORE(mContext->GetLogger(),
+                             mContext->GetAlarm(),
+                             "param CompletionAction is not supported by the container stdio input",
+                             sName,
+                             mContext->GetConfigName(),
+                             mContext->GetProjectName(),
+                             mContext->GetLogstoreName(),
+                             mContext->GetRegion());
+    }
     // Multiline
     {
         const char* key = "Multiline";
@@ -81,7 +81,7 @@ bool ProcessorMergeMultilineLogNative::Init(const Json::Value& config) {
 }
 
 void ProcessorMergeMultilineLogNative::Process(PipelineEventGroup& logGroup) {
-    if (logGroup.GetEvents().empty()) {
+    if (logGroup.GetEvents().empty() || logGroup.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE)) {
         return;
     }
     if (mMergeType == MergeType::BY_REGEX) {
@@ -118,7 +118,7 @@ bool ProcessorParseContainerLogNative::Init(const Json::Value& config) {
 }
 
 void ProcessorParseContainerLogNative::Process(PipelineEventGroup& logGroup) {
-    if (logGroup.GetEvents().empty()) {
+    if (logGroup.GetEvents().empty() || logGroup.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE)) {
         return;
     }
 
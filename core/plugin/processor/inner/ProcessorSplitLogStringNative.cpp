@@ -72,7 +72,7 @@ bool ProcessorSplitLogStringNative::Init(const Json::Value& config) {
 }
 
 void ProcessorSplitLogStringNative::Process(PipelineEventGroup& logGroup) {
-    if (logGroup.GetEvents().empty()) {
+    if (logGroup.GetEvents().empty() || logGroup.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE)) {
         return;
     }
     EventsContainer newEvents;
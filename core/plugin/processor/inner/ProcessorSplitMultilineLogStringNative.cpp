@@ -81,7 +81,7 @@ bool ProcessorSplitMultilineLogStringNative::Init(const Json::Value& config) {
     3. The last \n of each log string is discarded in LogFileReader
 */
 void ProcessorSplitMultilineLogStringNative::Process(PipelineEventGroup& logGroup) {
-    if (logGroup.GetEvents().empty()) {
+    if (logGroup.GetEvents().empty() || logGroup.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE)) {
         return;
     }
     int inputLines = 0;
@@ -20,6 +20,7 @@
 #include <string>
 
 #include "collection_pipeline/CollectionPipeline.h"
+#include "collection_pipeline/queue/BoundedProcessQueue.h"
 #include "collection_pipeline/queue/ProcessQueueManager.h"
 #include "common/FileSystemUtil.h"
 #include "common/Flags.h"
@@ -43,6 +44,8 @@ public:
     void TestHandleContainerStoppedEventWhenNotReadToEnd();
     void TestHandleModifyEventWhenContainerStopped();
     void TestRecoverReaderFromCheckpoint();
+    void TestCollectionCompleteWhenContainerStopped();
+    void TestCollectionCompleteWhenReadToEnd();
 
 protected:
     static void SetUpTestCase() {
@@ -162,6 +165,8 @@ UNIT_TEST_CASE(ModifyHandlerUnittest, TestHandleContainerStoppedEventWhenReadToE
 UNIT_TEST_CASE(ModifyHandlerUnittest, TestHandleContainerStoppedEventWhenNotReadToEnd);
 UNIT_TEST_CASE(ModifyHandlerUnittest, TestHandleModifyEventWhenContainerStopped);
 UNIT_TEST_CASE(ModifyHandlerUnittest, TestRecoverReaderFromCheckpoint);
+UNIT_TEST_CASE(ModifyHandlerUnittest, TestCollectionCompleteWhenContainerStopped);
+UNIT_TEST_CASE(ModifyHandlerUnittest, TestCollectionCompleteWhenReadToEnd);
 
 void ModifyHandlerUnittest::TestHandleContainerStoppedEventWhenReadToEnd() {
     LOG_INFO(sLogger, ("TestHandleContainerStoppedEventWhenReadToEnd() begin", time(NULL)));
@@ -200,6 +205,57 @@ void ModifyHandlerUnittest::TestHandleModifyEventWhenContainerStopped() {
     APSARA_TEST_TRUE_FATAL(!mReaderPtr->mLogFileOp.IsOpen());
 }
 
+void ModifyHandlerUnittest::TestCollectionCompleteWhenContainerStopped() {
+    LOG_INFO(sLogger, ("TestCollectionCompleteWhenContainerStopped() begin", time(NULL)));
+    readerOpts.mEnableCompletionEvent = true;
+    readerOpts.mCompletionAction = FileReaderOptions::CompletionAction::REMOVE;
+    std::string logPath = gRootDir + PATH_SEPARATOR + gLogName;
+    Event event1(gRootDir, "", EVENT_MODIFY, 0);
+    LogBuffer logbuf;
+    APSARA_TEST_TRUE_FATAL(!mReaderPtr->ReadLog(logbuf, &event1)); // false means no more data
+
+    Event event2(gRootDir, "", EVENT_ISDIR | EVENT_CONTAINER_STOPPED, 0);
+    mHandlerPtr->Handle(event2);
+    auto queue = static_cast<BoundedProcessQueue*>(ProcessQueueManager::GetInstance()->mQueues[0].first->get());
+    APSARA_TEST_FALSE_FATAL(queue->mQueue.empty());
+    size_t queueSize = queue->mQueue.size();
+    const auto& group = queue->mQueue.back()->mEventGroup;
+    APSARA_TEST_TRUE(group.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE));
+    APSARA_TEST_EQUAL_FATAL(1U, group.GetEvents().size());
+    const auto& log = group.GetEvents()[0].Cast<LogEvent>();
+    APSARA_TEST_EQUAL("true", log.GetContent("_collection_complete_").to_string());
+    APSARA_TEST_EQUAL(logPath, log.GetContent("_path_").to_string());
+    APSARA_TEST_EQUAL(ToString(mReaderPtr->GetLastFilePos()), log.GetContent("_offset_").to_string());
+    // the file is deleted once it is completed
+    APSARA_TEST_FALSE(bfs::exists(logPath));
+
+    // the completion is only emitted once
+    mHandlerPtr->Handle(event2);
+    APSARA_TEST_EQUAL(queueSize, queue->mQueue.size());
+}
+
+void ModifyHandlerUnittest::TestCollectionCompleteWhenReadToEnd() {
+    LOG_INFO(sLogger, ("TestCollectionCompleteWhenReadToEnd() begin", time(NULL)));
+    readerOpts.mCompletionAction = FileReaderOptions::CompletionAction::ARCHIVE;
+    readerOpts.mCompletionArchiveDir = gRootDir + PATH_SEPARATOR + "archive";
+    std::string logPath = gRootDir + PATH_SEPARATOR + gLogName;
+
+    mReaderPtr->SetContainerStopped();
+    Event event(gRootDir, gLogName, EVENT_MODIFY, 0, 0, mReaderPtr->mDevInode.dev, mReaderPtr->mDevInode.inode);
+    mHandlerPtr->Handle(event);
+    APSARA_TEST_TRUE_FATAL(mReaderPtr->IsReadToEnd());
+    APSARA_TEST_TRUE_FATAL(!mReaderPtr->mLogFileOp.IsOpen());
+    // the completion event is disabled
+    auto queue = static_cast<BoundedProcessQueue*>(ProcessQueueManager::GetInstance()->mQueues[0].first->get());
+    for (const auto& item : queue->mQueue) {
+        APSARA_TEST_FALSE(item->mEventGroup.HasMetadata(EventGroupMetaKey::COLLECTION_COMPLETE));
+    }
+    // the file is moved into the archive dir with the inode prefix
+    APSARA_TEST_FALSE(bfs::exists(logPath));
+    APSARA_TEST_TRUE(bfs::exists(readerOpts.mCompletionArchiveDir + PATH_SEPARATOR
+                                 + ToString(mReaderPtr->mDevInode.inode) + "-" + gLogName));
+}
+
 void ModifyHandlerUnittest::TestRecoverReaderFromCheckpoint() {
     LOG_INFO(sLogger, ("TestRecoverReaderFromCheckpoint() begin", time(NULL)));
     std::string basicLogName = "rotate.log";
@@ -27,6 +27,7 @@ public:
     void TestCreateFromGoMetricMap();
     void TestMerge();
     void TestSendInterval();
+    void TestGoMetricMetadata();
 
 private:
     std::shared_ptr<SourceBuffer> mSourceBuffer;
@@ -38,6 +39,7 @@ APSARA_UNIT_TEST_CASE(SelfMonitorMetricEventUnittest, TestCreateFromMetricEvent,
 APSARA_UNIT_TEST_CASE(SelfMonitorMetricEventUnittest, TestCreateFromGoMetricMap, 1);
 APSARA_UNIT_TEST_CASE(SelfMonitorMetricEventUnittest, TestMerge, 2);
 APSARA_UNIT_TEST_CASE(SelfMonitorMetricEventUnittest, TestSendInterval, 3);
+APSARA_UNIT_TEST_CASE(SelfMonitorMetricEventUnittest, TestGoMetricMetadata, 4);
 
 void SelfMonitorMetricEventUnittest::TestCreateFromMetricEvent() {
     std::vector<std::pair<std::string, std::string>> labels;
@@ -198,6 +200,38 @@ void SelfMonitorMetricEventUnittest::TestSendInterval() {
     APSARA_TEST_TRUE(event.ShouldDelete()); // 第三次调用，间隔计数达到3，应返回true
 }
 
+void SelfMonitorMetricEventUnittest::TestGoMetricMetadata() {
+    std::map<std::string, std::string> pluginMetric;
+    pluginMetric["labels"] = R"({"metric_category":"plugin","plugin_type":"processor_rate_limit"})";
+    pluginMetric["counters"] = R"({"processor_rate_limit_limited": "3"})";
+    pluginMetric["gauges"] = R"({})";
+    std::string metadata
+        = R"({"processor_rate_limit_limited":{"type":"counter","unit":"events","help":"Number of events dropped."}})";
+    pluginMetric["metadata"] = metadata;
+    SelfMonitorMetricEvent event(pluginMetric);
+    APSARA_TEST_EQUAL(metadata, event.mMetadata);
+
+    // metadata does not take part in the key
+    pluginMetric.erase("metadata");
+    SelfMonitorMetricEvent eventWithoutMetadata(pluginMetric);
+    APSARA_TEST_EQUAL(event.mKey, eventWithoutMetadata.mKey);
+    APSARA_TEST_TRUE(eventWithoutMetadata.mMetadata.empty());
+    eventWithoutMetadata.Merge(event);
+    APSARA_TEST_EQUAL(metadata, eventWithoutMetadata.mMetadata);
+
+    mSourceBuffer.reset(new SourceBuffer);
+    mEventGroup.reset(new PipelineEventGroup(mSourceBuffer));
+    mMetricEvent = mEventGroup->CreateMetricEvent();
+    event.ReadAsMetricEvent(mMetricEvent.get());
+    APSARA_TEST_EQUAL(metadata, mMetricEvent->GetTag("metric_metadata").to_string());
+    APSARA_TEST_EQUAL("processor_rate_limit", mMetricEvent->GetTag("plugin_type").to_string());
+
+    // invalid metadata is ignored
+    pluginMetric["metadata"] = "not json";
+    SelfMonitorMetricEvent eventWithInvalidMetadata(pluginMetric);
+    APSARA_TEST_TRUE(eventWithInvalidMetadata.mMetadata.empty());
+}
+
 } // namespace logtail
 
 int main(int argc, char** argv) {
@@ -61,6 +61,8 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
     APSARA_TEST_EQUAL(static_cast<uint32_t>(INT32_FLAG(reader_close_unused_file_time)),
                       config->mCloseUnusedReaderIntervalSec);
     APSARA_TEST_EQUAL(static_cast<uint32_t>(INT32_FLAG(logreader_max_rotate_queue_size)), config->mRotatorQueueSize);
+    APSARA_TEST_FALSE(config->mEnableCompletionEvent);
+    APSARA_TEST_EQUAL(FileReaderOptions::CompletionAction::NONE, config->mCompletionAction);
 
     // valid optional param
     configStr = R"(
@@ -72,7 +74,10 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
             "ReadDelaySkipThresholdBytes": 1000,
             "ReadDelayAlertThresholdBytes": 100,
             "CloseUnusedReaderIntervalSec": 10,
-            "RotatorQueueSize": 15
+            "RotatorQueueSize": 15,
+            "EnableCompletionEvent": true,
+            "CompletionAction": "archive",
+            "CompletionArchiveDir": "/var/log/archive"
         }
     )";
     APSARA_TEST_TRUE(ParseJsonTable(configStr, configJson, errorMsg));
@@ -86,6 +91,9 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
     APSARA_TEST_EQUAL(100U, config->mReadDelayAlertThresholdBytes);
     APSARA_TEST_EQUAL(10U, config->mCloseUnusedReaderIntervalSec);
     APSARA_TEST_EQUAL(15U, config->mRotatorQueueSize);
+    APSARA_TEST_TRUE(config->mEnableCompletionEvent);
+    APSARA_TEST_EQUAL(FileReaderOptions::CompletionAction::ARCHIVE, config->mCompletionAction);
+    APSARA_TEST_EQUAL("/var/log/archive", config->mCompletionArchiveDir);
 
     // invalid optional param (except for FileEcoding)
     configStr = R"(
@@ -97,7 +105,9 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
             "ReadDelaySkipThresholdBytes": "1000",
             "ReadDelayAlertThresholdBytes": "100",
             "CloseUnusedReaderIntervalSec": "10",
-            "RotatorQueueSize": "15"
+            "RotatorQueueSize": "15",
+            "EnableCompletionEvent": "true",
+            "CompletionAction": "move"
         }
     )";
     APSARA_TEST_TRUE(ParseJsonTable(configStr, configJson, errorMsg));
@@ -112,6 +122,8 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
     APSARA_TEST_EQUAL(static_cast<uint32_t>(INT32_FLAG(reader_close_unused_file_time)),
                       config->mCloseUnusedReaderIntervalSec);
     APSARA_TEST_EQUAL(static_cast<uint32_t>(INT32_FLAG(logreader_max_rotate_queue_size)), config->mRotatorQueueSize);
+    APSARA_TEST_FALSE(config->mEnableCompletionEvent);
+    APSARA_TEST_EQUAL(FileReaderOptions::CompletionAction::NONE, config->mCompletionAction);
 
     // FileEncoding
     configStr = R"(
@@ -136,6 +148,27 @@ void FileReaderOptionsUnittest::OnSuccessfulInit() const {
     APSARA_TEST_TRUE(config->Init(configJson, ctx, pluginType));
     APSARA_TEST_EQUAL(FileReaderOptions::Encoding::GBK, config->mFileEncoding);
     APSARA_TEST_EQUAL(static_cast<uint32_t>(INT32_FLAG(default_tail_limit_kb)), config->mTailSizeKB);
+
+    // CompletionAction
+    configStr = R"(
+        {
+            "CompletionAction": "delete"
+        }
+    )";
+    APSARA_TEST_TRUE(ParseJsonTable(configStr, configJson, errorMsg));
+    config.reset(new FileReaderOptions());
+    APSARA_TEST_TRUE(config->Init(configJson, ctx, pluginType));
+    APSARA_TEST_EQUAL(FileReaderOptions::CompletionAction::REMOVE, config->mCompletionAction);
+
+    configStr = R"(
+        {
+            "CompletionAction": "archive"
+        }
+    )";
+    APSARA_TEST_TRUE(ParseJsonTable(configStr, configJson, errorMsg));
+    config.reset(new FileReaderOptions());
+    APSARA_TEST_TRUE(config->Init(configJson, ctx, pluginType));
+    APSARA_TEST_EQUAL(FileReaderOptions::CompletionAction::NONE, config->mCompletionAction);
 }
 
 void FileReaderOptionsUnittest::OnFailedInit() const {
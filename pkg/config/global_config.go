@@ -62,6 +62,106 @@ type GlobalConfig struct {
 	PipelineMetaTagKey     map[string]string
 	AppendingAllEnvMetaTag bool
 	AgentEnvMetaTagKey     map[string]string
+
+	// Vars are referenced as ${vars.name} in the plugin configurations, the pipeline vars override the agent vars.
+	Vars map[string]string
+
+	DropGuardrail DropGuardrailConfig
+	Sequence      SequenceConfig
+	// EventTTLSec drops the events whose event time is older than it when they reach the flushers, 0 means never.
+	EventTTLSec int
+	// LatencyTracking reports the end-to-end latency of the events from entering the pipeline to being flushed.
+	LatencyTracking LatencyTrackingConfig
+	// ProcessingProfile reports the share of the processing time taken by each processor of the pipeline.
+	ProcessingProfile ProcessingProfileConfig
+	// Identity decides where the hostname, host ip, cluster and region of the agent come from.
+	Identity IdentityConfig
+	// AdaptV1Plugins runs the v1 processors and aggregators in the v2 pipelines by converting the log events.
+	AdaptV1Plugins bool
+	// PanicIsolation recovers the panics of the processors and service inputs instead of stopping the routines they run in.
+	PanicIsolation PanicIsolationConfig
+	// Schedule restricts the flushing, and optionally the collection, of the pipeline to the time-of-day windows.
+	Schedule ScheduleConfig
+	// DiskSpool spills the log groups which cannot be flushed out at exit to the disk instead of dropping them.
+	DiskSpool DiskSpoolConfig
+}
+
+// DropGuardrailConfig alarms when the processors of a pipeline drop too many of the received events in a window.
+type DropGuardrailConfig struct {
+	Enable       bool
+	WindowSec    int     // The length of the window to compute the drop ratio in.
+	MaxDropRatio float64 // The alarm is raised when the ratio of the dropped events to the received events exceeds it.
+	MinEvents    int     // The window receiving fewer events is not checked.
+	// DisableFilter bypasses the filter processor dropping the most events in the offending window until the pipeline is reloaded.
+	DisableFilter bool
+}
+
+// SequenceConfig stamps the events with the per-source sequence numbers when the inputs emit them,
+// and restores the order of each source within each batch before the events are flushed.
+type SequenceConfig struct {
+	Enable      bool
+	SequenceKey string // The key of the sequence number, in the contents of the logs for v1 or in the tags of the events for v2.
+	SourceKey   string // The key of the source the sequence number belongs to, not stamped if empty.
+}
+
+// LatencyTrackingConfig stamps the events with the time they enter the processor routine, and the flushers report the
+// distribution of the latency between it and the time the flush is acknowledged.
+type LatencyTrackingConfig struct {
+	Enable    bool
+	BucketsMs []int64 // The upper bounds of the latency histogram buckets in milliseconds.
+}
+
+// ProcessingProfileConfig times each processor on the sampled batches entering the processor chain, and reports the
+// share of the sampled processing time taken by each processor once per window.
+type ProcessingProfileConfig struct {
+	Enable     bool
+	SampleRate int // One in every SampleRate batches is timed.
+	WindowSec  int // The length of the window the shares are computed in.
+	TopN       int // The number of the most expensive processors logged in each report.
+}
+
+// PanicIsolationConfig recovers the panic of a plugin instance, and restarts the plugin after a backoff doubled on each
+// crash. The plugin is quarantined, that is bypassed until the pipeline is reloaded, once it has crashed MaxCrashes times.
+type PanicIsolationConfig struct {
+	Enable           bool
+	MaxCrashes       int // The plugin is quarantined after crashing so many times, 0 means never.
+	InitialBackoffMs int // The backoff before the first restart.
+	MaxBackoffMs     int // The upper bound of the backoff.
+}
+
+// ScheduleConfig holds the events of the pipeline before the flushers outside the time-of-day windows, so the queues
+// fill up and the inputs are backpressured until the next window opens. The pipeline is always open without windows.
+type ScheduleConfig struct {
+	Windows  []string // The windows formatted as "HH:MM-HH:MM", a window ending before it starts spans midnight.
+	Location string   // The IANA time zone the windows are in, the local time zone if empty.
+	// Mode is buffer or pause. Buffer only defers the flushing, and pause also stops the metric inputs collecting
+	// outside the windows. The service inputs are always backpressured only.
+	Mode string
+}
+
+// IdentityConfig resolves each field of the agent identity from the first source in Precedence providing it. The sources
+// are config (the static values below, or the ones passed by loongcollector), env, k8s (the downward API), cloud (the
+// instance metadata service) and host (the hostname and the network interfaces of the machine).
+type IdentityConfig struct {
+	Precedence []string
+	Hostname   string
+	HostIP     string
+	Cluster    string
+	Region     string
+}
+
+// DiskSpoolConfig writes the log groups still unflushed when the flushers are not ready before timeout at exit to the
+// segments in the disk, and sends them before the new data when the pipeline starts again. Only the v1 pipelines spool,
+// the v2 pipeline enabling it in its own global config is rejected.
+type DiskSpoolConfig struct {
+	Enable   bool
+	Dir      string // The directory of the segments, the disk_spool directory in LoongCollectorDataDir if empty.
+	MaxBytes int64  // The segments are not written once their total size exceeds it.
+	// Encrypt seals the segments with AES-GCM. The key is the base64 encoded secret named KeySecret, which must decode
+	// to 16, 24 or 32 bytes, resolved by the SecretProvider extension, e.g. ext_secret_provider/spool in the extensions.
+	Encrypt        bool
+	SecretProvider string
+	KeySecret      string
 }
 
 // LoongcollectorGlobalConfig is the singleton instance of GlobalConfig.
@@ -91,6 +191,37 @@ func newGlobalConfig() (cfg GlobalConfig) {
 		LoongCollectorThirdPartyDir:               "./thirdparty/",
 		LoongCollectorPrometheusAuthorizationPath: "./conf/",
 		DelayStopSec:                              300,
+		DropGuardrail: DropGuardrailConfig{
+			WindowSec:    60,
+			MaxDropRatio: 0.9,
+			MinEvents:    100,
+		},
+		Sequence: SequenceConfig{
+			SequenceKey: "__seq__",
+			SourceKey:   "__seq_source__",
+		},
+		LatencyTracking: LatencyTrackingConfig{
+			BucketsMs: []int64{10, 100, 500, 1000, 5000, 10000, 60000},
+		},
+		ProcessingProfile: ProcessingProfileConfig{
+			SampleRate: 100,
+			WindowSec:  60,
+			TopN:       3,
+		},
+		Identity: IdentityConfig{
+			Precedence: []string{"config", "env", "k8s", "host"},
+		},
+		PanicIsolation: PanicIsolationConfig{
+			MaxCrashes:       5,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     60000,
+		},
+		Schedule: ScheduleConfig{
+			Mode: "buffer",
+		},
+		DiskSpool: DiskSpoolConfig{
+			MaxBytes: 100 * 1024 * 1024,
+		},
 	}
 	return
 }
@@ -0,0 +1,87 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fips holds the FIPS crypto mode switch. When the mode is on, TLS
+// configurations and hashing plugins are limited to FIPS 140 approved
+// algorithms and configs asking for anything else fail validation.
+package fips
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/flags"
+)
+
+// CipherSuites are the TLS 1.2 cipher suites allowed in FIPS mode. TLS 1.3
+// suites are not configurable in crypto/tls.
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// CurvePreferences are the key exchange curves allowed in FIPS mode.
+var CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+var errTLSVersion = errors.New("TLS versions below 1.2 are not allowed in FIPS mode")
+
+var approvedHashes = map[string]struct{}{
+	"sha224":   {},
+	"sha256":   {},
+	"sha384":   {},
+	"sha512":   {},
+	"sha3-256": {},
+	"sha3-384": {},
+	"sha3-512": {},
+}
+
+// Enabled reports whether FIPS mode is on, either because the binary was
+// built with the fips tag or because FIPS_MODE is set.
+func Enabled() bool {
+	return buildEnabled || *flags.FIPSMode
+}
+
+// CheckHash returns an error if algorithm is not allowed in FIPS mode.
+func CheckHash(algorithm string) error {
+	if !Enabled() {
+		return nil
+	}
+	if _, ok := approvedHashes[strings.ToLower(algorithm)]; !ok {
+		return fmt.Errorf("hash algorithm %v is not allowed in FIPS mode", algorithm)
+	}
+	return nil
+}
+
+// RestrictTLSConfig limits cfg to the FIPS approved protocol versions, cipher
+// suites and curves. It returns an error if cfg explicitly allows a protocol
+// version below TLS 1.2.
+func RestrictTLSConfig(cfg *tls.Config) error {
+	if !Enabled() || cfg == nil {
+		return nil
+	}
+	if cfg.MinVersion != 0 && cfg.MinVersion < tls.VersionTLS12 {
+		return errTLSVersion
+	}
+	if cfg.MaxVersion != 0 && cfg.MaxVersion < tls.VersionTLS12 {
+		return errTLSVersion
+	}
+	cfg.MinVersion = tls.VersionTLS12
+	cfg.CipherSuites = CipherSuites
+	cfg.CurvePreferences = CurvePreferences
+	return nil
+}
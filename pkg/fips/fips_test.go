@@ -0,0 +1,55 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fips
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alibaba/ilogtail/pkg/flags"
+)
+
+func TestCheckHash(t *testing.T) {
+	if !buildEnabled {
+		assert.NoError(t, CheckHash("md5"))
+	}
+
+	*flags.FIPSMode = true
+	defer func() { *flags.FIPSMode = false }()
+	assert.Error(t, CheckHash("md5"))
+	assert.Error(t, CheckHash("sha1"))
+	assert.NoError(t, CheckHash("sha256"))
+	assert.NoError(t, CheckHash("SHA512"))
+}
+
+func TestRestrictTLSConfig(t *testing.T) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS10} //nolint:gosec
+	if !buildEnabled {
+		assert.NoError(t, RestrictTLSConfig(cfg))
+		assert.Nil(t, cfg.CipherSuites)
+	}
+
+	*flags.FIPSMode = true
+	defer func() { *flags.FIPSMode = false }()
+	assert.Error(t, RestrictTLSConfig(cfg))
+
+	cfg = &tls.Config{} //nolint:gosec
+	assert.NoError(t, RestrictTLSConfig(cfg))
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Equal(t, CipherSuites, cfg.CipherSuites)
+	assert.Equal(t, CurvePreferences, cfg.CurvePreferences)
+}
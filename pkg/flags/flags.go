@@ -142,6 +142,11 @@ var (
 	EnableKubernetesMeta = flag.Bool("ENABLE_KUBERNETES_META", false, "enable kubernetes meta")
 	ClusterID            = flag.String("GLOBAL_CLUSTER_ID", "", "cluster id")
 	ClusterType          = flag.String("GLOBAL_CLUSTER_TYPE", "", "cluster type, supporting ack, one, asi and k8s")
+	FIPSMode             = flag.Bool("FIPS_MODE", false, "restrict TLS and hashing plugins to FIPS approved algorithms")
+
+	Schema       = flag.Bool("schema", false, "render the output schema of the flushers in the plugin config for the sample events, without sending anything")
+	SchemaEvents = flag.String("schema-events", "./events.json", "the sample events to render the flusher schema, one json object per line")
+	SchemaFormat = flag.String("schema-format", "markdown", "the format of the rendered flusher schema, markdown or json")
 )
 
 // lookupFlag returns the flag.Flag for the given name, or an error if not found
@@ -343,6 +348,7 @@ func init() {
 	_ = util.InitFromEnvBool("ENABLE_KUBERNETES_META", EnableKubernetesMeta, *EnableKubernetesMeta)
 	_ = util.InitFromEnvString("GLOBAL_CLUSTER_ID", ClusterID, *ClusterID)
 	_ = util.InitFromEnvString("GLOBAL_CLUSTER_TYPE", ClusterType, *ClusterType)
+	_ = util.InitFromEnvBool("FIPS_MODE", FIPSMode, *FIPSMode)
 
 	if len(*DefaultRegion) == 0 {
 		*DefaultRegion = util.GuessRegionByEndpoint(*LogServiceEndpoint, "cn-hangzhou")
@@ -0,0 +1,126 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"sync"
+	"time"
+)
+
+var destinationRateLimiters = struct {
+	sync.Mutex
+	limiters map[string]*DestinationRateLimiter
+}{limiters: make(map[string]*DestinationRateLimiter)}
+
+// DestinationRateLimiter is a token bucket of bytes and requests shared by all the flushers writing to the same
+// destination, e.g. a sls project, so that their aggregate write rate respects the quota of the destination.
+// A write is allowed once the bucket is not in debt and takes its whole size, so that a large write is not split.
+// The bucket holds the tokens of one second at most. A nil limiter is unlimited. It is safe for concurrent use.
+type DestinationRateLimiter struct {
+	key string
+	// refs is guarded by the lock of destinationRateLimiters
+	refs int
+
+	mu                sync.Mutex
+	bytesPerSecond    float64
+	requestsPerSecond float64
+	bytes             float64
+	requests          float64
+	last              time.Time
+	now               func() time.Time
+}
+
+// AcquireDestinationRateLimiter returns the limiter of the destination shared in the process, a rate of 0 is unlimited
+// and nil is returned if both are 0. The rates of a shared limiter are updated to the latest acquired ones.
+// The limiter should be released once the flusher stops.
+func AcquireDestinationRateLimiter(key string, bytesPerSecond, requestsPerSecond float64) *DestinationRateLimiter {
+	if bytesPerSecond <= 0 && requestsPerSecond <= 0 {
+		return nil
+	}
+	destinationRateLimiters.Lock()
+	defer destinationRateLimiters.Unlock()
+	l, ok := destinationRateLimiters.limiters[key]
+	if !ok {
+		l = &DestinationRateLimiter{key: key, now: time.Now}
+		l.last = l.now()
+		l.bytes = bytesPerSecond
+		l.requests = requestsPerSecond
+		destinationRateLimiters.limiters[key] = l
+	}
+	l.refs++
+	l.mu.Lock()
+	l.bytesPerSecond = bytesPerSecond
+	l.requestsPerSecond = requestsPerSecond
+	l.mu.Unlock()
+	return l
+}
+
+// Release drops the reference of a flusher, the limiter is removed once no flusher uses it.
+func (l *DestinationRateLimiter) Release() {
+	if l == nil {
+		return
+	}
+	destinationRateLimiters.Lock()
+	defer destinationRateLimiters.Unlock()
+	l.refs--
+	if l.refs <= 0 && destinationRateLimiters.limiters[l.key] == l {
+		delete(destinationRateLimiters.limiters, l.key)
+	}
+}
+
+// Ready returns true if the destination is not in debt of either bytes or requests.
+func (l *DestinationRateLimiter) Ready() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return (l.bytesPerSecond <= 0 || l.bytes > 0) && (l.requestsPerSecond <= 0 || l.requests > 0)
+}
+
+// Take records a write request of the size in bytes, the bucket might go into debt.
+func (l *DestinationRateLimiter) Take(size int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.bytes -= float64(size)
+	l.requests--
+}
+
+func (l *DestinationRateLimiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	if elapsed <= 0 {
+		return
+	}
+	l.bytes = refillTokens(l.bytes, l.bytesPerSecond, elapsed)
+	l.requests = refillTokens(l.requests, l.requestsPerSecond, elapsed)
+}
+
+func refillTokens(tokens, perSecond, elapsed float64) float64 {
+	if perSecond <= 0 {
+		return 0
+	}
+	tokens += perSecond * elapsed
+	if tokens > perSecond {
+		return perSecond
+	}
+	return tokens
+}
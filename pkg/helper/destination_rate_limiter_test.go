@@ -0,0 +1,66 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestinationRateLimiter(t *testing.T) {
+	assert.Nil(t, AcquireDestinationRateLimiter("project", 0, 0))
+	var unlimited *DestinationRateLimiter
+	assert.True(t, unlimited.Ready())
+	unlimited.Take(100)
+	unlimited.Release()
+
+	now := time.Unix(1700000000, 0)
+	l := AcquireDestinationRateLimiter("endpoint/project", 1000, 10)
+	l.now = func() time.Time { return now }
+	l.last = now
+	// the limiter is shared by the flushers of the same destination
+	shared := AcquireDestinationRateLimiter("endpoint/project", 1000, 10)
+	assert.Same(t, l, shared)
+	other := AcquireDestinationRateLimiter("endpoint/other", 1000, 10)
+	assert.NotSame(t, l, other)
+	other.Release()
+
+	assert.True(t, l.Ready())
+	// a large write is allowed and puts the bucket into debt
+	l.Take(1500)
+	assert.False(t, shared.Ready())
+	now = now.Add(400 * time.Millisecond)
+	assert.False(t, l.Ready())
+	now = now.Add(200 * time.Millisecond)
+	assert.True(t, l.Ready())
+
+	// the tokens are capped at one second
+	now = now.Add(time.Minute)
+	for i := 0; i < 10; i++ {
+		assert.True(t, l.Ready())
+		l.Take(1)
+	}
+	assert.False(t, l.Ready(), "requests are exhausted")
+
+	l.Release()
+	assert.Same(t, l, AcquireDestinationRateLimiter("endpoint/project", 1000, 10))
+	l.Release()
+	shared.Release()
+	renewed := AcquireDestinationRateLimiter("endpoint/project", 1000, 10)
+	assert.NotSame(t, l, renewed)
+	renewed.Release()
+}
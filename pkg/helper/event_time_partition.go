@@ -0,0 +1,142 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+const (
+	// PartitionDateKey and PartitionHourKey are the default metadata keys of the event-time partition values,
+	// flushers could refer them by %{metadata.partition_date} and %{metadata.partition_hour}.
+	PartitionDateKey = "partition_date"
+	PartitionHourKey = "partition_hour"
+
+	defaultPartitionDateLayout = "2006-01-02"
+	defaultPartitionHourLayout = "15"
+)
+
+// EventTimePartitioner stamps group metadata with the partition values derived from the event time rather than
+// the processing time, so that object-store and ClickHouse flushers could partition correctly even when events
+// arrive late. Events of different partitions in the same group are split into separate groups.
+type EventTimePartitioner struct {
+	DateKey    string
+	HourKey    string // empty means no hour partition
+	DateLayout string
+	HourLayout string
+	Location   *time.Location
+
+	now func() time.Time
+}
+
+// NewEventTimePartitioner creates an EventTimePartitioner with date and hour partitions in local time.
+func NewEventTimePartitioner() *EventTimePartitioner {
+	return &EventTimePartitioner{
+		DateKey:    PartitionDateKey,
+		HourKey:    PartitionHourKey,
+		DateLayout: defaultPartitionDateLayout,
+		HourLayout: defaultPartitionHourLayout,
+		Location:   time.Local,
+		now:        time.Now,
+	}
+}
+
+type eventTimePartition struct {
+	date string
+	hour string
+}
+
+// Partition splits the group by the event-time partitions and stamps the partition values into the metadata of
+// each result group. The original group is returned directly when all events belong to the same partition.
+// Events without timestamp fall back to the observed timestamp, and then the current time.
+func (p *EventTimePartitioner) Partition(group *models.PipelineGroupEvents) []*models.PipelineGroupEvents {
+	if group == nil || len(group.Events) == 0 {
+		return nil
+	}
+	if group.Group == nil {
+		group.Group = models.NewGroup(models.NewMetadata(), models.NewTags())
+	}
+	if group.Group.Metadata == nil {
+		group.Group.Metadata = models.NewMetadata()
+	}
+
+	first := p.partitionOf(group.Events[0])
+	same := true
+	for _, event := range group.Events[1:] {
+		if p.partitionOf(event) != first {
+			same = false
+			break
+		}
+	}
+	if same {
+		p.stamp(group.Group.Metadata, first)
+		return []*models.PipelineGroupEvents{group}
+	}
+
+	var results []*models.PipelineGroupEvents
+	index := make(map[eventTimePartition]*models.PipelineGroupEvents)
+	for _, event := range group.Events {
+		partition := p.partitionOf(event)
+		result, ok := index[partition]
+		if !ok {
+			metadata := models.NewMetadata()
+			metadata.AddAll(group.Group.Metadata.Iterator())
+			p.stamp(metadata, partition)
+			result = &models.PipelineGroupEvents{Group: models.NewGroup(metadata, group.Group.Tags)}
+			index[partition] = result
+			results = append(results, result)
+		}
+		result.Events = append(result.Events, event)
+	}
+	return results
+}
+
+func (p *EventTimePartitioner) partitionOf(event models.PipelineEvent) eventTimePartition {
+	t := p.eventTime(event).In(p.location())
+	partition := eventTimePartition{date: t.Format(p.DateLayout)}
+	if p.HourKey != "" {
+		partition.hour = t.Format(p.HourLayout)
+	}
+	return partition
+}
+
+func (p *EventTimePartitioner) eventTime(event models.PipelineEvent) time.Time {
+	if ts := event.GetTimestamp(); ts > 0 {
+		return time.Unix(0, int64(ts))
+	}
+	if ts := event.GetObservedTimestamp(); ts > 0 {
+		return time.Unix(0, int64(ts))
+	}
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+func (p *EventTimePartitioner) location() *time.Location {
+	if p.Location == nil {
+		return time.Local
+	}
+	return p.Location
+}
+
+func (p *EventTimePartitioner) stamp(metadata models.Metadata, partition eventTimePartition) {
+	metadata.Add(p.DateKey, partition.date)
+	if p.HourKey != "" {
+		metadata.Add(p.HourKey, partition.hour)
+	}
+}
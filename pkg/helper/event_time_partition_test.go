@@ -0,0 +1,71 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+func TestEventTimePartitioner(t *testing.T) {
+	p := NewEventTimePartitioner()
+	p.Location = time.UTC
+	p.now = func() time.Time { return time.Date(2024, 5, 2, 0, 30, 0, 0, time.UTC) }
+
+	ts := func(hour int) uint64 {
+		return uint64(time.Date(2024, 5, 1, hour, 10, 0, 0, time.UTC).UnixNano())
+	}
+	group := &models.PipelineGroupEvents{
+		Group: models.NewGroup(models.NewMetadataWithKeyValues("db", "test"), models.NewTagsWithKeyValues("host", "a")),
+		Events: []models.PipelineEvent{
+			models.NewSimpleLog([]byte("late"), nil, ts(22)),
+			models.NewSimpleLog([]byte("on time"), nil, ts(23)),
+			models.NewSimpleLog([]byte("late again"), nil, ts(22)),
+			models.NewSimpleLog([]byte("no timestamp"), nil, 0),
+		},
+	}
+
+	results := p.Partition(group)
+	require.Len(t, results, 3)
+	assert.Equal(t, map[string]string{"db": "test", PartitionDateKey: "2024-05-01", PartitionHourKey: "22"}, results[0].Group.Metadata.Iterator())
+	assert.Len(t, results[0].Events, 2)
+	assert.Equal(t, "23", results[1].Group.Metadata.Get(PartitionHourKey))
+	assert.Equal(t, map[string]string{"db": "test", PartitionDateKey: "2024-05-02", PartitionHourKey: "00"}, results[2].Group.Metadata.Iterator())
+	assert.Equal(t, "a", results[2].Group.Tags.Get("host"))
+	// the original metadata is untouched when the group is split.
+	assert.False(t, group.Group.Metadata.Contains(PartitionDateKey))
+}
+
+func TestEventTimePartitionerSamePartition(t *testing.T) {
+	p := NewEventTimePartitioner()
+	p.Location = time.UTC
+	p.HourKey = ""
+	group := &models.PipelineGroupEvents{
+		Events: []models.PipelineEvent{
+			models.NewSimpleLog(nil, nil, uint64(time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC).UnixNano())),
+			models.NewSimpleLog(nil, nil, uint64(time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC).UnixNano())),
+		},
+	}
+	results := p.Partition(group)
+	require.Len(t, results, 1)
+	assert.Same(t, group, results[0])
+	assert.Equal(t, map[string]string{PartitionDateKey: "2024-05-01"}, group.Group.Metadata.Iterator())
+	assert.Nil(t, p.Partition(&models.PipelineGroupEvents{}))
+}
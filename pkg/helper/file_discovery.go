@@ -0,0 +1,253 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const doubleStar = "**"
+
+// FileDiscovery finds the files matched by the path patterns. Besides the syntax of filepath.Match, "**" in a pattern
+// matches any levels of directories. The symbolic links are followed, so the symlink farms like /var/log/containers
+// could be discovered, and a file reached from several links is returned only once. The files or directories matched
+// by the exclude patterns are skipped. The result is cached and rediscovered after the refresh interval.
+type FileDiscovery struct {
+	patterns        [][]string
+	excludePatterns [][]string
+	maxDepth        int
+	refreshInterval time.Duration
+
+	lock         sync.Mutex
+	files        []string
+	lastRefresh  time.Time
+	matchedFiles pipeline.GaugeMetric
+	now          func() time.Time
+}
+
+// NewFileDiscovery creates a FileDiscovery. maxDepth limits the levels of directories matched by "**", and 0 means
+// unlimited. The files are rediscovered on every call of Files if refreshInterval is not positive. The matched file
+// count is reported to metricsRecord if it is not nil.
+func NewFileDiscovery(patterns, excludePatterns []string, maxDepth int, refreshInterval time.Duration, metricsRecord *pipeline.MetricsRecord) (*FileDiscovery, error) {
+	d := &FileDiscovery{
+		maxDepth:        maxDepth,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+	for _, pattern := range patterns {
+		segments, err := splitPathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		d.patterns = append(d.patterns, segments)
+	}
+	for _, pattern := range excludePatterns {
+		segments, err := splitPathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		d.excludePatterns = append(d.excludePatterns, segments)
+	}
+	if metricsRecord != nil {
+		d.matchedFiles = NewGaugeMetricAndRegister(metricsRecord, MetricPluginMatchedFileTotal)
+	}
+	return d, nil
+}
+
+// Files returns the sorted matched files, which are rediscovered if the refresh interval has passed.
+func (d *FileDiscovery) Files() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := d.now()
+	if d.files != nil && d.refreshInterval > 0 && now.Sub(d.lastRefresh) < d.refreshInterval {
+		return d.files
+	}
+	d.files = d.discover()
+	d.lastRefresh = now
+	if d.matchedFiles != nil {
+		d.matchedFiles.Set(float64(len(d.files)))
+	}
+	return d.files
+}
+
+func (d *FileDiscovery) discover() []string {
+	w := &fileWalker{
+		discovery: d,
+		files:     make([]string, 0),
+		realFiles: make(map[string]struct{}),
+		visited:   make(map[string]struct{}),
+	}
+	for _, segments := range d.patterns {
+		root, rest := patternRoot(segments)
+		w.walk(root, rest, 0)
+	}
+	sort.Strings(w.files)
+	return w.files
+}
+
+func (d *FileDiscovery) excluded(path string) bool {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for _, pattern := range d.excludePatterns {
+		if matchPathSegments(pattern, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+type fileWalker struct {
+	discovery *FileDiscovery
+	files     []string
+	// the real paths of the found files and the visited directories, to skip the duplicated links and the link cycles
+	realFiles map[string]struct{}
+	visited   map[string]struct{}
+}
+
+// walk matches the rest segments of the pattern under the path, depth is the levels of directories matched by "**".
+func (w *fileWalker) walk(path string, segments []string, depth int) {
+	if w.discovery.excluded(path) {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if len(segments) == 0 {
+		if !info.Mode().IsRegular() {
+			return
+		}
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return
+		}
+		if _, ok := w.realFiles[realPath]; ok {
+			return
+		}
+		w.realFiles[realPath] = struct{}{}
+		w.files = append(w.files, path)
+		return
+	}
+	if !info.IsDir() {
+		return
+	}
+	segment := segments[0]
+	if segment == doubleStar {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return
+		}
+		key := fmt.Sprintf("%s:%d", realPath, len(segments))
+		if _, ok := w.visited[key]; ok {
+			return
+		}
+		w.visited[key] = struct{}{}
+		// "**" matches zero directory
+		w.walk(path, segments[1:], depth)
+		if w.discovery.maxDepth > 0 && depth >= w.discovery.maxDepth {
+			return
+		}
+		for _, name := range readDirNames(path) {
+			w.walk(filepath.Join(path, name), segments, depth+1)
+		}
+		return
+	}
+	if !hasGlobMeta(segment) {
+		w.walk(filepath.Join(path, segment), segments[1:], depth)
+		return
+	}
+	for _, name := range readDirNames(path) {
+		if matched, _ := filepath.Match(segment, name); matched {
+			w.walk(filepath.Join(path, name), segments[1:], depth)
+		}
+	}
+}
+
+func readDirNames(path string) []string {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func splitPathPattern(pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty path pattern")
+	}
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	for _, segment := range segments {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %s: %v", pattern, err)
+		}
+	}
+	return segments, nil
+}
+
+// patternRoot returns the directory made of the leading segments without glob meta characters and the rest segments.
+func patternRoot(segments []string) (string, []string) {
+	i := 0
+	for ; i < len(segments)-1; i++ {
+		if segments[i] == doubleStar || hasGlobMeta(segments[i]) {
+			break
+		}
+	}
+	root := strings.Join(segments[:i], "/")
+	if root == "" && i > 0 {
+		// the pattern starts with "/"
+		root = "/"
+	}
+	if root == "" {
+		root = "."
+	}
+	return filepath.FromSlash(root), segments[i:]
+}
+
+// matchPathSegments reports whether the path segments match the pattern segments, "**" matches any levels of them.
+func matchPathSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == doubleStar {
+		for i := 0; i <= len(path); i++ {
+			if matchPathSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+		return false
+	}
+	return matchPathSegments(pattern[1:], path[1:])
+}
+
+func hasGlobMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
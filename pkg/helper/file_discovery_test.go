@@ -0,0 +1,122 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+func writeTestFile(t *testing.T, path string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte("test"), 0600))
+}
+
+func TestFileDiscovery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links are not supported")
+	}
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "pods", "default_app_1", "app", "0.log"))
+	writeTestFile(t, filepath.Join(root, "pods", "default_app_1", "app", "0.log.gz"))
+	writeTestFile(t, filepath.Join(root, "pods", "kube-system_dns_1", "dns", "0.log"))
+	writeTestFile(t, filepath.Join(root, "pods", "default_app_1", "app", "deep", "er", "1.log"))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "containers"), 0750))
+	require.NoError(t, os.Symlink(filepath.Join(root, "pods", "default_app_1", "app", "0.log"), filepath.Join(root, "containers", "app.log")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "pods"), filepath.Join(root, "pods", "default_app_1", "loop")))
+
+	// the links are followed, and the file reached from several paths is returned once
+	d, err := NewFileDiscovery([]string{filepath.Join(root, "**", "*.log")}, nil, 0, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "containers", "app.log"),
+		filepath.Join(root, "pods", "default_app_1", "app", "deep", "er", "1.log"),
+		filepath.Join(root, "pods", "kube-system_dns_1", "dns", "0.log"),
+	}, d.Files())
+
+	d, err = NewFileDiscovery([]string{filepath.Join(root, "containers", "*.log")}, nil, 0, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "containers", "app.log")}, d.Files())
+
+	// exclude the directories and the files
+	d, err = NewFileDiscovery([]string{filepath.Join(root, "pods", "**", "*.log")},
+		[]string{filepath.Join(root, "pods", "kube-system_*"), filepath.Join(root, "**", "deep", "**")}, 0, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "pods", "default_app_1", "app", "0.log")}, d.Files())
+
+	// limit the levels of directories matched by "**"
+	d, err = NewFileDiscovery([]string{filepath.Join(root, "pods", "**", "*.log")}, nil, 2, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "pods", "default_app_1", "app", "0.log"),
+		filepath.Join(root, "pods", "kube-system_dns_1", "dns", "0.log"),
+	}, d.Files())
+
+	_, err = NewFileDiscovery([]string{filepath.Join(root, "[")}, nil, 0, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestFileDiscoveryRefresh(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "a.log"))
+	metricsRecord := &pipeline.MetricsRecord{}
+	d, err := NewFileDiscovery([]string{filepath.Join(root, "*.log")}, nil, 0, time.Minute, metricsRecord)
+	require.NoError(t, err)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	assert.Len(t, d.Files(), 1)
+	assert.Equal(t, 1., d.matchedFiles.Collect().Value)
+
+	writeTestFile(t, filepath.Join(root, "b.log"))
+	now = now.Add(30 * time.Second)
+	assert.Len(t, d.Files(), 1)
+	now = now.Add(31 * time.Second)
+	assert.Len(t, d.Files(), 2)
+	assert.Equal(t, 2., d.matchedFiles.Collect().Value)
+}
+
+func TestMatchPathSegments(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/var/log/**", "/var/log", true},
+		{"/var/log/**", "/var/log/pods/a/b.log", true},
+		{"/var/log/**/*.log", "/var/log/b.log", true},
+		{"/var/log/**/*.log", "/var/log/pods/a/b.log", true},
+		{"/var/log/**/*.log", "/var/log/pods/a/b.txt", false},
+		{"/var/log/*/b.log", "/var/log/pods/a/b.log", false},
+		{"/var/**/a/*.log", "/var/log/pods/a/b.log", true},
+	}
+	for _, c := range cases {
+		pattern, err := splitPathPattern(c.pattern)
+		require.NoError(t, err)
+		assert.Equal(t, c.matched, matchPathSegments(pattern, splitTestPath(c.path)), c.pattern+" "+c.path)
+	}
+}
+
+func splitTestPath(path string) []string {
+	segments, _ := splitPathPattern(path)
+	return segments
+}
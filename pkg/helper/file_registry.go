@@ -0,0 +1,108 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"sort"
+	"sync"
+)
+
+// FileRegistration is a pipeline config reading the file.
+type FileRegistration struct {
+	ConfigName string
+	Path       string
+}
+
+// FileRegistry records the pipeline configs reading the files, it is shared by all configs so that the same file
+// matched by several configs could be detected instead of being shipped twice silently. The files are identified by
+// their real paths, which are stable over the rotation.
+type FileRegistry struct {
+	lock  sync.Mutex
+	files map[string]map[string]FileRegistration
+}
+
+var (
+	fileRegistry     *FileRegistry
+	fileRegistryOnce sync.Once
+)
+
+// GetFileRegistry returns the file registry shared by all configs.
+func GetFileRegistry() *FileRegistry {
+	fileRegistryOnce.Do(func() {
+		fileRegistry = NewFileRegistry()
+	})
+	return fileRegistry
+}
+
+func NewFileRegistry() *FileRegistry {
+	return &FileRegistry{
+		files: make(map[string]map[string]FileRegistration),
+	}
+}
+
+// Register records the config as a reader of the file, and returns the other configs reading it sorted by the config
+// name. If exclusive is true, the config is recorded only when no other config reads the file, and false is returned
+// if it is not recorded. Registering a config twice is harmless.
+func (r *FileRegistry) Register(path, configName string, exclusive bool) ([]FileRegistration, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	readers := r.files[path]
+	others := make([]FileRegistration, 0, len(readers))
+	for name, registration := range readers {
+		if name != configName {
+			others = append(others, registration)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].ConfigName < others[j].ConfigName
+	})
+	if exclusive && len(others) > 0 {
+		return others, false
+	}
+	if readers == nil {
+		readers = make(map[string]FileRegistration)
+		r.files[path] = readers
+	}
+	readers[configName] = FileRegistration{ConfigName: configName, Path: path}
+	return others, true
+}
+
+// Unregister removes the config from the readers of the file.
+func (r *FileRegistry) Unregister(path, configName string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	readers, ok := r.files[path]
+	if !ok {
+		return
+	}
+	delete(readers, configName)
+	if len(readers) == 0 {
+		delete(r.files, path)
+	}
+}
+
+// Readers returns the configs reading the file sorted by the config name.
+func (r *FileRegistry) Readers(path string) []FileRegistration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	readers := make([]FileRegistration, 0, len(r.files[path]))
+	for _, registration := range r.files[path] {
+		readers = append(readers, registration)
+	}
+	sort.Slice(readers, func(i, j int) bool {
+		return readers[i].ConfigName < readers[j].ConfigName
+	})
+	return readers
+}
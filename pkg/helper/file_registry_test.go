@@ -0,0 +1,55 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRegistry(t *testing.T) {
+	r := NewFileRegistry()
+	others, ok := r.Register("/var/log/a.log", "config_b", false)
+	assert.True(t, ok)
+	assert.Empty(t, others)
+
+	// registering twice is harmless
+	others, ok = r.Register("/var/log/a.log", "config_b", true)
+	assert.True(t, ok)
+	assert.Empty(t, others)
+
+	others, ok = r.Register("/var/log/a.log", "config_a", false)
+	assert.True(t, ok)
+	assert.Equal(t, []FileRegistration{{ConfigName: "config_b", Path: "/var/log/a.log"}}, others)
+
+	// the exclusive config is not recorded when the file is read by others
+	others, ok = r.Register("/var/log/a.log", "config_c", true)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"config_a", "config_b"}, []string{others[0].ConfigName, others[1].ConfigName})
+	assert.Len(t, r.Readers("/var/log/a.log"), 2)
+
+	r.Unregister("/var/log/a.log", "config_a")
+	r.Unregister("/var/log/a.log", "config_b")
+	assert.Empty(t, r.Readers("/var/log/a.log"))
+	others, ok = r.Register("/var/log/a.log", "config_c", true)
+	assert.True(t, ok)
+	assert.Empty(t, others)
+
+	// unknown files and configs are ignored
+	r.Unregister("/var/log/b.log", "config_a")
+	r.Unregister("/var/log/a.log", "config_a")
+	assert.Len(t, r.Readers("/var/log/a.log"), 1)
+}
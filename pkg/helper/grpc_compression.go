@@ -0,0 +1,87 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // register the gzip compressor
+)
+
+// The compressors are registered to grpc, so that the grpc servers could accept the requests compressed by them.
+// The size of the decompressed message is limited by the MaxRecvMsgSize of the server.
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+	encoding.RegisterCompressor(&snappyCompressor{})
+}
+
+type zstdCompressor struct {
+	encoders sync.Pool
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w)
+	return err
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	if zw, ok := c.encoders.Get().(*zstdWriter); ok {
+		zw.Encoder.Reset(w)
+		return zw, nil
+	}
+	encoder, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdWriter{Encoder: encoder, pool: &c.encoders}, nil
+}
+
+// Decompress doesn't reuse the decoders, because grpc gives no signal when the reader is drained.
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+func (c *zstdCompressor) Name() string {
+	return "zstd"
+}
+
+// snappyCompressor uses the snappy framing format.
+type snappyCompressor struct{}
+
+func (c *snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (c *snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
+
+func (c *snappyCompressor) Name() string {
+	return "snappy"
+}
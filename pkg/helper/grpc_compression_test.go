@@ -0,0 +1,49 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestGRPCCompressors(t *testing.T) {
+	data := bytes.Repeat([]byte("hello world "), 1000)
+	for _, name := range []string{"gzip", "zstd", "snappy"} {
+		compressor := encoding.GetCompressor(name)
+		require.NotNil(t, compressor, name)
+		// compress twice to reuse the pooled encoders
+		for i := 0; i < 2; i++ {
+			var buf bytes.Buffer
+			w, err := compressor.Compress(&buf)
+			require.NoError(t, err)
+			_, err = w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			assert.Less(t, buf.Len(), len(data))
+
+			r, err := compressor.Decompress(&buf)
+			require.NoError(t, err)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, data, got, name)
+		}
+	}
+}
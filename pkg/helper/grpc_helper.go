@@ -246,6 +246,8 @@ func (cfg *GRPCServerSettings) GetServerOption() ([]grpc.ServerOption, error) {
 			switch dc {
 			case "gzip":
 				opts = append(opts, grpc.RPCDecompressor(grpc.NewGZIPDecompressor()))
+			case "zstd", "snappy":
+				// decompressed by the registered compressors
 			default:
 				err = fmt.Errorf("invalid decompression: %s", cfg.Decompression)
 			}
@@ -0,0 +1,154 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/flags"
+	"github.com/alibaba/ilogtail/pkg/helper/platformmeta"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	IdentitySourceConfig = "config"
+	IdentitySourceEnv    = "env"
+	IdentitySourceK8s    = "k8s"
+	IdentitySourceCloud  = "cloud"
+	IdentitySourceHost   = "host"
+
+	identityHostnameEnv = "LOGTAIL_HOSTNAME"
+	identityHostIPEnv   = "LOGTAIL_HOST_IP"
+	identityRegionEnv   = "LOGTAIL_REGION"
+	// the downward API env of the daemonset
+	k8sNodeNameEnv = "_node_name_"
+	k8sNodeIPEnv   = "_node_ip_"
+)
+
+// Identity is how the agent names itself in the tags and the self monitoring data.
+type Identity struct {
+	Hostname string
+	HostIP   string
+	Cluster  string
+	Region   string
+}
+
+// identitySourceFunc returns the fields the source knows about, and empty for the others.
+type identitySourceFunc func() Identity
+
+type identityResolver struct {
+	sources map[string]identitySourceFunc
+}
+
+func newIdentityResolver(cfg *config.GlobalConfig) *identityResolver {
+	return &identityResolver{
+		sources: map[string]identitySourceFunc{
+			IdentitySourceConfig: func() Identity {
+				id := Identity{
+					Hostname: cfg.Identity.Hostname,
+					HostIP:   cfg.Identity.HostIP,
+					Cluster:  cfg.Identity.Cluster,
+					Region:   cfg.Identity.Region,
+				}
+				// loongcollector passes both or neither of them
+				if id.Hostname == "" && id.HostIP == "" && cfg.Hostname != "" && cfg.HostIP != "" {
+					id.Hostname, id.HostIP = cfg.Hostname, cfg.HostIP
+				}
+				return id
+			},
+			IdentitySourceEnv: func() Identity {
+				return Identity{
+					Hostname: os.Getenv(identityHostnameEnv),
+					HostIP:   os.Getenv(identityHostIPEnv),
+					Cluster:  *flags.ClusterID,
+					Region:   os.Getenv(identityRegionEnv),
+				}
+			},
+			IdentitySourceK8s: func() Identity {
+				return Identity{
+					Hostname: os.Getenv(k8sNodeNameEnv),
+					HostIP:   os.Getenv(k8sNodeIPEnv),
+				}
+			},
+			IdentitySourceCloud: func() Identity {
+				manager := platformmeta.GetManager(platformmeta.Auto)
+				if manager == nil {
+					return Identity{}
+				}
+				manager.StartCollect()
+				return Identity{
+					Hostname: manager.GetInstanceHostname(),
+					HostIP:   manager.GetInstancePrivateIP(),
+					Region:   manager.GetInstanceRegion(),
+				}
+			},
+			IdentitySourceHost: func() Identity {
+				return Identity{
+					Hostname: util.GetDetectedHostName(),
+					HostIP:   util.GetDetectedIPAddress(),
+				}
+			},
+		},
+	}
+}
+
+// resolve fills each field with the value of the first source providing it, and returns the source of each field.
+func (r *identityResolver) resolve(precedence []string) (Identity, map[string]string, error) {
+	var id Identity
+	sourceOf := make(map[string]string)
+	pick := func(field string, value string, target *string, source string) {
+		if *target == "" && value != "" {
+			*target = value
+			sourceOf[field] = source
+		}
+	}
+	for _, source := range precedence {
+		sourceFunc, ok := r.sources[source]
+		if !ok {
+			return Identity{}, nil, fmt.Errorf("unknown identity source %q", source)
+		}
+		value := sourceFunc()
+		pick("hostname", value.Hostname, &id.Hostname, source)
+		pick("host_ip", value.HostIP, &id.HostIP, source)
+		pick("cluster", value.Cluster, &id.Cluster, source)
+		pick("region", value.Region, &id.Region, source)
+		if id.Hostname != "" && id.HostIP != "" && id.Cluster != "" && id.Region != "" {
+			break
+		}
+	}
+	return id, sourceOf, nil
+}
+
+// InitIdentity resolves the identity by the precedence of the global config, and makes it the one returned by
+// util.GetHostName, util.GetIPAddress, util.GetCluster and util.GetRegion. The cluster is also the default of
+// GLOBAL_CLUSTER_ID. The host source is used alone if the precedence is invalid.
+func InitIdentity(cfg *config.GlobalConfig) Identity {
+	id, sourceOf, err := newIdentityResolver(cfg).resolve(cfg.Identity.Precedence)
+	if err != nil {
+		logger.Warning(context.Background(), "IDENTITY_ALARM", "invalid identity precedence, use the host", err)
+		id, sourceOf, _ = newIdentityResolver(cfg).resolve([]string{IdentitySourceHost})
+	}
+	util.SetNetworkIdentification(id.HostIP, id.Hostname)
+	util.SetClusterAndRegion(id.Cluster, id.Region)
+	if *flags.ClusterID == "" {
+		*flags.ClusterID = id.Cluster
+	}
+	logger.Info(context.Background(), "identity", id, "sources", sourceOf)
+	return id
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/flags"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+func TestIdentityResolverPrecedence(t *testing.T) {
+	t.Setenv(identityHostnameEnv, "env-host")
+	t.Setenv(identityRegionEnv, "cn-hangzhou")
+	t.Setenv(k8sNodeNameEnv, "node-1")
+	t.Setenv(k8sNodeIPEnv, "10.0.0.1")
+	cfg := &config.GlobalConfig{Identity: config.IdentityConfig{Cluster: "prod"}}
+	r := newIdentityResolver(cfg)
+
+	id, sourceOf, err := r.resolve([]string{IdentitySourceConfig, IdentitySourceEnv, IdentitySourceK8s, IdentitySourceHost})
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Hostname: "env-host", HostIP: "10.0.0.1", Cluster: "prod", Region: "cn-hangzhou"}, id)
+	assert.Equal(t, map[string]string{"hostname": "env", "host_ip": "k8s", "cluster": "config", "region": "env"}, sourceOf)
+
+	id, _, err = r.resolve([]string{IdentitySourceK8s, IdentitySourceEnv})
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", id.Hostname)
+	assert.Equal(t, "10.0.0.1", id.HostIP)
+	assert.Empty(t, id.Cluster)
+
+	id, _, err = r.resolve([]string{IdentitySourceHost})
+	require.NoError(t, err)
+	assert.Equal(t, util.GetDetectedHostName(), id.Hostname)
+
+	_, _, err = r.resolve([]string{"dns"})
+	assert.Error(t, err)
+}
+
+func TestIdentityResolverLoongcollectorConfig(t *testing.T) {
+	cfg := &config.GlobalConfig{Hostname: "lc-host", HostIP: "192.168.1.1"}
+	id, _, err := newIdentityResolver(cfg).resolve([]string{IdentitySourceConfig})
+	require.NoError(t, err)
+	assert.Equal(t, "lc-host", id.Hostname)
+	assert.Equal(t, "192.168.1.1", id.HostIP)
+
+	// the static values override the ones passed by loongcollector
+	cfg.Identity.Hostname = "static-host"
+	id, _, err = newIdentityResolver(cfg).resolve([]string{IdentitySourceConfig})
+	require.NoError(t, err)
+	assert.Equal(t, "static-host", id.Hostname)
+	assert.Empty(t, id.HostIP)
+}
+
+func TestInitIdentity(t *testing.T) {
+	hostname, hostIP, clusterID := util.GetHostName(), util.GetIPAddress(), *flags.ClusterID
+	defer func() {
+		util.SetNetworkIdentification(hostIP, hostname)
+		util.SetClusterAndRegion("", "")
+		*flags.ClusterID = clusterID
+	}()
+	*flags.ClusterID = ""
+
+	cfg := &config.GlobalConfig{Identity: config.IdentityConfig{
+		Precedence: []string{IdentitySourceConfig, IdentitySourceHost},
+		Hostname:   "static-host",
+		Cluster:    "prod",
+		Region:     "cn-beijing",
+	}}
+	InitIdentity(cfg)
+	assert.Equal(t, "static-host", util.GetHostName())
+	assert.Equal(t, util.GetDetectedIPAddress(), util.GetIPAddress())
+	assert.Equal(t, "prod", util.GetCluster())
+	assert.Equal(t, "cn-beijing", util.GetRegion())
+	assert.Equal(t, "prod", *flags.ClusterID)
+
+	cfg.Identity.Precedence = []string{"unknown"}
+	InitIdentity(cfg)
+	assert.Equal(t, util.GetDetectedHostName(), util.GetHostName())
+}
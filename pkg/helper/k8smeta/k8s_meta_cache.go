@@ -2,16 +2,27 @@ package k8smeta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	app "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
 	networking "k8s.io/api/networking/v1"
 	storage "k8s.io/api/storage/v1"
 	meta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -20,7 +31,13 @@ import (
 	"github.com/alibaba/ilogtail/pkg/logger"
 )
 
-const hostIPIndexPrefix = "host/"
+const (
+	hostIPIndexPrefix        = "host/"
+	ownerIndexPrefix         = "owner/"
+	containerNameIndexPrefix = "container/"
+	ipPortIndexPrefix        = "ipport/"
+	serviceIndexPrefix       = "service/"
+)
 
 type k8sMetaCache struct {
 	metaStore *DeferredDeletionMetaStore
@@ -29,8 +46,17 @@ type k8sMetaCache struct {
 	eventCh chan *K8sMetaEvent
 	stopCh  chan struct{}
 
-	resourceType string
-	schema       *runtime.Scheme
+	resourceType    string
+	schema          *runtime.Scheme
+	informerOptions informerOptions
+
+	// the custom resources are watched by the dynamic informers
+	gvr           *schema.GroupVersionResource
+	dynamicClient dynamic.Interface
+
+	// synced is set once the informer is synced, lastEventTime is the unix second of the last informer event
+	synced        atomic.Bool
+	lastEventTime atomic.Int64
 }
 
 func newK8sMetaCache(stopCh chan struct{}, resourceType string) *k8sMetaCache {
@@ -38,7 +64,10 @@ func newK8sMetaCache(stopCh chan struct{}, resourceType string) *k8sMetaCache {
 	m := &k8sMetaCache{}
 	m.eventCh = make(chan *K8sMetaEvent, 100)
 	m.stopCh = stopCh
-	m.metaStore = NewDeferredDeletionMetaStore(m.eventCh, m.stopCh, 120, cache.MetaNamespaceKeyFunc, idxRules...)
+	retention := newDeletedRetentionFromEnv(resourceType)
+	m.informerOptions = newInformerOptionsFromEnv(resourceType)
+	m.metaStore = NewDeferredDeletionMetaStore(m.eventCh, m.stopCh, retention.ttlSec, cache.MetaNamespaceKeyFunc, idxRules...)
+	m.metaStore.deleted = newDeletedLRU(retention.maxEntries)
 	m.resourceType = resourceType
 	m.schema = runtime.NewScheme()
 	_ = v1.AddToScheme(m.schema)
@@ -46,6 +75,14 @@ func newK8sMetaCache(stopCh chan struct{}, resourceType string) *k8sMetaCache {
 	_ = app.AddToScheme(m.schema)
 	_ = networking.AddToScheme(m.schema)
 	_ = storage.AddToScheme(m.schema)
+	_ = discovery.AddToScheme(m.schema)
+	return m
+}
+
+func newCustomMetaCache(stopCh chan struct{}, resourceType string, gvr schema.GroupVersionResource, dynamicClient dynamic.Interface) *k8sMetaCache {
+	m := newK8sMetaCache(stopCh, resourceType)
+	m.gvr = &gvr
+	m.dynamicClient = dynamicClient
 	return m
 }
 
@@ -67,6 +104,15 @@ func (m *k8sMetaCache) GetQueueSize() int {
 	return len(m.eventCh)
 }
 
+func (m *k8sMetaCache) syncStatus() *cacheSyncStatus {
+	return &cacheSyncStatus{
+		Synced:        m.synced.Load(),
+		LastEventTime: m.lastEventTime.Load(),
+		Entries:       m.GetSize(),
+		QueueSize:     m.GetQueueSize(),
+	}
+}
+
 func (m *k8sMetaCache) List() []*ObjectWrapper {
 	return m.metaStore.List()
 }
@@ -80,14 +126,31 @@ func (m *k8sMetaCache) RegisterSendFunc(key string, sendFunc SendFunc, interval
 	logger.Debug(context.Background(), "register send func", m.resourceType)
 }
 
+func (m *k8sMetaCache) RegisterWatchFunc(key string, sendFunc SendFunc) {
+	m.metaStore.RegisterWatchFunc(key, sendFunc)
+}
+
+func (m *k8sMetaCache) RegisterListWatchFunc(key string, sendFunc SendFunc) ([]*ObjectWrapper, <-chan struct{}) {
+	return m.metaStore.RegisterListWatchFunc(key, sendFunc)
+}
+
 func (m *k8sMetaCache) UnRegisterSendFunc(key string) {
 	m.metaStore.UnRegisterSendFunc(key)
 }
 
 func (m *k8sMetaCache) watch(stopCh <-chan struct{}) {
-	factory, informer := m.getFactoryInformer()
-	if informer == nil {
-		return
+	var informer cache.SharedIndexInformer
+	var startFactory func(stopCh <-chan struct{})
+	if m.gvr != nil {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(m.dynamicClient, m.informerOptions.resyncPeriod, metav1.NamespaceAll, m.informerOptions.tweakListOptions)
+		informer = factory.ForResource(*m.gvr).Informer()
+		startFactory = factory.Start
+	} else {
+		var factory informers.SharedInformerFactory
+		if factory, informer = m.getFactoryInformer(); informer == nil {
+			return
+		}
+		startFactory = factory.Start
 	}
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -101,6 +164,7 @@ func (m *k8sMetaCache) watch(stopCh <-chan struct{}) {
 					LastObservedTime:  nowTime,
 				},
 			}
+			m.lastEventTime.Store(nowTime)
 			metaManager.addEventCount.Add(1)
 		},
 		UpdateFunc: func(oldObj interface{}, obj interface{}) {
@@ -114,21 +178,24 @@ func (m *k8sMetaCache) watch(stopCh <-chan struct{}) {
 					LastObservedTime:  nowTime,
 				},
 			}
+			m.lastEventTime.Store(nowTime)
 			metaManager.updateEventCount.Add(1)
 		},
 		DeleteFunc: func(obj interface{}) {
+			nowTime := time.Now().Unix()
 			m.eventCh <- &K8sMetaEvent{
 				EventType: EventTypeDelete,
 				Object: &ObjectWrapper{
 					ResourceType:     m.resourceType,
 					Raw:              m.preProcess(obj),
-					LastObservedTime: time.Now().Unix(),
+					LastObservedTime: nowTime,
 				},
 			}
+			m.lastEventTime.Store(nowTime)
 			metaManager.deleteEventCount.Add(1)
 		},
 	})
-	go factory.Start(stopCh)
+	go startFactory(stopCh)
 	// wait infinite for first cache sync success
 	for {
 		if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
@@ -138,16 +205,72 @@ func (m *k8sMetaCache) watch(stopCh <-chan struct{}) {
 			break
 		}
 	}
+	m.synced.Store(true)
+	if expired := m.metaStore.expireRestored(informer.GetStore().ListKeys()); expired > 0 {
+		logger.Info(context.Background(), "expire k8s meta restored from snapshot", m.resourceType, "count", expired)
+	}
 }
 
-func (m *k8sMetaCache) getFactoryInformer() (informers.SharedInformerFactory, cache.SharedIndexInformer) {
-	var factory informers.SharedInformerFactory
-	switch m.resourceType {
-	case POD:
-		factory = informers.NewSharedInformerFactory(m.clientset, time.Hour*24)
-	default:
-		factory = informers.NewSharedInformerFactory(m.clientset, time.Hour*1)
+// dump returns the objects not deleted in json, the objects of the built-in resources carry their kind as filled by preProcess.
+func (m *k8sMetaCache) dump() []json.RawMessage {
+	items := m.metaStore.Filter(func(ow *ObjectWrapper) bool { return !ow.Deleted }, 0)
+	result := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := json.Marshal(item.Raw)
+		if err != nil {
+			logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "marshal object error", err, "resource", m.resourceType)
+			continue
+		}
+		result = append(result, data)
 	}
+	return result
+}
+
+// restore decodes the objects dumped and adds them to the store, the number of the restored objects is returned.
+func (m *k8sMetaCache) restore(items []json.RawMessage) int {
+	nowTime := time.Now().Unix()
+	objs := make([]*ObjectWrapper, 0, len(items))
+	for _, item := range items {
+		raw, err := m.decode(item)
+		if err != nil {
+			logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "decode object error", err, "resource", m.resourceType)
+			continue
+		}
+		objs = append(objs, &ObjectWrapper{
+			ResourceType:      m.resourceType,
+			Raw:               raw,
+			FirstObservedTime: nowTime,
+			LastObservedTime:  nowTime,
+		})
+	}
+	return m.metaStore.restore(objs)
+}
+
+func (m *k8sMetaCache) decode(data []byte) (runtime.Object, error) {
+	if m.gvr != nil {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	obj, err := m.schema.New(typeMeta.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (m *k8sMetaCache) getFactoryInformer() (informers.SharedInformerFactory, cache.SharedIndexInformer) {
+	factory := informers.NewSharedInformerFactoryWithOptions(m.clientset, m.informerOptions.resyncPeriod,
+		informers.WithTweakListOptions(m.informerOptions.tweakListOptions))
 	var informer cache.SharedIndexInformer
 	switch m.resourceType {
 	case POD:
@@ -180,6 +303,8 @@ func (m *k8sMetaCache) getFactoryInformer() (informers.SharedInformerFactory, ca
 		informer = factory.Storage().V1().StorageClasses().Informer()
 	case INGRESS:
 		informer = factory.Networking().V1().Ingresses().Informer()
+	case ENDPOINTSLICE:
+		informer = factory.Discovery().V1().EndpointSlices().Informer()
 	default:
 		logger.Error(context.Background(), "ENTITY_PIPELINE_REGISTER_ERROR", "resourceType not support", m.resourceType)
 		return factory, nil
@@ -190,17 +315,22 @@ func (m *k8sMetaCache) getFactoryInformer() (informers.SharedInformerFactory, ca
 func getIdxRules(resourceType string) []IdxFunc {
 	switch resourceType {
 	case NODE:
-		return []IdxFunc{generateNodeKey}
+		return []IdxFunc{generateNodeKey, generateNodeIPKey}
 	case POD:
-		return []IdxFunc{generateCommonKey, generatePodIPKey, generateContainerIDKey, generateHostIPKey}
+		return []IdxFunc{generateCommonKey, generatePodIPKey, generatePodIPPortKey, generateContainerIDKey, generateContainerNameKey, generateHostIPKey, generateOwnerKey}
 	case SERVICE:
 		return []IdxFunc{generateCommonKey, generateServiceIPKey}
+	case ENDPOINTSLICE:
+		return []IdxFunc{generateCommonKey, generateEndpointSliceServiceKey}
 	default:
-		return []IdxFunc{generateCommonKey}
+		return []IdxFunc{generateCommonKey, generateOwnerKey}
 	}
 }
 
 func (m *k8sMetaCache) preProcess(obj interface{}) interface{} {
+	if m.gvr != nil {
+		return m.preProcessCustom(obj)
+	}
 	switch m.resourceType {
 	case POD:
 		return m.preProcessPod(obj)
@@ -251,6 +381,14 @@ func (m *k8sMetaCache) preProcessPod(obj interface{}) interface{} {
 	return pod
 }
 
+func (m *k8sMetaCache) preProcessCustom(obj interface{}) interface{} {
+	m.preProcessCommon(obj)
+	if metaObj, err := meta.Accessor(obj); err == nil {
+		metaObj.SetManagedFields(nil)
+	}
+	return obj
+}
+
 func generateCommonKey(obj interface{}) ([]string, error) {
 	meta, err := meta.Accessor(obj)
 	if err != nil {
@@ -267,6 +405,23 @@ func generateNodeKey(obj interface{}) ([]string, error) {
 	return []string{node.GetName()}, nil
 }
 
+func generateNodeIPKey(obj interface{}) ([]string, error) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return []string{}, fmt.Errorf("object is not a node")
+	}
+	results := make([]string, 0)
+	for _, address := range node.Status.Addresses {
+		if address.Type != v1.NodeInternalIP && address.Type != v1.NodeExternalIP {
+			continue
+		}
+		if address.Address != "" {
+			results = append(results, normalizeIP(address.Address))
+		}
+	}
+	return results, nil
+}
+
 func generateNameWithNamespaceKey(namespace, name string) string {
 	return fmt.Sprintf("%s/%s", namespace, name)
 }
@@ -276,7 +431,70 @@ func generatePodIPKey(obj interface{}) ([]string, error) {
 	if !ok {
 		return []string{}, fmt.Errorf("object is not a pod")
 	}
-	return []string{pod.Status.PodIP}, nil
+	return podIPs(pod), nil
+}
+
+// podIPs returns the normalized ips of the pod, the primary PodIP first and then the others of a dual-stack pod.
+func podIPs(pod *v1.Pod) []string {
+	ips := make([]string, 0, 1+len(pod.Status.PodIPs))
+	add := func(ip string) {
+		if ip == "" {
+			return
+		}
+		ip = normalizeIP(ip)
+		for _, existing := range ips {
+			if existing == ip {
+				return
+			}
+		}
+		ips = append(ips, ip)
+	}
+	add(pod.Status.PodIP)
+	for _, podIP := range pod.Status.PodIPs {
+		add(podIP.IP)
+	}
+	return ips
+}
+
+// normalizeIP returns the canonical form of the ip, so that the different forms of an IPv6 address, e.g. fd00:0::1 and
+// FD00::1, are the same key. The value which is not an ip is returned as is.
+func normalizeIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// generatePodIPPortKey indexes the pod by ip:port of its declared ports, the pod ip with the container ports and the host
+// ip with the host ports, so that the hostNetwork pods sharing the node ip could be told apart.
+func generatePodIPPortKey(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return []string{}, fmt.Errorf("object is not a pod")
+	}
+	keys := make(map[string]struct{})
+	ips := podIPs(pod)
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.ContainerPort != 0 {
+				for _, ip := range ips {
+					keys[addIPPortIndexPrefix(ip, port.ContainerPort)] = struct{}{}
+				}
+			}
+			if pod.Status.HostIP != "" && port.HostPort != 0 {
+				keys[addIPPortIndexPrefix(normalizeIP(pod.Status.HostIP), port.HostPort)] = struct{}{}
+			}
+		}
+	}
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func addIPPortIndexPrefix(ip string, port int32) string {
+	return ipPortIndexPrefix + net.JoinHostPort(ip, strconv.Itoa(int(port)))
 }
 
 func generateContainerIDKey(obj interface{}) ([]string, error) {
@@ -291,18 +509,54 @@ func generateContainerIDKey(obj interface{}) ([]string, error) {
 	return result, nil
 }
 
+// generateContainerNameKey indexes the pod by namespace/containerName of each container, as the container id is not
+// known from the CRI log path.
+func generateContainerNameKey(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return []string{}, fmt.Errorf("object is not a pod")
+	}
+	result := make([]string, len(pod.Spec.Containers))
+	for i, container := range pod.Spec.Containers {
+		result[i] = addContainerNameIndexPrefix(pod.Namespace, container.Name)
+	}
+	return result, nil
+}
+
+func addContainerNameIndexPrefix(namespace, containerName string) string {
+	return containerNameIndexPrefix + generateNameWithNamespaceKey(namespace, containerName)
+}
+
 func generateHostIPKey(obj interface{}) ([]string, error) {
 	pod, ok := obj.(*v1.Pod)
 	if !ok {
 		return []string{}, fmt.Errorf("object is not a pod")
 	}
-	return []string{addHostIPIndexPrefex(pod.Status.HostIP)}, nil
+	return []string{addHostIPIndexPrefex(normalizeIP(pod.Status.HostIP))}, nil
 }
 
 func addHostIPIndexPrefex(ip string) string {
 	return hostIPIndexPrefix + ip
 }
 
+// generateOwnerKey indexes the object by its controller owner, so that the objects owned by a workload could be
+// listed without scanning the cache.
+func generateOwnerKey(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, err
+	}
+	reference := controllerOwner(accessor.GetOwnerReferences())
+	if reference == nil {
+		return []string{}, nil
+	}
+	return []string{addOwnerIndexPrefix(strings.ToLower(reference.Kind), accessor.GetNamespace(), reference.Name)}, nil
+}
+
+func addOwnerIndexPrefix(kind, namespace, name string) string {
+	return ownerIndexPrefix + kind + "/" + generateNameWithNamespaceKey(namespace, name)
+}
+
 func generateServiceIPKey(obj interface{}) ([]string, error) {
 	svc, ok := obj.(*v1.Service)
 	if !ok {
@@ -311,16 +565,33 @@ func generateServiceIPKey(obj interface{}) ([]string, error) {
 	results := make([]string, 0)
 	for _, ip := range svc.Spec.ClusterIPs {
 		if ip != "" {
-			results = append(results, ip)
+			results = append(results, normalizeIP(ip))
 		}
 	}
 	for _, ip := range svc.Spec.ExternalIPs {
 		if ip != "" {
-			results = append(results, ip)
+			results = append(results, normalizeIP(ip))
 		}
 	}
 	if svc.Spec.LoadBalancerIP != "" {
-		results = append(results, svc.Spec.LoadBalancerIP)
+		results = append(results, normalizeIP(svc.Spec.LoadBalancerIP))
 	}
 	return results, nil
 }
+
+// generateEndpointSliceServiceKey indexes the endpoint slice by the service owning it.
+func generateEndpointSliceServiceKey(obj interface{}) ([]string, error) {
+	slice, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return []string{}, fmt.Errorf("object is not an endpoint slice")
+	}
+	serviceName := slice.Labels[discovery.LabelServiceName]
+	if serviceName == "" {
+		return []string{}, nil
+	}
+	return []string{addServiceIndexPrefix(slice.Namespace, serviceName)}, nil
+}
+
+func addServiceIndexPrefix(namespace, name string) string {
+	return serviceIndexPrefix + generateNameWithNamespaceKey(namespace, name)
+}
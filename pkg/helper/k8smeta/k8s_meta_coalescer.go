@@ -0,0 +1,95 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// maxRequestBodySize bounds the body buffered to identify a request, it is far above the body of a batch lookup.
+const maxRequestBodySize = 1 << 20
+
+// requestCoalescer shares the response of the in-flight request with the identical requests arriving meanwhile, so
+// that a burst of the same lookups, e.g. the pipelines enriching the events of the same pod, walks the caches and
+// serializes the response only once.
+type requestCoalescer struct {
+	group singleflight.Group
+}
+
+// coalescedResponse is the response recorded from the leading request and replayed to all the coalesced ones.
+type coalescedResponse struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newCoalescedResponse() *coalescedResponse {
+	return &coalescedResponse{status: http.StatusOK, header: make(http.Header)}
+}
+
+func (c *coalescedResponse) Header() http.Header {
+	return c.header
+}
+
+func (c *coalescedResponse) Write(b []byte) (int, error) {
+	return c.body.Write(b)
+}
+
+func (c *coalescedResponse) WriteHeader(statusCode int) {
+	c.status = statusCode
+}
+
+func (c *coalescedResponse) replay(w http.ResponseWriter) {
+	for key, values := range c.header {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(c.status)
+	_, _ = w.Write(c.body.Bytes())
+}
+
+// serve calls @handleFunc for the request, or waits for the identical request in flight and replays its response.
+// The requests are identical if they have the same path, body and federation header. True is returned if the
+// response of another request is replayed. It must be called after the request is admitted by the limiter, since
+// the body is buffered.
+func (c *requestCoalescer) serve(handleFunc http.HandlerFunc, w http.ResponseWriter, r *http.Request) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body is too large, the limit is "+strconv.Itoa(maxRequestBodySize)+" bytes", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Error reading body: "+err.Error(), http.StatusBadRequest)
+		}
+		return false
+	}
+	_ = r.Body.Close()
+	key := r.URL.Path + "\x00" + r.Header.Get(federatedHeader) + "\x00" + string(body)
+	leading := false
+	result, _, _ := c.group.Do(key, func() (interface{}, error) {
+		leading = true
+		response := newCoalescedResponse()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handleFunc(response, r)
+		return response, nil
+	})
+	result.(*coalescedResponse).replay(w)
+	return !leading
+}
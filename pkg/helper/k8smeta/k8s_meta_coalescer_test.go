@@ -0,0 +1,125 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestCoalescer(t *testing.T) {
+	var coalescer requestCoalescer
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handleFunc := func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(body)
+	}
+	serve := func(body string) (*httptest.ResponseRecorder, bool) {
+		rec := httptest.NewRecorder()
+		shared := coalescer.serve(handleFunc, rec, httptest.NewRequest(http.MethodPost, "/metadata/containerid", strings.NewReader(body)))
+		return rec, shared
+	}
+
+	var wg sync.WaitGroup
+	var coalesced atomic.Int32
+	recs := make([]*httptest.ResponseRecorder, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var shared bool
+		if recs[0], shared = serve(`{"keys":["abc"]}`); shared {
+			coalesced.Add(1)
+		}
+	}()
+	<-started
+	for i := 1; i < len(recs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var shared bool
+			if recs[i], shared = serve(`{"keys":["abc"]}`); shared {
+				coalesced.Add(1)
+			}
+		}(i)
+	}
+	// wait for the identical requests to join the one in flight
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(4), coalesced.Load())
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"keys":["abc"]}`, rec.Body.String())
+	}
+
+	// the requests not in flight together are served separately
+	rec, shared := serve(`{"keys":["abc"]}`)
+	assert.False(t, shared)
+	assert.Equal(t, int32(2), calls.Load())
+	rec2, _ := serve(`{"keys":["def"]}`)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, `{"keys":["abc"]}`, rec.Body.String())
+	assert.Equal(t, `{"keys":["def"]}`, rec2.Body.String())
+}
+
+// failingReader fails the test if the body is read.
+type failingReader struct {
+	t *testing.T
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	r.t.Error("the body should not be read")
+	return 0, io.EOF
+}
+
+func TestRequestCoalescerBodyLimit(t *testing.T) {
+	var coalescer requestCoalescer
+	handleFunc := func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the handler should not be called")
+	}
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(strings.Repeat("a", maxRequestBodySize+1))
+	assert.False(t, coalescer.serve(handleFunc, rec, httptest.NewRequest(http.MethodPost, "/metadata/containerid", body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// the body of a request rejected by the limiter is not read
+	manager := &MetaManager{cacheMap: map[string]MetaCache{}}
+	manager.snapshotLoaded.Store(true)
+	handler := newMetadataHandler(manager)
+	handler.limiter = newRequestLimiter(0, 1, 0, 0)
+	handler.limiter.inflight <- struct{}{}
+	rec = httptest.NewRecorder()
+	handler.handler(handleFunc)(rec, httptest.NewRequest(http.MethodPost, "/metadata/containerid", failingReader{t: t}))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
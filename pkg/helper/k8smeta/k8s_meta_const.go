@@ -23,6 +23,7 @@ const (
 	PERSISTENTVOLUMECLAIM = "persistentvolumeclaim"
 	STORAGECLASS          = "storageclass"
 	INGRESS               = "ingress"
+	ENDPOINTSLICE         = "endpointslice"
 	CONTAINER             = "container"
 	// entity link type
 	//revive:disable:var-naming
@@ -58,6 +59,7 @@ var AllResources = []string{
 	PERSISTENTVOLUMECLAIM,
 	STORAGECLASS,
 	INGRESS,
+	ENDPOINTSLICE,
 }
 
 type NodePod struct {
@@ -133,8 +135,128 @@ type PodMetadata struct {
 	Envs         map[string]string `json:"envs"`
 	Images       map[string]string `json:"images"`
 
+	// NamespaceLabels and NamespaceAnnotations are of the namespace the pod belongs to,
+	// the compliance tags like team and cost-center are usually set on the namespace.
+	NamespaceLabels      map[string]string `json:"namespaceLabels,omitempty"`
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+
+	// Annotations carry the routing hints of the pod, e.g. the project and logstore overrides.
+	Annotations map[string]string       `json:"annotations,omitempty"`
+	Containers  []*PodContainerMetadata `json:"containers,omitempty"`
+
 	ServiceName  string   `json:"serviceName,omitempty"`
 	ContainerIDs []string `json:"containerIDs,omitempty"`
 	PodIP        string   `json:"podIP,omitempty"`
-	IsDeleted    bool     `json:"-"`
+	// PodIPs are all the ips of a dual-stack pod, the first one is PodIP.
+	PodIPs []string `json:"podIPs,omitempty"`
+
+	// IsDeleted is set for the pods deleted but still kept for the late logs, DeletedAt is the unix second of the deletion.
+	IsDeleted bool  `json:"isDeleted,omitempty"`
+	DeletedAt int64 `json:"deletedAt,omitempty"`
+
+	// ClusterID is the cluster the pod belongs to, set if the cluster id is configured or the pod is found by the federation.
+	ClusterID string `json:"clusterID,omitempty"`
+}
+
+// PodContainerMetadata describes a container in the pod spec, the status fields are empty before the container is created.
+type PodContainerMetadata struct {
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	ContainerID  string            `json:"containerID,omitempty"`
+	Runtime      string            `json:"runtime,omitempty"`
+	RestartCount int32             `json:"restartCount"`
+	Requests     map[string]string `json:"requests,omitempty"`
+	Limits       map[string]string `json:"limits,omitempty"`
+}
+
+type ServicePortMetadata struct {
+	Name       string `json:"name,omitempty"`
+	Protocol   string `json:"protocol"`
+	Port       int32  `json:"port"`
+	TargetPort string `json:"targetPort,omitempty"`
+	NodePort   int32  `json:"nodePort,omitempty"`
+}
+
+type ServiceMetadata struct {
+	ServiceName string                 `json:"serviceName"`
+	Namespace   string                 `json:"namespace"`
+	Type        string                 `json:"type"`
+	Labels      map[string]string      `json:"labels"`
+	Selector    map[string]string      `json:"selector"`
+	ClusterIP   string                 `json:"clusterIP"`
+	ClusterIPs  []string               `json:"clusterIPs,omitempty"`
+	Ports       []*ServicePortMetadata `json:"ports"`
+}
+
+type EndpointPortMetadata struct {
+	Name     string `json:"name,omitempty"`
+	Protocol string `json:"protocol"`
+	Port     int32  `json:"port"`
+}
+
+// EndpointMetadata is a backend of a service, the pod is the metadata of the target pod if it is cached.
+type EndpointMetadata struct {
+	Addresses   []string                `json:"addresses"`
+	Ports       []*EndpointPortMetadata `json:"ports"`
+	Ready       bool                    `json:"ready"`
+	Terminating bool                    `json:"terminating,omitempty"`
+	NodeName    string                  `json:"nodeName,omitempty"`
+	Pod         interface{}             `json:"pod,omitempty"`
+}
+
+type ServiceEndpointsMetadata struct {
+	Service   *ServiceMetadata    `json:"service"`
+	Endpoints []*EndpointMetadata `json:"endpoints"`
+}
+
+type NodeTaintMetadata struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+type NodeMetadata struct {
+	NodeName       string               `json:"nodeName"`
+	Labels         map[string]string    `json:"labels"`
+	Taints         []*NodeTaintMetadata `json:"taints"`
+	KubeletVersion string               `json:"kubeletVersion"`
+	Allocatable    map[string]string    `json:"allocatable"`
+	InternalIP     string               `json:"internalIP,omitempty"`
+	ExternalIP     string               `json:"externalIP,omitempty"`
+}
+
+type WorkloadOwnerMetadata struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// WorkloadMetadata describes a deployment, statefulset, daemonset or cronjob. The replicas of a
+// daemonset are the numbers of the scheduled pods, and a cronjob has the schedule and the active jobs instead.
+type WorkloadMetadata struct {
+	WorkloadName      string                   `json:"workloadName"`
+	WorkloadKind      string                   `json:"workloadKind"`
+	Namespace         string                   `json:"namespace"`
+	StartTime         int64                    `json:"startTime"`
+	Labels            map[string]string        `json:"labels"`
+	Annotations       map[string]string        `json:"annotations"`
+	Owners            []*WorkloadOwnerMetadata `json:"owners"`
+	Replicas          int32                    `json:"replicas"`
+	ReadyReplicas     int32                    `json:"readyReplicas"`
+	AvailableReplicas int32                    `json:"availableReplicas"`
+	Schedule          string                   `json:"schedule,omitempty"`
+	Suspend           bool                     `json:"suspend,omitempty"`
+	ActiveJobs        int32                    `json:"activeJobs,omitempty"`
+}
+
+type CustomResourceMetadata struct {
+	Name        string                   `json:"name"`
+	Namespace   string                   `json:"namespace,omitempty"`
+	Kind        string                   `json:"kind"`
+	APIVersion  string                   `json:"apiVersion"`
+	StartTime   int64                    `json:"startTime"`
+	Labels      map[string]string        `json:"labels"`
+	Annotations map[string]string        `json:"annotations"`
+	Owners      []*WorkloadOwnerMetadata `json:"owners"`
+	Spec        map[string]interface{}   `json:"spec,omitempty"`
+	Status      map[string]interface{}   `json:"status,omitempty"`
 }
@@ -0,0 +1,173 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	meta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// customResourcesEnv lists the custom resources to cache in the group/version/resource form,
+// e.g. "argoproj.io/v1alpha1/rollouts,apps.kruise.io/v1alpha1/clonesets".
+const customResourcesEnv = "KUBERNETES_METADATA_CUSTOM_RESOURCES"
+
+type customRequestBody struct {
+	Kind string   `json:"kind"`
+	Keys []string `json:"keys"`
+}
+
+func parseCustomResources(config string) ([]schema.GroupVersionResource, error) {
+	gvrs := make([]schema.GroupVersionResource, 0)
+	for _, item := range strings.Split(config, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid custom resource %q, group/version/resource is expected", item)
+		}
+		gvrs = append(gvrs, schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]})
+	}
+	return gvrs, nil
+}
+
+// addCustomResources resolves the kinds of the custom resources by the discovery and adds their caches keyed
+// by the lower case kind, the same as the built-in resources, so that they could be walked through in the owner chain.
+func (m *MetaManager) addCustomResources(discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface, gvrs []schema.GroupVersionResource) {
+	for _, gvr := range gvrs {
+		resources, err := discoveryClient.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil {
+			logger.Error(context.Background(), "K8S_META_CUSTOM_RESOURCE_ALARM", "discover custom resource error", err, "resource", gvr.String())
+			continue
+		}
+		kind := ""
+		for _, resource := range resources.APIResources {
+			if resource.Name == gvr.Resource {
+				kind = strings.ToLower(resource.Kind)
+				break
+			}
+		}
+		if kind == "" {
+			logger.Error(context.Background(), "K8S_META_CUSTOM_RESOURCE_ALARM", "custom resource not found", gvr.String())
+			continue
+		}
+		if _, ok := m.cacheMap[kind]; ok {
+			logger.Error(context.Background(), "K8S_META_CUSTOM_RESOURCE_ALARM", "custom resource kind conflicts with the cached kind", kind, "resource", gvr.String())
+			continue
+		}
+		m.cacheMap[kind] = newCustomMetaCache(m.stopCh, kind, gvr, dynamicClient)
+		m.customResources[kind] = gvr
+		logger.Info(context.Background(), "cache custom resource", gvr.String(), "kind", kind)
+	}
+}
+
+func (m *MetaManager) initCustomResources(discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) {
+	gvrs, err := parseCustomResources(os.Getenv(customResourcesEnv))
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_CUSTOM_RESOURCE_ALARM", "parse custom resources error", err)
+		return
+	}
+	if len(gvrs) == 0 {
+		return
+	}
+	m.addCustomResources(discoveryClient, dynamicClient, gvrs)
+	// the custom kinds could be configured in the owner chain
+	m.metadataHandler.ownerResolver = newOwnerResolverFromEnv(m)
+}
+
+// handleCustomMeta resolves the custom resources of the kind by namespace/name, or name for the cluster scoped ones.
+func (m *metadataHandler) handleCustomMeta(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody customRequestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	kind := strings.ToLower(rBody.Kind)
+	if _, ok := m.metaManager.customResources[kind]; !ok {
+		http.Error(w, "Unknown custom resource kind: "+rBody.Kind, http.StatusBadRequest)
+		return
+	}
+
+	// Get the metadata
+	metadata := make(map[string]*CustomResourceMetadata)
+	for _, key := range rBody.Keys {
+		cacheKey := key
+		if !strings.Contains(key, "/") {
+			cacheKey = generateNameWithNamespaceKey("", key)
+		}
+		objs := m.metaManager.cacheMap[kind].Get([]string{cacheKey})
+		for _, obj := range objs[cacheKey] {
+			if customMetadata := convertObj2CustomResponse(obj); customMetadata != nil {
+				metadata[key] = customMetadata
+				break
+			}
+		}
+	}
+	wrapperResponse(w, metadata)
+}
+
+func convertObj2CustomResponse(obj *ObjectWrapper) *CustomResourceMetadata {
+	object, ok := obj.Raw.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return nil
+	}
+	owners := make([]*WorkloadOwnerMetadata, 0, len(accessor.GetOwnerReferences()))
+	for _, reference := range accessor.GetOwnerReferences() {
+		owners = append(owners, &WorkloadOwnerMetadata{
+			Kind: strings.ToLower(reference.Kind),
+			Name: reference.Name,
+		})
+	}
+	customMetadata := &CustomResourceMetadata{
+		Name:        accessor.GetName(),
+		Namespace:   accessor.GetNamespace(),
+		Kind:        strings.ToLower(object.GetKind()),
+		APIVersion:  object.GetAPIVersion(),
+		StartTime:   accessor.GetCreationTimestamp().Unix(),
+		Labels:      accessor.GetLabels(),
+		Annotations: accessor.GetAnnotations(),
+		Owners:      owners,
+	}
+	if spec, ok := object.Object["spec"].(map[string]interface{}); ok {
+		customMetadata.Spec = spec
+	}
+	if status, ok := object.Object["status"].(map[string]interface{}); ok {
+		customMetadata.Status = status
+	}
+	return customMetadata
+}
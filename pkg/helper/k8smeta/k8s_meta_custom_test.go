@@ -0,0 +1,125 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestParseCustomResources(t *testing.T) {
+	gvrs, err := parseCustomResources("argoproj.io/v1alpha1/rollouts, apps.kruise.io/v1alpha1/clonesets,")
+	require.NoError(t, err)
+	assert.Equal(t, []schema.GroupVersionResource{
+		{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"},
+		{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"},
+	}, gvrs)
+	gvrs, err = parseCustomResources("")
+	require.NoError(t, err)
+	assert.Empty(t, gvrs)
+	_, err = parseCustomResources("rollouts")
+	assert.Error(t, err)
+	_, err = parseCustomResources("argoproj.io//rollouts")
+	assert.Error(t, err)
+}
+
+func TestCustomResources(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "argoproj.io/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "rollouts", Kind: "Rollout", Namespaced: true}},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true}},
+		},
+	}
+	rolloutGVR := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	manager.addCustomResources(discoveryClient, fakedynamic.NewSimpleDynamicClient(runtime.NewScheme()), []schema.GroupVersionResource{
+		rolloutGVR,
+		{Group: "example.com", Version: "v1", Resource: "deployments"},
+		{Group: "example.com", Version: "v1", Resource: "missing"},
+		{Group: "missing.io", Version: "v1", Resource: "missing"},
+	})
+	defer func() {
+		delete(manager.cacheMap, "rollout")
+		delete(manager.customResources, "rollout")
+	}()
+	assert.Equal(t, map[string]schema.GroupVersionResource{"rollout": rolloutGVR}, manager.customResources)
+	rolloutCache, ok := manager.cacheMap["rollout"].(*k8sMetaCache)
+	require.True(t, ok)
+	assert.Equal(t, rolloutGVR, *rolloutCache.gvr)
+
+	rollout := &unstructured.Unstructured{}
+	rollout.SetAPIVersion("argoproj.io/v1alpha1")
+	rollout.SetKind("Rollout")
+	rollout.SetName("web")
+	rollout.SetNamespace("prod")
+	rollout.SetLabels(map[string]string{"app": "web"})
+	rollout.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Application", Name: "web-app"}})
+	rollout.Object["spec"] = map[string]interface{}{"replicas": int64(3)}
+	rollout.Object["status"] = map[string]interface{}{"phase": "Healthy"}
+	rolloutCache.metaStore.Items["prod/web"] = &ObjectWrapper{Raw: rolloutCache.preProcess(rollout)}
+	rolloutCache.metaStore.Index["prod/web"] = NewIndexItem()
+	rolloutCache.metaStore.Index["prod/web"].Add("prod/web")
+	addOwnerTestObject(manager, REPLICASET, "prod/web-abc", &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Rollout", Name: "web"},
+		}},
+	})
+
+	// the custom kind is walked through in the owner chain
+	resolver := newOwnerResolver(manager, "replicaset,rollout", "")
+	assert.Equal(t, ownerReference{kind: "application", name: "web-app"}, resolver.resolve("prod", ownerReference{kind: REPLICASET, name: "web-abc"}))
+
+	handler := newMetadataHandler(manager)
+	result := make(map[string]*CustomResourceMetadata)
+	body, err := json.Marshal(customRequestBody{Kind: "Rollout", Keys: []string{"prod/web", "prod/api"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handleCustomMeta(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result, 1)
+	web := result["prod/web"]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, "prod", web.Namespace)
+	assert.Equal(t, "rollout", web.Kind)
+	assert.Equal(t, "argoproj.io/v1alpha1", web.APIVersion)
+	assert.Equal(t, map[string]string{"app": "web"}, web.Labels)
+	assert.Equal(t, []*WorkloadOwnerMetadata{{Kind: "application", Name: "web-app"}}, web.Owners)
+	assert.Equal(t, map[string]interface{}{"replicas": float64(3)}, web.Spec)
+	assert.Equal(t, map[string]interface{}{"phase": "Healthy"}, web.Status)
+
+	rec = httptest.NewRecorder()
+	handler.handleCustomMeta(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(`{"kind":"deployment","keys":["prod/web"]}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -39,6 +39,12 @@ type DeferredDeletionMetaStore struct {
 	Items map[string]*ObjectWrapper
 	Index map[string]IndexItem
 	lock  sync.RWMutex
+	// restored are the keys of the items loaded from the snapshot and not seen by the informer yet
+	restored map[string]struct{}
+	// deleted orders the deleted items by the last access, the least recently used ones are evicted before the
+	// grace period if there are more than its max entries of them. deletedLock is acquired after lock.
+	deleted     *deletedLRU
+	deletedLock sync.Mutex
 
 	// timer
 	gracePeriod  int64
@@ -69,6 +75,7 @@ func NewDeferredDeletionMetaStore(eventCh chan *K8sMetaEvent, stopCh <-chan stru
 
 		gracePeriod: gracePeriod,
 		sendFuncs:   make(map[string]*SendFuncWithStopCh),
+		deleted:     newDeletedLRU(0),
 	}
 	return m
 }
@@ -90,6 +97,11 @@ func (m *DeferredDeletionMetaStore) Get(key []string) map[string][]*ObjectWrappe
 			if obj, ok := m.Items[realKey]; ok {
 				if obj.Raw != nil {
 					result[k] = append(result[k], obj)
+					if obj.Deleted {
+						m.deletedLock.Lock()
+						m.deleted.touch(realKey)
+						m.deletedLock.Unlock()
+					}
 				} else {
 					logger.Error(context.Background(), "K8S_META_HANDLE_ALARM", "raw object not found", realKey)
 				}
@@ -170,6 +182,34 @@ func (m *DeferredDeletionMetaStore) RegisterSendFunc(key string, f SendFunc, int
 	}()
 }
 
+// RegisterWatchFunc registers a send func which only receives the realtime events (add, update, delete),
+// it is called in the event handling goroutine and must not block. Use UnRegisterSendFunc to unregister it.
+func (m *DeferredDeletionMetaStore) RegisterWatchFunc(key string, f SendFunc) {
+	m.registerLock.Lock()
+	m.sendFuncs[key] = &SendFuncWithStopCh{
+		SendFunc: f,
+		StopCh:   make(chan struct{}),
+	}
+	m.registerLock.Unlock()
+}
+
+// RegisterListWatchFunc registers a watch func like RegisterWatchFunc, and returns the objects not deleted at the time
+// of the registration, so that no event is lost between the list and the watch. The returned channel is closed when
+// the func is unregistered or the store is stopped.
+func (m *DeferredDeletionMetaStore) RegisterListWatchFunc(key string, f SendFunc) ([]*ObjectWrapper, <-chan struct{}) {
+	sendFuncWithStopCh := &SendFuncWithStopCh{
+		SendFunc: f,
+		StopCh:   make(chan struct{}),
+	}
+	m.registerLock.Lock()
+	defer m.registerLock.Unlock()
+	m.sendFuncs[key] = sendFuncWithStopCh
+	objs := m.Filter(func(ow *ObjectWrapper) bool {
+		return !ow.Deleted
+	}, 0)
+	return objs, sendFuncWithStopCh.StopCh
+}
+
 func (m *DeferredDeletionMetaStore) UnRegisterSendFunc(key string) {
 	m.registerLock.Lock()
 	if stopCh, ok := m.sendFuncs[key]; ok {
@@ -229,6 +269,10 @@ func (m *DeferredDeletionMetaStore) handleAddOrUpdateEvent(event *K8sMetaEvent)
 	}
 
 	m.Items[key] = event.Object
+	delete(m.restored, key)
+	m.deletedLock.Lock()
+	m.deleted.remove(key)
+	m.deletedLock.Unlock()
 	for _, idxKey := range idxKeys {
 		if _, ok := m.Index[idxKey]; !ok {
 			m.Index[idxKey] = NewIndexItem()
@@ -249,11 +293,21 @@ func (m *DeferredDeletionMetaStore) handleDeleteEvent(event *K8sMetaEvent) {
 		logger.Error(context.Background(), "K8S_META_HANDLE_ALARM", "handle k8s meta with keyFunc error", err)
 		return
 	}
+	deletedTime := time.Now().Unix()
 	m.lock.Lock()
 	if obj, ok := m.Items[key]; ok {
 		obj.Deleted = true
+		obj.DeletedTime = deletedTime
 		event.Object.FirstObservedTime = obj.FirstObservedTime
+		m.deletedLock.Lock()
+		evicted := m.deleted.add(key)
+		m.deletedLock.Unlock()
+		for _, evictedKey := range evicted {
+			m.removeItem(evictedKey)
+		}
 	}
+	event.Object.Deleted = true
+	event.Object.DeletedTime = deletedTime
 	m.lock.Unlock()
 	m.registerLock.RLock()
 	for _, f := range m.sendFuncs {
@@ -276,24 +330,34 @@ func (m *DeferredDeletionMetaStore) handleDeferredDeleteEvent(event *K8sMetaEven
 		logger.Error(context.Background(), "handleDeferredDeleteEvent keyFunc error", err)
 		return
 	}
-	idxKeys := m.getIdxKeys(event.Object)
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	if obj, ok := m.Items[key]; ok {
-		if obj.Deleted {
-			delete(m.Items, key)
-			for _, idxKey := range idxKeys {
-				if _, ok := m.Index[idxKey]; !ok {
-					continue
-				}
-				m.Index[idxKey].Remove(key)
-				if len(m.Index[idxKey].Keys) == 0 {
-					delete(m.Index, idxKey)
-				}
-			}
+	// if deleted is false, there is a new add event between delete event and deferred delete event,
+	// and if the item is deleted again after that, it is removed by the deferred delete event of the later deletion.
+	if obj, ok := m.Items[key]; ok && obj.Deleted && time.Now().Unix()-obj.DeletedTime >= m.gracePeriod {
+		m.removeItem(key)
+		m.deletedLock.Lock()
+		m.deleted.remove(key)
+		m.deletedLock.Unlock()
+	}
+}
+
+// removeItem removes the item and its index keys, the caller must hold the lock.
+func (m *DeferredDeletionMetaStore) removeItem(key string) {
+	obj, ok := m.Items[key]
+	if !ok {
+		return
+	}
+	for _, idxKey := range m.getIdxKeys(obj) {
+		if _, ok := m.Index[idxKey]; !ok {
+			continue
+		}
+		m.Index[idxKey].Remove(key)
+		if len(m.Index[idxKey].Keys) == 0 {
+			delete(m.Index, idxKey)
 		}
-		// if deleted is false, there is a new add event between delete event and deferred delete event
 	}
+	delete(m.Items, key)
 }
 
 func (m *DeferredDeletionMetaStore) handleTimerEvent(event *K8sMetaEvent) {
@@ -317,6 +381,52 @@ func (m *DeferredDeletionMetaStore) handleTimerEvent(event *K8sMetaEvent) {
 	}
 }
 
+// restore adds the items loaded from the snapshot without notifying the send funcs,
+// they are served until the informer syncs and expireRestored is called.
+func (m *DeferredDeletionMetaStore) restore(objs []*ObjectWrapper) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.restored == nil {
+		m.restored = make(map[string]struct{})
+	}
+	count := 0
+	for _, obj := range objs {
+		key, err := m.keyFunc(obj.Raw)
+		if err != nil {
+			continue
+		}
+		if _, ok := m.Items[key]; ok {
+			continue
+		}
+		idxKeys := m.getIdxKeys(obj)
+		m.Items[key] = obj
+		m.restored[key] = struct{}{}
+		for _, idxKey := range idxKeys {
+			if _, ok := m.Index[idxKey]; !ok {
+				m.Index[idxKey] = NewIndexItem()
+			}
+			m.Index[idxKey].Add(key)
+		}
+		count++
+	}
+	return count
+}
+
+// expireRestored removes the restored items absent from the synced informer, which are deleted while the agent is down.
+func (m *DeferredDeletionMetaStore) expireRestored(liveKeys []string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, key := range liveKeys {
+		delete(m.restored, key)
+	}
+	count := len(m.restored)
+	for key := range m.restored {
+		m.removeItem(key)
+	}
+	m.restored = nil
+	return count
+}
+
 func (m *DeferredDeletionMetaStore) getIdxKeys(obj *ObjectWrapper) []string {
 	result := make([]string, 0)
 	for _, rule := range m.indexRules {
@@ -434,3 +434,65 @@ func TestRegisterAndUnRegisterSendFunc(t *testing.T) {
 	time.Sleep(time.Duration(interval) * time.Second)
 	assert.Equal(t, 3, counter)
 }
+
+func TestDeferredDeletionEvictLeastRecentlyUsed(t *testing.T) {
+	eventCh := make(chan *K8sMetaEvent)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	cache := NewDeferredDeletionMetaStore(eventCh, stopCh, 3600, cache.MetaNamespaceKeyFunc, generatePodIPKey)
+	cache.deleted = newDeletedLRU(2)
+	cache.Start()
+	pods := make([]*corev1.Pod, 0, 3)
+	for _, name := range []string{"a", "b", "c"} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0." + name},
+		}
+		pods = append(pods, pod)
+		eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: pod}}
+	}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pods[0]}}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pods[1]}}
+	time.Sleep(10 * time.Millisecond)
+	// a is used after b, so b is evicted when c is deleted
+	objs := cache.Get([]string{"10.0.0.a"})["10.0.0.a"]
+	assert.Len(t, objs, 1)
+	assert.True(t, objs[0].Deleted)
+	assert.NotZero(t, objs[0].DeletedTime)
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pods[2]}}
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, cache.Get([]string{"10.0.0.a"}), 1)
+	assert.Len(t, cache.Get([]string{"10.0.0.b"}), 0)
+	assert.Len(t, cache.Get([]string{"10.0.0.c"}), 1)
+
+	// the re-added pod is not deleted any more and not evicted
+	eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: pods[0]}}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pods[1]}}
+	time.Sleep(10 * time.Millisecond)
+	cache.lock.RLock()
+	assert.False(t, cache.Items["default/a"].Deleted)
+	assert.True(t, cache.Items["default/c"].Deleted)
+	cache.lock.RUnlock()
+}
+
+func TestDeferredDeletionRedeleted(t *testing.T) {
+	eventCh := make(chan *K8sMetaEvent)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	cache := NewDeferredDeletionMetaStore(eventCh, stopCh, 2, cache.MetaNamespaceKeyFunc, generatePodIPKey)
+	cache.Start()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Status:     corev1.PodStatus{PodIP: "127.0.0.1"},
+	}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: pod}}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pod}}
+	time.Sleep(1200 * time.Millisecond)
+	eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: pod}}
+	eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: pod}}
+	// the deferred delete event of the first deletion should not remove the pod deleted again
+	time.Sleep(1200 * time.Millisecond)
+	assert.Len(t, cache.Get([]string{"127.0.0.1"}), 1)
+	time.Sleep(2 * time.Second)
+	assert.Len(t, cache.Get([]string{"127.0.0.1"}), 0)
+}
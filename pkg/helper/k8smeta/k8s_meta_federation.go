@@ -0,0 +1,163 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	// clusterIDEnv is the id of the local cluster returned in the pod metadata.
+	clusterIDEnv = "KUBERNETES_METADATA_CLUSTER_ID"
+	// federationServersEnv enables the federation with the comma separated clusterID=url of the remote meta servers.
+	federationServersEnv = "KUBERNETES_METADATA_FEDERATION_SERVERS"
+	federationTokenEnv   = "KUBERNETES_METADATA_FEDERATION_TOKEN"
+	federationTimeoutEnv = "KUBERNETES_METADATA_FEDERATION_TIMEOUT_MS"
+
+	defaultFederationTimeout = time.Second
+	// federatedHeader marks the lookups forwarded by another meta server, which are not forwarded again to avoid loops.
+	federatedHeader = "X-Metadata-Federated"
+)
+
+// metaFederation forwards the pod lookups not resolved by the local cluster to the meta servers of the remote clusters,
+// so that a central enrichment service has a single query surface across the clusters. The remote servers are queried
+// concurrently, and a failed or slow one is skipped with an alarm.
+type metaFederation struct {
+	remotes []*federationRemote
+	token   string
+	client  *http.Client
+}
+
+type federationRemote struct {
+	clusterID string
+	url       string
+}
+
+// newMetaFederationFromEnv returns nil if no remote server is configured, and a nil federation resolves nothing.
+func newMetaFederationFromEnv() *metaFederation {
+	var remotes []*federationRemote
+	for _, server := range strings.Split(os.Getenv(federationServersEnv), ",") {
+		if server = strings.TrimSpace(server); server == "" {
+			continue
+		}
+		clusterID, url, found := strings.Cut(server, "=")
+		if !found || clusterID == "" || url == "" {
+			logger.Warning(context.Background(), "K8S_META_FEDERATION_ALARM", "invalid federation server, clusterID=url is expected", server)
+			continue
+		}
+		remotes = append(remotes, &federationRemote{clusterID: clusterID, url: strings.TrimSuffix(url, "/")})
+	}
+	if len(remotes) == 0 {
+		return nil
+	}
+	timeout := defaultFederationTimeout
+	if timeoutMs := intFromEnv(federationTimeoutEnv); timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	return newMetaFederation(remotes, os.Getenv(federationTokenEnv), timeout)
+}
+
+func newMetaFederation(remotes []*federationRemote, token string, timeout time.Duration) *metaFederation {
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].clusterID < remotes[j].clusterID
+	})
+	return &metaFederation{
+		remotes: remotes,
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// resolve looks up the @keys missing in @metadata on the remote servers at @path, and adds the found pods to it with
+// the cluster ids of the remote servers. The pods of the remote cluster first in the order of the cluster ids win.
+func (f *metaFederation) resolve(r *http.Request, path string, keys []string, fields []string, metadata map[string]*PodMetadata) {
+	if f == nil || r.Header.Get(federatedHeader) != "" {
+		return
+	}
+	missing := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := metadata[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	body, err := json.Marshal(requestBody{Keys: missing, Fields: fields})
+	if err != nil {
+		return
+	}
+	results := make([]map[string]*PodMetadata, len(f.remotes))
+	var wg sync.WaitGroup
+	for i, remote := range f.remotes {
+		wg.Add(1)
+		go func(i int, remote *federationRemote) {
+			defer wg.Done()
+			defer panicRecover()
+			result, err := f.query(r.Context(), remote, path, body)
+			if err != nil {
+				logger.Warning(context.Background(), "K8S_META_FEDERATION_ALARM", "query remote meta server error", err, "cluster", remote.clusterID)
+				return
+			}
+			results[i] = result
+		}(i, remote)
+	}
+	wg.Wait()
+	for _, key := range missing {
+		for i, result := range results {
+			if podMetadata := result[key]; podMetadata != nil {
+				podMetadata.ClusterID = f.remotes[i].clusterID
+				metadata[key] = podMetadata
+				break
+			}
+		}
+	}
+}
+
+func (f *metaFederation) query(ctx context.Context, remote *federationRemote, path string, body []byte) (map[string]*PodMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, remote.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(federatedHeader, "1")
+	if f.token != "" {
+		req.Header.Set("Authorization", bearerPrefix+f.token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	result := make(map[string]*PodMetadata)
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
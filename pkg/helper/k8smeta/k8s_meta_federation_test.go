@@ -0,0 +1,105 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewMetaFederationFromEnv(t *testing.T) {
+	t.Setenv(federationServersEnv, "")
+	assert.Nil(t, newMetaFederationFromEnv())
+
+	t.Setenv(federationServersEnv, "west=http://10.0.0.2:9000/, invalid ,east=http://10.0.0.1:9000")
+	t.Setenv(federationTimeoutEnv, "500")
+	federation := newMetaFederationFromEnv()
+	require.NotNil(t, federation)
+	assert.Equal(t, []*federationRemote{
+		{clusterID: "east", url: "http://10.0.0.1:9000"},
+		{clusterID: "west", url: "http://10.0.0.2:9000"},
+	}, federation.remotes)
+	assert.Equal(t, 500*time.Millisecond, federation.client.Timeout)
+}
+
+func TestHandlePodMetaFederated(t *testing.T) {
+	var forwarded []requestBody
+	east := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metadata/containerid", r.URL.Path)
+		assert.Equal(t, "1", r.Header.Get(federatedHeader))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var rBody requestBody
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&rBody))
+		forwarded = append(forwarded, rBody)
+		writeJSONResponse(w, map[string]interface{}{
+			"def": map[string]interface{}{"podName": "east-pod", "namespace": "prod"},
+			"ghi": map[string]interface{}{"podName": "east-pod-2", "namespace": "prod"},
+		})
+	}))
+	defer east.Close()
+	west := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer west.Close()
+
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	addIndexedTestObject(podCache, "prod/web", &ObjectWrapper{Raw: &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+		Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{ContainerID: "containerd://abc"}}},
+	}})
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+	handler.clusterID = "local"
+	handler.federation = newMetaFederation([]*federationRemote{
+		{clusterID: "west", url: west.URL},
+		{clusterID: "east", url: east.URL},
+	}, "secret", time.Second)
+
+	body, err := json.Marshal(requestBody{Keys: []string{"abc", "def"}, Fields: []string{"podName", "clusterID"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handlePodMetaByContainerID(rec, httptest.NewRequest(http.MethodPost, "/metadata/containerid", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result map[string]*PodMetadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	// only the keys missing locally are forwarded, and the remote pods not asked for are ignored
+	require.Len(t, forwarded, 1)
+	assert.Equal(t, requestBody{Keys: []string{"def"}, Fields: []string{"podName", "clusterID"}}, forwarded[0])
+	assert.Equal(t, map[string]*PodMetadata{
+		"abc": {PodName: "web", ClusterID: "local"},
+		"def": {PodName: "east-pod", ClusterID: "east"},
+	}, result)
+
+	// the forwarded lookups are not forwarded again
+	req := httptest.NewRequest(http.MethodPost, "/metadata/containerid", bytes.NewReader(body))
+	req.Header.Set(federatedHeader, "1")
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaByContainerID(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	result = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Len(t, forwarded, 1)
+	assert.Len(t, result, 1)
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta/metadatapb"
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const defaultGRPCBatchSize = 100
+
+// metadataGRPCServer serves the pod metadata lookups of the http server over grpc, the responses are streamed in
+// batches and the lookups stop once the deadline of the call is exceeded.
+type metadataGRPCServer struct {
+	metadatapb.UnimplementedMetadataServiceServer
+	handler *metadataHandler
+}
+
+// K8sGRPCServerRun starts the grpc server if KUBERNETES_METADATA_GRPC_PORT is set.
+func (m *metadataHandler) K8sGRPCServerRun(stopCh <-chan struct{}) error {
+	defer panicRecover()
+	portEnv := os.Getenv("KUBERNETES_METADATA_GRPC_PORT")
+	if len(portEnv) == 0 {
+		return nil
+	}
+	port, err := strconv.Atoi(portEnv)
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_SERVER_ALARM", "invalid grpc port", portEnv)
+		return err
+	}
+	security, err := loadServerSecurity()
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_SERVER_ALARM", "load k8s meta server security options error", err)
+		return err
+	}
+	listener, err := net.Listen("tcp", listenAddress(port))
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_SERVER_ALARM", "listen grpc port error", err)
+		return err
+	}
+	server := grpc.NewServer(security.grpcServerOptions()...)
+	metadatapb.RegisterMetadataServiceServer(server, &metadataGRPCServer{handler: m})
+	logger.Info(context.Background(), "k8s meta grpc server", "started", "port", port, "tls", security.tlsConfig != nil)
+	go func() {
+		defer panicRecover()
+		_ = server.Serve(listener)
+	}()
+	<-stopCh
+	server.Stop()
+	return nil
+}
+
+func (s *metadataGRPCServer) GetPodMetadataByIPPort(req *metadatapb.MetadataRequest, stream metadatapb.MetadataService_GetPodMetadataByIPPortServer) error {
+	return s.serve(stream.Context(), req, stream.Send, s.handler.getPodMetaByIPPort)
+}
+
+func (s *metadataGRPCServer) GetPodMetadataByContainerID(req *metadatapb.MetadataRequest, stream metadatapb.MetadataService_GetPodMetadataByContainerIDServer) error {
+	return s.serve(stream.Context(), req, stream.Send, s.handler.getPodMetaByContainerID)
+}
+
+func (s *metadataGRPCServer) GetPodMetadataByHostIP(req *metadatapb.MetadataRequest, stream metadatapb.MetadataService_GetPodMetadataByHostIPServer) error {
+	return s.serve(stream.Context(), req, stream.Send, s.handler.getPodMetaByHostIP)
+}
+
+func (s *metadataGRPCServer) serve(ctx context.Context, req *metadatapb.MetadataRequest,
+	send func(*metadatapb.PodMetadataBatch) error, lookup func([]string) map[string]*PodMetadata) error {
+	defer panicRecover()
+	if !s.handler.metaManager.isServing() {
+		return status.Error(codes.Unavailable, "k8s meta manager is not ready")
+	}
+	batchSize := int(req.GetBatchSize())
+	if batchSize <= 0 {
+		batchSize = defaultGRPCBatchSize
+	}
+	keys := req.GetKeys()
+	for start := 0; start < len(keys); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		metadata := lookup(keys[start:end])
+		batch := &metadatapb.PodMetadataBatch{
+			Metadata: make(map[string]*metadatapb.PodMetadata, len(metadata)),
+		}
+		for key, podMetadata := range metadata {
+			batch.Metadata[key] = convertPodMetadata2PB(podMetadata)
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertPodMetadata2PB(podMetadata *PodMetadata) *metadatapb.PodMetadata {
+	return &metadatapb.PodMetadata{
+		PodName:      podMetadata.PodName,
+		StartTime:    podMetadata.StartTime,
+		Namespace:    podMetadata.Namespace,
+		WorkloadName: podMetadata.WorkloadName,
+		WorkloadKind: podMetadata.WorkloadKind,
+		Labels:       podMetadata.Labels,
+		Envs:         podMetadata.Envs,
+		Images:       podMetadata.Images,
+		ServiceName:  podMetadata.ServiceName,
+		ContainerIds: podMetadata.ContainerIDs,
+		PodIp:        podMetadata.PodIP,
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta/metadatapb"
+)
+
+func newGRPCTestClient(t *testing.T, handler *metadataHandler, opts ...grpc.ServerOption) metadatapb.MetadataServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts...)
+	metadatapb.RegisterMetadataServiceServer(server, &metadataGRPCServer{handler: handler})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return metadatapb.NewMetadataServiceClient(conn)
+}
+
+func TestGRPCGetPodMetadataByContainerID(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	for _, name := range []string{"pod1", "pod2", "pod3"} {
+		key := "default/" + name
+		podCache.metaStore.Items[key] = &ObjectWrapper{
+			Raw: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Labels:    map[string]string{"app": name},
+				},
+				Status: corev1.PodStatus{
+					PodIP: "1.1.1.1",
+					ContainerStatuses: []corev1.ContainerStatus{
+						{ContainerID: "containerd://" + name + "-container"},
+					},
+				},
+			},
+		}
+		podCache.metaStore.Index[name+"-container"] = NewIndexItem()
+		podCache.metaStore.Index[name+"-container"].Add(key)
+	}
+	manager.cacheMap[POD] = podCache
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+	client := newGRPCTestClient(t, newMetadataHandler(manager))
+
+	stream, err := client.GetPodMetadataByContainerID(context.Background(), &metadatapb.MetadataRequest{
+		Keys:      []string{"pod1-container", "pod2-container", "unknown", "pod3-container"},
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+	var batches []*metadatapb.PodMetadataBatch
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		batches = append(batches, batch)
+	}
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0].Metadata, 2)
+	assert.Equal(t, "pod1", batches[0].Metadata["pod1-container"].PodName)
+	assert.Equal(t, map[string]string{"app": "pod2"}, batches[0].Metadata["pod2-container"].Labels)
+	require.Len(t, batches[1].Metadata, 1)
+	assert.Equal(t, "1.1.1.1", batches[1].Metadata["pod3-container"].PodIp)
+}
+
+func TestGRPCServeDeadline(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	server := &metadataGRPCServer{handler: newMetadataHandler(manager)}
+	send := func(*metadatapb.PodMetadataBatch) error { return nil }
+	lookup := func([]string) map[string]*PodMetadata { return nil }
+	req := &metadatapb.MetadataRequest{Keys: []string{"a"}}
+
+	manager.ready.Store(false)
+	err := server.serve(context.Background(), req, send, lookup)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = server.serve(ctx, req, send, lookup)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	// the rest batches are not looked up once the deadline is exceeded
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	lookups := 0
+	err = server.serve(ctx, &metadatapb.MetadataRequest{Keys: []string{"a", "b", "c"}, BatchSize: 1}, send, func([]string) map[string]*PodMetadata {
+		lookups++
+		cancel()
+		return nil
+	})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Equal(t, 1, lookups)
+}
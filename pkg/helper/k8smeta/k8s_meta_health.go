@@ -0,0 +1,85 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusNotReady = "not ready"
+)
+
+// cacheSyncStatus is the state of the informer of a resource, LastEventTime is 0 if no event is received yet.
+type cacheSyncStatus struct {
+	Synced        bool  `json:"synced"`
+	LastEventTime int64 `json:"lastEventTime"`
+	Entries       int   `json:"entries"`
+	QueueSize     int   `json:"queueSize"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	// Synced is true once all the informers are synced, SnapshotLoaded is true if the lookups are answered
+	// from the snapshot before that.
+	Synced         bool                        `json:"synced"`
+	SnapshotLoaded bool                        `json:"snapshotLoaded"`
+	Resources      map[string]*cacheSyncStatus `json:"resources"`
+}
+
+func (m *metadataHandler) healthDetail() *healthResponse {
+	resp := &healthResponse{
+		Status:         healthStatusOK,
+		Synced:         m.metaManager.ready.Load(),
+		SnapshotLoaded: m.metaManager.snapshotLoaded.Load(),
+		Resources:      make(map[string]*cacheSyncStatus, len(m.metaManager.cacheMap)),
+	}
+	for resourceType, cache := range m.metaManager.cacheMap {
+		resp.Resources[resourceType] = cache.syncStatus()
+	}
+	return resp
+}
+
+// handleHealthz reports the server is alive with the sync state of each resource, it always succeeds
+// so that a stuck informer could be inspected without the server being restarted.
+func (m *metadataHandler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	defer panicRecover()
+	writeHealth(w, http.StatusOK, m.healthDetail())
+}
+
+// handleReadyz succeeds once the lookups can be answered, and returns 503 with the same detail before that.
+func (m *metadataHandler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	defer panicRecover()
+	resp := m.healthDetail()
+	code := http.StatusOK
+	if !m.metaManager.isServing() {
+		resp.Status = healthStatusNotReady
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, resp)
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp *healthResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, "Error marshaling health: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
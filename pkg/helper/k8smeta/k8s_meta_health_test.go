@@ -0,0 +1,70 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleHealth(t *testing.T) {
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	podCache.metaStore.Items["default/pod1"] = &ObjectWrapper{
+		Raw: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}},
+	}
+	podCache.lastEventTime.Store(1700000000)
+	manager := &MetaManager{
+		cacheMap: map[string]MetaCache{
+			POD:     podCache,
+			SERVICE: newK8sMetaCache(make(chan struct{}), SERVICE),
+		},
+	}
+	handler := newMetadataHandler(manager)
+
+	get := func(handle http.HandlerFunc, path string) (int, *healthResponse) {
+		rec := httptest.NewRecorder()
+		handle(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp healthResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, &resp
+	}
+
+	code, resp := get(handler.handleReadyz, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthStatusNotReady, resp.Status)
+	assert.Equal(t, &cacheSyncStatus{LastEventTime: 1700000000, Entries: 1}, resp.Resources[POD])
+	assert.Equal(t, &cacheSyncStatus{}, resp.Resources[SERVICE])
+
+	code, resp = get(handler.handleHealthz, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthStatusOK, resp.Status)
+	assert.False(t, resp.Synced)
+
+	podCache.synced.Store(true)
+	manager.snapshotLoaded.Store(true)
+	code, resp = get(handler.handleReadyz, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.SnapshotLoaded)
+	assert.False(t, resp.Synced)
+	assert.True(t, resp.Resources[POD].Synced)
+	assert.False(t, resp.Resources[SERVICE].Synced)
+}
@@ -1,32 +1,80 @@
 package k8smeta
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	app "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/alibaba/ilogtail/pkg/logger"
 )
 
+// bindAddressEnv is the address of the interface the http and grpc servers listen on, e.g. 0.0.0.0 for ipv4 only or
+// an ipv6 address, they listen on all the interfaces of both families if it is empty.
+const bindAddressEnv = "KUBERNETES_METADATA_BIND_ADDRESS"
+
 type requestBody struct {
 	Keys []string `json:"keys"`
+	// Fields selects the fields of the pod metadata in the responses, all the fields are returned if it is empty.
+	Fields []string `json:"fields"`
+}
+
+// batchRequestBody mixes the key types of the pod lookups, so that the pods of heterogeneous events are resolved in
+// one round trip.
+type batchRequestBody struct {
+	IPPorts      []string `json:"ip"`
+	ContainerIDs []string `json:"containerid"`
+	HostIPs      []string `json:"hostip"`
+	Fields       []string `json:"fields"`
+}
+
+// batchResponse is generic so that the projected pod metadata could be returned.
+type batchResponse[T any] struct {
+	IPPorts      map[string]T `json:"ip,omitempty"`
+	ContainerIDs map[string]T `json:"containerid,omitempty"`
+	HostIPs      map[string]T `json:"hostip,omitempty"`
+}
+
+type selectRequestBody struct {
+	Namespace     string   `json:"namespace"`
+	LabelSelector string   `json:"labelSelector"`
+	Limit         int      `json:"limit"`
+	Fields        []string `json:"fields"`
 }
 
 type metadataHandler struct {
-	metaManager *MetaManager
+	metaManager   *MetaManager
+	ownerResolver *ownerResolver
+	limiter       *requestLimiter
+	watchSeq      atomic.Int64
+	clusterID     string
+	federation    *metaFederation
+	coalescer     requestCoalescer
+	metrics       *serverMetrics
 }
 
 func newMetadataHandler(metaManager *MetaManager) *metadataHandler {
 	metadataHandler := &metadataHandler{
-		metaManager: metaManager,
+		metaManager:   metaManager,
+		ownerResolver: newOwnerResolverFromEnv(metaManager),
+		limiter:       newRequestLimiterFromEnv(),
+		clusterID:     os.Getenv(clusterIDEnv),
+		federation:    newMetaFederationFromEnv(),
+		metrics:       newServerMetrics(metaManager),
 	}
 	return metadataHandler
 }
@@ -41,37 +89,85 @@ func (m *metadataHandler) K8sServerRun(stopCh <-chan struct{}) error {
 	if err != nil {
 		port = 9000
 	}
+	security, err := loadServerSecurity()
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_SERVER_ALARM", "load k8s meta server security options error", err)
+		return err
+	}
 	server := &http.Server{ //nolint:gosec
-		Addr: ":" + strconv.Itoa(port),
+		Addr:      listenAddress(port),
+		TLSConfig: security.tlsConfig,
 	}
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/metadata/ipport", m.handler(m.handlePodMetaByIPPort))
 	mux.HandleFunc("/metadata/containerid", m.handler(m.handlePodMetaByContainerID))
+	mux.HandleFunc("/metadata/containername", m.handler(m.handlePodMetaByContainerName))
 	mux.HandleFunc("/metadata/host", m.handler(m.handlePodMetaByHostIP))
-	server.Handler = mux
-	logger.Info(context.Background(), "k8s meta server", "started", "port", port)
+	mux.HandleFunc("/metadata/batch", m.handler(m.handlePodMetaBatch))
+	mux.HandleFunc("/metadata/pods/select", m.handler(m.handlePodMetaBySelector))
+	mux.HandleFunc("/metadata/workload/pods", m.handler(m.handlePodMetaByWorkload))
+	mux.HandleFunc("/metadata/service", m.handler(m.handleServiceMeta))
+	mux.HandleFunc("/metadata/service/endpoints", m.handler(m.handleServiceEndpoints))
+	mux.HandleFunc("/metadata/node", m.handler(m.handleNodeMeta))
+	for _, resourceType := range []string{DEPLOYMENT, STATEFULSET, DAEMONSET, CRONJOB} {
+		mux.HandleFunc("/metadata/"+resourceType, m.handler(m.handleWorkloadMeta(resourceType)))
+	}
+	mux.HandleFunc("/metadata/custom", m.handler(m.handleCustomMeta))
+	// the watch stream is long-lived, so it is not counted in the request latency
+	mux.HandleFunc("/metadata/watch", m.handleWatch)
+	// the probes and the metrics are not authorized by the token, and the detail of the caches does not contain any metadata
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", m.handleHealthz)
+	root.HandleFunc("/readyz", m.handleReadyz)
+	root.Handle("/metrics", m.metrics.handler)
+	root.Handle("/", security.wrapHandler(mux))
+	server.Handler = root
+	logger.Info(context.Background(), "k8s meta server", "started", "port", port, "tls", security.tlsConfig != nil)
 	go func() {
 		defer panicRecover()
-		_ = server.ListenAndServe()
+		if security.tlsConfig != nil {
+			_ = server.ListenAndServeTLS("", "")
+		} else {
+			_ = server.ListenAndServe()
+		}
 	}()
 	<-stopCh
 	return nil
 }
 
+func listenAddress(port int) string {
+	return net.JoinHostPort(os.Getenv(bindAddressEnv), strconv.Itoa(port))
+}
+
 func (m *metadataHandler) handler(handleFunc func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer panicRecover()
-		if !m.metaManager.IsReady() {
+		startTime := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		w = recorder
+		defer func() {
+			m.metrics.observe(r.URL.Path, recorder.code, time.Since(startTime))
+		}()
+		if !m.metaManager.isServing() {
 			w.WriteHeader(http.StatusServiceUnavailable)
 			return
 		}
-		startTime := time.Now()
-		m.metaManager.httpRequestCount.Add(1)
-		handleFunc(w, r)
-		latency := time.Since(startTime).Milliseconds()
-		m.metaManager.httpAvgDelayMs.Add(latency)
-		m.metaManager.httpMaxDelayMs.Set(float64(latency))
+		if !m.limiter.acquire(w, r) {
+			m.metaManager.httpRejectedCount.Add(1)
+			m.metrics.addRejected()
+			return
+		}
+		defer m.limiter.release()
+		if acceptsGzip(r) {
+			gzipWriter := newGzipResponseWriter(w)
+			defer gzipWriter.Close()
+			w = gzipWriter
+		}
+		if m.coalescer.serve(handleFunc, w, r) {
+			m.metaManager.httpCoalescedCount.Add(1)
+			m.metrics.addCoalesced()
+		}
 	}
 }
 
@@ -84,19 +180,46 @@ func (m *metadataHandler) handlePodMetaByIPPort(w http.ResponseWriter, r *http.R
 		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Get the metadata
-	metadata := make(map[string]*PodMetadata)
-	for _, key := range rBody.Keys {
-		ipPort := strings.Split(key, ":")
-		if len(ipPort) == 0 {
-			continue
+	metadata := m.getPodMetaByIPPort(rBody.Keys)
+	m.federation.resolve(r, "/metadata/ipport", rBody.Keys, rBody.Fields, metadata)
+	wrapperResponse(w, projection.projectMap(metadata))
+}
+
+// splitIPPort splits the key of ip, ip:port, IPv6 or [IPv6]:port, and normalizes the ip. The port is 0 if absent or invalid.
+func splitIPPort(key string) (string, int32) {
+	host, portStr := key, ""
+	if strings.HasPrefix(key, "[") || strings.Count(key, ":") == 1 {
+		if h, p, err := net.SplitHostPort(key); err == nil {
+			host, portStr = h, p
+		} else if strings.HasSuffix(key, "]") {
+			host = key[1 : len(key)-1]
 		}
-		ip := ipPort[0]
-		port := int32(0)
-		if len(ipPort) > 1 {
-			tmp, _ := strconv.ParseInt(ipPort[1], 10, 32)
-			port = int32(tmp)
+	}
+	port, _ := strconv.ParseInt(portStr, 10, 32)
+	return normalizeIP(host), int32(port)
+}
+
+func (m *metadataHandler) getPodMetaByIPPort(keys []string) map[string]*PodMetadata {
+	metadata := make(map[string]*PodMetadata)
+	for _, key := range keys {
+		ip, port := splitIPPort(key)
+		if port != 0 {
+			ipPortKey := addIPPortIndexPrefix(ip, port)
+			if obj := preferLivingObject(m.metaManager.cacheMap[POD].Get([]string{ipPortKey})[ipPortKey]); obj != nil {
+				if podMetadata := m.convertObj2PodResponse(obj); podMetadata != nil {
+					metadata[key] = podMetadata
+					continue
+				}
+			}
 		}
 		objs := m.metaManager.cacheMap[POD].Get([]string{ip})
 		if len(objs) == 0 {
@@ -111,7 +234,20 @@ func (m *metadataHandler) handlePodMetaByIPPort(w http.ResponseWriter, r *http.R
 			}
 		}
 	}
-	wrapperResponse(w, metadata)
+	return metadata
+}
+
+// preferLivingObject returns the first object not deleted, or the first one if all are deleted.
+func preferLivingObject(objs []*ObjectWrapper) *ObjectWrapper {
+	for _, obj := range objs {
+		if !obj.Deleted {
+			return obj
+		}
+	}
+	if len(objs) > 0 {
+		return objs[0]
+	}
+	return nil
 }
 
 func (m *metadataHandler) findPodByServiceIPPort(ip string, port int32) *PodMetadata {
@@ -223,6 +359,11 @@ func (m *metadataHandler) convertObj2PodResponse(obj *ObjectWrapper) *PodMetadat
 	}
 	podMetadata.ContainerIDs = containerIDs
 	podMetadata.PodIP = pod.Status.PodIP
+	if ips := podIPs(pod); len(ips) > 1 {
+		podMetadata.PodIPs = ips
+	}
+	podMetadata.IsDeleted = obj.Deleted
+	podMetadata.DeletedAt = obj.DeletedTime
 	return podMetadata
 }
 
@@ -235,10 +376,23 @@ func (m *metadataHandler) handlePodMetaByContainerID(w http.ResponseWriter, r *h
 		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Get the metadata
+	metadata := m.getPodMetaByContainerID(rBody.Keys)
+	m.federation.resolve(r, "/metadata/containerid", rBody.Keys, rBody.Fields, metadata)
+	wrapperResponse(w, projection.projectMap(metadata))
+}
+
+func (m *metadataHandler) getPodMetaByContainerID(keys []string) map[string]*PodMetadata {
 	metadata := make(map[string]*PodMetadata)
-	objs := m.metaManager.cacheMap[POD].Get(rBody.Keys)
+	objs := m.metaManager.cacheMap[POD].Get(keys)
 	for key, obj := range objs {
 		podMetadata := m.convertObjs2ContainerResponse(obj)
 		if len(podMetadata) > 1 {
@@ -248,7 +402,78 @@ func (m *metadataHandler) handlePodMetaByContainerID(w http.ResponseWriter, r *h
 			metadata[key] = podMetadata[0]
 		}
 	}
-	wrapperResponse(w, metadata)
+	return metadata
+}
+
+// handlePodMetaByContainerName resolves the keys of namespace/podNamePrefix/containerName to the pods, e.g. the names in
+// the CRI log path /var/log/pods/<namespace>_<podName>_<podUID>/<containerName>/.
+// The pod named exactly podNamePrefix is preferred, then the newest living pod whose name starts with it.
+func (m *metadataHandler) handlePodMetaByContainerName(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody requestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, key := range rBody.Keys {
+		parts := strings.Split(key, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			http.Error(w, "Invalid container name key, namespace/podNamePrefix/containerName is expected: "+key, http.StatusBadRequest)
+			return
+		}
+	}
+
+	metadata := m.getPodMetaByContainerName(rBody.Keys)
+	m.federation.resolve(r, "/metadata/containername", rBody.Keys, rBody.Fields, metadata)
+	wrapperResponse(w, projection.projectMap(metadata))
+}
+
+func (m *metadataHandler) getPodMetaByContainerName(keys []string) map[string]*PodMetadata {
+	metadata := make(map[string]*PodMetadata)
+	queryKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.Split(key, "/")
+		queryKeys = append(queryKeys, addContainerNameIndexPrefix(parts[0], parts[2]))
+	}
+	objs := m.metaManager.cacheMap[POD].Get(queryKeys)
+	for _, key := range keys {
+		parts := strings.Split(key, "/")
+		if obj := matchPodByNamePrefix(objs[addContainerNameIndexPrefix(parts[0], parts[2])], parts[1]); obj != nil {
+			if podMetadata := m.convertObj2PodResponse(obj); podMetadata != nil {
+				metadata[key] = podMetadata
+			}
+		}
+	}
+	return metadata
+}
+
+func matchPodByNamePrefix(objs []*ObjectWrapper, podNamePrefix string) *ObjectWrapper {
+	var matched *ObjectWrapper
+	var matchedPod *v1.Pod
+	for _, obj := range objs {
+		pod, ok := obj.Raw.(*v1.Pod)
+		if !ok || !strings.HasPrefix(pod.Name, podNamePrefix) {
+			continue
+		}
+		if pod.Name == podNamePrefix {
+			return obj
+		}
+		if matched == nil || (matched.Deleted && !obj.Deleted) ||
+			(matched.Deleted == obj.Deleted && matchedPod.CreationTimestamp.Before(&pod.CreationTimestamp)) {
+			matched, matchedPod = obj, pod
+		}
+	}
+	return matched
 }
 
 func (m *metadataHandler) convertObjs2ContainerResponse(objs []*ObjectWrapper) []*PodMetadata {
@@ -274,12 +499,60 @@ func (m *metadataHandler) handlePodMetaByHostIP(w http.ResponseWriter, r *http.R
 		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Get the metadata
+	wrapperResponse(w, projection.projectMap(m.getPodMetaByHostIP(rBody.Keys)))
+}
+
+// handlePodMetaBatch resolves the pods by ip:port, container id and host ip in one request, the result of each key type
+// is the same as that of its own endpoint.
+func (m *metadataHandler) handlePodMetaBatch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody batchRequestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.IPPorts)+len(rBody.ContainerIDs)+len(rBody.HostIPs)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var response batchResponse[interface{}]
+	if len(rBody.IPPorts) > 0 {
+		metadata := m.getPodMetaByIPPort(rBody.IPPorts)
+		m.federation.resolve(r, "/metadata/ipport", rBody.IPPorts, rBody.Fields, metadata)
+		response.IPPorts = projection.projectMap(metadata)
+	}
+	if len(rBody.ContainerIDs) > 0 {
+		metadata := m.getPodMetaByContainerID(rBody.ContainerIDs)
+		m.federation.resolve(r, "/metadata/containerid", rBody.ContainerIDs, rBody.Fields, metadata)
+		response.ContainerIDs = projection.projectMap(metadata)
+	}
+	if len(rBody.HostIPs) > 0 {
+		response.HostIPs = projection.projectMap(m.getPodMetaByHostIP(rBody.HostIPs))
+	}
+	writeJSONResponse(w, response)
+}
+
+func (m *metadataHandler) getPodMetaByHostIP(keys []string) map[string]*PodMetadata {
 	metadata := make(map[string]*PodMetadata)
-	queryKeys := make([]string, len(rBody.Keys))
-	for _, key := range rBody.Keys {
-		queryKeys = append(queryKeys, addHostIPIndexPrefex(key))
+	queryKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		queryKeys = append(queryKeys, addHostIPIndexPrefex(normalizeIP(key)))
 	}
 	objs := m.metaManager.cacheMap[POD].Get(queryKeys)
 	for _, obj := range objs {
@@ -292,7 +565,7 @@ func (m *metadataHandler) handlePodMetaByHostIP(w http.ResponseWriter, r *http.R
 			metadata[pod.Status.PodIP] = meta
 		}
 	}
-	wrapperResponse(w, metadata)
+	return metadata
 }
 
 func (m *metadataHandler) convertObjs2HostResponse(objs []*ObjectWrapper) []*PodMetadata {
@@ -313,6 +586,417 @@ func (m *metadataHandler) convertObjs2HostResponse(objs []*ObjectWrapper) []*Pod
 	return metadatas
 }
 
+// handlePodMetaBySelector returns the pods matched by the label selector in the namespace, keyed by namespace/name.
+// All namespaces are selected if the namespace is empty.
+func (m *metadataHandler) handlePodMetaBySelector(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody selectRequestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	selector, err := labels.Parse(rBody.LabelSelector)
+	if err != nil {
+		http.Error(w, "Error parsing label selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the metadata
+	metadata := make(map[string]interface{})
+	objs := m.metaManager.cacheMap[POD].Filter(func(ow *ObjectWrapper) bool {
+		if ow.Deleted {
+			return false
+		}
+		pod, ok := ow.Raw.(*v1.Pod)
+		if !ok {
+			return false
+		}
+		if rBody.Namespace != "" && pod.Namespace != rBody.Namespace {
+			return false
+		}
+		return selector.Matches(labels.Set(pod.Labels))
+	}, rBody.Limit)
+	for _, obj := range objs {
+		podMetadata := m.convertObj2PodResponse(obj)
+		if podMetadata != nil {
+			metadata[generateNameWithNamespaceKey(podMetadata.Namespace, podMetadata.PodName)] = projection.project(podMetadata)
+		}
+	}
+	wrapperResponse(w, metadata)
+}
+
+// handlePodMetaByWorkload returns the pods of the workloads keyed by kind/namespace/name, e.g. deployment/prod/web.
+// The pods are found through the owned replicasets and jobs, as the owner chain configures.
+func (m *metadataHandler) handlePodMetaByWorkload(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody requestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metadata := make(map[string][]interface{})
+	for _, key := range rBody.Keys {
+		parts := strings.Split(key, "/")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			http.Error(w, "Invalid workload key, kind/namespace/name is expected: "+key, http.StatusBadRequest)
+			return
+		}
+		pods := m.ownerResolver.ownedPods(parts[1], ownerReference{kind: strings.ToLower(parts[0]), name: parts[2]})
+		podMetadatas := make([]*PodMetadata, 0, len(pods))
+		for _, pod := range pods {
+			if podMetadata := m.convertObj2PodResponse(pod); podMetadata != nil {
+				podMetadatas = append(podMetadatas, podMetadata)
+			}
+		}
+		metadata[key] = projection.projectList(podMetadatas)
+	}
+	wrapperResponse(w, metadata)
+}
+
+// handleServiceMeta resolves services by cluster ip, namespace/name or bare name.
+func (m *metadataHandler) handleServiceMeta(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody requestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+
+	// Get the metadata
+	metadata := make(map[string]*ServiceMetadata)
+	for _, key := range rBody.Keys {
+		if serviceMetadata := m.findService(key); serviceMetadata != nil {
+			metadata[key] = serviceMetadata
+		}
+	}
+	wrapperResponse(w, metadata)
+}
+
+func (m *metadataHandler) findService(key string) *ServiceMetadata {
+	ipKey := normalizeIP(key)
+	objs := m.metaManager.cacheMap[SERVICE].Get([]string{ipKey})
+	for _, obj := range objs[ipKey] {
+		if serviceMetadata := convertObj2ServiceResponse(obj); serviceMetadata != nil {
+			return serviceMetadata
+		}
+	}
+	if strings.Contains(key, "/") {
+		return nil
+	}
+	// bare service name, the first matched namespace wins
+	objList := m.metaManager.cacheMap[SERVICE].Filter(func(ow *ObjectWrapper) bool {
+		svc, ok := ow.Raw.(*v1.Service)
+		return ok && svc.Name == key
+	}, 1)
+	if len(objList) == 0 {
+		return nil
+	}
+	return convertObj2ServiceResponse(objList[0])
+}
+
+// handleServiceEndpoints resolves services like handleServiceMeta and returns their backends from the endpoint slices,
+// with the metadata of the backend pods.
+func (m *metadataHandler) handleServiceEndpoints(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody requestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+	projection, err := newPodFieldProjection(rBody.Fields)
+	if err != nil {
+		http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get the metadata
+	metadata := make(map[string]*ServiceEndpointsMetadata)
+	for _, key := range rBody.Keys {
+		serviceMetadata := m.findService(key)
+		if serviceMetadata == nil {
+			continue
+		}
+		metadata[key] = &ServiceEndpointsMetadata{
+			Service:   serviceMetadata,
+			Endpoints: m.getServiceEndpoints(serviceMetadata.Namespace, serviceMetadata.ServiceName, projection),
+		}
+	}
+	wrapperResponse(w, metadata)
+}
+
+func (m *metadataHandler) getServiceEndpoints(namespace, name string, projection podFieldProjection) []*EndpointMetadata {
+	sliceKey := addServiceIndexPrefix(namespace, name)
+	endpoints := make([]*EndpointMetadata, 0)
+	for _, obj := range m.metaManager.cacheMap[ENDPOINTSLICE].Get([]string{sliceKey})[sliceKey] {
+		slice, ok := obj.Raw.(*discovery.EndpointSlice)
+		if !ok || obj.Deleted {
+			continue
+		}
+		ports := make([]*EndpointPortMetadata, 0, len(slice.Ports))
+		for _, port := range slice.Ports {
+			portMetadata := &EndpointPortMetadata{}
+			if port.Name != nil {
+				portMetadata.Name = *port.Name
+			}
+			if port.Protocol != nil {
+				portMetadata.Protocol = string(*port.Protocol)
+			}
+			if port.Port != nil {
+				portMetadata.Port = *port.Port
+			}
+			ports = append(ports, portMetadata)
+		}
+		for i := range slice.Endpoints {
+			endpoint := &slice.Endpoints[i]
+			// a nil ready condition means the endpoint is ready
+			endpointMetadata := &EndpointMetadata{
+				Addresses:   endpoint.Addresses,
+				Ports:       ports,
+				Ready:       endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready,
+				Terminating: endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating,
+			}
+			if endpoint.NodeName != nil {
+				endpointMetadata.NodeName = *endpoint.NodeName
+			}
+			if podMetadata := m.findEndpointPod(slice.Namespace, endpoint); podMetadata != nil {
+				endpointMetadata.Pod = projection.project(podMetadata)
+			}
+			endpoints = append(endpoints, endpointMetadata)
+		}
+	}
+	return endpoints
+}
+
+// findEndpointPod finds the pod by the target reference of the endpoint, or by its addresses if it has no pod reference.
+func (m *metadataHandler) findEndpointPod(namespace string, endpoint *discovery.Endpoint) *PodMetadata {
+	var keys []string
+	if endpoint.TargetRef != nil {
+		if endpoint.TargetRef.Kind != "Pod" {
+			return nil
+		}
+		if endpoint.TargetRef.Namespace != "" {
+			namespace = endpoint.TargetRef.Namespace
+		}
+		keys = []string{generateNameWithNamespaceKey(namespace, endpoint.TargetRef.Name)}
+	} else {
+		for _, address := range endpoint.Addresses {
+			keys = append(keys, normalizeIP(address))
+		}
+	}
+	objs := m.metaManager.cacheMap[POD].Get(keys)
+	for _, key := range keys {
+		if obj := preferLivingObject(objs[key]); obj != nil {
+			return m.convertObj2PodResponse(obj)
+		}
+	}
+	return nil
+}
+
+func convertObj2ServiceResponse(obj *ObjectWrapper) *ServiceMetadata {
+	svc, ok := obj.Raw.(*v1.Service)
+	if !ok {
+		return nil
+	}
+	ports := make([]*ServicePortMetadata, 0, len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		ports = append(ports, &ServicePortMetadata{
+			Name:       port.Name,
+			Protocol:   string(port.Protocol),
+			Port:       port.Port,
+			TargetPort: port.TargetPort.String(),
+			NodePort:   port.NodePort,
+		})
+	}
+	return &ServiceMetadata{
+		ServiceName: svc.Name,
+		Namespace:   svc.Namespace,
+		Type:        string(svc.Spec.Type),
+		Labels:      svc.Labels,
+		Selector:    svc.Spec.Selector,
+		ClusterIP:   svc.Spec.ClusterIP,
+		ClusterIPs:  svc.Spec.ClusterIPs,
+		Ports:       ports,
+	}
+}
+
+// handleNodeMeta resolves nodes by node name or node internal/external ip.
+func (m *metadataHandler) handleNodeMeta(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var rBody requestBody
+	// Decode the JSON data into the struct
+	err := json.NewDecoder(r.Body).Decode(&rBody)
+	if err != nil {
+		http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+		return
+	}
+
+	// Get the metadata
+	metadata := make(map[string]*NodeMetadata)
+	for _, key := range rBody.Keys {
+		ipKey := normalizeIP(key)
+		for _, o := range m.metaManager.cacheMap[NODE].Get([]string{ipKey})[ipKey] {
+			if nodeMetadata := convertObj2NodeResponse(o); nodeMetadata != nil {
+				metadata[key] = nodeMetadata
+				break
+			}
+		}
+	}
+	wrapperResponse(w, metadata)
+}
+
+func convertObj2NodeResponse(obj *ObjectWrapper) *NodeMetadata {
+	node, ok := obj.Raw.(*v1.Node)
+	if !ok {
+		return nil
+	}
+	taints := make([]*NodeTaintMetadata, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		taints = append(taints, &NodeTaintMetadata{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: string(taint.Effect),
+		})
+	}
+	allocatable := make(map[string]string, len(node.Status.Allocatable))
+	for name, quantity := range node.Status.Allocatable {
+		allocatable[string(name)] = quantity.String()
+	}
+	nodeMetadata := &NodeMetadata{
+		NodeName:       node.Name,
+		Labels:         node.Labels,
+		Taints:         taints,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		Allocatable:    allocatable,
+	}
+	for _, address := range node.Status.Addresses {
+		switch address.Type {
+		case v1.NodeInternalIP:
+			if nodeMetadata.InternalIP == "" {
+				nodeMetadata.InternalIP = address.Address
+			}
+		case v1.NodeExternalIP:
+			if nodeMetadata.ExternalIP == "" {
+				nodeMetadata.ExternalIP = address.Address
+			}
+		}
+	}
+	return nodeMetadata
+}
+
+// handleWorkloadMeta returns the handler resolving the workloads of the resource type by namespace/name.
+func (m *metadataHandler) handleWorkloadMeta(resourceType string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var rBody requestBody
+		// Decode the JSON data into the struct
+		err := json.NewDecoder(r.Body).Decode(&rBody)
+		if err != nil {
+			http.Error(w, "Error parsing JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !m.limiter.allowKeys(w, len(rBody.Keys)) {
+			return
+		}
+
+		// Get the metadata
+		metadata := make(map[string]*WorkloadMetadata)
+		objs := m.metaManager.cacheMap[resourceType].Get(rBody.Keys)
+		for key, obj := range objs {
+			for _, o := range obj {
+				if workloadMetadata := convertObj2WorkloadResponse(o); workloadMetadata != nil {
+					metadata[key] = workloadMetadata
+					break
+				}
+			}
+		}
+		wrapperResponse(w, metadata)
+	}
+}
+
+func convertObj2WorkloadResponse(obj *ObjectWrapper) *WorkloadMetadata {
+	var objectMeta *metav1.ObjectMeta
+	workloadMetadata := &WorkloadMetadata{}
+	switch workload := obj.Raw.(type) {
+	case *app.Deployment:
+		objectMeta = &workload.ObjectMeta
+		workloadMetadata.WorkloadKind = DEPLOYMENT
+		workloadMetadata.Replicas = workload.Status.Replicas
+		if workload.Spec.Replicas != nil {
+			workloadMetadata.Replicas = *workload.Spec.Replicas
+		}
+		workloadMetadata.ReadyReplicas = workload.Status.ReadyReplicas
+		workloadMetadata.AvailableReplicas = workload.Status.AvailableReplicas
+	case *app.StatefulSet:
+		objectMeta = &workload.ObjectMeta
+		workloadMetadata.WorkloadKind = STATEFULSET
+		workloadMetadata.Replicas = workload.Status.Replicas
+		if workload.Spec.Replicas != nil {
+			workloadMetadata.Replicas = *workload.Spec.Replicas
+		}
+		workloadMetadata.ReadyReplicas = workload.Status.ReadyReplicas
+		workloadMetadata.AvailableReplicas = workload.Status.AvailableReplicas
+	case *app.DaemonSet:
+		objectMeta = &workload.ObjectMeta
+		workloadMetadata.WorkloadKind = DAEMONSET
+		workloadMetadata.Replicas = workload.Status.DesiredNumberScheduled
+		workloadMetadata.ReadyReplicas = workload.Status.NumberReady
+		workloadMetadata.AvailableReplicas = workload.Status.NumberAvailable
+	case *batch.CronJob:
+		objectMeta = &workload.ObjectMeta
+		workloadMetadata.WorkloadKind = CRONJOB
+		workloadMetadata.Schedule = workload.Spec.Schedule
+		workloadMetadata.Suspend = workload.Spec.Suspend != nil && *workload.Spec.Suspend
+		workloadMetadata.ActiveJobs = int32(len(workload.Status.Active)) //nolint:gosec
+	default:
+		return nil
+	}
+	workloadMetadata.WorkloadName = objectMeta.Name
+	workloadMetadata.Namespace = objectMeta.Namespace
+	workloadMetadata.StartTime = objectMeta.CreationTimestamp.Time.Unix()
+	workloadMetadata.Labels = objectMeta.Labels
+	workloadMetadata.Annotations = dropLastAppliedConfiguration(objectMeta.Annotations)
+	owners := make([]*WorkloadOwnerMetadata, 0, len(objectMeta.OwnerReferences))
+	for _, reference := range objectMeta.OwnerReferences {
+		owners = append(owners, &WorkloadOwnerMetadata{
+			Kind: strings.ToLower(reference.Kind),
+			Name: reference.Name,
+		})
+	}
+	workloadMetadata.Owners = owners
+	return workloadMetadata
+}
+
 func (m *metadataHandler) getCommonPodMetadata(pod *v1.Pod) *PodMetadata {
 	images := make(map[string]string)
 	envs := make(map[string]string)
@@ -330,36 +1014,96 @@ func (m *metadataHandler) getCommonPodMetadata(pod *v1.Pod) *PodMetadata {
 		Images:    images,
 		Envs:      envs,
 		IsDeleted: false,
+
+		Annotations: dropLastAppliedConfiguration(pod.Annotations),
+		Containers:  getPodContainerMetadata(pod),
+		ClusterID:   m.clusterID,
 	}
-	if len(pod.GetOwnerReferences()) == 0 {
-		podMetadata.WorkloadName = ""
-		podMetadata.WorkloadKind = ""
+	reference := controllerOwner(pod.GetOwnerReferences())
+	if reference == nil {
 		logger.Warning(context.Background(), "Pod has no owner", pod.Name)
 	} else {
-		reference := pod.GetOwnerReferences()[0]
-		podMetadata.WorkloadName = reference.Name
-		podMetadata.WorkloadKind = strings.ToLower(reference.Kind)
-		if podMetadata.WorkloadKind == REPLICASET {
-			// replicaset -> deployment
-			replicasetKey := generateNameWithNamespaceKey(pod.Namespace, podMetadata.WorkloadName)
-			replicasets := m.metaManager.cacheMap[REPLICASET].Get([]string{replicasetKey})
-			for _, replicaset := range replicasets[replicasetKey] {
-				replicaset, ok := replicaset.Raw.(*app.ReplicaSet)
-				if !ok {
-					continue
-				}
-				if len(replicaset.OwnerReferences) > 0 {
-					podMetadata.WorkloadName = replicaset.OwnerReferences[0].Name
-					podMetadata.WorkloadKind = strings.ToLower(replicaset.OwnerReferences[0].Kind)
-					break
-				}
+		owner := m.ownerResolver.resolve(pod.Namespace, ownerReference{kind: strings.ToLower(reference.Kind), name: reference.Name})
+		podMetadata.WorkloadName = owner.name
+		podMetadata.WorkloadKind = owner.kind
+	}
+	podMetadata.NamespaceLabels, podMetadata.NamespaceAnnotations = m.getNamespaceMetadata(pod.Namespace)
+	return podMetadata
+}
+
+func getPodContainerMetadata(pod *v1.Pod) []*PodContainerMetadata {
+	statuses := make(map[string]*v1.ContainerStatus, len(pod.Status.ContainerStatuses))
+	for i := range pod.Status.ContainerStatuses {
+		statuses[pod.Status.ContainerStatuses[i].Name] = &pod.Status.ContainerStatuses[i]
+	}
+	containers := make([]*PodContainerMetadata, 0, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containerMetadata := &PodContainerMetadata{
+			Name:     container.Name,
+			Image:    container.Image,
+			Requests: convertResourceList(container.Resources.Requests),
+			Limits:   convertResourceList(container.Resources.Limits),
+		}
+		if status, ok := statuses[container.Name]; ok {
+			if runtime, id, found := strings.Cut(status.ContainerID, "://"); found {
+				containerMetadata.Runtime = runtime
+				containerMetadata.ContainerID = id
+			}
+			containerMetadata.RestartCount = status.RestartCount
+		}
+		containers = append(containers, containerMetadata)
+	}
+	return containers
+}
+
+func convertResourceList(resources v1.ResourceList) map[string]string {
+	if len(resources) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(resources))
+	for name, quantity := range resources {
+		result[string(name)] = quantity.String()
+	}
+	return result
+}
+
+// dropLastAppliedConfiguration copies the annotations, the last applied configuration is emptied when cached, so it is dropped.
+func dropLastAppliedConfiguration(annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		if k != "kubectl.kubernetes.io/last-applied-configuration" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// getNamespaceMetadata returns the labels and annotations of the namespace, nil is returned if the namespace is not cached.
+func (m *metadataHandler) getNamespaceMetadata(namespace string) (map[string]string, map[string]string) {
+	cache, ok := m.metaManager.cacheMap[NAMESPACE]
+	if !ok {
+		return nil, nil
+	}
+	key := generateNameWithNamespaceKey("", namespace)
+	for _, obj := range cache.Get([]string{key})[key] {
+		ns, ok := obj.Raw.(*v1.Namespace)
+		if !ok {
+			continue
+		}
+		var annotations map[string]string
+		for k, v := range ns.Annotations {
+			// the last applied configuration is emptied when cached
+			if k == "kubectl.kubernetes.io/last-applied-configuration" && v == "" {
+				continue
 			}
-			if podMetadata.WorkloadKind == "replicaset" {
-				logger.Warning(context.Background(), "ReplicaSet has no owner", podMetadata.WorkloadName)
+			if annotations == nil {
+				annotations = make(map[string]string, len(ns.Annotations))
 			}
+			annotations[k] = v
 		}
+		return ns.Labels, annotations
 	}
-	return podMetadata
+	return nil, nil
 }
 
 func truncateContainerID(containerID string) string {
@@ -371,7 +1115,11 @@ func truncateContainerID(containerID string) string {
 	return separated[1]
 }
 
-func wrapperResponse(w http.ResponseWriter, metadata map[string]*PodMetadata) {
+func wrapperResponse[T any](w http.ResponseWriter, metadata map[string]T) {
+	writeJSONResponse(w, metadata)
+}
+
+func writeJSONResponse(w http.ResponseWriter, metadata interface{}) {
 	// Convert metadata to JSON
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
@@ -387,3 +1135,48 @@ func wrapperResponse(w http.ResponseWriter, metadata map[string]*PodMetadata) {
 		return
 	}
 }
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// gzipResponseWriter compresses the response body for the clients sending Accept-Encoding: gzip.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer *gzip.Writer
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func newGzipResponseWriter(w http.ResponseWriter) *gzipResponseWriter {
+	writer := gzipWriterPool.Get().(*gzip.Writer)
+	writer.Reset(w)
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{ResponseWriter: w, writer: writer}
+}
+
+func (g *gzipResponseWriter) WriteHeader(statusCode int) {
+	g.Header().Del("Content-Length")
+	g.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	g.Header().Del("Content-Length")
+	return g.writer.Write(b)
+}
+
+func (g *gzipResponseWriter) Close() {
+	_ = g.writer.Close()
+	g.writer.Reset(nil)
+	gzipWriterPool.Put(g.writer)
+}
@@ -1,11 +1,22 @@
 package k8smeta
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestFindPodByServiceIPPort(t *testing.T) {
@@ -130,3 +141,651 @@ func TestFindPodByPodIPPort(t *testing.T) {
 	podMetadata = handler.findPodByPodIPPort("2.2.2.2", 0, pods)
 	assert.Nil(t, podMetadata)
 }
+
+func TestGetPodMetaByIPPortHostNetwork(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	newHostNetworkPod := func(name string, ports ...corev1.ContainerPort) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Spec: corev1.PodSpec{
+				HostNetwork: true,
+				Containers:  []corev1.Container{{Name: name, Ports: ports}},
+			},
+			Status: corev1.PodStatus{PodIP: "192.168.0.1", HostIP: "192.168.0.1"},
+		}
+	}
+	addIndexedTestObject(podCache, "kube-system/exporter", &ObjectWrapper{Raw: newHostNetworkPod("exporter", corev1.ContainerPort{ContainerPort: 9100, HostPort: 9100})})
+	addIndexedTestObject(podCache, "kube-system/proxy", &ObjectWrapper{Raw: newHostNetworkPod("proxy", corev1.ContainerPort{ContainerPort: 10249})})
+	addIndexedTestObject(podCache, "kube-system/proxy-old", &ObjectWrapper{Raw: newHostNetworkPod("proxy-old", corev1.ContainerPort{ContainerPort: 10249}), Deleted: true})
+	addIndexedTestObject(podCache, "prod/web", &ObjectWrapper{Raw: &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Ports: []corev1.ContainerPort{{ContainerPort: 80, HostPort: 8080}}}}},
+		Status:     corev1.PodStatus{PodIP: "10.1.0.5", HostIP: "192.168.0.1"},
+	}})
+	manager.cacheMap[POD] = podCache
+	manager.cacheMap[SERVICE] = newK8sMetaCache(make(chan struct{}), SERVICE)
+	handler := newMetadataHandler(manager)
+
+	metadata := handler.getPodMetaByIPPort([]string{"192.168.0.1:9100", "192.168.0.1:10249", "192.168.0.1:8080", "10.1.0.5:80", "192.168.0.1:22"})
+	require.Len(t, metadata, 4)
+	assert.Equal(t, "exporter", metadata["192.168.0.1:9100"].PodName)
+	// the living pod is preferred
+	assert.Equal(t, "proxy", metadata["192.168.0.1:10249"].PodName)
+	// the host port of a pod not in the host network
+	assert.Equal(t, "web", metadata["192.168.0.1:8080"].PodName)
+	assert.Equal(t, "web", metadata["10.1.0.5:80"].PodName)
+}
+
+func TestSplitIPPort(t *testing.T) {
+	for key, expected := range map[string]struct {
+		ip   string
+		port int32
+	}{
+		"10.1.0.5":          {"10.1.0.5", 0},
+		"10.1.0.5:80":       {"10.1.0.5", 80},
+		"fd00:0::5":         {"fd00::5", 0},
+		"[FD00::5]:8080":    {"fd00::5", 8080},
+		"[fd00::5]":         {"fd00::5", 0},
+		"10.1.0.5:invalid":  {"10.1.0.5", 0},
+		"::ffff:10.1.0.5":   {"10.1.0.5", 0},
+		"[::ffff:10.1.0.5]": {"10.1.0.5", 0},
+	} {
+		ip, port := splitIPPort(key)
+		assert.Equal(t, expected.ip, ip, key)
+		assert.Equal(t, expected.port, port, key)
+	}
+}
+
+func TestGetPodMetaDualStack(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	addIndexedTestObject(podCache, "prod/web", &ObjectWrapper{Raw: &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Ports: []corev1.ContainerPort{{ContainerPort: 80}}}}},
+		Status: corev1.PodStatus{
+			PodIP:  "10.1.0.5",
+			PodIPs: []corev1.PodIP{{IP: "10.1.0.5"}, {IP: "FD00:0::5"}},
+			HostIP: "fd00::1",
+		},
+	}})
+	manager.cacheMap[POD] = podCache
+	manager.cacheMap[SERVICE] = newK8sMetaCache(make(chan struct{}), SERVICE)
+	handler := newMetadataHandler(manager)
+
+	metadata := handler.getPodMetaByIPPort([]string{"10.1.0.5:80", "fd00::5", "[fd00:0:0::5]:80", "[fd00::5]:81"})
+	require.Len(t, metadata, 3)
+	for _, podMetadata := range metadata {
+		assert.Equal(t, "web", podMetadata.PodName)
+		assert.Equal(t, "10.1.0.5", podMetadata.PodIP)
+		assert.Equal(t, []string{"10.1.0.5", "fd00::5"}, podMetadata.PodIPs)
+	}
+	metadata = handler.getPodMetaByHostIP([]string{"FD00:0::1"})
+	require.Len(t, metadata, 1)
+	assert.Equal(t, "web", metadata["10.1.0.5"].PodName)
+}
+
+func TestHandleServiceMeta(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	serviceCache := newK8sMetaCache(make(chan struct{}), SERVICE)
+	serviceCache.metaStore.Items["prod/frontend"] = &ObjectWrapper{
+		Raw: &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "frontend",
+				Namespace: "prod",
+				Labels: map[string]string{
+					"team": "web",
+				},
+			},
+			Spec: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeClusterIP,
+				Selector: map[string]string{
+					"app": "frontend",
+				},
+				ClusterIP:  "10.0.0.10",
+				ClusterIPs: []string{"10.0.0.10"},
+				Ports: []corev1.ServicePort{
+					{
+						Name:       "http",
+						Protocol:   corev1.ProtocolTCP,
+						Port:       80,
+						TargetPort: intstr.FromInt(8080),
+					},
+				},
+			},
+		},
+	}
+	serviceCache.metaStore.Index["prod/frontend"] = NewIndexItem()
+	serviceCache.metaStore.Index["prod/frontend"].Add("prod/frontend")
+	serviceCache.metaStore.Index["10.0.0.10"] = NewIndexItem()
+	serviceCache.metaStore.Index["10.0.0.10"].Add("prod/frontend")
+	manager.cacheMap[SERVICE] = serviceCache
+	handler := newMetadataHandler(manager)
+
+	result := make(map[string]*ServiceMetadata)
+	doMetadataRequest(t, handler.handleServiceMeta, []string{"10.0.0.10", "prod/frontend", "frontend", "prod/backend", "backend"}, &result)
+	require.Len(t, result, 3)
+	for _, key := range []string{"10.0.0.10", "prod/frontend", "frontend"} {
+		svc := result[key]
+		require.NotNil(t, svc, key)
+		assert.Equal(t, "frontend", svc.ServiceName)
+		assert.Equal(t, "prod", svc.Namespace)
+		assert.Equal(t, "ClusterIP", svc.Type)
+		assert.Equal(t, "10.0.0.10", svc.ClusterIP)
+		assert.Equal(t, map[string]string{"app": "frontend"}, svc.Selector)
+		assert.Equal(t, map[string]string{"team": "web"}, svc.Labels)
+		require.Len(t, svc.Ports, 1)
+		assert.Equal(t, "http", svc.Ports[0].Name)
+		assert.Equal(t, "TCP", svc.Ports[0].Protocol)
+		assert.Equal(t, int32(80), svc.Ports[0].Port)
+		assert.Equal(t, "8080", svc.Ports[0].TargetPort)
+	}
+}
+
+func TestHandleServiceEndpoints(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	serviceCache := newK8sMetaCache(make(chan struct{}), SERVICE)
+	addIndexedTestObject(serviceCache, "prod/frontend", &ObjectWrapper{Raw: &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "prod"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.10", ClusterIPs: []string{"10.0.0.10"}},
+	}})
+	addIndexedTestObject(serviceCache, "prod/idle", &ObjectWrapper{Raw: &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "idle", Namespace: "prod"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.11", ClusterIPs: []string{"10.0.0.11"}},
+	}})
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	for name, podIP := range map[string]string{"frontend-1": "172.16.0.1", "frontend-2": "172.16.0.2"} {
+		addIndexedTestObject(podCache, "prod/"+name, &ObjectWrapper{Raw: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod"},
+			Status:     corev1.PodStatus{PodIP: podIP},
+		}})
+	}
+	portName, protocol, port := "http", corev1.ProtocolTCP, int32(8080)
+	ready, notReady, nodeName := true, false, "node-1"
+	sliceCache := newK8sMetaCache(make(chan struct{}), ENDPOINTSLICE)
+	addIndexedTestObject(sliceCache, "prod/frontend-abc", &ObjectWrapper{Raw: &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "frontend-abc",
+			Namespace: "prod",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "frontend"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{
+				Addresses:  []string{"172.16.0.1"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+				NodeName:   &nodeName,
+				TargetRef:  &corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "frontend-1"},
+			},
+			{
+				// resolved by the address without a target reference
+				Addresses:  []string{"172.16.0.2"},
+				Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+			},
+		},
+		Ports: []discoveryv1.EndpointPort{{Name: &portName, Protocol: &protocol, Port: &port}},
+	}})
+	// the deleted slice is skipped
+	addIndexedTestObject(sliceCache, "prod/frontend-old", &ObjectWrapper{Raw: &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "frontend-old",
+			Namespace: "prod",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "frontend"},
+		},
+		Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"172.16.0.9"}}},
+	}, Deleted: true})
+	manager.cacheMap[SERVICE] = serviceCache
+	manager.cacheMap[POD] = podCache
+	manager.cacheMap[ENDPOINTSLICE] = sliceCache
+	handler := newMetadataHandler(manager)
+
+	result := make(map[string]*ServiceEndpointsMetadata)
+	doMetadataRequest(t, handler.handleServiceEndpoints, []string{"10.0.0.10", "prod/idle", "unknown"}, &result)
+	require.Len(t, result, 2)
+	assert.Equal(t, "idle", result["prod/idle"].Service.ServiceName)
+	assert.Empty(t, result["prod/idle"].Endpoints)
+
+	frontend := result["10.0.0.10"]
+	require.NotNil(t, frontend)
+	assert.Equal(t, "frontend", frontend.Service.ServiceName)
+	require.Len(t, frontend.Endpoints, 2)
+	first, second := frontend.Endpoints[0], frontend.Endpoints[1]
+	assert.Equal(t, []string{"172.16.0.1"}, first.Addresses)
+	assert.True(t, first.Ready)
+	assert.Equal(t, "node-1", first.NodeName)
+	require.Len(t, first.Ports, 1)
+	assert.Equal(t, &EndpointPortMetadata{Name: "http", Protocol: "TCP", Port: 8080}, first.Ports[0])
+	assert.Equal(t, "frontend-1", first.Pod.(map[string]interface{})["podName"])
+	assert.False(t, second.Ready)
+	assert.Equal(t, "frontend-2", second.Pod.(map[string]interface{})["podName"])
+}
+
+func TestHandleNodeMeta(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	nodeCache := newK8sMetaCache(make(chan struct{}), NODE)
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone": "zone-a",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{
+					Key:    "dedicated",
+					Value:  "infra",
+					Effect: corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "node1"},
+				{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "47.0.0.1"},
+			},
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("16Gi"),
+			},
+			NodeInfo: corev1.NodeSystemInfo{
+				KubeletVersion: "v1.28.3",
+			},
+		},
+	}
+	keys, err := generateNodeIPKey(node)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.168.0.1", "47.0.0.1"}, keys)
+	nodeCache.metaStore.Items["node1"] = &ObjectWrapper{Raw: node}
+	for _, key := range []string{"node1", "192.168.0.1", "47.0.0.1"} {
+		nodeCache.metaStore.Index[key] = NewIndexItem()
+		nodeCache.metaStore.Index[key].Add("node1")
+	}
+	manager.cacheMap[NODE] = nodeCache
+	handler := newMetadataHandler(manager)
+
+	result := make(map[string]*NodeMetadata)
+	doMetadataRequest(t, handler.handleNodeMeta, []string{"node1", "192.168.0.1", "node2"}, &result)
+	require.Len(t, result, 2)
+	for _, key := range []string{"node1", "192.168.0.1"} {
+		meta := result[key]
+		require.NotNil(t, meta, key)
+		assert.Equal(t, "node1", meta.NodeName)
+		assert.Equal(t, "zone-a", meta.Labels["topology.kubernetes.io/zone"])
+		assert.Equal(t, "v1.28.3", meta.KubeletVersion)
+		assert.Equal(t, map[string]string{"cpu": "4", "memory": "16Gi"}, meta.Allocatable)
+		assert.Equal(t, "192.168.0.1", meta.InternalIP)
+		assert.Equal(t, "47.0.0.1", meta.ExternalIP)
+		require.Len(t, meta.Taints, 1)
+		assert.Equal(t, "dedicated", meta.Taints[0].Key)
+		assert.Equal(t, "infra", meta.Taints[0].Value)
+		assert.Equal(t, "NoSchedule", meta.Taints[0].Effect)
+	}
+}
+
+func TestHandleWorkloadMeta(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	replicas := int32(3)
+	suspend := true
+	workloads := map[string]interface{}{
+		DEPLOYMENT: &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web",
+				Namespace: "prod",
+				Labels:    map[string]string{"app": "web"},
+				Annotations: map[string]string{
+					"deployment.kubernetes.io/revision":                "2",
+					"kubectl.kubernetes.io/last-applied-configuration": "",
+				},
+			},
+			Spec:   appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{Replicas: 3, ReadyReplicas: 2, AvailableReplicas: 2},
+		},
+		DAEMONSET: &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 5, NumberReady: 4, NumberAvailable: 4},
+		},
+		CRONJOB: &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Spec:       batchv1.CronJobSpec{Schedule: "*/5 * * * *", Suspend: &suspend},
+			Status:     batchv1.CronJobStatus{Active: []corev1.ObjectReference{{Name: "web-1"}}},
+		},
+		STATEFULSET: &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web",
+				Namespace:       "prod",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Rollout", Name: "web-rollout"}},
+			},
+			Spec:   appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status: appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, AvailableReplicas: 3},
+		},
+	}
+	for resourceType, workload := range workloads {
+		workloadCache := newK8sMetaCache(make(chan struct{}), resourceType)
+		workloadCache.metaStore.Items["prod/web"] = &ObjectWrapper{Raw: workload}
+		workloadCache.metaStore.Index["prod/web"] = NewIndexItem()
+		workloadCache.metaStore.Index["prod/web"].Add("prod/web")
+		manager.cacheMap[resourceType] = workloadCache
+	}
+	handler := newMetadataHandler(manager)
+
+	result := make(map[string]*WorkloadMetadata)
+	doMetadataRequest(t, handler.handleWorkloadMeta(DEPLOYMENT), []string{"prod/web", "prod/api"}, &result)
+	require.Len(t, result, 1)
+	deployment := result["prod/web"]
+	assert.Equal(t, "web", deployment.WorkloadName)
+	assert.Equal(t, DEPLOYMENT, deployment.WorkloadKind)
+	assert.Equal(t, "prod", deployment.Namespace)
+	assert.Equal(t, map[string]string{"app": "web"}, deployment.Labels)
+	assert.Equal(t, map[string]string{"deployment.kubernetes.io/revision": "2"}, deployment.Annotations)
+	assert.Empty(t, deployment.Owners)
+	assert.Equal(t, int32(3), deployment.Replicas)
+	assert.Equal(t, int32(2), deployment.ReadyReplicas)
+	assert.Equal(t, int32(2), deployment.AvailableReplicas)
+
+	result = make(map[string]*WorkloadMetadata)
+	doMetadataRequest(t, handler.handleWorkloadMeta(STATEFULSET), []string{"prod/web"}, &result)
+	require.Len(t, result["prod/web"].Owners, 1)
+	assert.Equal(t, WorkloadOwnerMetadata{Kind: "rollout", Name: "web-rollout"}, *result["prod/web"].Owners[0])
+	assert.Equal(t, int32(3), result["prod/web"].ReadyReplicas)
+
+	result = make(map[string]*WorkloadMetadata)
+	doMetadataRequest(t, handler.handleWorkloadMeta(DAEMONSET), []string{"prod/web"}, &result)
+	assert.Equal(t, int32(5), result["prod/web"].Replicas)
+	assert.Equal(t, int32(4), result["prod/web"].ReadyReplicas)
+
+	result = make(map[string]*WorkloadMetadata)
+	doMetadataRequest(t, handler.handleWorkloadMeta(CRONJOB), []string{"prod/web"}, &result)
+	assert.Equal(t, CRONJOB, result["prod/web"].WorkloadKind)
+	assert.Equal(t, "*/5 * * * *", result["prod/web"].Schedule)
+	assert.True(t, result["prod/web"].Suspend)
+	assert.Equal(t, int32(1), result["prod/web"].ActiveJobs)
+}
+
+func doMetadataRequest(t *testing.T, handle http.HandlerFunc, keys []string, result interface{}) {
+	body, err := json.Marshal(requestBody{Keys: keys})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handle(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), result))
+}
+
+func TestHandlePodMetaBySelector(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	newPod := func(namespace, name string, podLabels map[string]string) *ObjectWrapper {
+		return &ObjectWrapper{
+			Raw: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    podLabels,
+				},
+				Status: corev1.PodStatus{
+					PodIP: "1.1.1.1",
+				},
+			},
+		}
+	}
+	podCache.metaStore.Items["prod/web-1"] = newPod("prod", "web-1", map[string]string{"app": "web", "tier": "frontend"})
+	podCache.metaStore.Items["prod/web-2"] = newPod("prod", "web-2", map[string]string{"app": "web", "tier": "canary"})
+	podCache.metaStore.Items["prod/db-1"] = newPod("prod", "db-1", map[string]string{"app": "db"})
+	podCache.metaStore.Items["test/web-1"] = newPod("test", "web-1", map[string]string{"app": "web"})
+	deleted := newPod("prod", "web-3", map[string]string{"app": "web"})
+	deleted.Deleted = true
+	podCache.metaStore.Items["prod/web-3"] = deleted
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+
+	doSelect := func(body selectRequestBody) (int, map[string]*PodMetadata) {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/metadata/pods/select", bytes.NewReader(data))
+		rec := httptest.NewRecorder()
+		handler.handlePodMetaBySelector(rec, req)
+		result := make(map[string]*PodMetadata)
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		}
+		return rec.Code, result
+	}
+
+	code, result := doSelect(selectRequestBody{Namespace: "prod", LabelSelector: "app=web"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "web-1", result["prod/web-1"].PodName)
+	assert.Equal(t, "web-2", result["prod/web-2"].PodName)
+
+	_, result = doSelect(selectRequestBody{LabelSelector: "app=web,tier notin (canary)"})
+	assert.Len(t, result, 2)
+	assert.Contains(t, result, "prod/web-1")
+	assert.Contains(t, result, "test/web-1")
+
+	_, result = doSelect(selectRequestBody{Namespace: "prod"})
+	assert.Len(t, result, 3)
+
+	_, result = doSelect(selectRequestBody{Namespace: "prod", Limit: 1})
+	assert.Len(t, result, 1)
+
+	code, _ = doSelect(selectRequestBody{LabelSelector: "app in (web"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGetCommonPodMetadataNamespace(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	addOwnerTestObject(manager, NAMESPACE, "/finance", &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "finance",
+			Labels: map[string]string{"team": "billing", "cost-center": "cc-42"},
+			Annotations: map[string]string{
+				"owner": "billing@example.com",
+				"kubectl.kubernetes.io/last-applied-configuration": "",
+			},
+		},
+	})
+	handler := newMetadataHandler(manager)
+	podMetadata := handler.getCommonPodMetadata(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "invoice", Namespace: "finance", Labels: map[string]string{"app": "invoice"}},
+	})
+	assert.Equal(t, map[string]string{"app": "invoice"}, podMetadata.Labels)
+	assert.Equal(t, map[string]string{"team": "billing", "cost-center": "cc-42"}, podMetadata.NamespaceLabels)
+	assert.Equal(t, map[string]string{"owner": "billing@example.com"}, podMetadata.NamespaceAnnotations)
+
+	podMetadata = handler.getCommonPodMetadata(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "unknown"}})
+	assert.Nil(t, podMetadata.NamespaceLabels)
+	assert.Nil(t, podMetadata.NamespaceAnnotations)
+}
+
+func TestHandlePodMetaBatch(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	podCache.metaStore.Items["prod/web"] = &ObjectWrapper{
+		Raw: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"},
+			Status: corev1.PodStatus{
+				PodIP:             "10.1.0.5",
+				HostIP:            "192.168.0.1",
+				ContainerStatuses: []corev1.ContainerStatus{{ContainerID: "containerd://abc"}},
+			},
+		},
+	}
+	for _, key := range []string{"10.1.0.5", "abc", addHostIPIndexPrefex("192.168.0.1")} {
+		podCache.metaStore.Index[key] = NewIndexItem()
+		podCache.metaStore.Index[key].Add("prod/web")
+	}
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+
+	body, err := json.Marshal(batchRequestBody{
+		IPPorts:      []string{"10.1.0.5", "10.1.0.6"},
+		ContainerIDs: []string{"abc"},
+		HostIPs:      []string{"192.168.0.1"},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/metadata/batch", bytes.NewReader(body))
+	req.Header.Set("Accept-Encoding", "deflate, gzip;q=0.8")
+	require.True(t, acceptsGzip(req))
+	rec := httptest.NewRecorder()
+	gzipWriter := newGzipResponseWriter(rec)
+	handler.handlePodMetaBatch(gzipWriter, req)
+	gzipWriter.Close()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	var result batchResponse[*PodMetadata]
+	require.NoError(t, json.NewDecoder(reader).Decode(&result))
+	require.Len(t, result.IPPorts, 1)
+	assert.Equal(t, "web", result.IPPorts["10.1.0.5"].PodName)
+	require.Len(t, result.ContainerIDs, 1)
+	assert.Equal(t, "web", result.ContainerIDs["abc"].PodName)
+	require.Len(t, result.HostIPs, 1)
+	assert.Equal(t, "web", result.HostIPs["10.1.0.5"].PodName)
+
+	// plain json without Accept-Encoding
+	req = httptest.NewRequest(http.MethodPost, "/metadata/batch", bytes.NewReader(body))
+	require.False(t, acceptsGzip(req))
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaBatch(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+}
+
+func addIndexedTestObject(cache *k8sMetaCache, key string, obj *ObjectWrapper) {
+	cache.metaStore.Items[key] = obj
+	for _, idxKey := range cache.metaStore.getIdxKeys(obj) {
+		if _, ok := cache.metaStore.Index[idxKey]; !ok {
+			cache.metaStore.Index[idxKey] = NewIndexItem()
+		}
+		cache.metaStore.Index[idxKey].Add(key)
+	}
+}
+
+func TestHandlePodMetaByWorkload(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	isController := true
+	replicaSetCache := newK8sMetaCache(make(chan struct{}), REPLICASET)
+	addIndexedTestObject(replicaSetCache, "prod/web-abc", &ObjectWrapper{Raw: &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Deployment", Name: "web", Controller: &isController},
+		}},
+	}})
+	manager.cacheMap[REPLICASET] = replicaSetCache
+	manager.cacheMap[JOB] = newK8sMetaCache(make(chan struct{}), JOB)
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	newPod := func(name, ownerKind, ownerName string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: ownerKind, Name: ownerName, Controller: &isController},
+		}}}
+	}
+	addIndexedTestObject(podCache, "prod/web-abc-1", &ObjectWrapper{Raw: newPod("web-abc-1", "ReplicaSet", "web-abc")})
+	addIndexedTestObject(podCache, "prod/web-abc-2", &ObjectWrapper{Raw: newPod("web-abc-2", "ReplicaSet", "web-abc"), Deleted: true})
+	addIndexedTestObject(podCache, "prod/db-0", &ObjectWrapper{Raw: newPod("db-0", "StatefulSet", "db")})
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+
+	body, err := json.Marshal(requestBody{Keys: []string{"deployment/prod/web", "StatefulSet/prod/db", "deployment/test/web"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handlePodMetaByWorkload(rec, httptest.NewRequest(http.MethodPost, "/metadata/workload/pods", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result map[string][]*PodMetadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result["deployment/prod/web"], 1)
+	assert.Equal(t, "web-abc-1", result["deployment/prod/web"][0].PodName)
+	assert.Equal(t, DEPLOYMENT, result["deployment/prod/web"][0].WorkloadKind)
+	require.Len(t, result["StatefulSet/prod/db"], 1)
+	assert.Equal(t, "db-0", result["StatefulSet/prod/db"][0].PodName)
+	assert.Empty(t, result["deployment/test/web"])
+
+	body, err = json.Marshal(requestBody{Keys: []string{"prod/web"}})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaByWorkload(rec, httptest.NewRequest(http.MethodPost, "/metadata/workload/pods", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandlePodMetaByContainerName(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	newPod := func(name string, created int64, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod", CreationTimestamp: metav1.Unix(created, 0)}}
+		for _, container := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		}
+		return pod
+	}
+	addIndexedTestObject(podCache, "prod/web-abc-1", &ObjectWrapper{Raw: newPod("web-abc-1", 100, "app", "sidecar")})
+	addIndexedTestObject(podCache, "prod/web-abc-2", &ObjectWrapper{Raw: newPod("web-abc-2", 200, "app", "sidecar")})
+	addIndexedTestObject(podCache, "prod/web-abc-3", &ObjectWrapper{Raw: newPod("web-abc-3", 300, "app"), Deleted: true})
+	addIndexedTestObject(podCache, "prod/web", &ObjectWrapper{Raw: newPod("web", 50, "app")})
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+
+	body, err := json.Marshal(requestBody{Keys: []string{"prod/web-abc-1/app", "prod/web-abc/app", "prod/web/app", "prod/web/sidecar", "test/web/app", "prod/db/app"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handlePodMetaByContainerName(rec, httptest.NewRequest(http.MethodPost, "/metadata/containername", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result map[string]*PodMetadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Len(t, result, 4)
+	assert.Equal(t, "web-abc-1", result["prod/web-abc-1/app"].PodName)
+	// the newest living pod is preferred among the pods matched by prefix
+	assert.Equal(t, "web-abc-2", result["prod/web-abc/app"].PodName)
+	// the exact name is preferred
+	assert.Equal(t, "web", result["prod/web/app"].PodName)
+	assert.Equal(t, "web-abc-2", result["prod/web/sidecar"].PodName)
+
+	body, err = json.Marshal(requestBody{Keys: []string{"prod/web"}})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaByContainerName(rec, httptest.NewRequest(http.MethodPost, "/metadata/containername", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetCommonPodMetadataContainers(t *testing.T) {
+	handler := newMetadataHandler(GetMetaManagerInstance())
+	podMetadata := handler.getCommonPodMetadata(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod", Annotations: map[string]string{
+			"kubectl.kubernetes.io/last-applied-configuration": "",
+			"sls.aliyun.com/logstore":                          "web-log",
+		}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Image: "web:1.0", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			}},
+			{Name: "sidecar", Image: "proxy:2.0"},
+		}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "app", ContainerID: "containerd://abc", RestartCount: 3},
+		}},
+	})
+	assert.Equal(t, map[string]string{"sls.aliyun.com/logstore": "web-log"}, podMetadata.Annotations)
+	assert.Equal(t, []*PodContainerMetadata{
+		{
+			Name:         "app",
+			Image:        "web:1.0",
+			ContainerID:  "abc",
+			Runtime:      "containerd",
+			RestartCount: 3,
+			Requests:     map[string]string{"cpu": "250m"},
+			Limits:       map[string]string{"memory": "512Mi"},
+		},
+		{Name: "sidecar", Image: "proxy:2.0"},
+	}, podMetadata.Containers)
+}
+
+func TestListenAddress(t *testing.T) {
+	assert.Equal(t, ":9000", listenAddress(9000))
+	t.Setenv(bindAddressEnv, "::")
+	assert.Equal(t, "[::]:9000", listenAddress(9000))
+	t.Setenv(bindAddressEnv, "0.0.0.0")
+	assert.Equal(t, "0.0.0.0:9000", listenAddress(9000))
+}
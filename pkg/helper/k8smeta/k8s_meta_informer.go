@@ -0,0 +1,83 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	resyncPeriodEnv  = "KUBERNETES_METADATA_RESYNC_PERIOD_SEC"
+	listPageSizeEnv  = "KUBERNETES_METADATA_LIST_PAGE_SIZE"
+	fieldSelectorEnv = "KUBERNETES_METADATA_FIELD_SELECTOR"
+
+	defaultPodResyncPeriod = 24 * time.Hour
+	defaultResyncPeriod    = time.Hour
+)
+
+// informerOptions tunes how the informer of a resource lists and watches the objects.
+type informerOptions struct {
+	resyncPeriod time.Duration
+	// listPageSize is the limit of a list request, 0 lists all the objects at once
+	listPageSize int64
+	// fieldSelector restricts the watched objects, e.g. spec.nodeName=node-1 for the pods on a node
+	fieldSelector string
+}
+
+// newInformerOptionsFromEnv reads the informer options of the resource. KUBERNETES_METADATA_<RESOURCE>_RESYNC_PERIOD_SEC
+// and KUBERNETES_METADATA_<RESOURCE>_LIST_PAGE_SIZE override the ones of all resources for the resource, and the field
+// selector is only configured by KUBERNETES_METADATA_<RESOURCE>_FIELD_SELECTOR since the fields differ by resource.
+func newInformerOptionsFromEnv(resourceType string) informerOptions {
+	options := informerOptions{
+		resyncPeriod: defaultResyncPeriod,
+		listPageSize: int64(intFromEnv(listPageSizeEnv)),
+	}
+	if resourceType == POD {
+		options.resyncPeriod = defaultPodResyncPeriod
+	}
+	if period := intFromEnv(resyncPeriodEnv); period > 0 {
+		options.resyncPeriod = time.Duration(period) * time.Second
+	}
+	if period := intFromEnv(resourceEnv(resourceType, resyncPeriodEnv)); period > 0 {
+		options.resyncPeriod = time.Duration(period) * time.Second
+	}
+	if pageSize := intFromEnv(resourceEnv(resourceType, listPageSizeEnv)); pageSize > 0 {
+		options.listPageSize = int64(pageSize)
+	}
+	if selector := os.Getenv(resourceEnv(resourceType, fieldSelectorEnv)); selector != "" {
+		if _, err := fields.ParseSelector(selector); err != nil {
+			logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "invalid field selector, ignore it", resourceType, "selector", selector, "error", err)
+		} else {
+			options.fieldSelector = selector
+		}
+	}
+	return options
+}
+
+func (o informerOptions) tweakListOptions(options *metav1.ListOptions) {
+	if o.listPageSize > 0 {
+		options.Limit = o.listPageSize
+	}
+	if o.fieldSelector != "" {
+		options.FieldSelector = o.fieldSelector
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewInformerOptionsFromEnv(t *testing.T) {
+	assert.Equal(t, informerOptions{resyncPeriod: defaultPodResyncPeriod}, newInformerOptionsFromEnv(POD))
+	assert.Equal(t, informerOptions{resyncPeriod: defaultResyncPeriod}, newInformerOptionsFromEnv(NODE))
+
+	t.Setenv(resyncPeriodEnv, "600")
+	t.Setenv(listPageSizeEnv, "500")
+	t.Setenv("KUBERNETES_METADATA_POD_RESYNC_PERIOD_SEC", "7200")
+	t.Setenv("KUBERNETES_METADATA_POD_LIST_PAGE_SIZE", "1000")
+	t.Setenv("KUBERNETES_METADATA_POD_FIELD_SELECTOR", "spec.nodeName=node-1")
+	t.Setenv("KUBERNETES_METADATA_NODE_FIELD_SELECTOR", "metadata.name")
+	pod := newInformerOptionsFromEnv(POD)
+	assert.Equal(t, informerOptions{resyncPeriod: 2 * time.Hour, listPageSize: 1000, fieldSelector: "spec.nodeName=node-1"}, pod)
+	// the invalid selector is ignored
+	assert.Equal(t, informerOptions{resyncPeriod: 10 * time.Minute, listPageSize: 500}, newInformerOptionsFromEnv(NODE))
+
+	options := metav1.ListOptions{LabelSelector: "app=web"}
+	pod.tweakListOptions(&options)
+	assert.Equal(t, metav1.ListOptions{LabelSelector: "app=web", FieldSelector: "spec.nodeName=node-1", Limit: 1000}, options)
+}
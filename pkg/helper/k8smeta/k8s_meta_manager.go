@@ -2,12 +2,15 @@ package k8smeta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -30,9 +33,14 @@ type MetaCache interface {
 	List() []*ObjectWrapper
 	Filter(filterFunc func(*ObjectWrapper) bool, limit int) []*ObjectWrapper
 	RegisterSendFunc(key string, sendFunc SendFunc, interval int)
+	RegisterWatchFunc(key string, sendFunc SendFunc)
+	RegisterListWatchFunc(key string, sendFunc SendFunc) ([]*ObjectWrapper, <-chan struct{})
 	UnRegisterSendFunc(key string)
 	init(*kubernetes.Clientset)
 	watch(stopCh <-chan struct{})
+	dump() []json.RawMessage
+	restore(items []json.RawMessage) int
+	syncStatus() *cacheSyncStatus
 }
 
 type FlushCh struct {
@@ -45,12 +53,16 @@ type MetaManager struct {
 	stopCh    chan struct{}
 
 	ready atomic.Bool
+	// snapshotLoaded is set if the caches are restored from the snapshot, so the lookups are served before the informers sync
+	snapshotLoaded atomic.Bool
 
 	metadataHandler *metadataHandler
 	cacheMap        map[string]MetaCache
+	customResources map[string]schema.GroupVersionResource
 	linkGenerator   *LinkGenerator
 	linkRegisterMap map[string][]string
 	registerLock    sync.RWMutex
+	handlerSeq      atomic.Int64
 
 	// self metrics
 	projectNames       map[string]int
@@ -60,9 +72,8 @@ type MetaManager struct {
 	deleteEventCount   pipeline.CounterMetric
 	cacheResourceGauge pipeline.GaugeMetric
 	queueSizeGauge     pipeline.GaugeMetric
-	httpRequestCount   pipeline.CounterMetric
-	httpAvgDelayMs     pipeline.CounterMetric
-	httpMaxDelayMs     pipeline.GaugeMetric
+	httpRejectedCount  pipeline.CounterMetric
+	httpCoalescedCount pipeline.CounterMetric
 }
 
 func GetMetaManagerInstance() *MetaManager {
@@ -70,11 +81,12 @@ func GetMetaManagerInstance() *MetaManager {
 		metaManager = &MetaManager{
 			stopCh: make(chan struct{}),
 		}
-		metaManager.metadataHandler = newMetadataHandler(metaManager)
 		metaManager.cacheMap = make(map[string]MetaCache)
 		for _, resource := range AllResources {
 			metaManager.cacheMap[resource] = newK8sMetaCache(metaManager.stopCh, resource)
 		}
+		metaManager.customResources = make(map[string]schema.GroupVersionResource)
+		metaManager.metadataHandler = newMetadataHandler(metaManager)
 		metaManager.linkGenerator = NewK8sMetaLinkGenerator(metaManager.cacheMap)
 		metaManager.linkRegisterMap = make(map[string][]string)
 		metaManager.projectNames = make(map[string]int)
@@ -99,6 +111,15 @@ func (m *MetaManager) Init(configPath string) (err error) {
 		return err
 	}
 	m.clientset = clientset
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	m.initCustomResources(clientset.Discovery(), dynamicClient)
+	snapshot := newMetaSnapshotterFromEnv(m)
+	snapshot.load()
+	stopCh := m.stopCh
+	newMetaWebhookNotifierFromEnv(m).start(stopCh)
 
 	m.metricRecord = pipeline.MetricsRecord{}
 	m.addEventCount = helper.NewCounterMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaAddEventTotal)
@@ -106,9 +127,8 @@ func (m *MetaManager) Init(configPath string) (err error) {
 	m.deleteEventCount = helper.NewCounterMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaDeleteEventTotal)
 	m.cacheResourceGauge = helper.NewGaugeMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaCacheSize)
 	m.queueSizeGauge = helper.NewGaugeMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaQueueSize)
-	m.httpRequestCount = helper.NewCounterMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaHTTPRequestTotal)
-	m.httpAvgDelayMs = helper.NewAverageMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaHTTPAvgDelayMs)
-	m.httpMaxDelayMs = helper.NewMaxMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaHTTPMaxDelayMs)
+	m.httpRejectedCount = helper.NewCounterMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaHTTPRejectedTotal)
+	m.httpCoalescedCount = helper.NewCounterMetricAndRegister(&m.metricRecord, helper.MetricRunnerK8sMetaHTTPCoalescedTotal)
 
 	go func() {
 		startTime := time.Now()
@@ -117,6 +137,7 @@ func (m *MetaManager) Init(configPath string) (err error) {
 		}
 		m.ready.Store(true)
 		logger.Info(context.Background(), "init k8s meta manager", "success", "latancy (ms)", fmt.Sprintf("%d", time.Since(startTime).Milliseconds()))
+		snapshot.run(stopCh)
 	}()
 	return nil
 }
@@ -130,6 +151,25 @@ func (m *MetaManager) IsReady() bool {
 	return m.ready.Load()
 }
 
+// isServing returns whether the lookups can be answered, from the synced informers or the snapshot restored on start.
+func (m *MetaManager) isServing() bool {
+	return m.ready.Load() || m.snapshotLoaded.Load()
+}
+
+// GetPodMetadataByIP returns the metadata of the pod which owns the ip,
+// nil is returned if the pod is unknown or the manager is not ready yet.
+func (m *MetaManager) GetPodMetadataByIP(ip string) *PodMetadata {
+	if !m.isServing() {
+		return nil
+	}
+	ip = normalizeIP(ip)
+	objs := m.cacheMap[POD].Get([]string{ip})
+	if len(objs) == 0 {
+		return nil
+	}
+	return m.metadataHandler.findPodByPodIPPort(ip, 0, objs)
+}
+
 func (m *MetaManager) RegisterSendFunc(projectName, configName, resourceType string, sendFunc SendFunc, interval int) {
 	if cache, ok := m.cacheMap[resourceType]; ok {
 		cache.RegisterSendFunc(configName, func(events []*K8sMetaEvent) {
@@ -231,6 +271,7 @@ func GetMetaManagerMetrics() []map[string]string {
 
 func (m *MetaManager) runServer() {
 	go m.metadataHandler.K8sServerRun(m.stopCh)
+	go m.metadataHandler.K8sGRPCServerRun(m.stopCh)
 }
 
 func isEntity(resourceType string) bool {
@@ -0,0 +1,265 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	// ownerChainEnv lists the kinds that are resolved to their owners, e.g. "replicaset,job,statefulset"
+	// reports the custom controller owning a statefulset as the workload of its pods.
+	ownerChainEnv = "KUBERNETES_METADATA_OWNER_CHAIN"
+	// ownerResolutionEnv sets how the parent of each kind is resolved as kind=strategy pairs, and overrides the owner
+	// chain, e.g. "job=keep,replicationcontroller=name:deploymentconfig". The strategies are owner, keep and
+	// name:<parentKind>, see ownerStrategy.
+	ownerResolutionEnv = "KUBERNETES_METADATA_OWNER_RESOLUTION"
+	// ownerKeepImmediateEnv reports the immediate owner of the pod instead of the last resolved one if the resolution
+	// fails, e.g. the replicaset of a pod rather than a deployment whose owner is not synced yet.
+	ownerKeepImmediateEnv = "KUBERNETES_METADATA_OWNER_KEEP_IMMEDIATE"
+	// ownerChainMaxDepth stops the resolution on cyclic owner references.
+	ownerChainMaxDepth = 8
+	ownerCacheTTL      = 10 * time.Minute
+	ownerCacheMaxSize  = 10000
+)
+
+const (
+	ownerStrategyOwner      = "owner"
+	ownerStrategyKeep       = "keep"
+	ownerStrategyNamePrefix = "name:"
+)
+
+var defaultOwnerChain = []string{REPLICASET, JOB}
+
+// ownerStrategy resolves the parent of a kind through the controller owner reference of the cached object if
+// parentKind is empty. Otherwise the parent of parentKind is named by trimming the last "-" segment of the name,
+// e.g. the replication controller app-1 of the deployment config app, which works for the kinds not cached.
+type ownerStrategy struct {
+	parentKind string
+}
+
+type ownerReference struct {
+	kind string
+	name string
+}
+
+type ownerCacheEntry struct {
+	owner    ownerReference
+	expireAt time.Time
+}
+
+// ownerResolver walks up the owner references of the cached objects until the owner kind is not in the chain,
+// e.g. pod -> replicaset -> deployment and pod -> job -> cronjob. Only the kinds cached by the meta manager
+// could be walked through, other kinds in the chain are ignored.
+type ownerResolver struct {
+	metaManager   *MetaManager
+	chain         map[string]ownerStrategy
+	keepImmediate bool
+
+	lock  sync.Mutex
+	cache map[string]*ownerCacheEntry
+}
+
+func newOwnerResolver(metaManager *MetaManager, chainConfig, resolutionConfig string) *ownerResolver {
+	kinds := defaultOwnerChain
+	if chainConfig != "" {
+		kinds = strings.Split(chainConfig, ",")
+	}
+	r := &ownerResolver{
+		metaManager: metaManager,
+		chain:       make(map[string]ownerStrategy, len(kinds)),
+		cache:       make(map[string]*ownerCacheEntry),
+	}
+	for _, kind := range kinds {
+		r.setStrategy(strings.ToLower(strings.TrimSpace(kind)), ownerStrategyOwner)
+	}
+	for _, item := range strings.Split(resolutionConfig, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		kind, strategy, ok := strings.Cut(item, "=")
+		if !ok {
+			logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "invalid owner resolution, kind=strategy is expected", item)
+			continue
+		}
+		r.setStrategy(strings.ToLower(strings.TrimSpace(kind)), strings.TrimSpace(strategy))
+	}
+	return r
+}
+
+func newOwnerResolverFromEnv(metaManager *MetaManager) *ownerResolver {
+	r := newOwnerResolver(metaManager, os.Getenv(ownerChainEnv), os.Getenv(ownerResolutionEnv))
+	r.keepImmediate, _ = strconv.ParseBool(os.Getenv(ownerKeepImmediateEnv))
+	return r
+}
+
+func (r *ownerResolver) setStrategy(kind, strategy string) {
+	if kind == "" {
+		return
+	}
+	switch {
+	case strategy == ownerStrategyOwner:
+		if _, ok := r.metaManager.cacheMap[kind]; !ok {
+			logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "owner kind is not cached, ignore it in the owner chain", kind)
+			return
+		}
+		r.chain[kind] = ownerStrategy{}
+	case strategy == ownerStrategyKeep:
+		delete(r.chain, kind)
+	case strings.HasPrefix(strategy, ownerStrategyNamePrefix) && len(strategy) > len(ownerStrategyNamePrefix):
+		r.chain[kind] = ownerStrategy{parentKind: strings.ToLower(strings.TrimPrefix(strategy, ownerStrategyNamePrefix))}
+	default:
+		logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "unknown owner resolution strategy, ignore it", kind, "strategy", strategy)
+	}
+}
+
+// resolve returns the top owner of the object. The result is cached only if all the owners in the chain are
+// found, so that the objects not synced yet are resolved again by the next query.
+func (r *ownerResolver) resolve(namespace string, owner ownerReference) ownerReference {
+	if _, ok := r.chain[owner.kind]; !ok {
+		return owner
+	}
+	cacheKey := owner.kind + "/" + generateNameWithNamespaceKey(namespace, owner.name)
+	now := time.Now()
+	r.lock.Lock()
+	entry, ok := r.cache[cacheKey]
+	r.lock.Unlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.owner
+	}
+
+	current := owner
+	for depth := 0; depth < ownerChainMaxDepth; depth++ {
+		strategy, ok := r.chain[current.kind]
+		if !ok {
+			break
+		}
+		next, found := r.getParent(namespace, current, strategy)
+		if !found {
+			if r.keepImmediate {
+				return owner
+			}
+			return current
+		}
+		if next == nil {
+			break
+		}
+		current = *next
+	}
+
+	r.lock.Lock()
+	if len(r.cache) >= ownerCacheMaxSize {
+		r.cache = make(map[string]*ownerCacheEntry)
+	}
+	r.cache[cacheKey] = &ownerCacheEntry{owner: current, expireAt: now.Add(ownerCacheTTL)}
+	r.lock.Unlock()
+	return current
+}
+
+// ownedPods walks down from the workload through the owned objects of the kinds in the chain, e.g. deployment ->
+// replicaset -> pod, and returns the pods whose owner resolves to the workload.
+func (r *ownerResolver) ownedPods(namespace string, workload ownerReference) []*ObjectWrapper {
+	pods := make([]*ObjectWrapper, 0)
+	owners := []ownerReference{workload}
+	visited := map[ownerReference]struct{}{workload: {}}
+	for depth := 0; depth < ownerChainMaxDepth && len(owners) > 0; depth++ {
+		keys := make([]string, 0, len(owners))
+		for _, owner := range owners {
+			keys = append(keys, addOwnerIndexPrefix(owner.kind, namespace, owner.name))
+		}
+		owners = owners[:0:0]
+		if podCache, ok := r.metaManager.cacheMap[POD]; ok {
+			for _, objs := range podCache.Get(keys) {
+				for _, obj := range objs {
+					if !obj.Deleted {
+						pods = append(pods, obj)
+					}
+				}
+			}
+		}
+		for kind, strategy := range r.chain {
+			if strategy.parentKind != "" {
+				// the objects resolved by name might not be cached
+				continue
+			}
+			for _, objs := range r.metaManager.cacheMap[kind].Get(keys) {
+				for _, obj := range objs {
+					accessor, err := meta.Accessor(obj.Raw)
+					if obj.Deleted || err != nil {
+						continue
+					}
+					owner := ownerReference{kind: kind, name: accessor.GetName()}
+					if _, ok := visited[owner]; !ok {
+						visited[owner] = struct{}{}
+						owners = append(owners, owner)
+					}
+				}
+			}
+		}
+	}
+	return pods
+}
+
+func (r *ownerResolver) getParent(namespace string, object ownerReference, strategy ownerStrategy) (*ownerReference, bool) {
+	if strategy.parentKind == "" {
+		return r.getOwner(namespace, object)
+	}
+	idx := strings.LastIndex(object.name, "-")
+	if idx <= 0 {
+		return nil, false
+	}
+	return &ownerReference{kind: strategy.parentKind, name: object.name[:idx]}, true
+}
+
+// getOwner returns the controller owner of the object, nil is returned if the object has no owner.
+func (r *ownerResolver) getOwner(namespace string, object ownerReference) (*ownerReference, bool) {
+	key := generateNameWithNamespaceKey(namespace, object.name)
+	objs := r.metaManager.cacheMap[object.kind].Get([]string{key})
+	for _, obj := range objs[key] {
+		accessor, err := meta.Accessor(obj.Raw)
+		if err != nil {
+			continue
+		}
+		reference := controllerOwner(accessor.GetOwnerReferences())
+		if reference == nil {
+			return nil, true
+		}
+		return &ownerReference{kind: strings.ToLower(reference.Kind), name: reference.Name}, true
+	}
+	return nil, false
+}
+
+// controllerOwner prefers the owner reference of the managing controller, and falls back to the first one.
+func controllerOwner(references []metav1.OwnerReference) *metav1.OwnerReference {
+	if len(references) == 0 {
+		return nil
+	}
+	for i := range references {
+		if references[i].Controller != nil && *references[i].Controller {
+			return &references[i]
+		}
+	}
+	return &references[0]
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func addOwnerTestObject(manager *MetaManager, resourceType, key string, raw interface{}) {
+	metaCache := newK8sMetaCache(make(chan struct{}), resourceType)
+	metaCache.metaStore.Items[key] = &ObjectWrapper{Raw: raw}
+	metaCache.metaStore.Index[key] = NewIndexItem()
+	metaCache.metaStore.Index[key].Add(key)
+	manager.cacheMap[resourceType] = metaCache
+}
+
+func TestOwnerResolver(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	isController := true
+	addOwnerTestObject(manager, JOB, "prod/report-1", &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-1", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Workflow", Name: "not-controller"},
+			{Kind: "CronJob", Name: "report", Controller: &isController},
+		}},
+	})
+	addOwnerTestObject(manager, REPLICASET, "prod/web-abc", &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Deployment", Name: "web"},
+		}},
+	})
+	addOwnerTestObject(manager, STATEFULSET, "prod/db", &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "CloneSet", Name: "db-clone"},
+		}},
+	})
+
+	resolver := newOwnerResolver(manager, "", "")
+	assert.Equal(t, ownerReference{kind: CRONJOB, name: "report"}, resolver.resolve("prod", ownerReference{kind: JOB, name: "report-1"}))
+	assert.Equal(t, ownerReference{kind: DEPLOYMENT, name: "web"}, resolver.resolve("prod", ownerReference{kind: REPLICASET, name: "web-abc"}))
+	assert.Equal(t, ownerReference{kind: STATEFULSET, name: "db"}, resolver.resolve("prod", ownerReference{kind: STATEFULSET, name: "db"}))
+	// the replicaset not synced yet is not cached
+	assert.Equal(t, ownerReference{kind: REPLICASET, name: "api-abc"}, resolver.resolve("prod", ownerReference{kind: REPLICASET, name: "api-abc"}))
+	assert.Len(t, resolver.cache, 2)
+
+	// the resolution is cached
+	manager.cacheMap[JOB] = newK8sMetaCache(make(chan struct{}), JOB)
+	assert.Equal(t, ownerReference{kind: CRONJOB, name: "report"}, resolver.resolve("prod", ownerReference{kind: JOB, name: "report-1"}))
+
+	resolver = newOwnerResolver(manager, "replicaset, StatefulSet, cloneset", "")
+	assert.Equal(t, ownerReference{kind: "cloneset", name: "db-clone"}, resolver.resolve("prod", ownerReference{kind: STATEFULSET, name: "db"}))
+	assert.Equal(t, ownerReference{kind: JOB, name: "report-1"}, resolver.resolve("prod", ownerReference{kind: JOB, name: "report-1"}))
+	assert.NotContains(t, resolver.chain, "cloneset")
+}
+
+func TestOwnerResolution(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	addOwnerTestObject(manager, JOB, "prod/report-1", &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-1", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "CronJob", Name: "report"},
+		}},
+	})
+	addOwnerTestObject(manager, REPLICASET, "prod/web-abc", &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Deployment", Name: "web"},
+		}},
+	})
+	manager.cacheMap[DEPLOYMENT] = newK8sMetaCache(make(chan struct{}), DEPLOYMENT)
+
+	resolver := newOwnerResolver(manager, "", "job=keep, ReplicationController=name:DeploymentConfig,deployment=owner,cloneset=owner,statefulset=unknown,invalid")
+	assert.Equal(t, map[string]ownerStrategy{
+		REPLICASET:              {},
+		DEPLOYMENT:              {},
+		"replicationcontroller": {parentKind: "deploymentconfig"},
+	}, resolver.chain)
+	assert.Equal(t, ownerReference{kind: JOB, name: "report-1"}, resolver.resolve("prod", ownerReference{kind: JOB, name: "report-1"}))
+	assert.Equal(t, ownerReference{kind: "deploymentconfig", name: "app"}, resolver.resolve("prod", ownerReference{kind: "replicationcontroller", name: "app-1"}))
+	assert.Equal(t, ownerReference{kind: "replicationcontroller", name: "app"}, resolver.resolve("prod", ownerReference{kind: "replicationcontroller", name: "app"}))
+	// the deployment is not synced yet, the last resolved owner is reported by default
+	assert.Equal(t, ownerReference{kind: DEPLOYMENT, name: "web"}, resolver.resolve("prod", ownerReference{kind: REPLICASET, name: "web-abc"}))
+
+	resolver.keepImmediate = true
+	assert.Equal(t, ownerReference{kind: REPLICASET, name: "web-abc"}, resolver.resolve("prod", ownerReference{kind: REPLICASET, name: "web-abc"}))
+}
+
+func TestGetCommonPodMetadataOwner(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	addOwnerTestObject(manager, JOB, "prod/report-1", &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-1", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "CronJob", Name: "report"},
+		}},
+	})
+	handler := newMetadataHandler(manager)
+	podMetadata := handler.getCommonPodMetadata(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "report-1-xyz", Namespace: "prod", OwnerReferences: []metav1.OwnerReference{
+			{Kind: "Job", Name: "report-1"},
+		}},
+	})
+	assert.Equal(t, CRONJOB, podMetadata.WorkloadKind)
+	assert.Equal(t, "report", podMetadata.WorkloadName)
+
+	podMetadata = handler.getCommonPodMetadata(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "static", Namespace: "prod"}})
+	assert.Empty(t, podMetadata.WorkloadKind)
+	assert.Empty(t, podMetadata.WorkloadName)
+}
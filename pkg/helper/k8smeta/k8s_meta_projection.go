@@ -0,0 +1,95 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// podMetadataFields maps the json names of the pod metadata fields to their indexes.
+var podMetadataFields = func() map[string]int {
+	fields := make(map[string]int)
+	podType := reflect.TypeOf(PodMetadata{})
+	for i := 0; i < podType.NumField(); i++ {
+		name, _, _ := strings.Cut(podType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+type podField struct {
+	name  string
+	index int
+}
+
+// podFieldProjection selects the pod metadata fields serialized in the responses by their json names, e.g. only
+// the labels and the workload of the pods, nil selects all the fields.
+type podFieldProjection []podField
+
+func newPodFieldProjection(fields []string) (podFieldProjection, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	projection := make(podFieldProjection, 0, len(fields))
+	selected := make(map[string]struct{}, len(fields))
+	for _, name := range fields {
+		index, ok := podMetadataFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown pod metadata field %q", name)
+		}
+		if _, ok := selected[name]; ok {
+			continue
+		}
+		selected[name] = struct{}{}
+		projection = append(projection, podField{name: name, index: index})
+	}
+	return projection, nil
+}
+
+// project returns the pod metadata itself if all the fields are selected, otherwise a map of the selected fields.
+func (p podFieldProjection) project(podMetadata *PodMetadata) interface{} {
+	if p == nil || podMetadata == nil {
+		return podMetadata
+	}
+	value := reflect.ValueOf(podMetadata).Elem()
+	result := make(map[string]interface{}, len(p))
+	for _, field := range p {
+		result[field.name] = value.Field(field.index).Interface()
+	}
+	return result
+}
+
+func (p podFieldProjection) projectMap(metadata map[string]*PodMetadata) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(metadata))
+	for key, podMetadata := range metadata {
+		result[key] = p.project(podMetadata)
+	}
+	return result
+}
+
+func (p podFieldProjection) projectList(metadata []*PodMetadata) []interface{} {
+	result := make([]interface{}, 0, len(metadata))
+	for _, podMetadata := range metadata {
+		result = append(result, p.project(podMetadata))
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodFieldProjection(t *testing.T) {
+	podMetadata := &PodMetadata{
+		PodName:      "web",
+		WorkloadName: "web",
+		WorkloadKind: DEPLOYMENT,
+		Labels:       map[string]string{"app": "web"},
+		Envs:         map[string]string{"PATH": "/bin"},
+	}
+	projection, err := newPodFieldProjection(nil)
+	require.NoError(t, err)
+	assert.Same(t, podMetadata, projection.project(podMetadata))
+
+	projection, err = newPodFieldProjection([]string{"labels", "workloadName", "workloadKind", "labels", "podIP"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"labels":       map[string]string{"app": "web"},
+		"workloadName": "web",
+		"workloadKind": DEPLOYMENT,
+		"podIP":        "",
+	}, projection.project(podMetadata))
+	assert.Nil(t, projection.project(nil))
+
+	_, err = newPodFieldProjection([]string{"labels", "IsDeleted"})
+	assert.Error(t, err)
+}
+
+func TestHandlePodMetaWithFields(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	addIndexedTestObject(podCache, "prod/web", &ObjectWrapper{Raw: &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Env: []corev1.EnvVar{{Name: "PATH", Value: "/bin"}}},
+		}},
+		Status: corev1.PodStatus{PodIP: "10.1.0.5"},
+	}})
+	manager.cacheMap[POD] = podCache
+	handler := newMetadataHandler(manager)
+
+	body, err := json.Marshal(requestBody{Keys: []string{"10.1.0.5"}, Fields: []string{"podName", "labels"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handlePodMetaByIPPort(rec, httptest.NewRequest(http.MethodPost, "/metadata/ipport", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, map[string]interface{}{
+		"podName": "web",
+		"labels":  map[string]interface{}{"app": "web"},
+	}, result["10.1.0.5"])
+
+	body, err = json.Marshal(batchRequestBody{IPPorts: []string{"10.1.0.5"}, Fields: []string{"podIP"}})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaBatch(rec, httptest.NewRequest(http.MethodPost, "/metadata/batch", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ip": {"10.1.0.5": {"podIP": "10.1.0.5"}}}`, rec.Body.String())
+
+	body, err = json.Marshal(requestBody{Keys: []string{"10.1.0.5"}, Fields: []string{"env"}})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.handlePodMetaByIPPort(rec, httptest.NewRequest(http.MethodPost, "/metadata/ipport", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
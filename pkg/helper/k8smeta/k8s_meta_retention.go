@@ -0,0 +1,112 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"container/list"
+	"strings"
+)
+
+const (
+	deletedTTLEnv        = "KUBERNETES_METADATA_DELETED_TTL_SEC"
+	deletedMaxEntriesEnv = "KUBERNETES_METADATA_DELETED_MAX_ENTRIES"
+
+	defaultDeletedTTLSec = 120
+)
+
+// deletedRetention is how long the deleted objects of a resource stay queryable, and how many of them are kept.
+type deletedRetention struct {
+	ttlSec     int64
+	maxEntries int
+}
+
+// newDeletedRetentionFromEnv reads the retention of the resource, KUBERNETES_METADATA_<RESOURCE>_DELETED_TTL_SEC
+// and KUBERNETES_METADATA_<RESOURCE>_DELETED_MAX_ENTRIES override the ones of all resources for the resource.
+func newDeletedRetentionFromEnv(resourceType string) deletedRetention {
+	retention := deletedRetention{
+		ttlSec:     defaultDeletedTTLSec,
+		maxEntries: intFromEnv(deletedMaxEntriesEnv),
+	}
+	if ttl := intFromEnv(deletedTTLEnv); ttl > 0 {
+		retention.ttlSec = int64(ttl)
+	}
+	if ttl := intFromEnv(resourceEnv(resourceType, deletedTTLEnv)); ttl > 0 {
+		retention.ttlSec = int64(ttl)
+	}
+	if maxEntries := intFromEnv(resourceEnv(resourceType, deletedMaxEntriesEnv)); maxEntries > 0 {
+		retention.maxEntries = maxEntries
+	}
+	return retention
+}
+
+// resourceEnv inserts the resource type after the KUBERNETES_METADATA_ prefix of the env.
+func resourceEnv(resourceType, env string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(resourceType))
+	return strings.Replace(env, "KUBERNETES_METADATA_", "KUBERNETES_METADATA_"+name+"_", 1)
+}
+
+// deletedLRU keeps the keys of the deleted objects from the most to the least recently used.
+type deletedLRU struct {
+	maxEntries int
+	order      *list.List
+	elements   map[string]*list.Element
+}
+
+func newDeletedLRU(maxEntries int) *deletedLRU {
+	return &deletedLRU{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		elements:   make(map[string]*list.Element),
+	}
+}
+
+// add adds the key as the most recently used, and returns the keys evicted for exceeding the max entries.
+func (l *deletedLRU) add(key string) []string {
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToFront(e)
+		return nil
+	}
+	l.elements[key] = l.order.PushFront(key)
+	if l.maxEntries <= 0 {
+		return nil
+	}
+	var evicted []string
+	for l.order.Len() > l.maxEntries {
+		e := l.order.Back()
+		evictedKey := e.Value.(string)
+		l.order.Remove(e)
+		delete(l.elements, evictedKey)
+		evicted = append(evicted, evictedKey)
+	}
+	return evicted
+}
+
+func (l *deletedLRU) touch(key string) {
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *deletedLRU) remove(key string) {
+	if e, ok := l.elements[key]; ok {
+		l.order.Remove(e)
+		delete(l.elements, key)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeletedRetentionFromEnv(t *testing.T) {
+	assert.Equal(t, deletedRetention{ttlSec: defaultDeletedTTLSec}, newDeletedRetentionFromEnv(POD))
+
+	t.Setenv(deletedTTLEnv, "600")
+	t.Setenv(deletedMaxEntriesEnv, "100")
+	t.Setenv("KUBERNETES_METADATA_POD_DELETED_TTL_SEC", "3600")
+	t.Setenv("KUBERNETES_METADATA_INGRESS_DELETED_MAX_ENTRIES", "10")
+	assert.Equal(t, deletedRetention{ttlSec: 3600, maxEntries: 100}, newDeletedRetentionFromEnv(POD))
+	assert.Equal(t, deletedRetention{ttlSec: 600, maxEntries: 10}, newDeletedRetentionFromEnv(INGRESS))
+	assert.Equal(t, "KUBERNETES_METADATA_MY_CRD_DELETED_TTL_SEC", resourceEnv("my-crd", deletedTTLEnv))
+}
+
+func TestDeletedLRU(t *testing.T) {
+	l := newDeletedLRU(2)
+	assert.Empty(t, l.add("a"))
+	assert.Empty(t, l.add("b"))
+	l.touch("a")
+	assert.Equal(t, []string{"b"}, l.add("c"))
+	l.remove("a")
+	assert.Empty(t, l.add("d"))
+	assert.Equal(t, []string{"c"}, l.add("e"))
+
+	unlimited := newDeletedLRU(0)
+	for _, key := range []string{"a", "b", "c"} {
+		assert.Empty(t, unlimited.add(key))
+	}
+}
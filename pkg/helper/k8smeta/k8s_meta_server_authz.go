@@ -0,0 +1,261 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+// envMetadataAuthzFile is the path of the authorization policy, which allows each endpoint to the listed principals only.
+const envMetadataAuthzFile = "KUBERNETES_METADATA_AUTHZ_FILE"
+
+// anyPrincipal in the allow list of an endpoint allows all the identified principals.
+const anyPrincipal = "*"
+
+// authzPolicy is the json content of the authorization policy file.
+type authzPolicy struct {
+	Principals []authzPrincipal `json:"principals"`
+	Endpoints  []authzEndpoint  `json:"endpoints"`
+}
+
+// authzPrincipal is identified by the bearer token read from the token file, or by the verified client cert whose
+// common name or dns names contain one of the cert names.
+type authzPrincipal struct {
+	Name      string   `json:"name"`
+	TokenFile string   `json:"tokenFile"`
+	CertNames []string `json:"certNames"`
+}
+
+// authzEndpoint allows the principals to call the path, which is the path of the http endpoint or the full method of
+// the grpc service, e.g. /k8smeta.MetadataService/GetPodMetadataByHostIP. A path ending with * matches the prefix.
+type authzEndpoint struct {
+	Path  string   `json:"path"`
+	Allow []string `json:"allow"`
+}
+
+type authzRule struct {
+	path   string
+	prefix bool
+	allow  map[string]struct{}
+}
+
+// endpointAuthorizer identifies the principal of a request and checks it against the allow list of the endpoint.
+// The request to a path matched by no rule is denied.
+type endpointAuthorizer struct {
+	tokens    map[string]string // token -> principal
+	certNames map[string]string // cert name -> principal
+	rules     []*authzRule
+}
+
+// loadEndpointAuthorizer loads the policy from the file of envMetadataAuthzFile, nil is returned if it is not set.
+// The cert names are only trusted if the client certs are verified by the client CA.
+func loadEndpointAuthorizer(verifyClientCert bool) (*endpointAuthorizer, error) {
+	policyFile := os.Getenv(envMetadataAuthzFile)
+	if policyFile == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(filepath.Clean(policyFile))
+	if err != nil {
+		return nil, err
+	}
+	var policy authzPolicy
+	if err = json.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("invalid authorization policy %s: %v", policyFile, err)
+	}
+	a := &endpointAuthorizer{
+		tokens:    make(map[string]string),
+		certNames: make(map[string]string),
+	}
+	principals := make(map[string]struct{})
+	for _, principal := range policy.Principals {
+		if principal.Name == "" || principal.Name == anyPrincipal {
+			return nil, fmt.Errorf("invalid principal name %q", principal.Name)
+		}
+		if _, ok := principals[principal.Name]; ok {
+			return nil, fmt.Errorf("duplicate principal %s", principal.Name)
+		}
+		principals[principal.Name] = struct{}{}
+		if principal.TokenFile != "" {
+			token, err := os.ReadFile(filepath.Clean(principal.TokenFile))
+			if err != nil {
+				return nil, err
+			}
+			t := strings.TrimSpace(string(token))
+			if t == "" {
+				return nil, errors.New("empty token in " + principal.TokenFile)
+			}
+			if _, ok := a.tokens[t]; ok {
+				return nil, fmt.Errorf("principal %s shares the token with another one", principal.Name)
+			}
+			a.tokens[t] = principal.Name
+		}
+		if len(principal.CertNames) > 0 && !verifyClientCert {
+			return nil, fmt.Errorf("the cert names of principal %s require %s", principal.Name, envMetadataTLSClientCAFile)
+		}
+		for _, certName := range principal.CertNames {
+			if owner, ok := a.certNames[certName]; ok {
+				return nil, fmt.Errorf("cert name %s is shared by principals %s and %s", certName, owner, principal.Name)
+			}
+			a.certNames[certName] = principal.Name
+		}
+	}
+	for _, endpoint := range policy.Endpoints {
+		rule := &authzRule{
+			path:  endpoint.Path,
+			allow: make(map[string]struct{}, len(endpoint.Allow)),
+		}
+		if strings.HasSuffix(rule.path, "*") {
+			rule.path = strings.TrimSuffix(rule.path, "*")
+			rule.prefix = true
+		}
+		if !strings.HasPrefix(rule.path, "/") && rule.path != "" {
+			return nil, fmt.Errorf("invalid endpoint path %q, must start with /", endpoint.Path)
+		}
+		for _, name := range endpoint.Allow {
+			if _, ok := principals[name]; !ok && name != anyPrincipal {
+				return nil, fmt.Errorf("unknown principal %s allowed to %s", name, endpoint.Path)
+			}
+			rule.allow[name] = struct{}{}
+		}
+		a.rules = append(a.rules, rule)
+	}
+	return a, nil
+}
+
+// identify returns the principal of the bearer token if any, otherwise the principal of the client cert.
+// Empty is returned if the request is anonymous.
+func (a *endpointAuthorizer) identify(authorizations []string, certs []*x509.Certificate) string {
+	for _, authorization := range authorizations {
+		if !strings.HasPrefix(authorization, bearerPrefix) {
+			continue
+		}
+		got := []byte(strings.TrimPrefix(authorization, bearerPrefix))
+		// all the tokens are compared to not leak which one is matched by the timing
+		principal := ""
+		for token, name := range a.tokens {
+			if subtle.ConstantTimeCompare(got, []byte(token)) == 1 {
+				principal = name
+			}
+		}
+		if principal != "" {
+			return principal
+		}
+	}
+	if len(certs) > 0 {
+		cert := certs[0]
+		if principal, ok := a.certNames[cert.Subject.CommonName]; ok {
+			return principal
+		}
+		for _, dnsName := range cert.DNSNames {
+			if principal, ok := a.certNames[dnsName]; ok {
+				return principal
+			}
+		}
+	}
+	return ""
+}
+
+// allowed checks the principal against the rule of the path, the exact rule wins over the longest prefix rule.
+func (a *endpointAuthorizer) allowed(principal, path string) bool {
+	var matched *authzRule
+	for _, rule := range a.rules {
+		if rule.path == path && !rule.prefix {
+			matched = rule
+			break
+		}
+		if rule.prefix && strings.HasPrefix(path, rule.path) && (matched == nil || len(rule.path) > len(matched.path)) {
+			matched = rule
+		}
+	}
+	if matched == nil {
+		return false
+	}
+	if _, ok := matched.allow[principal]; ok {
+		return true
+	}
+	_, ok := matched.allow[anyPrincipal]
+	return ok
+}
+
+// authorize returns the principal and whether it is allowed to the path, the denied request is audited.
+func (a *endpointAuthorizer) authorize(path, remoteAddr string, authorizations []string, certs []*x509.Certificate) (string, bool) {
+	principal := a.identify(authorizations, certs)
+	if principal != "" && a.allowed(principal, path) {
+		return principal, true
+	}
+	logger.Warning(context.Background(), "K8S_META_AUTHZ_ALARM", "action", "request denied", "path", path,
+		"principal", principal, "remote", remoteAddr)
+	return principal, false
+}
+
+func (a *endpointAuthorizer) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var certs []*x509.Certificate
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			certs = r.TLS.VerifiedChains[0]
+		}
+		principal, ok := a.authorize(r.URL.Path, r.RemoteAddr, r.Header.Values("Authorization"), certs)
+		if ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if principal == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+}
+
+func (a *endpointAuthorizer) authorizeGRPC(ctx context.Context, fullMethod string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var certs []*x509.Certificate
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			remoteAddr = p.Addr.String()
+		}
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			certs = tlsInfo.State.VerifiedChains[0]
+		}
+	}
+	principal, ok := a.authorize(fullMethod, remoteAddr, md.Get("authorization"), certs)
+	switch {
+	case ok:
+		return nil
+	case principal == "":
+		return status.Error(codes.Unauthenticated, "unknown principal")
+	default:
+		return status.Error(codes.PermissionDenied, "principal "+principal+" is not allowed to "+fullMethod)
+	}
+}
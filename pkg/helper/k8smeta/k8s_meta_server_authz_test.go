@@ -0,0 +1,185 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta/metadatapb"
+)
+
+func writeAuthzPolicy(t *testing.T, policy *authzPolicy) string {
+	content, err := json.Marshal(policy)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "authz.json")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func TestLoadEndpointAuthorizer(t *testing.T) {
+	a, err := loadEndpointAuthorizer(false)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	tokenFile := writeTokenFile(t, "admin-token\n")
+	for _, policy := range []*authzPolicy{
+		{Principals: []authzPrincipal{{Name: ""}}},
+		{Principals: []authzPrincipal{{Name: "a"}, {Name: "a"}}},
+		{Principals: []authzPrincipal{{Name: "a", TokenFile: tokenFile}, {Name: "b", TokenFile: tokenFile}}},
+		{Principals: []authzPrincipal{{Name: "a", CertNames: []string{"node"}}}},
+		{Principals: []authzPrincipal{{Name: "a"}}, Endpoints: []authzEndpoint{{Path: "/metadata/host", Allow: []string{"b"}}}},
+		{Endpoints: []authzEndpoint{{Path: "metadata/host"}}},
+	} {
+		t.Setenv(envMetadataAuthzFile, writeAuthzPolicy(t, policy))
+		_, err = loadEndpointAuthorizer(false)
+		assert.Error(t, err, policy)
+	}
+
+	t.Setenv(envMetadataAuthzFile, writeAuthzPolicy(t, &authzPolicy{
+		Principals: []authzPrincipal{
+			{Name: "admin", TokenFile: tokenFile},
+			{Name: "node-agent", CertNames: []string{"node-agent"}},
+		},
+		Endpoints: []authzEndpoint{
+			{Path: "/metadata/host", Allow: []string{"node-agent"}},
+			{Path: "/metadata/*", Allow: []string{"admin"}},
+			{Path: "/metadata/custom", Allow: []string{anyPrincipal}},
+		},
+	}))
+	a, err = loadEndpointAuthorizer(true)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", a.identify([]string{"Bearer admin-token"}, nil))
+	assert.Equal(t, "", a.identify([]string{"admin-token"}, nil))
+	assert.Equal(t, "node-agent", a.identify([]string{"Bearer wrong"}, []*x509.Certificate{{DNSNames: []string{"node-agent"}}}))
+	assert.Equal(t, "", a.identify(nil, []*x509.Certificate{{DNSNames: []string{"other"}}}))
+
+	assert.True(t, a.allowed("node-agent", "/metadata/host"))
+	assert.False(t, a.allowed("admin", "/metadata/host"), "the exact rule wins over the prefix rule")
+	assert.True(t, a.allowed("admin", "/metadata/node"))
+	assert.False(t, a.allowed("node-agent", "/metadata/node"))
+	assert.True(t, a.allowed("node-agent", "/metadata/custom"))
+	assert.False(t, a.allowed("admin", "/other"), "the path without rule is denied")
+
+	// the single token cannot be used together with the principals
+	t.Setenv(envMetadataTokenFile, tokenFile)
+	_, err = loadServerSecurity()
+	assert.Error(t, err)
+}
+
+func TestEndpointAuthorizerHTTP(t *testing.T) {
+	certs := generateTestCerts(t)
+	t.Setenv(envMetadataTLSCertFile, certs.serverCert)
+	t.Setenv(envMetadataTLSKeyFile, certs.serverKey)
+	t.Setenv(envMetadataTLSClientCAFile, certs.ca)
+	t.Setenv(envMetadataAuthzFile, writeAuthzPolicy(t, &authzPolicy{
+		Principals: []authzPrincipal{
+			{Name: "admin", TokenFile: writeTokenFile(t, "admin-token")},
+			// the test client cert is issued for localhost
+			{Name: "node-agent", CertNames: []string{"localhost"}},
+		},
+		Endpoints: []authzEndpoint{
+			{Path: "/metadata/host", Allow: []string{"node-agent"}},
+			{Path: "/metadata/*", Allow: []string{"admin"}},
+		},
+	}))
+	security, err := loadServerSecurity()
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(security.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})))
+	server.TLS = security.tlsConfig
+	server.StartTLS()
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      certs.pool,
+		Certificates: []tls.Certificate{certs.client},
+		MinVersion:   tls.VersionTLS12,
+	}}}
+	request := func(path, token string) int {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, request("/metadata/host", ""))
+	assert.Equal(t, http.StatusForbidden, request("/metadata/node", ""))
+	assert.Equal(t, http.StatusOK, request("/metadata/node", "admin-token"))
+	assert.Equal(t, http.StatusForbidden, request("/metadata/host", "admin-token"), "the token takes precedence over the cert")
+	assert.Equal(t, http.StatusOK, request("/metadata/host", "wrong"), "the cert identifies the request with an unknown token")
+}
+
+func TestEndpointAuthorizerGRPC(t *testing.T) {
+	t.Setenv(envMetadataAuthzFile, writeAuthzPolicy(t, &authzPolicy{
+		Principals: []authzPrincipal{
+			{Name: "node-agent", TokenFile: writeTokenFile(t, "node-token")},
+			{Name: "viewer", TokenFile: writeTokenFile(t, "viewer-token")},
+		},
+		Endpoints: []authzEndpoint{
+			{Path: "/k8smeta.MetadataService/GetPodMetadataByHostIP", Allow: []string{"node-agent"}},
+			{Path: "/k8smeta.MetadataService/*", Allow: []string{"viewer"}},
+		},
+	}))
+	security, err := loadServerSecurity()
+	require.NoError(t, err)
+	manager := GetMetaManagerInstance()
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+	client := newGRPCTestClient(t, newMetadataHandler(manager), security.grpcServerOptions()...)
+
+	call := func(token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		stream, err := client.GetPodMetadataByHostIP(ctx, &metadatapb.MetadataRequest{Keys: []string{"127.0.0.1"}})
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err = stream.Recv(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("viewer-token")))
+	assert.NoError(t, call("node-token"))
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	maxKeysEnv               = "KUBERNETES_METADATA_MAX_KEYS"
+	maxConcurrentRequestsEnv = "KUBERNETES_METADATA_MAX_CONCURRENT_REQUESTS"
+	rateLimitQPSEnv          = "KUBERNETES_METADATA_RATE_LIMIT_QPS"
+	rateLimitBurstEnv        = "KUBERNETES_METADATA_RATE_LIMIT_BURST"
+	// clientBucketIdle is how long the bucket of a client is kept after its last request, a bucket idle for that
+	// long is full again anyway.
+	clientBucketIdle = time.Minute
+)
+
+// requestLimiter protects the handler goroutines of the http server from a single misbehaving consumer. It limits the
+// keys per request, the concurrent requests and the request rate of each client ip, and all limits are disabled by
+// default.
+type requestLimiter struct {
+	maxKeys  int
+	inflight chan struct{} // nil if the concurrent requests are not limited
+	qps      float64       // 0 if the request rate is not limited
+	burst    float64
+
+	lock      sync.Mutex
+	buckets   map[string]*clientBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type clientBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRequestLimiterFromEnv returns nil if no limit is configured, and all methods of a nil limiter let the requests pass.
+func newRequestLimiterFromEnv() *requestLimiter {
+	maxKeys := intFromEnv(maxKeysEnv)
+	maxConcurrent := intFromEnv(maxConcurrentRequestsEnv)
+	qps := intFromEnv(rateLimitQPSEnv)
+	burst := intFromEnv(rateLimitBurstEnv)
+	return newRequestLimiter(maxKeys, maxConcurrent, qps, burst)
+}
+
+func newRequestLimiter(maxKeys, maxConcurrent, qps, burst int) *requestLimiter {
+	if maxKeys <= 0 && maxConcurrent <= 0 && qps <= 0 {
+		return nil
+	}
+	l := &requestLimiter{
+		maxKeys: maxKeys,
+		buckets: make(map[string]*clientBucket),
+		now:     time.Now,
+	}
+	if maxConcurrent > 0 {
+		l.inflight = make(chan struct{}, maxConcurrent)
+	}
+	if qps > 0 {
+		l.qps = float64(qps)
+		l.burst = float64(burst)
+		if burst < qps {
+			l.burst = float64(qps)
+		}
+	}
+	return l
+}
+
+func intFromEnv(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "invalid limit, ignore it", key, "value", value)
+		return 0
+	}
+	return n
+}
+
+// allow checks the request rate of the client, it writes 429 with Retry-After and returns false if the request is
+// rejected.
+func (l *requestLimiter) allow(w http.ResponseWriter, r *http.Request) bool {
+	if l == nil {
+		return true
+	}
+	if wait := l.reserve(clientIP(r)); wait > 0 {
+		tooManyRequests(w, wait)
+		return false
+	}
+	return true
+}
+
+// acquire is allow plus a concurrency slot, the caller must call release when the request is done if it returns true.
+func (l *requestLimiter) acquire(w http.ResponseWriter, r *http.Request) bool {
+	if !l.allow(w, r) {
+		return false
+	}
+	if l != nil && l.inflight != nil {
+		select {
+		case l.inflight <- struct{}{}:
+		default:
+			tooManyRequests(w, time.Second)
+			return false
+		}
+	}
+	return true
+}
+
+func (l *requestLimiter) release() {
+	if l == nil || l.inflight == nil {
+		return
+	}
+	<-l.inflight
+}
+
+// allowKeys writes 413 and returns false if the request asks for more keys than allowed.
+func (l *requestLimiter) allowKeys(w http.ResponseWriter, keys int) bool {
+	if l == nil || l.maxKeys <= 0 || keys <= l.maxKeys {
+		return true
+	}
+	http.Error(w, "Too many keys in one request, the limit is "+strconv.Itoa(l.maxKeys), http.StatusRequestEntityTooLarge)
+	return false
+}
+
+// reserve takes a token from the bucket of the client, and returns how long to wait for the next token if the bucket
+// is empty.
+func (l *requestLimiter) reserve(client string) time.Duration {
+	if l.qps <= 0 {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) > clientBucketIdle {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.last) > clientBucketIdle {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &clientBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.qps)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / l.qps * float64(time.Second))
+	}
+	bucket.tokens--
+	return 0
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
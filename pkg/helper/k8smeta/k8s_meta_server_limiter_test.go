@@ -0,0 +1,103 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequestLimiterFromEnv(t *testing.T) {
+	assert.Nil(t, newRequestLimiterFromEnv())
+
+	t.Setenv(maxKeysEnv, "100")
+	t.Setenv(rateLimitQPSEnv, "10")
+	t.Setenv(rateLimitBurstEnv, "5")
+	t.Setenv(maxConcurrentRequestsEnv, "bad")
+	l := newRequestLimiterFromEnv()
+	require.NotNil(t, l)
+	assert.Equal(t, 100, l.maxKeys)
+	assert.Nil(t, l.inflight)
+	assert.Equal(t, 10.0, l.qps)
+	// the burst is at least the qps
+	assert.Equal(t, 10.0, l.burst)
+}
+
+func TestRequestLimiterRate(t *testing.T) {
+	l := newRequestLimiter(0, 0, 2, 2)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	assert.Zero(t, l.reserve("10.0.0.1"))
+	assert.Zero(t, l.reserve("10.0.0.1"))
+	assert.Equal(t, 500*time.Millisecond, l.reserve("10.0.0.1"))
+	// the other clients have their own buckets
+	assert.Zero(t, l.reserve("10.0.0.2"))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Zero(t, l.reserve("10.0.0.1"))
+
+	now = now.Add(2 * clientBucketIdle)
+	assert.Zero(t, l.reserve("10.0.0.3"))
+	assert.Len(t, l.buckets, 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.3:12345"
+	assert.True(t, l.allow(httptest.NewRecorder(), req))
+	rec := httptest.NewRecorder()
+	assert.False(t, l.allow(rec, req))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+}
+
+func TestRequestLimiterConcurrency(t *testing.T) {
+	l := newRequestLimiter(0, 1, 0, 0)
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	require.True(t, l.acquire(httptest.NewRecorder(), req))
+	rec := httptest.NewRecorder()
+	assert.False(t, l.acquire(rec, req))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	l.release()
+	assert.True(t, l.acquire(httptest.NewRecorder(), req))
+	l.release()
+
+	var nilLimiter *requestLimiter
+	assert.True(t, nilLimiter.acquire(httptest.NewRecorder(), req))
+	nilLimiter.release()
+}
+
+func TestRequestLimiterMaxKeys(t *testing.T) {
+	handler := newMetadataHandler(GetMetaManagerInstance())
+	handler.limiter = newRequestLimiter(2, 0, 0, 0)
+	body, err := json.Marshal(requestBody{Keys: []string{"a", "b", "c"}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	handler.handleServiceMeta(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	body, err = json.Marshal(requestBody{Keys: []string{"a", "b"}})
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	handler.handleServiceMeta(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
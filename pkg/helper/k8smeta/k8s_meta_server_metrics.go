@@ -0,0 +1,142 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// requestLatencyBuckets are the upper bounds of the request latency histogram buckets in seconds.
+var requestLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+var (
+	cacheSizeDesc = prometheus.NewDesc("k8s_meta_cache_size",
+		"The number of the cached objects by resource type.", []string{"resource"}, nil)
+	queueSizeDesc = prometheus.NewDesc("k8s_meta_queue_size",
+		"The number of the events waiting to be handled by resource type.", []string{"resource"}, nil)
+	informerSyncedDesc = prometheus.NewDesc("k8s_meta_informer_synced",
+		"Whether the informer of the resource type has synced, 1 for synced.", []string{"resource"}, nil)
+	informerLastEventDesc = prometheus.NewDesc("k8s_meta_informer_last_event_timestamp_seconds",
+		"The unix time of the last event of the resource type, 0 if none is received yet.", []string{"resource"}, nil)
+	servingDesc = prometheus.NewDesc("k8s_meta_serving",
+		"Whether the lookups can be answered, 1 once all the informers are synced or the snapshot is loaded.", nil, nil)
+)
+
+// serverMetrics is the cumulative statistics of the http server, exposed with the cache state by /metrics. Each server
+// has its own registry, so the metrics of the agent process are not mixed in.
+type serverMetrics struct {
+	// handler serves the registry in the prometheus exposition format.
+	handler   http.Handler
+	requests  *prometheus.CounterVec
+	latencies *prometheus.HistogramVec
+	rejected  prometheus.Counter
+	coalesced prometheus.Counter
+}
+
+func newServerMetrics(metaManager *MetaManager) *serverMetrics {
+	s := &serverMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "k8s_meta_http_requests_total",
+			Help: "The number of the http requests by endpoint and status code.",
+		}, []string{"endpoint", "code"}),
+		latencies: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "k8s_meta_http_request_duration_seconds",
+			Help:    "The latency of the http requests by endpoint.",
+			Buckets: requestLatencyBuckets,
+		}, []string{"endpoint"}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "k8s_meta_http_rejected_total",
+			Help: "The number of the http requests rejected by the rate or concurrency limit.",
+		}),
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "k8s_meta_http_coalesced_total",
+			Help: "The number of the http requests served with the response of an identical request in flight.",
+		}),
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(s.requests, s.latencies, s.rejected, s.coalesced, &cacheCollector{metaManager: metaManager})
+	s.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return s
+}
+
+// observe records a request of the endpoint answered with the code, and its latency if it is positive.
+func (s *serverMetrics) observe(endpoint string, code int, latency time.Duration) {
+	s.requests.WithLabelValues(endpoint, strconv.Itoa(code)).Inc()
+	if latency > 0 {
+		s.latencies.WithLabelValues(endpoint).Observe(latency.Seconds())
+	}
+}
+
+func (s *serverMetrics) addRejected() {
+	s.rejected.Inc()
+}
+
+func (s *serverMetrics) addCoalesced() {
+	s.coalesced.Inc()
+}
+
+// statusRecorder records the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// cacheCollector reports the size, queue size and sync state of the cache of each resource when scraped.
+type cacheCollector struct {
+	metaManager *MetaManager
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheSizeDesc
+	ch <- queueSizeDesc
+	ch <- informerSyncedDesc
+	ch <- informerLastEventDesc
+	ch <- servingDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	defer panicRecover()
+	resourceTypes := make([]string, 0, len(c.metaManager.cacheMap))
+	for resourceType := range c.metaManager.cacheMap {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+	for _, resourceType := range resourceTypes {
+		status := c.metaManager.cacheMap[resourceType].syncStatus()
+		ch <- prometheus.MustNewConstMetric(cacheSizeDesc, prometheus.GaugeValue, float64(status.Entries), resourceType)
+		ch <- prometheus.MustNewConstMetric(queueSizeDesc, prometheus.GaugeValue, float64(status.QueueSize), resourceType)
+		ch <- prometheus.MustNewConstMetric(informerSyncedDesc, prometheus.GaugeValue, boolToFloat(status.Synced), resourceType)
+		ch <- prometheus.MustNewConstMetric(informerLastEventDesc, prometheus.GaugeValue, float64(status.LastEventTime), resourceType)
+	}
+	ch <- prometheus.MustNewConstMetric(servingDesc, prometheus.GaugeValue, boolToFloat(c.metaManager.isServing()))
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	podCache := newK8sMetaCache(make(chan struct{}), POD)
+	podCache.metaStore.Items["default/pod1"] = &ObjectWrapper{
+		Raw: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}},
+	}
+	podCache.synced.Store(true)
+	podCache.lastEventTime.Store(1700000000)
+	manager := &MetaManager{
+		cacheMap: map[string]MetaCache{
+			POD:     podCache,
+			SERVICE: newK8sMetaCache(make(chan struct{}), SERVICE),
+		},
+	}
+	handler := newMetadataHandler(manager)
+	serve := handler.handler(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	request := func() int {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, "/metadata/host", nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, request())
+	manager.snapshotLoaded.Store(true)
+	assert.Equal(t, http.StatusOK, request())
+	assert.Equal(t, http.StatusOK, request())
+	handler.metrics.observe("/metadata/node", http.StatusOK, 30*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.metrics.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE k8s_meta_http_requests_total counter",
+		`k8s_meta_http_requests_total{code="200",endpoint="/metadata/host"} 2`,
+		`k8s_meta_http_requests_total{code="503",endpoint="/metadata/host"} 1`,
+		"# TYPE k8s_meta_http_request_duration_seconds histogram",
+		`k8s_meta_http_request_duration_seconds_bucket{endpoint="/metadata/node",le="0.025"} 0`,
+		`k8s_meta_http_request_duration_seconds_bucket{endpoint="/metadata/node",le="0.05"} 1`,
+		`k8s_meta_http_request_duration_seconds_bucket{endpoint="/metadata/node",le="+Inf"} 1`,
+		`k8s_meta_http_request_duration_seconds_sum{endpoint="/metadata/node"} 0.03`,
+		`k8s_meta_http_request_duration_seconds_count{endpoint="/metadata/host"} 3`,
+		"k8s_meta_http_rejected_total 0",
+		`k8s_meta_cache_size{resource="pod"} 1`,
+		`k8s_meta_cache_size{resource="service"} 0`,
+		`k8s_meta_informer_synced{resource="pod"} 1`,
+		`k8s_meta_informer_synced{resource="service"} 0`,
+		`k8s_meta_informer_last_event_timestamp_seconds{resource="pod"} 1.7e+09`,
+		"k8s_meta_serving 1",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
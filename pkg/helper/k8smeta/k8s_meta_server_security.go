@@ -0,0 +1,159 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/tlscommon"
+)
+
+const (
+	envMetadataTLSCertFile     = "KUBERNETES_METADATA_TLS_CERT_FILE"
+	envMetadataTLSKeyFile      = "KUBERNETES_METADATA_TLS_KEY_FILE"
+	envMetadataTLSClientCAFile = "KUBERNETES_METADATA_TLS_CLIENT_CA_FILE"
+	envMetadataTokenFile       = "KUBERNETES_METADATA_TOKEN_FILE"
+
+	bearerPrefix = "Bearer "
+)
+
+// serverSecurity is the tls and the bearer token authentication shared by the http and grpc servers.
+type serverSecurity struct {
+	tlsConfig *tls.Config // nil to serve in plaintext
+	token     string      // empty to skip the token authentication
+	// authz authorizes each endpoint by the principals instead of the single token, nil to skip it.
+	authz *endpointAuthorizer
+}
+
+// loadServerSecurity loads the security options from the environment variables. The servers are served with tls if
+// the cert and key are set, and require the client certs signed by the client CA if it is also set.
+func loadServerSecurity() (*serverSecurity, error) {
+	s := &serverSecurity{}
+	certFile, keyFile, clientCAFile := os.Getenv(envMetadataTLSCertFile), os.Getenv(envMetadataTLSKeyFile), os.Getenv(envMetadataTLSClientCAFile)
+	if certFile != "" || keyFile != "" {
+		tlsConfig := &tlscommon.TLSConfig{
+			Enabled:  true,
+			CAFile:   clientCAFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		}
+		var err error
+		if s.tlsConfig, err = tlsConfig.LoadTLSConfig(); err != nil {
+			return nil, err
+		}
+		// the CA loaded as the root CAs of a client verifies the client certs for the server
+		if clientCAFile != "" {
+			s.tlsConfig.ClientCAs, s.tlsConfig.RootCAs = s.tlsConfig.RootCAs, nil
+			s.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if clientCAFile != "" {
+		return nil, errors.New(envMetadataTLSClientCAFile + " requires " + envMetadataTLSCertFile + " and " + envMetadataTLSKeyFile)
+	}
+	if tokenFile := os.Getenv(envMetadataTokenFile); tokenFile != "" {
+		token, err := os.ReadFile(filepath.Clean(tokenFile))
+		if err != nil {
+			return nil, err
+		}
+		s.token = strings.TrimSpace(string(token))
+		if s.token == "" {
+			return nil, errors.New("empty token in " + tokenFile)
+		}
+	}
+	var err error
+	if s.authz, err = loadEndpointAuthorizer(s.tlsConfig != nil && s.tlsConfig.ClientCAs != nil); err != nil {
+		return nil, err
+	}
+	if s.authz != nil && s.token != "" {
+		return nil, errors.New(envMetadataAuthzFile + " conflicts with " + envMetadataTokenFile + ", declare the token as a principal instead")
+	}
+	return s, nil
+}
+
+// authorized checks the value of the authorization header against the token.
+func (s *serverSecurity) authorized(authorization string) bool {
+	if s.token == "" {
+		return true
+	}
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authorization, bearerPrefix)), []byte(s.token)) == 1
+}
+
+func (s *serverSecurity) wrapHandler(handler http.Handler) http.Handler {
+	if s.authz != nil {
+		return s.authz.wrapHandler(handler)
+	}
+	if s.token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *serverSecurity) grpcServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	if s.token != "" || s.authz != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := s.authorizeGRPC(ctx, info.FullMethod); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := s.authorizeGRPC(ss.Context(), info.FullMethod); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}))
+	}
+	return opts
+}
+
+func (s *serverSecurity) authorizeGRPC(ctx context.Context, fullMethod string) error {
+	if s.authz != nil {
+		return s.authz.authorizeGRPC(ctx, fullMethod)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, authorization := range md.Get("authorization") {
+		if s.authorized(authorization) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid bearer token")
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta/metadatapb"
+)
+
+type testCerts struct {
+	ca, serverCert, serverKey string
+	client                    tls.Certificate
+	pool                      *x509.CertPool
+}
+
+func generateTestCerts(t *testing.T) *testCerts {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+
+	c := &testCerts{pool: x509.NewCertPool()}
+	c.pool.AddCert(caCert)
+	c.ca = write("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	serverCert, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	c.serverCert = write("server.pem", serverCert)
+	c.serverKey = write("server.key", serverKey)
+	clientCert, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+	c.client, err = tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	return c
+}
+
+func writeTokenFile(t *testing.T, token string) string {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte(token), 0600))
+	return path
+}
+
+func TestLoadServerSecurity(t *testing.T) {
+	security, err := loadServerSecurity()
+	require.NoError(t, err)
+	assert.Nil(t, security.tlsConfig)
+	assert.True(t, security.authorized(""))
+
+	certs := generateTestCerts(t)
+	t.Setenv(envMetadataTLSClientCAFile, certs.ca)
+	_, err = loadServerSecurity()
+	assert.Error(t, err)
+
+	t.Setenv(envMetadataTLSCertFile, certs.serverCert)
+	_, err = loadServerSecurity()
+	assert.Error(t, err)
+
+	t.Setenv(envMetadataTLSKeyFile, certs.serverKey)
+	t.Setenv(envMetadataTokenFile, writeTokenFile(t, " \n"))
+	_, err = loadServerSecurity()
+	assert.Error(t, err)
+
+	t.Setenv(envMetadataTokenFile, writeTokenFile(t, "secret\n"))
+	security, err = loadServerSecurity()
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, security.tlsConfig.ClientAuth)
+	assert.NotNil(t, security.tlsConfig.ClientCAs)
+	assert.Nil(t, security.tlsConfig.RootCAs)
+	assert.True(t, security.authorized("Bearer secret"))
+	assert.False(t, security.authorized("Bearer secret2"))
+	assert.False(t, security.authorized("secret"))
+}
+
+func TestServerSecurityHTTP(t *testing.T) {
+	certs := generateTestCerts(t)
+	t.Setenv(envMetadataTLSCertFile, certs.serverCert)
+	t.Setenv(envMetadataTLSKeyFile, certs.serverKey)
+	t.Setenv(envMetadataTLSClientCAFile, certs.ca)
+	t.Setenv(envMetadataTokenFile, writeTokenFile(t, "secret"))
+	security, err := loadServerSecurity()
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(security.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})))
+	server.TLS = security.tlsConfig
+	server.StartTLS()
+	defer server.Close()
+	request := func(clientCerts []tls.Certificate, token string) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      certs.pool,
+			Certificates: clientCerts,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return client.Do(req)
+	}
+
+	// the client without cert is rejected
+	_, err = request(nil, "secret")
+	assert.Error(t, err)
+
+	resp, err := request([]tls.Certificate{certs.client}, "wrong")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+
+	resp, err = request([]tls.Certificate{certs.client}, "secret")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestServerSecurityGRPC(t *testing.T) {
+	t.Setenv(envMetadataTokenFile, writeTokenFile(t, "secret"))
+	security, err := loadServerSecurity()
+	require.NoError(t, err)
+	manager := GetMetaManagerInstance()
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+	client := newGRPCTestClient(t, newMetadataHandler(manager), security.grpcServerOptions()...)
+
+	call := func(ctx context.Context) error {
+		stream, err := client.GetPodMetadataByHostIP(ctx, &metadatapb.MetadataRequest{Keys: []string{"127.0.0.1"}})
+		if err != nil {
+			return err
+		}
+		for {
+			if _, err = stream.Recv(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	err = call(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	err = call(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.NoError(t, call(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")))
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	// snapshotDirEnv enables the snapshot of the caches, which is loaded on start so that the lookups are answered
+	// before the informers sync, e.g. a hostPath directory surviving the restarts of the agent.
+	snapshotDirEnv          = "KUBERNETES_METADATA_SNAPSHOT_DIR"
+	snapshotIntervalEnv     = "KUBERNETES_METADATA_SNAPSHOT_INTERVAL_SEC"
+	snapshotFileName        = "k8s_meta_snapshot.json.gz"
+	snapshotVersion         = 1
+	defaultSnapshotInterval = 5 * time.Minute
+	// snapshotMaxAge skips the snapshot too old to be useful, e.g. after the node is down for days.
+	snapshotMaxAge = 24 * time.Hour
+)
+
+type metaSnapshot struct {
+	Version   int                          `json:"version"`
+	Time      int64                        `json:"time"`
+	Resources map[string][]json.RawMessage `json:"resources"`
+}
+
+// metaSnapshotter saves the caches to the local disk periodically after the informers sync, and restores them on start.
+// The restored objects are served until the informer of their resource syncs, and those absent from it are removed then.
+type metaSnapshotter struct {
+	metaManager *MetaManager
+	path        string
+	interval    time.Duration
+}
+
+// newMetaSnapshotterFromEnv returns nil if the snapshot is disabled, and all methods of a nil snapshotter are no-op.
+func newMetaSnapshotterFromEnv(metaManager *MetaManager) *metaSnapshotter {
+	dir := os.Getenv(snapshotDirEnv)
+	if dir == "" {
+		return nil
+	}
+	interval := defaultSnapshotInterval
+	if intervalEnv := os.Getenv(snapshotIntervalEnv); intervalEnv != "" {
+		if sec, err := strconv.Atoi(intervalEnv); err == nil && sec > 0 {
+			interval = time.Duration(sec) * time.Second
+		} else {
+			logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "invalid snapshot interval, use default", intervalEnv)
+		}
+	}
+	return &metaSnapshotter{
+		metaManager: metaManager,
+		path:        filepath.Join(dir, snapshotFileName),
+		interval:    interval,
+	}
+}
+
+// load restores the caches from the snapshot, it must be called before the caches start watching.
+func (s *metaSnapshotter) load() {
+	if s == nil {
+		return
+	}
+	snapshot, err := s.read()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "read snapshot error", err, "path", s.path)
+		}
+		return
+	}
+	if snapshot.Version != snapshotVersion {
+		logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "unknown snapshot version", snapshot.Version, "path", s.path)
+		return
+	}
+	if age := time.Since(time.Unix(snapshot.Time, 0)); age > snapshotMaxAge {
+		logger.Info(context.Background(), "skip snapshot too old", s.path, "age", age.String())
+		return
+	}
+	total := 0
+	for resourceType, items := range snapshot.Resources {
+		if cache, ok := s.metaManager.cacheMap[resourceType]; ok {
+			total += cache.restore(items)
+		}
+	}
+	if total > 0 {
+		s.metaManager.snapshotLoaded.Store(true)
+	}
+	logger.Info(context.Background(), "restore k8s meta from snapshot", s.path, "count", total,
+		"snapshot time", time.Unix(snapshot.Time, 0).Format(time.RFC3339))
+}
+
+func (s *metaSnapshotter) read() (*metaSnapshot, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	snapshot := &metaSnapshot{}
+	if err = json.NewDecoder(reader).Decode(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// run saves the snapshot every interval until stopped, it must be called after the informers sync,
+// otherwise the good snapshot would be overwritten by the partial caches.
+func (s *metaSnapshotter) run(stopCh <-chan struct{}) {
+	if s == nil {
+		return
+	}
+	go func() {
+		defer panicRecover()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.save(); err != nil {
+				logger.Warning(context.Background(), "K8S_META_SNAPSHOT_ALARM", "save snapshot error", err, "path", s.path)
+			}
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// save writes the snapshot to a temporary file and renames it, so that a crash never leaves a partial snapshot.
+func (s *metaSnapshotter) save() error {
+	snapshot := &metaSnapshot{
+		Version:   snapshotVersion,
+		Time:      time.Now().Unix(),
+		Resources: make(map[string][]json.RawMessage, len(s.metaManager.cacheMap)),
+	}
+	for resourceType, cache := range s.metaManager.cacheMap {
+		snapshot.Resources[resourceType] = cache.dump()
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	// the pod envs are in the snapshot, so it is only readable by the agent
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(file)
+	err = json.NewEncoder(writer).Encode(snapshot)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write snapshot error: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newSnapshotTestManager() *MetaManager {
+	gvr := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	return &MetaManager{cacheMap: map[string]MetaCache{
+		POD:       newK8sMetaCache(make(chan struct{}), POD),
+		"rollout": newCustomMetaCache(make(chan struct{}), "rollout", gvr, nil),
+	}}
+}
+
+func addSnapshotTestObject(manager *MetaManager, resourceType string, raw interface{}) {
+	metaCache := manager.cacheMap[resourceType].(*k8sMetaCache)
+	obj := &ObjectWrapper{ResourceType: resourceType, Raw: metaCache.preProcess(raw)}
+	key, _ := metaCache.metaStore.keyFunc(obj.Raw)
+	metaCache.metaStore.Items[key] = obj
+	for _, idxKey := range metaCache.metaStore.getIdxKeys(obj) {
+		if _, ok := metaCache.metaStore.Index[idxKey]; !ok {
+			metaCache.metaStore.Index[idxKey] = NewIndexItem()
+		}
+		metaCache.metaStore.Index[idxKey].Add(key)
+	}
+}
+
+func TestMetaSnapshot(t *testing.T) {
+	assert.Nil(t, newMetaSnapshotterFromEnv(newSnapshotTestManager()))
+	t.Setenv(snapshotDirEnv, t.TempDir())
+
+	source := newSnapshotTestManager()
+	for _, name := range []string{"web", "gone"} {
+		addSnapshotTestObject(source, POD, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod", Labels: map[string]string{"app": name}},
+			Status:     corev1.PodStatus{PodIP: "10.0.0." + name},
+		})
+	}
+	rollout := &unstructured.Unstructured{}
+	rollout.SetAPIVersion("argoproj.io/v1alpha1")
+	rollout.SetKind("Rollout")
+	rollout.SetNamespace("prod")
+	rollout.SetName("web")
+	addSnapshotTestObject(source, "rollout", rollout)
+	require.NoError(t, newMetaSnapshotterFromEnv(source).save())
+
+	restored := newSnapshotTestManager()
+	assert.False(t, restored.isServing())
+	newMetaSnapshotterFromEnv(restored).load()
+	assert.True(t, restored.isServing())
+	assert.False(t, restored.IsReady())
+
+	objs := restored.cacheMap[POD].Get([]string{"10.0.0.web", "prod/gone"})
+	require.Len(t, objs["10.0.0.web"], 1)
+	pod, ok := objs["10.0.0.web"][0].Raw.(*corev1.Pod)
+	require.True(t, ok)
+	assert.Equal(t, "web", pod.Labels["app"])
+	assert.Len(t, objs["prod/gone"], 1)
+	objs = restored.cacheMap["rollout"].Get([]string{"prod/web"})
+	require.Len(t, objs["prod/web"], 1)
+	_, ok = objs["prod/web"][0].Raw.(*unstructured.Unstructured)
+	assert.True(t, ok)
+
+	// the pod deleted while the agent is down is removed once the informer syncs
+	podStore := restored.cacheMap[POD].(*k8sMetaCache).metaStore
+	assert.Equal(t, 1, podStore.expireRestored([]string{"prod/web"}))
+	objs = restored.cacheMap[POD].Get([]string{"10.0.0.web", "10.0.0.gone", "prod/gone"})
+	assert.Len(t, objs["10.0.0.web"], 1)
+	assert.Empty(t, objs["10.0.0.gone"])
+	assert.Empty(t, objs["prod/gone"])
+	assert.Len(t, podStore.Items, 1)
+}
+
+func TestMetaSnapshotSkip(t *testing.T) {
+	t.Setenv(snapshotDirEnv, t.TempDir())
+	manager := newSnapshotTestManager()
+	// no snapshot yet
+	newMetaSnapshotterFromEnv(manager).load()
+	assert.False(t, manager.isServing())
+
+	// the empty snapshot doesn't make the manager serving
+	require.NoError(t, newMetaSnapshotterFromEnv(manager).save())
+	newMetaSnapshotterFromEnv(manager).load()
+	assert.False(t, manager.isServing())
+}
@@ -20,6 +20,8 @@ type ObjectWrapper struct {
 	FirstObservedTime int64
 	LastObservedTime  int64
 	Deleted           bool
+	// DeletedTime is the unix second the object is deleted
+	DeletedTime int64
 }
 
 type IdxFunc func(obj interface{}) ([]string, error)
@@ -0,0 +1,116 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const subscriberQueueSize = 1000
+
+// MetaEventFunc handles an object event of a subscribed resource type, the Raw of the object is the typed
+// kubernetes object, e.g. *v1.Pod for POD. The object is shared with the cache and must not be modified.
+type MetaEventFunc func(obj *ObjectWrapper)
+
+type metaSubscriber struct {
+	key        string
+	addFunc    MetaEventFunc
+	updateFunc MetaEventFunc
+	deleteFunc MetaEventFunc
+	queue      chan *K8sMetaEvent
+	overflowed bool
+}
+
+// RegisterHandler subscribes the add, update and delete events of the resource type for the plugins in the same
+// process, a nil func skips the events of its type. The objects already cached are delivered as add events first,
+// an object added meanwhile might be delivered twice.
+// The funcs of a subscriber are called in order in its own goroutine, the events are dropped with an alarm
+// if the subscriber cannot keep up with them. The returned key is used to unregister the handler.
+func (m *MetaManager) RegisterHandler(resourceType string, addFunc, updateFunc, deleteFunc MetaEventFunc) (string, error) {
+	cache, ok := m.cacheMap[resourceType]
+	if !ok {
+		return "", fmt.Errorf("resource type %s is not supported", resourceType)
+	}
+	s := &metaSubscriber{
+		key:        "handler/" + strconv.FormatInt(m.handlerSeq.Add(1), 10),
+		addFunc:    addFunc,
+		updateFunc: updateFunc,
+		deleteFunc: deleteFunc,
+		queue:      make(chan *K8sMetaEvent, subscriberQueueSize),
+	}
+	objs, stopCh := cache.RegisterListWatchFunc(s.key, s.enqueue)
+	go s.run(objs, stopCh)
+	return s.key, nil
+}
+
+// UnRegisterHandler stops the handler registered by RegisterHandler, the events being handled are finished.
+func (m *MetaManager) UnRegisterHandler(resourceType, key string) {
+	if cache, ok := m.cacheMap[resourceType]; ok {
+		cache.UnRegisterSendFunc(key)
+	}
+}
+
+// enqueue is called in the event handling goroutine of the cache and must not block.
+func (s *metaSubscriber) enqueue(events []*K8sMetaEvent) {
+	for _, event := range events {
+		select {
+		case s.queue <- event:
+			s.overflowed = false
+		default:
+			if !s.overflowed {
+				s.overflowed = true
+				logger.Warning(context.Background(), "K8S_META_HANDLER_ALARM", "subscriber is too slow, drop events", s.key)
+			}
+		}
+	}
+}
+
+func (s *metaSubscriber) run(objs []*ObjectWrapper, stopCh <-chan struct{}) {
+	for _, obj := range objs {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		s.handle(s.addFunc, obj)
+	}
+	for {
+		select {
+		case event := <-s.queue:
+			switch event.EventType {
+			case EventTypeAdd:
+				s.handle(s.addFunc, event.Object)
+			case EventTypeUpdate:
+				s.handle(s.updateFunc, event.Object)
+			case EventTypeDelete:
+				s.handle(s.deleteFunc, event.Object)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (s *metaSubscriber) handle(f MetaEventFunc, obj *ObjectWrapper) {
+	if f == nil {
+		return
+	}
+	defer panicRecover()
+	f(obj)
+}
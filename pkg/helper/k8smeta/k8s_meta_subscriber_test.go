@@ -0,0 +1,81 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegisterHandler(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	podCache := newK8sMetaCache(stopCh, POD)
+	newPod := func(name, podIP string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: podIP},
+		}
+	}
+	podCache.metaStore.Items["default/pod1"] = &ObjectWrapper{Raw: newPod("pod1", "10.0.0.1")}
+	podCache.metaStore.Items["default/pod0"] = &ObjectWrapper{Raw: newPod("pod0", "10.0.0.0"), Deleted: true}
+	podCache.metaStore.Start()
+	manager := &MetaManager{cacheMap: map[string]MetaCache{POD: podCache}}
+
+	_, err := manager.RegisterHandler("unknown", nil, nil, nil)
+	assert.Error(t, err)
+
+	var lock sync.Mutex
+	var events []string
+	record := func(eventType string) MetaEventFunc {
+		return func(obj *ObjectWrapper) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, eventType+" "+obj.Raw.(*corev1.Pod).Name)
+		}
+	}
+	key, err := manager.RegisterHandler(POD, record(EventTypeAdd), record(EventTypeUpdate), func(obj *ObjectWrapper) {
+		record(EventTypeDelete)(obj)
+		panic("handler panics")
+	})
+	require.NoError(t, err)
+	getEvents := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), events...)
+	}
+
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: newPod("pod2", "10.0.0.2")}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod1", "10.0.0.3")}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: newPod("pod2", "10.0.0.2")}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: newPod("pod4", "10.0.0.4")}}
+	expected := []string{"add pod1", "add pod2", "update pod1", "delete pod2", "add pod4"}
+	assert.Eventually(t, func() bool {
+		return len(getEvents()) == len(expected)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, getEvents())
+
+	// no events are delivered after the handler is unregistered
+	manager.UnRegisterHandler(POD, key)
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: newPod("pod5", "10.0.0.5")}}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, expected, getEvents())
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	watchEventBufferSize = 1000
+	watchHeartbeatPeriod = 15 * time.Second
+)
+
+// watchEvent is written as the data of a server-sent event, the metadata of a delete event is the last known one.
+// It is generic so that the projected pod metadata could be sent.
+type watchEvent[T any] struct {
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+	Metadata T      `json:"metadata"`
+}
+
+// watchFilter selects the watched pods, an empty field matches all pods.
+type watchFilter struct {
+	namespace string
+	hostIP    string
+	selector  labels.Selector
+	// fields selects the fields of the pod metadata in the events
+	fields podFieldProjection
+}
+
+func (f *watchFilter) matches(pod *v1.Pod) bool {
+	if f.namespace != "" && pod.Namespace != f.namespace {
+		return false
+	}
+	if f.hostIP != "" && normalizeIP(pod.Status.HostIP) != f.hostIP {
+		return false
+	}
+	return f.selector.Matches(labels.Set(pod.Labels))
+}
+
+// handleWatch streams the add, update and delete events of the pods matched by the query parameters namespace,
+// labelSelector and hostIP as server-sent events. The matched pods are sent as add events first, then a pod is
+// reported as deleted once it is removed or no longer matches the filter.
+// A client which cannot keep up with the events is disconnected and should watch again to resync.
+func (m *metadataHandler) handleWatch(w http.ResponseWriter, r *http.Request) {
+	defer panicRecover()
+	if !m.metaManager.isServing() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	// the watch stream is long-lived and does not hold a concurrency slot, only the rate of opening it is limited
+	if !m.limiter.allow(w, r) {
+		m.metaManager.httpRejectedCount.Add(1)
+		m.metrics.addRejected()
+		m.metrics.observe(r.URL.Path, http.StatusTooManyRequests, 0)
+		return
+	}
+	// the latency of the stream is meaningless, only the opening is counted
+	m.metrics.observe(r.URL.Path, http.StatusOK, 0)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		http.Error(w, "Error parsing label selector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := &watchFilter{
+		namespace: query.Get("namespace"),
+		hostIP:    normalizeIP(query.Get("hostIP")),
+		selector:  selector,
+	}
+	if fields := query.Get("fields"); fields != "" {
+		if filter.fields, err = newPodFieldProjection(strings.Split(fields, ",")); err != nil {
+			http.Error(w, "Error parsing fields: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// register before listing the pods so that no event is lost in between,
+	// the events already covered by the list are sent as updates.
+	podCache := m.metaManager.cacheMap[POD]
+	eventCh := make(chan *K8sMetaEvent, watchEventBufferSize)
+	overflowCh := make(chan struct{})
+	overflowed := false
+	watchKey := "watch/" + strconv.FormatInt(m.watchSeq.Add(1), 10)
+	podCache.RegisterWatchFunc(watchKey, func(events []*K8sMetaEvent) {
+		if overflowed {
+			return
+		}
+		for _, event := range events {
+			select {
+			case eventCh <- event:
+			default:
+				overflowed = true
+				close(overflowCh)
+				return
+			}
+		}
+	})
+	defer podCache.UnRegisterSendFunc(watchKey)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	matched := make(map[string]struct{})
+	objs := podCache.Filter(func(ow *ObjectWrapper) bool {
+		if ow.Deleted {
+			return false
+		}
+		pod, ok := ow.Raw.(*v1.Pod)
+		return ok && filter.matches(pod)
+	}, 0)
+	for _, obj := range objs {
+		if event := m.toWatchEvent(matched, filter, EventTypeAdd, obj); event != nil {
+			if err := writeWatchEvent(w, event); err != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeatPeriod)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event := <-eventCh:
+			watchEvent := m.toWatchEvent(matched, filter, event.EventType, event.Object)
+			if watchEvent == nil {
+				continue
+			}
+			if err := writeWatchEvent(w, watchEvent); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-overflowCh:
+			logger.Warning(context.Background(), "K8S_META_SERVER_ALARM", "watch client is too slow, disconnect it", r.RemoteAddr)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// toWatchEvent converts the pod event to the event seen by the watcher according to the matched pods,
+// nil is returned if the watcher is not interested in it.
+func (m *metadataHandler) toWatchEvent(matched map[string]struct{}, filter *watchFilter, eventType string, obj *ObjectWrapper) *watchEvent[interface{}] {
+	pod, ok := obj.Raw.(*v1.Pod)
+	if !ok {
+		return nil
+	}
+	key := generateNameWithNamespaceKey(pod.Namespace, pod.Name)
+	_, wasMatched := matched[key]
+	if eventType != EventTypeDelete && filter.matches(pod) {
+		matched[key] = struct{}{}
+		eventType = EventTypeAdd
+		if wasMatched {
+			eventType = EventTypeUpdate
+		}
+	} else {
+		if !wasMatched {
+			return nil
+		}
+		delete(matched, key)
+		eventType = EventTypeDelete
+	}
+	podMetadata := m.convertObj2PodResponse(obj)
+	podMetadata.IsDeleted = eventType == EventTypeDelete
+	return &watchEvent[interface{}]{
+		Type:     eventType,
+		Key:      key,
+		Metadata: filter.fields.project(podMetadata),
+	}
+}
+
+func writeWatchEvent(w http.ResponseWriter, event *watchEvent[interface{}]) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_SERVER_ALARM", "marshal watch event error", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
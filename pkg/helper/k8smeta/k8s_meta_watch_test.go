@@ -0,0 +1,128 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleWatch(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	podCache := newK8sMetaCache(stopCh, POD)
+	newPod := func(name, hostIP string, podLabels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    podLabels,
+			},
+			Status: corev1.PodStatus{
+				HostIP: hostIP,
+				PodIP:  "10.0.0." + strings.TrimPrefix(name, "pod"),
+			},
+		}
+	}
+	podCache.metaStore.Items["default/pod1"] = &ObjectWrapper{Raw: newPod("pod1", "192.168.0.1", map[string]string{"app": "web"})}
+	podCache.metaStore.Items["default/pod2"] = &ObjectWrapper{Raw: newPod("pod2", "192.168.0.1", map[string]string{"app": "db"})}
+	podCache.metaStore.Items["default/pod3"] = &ObjectWrapper{Raw: newPod("pod3", "192.168.0.2", map[string]string{"app": "web"})}
+	podCache.metaStore.Start()
+	manager.cacheMap[POD] = podCache
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+
+	server := httptest.NewServer(http.HandlerFunc(newMetadataHandler(manager).handleWatch))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "?hostIP=192.168.0.1&labelSelector=app%3Dweb")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan *watchEvent[*PodMetadata], 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var event watchEvent[*PodMetadata]
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event) == nil {
+				events <- &event
+			}
+		}
+		close(events)
+	}()
+	nextEvent := func() *watchEvent[*PodMetadata] {
+		select {
+		case event := <-events:
+			require.NotNil(t, event)
+			return event
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no watch event received")
+		}
+		return nil
+	}
+
+	event := nextEvent()
+	assert.Equal(t, EventTypeAdd, event.Type)
+	assert.Equal(t, "default/pod1", event.Key)
+	assert.Equal(t, "10.0.0.1", event.Metadata.PodIP)
+
+	// pod3 is on another host and is ignored, pod2 starts to match the selector
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod3", "192.168.0.2", map[string]string{"app": "web", "v": "2"})}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod2", "192.168.0.1", map[string]string{"app": "web"})}}
+	event = nextEvent()
+	assert.Equal(t, EventTypeAdd, event.Type)
+	assert.Equal(t, "default/pod2", event.Key)
+
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod2", "192.168.0.1", map[string]string{"app": "web", "v": "2"})}}
+	event = nextEvent()
+	assert.Equal(t, EventTypeUpdate, event.Type)
+	assert.Equal(t, map[string]string{"app": "web", "v": "2"}, event.Metadata.Labels)
+
+	// pod1 no longer matches the selector and pod2 is deleted
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod1", "192.168.0.1", map[string]string{"app": "db"})}}
+	event = nextEvent()
+	assert.Equal(t, EventTypeDelete, event.Type)
+	assert.Equal(t, "default/pod1", event.Key)
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: newPod("pod2", "192.168.0.1", map[string]string{"app": "web"})}}
+	event = nextEvent()
+	assert.Equal(t, EventTypeDelete, event.Type)
+	assert.Equal(t, "default/pod2", event.Key)
+}
+
+func TestHandleWatchInvalidSelector(t *testing.T) {
+	manager := GetMetaManagerInstance()
+	manager.ready.Store(true)
+	defer manager.ready.Store(false)
+	req := httptest.NewRequest(http.MethodGet, "/metadata/watch?labelSelector=app%3D%3D%3Dweb", nil)
+	w := httptest.NewRecorder()
+	newMetadataHandler(manager).handleWatch(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
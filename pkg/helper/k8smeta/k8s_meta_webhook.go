@@ -0,0 +1,241 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	// webhookURLsEnv enables pushing the changes of the pod metadata to the comma separated urls.
+	webhookURLsEnv          = "KUBERNETES_METADATA_WEBHOOK_URLS"
+	webhookTokenEnv         = "KUBERNETES_METADATA_WEBHOOK_TOKEN"
+	webhookBatchSizeEnv     = "KUBERNETES_METADATA_WEBHOOK_BATCH_SIZE"
+	webhookFlushIntervalEnv = "KUBERNETES_METADATA_WEBHOOK_FLUSH_INTERVAL_MS"
+	webhookMaxRetriesEnv    = "KUBERNETES_METADATA_WEBHOOK_MAX_RETRIES"
+
+	defaultWebhookBatchSize     = 100
+	defaultWebhookFlushInterval = time.Second
+	defaultWebhookMaxRetries    = 5
+	webhookQueueSize            = 10000
+	webhookTimeout              = 10 * time.Second
+	webhookInitialBackoff       = 500 * time.Millisecond
+	webhookMaxBackoff           = 30 * time.Second
+	webhookWatchKey             = "webhook"
+)
+
+// webhookBatch is the body posted to the webhook, the events are in the order they happen.
+type webhookBatch struct {
+	Events []*watchEvent[*PodMetadata] `json:"events"`
+}
+
+// metaWebhookNotifier posts the add, update and delete events of the pods to the webhooks in batches, so that the
+// consumers without the agent sidecar keep their local caches warm without polling. Each webhook has its own queue,
+// a slow one does not delay the others, and the events are dropped when its queue is full.
+type metaWebhookNotifier struct {
+	metaManager    *MetaManager
+	token          string
+	batchSize      int
+	flushInterval  time.Duration
+	maxRetries     int
+	initialBackoff time.Duration
+	client         *http.Client
+	sinks          []*webhookSink
+}
+
+type webhookSink struct {
+	url     string
+	queue   chan *watchEvent[*PodMetadata]
+	dropped atomic.Int64
+}
+
+// newMetaWebhookNotifierFromEnv returns nil if no webhook is configured, and all methods of a nil notifier are no-op.
+func newMetaWebhookNotifierFromEnv(metaManager *MetaManager) *metaWebhookNotifier {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv(webhookURLsEnv), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	n := newMetaWebhookNotifier(metaManager, urls)
+	n.token = os.Getenv(webhookTokenEnv)
+	if batchSize := intFromEnv(webhookBatchSizeEnv); batchSize > 0 {
+		n.batchSize = batchSize
+	}
+	if interval := intFromEnv(webhookFlushIntervalEnv); interval > 0 {
+		n.flushInterval = time.Duration(interval) * time.Millisecond
+	}
+	if os.Getenv(webhookMaxRetriesEnv) != "" {
+		n.maxRetries = intFromEnv(webhookMaxRetriesEnv)
+	}
+	return n
+}
+
+func newMetaWebhookNotifier(metaManager *MetaManager, urls []string) *metaWebhookNotifier {
+	n := &metaWebhookNotifier{
+		metaManager:    metaManager,
+		batchSize:      defaultWebhookBatchSize,
+		flushInterval:  defaultWebhookFlushInterval,
+		maxRetries:     defaultWebhookMaxRetries,
+		initialBackoff: webhookInitialBackoff,
+		client:         &http.Client{Timeout: webhookTimeout},
+	}
+	for _, url := range urls {
+		n.sinks = append(n.sinks, &webhookSink{
+			url:   url,
+			queue: make(chan *watchEvent[*PodMetadata], webhookQueueSize),
+		})
+	}
+	return n
+}
+
+// start subscribes the pod events and posts them until stopped, it must be called before the caches start watching
+// so that the pods listed on start are pushed as add events.
+func (n *metaWebhookNotifier) start(stopCh <-chan struct{}) {
+	if n == nil {
+		return
+	}
+	podCache, ok := n.metaManager.cacheMap[POD]
+	if !ok {
+		return
+	}
+	podCache.RegisterWatchFunc(webhookWatchKey, n.notify)
+	for _, sink := range n.sinks {
+		go n.run(sink, stopCh)
+	}
+	logger.Info(context.Background(), "k8s meta webhook", "start", "urls", len(n.sinks))
+}
+
+// notify is called in the event handling goroutine and must not block.
+func (n *metaWebhookNotifier) notify(events []*K8sMetaEvent) {
+	for _, event := range events {
+		pod, ok := event.Object.Raw.(*v1.Pod)
+		if !ok {
+			continue
+		}
+		podMetadata := n.metaManager.metadataHandler.convertObj2PodResponse(event.Object)
+		podMetadata.IsDeleted = event.EventType == EventTypeDelete
+		webhookEvent := &watchEvent[*PodMetadata]{
+			Type:     event.EventType,
+			Key:      generateNameWithNamespaceKey(pod.Namespace, pod.Name),
+			Metadata: podMetadata,
+		}
+		for _, sink := range n.sinks {
+			select {
+			case sink.queue <- webhookEvent:
+			default:
+				sink.dropped.Add(1)
+			}
+		}
+	}
+}
+
+func (n *metaWebhookNotifier) run(sink *webhookSink, stopCh <-chan struct{}) {
+	defer panicRecover()
+	ticker := time.NewTicker(n.flushInterval)
+	defer ticker.Stop()
+	batch := make([]*watchEvent[*PodMetadata], 0, n.batchSize)
+	flush := func() {
+		if dropped := sink.dropped.Swap(0); dropped > 0 {
+			logger.Warning(context.Background(), "K8S_META_WEBHOOK_ALARM", "webhook queue is full, drop events", dropped, "url", sink.url)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		n.send(sink.url, batch, stopCh)
+		batch = make([]*watchEvent[*PodMetadata], 0, n.batchSize)
+	}
+	for {
+		select {
+		case event := <-sink.queue:
+			batch = append(batch, event)
+			if len(batch) >= n.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// send posts the batch, and retries with exponential backoff on the network errors, 429 and 5xx responses.
+func (n *metaWebhookNotifier) send(url string, batch []*watchEvent[*PodMetadata], stopCh <-chan struct{}) {
+	body, err := json.Marshal(&webhookBatch{Events: batch})
+	if err != nil {
+		logger.Error(context.Background(), "K8S_META_WEBHOOK_ALARM", "marshal webhook batch error", err)
+		return
+	}
+	backoff := n.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.post(url, body)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt >= n.maxRetries {
+			logger.Warning(context.Background(), "K8S_META_WEBHOOK_ALARM", "post webhook error, drop events", len(batch),
+				"url", url, "attempts", attempt+1, "error", err)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-stopCh:
+			return
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+func (n *metaWebhookNotifier) post(url string, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8smeta
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWebhookNotifierPushBatches(t *testing.T) {
+	batches := make(chan *webhookBatch, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		batch := &webhookBatch{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(batch))
+		batches <- batch
+	}))
+	defer server.Close()
+
+	manager := GetMetaManagerInstance()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	podCache := newK8sMetaCache(stopCh, POD)
+	podCache.metaStore.Start()
+	manager.cacheMap[POD] = podCache
+
+	notifier := newMetaWebhookNotifier(manager, []string{server.URL})
+	notifier.token = "secret"
+	notifier.batchSize = 2
+	notifier.flushInterval = 50 * time.Millisecond
+	notifier.start(stopCh)
+	defer podCache.UnRegisterSendFunc(webhookWatchKey)
+
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+		}
+	}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeAdd, Object: &ObjectWrapper{Raw: newPod("pod1")}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeUpdate, Object: &ObjectWrapper{Raw: newPod("pod1")}}
+	podCache.eventCh <- &K8sMetaEvent{EventType: EventTypeDelete, Object: &ObjectWrapper{Raw: newPod("pod1")}}
+
+	nextBatch := func() *webhookBatch {
+		select {
+		case batch := <-batches:
+			return batch
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no webhook batch received")
+		}
+		return nil
+	}
+	// the first batch is full, the second one is flushed by the interval
+	batch := nextBatch()
+	require.Len(t, batch.Events, 2)
+	assert.Equal(t, EventTypeAdd, batch.Events[0].Type)
+	assert.Equal(t, "default/pod1", batch.Events[0].Key)
+	assert.Equal(t, "10.0.0.1", batch.Events[0].Metadata.PodIP)
+	assert.Equal(t, EventTypeUpdate, batch.Events[1].Type)
+	batch = nextBatch()
+	require.Len(t, batch.Events, 1)
+	assert.Equal(t, EventTypeDelete, batch.Events[0].Type)
+	assert.True(t, batch.Events[0].Metadata.IsDeleted)
+}
+
+func TestWebhookNotifierRetry(t *testing.T) {
+	var attempts atomic.Int32
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCodes[attempts.Add(1)-1])
+	}))
+	defer server.Close()
+
+	notifier := newMetaWebhookNotifier(GetMetaManagerInstance(), []string{server.URL})
+	notifier.initialBackoff = time.Millisecond
+	batch := []*watchEvent[*PodMetadata]{{Type: EventTypeAdd, Key: "default/pod1", Metadata: &PodMetadata{}}}
+	notifier.send(server.URL, batch, make(chan struct{}))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// the retries are limited
+	attempts.Store(0)
+	statusCodes = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	notifier.maxRetries = 1
+	notifier.send(server.URL, batch, make(chan struct{}))
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// the client errors are not retried
+	attempts.Store(0)
+	statusCodes = []int{http.StatusBadRequest, http.StatusOK}
+	notifier.send(server.URL, batch, make(chan struct{}))
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestNewMetaWebhookNotifierFromEnv(t *testing.T) {
+	assert.Nil(t, newMetaWebhookNotifierFromEnv(GetMetaManagerInstance()))
+
+	t.Setenv(webhookURLsEnv, "http://a/hook, ,http://b/hook")
+	t.Setenv(webhookBatchSizeEnv, "10")
+	t.Setenv(webhookMaxRetriesEnv, "0")
+	notifier := newMetaWebhookNotifierFromEnv(GetMetaManagerInstance())
+	require.NotNil(t, notifier)
+	require.Len(t, notifier.sinks, 2)
+	assert.Equal(t, "http://b/hook", notifier.sinks[1].url)
+	assert.Equal(t, 10, notifier.batchSize)
+	assert.Equal(t, defaultWebhookFlushInterval, notifier.flushInterval)
+	assert.Equal(t, 0, notifier.maxRetries)
+}
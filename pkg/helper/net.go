@@ -15,7 +15,22 @@
 package helper
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+
+	// defaultFallbackDelay is the head start of the preferred address family, the same as the one recommended by
+	// RFC 8305 and used by net.Dialer.
+	defaultFallbackDelay = 300 * time.Millisecond
 )
 
 func GetFreePort() (port int, err error) {
@@ -26,3 +41,165 @@ func GetFreePort() (port int, err error) {
 	defer listener.Close()
 	return listener.Addr().(*net.TCPAddr).Port, nil
 }
+
+// ParseListenAddress splits the listening address of the tcp servers into the network and the address for net.Listen.
+// The address is host:port, [ipv6]:port or :port for all the interfaces of both families, optionally with the scheme
+// http, https or tcp, while tcp4 and tcp6 restrict the family, e.g. tcp6://[::]:8080 to listen on ipv6 only.
+func ParseListenAddress(address string) (network string, host string, err error) {
+	if !strings.Contains(address, "://") {
+		return "tcp", address, nil
+	}
+	configURL, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	switch configURL.Scheme {
+	case "http", "https", "tcp":
+		return "tcp", configURL.Host, nil
+	case "tcp4", "tcp6":
+		return configURL.Scheme, configURL.Host, nil
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %q in listening address %q", configURL.Scheme, address)
+	}
+}
+
+// DialConfig controls the address family of the outgoing connections to the hosts resolved to both ipv4 and ipv6
+// addresses. The connection attempts follow happy eyeballs (RFC 8305): the preferred family is tried first and the
+// other one is raced after FallbackDelay.
+type DialConfig struct {
+	// PreferredAddressFamily is ipv4 or ipv6, the order of the resolver is kept if empty.
+	PreferredAddressFamily string
+	// FallbackDelay is the head start of the preferred family, default is 300ms.
+	FallbackDelay time.Duration
+}
+
+func (c *DialConfig) Validate() error {
+	switch c.PreferredAddressFamily {
+	case "", AddressFamilyIPv4, AddressFamilyIPv6:
+		return nil
+	default:
+		return fmt.Errorf("invalid PreferredAddressFamily %q, should be %q or %q", c.PreferredAddressFamily, AddressFamilyIPv4, AddressFamilyIPv6)
+	}
+}
+
+// DialContext returns the dial function of dialer honoring the preference, which can be used as
+// http.Transport.DialContext.
+func (c *DialConfig) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if c.FallbackDelay > 0 {
+		dialer.FallbackDelay = c.FallbackDelay
+	}
+	if c.PreferredAddressFamily == "" {
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network != "tcp" && network != "udp" {
+			return dialer.DialContext(ctx, network, address)
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		resolver := dialer.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		ips, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var primaries, fallbacks []string
+		for _, ip := range ips {
+			isIPv4 := ip.IP.To4() != nil
+			if isIPv4 == (c.PreferredAddressFamily == AddressFamilyIPv4) {
+				primaries = append(primaries, net.JoinHostPort(ip.String(), port))
+			} else {
+				fallbacks = append(fallbacks, net.JoinHostPort(ip.String(), port))
+			}
+		}
+		if len(primaries) == 0 || len(fallbacks) == 0 {
+			return dialSerial(ctx, dialer, network, append(primaries, fallbacks...))
+		}
+		return c.dialParallel(ctx, dialer, network, primaries, fallbacks)
+	}
+}
+
+// dialParallel races the fallback addresses against the primary ones once the primaries fail or take longer than
+// the fallback delay, and returns the first established connection.
+func (c *DialConfig) dialParallel(ctx context.Context, dialer *net.Dialer, network string, primaries, fallbacks []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan dialResult, 2)
+	start := func(addresses []string) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, network, addresses)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+	fallbackDelay := c.FallbackDelay
+	if fallbackDelay <= 0 {
+		fallbackDelay = defaultFallbackDelay
+	}
+	timer := time.NewTimer(fallbackDelay)
+	defer timer.Stop()
+
+	start(primaries)
+	pending, fallbackStarted := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					// the loser may still connect before it is canceled
+					go func() {
+						if res := <-results; res.conn != nil {
+							_ = res.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+func dialSerial(ctx context.Context, dialer *net.Dialer, network string, addresses []string) (net.Conn, error) {
+	var firstErr error
+	for _, address := range addresses {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no address to dial")
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListenAddress(t *testing.T) {
+	cases := []struct {
+		address, network, host string
+	}{
+		{":8080", "tcp", ":8080"},
+		{"[::1]:8080", "tcp", "[::1]:8080"},
+		{"http://0.0.0.0:8080", "tcp", "0.0.0.0:8080"},
+		{"https://[::]:8443", "tcp", "[::]:8443"},
+		{"tcp4://:8080", "tcp4", ":8080"},
+		{"tcp6://[::]:8080", "tcp6", "[::]:8080"},
+	}
+	for _, c := range cases {
+		network, host, err := ParseListenAddress(c.address)
+		require.NoError(t, err, c.address)
+		assert.Equal(t, c.network, network, c.address)
+		assert.Equal(t, c.host, host, c.address)
+	}
+	_, _, err := ParseListenAddress("udp://:8080")
+	assert.Error(t, err)
+}
+
+func TestDialConfigValidate(t *testing.T) {
+	assert.NoError(t, (&DialConfig{}).Validate())
+	assert.NoError(t, (&DialConfig{PreferredAddressFamily: AddressFamilyIPv6}).Validate())
+	assert.Error(t, (&DialConfig{PreferredAddressFamily: "ipv5"}).Validate())
+}
+
+func listenLocal(t *testing.T, network, address string) net.Listener {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", address, err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return listener
+}
+
+func TestDialConfigDialParallel(t *testing.T) {
+	ipv4 := listenLocal(t, "tcp4", "127.0.0.1:0")
+	ipv6 := listenLocal(t, "tcp6", "[::1]:0")
+	closed := listenLocal(t, "tcp4", "127.0.0.1:0")
+	closedAddress := closed.Addr().String()
+	_ = closed.Close()
+
+	c := &DialConfig{PreferredAddressFamily: AddressFamilyIPv6, FallbackDelay: time.Second}
+	dialer := &net.Dialer{Timeout: time.Second}
+
+	// the preferred family wins when it is reachable
+	conn, err := c.dialParallel(context.Background(), dialer, "tcp", []string{ipv6.Addr().String()}, []string{ipv4.Addr().String()})
+	require.NoError(t, err)
+	assert.Equal(t, ipv6.Addr().String(), conn.RemoteAddr().String())
+	_ = conn.Close()
+
+	// the fallback is raced at once when the preferred family fails
+	start := time.Now()
+	conn, err = c.dialParallel(context.Background(), dialer, "tcp", []string{closedAddress}, []string{ipv4.Addr().String()})
+	require.NoError(t, err)
+	assert.Equal(t, ipv4.Addr().String(), conn.RemoteAddr().String())
+	assert.Less(t, time.Since(start), time.Second)
+	_ = conn.Close()
+
+	_, err = c.dialParallel(context.Background(), dialer, "tcp", []string{closedAddress}, []string{closedAddress})
+	assert.Error(t, err)
+}
@@ -80,6 +80,8 @@ type Data struct {
 	zone         string
 	instanceType string
 	imageID      string
+	hostname     string
+	privateIP    string
 
 	// dynamic changed meta
 	name          string
@@ -173,6 +175,8 @@ func (m *ECSManager) fetchAPI() {
 		asyncReadMetaFunc("/meta-data/zone-id", "", func(key, val string) { m.data.zone = val })
 		asyncReadMetaFunc("/meta-data/image-id", "", func(key, val string) { m.data.imageID = val })
 		asyncReadMetaFunc("/meta-data/instance/instance-type", "", func(key, val string) { m.data.instanceType = val })
+		asyncReadMetaFunc("/meta-data/hostname", "", func(key, val string) { m.data.hostname = val })
+		asyncReadMetaFunc("/meta-data/private-ipv4", "", func(key, val string) { m.data.privateIP = val })
 		for i := 0; i < asyncCount; i++ {
 			ok := <-m.resChan
 			success = success && ok
@@ -253,6 +257,20 @@ func (m *ECSManager) GetInstanceRegion() string {
 	return m.data.region
 }
 
+func (m *ECSManager) GetInstanceHostname() string {
+	if !m.fetchRes {
+		return ""
+	}
+	return m.data.hostname
+}
+
+func (m *ECSManager) GetInstancePrivateIP() string {
+	if !m.fetchRes {
+		return ""
+	}
+	return m.data.privateIP
+}
+
 func (m *ECSManager) GetInstanceZone() string {
 	if !m.fetchRes {
 		return ""
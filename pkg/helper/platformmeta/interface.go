@@ -42,6 +42,8 @@ type Manager interface {
 	GetInstanceRegion() string
 	GetInstanceZone() string
 	GetInstanceName() string
+	GetInstanceHostname() string
+	GetInstancePrivateIP() string
 	GetInstanceVpcID() string
 	GetInstanceVswitchID() string
 	GetInstanceMaxNetEgress() int64
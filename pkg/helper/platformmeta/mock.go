@@ -49,6 +49,14 @@ func (m *MockManager) GetInstanceName() string {
 	return "name_xxx"
 }
 
+func (m *MockManager) GetInstanceHostname() string {
+	return "hostname_xxx"
+}
+
+func (m *MockManager) GetInstancePrivateIP() string {
+	return "192.168.0.100"
+}
+
 func (m *MockManager) GetInstanceVpcID() string {
 	return "vpc_xxx"
 }
@@ -0,0 +1,53 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"io"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// DecodePrometheusText decodes the metrics in the Prometheus text format and calls handle for each sample,
+// the samples without timestamp are stamped with now.
+func DecodePrometheusText(r io.Reader, now time.Time, handle func(sample *model.Sample)) error {
+	sampleDecoder := expfmt.SampleDecoder{
+		Dec:  expfmt.NewDecoder(r, expfmt.FmtText),
+		Opts: &expfmt.DecodeOptions{Timestamp: model.TimeFromUnixNano(now.UnixNano())},
+	}
+	for {
+		samples := model.Vector{}
+		if err := sampleDecoder.Decode(&samples); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		for _, sample := range samples {
+			handle(sample)
+		}
+	}
+}
+
+// AppendSampleLabels appends the labels of the sample except the metric name to the labels.
+func AppendSampleLabels(labels *MetricLabels, metric model.Metric) {
+	for k, v := range metric {
+		if k != model.MetricNameLabel {
+			labels.Append(string(k), string(v))
+		}
+	}
+}
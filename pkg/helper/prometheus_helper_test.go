@@ -0,0 +1,50 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePrometheusText(t *testing.T) {
+	text := `# TYPE up gauge
+up{job="a"} 1
+# TYPE requests_total counter
+requests_total{code="200",method="get"} 10 1691646109945
+`
+	now := time.Unix(1700000000, 0)
+	var logs []string
+	err := DecodePrometheusText(strings.NewReader(text), now, func(sample *model.Sample) {
+		labels := &MetricLabels{}
+		labels.Append("instance", "host1")
+		AppendSampleLabels(labels, sample.Metric)
+		log := NewMetricLog(string(sample.Metric[model.MetricNameLabel]), sample.Timestamp.UnixNano(), float64(sample.Value), labels)
+		logs = append(logs, log.Contents[0].Value+" "+log.Contents[1].Value+" "+log.Contents[2].Value+" "+log.Contents[3].Value)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"up 1700000000000000000 instance#$#host1|job#$#a 1",
+		"requests_total 1691646109945000000 code#$#200|instance#$#host1|method#$#get 10",
+	}, logs)
+
+	err = DecodePrometheusText(strings.NewReader("up{job=\"a\" 1\n"), now, func(sample *model.Sample) {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	retryAfterHeader = "Retry-After"
+
+	// timestamps larger than it are regarded as unix epoch seconds rather than delta seconds in reset headers.
+	epochSecondsThreshold = 1000000000
+)
+
+// rateLimitHeaderPrefixes are the prefixes of the widely used quota headers,
+// e.g. X-RateLimit-Remaining/X-RateLimit-Reset and the IETF draft RateLimit-Remaining/RateLimit-Reset.
+var rateLimitHeaderPrefixes = []string{"X-RateLimit-", "RateLimit-", "X-Rate-Limit-"}
+
+// QuotaThrottle records the quota state reported by a remote endpoint through
+// standard quota headers (Retry-After, X-RateLimit-*), so that flushers can pause
+// sending until the quota is restored. It is safe for concurrent use.
+type QuotaThrottle struct {
+	mu      sync.RWMutex
+	until   time.Time
+	maxWait time.Duration
+	now     func() time.Time
+}
+
+// NewQuotaThrottle creates a QuotaThrottle which is not throttled.
+// @maxWait caps a single wait reported by the endpoint, 0 means no cap.
+func NewQuotaThrottle(maxWait time.Duration) *QuotaThrottle {
+	return &QuotaThrottle{maxWait: maxWait, now: time.Now}
+}
+
+// Observe parses the quota headers of the response and extends the throttle window if needed.
+// It returns the wait duration reported by the response, 0 means no throttle is required.
+func (t *QuotaThrottle) Observe(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	now := t.now()
+	wait := ParseQuotaHeaders(resp.StatusCode, resp.Header, now)
+	if wait <= 0 {
+		return 0
+	}
+	if t.maxWait > 0 && wait > t.maxWait {
+		wait = t.maxWait
+	}
+	t.mu.Lock()
+	if until := now.Add(wait); until.After(t.until) {
+		t.until = until
+	}
+	t.mu.Unlock()
+	return wait
+}
+
+// Throttled returns true if the remote endpoint asked to pause sending.
+func (t *QuotaThrottle) Throttled() bool {
+	return t.Remaining() > 0
+}
+
+// Remaining returns how long the sender should still wait.
+func (t *QuotaThrottle) Remaining() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.until.IsZero() {
+		return 0
+	}
+	if d := t.until.Sub(t.now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Wait blocks until the throttle window ends or stop is closed.
+func (t *QuotaThrottle) Wait(stop <-chan struct{}) {
+	d := t.Remaining()
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+}
+
+// ParseQuotaHeaders returns how long the sender should wait according to the response status and headers.
+// Retry-After is honored for any status, and the rate limit headers are honored only when the remaining quota is exhausted.
+func ParseQuotaHeaders(statusCode int, header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if v := header.Get(retryAfterHeader); v != "" {
+		if d, ok := parseRetryAfter(v, now); ok {
+			return d
+		}
+	}
+	for _, prefix := range rateLimitHeaderPrefixes {
+		remaining := header.Get(prefix + "Remaining")
+		if remaining == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(firstToken(remaining)), 64)
+		if err != nil || n > 0 {
+			// rate limit headers of the same family are exclusive, no need to check the others.
+			return 0
+		}
+		if d, ok := parseRateLimitReset(header.Get(prefix+"Reset"), now); ok {
+			return d
+		}
+		return time.Second
+	}
+	if statusCode == http.StatusTooManyRequests {
+		// the endpoint is throttling without telling us for how long.
+		return time.Second
+	}
+	return 0
+}
+
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func parseRateLimitReset(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(firstToken(v))
+	if v == "" {
+		return 0, false
+	}
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	if secs > epochSecondsThreshold {
+		reset := time.Unix(0, int64(secs*float64(time.Second)))
+		if d := reset.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// firstToken returns the first item of the header value, e.g. "100, 100;w=60" -> "100".
+func firstToken(v string) string {
+	if idx := strings.IndexAny(v, ",;"); idx >= 0 {
+		return v[:idx]
+	}
+	return v
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuotaHeaders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cases := []struct {
+		name   string
+		status int
+		header map[string]string
+		want   time.Duration
+	}{
+		{"no headers", http.StatusOK, nil, 0},
+		{"retry after seconds", http.StatusServiceUnavailable, map[string]string{"Retry-After": "5"}, 5 * time.Second},
+		{"retry after date", http.StatusTooManyRequests, map[string]string{"Retry-After": now.Add(10 * time.Second).UTC().Format(http.TimeFormat)}, 10 * time.Second},
+		{"quota left", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "3", "X-RateLimit-Reset": "20"}, 0},
+		{"quota exhausted with delta reset", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "20"}, 20 * time.Second},
+		{"quota exhausted with epoch reset", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Unix()+30, 10)}, 30 * time.Second},
+		{"ietf draft headers", http.StatusOK, map[string]string{"RateLimit-Remaining": "0, 0;w=60", "RateLimit-Reset": "7"}, 7 * time.Second},
+		{"quota exhausted without reset", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0"}, time.Second},
+		{"429 without headers", http.StatusTooManyRequests, nil, time.Second},
+		{"invalid retry after", http.StatusOK, map[string]string{"Retry-After": "soon"}, 0},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		for k, v := range c.header {
+			header.Set(k, v)
+		}
+		assert.Equal(t, c.want, ParseQuotaHeaders(c.status, header, now), c.name)
+	}
+}
+
+func TestQuotaThrottle(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	throttle := NewQuotaThrottle(time.Minute)
+	throttle.now = func() time.Time { return now }
+	assert.False(t, throttle.Throttled())
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "10")
+	assert.Equal(t, 10*time.Second, throttle.Observe(resp))
+	assert.True(t, throttle.Throttled())
+
+	// a shorter window never shrinks the current one
+	resp.Header.Set("Retry-After", "1")
+	throttle.Observe(resp)
+	assert.Equal(t, 10*time.Second, throttle.Remaining())
+
+	now = now.Add(11 * time.Second)
+	assert.False(t, throttle.Throttled())
+
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(t, time.Minute, throttle.Observe(resp))
+	now = now.Add(time.Minute)
+	assert.False(t, throttle.Throttled())
+
+	stop := make(chan struct{})
+	close(stop)
+	throttle.Wait(stop)
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+// builtinMetricDescriptions describes the metrics shared by plugins, the kind is filled by the metric type.
+var builtinMetricDescriptions = map[string]pipeline.MetricDescription{
+	MetricPluginInEventsTotal:       {Unit: "events", Help: "Number of events received by the plugin."},
+	MetricPluginInEventGroupsTotal:  {Unit: "groups", Help: "Number of event groups received by the plugin."},
+	MetricPluginInSizeBytes:         {Unit: "bytes", Help: "Size of the events received by the plugin."},
+	MetricPluginOutEventsTotal:      {Unit: "events", Help: "Number of events emitted by the plugin."},
+	MetricPluginOutEventGroupsTotal: {Unit: "groups", Help: "Number of event groups emitted by the plugin."},
+	MetricPluginOutSizeBytes:        {Unit: "bytes", Help: "Size of the events emitted by the plugin."},
+	MetricPluginTotalDelayMs:        {Unit: "ms", Help: "Total delay between the event time and the time the plugin handles it."},
+	MetricPluginTotalProcessTimeMs:  {Unit: "ms", Help: "Total time the plugin spends on handling events."},
+	MetricPluginDroppedEventsTotal:  {Unit: "events", Help: "Number of events dropped by the processor, partitioned by drop_reason."},
+
+	MetricPluginEndToEndAvgLatencyMs: {Unit: "ms", Help: "Average latency of the events from entering the pipeline to being flushed."},
+	MetricPluginEndToEndMaxLatencyMs: {Unit: "ms", Help: "Max latency of the events from entering the pipeline to being flushed."},
+
+	MetricPluginProcessTimeSharePercent: {Unit: "percent", Help: "Share of the sampled processing time of the pipeline taken by the processor in the last window."},
+
+	MetricPluginDiscardedEventsTotal:      {Unit: "events", Help: "Number of events discarded by the processor."},
+	MetricPluginOutFailedEventsTotal:      {Unit: "events", Help: "Number of events the processor failed to parse."},
+	MetricPluginOutKeyNotFoundEventsTotal: {Unit: "events", Help: "Number of events without the source key."},
+	MetricPluginOutSuccessfulEventsTotal:  {Unit: "events", Help: "Number of events parsed successfully."},
+	PluginPairsPerLogTotal:                {Unit: "pairs", Help: "Number of key-value pairs extracted from the logs."},
+
+	MetricPluginContainerTotal:       {Unit: "containers", Help: "Number of containers discovered."},
+	MetricPluginAddContainerTotal:    {Unit: "containers", Help: "Number of containers added."},
+	MetricPluginRemoveContainerTotal: {Unit: "containers", Help: "Number of containers removed."},
+	MetricPluginUpdateContainerTotal: {Unit: "containers", Help: "Number of containers updated."},
+
+	MetricPluginMatchedFileTotal: {Unit: "files", Help: "Number of files matched by the path patterns."},
+
+	MetricPluginCollectAvgCostTimeMs: {Unit: "ms", Help: "Average time cost of a collection."},
+	MetricPluginCollectTotal:         {Help: "Number of collections."},
+}
+
+// metricUnitSuffixes infers the unit from the metric name following the naming conventions of self metrics.
+var metricUnitSuffixes = []struct {
+	suffix string
+	unit   string
+}{
+	{"_bytes", "bytes"},
+	{"_ms", "ms"},
+	{"_us", "us"},
+	{"_ns", "ns"},
+	{"_seconds", "seconds"},
+	{"_mb", "MB"},
+}
+
+// describeMetric returns the description of the builtin metric, or infers the unit from the metric name.
+func describeMetric(name string, metricType pipeline.SelfMetricType) pipeline.MetricDescription {
+	desc, ok := builtinMetricDescriptions[name]
+	if !ok && metricType == pipeline.LatencyType {
+		// latency metrics are always exported in microseconds.
+		desc.Unit = "us"
+	} else if !ok {
+		for _, s := range metricUnitSuffixes {
+			if strings.HasSuffix(name, s.suffix) {
+				desc.Unit = s.unit
+				break
+			}
+		}
+	}
+	desc.Kind = metricType.Kind()
+	return desc
+}
@@ -28,6 +28,7 @@ const (
 	MetricLabelKeyPipelineName   = "pipeline_name"
 	MetricLabelKeyPluginType     = "plugin_type"
 	MetricLabelKeyPluginID       = "plugin_id"
+	MetricLabelKeyDropReason     = "drop_reason"
 )
 
 // label values
@@ -45,6 +46,19 @@ const (
 	MetricPluginOutSizeBytes        = "out_size_bytes"
 	MetricPluginTotalDelayMs        = "total_delay_ms"
 	MetricPluginTotalProcessTimeMs  = "total_process_time_ms"
+	MetricPluginDroppedEventsTotal  = "dropped_events_total"
+
+	// the end-to-end latency of the flushers, the buckets are named as end_to_end_latency_ms_bucket_le_<bound>.
+	MetricPluginEndToEndLatencyBucket = "end_to_end_latency_ms_bucket"
+	MetricPluginEndToEndAvgLatencyMs  = "end_to_end_avg_latency_ms"
+	MetricPluginEndToEndMaxLatencyMs  = "end_to_end_max_latency_ms"
+
+	// the share of the sampled processing time of the pipeline taken by the processor, reported by the processing profile.
+	MetricPluginProcessTimeSharePercent = "process_time_share_percent"
+
+	// the recovered panics of the plugin and whether it is quarantined, reported by the panic isolation.
+	MetricPluginPanicsTotal = "panics_total"
+	MetricPluginQuarantined = "quarantined"
 )
 
 /**********************************************************
@@ -72,6 +86,13 @@ const (
 	MetricPluginUpdateContainerTotal = "update_container_total"
 )
 
+/**********************************************************
+*   metric_tls_cert
+**********************************************************/
+const (
+	MetricPluginMatchedFileTotal = "matched_file_total"
+)
+
 /**********************************************************
 *   service_mysql
 *   service_rdb
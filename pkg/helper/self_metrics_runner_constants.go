@@ -49,7 +49,8 @@ const (
 	MetricRunnerK8sMetaDeleteEventTotal = "delete_event_total"
 	MetricRunnerK8sMetaCacheSize        = "cache_size"
 	MetricRunnerK8sMetaQueueSize        = "queue_size"
-	MetricRunnerK8sMetaHTTPRequestTotal = "http_request_total"
-	MetricRunnerK8sMetaHTTPAvgDelayMs   = "avg_delay_ms"
-	MetricRunnerK8sMetaHTTPMaxDelayMs   = "max_delay_ms"
+
+	MetricRunnerK8sMetaHTTPRejectedTotal = "http_rejected_total"
+	// the requests served with the response of an identical request in flight.
+	MetricRunnerK8sMetaHTTPCoalescedTotal = "http_coalesced_total"
 )
@@ -213,6 +213,7 @@ func NewStringMetricAndRegister(c *pipeline.MetricsRecord, n string, lables ...*
 
 var (
 	_ pipeline.MetricCollector                      = (*MetricVectorImpl[pipeline.CounterMetric])(nil)
+	_ pipeline.MetricDescriber                      = (*MetricVectorImpl[pipeline.CounterMetric])(nil)
 	_ pipeline.MetricSet                            = (*MetricVectorImpl[pipeline.StringMetric])(nil)
 	_ pipeline.MetricVector[pipeline.CounterMetric] = (*MetricVectorImpl[pipeline.CounterMetric])(nil)
 	_ pipeline.MetricVector[pipeline.GaugeMetric]   = (*MetricVectorImpl[pipeline.GaugeMetric])(nil)
@@ -292,6 +293,11 @@ func (v *metricVector) Type() pipeline.SelfMetricType {
 	return v.metricType
 }
 
+// Describe implements pipeline.MetricDescriber, so that MetricsRecord knows the kind and unit of the vector.
+func (v *metricVector) Describe() (string, pipeline.MetricDescription) {
+	return v.name, describeMetric(v.name, v.metricType)
+}
+
 func (v *metricVector) ConstLabels() []pipeline.Label {
 	return v.constLabels
 }
@@ -15,6 +15,7 @@
 package helper
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"testing"
@@ -342,3 +343,26 @@ func Test_NewCounterMetricAndRegister(t *testing.T) {
 	value := counter.Collect()
 	assert.Equal(t, 1.0, value.Value)
 }
+
+func Test_ExportMetricRecordsWithMetadata(t *testing.T) {
+	metricsRecord := &pipeline.MetricsRecord{Context: nil}
+	NewCounterMetricAndRegister(metricsRecord, MetricPluginInEventsTotal).Add(2)
+	NewGaugeMetricAndRegister(metricsRecord, "queue_size_bytes").Set(10)
+	NewLatencyMetricAndRegister(metricsRecord, "flush_latency").Observe(1000)
+	NewCounterMetricAndRegister(metricsRecord, "custom_total").Add(1)
+	metricsRecord.DescribeMetric("custom_total", pipeline.MetricDescription{Unit: "requests", Help: "custom help"})
+
+	record := metricsRecord.ExportMetricRecords()
+	counters := map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(record[pipeline.MetricCounterPrefix]), &counters))
+	assert.Equal(t, map[string]string{MetricPluginInEventsTotal: "2.0000", "custom_total": "1.0000"}, counters)
+
+	metadata := map[string]pipeline.MetricDescription{}
+	assert.NoError(t, json.Unmarshal([]byte(record[pipeline.MetricMetadataPrefix]), &metadata))
+	assert.Equal(t, pipeline.MetricKindCounter, metadata[MetricPluginInEventsTotal].Kind)
+	assert.Equal(t, "events", metadata[MetricPluginInEventsTotal].Unit)
+	assert.NotEmpty(t, metadata[MetricPluginInEventsTotal].Help)
+	assert.Equal(t, pipeline.MetricDescription{Kind: pipeline.MetricKindGauge, Unit: "bytes"}, metadata["queue_size_bytes"])
+	assert.Equal(t, pipeline.MetricDescription{Kind: pipeline.MetricKindGauge, Unit: "us"}, metadata["flush_latency"])
+	assert.Equal(t, pipeline.MetricDescription{Kind: pipeline.MetricKindCounter, Unit: "requests", Help: "custom help"}, metadata["custom_total"])
+}
@@ -55,3 +55,34 @@ type AggregatorV2 interface {
 	// GetResult the current aggregates to the accumulator.
 	GetResult(PipelineContext) error
 }
+
+// AggregatorOutputClass is a class of the aggregator outputs which is flushed with its own cadence.
+type AggregatorOutputClass struct {
+	Name string
+	// flush interval(ms) of the class, if interval is 0, use the interval returned by Init
+	IntervalMs int
+}
+
+// MultiIntervalAggregator is implemented by the aggregator which defines several output classes with different
+// flush cadences, e.g. raw events every 3s and minute rollups every 60s. Each class is flushed independently and
+// emitted as separate groups, instead of being flushed by the single interval returned by Init.
+type MultiIntervalAggregator interface {
+	// OutputClasses is called after Init, an empty result means the aggregator is flushed as usual.
+	OutputClasses() []AggregatorOutputClass
+}
+
+// MultiIntervalAggregatorV1 flushes the aggregates of each output class by FlushClass instead of Flush.
+type MultiIntervalAggregatorV1 interface {
+	AggregatorV1
+	MultiIntervalAggregator
+	// FlushClass pushes the current aggregates of the output class to the accumulator.
+	FlushClass(class string) []*protocol.LogGroup
+}
+
+// MultiIntervalAggregatorV2 flushes the aggregates of each output class by GetClassResult instead of GetResult.
+type MultiIntervalAggregatorV2 interface {
+	AggregatorV2
+	MultiIntervalAggregator
+	// GetClassResult pushes the current aggregates of the output class to the accumulator.
+	GetClassResult(class string, ctx PipelineContext) error
+}
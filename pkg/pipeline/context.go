@@ -34,6 +34,7 @@ const SelfMetricNameKey = "__name__"
 const MetricLabelPrefix = "labels"
 const MetricCounterPrefix = "counters"
 const MetricGaugePrefix = "gauges"
+const MetricMetadataPrefix = "metadata"
 
 type MetricsRecord struct {
 	Context Context
@@ -41,6 +42,7 @@ type MetricsRecord struct {
 
 	sync.RWMutex
 	MetricCollectors []MetricCollector
+	descriptions     map[string]MetricDescription
 }
 
 func (m *MetricsRecord) insertLabels(record map[string]string) {
@@ -56,9 +58,31 @@ func (m *MetricsRecord) RegisterMetricCollector(collector MetricCollector) {
 	m.Lock()
 	defer m.Unlock()
 	m.MetricCollectors = append(m.MetricCollectors, collector)
+	if describer, ok := collector.(MetricDescriber); ok {
+		name, desc := describer.Describe()
+		if _, exist := m.descriptions[name]; !exist {
+			m.describeMetric(name, desc)
+		}
+	}
+}
+
+// DescribeMetric declares the type, unit and help text of the metric with the name.
+// The kind would be inferred from the metric type when exporting if it is empty.
+func (m *MetricsRecord) DescribeMetric(name string, desc MetricDescription) {
+	m.Lock()
+	defer m.Unlock()
+	m.describeMetric(name, desc)
+}
+
+func (m *MetricsRecord) describeMetric(name string, desc MetricDescription) {
+	if m.descriptions == nil {
+		m.descriptions = make(map[string]MetricDescription)
+	}
+	m.descriptions[name] = desc
 }
 
 // ExportMetricRecords is used for exporting metrics records.
+// The metadata is emitted as the metric_metadata tag by the self monitor of the core.
 // It will replace Serialize in the future.
 func (m *MetricsRecord) ExportMetricRecords() map[string]string {
 	m.RLock()
@@ -66,10 +90,11 @@ func (m *MetricsRecord) ExportMetricRecords() map[string]string {
 
 	record := map[string]string{}
 	m.insertLabels(record)
+	counters := map[string]string{}
+	gauges := map[string]string{}
+	metadata := map[string]MetricDescription{}
 	for _, metricCollector := range m.MetricCollectors {
 		metrics := metricCollector.Collect()
-		counters := map[string]string{}
-		gauges := map[string]string{}
 		for _, metric := range metrics {
 			singleMetric := metric.Export()
 			if len(singleMetric) == 0 {
@@ -83,11 +108,20 @@ func (m *MetricsRecord) ExportMetricRecords() map[string]string {
 			if metric.Type() == GaugeType {
 				gauges[valueName] = valueValue
 			}
+			desc := m.descriptions[valueName]
+			if desc.Kind == "" {
+				desc.Kind = metric.Type().Kind()
+			}
+			metadata[valueName] = desc
 		}
-		countersStr, _ := json.Marshal(counters)
-		record[MetricCounterPrefix] = string(countersStr)
-		gaugesStr, _ := json.Marshal(gauges)
-		record[MetricGaugePrefix] = string(gaugesStr)
+	}
+	countersStr, _ := json.Marshal(counters)
+	record[MetricCounterPrefix] = string(countersStr)
+	gaugesStr, _ := json.Marshal(gauges)
+	record[MetricGaugePrefix] = string(gaugesStr)
+	if len(metadata) > 0 {
+		metadataStr, _ := json.Marshal(metadata)
+		record[MetricMetadataPrefix] = string(metadataStr)
 	}
 	return record
 }
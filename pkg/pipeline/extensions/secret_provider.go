@@ -0,0 +1,22 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensions
+
+// SecretProvider resolves the secrets referenced by name in the plugin configs, so that the credentials
+// need not be written in plain text.
+type SecretProvider interface {
+	// GetSecret returns the secret of the name, or an error if it cannot be found.
+	GetSecret(name string) (string, error)
+}
@@ -38,3 +38,21 @@ type ProcessorV2 interface {
 	Processor
 	Process(in *models.PipelineGroupEvents, context PipelineContext)
 }
+
+// DropReasonUnspecified is the reason of dropped events that are not reported by the processor itself.
+const DropReasonUnspecified = "unspecified"
+
+// DropRecorder is the verdict channel from processors to the processor wrapper.
+// Processors call Drop when they decide to drop events, so that the dropped events
+// can be attributed to the processor and the reason in self monitor metrics.
+type DropRecorder interface {
+	// Drop records that count events are dropped for the reason, e.g. "exclude_match".
+	Drop(reason string, count int)
+}
+
+// DropReporter is an optional interface for processors which report the reasons of dropped events.
+// The processor wrapper sets the recorder before Init is called. Events dropped but not reported
+// are attributed to DropReasonUnspecified.
+type DropReporter interface {
+	SetDropRecorder(recorder DropRecorder)
+}
@@ -34,6 +34,40 @@ const (
 	HistogramType
 )
 
+// MetricKind is the kind of a self metric understood by the monitoring systems, e.g. Prometheus.
+type MetricKind string
+
+const (
+	MetricKindCounter   MetricKind = "counter"
+	MetricKindGauge     MetricKind = "gauge"
+	MetricKindHistogram MetricKind = "histogram"
+)
+
+// Kind returns the MetricKind the SelfMetricType is exported as.
+func (t SelfMetricType) Kind() MetricKind {
+	switch t {
+	case CounterType, CumulativeCounterType:
+		return MetricKindCounter
+	case HistogramType:
+		return MetricKindHistogram
+	default:
+		return MetricKindGauge
+	}
+}
+
+// MetricDescription is the self-description of a metric, which is exported together with the metric values,
+// so that the metrics could be used in Prometheus/Grafana without a lookup table.
+type MetricDescription struct {
+	Kind MetricKind `json:"type"`
+	Unit string     `json:"unit,omitempty"` // e.g. "bytes", "ms", "events"
+	Help string     `json:"help,omitempty"`
+}
+
+// MetricDescriber is implemented by the MetricCollector which could describe the metric it collects.
+type MetricDescriber interface {
+	Describe() (name string, desc MetricDescription)
+}
+
 type Label struct {
 	Key   string
 	Value string
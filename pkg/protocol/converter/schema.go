@@ -0,0 +1,149 @@
+// Copyright 2022 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const (
+	SchemaTypeString  = "string"
+	SchemaTypeInteger = "integer"
+	SchemaTypeNumber  = "number"
+	SchemaTypeBoolean = "boolean"
+	SchemaTypeArray   = "array"
+	SchemaTypeObject  = "object"
+	SchemaTypeNull    = "null"
+)
+
+// FieldSchema describes a field of the records emitted by the converter. The fields of the nested objects are
+// flattened with dots, and the type is the json types seen joined by "|" if they are not the same in all records.
+type FieldSchema struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Required bool        `json:"required"`
+	Example  interface{} `json:"example,omitempty"`
+}
+
+// InferSchema converts the log group and infers the schema of the emitted records. Only the protocols emitting
+// json records are supported.
+func (c *Converter) InferSchema(logGroup *protocol.LogGroup) ([]*FieldSchema, error) {
+	stream, err := c.ToByteStream(logGroup)
+	if err != nil {
+		return nil, err
+	}
+	var records [][]byte
+	switch c.Protocol {
+	case ProtocolCustomSingle, ProtocolCustomSingleFlatten:
+		records = stream.([][]byte)
+	case ProtocolJsonline:
+		for _, line := range bytes.Split(stream.([]byte), sep) {
+			if len(line) > 0 {
+				records = append(records, line)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("schema inference is not supported for protocol: %s", c.Protocol)
+	}
+	return InferRecordsSchema(records)
+}
+
+type fieldStat struct {
+	types   map[string]struct{}
+	count   int
+	example interface{}
+}
+
+// InferRecordsSchema infers the schema of the json records, a field is required if it is in all records.
+func InferRecordsSchema(records [][]byte) ([]*FieldSchema, error) {
+	stats := make(map[string]*fieldStat)
+	for _, record := range records {
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.UseNumber()
+		var obj map[string]interface{}
+		if err := decoder.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("unable to decode record %s: %v", record, err)
+		}
+		collectFieldStats(stats, "", obj)
+	}
+	schemas := make([]*FieldSchema, 0, len(stats))
+	for name, stat := range stats {
+		types := make([]string, 0, len(stat.types))
+		for t := range stat.types {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		schemas = append(schemas, &FieldSchema{
+			Name:     name,
+			Type:     strings.Join(types, "|"),
+			Required: stat.count == len(records),
+			Example:  stat.example,
+		})
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+	return schemas, nil
+}
+
+func collectFieldStats(stats map[string]*fieldStat, prefix string, obj map[string]interface{}) {
+	for key, value := range obj {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			collectFieldStats(stats, name, nested)
+			continue
+		}
+		stat, ok := stats[name]
+		if !ok {
+			stat = &fieldStat{types: make(map[string]struct{})}
+			stats[name] = stat
+		}
+		stat.count++
+		stat.types[jsonSchemaType(value)] = struct{}{}
+		if stat.example == nil && value != nil {
+			stat.example = value
+		}
+	}
+}
+
+func jsonSchemaType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return SchemaTypeNull
+	case bool:
+		return SchemaTypeBoolean
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return SchemaTypeInteger
+		}
+		return SchemaTypeNumber
+	case []interface{}:
+		return SchemaTypeArray
+	case map[string]interface{}:
+		// an empty object, the nested fields are flattened otherwise
+		return SchemaTypeObject
+	default:
+		return SchemaTypeString
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/flags"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+func TestInferSchema(t *testing.T) {
+	*flags.K8sFlag = false
+	logGroup := &protocol.LogGroup{
+		Logs: []*protocol.Log{
+			{
+				Time: 1662434209,
+				Contents: []*protocol.Log_Content{
+					{Key: "method", Value: "PUT"},
+					{Key: "__tag__:__path__", Value: "/root/test/origin/example.log"},
+				},
+			},
+			{
+				Time: 1662434487,
+				Contents: []*protocol.Log_Content{
+					{Key: "method", Value: "GET"},
+					{Key: "status", Value: "404"},
+					{Key: "__tag__:__path__", Value: "/root/test/origin/example.log"},
+				},
+			},
+		},
+		LogTags: []*protocol.LogTag{{Key: "__hostname__", Value: "alje834hgf"}},
+	}
+
+	Convey("Given a converter with protocol: custom_single", t, func() {
+		c, err := NewConverter(ProtocolCustomSingle, EncodingJSON, nil, nil, &config.GlobalConfig{})
+		So(err, ShouldBeNil)
+
+		Convey("Then the nested fields should be flattened and the optional ones should be marked", func() {
+			schemas, err := c.InferSchema(logGroup)
+			So(err, ShouldBeNil)
+			byName := make(map[string]*FieldSchema)
+			for _, schema := range schemas {
+				byName[schema.Name] = schema
+			}
+			So(byName["contents.method"], ShouldResemble, &FieldSchema{Name: "contents.method", Type: SchemaTypeString, Required: true, Example: "PUT"})
+			So(byName["contents.status"], ShouldResemble, &FieldSchema{Name: "contents.status", Type: SchemaTypeString, Required: false, Example: "404"})
+			So(byName["tags.host.name"].Example, ShouldEqual, "alje834hgf")
+			So(byName["tags.log.file.path"].Required, ShouldBeTrue)
+			So(byName["time"].Type, ShouldEqual, SchemaTypeInteger)
+		})
+	})
+
+	Convey("Given a converter with protocol: jsonline", t, func() {
+		c, err := NewConverter(ProtocolJsonline, EncodingJSON, nil, nil, &config.GlobalConfig{})
+		So(err, ShouldBeNil)
+
+		Convey("Then the schema of each line should be merged", func() {
+			schemas, err := c.InferSchema(logGroup)
+			So(err, ShouldBeNil)
+			names := make([]string, 0, len(schemas))
+			for _, schema := range schemas {
+				names = append(names, schema.Name)
+			}
+			So(names, ShouldContain, "method")
+			So(names, ShouldContain, "status")
+		})
+	})
+
+	Convey("Given a converter with protocol: influxdb", t, func() {
+		c, err := NewConverter(ProtocolInfluxdb, EncodingCustom, nil, nil, &config.GlobalConfig{})
+		So(err, ShouldBeNil)
+
+		Convey("Then the schema inference should be unsupported", func() {
+			_, err := c.InferSchema(logGroup)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestInferRecordsSchemaMixedTypes(t *testing.T) {
+	Convey("Given records with a field of different types", t, func() {
+		schemas, err := InferRecordsSchema([][]byte{[]byte(`{"a":1,"b":{}}`), []byte(`{"a":"x","b":{}}`)})
+		So(err, ShouldBeNil)
+		So(schemas, ShouldHaveLength, 2)
+		So(schemas[0].Type, ShouldEqual, "integer|string")
+		So(schemas[1].Type, ShouldEqual, SchemaTypeObject)
+	})
+}
@@ -16,7 +16,6 @@ package common
 
 import (
 	"bytes"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
@@ -24,13 +23,13 @@ import (
 	"strconv"
 	"sync"
 
-	"github.com/golang/snappy"
 	"github.com/pierrec/lz4"
 )
 
 const (
 	ProtocolSLS          = "sls"
 	ProtocolPrometheus   = "prometheus"
+	ProtocolRemoteWrite  = "prometheus_remote_write"
 	ProtocolInflux       = "influx"
 	ProtocolInfluxdb     = "influxdb"
 	ProtocolStatsd       = "statsd"
@@ -39,6 +38,7 @@ const (
 	ProtocolOTLPTraceV1  = "otlp_tracev1"
 	ProtocolRaw          = "raw"
 	ProtocolPyroscope    = "pyroscope"
+	ProtocolLoki         = "loki"
 )
 
 var bufPool = sync.Pool{
@@ -58,32 +58,24 @@ func PutPooledBuf(buf *bytes.Buffer) {
 	bufPool.Put(buf)
 }
 
+// CollectBody reads the request body limited by maxBodySize, and decompresses it by the Content-Encoding header.
+// The decompressed body is limited by maxBodySize too.
 func CollectBody(res http.ResponseWriter, req *http.Request, maxBodySize int64) ([]byte, int, error) {
-	body := req.Body
+	return CollectBodyWithLimit(res, req, maxBodySize, maxBodySize)
+}
 
-	// Handle gzip request bodies
-	if req.Header.Get("Content-Encoding") == "gzip" {
-		var err error
-		body, err = gzip.NewReader(req.Body)
-		if err != nil {
-			return nil, http.StatusBadRequest, err
-		}
-		defer body.Close()
+// CollectBodyWithLimit reads the request body limited by maxBodySize, and decompresses it by the Content-Encoding
+// header with the decompressed body limited by maxDecompressedSize, which is maxBodySize if not positive.
+func CollectBodyWithLimit(res http.ResponseWriter, req *http.Request, maxBodySize, maxDecompressedSize int64) ([]byte, int, error) {
+	if maxDecompressedSize <= 0 {
+		maxDecompressedSize = maxBodySize
 	}
+	body := http.MaxBytesReader(res, req.Body, maxBodySize)
 
-	body = http.MaxBytesReader(res, body, maxBodySize)
-
-	if req.Header.Get("Content-Encoding") == "snappy" {
-		// for snappy encoding, use pooled buf to read compressed request body
-		buf := GetPooledBuf()
-		defer PutPooledBuf(buf)
-		_, err := io.Copy(buf, body) // nolint
-		if err != nil {
-			return nil, http.StatusBadRequest, err
-		}
-		data, err := snappy.Decode(nil, buf.Bytes())
+	if encodings := contentEncodings(req); len(encodings) > 0 {
+		data, err := decompress(body, encodings, maxDecompressedSize)
 		if err != nil {
-			return nil, http.StatusBadRequest, err
+			return nil, decompressStatusCode(err), err
 		}
 		return data, http.StatusOK, nil
 	}
@@ -98,6 +90,9 @@ func CollectBody(res http.ResponseWriter, req *http.Request, maxBodySize int64)
 		if err != nil || rawBodySize <= 0 {
 			return nil, http.StatusBadRequest, errors.New("invalid x-log-compresstype header " + req.Header.Get("x-log-bodyrawsize"))
 		}
+		if int64(rawBodySize) > maxDecompressedSize {
+			return nil, http.StatusRequestEntityTooLarge, ErrDecompressedTooLarge
+		}
 		data := make([]byte, rawBodySize)
 		if readSize, err := lz4.UncompressBlock(bytes, data); readSize != rawBodySize || (err != nil && err != io.EOF) {
 			return nil, http.StatusBadRequest, fmt.Errorf("uncompress lz4 error, expect : %d, real : %d, error : %v ", readSize, rawBodySize, err)
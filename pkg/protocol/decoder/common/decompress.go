@@ -0,0 +1,165 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingIdentity = "identity"
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingZstd     = "zstd"
+	EncodingSnappy   = "snappy"
+)
+
+// SupportedEncodings lists the content encodings accepted by CollectBody.
+var SupportedEncodings = []string{EncodingGzip, EncodingDeflate, EncodingZstd, EncodingSnappy}
+
+// snappyStreamMagic is the stream identifier chunk of the snappy framing format.
+var snappyStreamMagic = []byte("\xff\x06\x00\x00sNaPpY")
+
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ErrDecompressedTooLarge is returned when the decompressed body exceeds the limit.
+var ErrDecompressedTooLarge = errors.New("decompressed body too large")
+
+// contentEncodings returns the content encodings of the request in the order they were applied.
+func contentEncodings(req *http.Request) []string {
+	var encodings []string
+	for _, header := range req.Header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(header, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			switch encoding {
+			case "", EncodingIdentity:
+				continue
+			case "x-gzip":
+				encoding = EncodingGzip
+			case "x-snappy-framed":
+				encoding = EncodingSnappy
+			}
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// decompress reads the body and undoes the encodings in the reverse order. The decompressed data is limited
+// to maxDecompressedSize bytes, so that a small compressed body could not exhaust the memory.
+func decompress(body io.Reader, encodings []string, maxDecompressedSize int64) ([]byte, error) {
+	var closers []io.Closer
+	defer func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}()
+	reader := body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case EncodingGzip:
+			r, err := gzip.NewReader(reader)
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, r)
+			reader = r
+		case EncodingDeflate:
+			// deflate should be the zlib format, but some clients send the raw deflate stream
+			br := bufio.NewReader(reader)
+			if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+				r, err := zlib.NewReader(br)
+				if err != nil {
+					return nil, err
+				}
+				closers = append(closers, r)
+				reader = r
+			} else {
+				r := flate.NewReader(br)
+				closers = append(closers, r)
+				reader = r
+			}
+		case EncodingZstd:
+			r, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxDecompressedSize)))
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, r.IOReadCloser())
+			reader = r
+		case EncodingSnappy:
+			br := bufio.NewReader(reader)
+			if header, err := br.Peek(len(snappyStreamMagic)); err == nil && bytes.Equal(header, snappyStreamMagic) {
+				reader = snappy.NewReader(br)
+				break
+			}
+			// the block format used by prometheus remote write must be read entirely before decoding
+			compressed, err := io.ReadAll(br)
+			if err != nil {
+				return nil, err
+			}
+			size, err := snappy.DecodedLen(compressed)
+			if err != nil {
+				return nil, err
+			}
+			if int64(size) > maxDecompressedSize {
+				return nil, ErrDecompressedTooLarge
+			}
+			data, err := snappy.Decode(nil, compressed)
+			if err != nil {
+				return nil, err
+			}
+			reader = bytes.NewReader(data)
+		default:
+			return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, encodings[i])
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxDecompressedSize {
+		return nil, ErrDecompressedTooLarge
+	}
+	return data, nil
+}
+
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// decompressStatusCode maps the error of reading or decompressing the body to the status code of the response.
+func decompressStatusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, ErrDecompressedTooLarge), errors.Is(err, zstd.ErrDecoderSizeExceeded), errors.Is(err, zstd.ErrWindowSizeExceeded):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnsupportedEncoding):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	switch encoding {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case "deflate":
+		w := zlib.NewWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case "raw-deflate":
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case "zstd":
+		w, _ := zstd.NewWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	case "snappy":
+		return snappy.Encode(nil, data)
+	case "snappy-framed":
+		w := snappy.NewBufferedWriter(&buf)
+		_, _ = w.Write(data)
+		require.NoError(t, w.Close())
+	}
+	return buf.Bytes()
+}
+
+func collect(body []byte, encoding string, maxBodySize, maxDecompressedSize int64) ([]byte, int, error) {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	return CollectBodyWithLimit(httptest.NewRecorder(), req, maxBodySize, maxDecompressedSize)
+}
+
+func TestCollectBodyWithEncodings(t *testing.T) {
+	data := bytes.Repeat([]byte("hello world "), 100)
+	cases := []struct {
+		compression string
+		encoding    string
+	}{
+		{"gzip", "gzip"},
+		{"gzip", "X-Gzip"},
+		{"deflate", "deflate"},
+		{"raw-deflate", "deflate"},
+		{"zstd", "zstd"},
+		{"snappy", "snappy"},
+		{"snappy-framed", "snappy"},
+	}
+	for _, c := range cases {
+		got, code, err := collect(compress(t, c.compression, data), c.encoding, 1024, 4096)
+		require.NoError(t, err, c.compression)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, data, got, c.compression)
+	}
+
+	// the encodings are undone in the reverse order
+	body := compress(t, "gzip", compress(t, "zstd", data))
+	got, code, err := collect(body, "zstd, gzip", 1024, 4096)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, data, got)
+
+	got, code, err = collect(data, "identity", 4096, 0)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, data, got)
+}
+
+func TestCollectBodyLimits(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1024*1024)
+	for _, encoding := range []string{"gzip", "deflate", "zstd", "snappy"} {
+		body := compress(t, encoding, data)
+		_, code, err := collect(body, encoding, int64(len(body)), 64*1024)
+		assert.Error(t, err, encoding)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code, encoding)
+	}
+
+	// the compressed body is limited by the max body size
+	body := compress(t, "gzip", data)
+	_, code, err := collect(body, "gzip", int64(len(body)-1), int64(len(data)))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	// the decompressed body is limited by the max body size by default
+	_, code, err = collect(body, "gzip", int64(len(body)), 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	_, code, err = collect(data, "br", int64(len(data)), 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+
+	_, code, err = collect([]byte("not compressed"), "gzip", 1024, 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
 	"github.com/alibaba/ilogtail/pkg/protocol/decoder/common"
 	"github.com/alibaba/ilogtail/pkg/protocol/decoder/influxdb"
+	"github.com/alibaba/ilogtail/pkg/protocol/decoder/loki"
 	"github.com/alibaba/ilogtail/pkg/protocol/decoder/opentelemetry"
 	"github.com/alibaba/ilogtail/pkg/protocol/decoder/prometheus"
 	"github.com/alibaba/ilogtail/pkg/protocol/decoder/pyroscope"
@@ -31,9 +32,10 @@ import (
 )
 
 type Option struct {
-	FieldsExtend      bool
-	DisableUncompress bool
-	AllowUnsafeMode   bool
+	FieldsExtend        bool
+	DisableUncompress   bool
+	AllowUnsafeMode     bool
+	MaxDecompressedSize int64
 }
 
 // GetDecoder return a new decoder for specific format
@@ -44,26 +46,31 @@ func GetDecoder(format string) (extensions.Decoder, error) {
 func GetDecoderWithOptions(format string, option Option) (extensions.Decoder, error) {
 	switch strings.TrimSpace(strings.ToLower(format)) {
 	case common.ProtocolSLS:
-		return &sls.Decoder{}, nil
+		return &sls.Decoder{MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolPrometheus:
-		return &prometheus.Decoder{AllowUnsafeMode: option.AllowUnsafeMode}, nil
+		return &prometheus.Decoder{AllowUnsafeMode: option.AllowUnsafeMode, MaxDecompressedSize: option.MaxDecompressedSize}, nil
+	case common.ProtocolRemoteWrite:
+		return &prometheus.Decoder{AllowUnsafeMode: option.AllowUnsafeMode, RemoteWriteOnly: true, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolInflux, common.ProtocolInfluxdb:
-		return &influxdb.Decoder{FieldsExtend: option.FieldsExtend}, nil
+		return &influxdb.Decoder{FieldsExtend: option.FieldsExtend, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolStatsd:
 		return &statsd.Decoder{
-			Time: time.Now(),
+			Time:                time.Now(),
+			MaxDecompressedSize: option.MaxDecompressedSize,
 		}, nil
 	case common.ProtocolOTLPLogV1:
-		return &opentelemetry.Decoder{Format: common.ProtocolOTLPLogV1}, nil
+		return &opentelemetry.Decoder{Format: common.ProtocolOTLPLogV1, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolOTLPMetricV1:
-		return &opentelemetry.Decoder{Format: common.ProtocolOTLPMetricV1}, nil
+		return &opentelemetry.Decoder{Format: common.ProtocolOTLPMetricV1, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolOTLPTraceV1:
-		return &opentelemetry.Decoder{Format: common.ProtocolOTLPTraceV1}, nil
+		return &opentelemetry.Decoder{Format: common.ProtocolOTLPTraceV1, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	case common.ProtocolRaw:
-		return &raw.Decoder{DisableUncompress: option.DisableUncompress}, nil
+		return &raw.Decoder{DisableUncompress: option.DisableUncompress, MaxDecompressedSize: option.MaxDecompressedSize}, nil
 
 	case common.ProtocolPyroscope:
-		return &pyroscope.Decoder{}, nil
+		return &pyroscope.Decoder{MaxDecompressedSize: option.MaxDecompressedSize}, nil
+	case common.ProtocolLoki:
+		return &loki.Decoder{MaxDecompressedSize: option.MaxDecompressedSize}, nil
 	default:
 		return nil, fmt.Errorf("not supported format: %s", format)
 	}
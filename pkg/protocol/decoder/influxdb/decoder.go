@@ -47,12 +47,14 @@ const metadataKeyDB = "db"
 
 const (
 	formDataKeyDB        = "db"
+	formDataKeyBucket    = "bucket" // the db of the influxdb 2.x write api
 	formDataKeyPrecision = "precision"
 )
 
 // Decoder impl
 type Decoder struct {
-	FieldsExtend bool
+	FieldsExtend        bool
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string) (logs []*protocol.Log, decodeErr error) {
@@ -65,7 +67,7 @@ func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string)
 }
 
 func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 func (d *Decoder) DecodeV2(data []byte, req *http.Request) ([]*imodels.PipelineGroupEvents, error) {
@@ -77,7 +79,7 @@ func (d *Decoder) DecodeV2(data []byte, req *http.Request) ([]*imodels.PipelineG
 }
 
 func (d *Decoder) decodeToInfluxdbPoints(data []byte, req *http.Request) ([]models.Point, error) {
-	precision := req.FormValue(formDataKeyPrecision)
+	precision := getPrecision(req)
 	var points []models.Point
 	var err error
 	if precision != "" {
@@ -91,12 +93,32 @@ func (d *Decoder) decodeToInfluxdbPoints(data []byte, req *http.Request) ([]mode
 	return points, nil
 }
 
+// getDB returns the db of the influxdb 1.x write api, or the bucket of the 2.x one.
+func getDB(req *http.Request) string {
+	if db := req.FormValue(formDataKeyDB); len(db) > 0 {
+		return db
+	}
+	return req.FormValue(formDataKeyBucket)
+}
+
+// getPrecision converts the precision of the influxdb 2.x write api, i.e. ns, us, ms and s, to the 1.x one.
+func getPrecision(req *http.Request) string {
+	switch precision := req.FormValue(formDataKeyPrecision); precision {
+	case "ns":
+		return "n"
+	case "us":
+		return "u"
+	default:
+		return precision
+	}
+}
+
 func (d *Decoder) parsePointsToEvents(points []models.Point, req *http.Request) ([]*imodels.PipelineGroupEvents, error) {
 	group := &imodels.PipelineGroupEvents{
 		Group:  imodels.NewGroup(imodels.NewMetadata(), imodels.NewTags()),
 		Events: make([]imodels.PipelineEvent, 0, len(points)),
 	}
-	if db := req.FormValue(formDataKeyDB); len(db) > 0 {
+	if db := getDB(req); len(db) > 0 {
 		group.Group.Metadata.Add(metadataKeyDB, db)
 	}
 	for _, point := range points {
@@ -157,7 +179,7 @@ func (d *Decoder) parsePointFieldsToMetricValues(p models.Point) (imodels.Metric
 }
 
 func (d *Decoder) parsePointsToLogs(points []models.Point, req *http.Request) []*protocol.Log {
-	db := req.FormValue("db")
+	db := getDB(req)
 	logs := make([]*protocol.Log, 0, len(points))
 	for _, s := range points {
 		fields, err := s.Fields()
@@ -213,3 +213,19 @@ func TestDecodeV2(t *testing.T) {
 	}
 	assert.Equal(t, want, groups[0])
 }
+
+func TestDecodeV2WithV2WriteAPI(t *testing.T) {
+	decoder := &Decoder{}
+	req := &http.Request{Form: map[string][]string{"bucket": {"mybucket"}, "org": {"myorg"}, "precision": {"us"}}}
+	groups, err := decoder.DecodeV2([]byte("cpu,host=server01 value=1 1434055562000000"), req)
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "mybucket", groups[0].Group.Metadata.Get("db"))
+	assert.Len(t, groups[0].Events, 1)
+	assert.Equal(t, uint64(1434055562000000000), groups[0].Events[0].GetTimestamp())
+
+	req = &http.Request{Form: map[string][]string{"precision": {"ns"}}}
+	groups, err = decoder.DecodeV2([]byte("cpu,host=server01 value=1 1434055562000000001"), req)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1434055562000000001), groups[0].Events[0].GetTimestamp())
+}
@@ -0,0 +1,368 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/richardartoul/molecule"
+	"github.com/richardartoul/molecule/src/codec"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/protocol/decoder/common"
+)
+
+const (
+	contentTypeKey     = "Content-Type"
+	contentEncodingKey = "Content-Encoding"
+	jsonContentType    = "application/json"
+	snappyEncoding     = "snappy"
+	tagPrefix          = "__tag__:"
+)
+
+// field index of the proto message logproto.PushRequest
+// ref: https://github.com/grafana/loki/blob/main/pkg/push/push.proto
+const (
+	pbFieldIndexStreams          = 1
+	pbFieldIndexStreamLabels     = 1
+	pbFieldIndexStreamEntries    = 2
+	pbFieldIndexEntryTimestamp   = 1
+	pbFieldIndexEntryLine        = 2
+	pbFieldIndexEntryMetadata    = 3
+	pbFieldIndexTimestampSeconds = 1
+	pbFieldIndexTimestampNanos   = 2
+	pbFieldIndexLabelPairName    = 1
+	pbFieldIndexLabelPairValue   = 2
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+type entry struct {
+	timestamp int64 // unix nano
+	line      string
+	metadata  []label
+}
+
+type stream struct {
+	labels  []label
+	entries []entry
+}
+
+// Decoder decodes the requests of the loki push api, the body could be snappy compressed protobuf or json.
+type Decoder struct {
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
+}
+
+// ParseRequest reads the body, and decompresses the protobuf body by snappy, because the clients like promtail
+// always compress it without the Content-Encoding header.
+func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
+	data, statusCode, err = common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
+	if err != nil || isJSON(req) || hasSnappyEncoding(req) {
+		return data, statusCode, err
+	}
+	maxDecompressedSize := d.MaxDecompressedSize
+	if maxDecompressedSize <= 0 {
+		maxDecompressedSize = maxBodySize
+	}
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if int64(size) > maxDecompressedSize {
+		return nil, http.StatusRequestEntityTooLarge, common.ErrDecompressedTooLarge
+	}
+	if data, err = snappy.Decode(nil, data); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return data, http.StatusOK, nil
+}
+
+// Decode converts each entry to a log, the stream labels are added as tags.
+func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string) (logs []*protocol.Log, err error) {
+	streams, err := decodeStreams(data, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range streams {
+		for _, e := range s.entries {
+			contents := make([]*protocol.Log_Content, 0, 1+len(e.metadata)+len(s.labels))
+			contents = append(contents, &protocol.Log_Content{Key: models.ContentKey, Value: e.line})
+			for _, m := range e.metadata {
+				contents = append(contents, &protocol.Log_Content{Key: m.name, Value: m.value})
+			}
+			for _, l := range s.labels {
+				contents = append(contents, &protocol.Log_Content{Key: tagPrefix + l.name, Value: l.value})
+			}
+			log := &protocol.Log{Contents: contents}
+			protocol.SetLogTimeWithNano(log, uint32(e.timestamp/1e9), uint32(e.timestamp%1e9))
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// DecodeV2 converts each stream to a group with the stream labels as the group tags, and the structured metadata
+// of the entries are the tags of the logs.
+func (d *Decoder) DecodeV2(data []byte, req *http.Request) (groups []*models.PipelineGroupEvents, err error) {
+	streams, err := decodeStreams(data, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range streams {
+		groupTags := models.NewTags()
+		for _, l := range s.labels {
+			groupTags.Add(l.name, l.value)
+		}
+		group := &models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), groupTags),
+			Events: make([]models.PipelineEvent, 0, len(s.entries)),
+		}
+		for _, e := range s.entries {
+			tags := models.NewTags()
+			for _, m := range e.metadata {
+				tags.Add(m.name, m.value)
+			}
+			group.Events = append(group.Events, models.NewLog("", []byte(e.line), "", "", "", tags, uint64(e.timestamp)))
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func isJSON(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(contentTypeKey))
+	return mediaType == jsonContentType
+}
+
+func hasSnappyEncoding(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get(contentEncodingKey), ",") {
+		if strings.EqualFold(strings.TrimSpace(encoding), snappyEncoding) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeStreams(data []byte, req *http.Request) ([]stream, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if isJSON(req) {
+		return decodeJSON(data)
+	}
+	return decodeProtobuf(data)
+}
+
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+func decodeJSON(data []byte) ([]stream, error) {
+	var req jsonPushRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	streams := make([]stream, 0, len(req.Streams))
+	for _, s := range req.Streams {
+		var st stream
+		st.labels = sortedLabels(s.Stream)
+		for _, value := range s.Values {
+			if len(value) < 2 || len(value) > 3 {
+				return nil, fmt.Errorf("invalid entry with %d elements", len(value))
+			}
+			var ts string
+			var e entry
+			if err := json.Unmarshal(value[0], &ts); err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			timestamp, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			e.timestamp = timestamp
+			if err = json.Unmarshal(value[1], &e.line); err != nil {
+				return nil, fmt.Errorf("invalid line: %w", err)
+			}
+			if len(value) == 3 {
+				var metadata map[string]string
+				if err = json.Unmarshal(value[2], &metadata); err != nil {
+					return nil, fmt.Errorf("invalid structured metadata: %w", err)
+				}
+				e.metadata = sortedLabels(metadata)
+			}
+			st.entries = append(st.entries, e)
+		}
+		streams = append(streams, st)
+	}
+	return streams, nil
+}
+
+func sortedLabels(m map[string]string) []label {
+	labels := make([]label, 0, len(m))
+	for name, value := range m {
+		labels = append(labels, label{name: name, value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+func decodeProtobuf(data []byte) ([]stream, error) {
+	var streams []stream
+	err := molecule.MessageEach(codec.NewBuffer(data), func(fieldNum int32, v molecule.Value) (bool, error) {
+		if fieldNum != pbFieldIndexStreams {
+			return true, nil
+		}
+		streamBytes, err := v.AsBytesUnsafe()
+		if err != nil {
+			return false, err
+		}
+		var st stream
+		err = molecule.MessageEach(codec.NewBuffer(streamBytes), func(fieldNum int32, v molecule.Value) (bool, error) {
+			switch fieldNum {
+			case pbFieldIndexStreamLabels:
+				labels, err := v.AsStringSafe()
+				if err != nil {
+					return false, err
+				}
+				if st.labels, err = parseLabels(labels); err != nil {
+					return false, err
+				}
+			case pbFieldIndexStreamEntries:
+				entryBytes, err := v.AsBytesUnsafe()
+				if err != nil {
+					return false, err
+				}
+				e, err := decodeProtobufEntry(entryBytes)
+				if err != nil {
+					return false, err
+				}
+				st.entries = append(st.entries, e)
+			}
+			return true, nil
+		})
+		if err != nil {
+			return false, err
+		}
+		streams = append(streams, st)
+		return true, nil
+	})
+	return streams, err
+}
+
+func decodeProtobufEntry(data []byte) (e entry, err error) {
+	err = molecule.MessageEach(codec.NewBuffer(data), func(fieldNum int32, v molecule.Value) (bool, error) {
+		var err error
+		switch fieldNum {
+		case pbFieldIndexEntryTimestamp:
+			var timestampBytes []byte
+			if timestampBytes, err = v.AsBytesUnsafe(); err != nil {
+				return false, err
+			}
+			var seconds, nanos int64
+			err = molecule.MessageEach(codec.NewBuffer(timestampBytes), func(fieldNum int32, v molecule.Value) (bool, error) {
+				var err error
+				switch fieldNum {
+				case pbFieldIndexTimestampSeconds:
+					seconds, err = v.AsInt64()
+				case pbFieldIndexTimestampNanos:
+					var n int32
+					n, err = v.AsInt32()
+					nanos = int64(n)
+				}
+				return err == nil, err
+			})
+			e.timestamp = seconds*1e9 + nanos
+		case pbFieldIndexEntryLine:
+			e.line, err = v.AsStringSafe()
+		case pbFieldIndexEntryMetadata:
+			var pairBytes []byte
+			if pairBytes, err = v.AsBytesUnsafe(); err != nil {
+				return false, err
+			}
+			var l label
+			err = molecule.MessageEach(codec.NewBuffer(pairBytes), func(fieldNum int32, v molecule.Value) (bool, error) {
+				var err error
+				switch fieldNum {
+				case pbFieldIndexLabelPairName:
+					l.name, err = v.AsStringSafe()
+				case pbFieldIndexLabelPairValue:
+					l.value, err = v.AsStringSafe()
+				}
+				return err == nil, err
+			})
+			e.metadata = append(e.metadata, l)
+		}
+		return err == nil, err
+	})
+	return e, err
+}
+
+// parseLabels parses the labels in the prometheus format, e.g. {job="varlogs", host="a"}.
+func parseLabels(s string) ([]label, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("invalid labels %q", s)
+	}
+	s = s[1 : len(s)-1]
+	var labels []label
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			return labels, nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid label %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " ")
+		if s == "" || s[0] != '"' {
+			return nil, fmt.Errorf("label %s value is not quoted", name)
+		}
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return nil, fmt.Errorf("label %s value is not terminated", name)
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid label %s value: %w", name, err)
+		}
+		labels = append(labels, label{name: name, value: value})
+		s = s[end+1:]
+	}
+}
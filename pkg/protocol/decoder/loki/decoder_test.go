@@ -0,0 +1,143 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func buildPushRequest() []byte {
+	var timestamp []byte
+	timestamp = protowire.AppendTag(timestamp, pbFieldIndexTimestampSeconds, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 1700000000)
+	timestamp = protowire.AppendTag(timestamp, pbFieldIndexTimestampNanos, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 123)
+
+	var metadata []byte
+	metadata = appendString(metadata, pbFieldIndexLabelPairName, "trace_id")
+	metadata = appendString(metadata, pbFieldIndexLabelPairValue, "abc")
+
+	var e []byte
+	e = appendMessage(e, pbFieldIndexEntryTimestamp, timestamp)
+	e = appendString(e, pbFieldIndexEntryLine, "hello loki")
+	e = appendMessage(e, pbFieldIndexEntryMetadata, metadata)
+
+	var s []byte
+	s = appendString(s, pbFieldIndexStreamLabels, `{job="varlogs", path="/var/log/a \"b\".log"}`)
+	s = appendMessage(s, pbFieldIndexStreamEntries, e)
+
+	return appendMessage(nil, pbFieldIndexStreams, s)
+}
+
+const jsonPushRequestBody = `{"streams":[{"stream":{"path":"/var/log/a \"b\".log","job":"varlogs"},
+"values":[["1700000000000000123","hello loki",{"trace_id":"abc"}]]}]}`
+
+func checkGroups(t *testing.T, groups []*models.PipelineGroupEvents) {
+	require.Len(t, groups, 1)
+	assert.Equal(t, "varlogs", groups[0].Group.Tags.Get("job"))
+	assert.Equal(t, `/var/log/a "b".log`, groups[0].Group.Tags.Get("path"))
+	require.Len(t, groups[0].Events, 1)
+	log := groups[0].Events[0].(*models.Log)
+	assert.Equal(t, "hello loki", string(log.GetBody()))
+	assert.Equal(t, uint64(1700000000000000123), log.GetTimestamp())
+	assert.Equal(t, "abc", log.GetTags().Get("trace_id"))
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader(snappy.Encode(nil, buildPushRequest())))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	decoder := &Decoder{}
+	data, code, err := decoder.ParseRequest(httptest.NewRecorder(), req, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	groups, err := decoder.DecodeV2(data, req)
+	require.NoError(t, err)
+	checkGroups(t, groups)
+
+	logs, err := decoder.Decode(data, req, nil)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, uint32(1700000000), logs[0].Time)
+	assert.Equal(t, uint32(123), *logs[0].TimeNs)
+	contents := make(map[string]string)
+	for _, c := range logs[0].Contents {
+		contents[c.Key] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"content":      "hello loki",
+		"trace_id":     "abc",
+		"__tag__:job":  "varlogs",
+		"__tag__:path": `/var/log/a "b".log`,
+	}, contents)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewBufferString(jsonPushRequestBody))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	decoder := &Decoder{}
+	data, _, err := decoder.ParseRequest(httptest.NewRecorder(), req, 1024)
+	require.NoError(t, err)
+	groups, err := decoder.DecodeV2(data, req)
+	require.NoError(t, err)
+	checkGroups(t, groups)
+
+	_, err = decoder.DecodeV2([]byte(`{"streams":[{"stream":{},"values":[["x","line"]]}]}`), req)
+	assert.Error(t, err)
+}
+
+func TestParseRequestLimit(t *testing.T) {
+	body := snappy.Encode(nil, bytes.Repeat([]byte("a"), 4096))
+	req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	_, code, err := (&Decoder{MaxDecompressedSize: 1024}).ParseRequest(httptest.NewRecorder(), req, 1024)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels(`{a="1",b = "x,y=\"z\"" , c=""}`)
+	require.NoError(t, err)
+	assert.Equal(t, []label{{"a", "1"}, {"b", `x,y="z"`}, {"c", ""}}, labels)
+
+	labels, err = parseLabels(`{}`)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	for _, s := range []string{`a="1"`, `{a=1}`, `{a="1}`, `{="1"}`} {
+		_, err = parseLabels(s)
+		assert.Error(t, err, s)
+	}
+}
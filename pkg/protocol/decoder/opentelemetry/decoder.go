@@ -38,7 +38,8 @@ const (
 
 // Decoder impl
 type Decoder struct {
-	Format string
+	Format              string
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 // Decode impl
@@ -73,7 +74,7 @@ func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string)
 
 // ParseRequest impl
 func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 // DecodeV2 impl
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 
@@ -48,6 +49,7 @@ const (
 	contentTypeKey     = "Content-Type"
 	pbContentType      = "application/x-protobuf"
 	snappyEncoding     = "snappy"
+	remoteWriteV1Proto = "prometheus.WriteRequest"
 )
 
 // field index of the proto message models
@@ -64,18 +66,39 @@ const (
 
 // Decoder impl
 type Decoder struct {
-	AllowUnsafeMode bool
+	AllowUnsafeMode     bool
+	RemoteWriteOnly     bool  // decode all the requests as remote write requests whatever the headers are
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 // Decode impl
 func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string) (logs []*protocol.Log, err error) {
-	if req.Header.Get(contentEncodingKey) == snappyEncoding &&
-		strings.HasPrefix(req.Header.Get(contentTypeKey), pbContentType) {
+	remoteWrite, err := d.isRemoteWrite(req)
+	if err != nil {
+		return nil, err
+	}
+	if remoteWrite {
 		return d.decodeInRemoteWriteFormat(data, req)
 	}
 	return d.decodeInExpFmt(data, req)
 }
 
+// isRemoteWrite checks whether the request is a remote write request by the protobuf content type. The body has
+// been decompressed by the Content-Encoding header when parsing the request, so the encoding is not checked.
+func (d *Decoder) isRemoteWrite(req *http.Request) (bool, error) {
+	contentType := req.Header.Get(contentTypeKey)
+	if !strings.HasPrefix(contentType, pbContentType) {
+		return d.RemoteWriteOnly, nil
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		// only remote write 1.0 is supported, whose message is prometheus.WriteRequest
+		if proto, ok := params["proto"]; ok && proto != remoteWriteV1Proto {
+			return false, fmt.Errorf("unsupported remote write message %s", proto)
+		}
+	}
+	return true, nil
+}
+
 func (d *Decoder) decodeInExpFmt(data []byte, _ *http.Request) (logs []*protocol.Log, err error) {
 	decoder := expfmt.NewDecoder(bytes.NewReader(data), expfmt.FmtText)
 	sampleDecoder := expfmt.SampleDecoder{
@@ -114,7 +137,7 @@ func (d *Decoder) decodeInExpFmt(data []byte, _ *http.Request) (logs []*protocol
 }
 
 func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 func (d *Decoder) decodeInRemoteWriteFormat(data []byte, req *http.Request) (logs []*protocol.Log, err error) {
@@ -161,8 +184,11 @@ func (d *Decoder) DecodeV2(data []byte, req *http.Request) (groups []*models.Pip
 		meta.Add(metaDBKey, db)
 	}
 
-	if req.Header.Get(contentEncodingKey) == snappyEncoding &&
-		strings.HasPrefix(req.Header.Get(contentTypeKey), pbContentType) {
+	remoteWrite, err := d.isRemoteWrite(req)
+	if err != nil {
+		return nil, err
+	}
+	if remoteWrite {
 		var groupEvents *models.PipelineGroupEvents
 		if d.AllowUnsafeMode {
 			groupEvents, err = ParsePromPbToPipelineGroupEventsUnsafe(data, meta, commonTags)
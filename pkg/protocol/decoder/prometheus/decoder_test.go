@@ -373,3 +373,36 @@ func TestConvertVectorToPipelineGroupEvents(t *testing.T) {
 
 	}
 }
+
+func TestDecodeRemoteWrite(t *testing.T) {
+	promRequest := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: metricNameKey, Value: "test_metric"}},
+				Samples: []prompb.Sample{{Timestamp: 1234567890, Value: 1.23}}}},
+	}
+	data, err := promRequest.Marshal()
+	assert.Nil(t, err)
+
+	// the body has been decompressed, so the content encoding is not required
+	req, _ := http.NewRequest("POST", "http://localhost", nil)
+	req.Header.Add(contentTypeKey, pbContentType)
+	groups, err := (&Decoder{}).DecodeV2(data, req)
+	assert.Nil(t, err)
+	assert.Len(t, groups[0].Events, 1)
+
+	// the remote write only decoder ignores the headers
+	req, _ = http.NewRequest("POST", "http://localhost", nil)
+	logs, err := (&Decoder{RemoteWriteOnly: true}).Decode(data, req, nil)
+	assert.Nil(t, err)
+	assert.Len(t, logs, 1)
+
+	req.Header.Set(contentTypeKey, pbContentType+";proto=prometheus.WriteRequest")
+	groups, err = (&Decoder{RemoteWriteOnly: true}).DecodeV2(data, req)
+	assert.Nil(t, err)
+	assert.Len(t, groups[0].Events, 1)
+
+	req.Header.Set(contentTypeKey, pbContentType+";proto=io.prometheus.write.v2.Request")
+	_, err = (&Decoder{RemoteWriteOnly: true}).DecodeV2(data, req)
+	assert.Error(t, err)
+}
@@ -38,6 +38,7 @@ import (
 const AlarmType = "PYROSCOPE_ALARM"
 
 type Decoder struct {
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 func (d *Decoder) DecodeV2(data []byte, req *http.Request) (groups []*models.PipelineGroupEvents, err error) {
@@ -88,7 +89,7 @@ func (d *Decoder) extractRawInput(data []byte, req *http.Request) (*profile.Inpu
 }
 
 func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 func (d *Decoder) parseInputMeta(req *http.Request) (*profile.Input, profile.Format, error) {
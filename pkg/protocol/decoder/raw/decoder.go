@@ -24,7 +24,8 @@ import (
 )
 
 type Decoder struct {
-	DisableUncompress bool
+	DisableUncompress   bool
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 func (d *Decoder) DecodeV2(data []byte, req *http.Request) (groups []*models.PipelineGroupEvents, decodeErr error) {
@@ -38,7 +39,7 @@ func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBo
 	if d.DisableUncompress {
 		return common.CollectRawBody(res, req, maxBodySize)
 	}
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string) (logs []*protocol.Log, err error) {
@@ -30,7 +30,8 @@ import (
 )
 
 type Decoder struct {
-	Time time.Time
+	Time                time.Time
+	MaxDecompressedSize int64 // the max size of the decompressed body, use the max body size if not positive
 }
 
 func parseLabels(metric *dogstatsd.Metric) *helper.MetricLabels {
@@ -64,7 +65,7 @@ func (d *Decoder) Decode(data []byte, req *http.Request, tags map[string]string)
 }
 
 func (d *Decoder) ParseRequest(res http.ResponseWriter, req *http.Request, maxBodySize int64) (data []byte, statusCode int, err error) {
-	return common.CollectBody(res, req, maxBodySize)
+	return common.CollectBodyWithLimit(res, req, maxBodySize, d.MaxDecompressedSize)
 }
 
 func (d *Decoder) DecodeV2(data []byte, req *http.Request) (groups []*models.PipelineGroupEvents, err error) {
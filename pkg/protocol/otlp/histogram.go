@@ -0,0 +1,363 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+// The values of TagKeyMetricHistogramType.
+var (
+	HistogramTypeExplicit    = pmetric.MetricTypeHistogram.String()
+	HistogramTypeExponential = pmetric.MetricTypeExponentialHistogram.String()
+)
+
+// The scale range of the exponential histograms. The Prometheus native histograms are the exponential histograms
+// with the scale (schema in Prometheus) in [NativeHistogramMinScale, NativeHistogramMaxScale].
+const (
+	ExponentialHistogramMinScale = -10
+	ExponentialHistogramMaxScale = 20
+	NativeHistogramMinScale      = -4
+	NativeHistogramMaxScale      = 8
+)
+
+// ExplicitBucket is a bucket (Lower, Upper] of the explicit bucket histograms, the count is not cumulative.
+type ExplicitBucket struct {
+	Lower float64
+	Upper float64
+	Count float64
+}
+
+// ParseExplicitBuckets parses the buckets of the explicit bucket histogram values, sorted by the upper bounds.
+func ParseExplicitBuckets(multiValues models.MetricFloatValues) []ExplicitBucket {
+	buckets := make([]ExplicitBucket, 0, multiValues.Len())
+	for k, v := range multiValues.Iterator() {
+		if !strings.HasPrefix(k, "(") || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		bounds := strings.Split(k[1:len(k)-1], ",")
+		if len(bounds) != 2 {
+			continue
+		}
+		lower, err := strconv.ParseFloat(bounds[0], 64)
+		if err != nil {
+			continue
+		}
+		upper, err := strconv.ParseFloat(bounds[1], 64)
+		if err != nil {
+			continue
+		}
+		buckets = append(buckets, ExplicitBucket{Lower: lower, Upper: upper, Count: v})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Upper < buckets[j].Upper
+	})
+	return buckets
+}
+
+// ExponentialBucketIndex returns the index of the bucket containing the positive value, the bucket i is (base^i, base^(i+1)].
+// The index is corrected by the boundaries from ExponentialBucketLower, as the logarithm may be inexact near them.
+func ExponentialBucketIndex(value float64, scale int32) int32 {
+	index := int32(math.Ceil(math.Ldexp(math.Log2(value), int(scale)))) - 1
+	if value <= ExponentialBucketLower(index, scale) {
+		index--
+	} else if value > ExponentialBucketLower(index+1, scale) {
+		index++
+	}
+	return index
+}
+
+// ExponentialBucketLower returns the lower boundary of the bucket with the index.
+func ExponentialBucketLower(index, scale int32) float64 {
+	return math.Exp2(math.Ldexp(float64(index), -int(scale)))
+}
+
+// copyHistogramStats copies the count, sum, min and max to a new multi values.
+func copyHistogramStats(multiValues models.MetricFloatValues) *models.MetricMultiValue {
+	result := models.NewMetricMultiValue()
+	for _, field := range []string{FieldCount, FieldSum, FieldMin, FieldMax} {
+		if multiValues.Contains(field) {
+			result.Add(field, multiValues.Get(field))
+		}
+	}
+	return result
+}
+
+// exponentialHistogram is the counts of the exponential buckets by index.
+type exponentialHistogram struct {
+	scale     int32
+	zero      float64
+	positives map[int32]float64
+	negatives map[int32]float64
+}
+
+func newExponentialHistogram(scale int32) *exponentialHistogram {
+	return &exponentialHistogram{scale: scale, positives: map[int32]float64{}, negatives: map[int32]float64{}}
+}
+
+func parseExponentialHistogram(multiValues models.MetricFloatValues) *exponentialHistogram {
+	h := newExponentialHistogram(int32(multiValues.Get(FieldScale)))
+	h.zero = multiValues.Get(FieldZeroCount)
+	positiveOffset := int32(multiValues.Get(FieldPositiveOffset))
+	_, counts := ComputeBuckets(multiValues, true)
+	for i, count := range counts {
+		h.positives[positiveOffset+int32(i)] = count
+	}
+	negativeOffset := int32(multiValues.Get(FieldNegativeOffset))
+	_, counts = ComputeBuckets(multiValues, false)
+	for i, count := range counts {
+		h.negatives[negativeOffset+int32(i)] = count
+	}
+	return h
+}
+
+// add adds the count to the bucket containing the value.
+func (h *exponentialHistogram) add(value, count float64) {
+	switch {
+	case value > 0:
+		h.positives[ExponentialBucketIndex(value, h.scale)] += count
+	case value < 0:
+		h.negatives[ExponentialBucketIndex(-value, h.scale)] += count
+	default:
+		h.zero += count
+	}
+}
+
+// downscale merges the buckets to the lower scale, the histogram itself is returned if the scale is not lower.
+func (h *exponentialHistogram) downscale(scale int32) *exponentialHistogram {
+	if scale >= h.scale {
+		return h
+	}
+	shift := h.scale - scale
+	downscaled := newExponentialHistogram(scale)
+	downscaled.zero = h.zero
+	for index, count := range h.positives {
+		downscaled.positives[index>>shift] += count
+	}
+	for index, count := range h.negatives {
+		downscaled.negatives[index>>shift] += count
+	}
+	return downscaled
+}
+
+func (h *exponentialHistogram) writeTo(result *models.MetricMultiValue) {
+	result.Add(FieldScale, float64(h.scale))
+	result.Add(FieldZeroCount, h.zero)
+	writeExponentialBuckets(result, h.positives, h.scale, true)
+	writeExponentialBuckets(result, h.negatives, h.scale, false)
+}
+
+// writeExponentialBuckets writes the contiguous buckets from the min index to the max index, as the converters
+// compute the indexes from the offsets and the order of the buckets.
+func writeExponentialBuckets(result *models.MetricMultiValue, counts map[int32]float64, scale int32, isPositive bool) {
+	offsetField := FieldNegativeOffset
+	if isPositive {
+		offsetField = FieldPositiveOffset
+	}
+	if len(counts) == 0 {
+		result.Add(offsetField, 0)
+		return
+	}
+	minIndex, maxIndex := int32(math.MaxInt32), int32(math.MinInt32)
+	for index := range counts {
+		if index < minIndex {
+			minIndex = index
+		}
+		if index > maxIndex {
+			maxIndex = index
+		}
+	}
+	result.Add(offsetField, float64(minIndex))
+	for index := minIndex; index <= maxIndex; index++ {
+		result.Add(ComposeBucketFieldName(ExponentialBucketLower(index, scale), ExponentialBucketLower(index+1, scale), isPositive), counts[index])
+	}
+}
+
+// ExplicitToExponential converts the explicit bucket histogram values to the exponential histogram values with
+// the scale. The count of each explicit bucket is put into the exponential bucket containing its upper bound, or
+// its lower bound for the +Inf bucket, so the error of each value is within an explicit bucket.
+func ExplicitToExponential(multiValues models.MetricFloatValues, scale int32) models.MetricFloatValues {
+	result := copyHistogramStats(multiValues)
+	h := newExponentialHistogram(scale)
+	for _, bucket := range ParseExplicitBuckets(multiValues) {
+		value := bucket.Upper
+		if math.IsInf(value, 1) {
+			value = bucket.Lower
+			if math.IsInf(value, -1) {
+				value = 0
+			}
+		}
+		h.add(value, bucket.Count)
+	}
+	h.writeTo(result)
+	return result.Values
+}
+
+// ExponentialToExplicit converts the exponential histogram values to the explicit bucket histogram values with
+// the bounds. The count of each exponential bucket is put into the explicit bucket containing its upper bound,
+// and the zero count is put into the bucket containing zero. If the bounds are empty, the boundaries of the
+// exponential buckets and zero are the bounds, which keeps all the buckets.
+func ExponentialToExplicit(multiValues models.MetricFloatValues, bounds []float64) models.MetricFloatValues {
+	h := parseExponentialHistogram(multiValues)
+	if len(bounds) == 0 {
+		bounds = append(bounds, 0)
+		for index := range h.positives {
+			bounds = append(bounds, ExponentialBucketLower(index+1, h.scale))
+		}
+		for index := range h.negatives {
+			bounds = append(bounds, -ExponentialBucketLower(index, h.scale))
+		}
+	}
+	buckets := newExplicitBuckets(bounds)
+	for index, count := range h.positives {
+		buckets.add(ExponentialBucketLower(index+1, h.scale), count)
+	}
+	for index, count := range h.negatives {
+		buckets.add(-ExponentialBucketLower(index, h.scale), count)
+	}
+	buckets.add(0, h.zero)
+	result := copyHistogramStats(multiValues)
+	buckets.writeTo(result)
+	return result.Values
+}
+
+// ReaggregateExplicit merges the explicit buckets into the buckets with the bounds, which is exact if the bounds
+// are a subset of the original bounds. The count of each bucket is put into the new bucket containing its upper bound.
+func ReaggregateExplicit(multiValues models.MetricFloatValues, bounds []float64) models.MetricFloatValues {
+	buckets := newExplicitBuckets(bounds)
+	for _, bucket := range ParseExplicitBuckets(multiValues) {
+		buckets.add(bucket.Upper, bucket.Count)
+	}
+	result := copyHistogramStats(multiValues)
+	buckets.writeTo(result)
+	return result.Values
+}
+
+// DownscaleExponential merges the exponential buckets to the lower scale, which is exact. The values are returned
+// as is if the scale is not lower.
+func DownscaleExponential(multiValues models.MetricFloatValues, scale int32) models.MetricFloatValues {
+	h := parseExponentialHistogram(multiValues)
+	if scale >= h.scale {
+		return multiValues
+	}
+	result := copyHistogramStats(multiValues)
+	h.downscale(scale).writeTo(result)
+	return result.Values
+}
+
+// AddHistograms returns the sum of the two histogram values, used to accumulate the deltas. The min and max are
+// the min and max of both if both have them. The exponential histograms are added at the lower scale, and false
+// is returned if the explicit bucket histograms have different bounds.
+func AddHistograms(a, b models.MetricFloatValues, exponential bool) (models.MetricFloatValues, bool) {
+	result, ok := combineHistograms(a, b, exponential, 1)
+	if !ok {
+		return nil, false
+	}
+	if a.Contains(FieldMin) && b.Contains(FieldMin) {
+		result.Add(FieldMin, math.Min(a.Get(FieldMin), b.Get(FieldMin)))
+	}
+	if a.Contains(FieldMax) && b.Contains(FieldMax) {
+		result.Add(FieldMax, math.Max(a.Get(FieldMax), b.Get(FieldMax)))
+	}
+	return result.Values, true
+}
+
+// SubtractHistograms returns the difference of the two histogram values, used to compute the delta from the
+// previous cumulative values b. The min and max are dropped as they cannot be computed. False is returned if the
+// explicit bucket histograms have different bounds, or the count of any bucket decreases, which means a reset.
+func SubtractHistograms(a, b models.MetricFloatValues, exponential bool) (models.MetricFloatValues, bool) {
+	result, ok := combineHistograms(a, b, exponential, -1)
+	if !ok {
+		return nil, false
+	}
+	for k, v := range result.Values.Iterator() {
+		if v < 0 && k != FieldSum && k != FieldScale && k != FieldPositiveOffset && k != FieldNegativeOffset {
+			return nil, false
+		}
+	}
+	return result.Values, true
+}
+
+// combineHistograms returns a + sign * b without the min and max.
+func combineHistograms(a, b models.MetricFloatValues, exponential bool, sign float64) (*models.MetricMultiValue, bool) {
+	result := models.NewMetricMultiValue()
+	result.Add(FieldCount, a.Get(FieldCount)+sign*b.Get(FieldCount))
+	if a.Contains(FieldSum) || b.Contains(FieldSum) {
+		result.Add(FieldSum, a.Get(FieldSum)+sign*b.Get(FieldSum))
+	}
+	if exponential {
+		ha, hb := parseExponentialHistogram(a), parseExponentialHistogram(b)
+		if ha.scale > hb.scale {
+			ha = ha.downscale(hb.scale)
+		} else {
+			hb = hb.downscale(ha.scale)
+		}
+		ha.zero += sign * hb.zero
+		for index, count := range hb.positives {
+			ha.positives[index] += sign * count
+		}
+		for index, count := range hb.negatives {
+			ha.negatives[index] += sign * count
+		}
+		ha.writeTo(result)
+		return result, true
+	}
+	bucketsA, bucketsB := ParseExplicitBuckets(a), ParseExplicitBuckets(b)
+	if len(bucketsA) != len(bucketsB) {
+		return nil, false
+	}
+	for i, bucket := range bucketsA {
+		if bucket.Lower != bucketsB[i].Lower || bucket.Upper != bucketsB[i].Upper {
+			return nil, false
+		}
+		result.Add(ComposeBucketFieldName(bucket.Lower, bucket.Upper, true), bucket.Count+sign*bucketsB[i].Count)
+	}
+	return result, true
+}
+
+type explicitBuckets struct {
+	bounds []float64
+	counts []float64
+}
+
+func newExplicitBuckets(bounds []float64) *explicitBuckets {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	return &explicitBuckets{bounds: sorted, counts: make([]float64, len(sorted)+1)}
+}
+
+func (b *explicitBuckets) add(value, count float64) {
+	b.counts[sort.SearchFloat64s(b.bounds, value)] += count
+}
+
+func (b *explicitBuckets) writeTo(result *models.MetricMultiValue) {
+	for i, count := range b.counts {
+		lower, upper := math.Inf(-1), math.Inf(1)
+		if i > 0 {
+			lower = b.bounds[i-1]
+		}
+		if i < len(b.bounds) {
+			upper = b.bounds[i]
+		}
+		result.Add(ComposeBucketFieldName(lower, upper, true), count)
+	}
+}
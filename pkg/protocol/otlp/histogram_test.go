@@ -0,0 +1,227 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+func TestExponentialBucketIndex(t *testing.T) {
+	tests := []struct {
+		value    float64
+		scale    int32
+		expected int32
+	}{
+		{value: 1, scale: 0, expected: -1},
+		{value: 1.5, scale: 0, expected: 0},
+		{value: 2, scale: 0, expected: 0},
+		{value: 2.1, scale: 0, expected: 1},
+		{value: 0.5, scale: 0, expected: -2},
+		{value: 2, scale: -1, expected: 0},
+		{value: 4, scale: -1, expected: 0},
+		{value: 4.1, scale: -1, expected: 1},
+		{value: ExponentialBucketLower(1, 1), scale: 1, expected: 0},
+		{value: 1.5, scale: 1, expected: 1},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ExponentialBucketIndex(test.value, test.scale), "value %v scale %v", test.value, test.scale)
+	}
+	assert.Equal(t, 2.0, ExponentialBucketLower(1, 0))
+	assert.Equal(t, 4.0, ExponentialBucketLower(1, -1))
+}
+
+func TestExplicitToExponentialAndBack(t *testing.T) {
+	explicit := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount:    10,
+		FieldSum:      30,
+		"(-Inf,0]":    1,
+		"(0,1]":       2,
+		"(1,2]":       3,
+		"(2,4]":       3,
+		"(4,+Inf]":    1,
+		"not_bucket]": 5,
+	}).Values
+	exponential := ExplicitToExponential(explicit, 0)
+	assert.Equal(t, map[string]float64{
+		FieldCount:          10,
+		FieldSum:            30,
+		FieldScale:          0,
+		FieldZeroCount:      1,
+		FieldPositiveOffset: -1,
+		FieldNegativeOffset: 0,
+		"(0.5,1]":           2,
+		"(1,2]":             3,
+		"(2,4]":             4,
+	}, exponential.Iterator())
+
+	back := ExponentialToExplicit(exponential, []float64{4, 0, 1, 2})
+	assert.Equal(t, map[string]float64{
+		FieldCount: 10,
+		FieldSum:   30,
+		"(-Inf,0]": 1,
+		"(0,1]":    2,
+		"(1,2]":    3,
+		"(2,4]":    4,
+		"(4,+Inf]": 0,
+	}, back.Iterator())
+}
+
+func TestExponentialToExplicitNegative(t *testing.T) {
+	exponential := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount:          6,
+		FieldScale:          0,
+		FieldZeroCount:      1,
+		FieldPositiveOffset: 0,
+		FieldNegativeOffset: 0,
+		"(1,2]":             2,
+		"(2,4]":             1,
+		"[-2,-1)":           1,
+		"[-4,-2)":           1,
+	}).Values
+	explicit := ExponentialToExplicit(exponential, []float64{-2, 0, 2})
+	assert.Equal(t, map[string]float64{
+		FieldCount:  6,
+		"(-Inf,-2]": 1,
+		"(-2,0]":    2,
+		"(0,2]":     2,
+		"(2,+Inf]":  1,
+	}, explicit.Iterator())
+
+	explicit = ExponentialToExplicit(exponential, nil)
+	assert.Equal(t, map[string]float64{
+		FieldCount:  6,
+		"(-Inf,-2]": 1,
+		"(-2,-1]":   1,
+		"(-1,0]":    1,
+		"(0,2]":     2,
+		"(2,4]":     1,
+		"(4,+Inf]":  0,
+	}, explicit.Iterator())
+}
+
+func TestDownscaleExponential(t *testing.T) {
+	exponential := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount:          7,
+		FieldScale:          1,
+		FieldZeroCount:      0,
+		FieldPositiveOffset: -1,
+		FieldNegativeOffset: 0,
+		ComposeBucketFieldName(ExponentialBucketLower(-1, 1), ExponentialBucketLower(0, 1), true): 1,
+		ComposeBucketFieldName(ExponentialBucketLower(0, 1), ExponentialBucketLower(1, 1), true):  2,
+		ComposeBucketFieldName(ExponentialBucketLower(1, 1), ExponentialBucketLower(2, 1), true):  4,
+	}).Values
+	downscaled := DownscaleExponential(exponential, 0)
+	assert.Equal(t, map[string]float64{
+		FieldCount:          7,
+		FieldScale:          0,
+		FieldZeroCount:      0,
+		FieldPositiveOffset: -1,
+		FieldNegativeOffset: 0,
+		"(0.5,1]":           1,
+		"(1,2]":             6,
+	}, downscaled.Iterator())
+	assert.Equal(t, exponential, DownscaleExponential(exponential, 2))
+}
+
+func TestReaggregateExplicit(t *testing.T) {
+	explicit := models.NewMetricMultiValueWithMap(map[string]float64{
+		"(-Inf,1]": 1,
+		"(1,2]":    2,
+		"(2,5]":    3,
+		"(5,+Inf]": 4,
+	}).Values
+	assert.Equal(t, map[string]float64{
+		"(-Inf,2]": 3,
+		"(2,+Inf]": 7,
+	}, ReaggregateExplicit(explicit, []float64{2}).Iterator())
+}
+
+func TestAddAndSubtractHistograms(t *testing.T) {
+	a := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount: 3,
+		FieldSum:   6,
+		FieldMin:   1,
+		FieldMax:   4,
+		"(-Inf,2]": 1,
+		"(2,+Inf]": 2,
+	}).Values
+	b := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount: 2,
+		FieldSum:   5,
+		FieldMin:   0.5,
+		FieldMax:   3,
+		"(-Inf,2]": 1,
+		"(2,+Inf]": 1,
+	}).Values
+	sum, ok := AddHistograms(a, b, false)
+	require.True(t, ok)
+	assert.Equal(t, map[string]float64{
+		FieldCount: 5,
+		FieldSum:   11,
+		FieldMin:   0.5,
+		FieldMax:   4,
+		"(-Inf,2]": 2,
+		"(2,+Inf]": 3,
+	}, sum.Iterator())
+
+	delta, ok := SubtractHistograms(sum, b, false)
+	require.True(t, ok)
+	assert.Equal(t, map[string]float64{
+		FieldCount: 3,
+		FieldSum:   6,
+		"(-Inf,2]": 1,
+		"(2,+Inf]": 2,
+	}, delta.Iterator())
+
+	_, ok = SubtractHistograms(b, sum, false)
+	assert.False(t, ok)
+	_, ok = AddHistograms(a, ReaggregateExplicit(b, []float64{1}), false)
+	assert.False(t, ok)
+}
+
+func TestAddExponentialHistograms(t *testing.T) {
+	a := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount:          3,
+		FieldScale:          1,
+		FieldZeroCount:      1,
+		FieldPositiveOffset: 0,
+		FieldNegativeOffset: 0,
+		ComposeBucketFieldName(ExponentialBucketLower(0, 1), ExponentialBucketLower(1, 1), true): 2,
+	}).Values
+	b := models.NewMetricMultiValueWithMap(map[string]float64{
+		FieldCount:          1,
+		FieldScale:          0,
+		FieldZeroCount:      0,
+		FieldPositiveOffset: 1,
+		FieldNegativeOffset: 0,
+		"(2,4]":             1,
+	}).Values
+	sum, ok := AddHistograms(a, b, true)
+	require.True(t, ok)
+	assert.Equal(t, map[string]float64{
+		FieldCount:          4,
+		FieldScale:          0,
+		FieldZeroCount:      1,
+		FieldPositiveOffset: 0,
+		FieldNegativeOffset: 0,
+		"(1,2]":             2,
+		"(2,4]":             1,
+	}, sum.Iterator())
+}
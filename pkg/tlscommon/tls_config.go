@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/alibaba/ilogtail/pkg/fips"
 )
 
 // The defaults should be a safe configuration
@@ -80,13 +82,17 @@ func (c *TLSConfig) LoadTLSConfig() (*tls.Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid TLS max_version: %w", err)
 	}
-	return &tls.Config{
+	tlsConfig := &tls.Config{
 		RootCAs:            certPool,
 		Certificates:       []tls.Certificate{cert},
 		InsecureSkipVerify: c.InsecureSkipVerify, //nolint:gosec
 		MinVersion:         minVersion,
 		MaxVersion:         maxVersion,
-	}, nil
+	}
+	if err = fips.RestrictTLSConfig(tlsConfig); err != nil {
+		return nil, err
+	}
+	return tlsConfig, nil
 }
 
 func (c *TLSConfig) loadCert(caPath string) (*x509.CertPool, error) {
@@ -0,0 +1,49 @@
+// Copyright 2023 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscommon
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/flags"
+)
+
+func TestLoadTLSConfigFIPSMode(t *testing.T) {
+	*flags.FIPSMode = true
+	defer func() { *flags.FIPSMode = false }()
+
+	c := &TLSConfig{Enabled: true}
+	tlsConfig, err := c.LoadTLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.NotContains(t, tlsConfig.CipherSuites, tls.TLS_RSA_WITH_AES_128_CBC_SHA)
+	assert.Contains(t, tlsConfig.CipherSuites, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+
+	c.MinVersion = "1.1"
+	_, err = c.LoadTLSConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadTLSConfigDefaultMode(t *testing.T) {
+	c := &TLSConfig{Enabled: true, MinVersion: "1.1"}
+	tlsConfig, err := c.LoadTLSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS11), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.CipherSuites)
+}
@@ -23,14 +23,19 @@ import (
 
 var ipAddress string
 var hostName string
+var detectedIPAddress string
+var detectedHostName string
+var cluster string
+var region string
 
 func init() {
 	var err error
-	ipAddress, err = getExternalIP()
+	detectedIPAddress, err = getExternalIP()
 	if err != nil {
 		log.Println(err)
 	}
-	hostName, _ = os.Hostname()
+	detectedHostName, _ = os.Hostname()
+	ipAddress, hostName = detectedIPAddress, detectedHostName
 }
 
 // SetNetworkIdentification updates return values of GetIPAddress and GetHostName.
@@ -40,6 +45,12 @@ func SetNetworkIdentification(hostIP, hostname string) {
 	hostName = hostname
 }
 
+// SetClusterAndRegion updates return values of GetCluster and GetRegion, it is called along with SetNetworkIdentification.
+func SetClusterAndRegion(clusterID, regionID string) {
+	cluster = clusterID
+	region = regionID
+}
+
 func GetIPAddress() string {
 	return ipAddress
 }
@@ -48,6 +59,24 @@ func GetHostName() string {
 	return hostName
 }
 
+// GetDetectedIPAddress returns the first ipv4 address of the network interfaces, regardless of the identity overrides.
+func GetDetectedIPAddress() string {
+	return detectedIPAddress
+}
+
+// GetDetectedHostName returns the hostname reported by the kernel, regardless of the identity overrides.
+func GetDetectedHostName() string {
+	return detectedHostName
+}
+
+func GetCluster() string {
+	return cluster
+}
+
+func GetRegion() string {
+	return region
+}
+
 func getExternalIP() (string, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
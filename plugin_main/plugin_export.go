@@ -133,12 +133,9 @@ func LoadGlobalConfig(jsonStr string) int {
 		}
 	})
 	if retcode == 0 {
-		// Update when both of them are not empty.
 		logger.Debugf(context.Background(), "host IP: %v, hostname: %v",
 			config.LoongcollectorGlobalConfig.HostIP, config.LoongcollectorGlobalConfig.Hostname)
-		if len(config.LoongcollectorGlobalConfig.Hostname) > 0 && len(config.LoongcollectorGlobalConfig.HostIP) > 0 {
-			util.SetNetworkIdentification(config.LoongcollectorGlobalConfig.HostIP, config.LoongcollectorGlobalConfig.Hostname)
-		}
+		helper.InitIdentity(&config.LoongcollectorGlobalConfig)
 	}
 	return retcode
 }
@@ -90,6 +90,10 @@ func HelpServer(w http.ResponseWriter, req *http.Request) {
 		/debug/pprof/heap?debug=1
 		/debug/pprof/threadcreate?debug=1
 		/forcegc
+		/config/rendered?name=  to dump the rendered pipeline configs
+		/metrics/history?name=&since=  to dump the recent history of the go plugin metrics
+		/checkpoint/export?name=  to export the checkpoints as a bundle
+		/checkpoint/import?overwrite=  to import the checkpoint bundle in the POST body
 		`)
 }
 
@@ -119,6 +123,13 @@ func HandleLoadConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleImportCheckpoints import the checkpoint bundle, which should be done before the pipelines load the checkpoints.
+func HandleImportCheckpoints(w http.ResponseWriter, r *http.Request) {
+	controlLock.Lock()
+	defer controlLock.Unlock()
+	pluginmanager.HandleImportCheckpoints(w, r)
+}
+
 // HandleHoldOn hold on the ilogtail process.
 func HandleHoldOn(w http.ResponseWriter, r *http.Request) {
 	controlLock.Lock()
@@ -178,6 +189,10 @@ func InitHTTPServer() {
 		if *flags.HTTPLoadFlag {
 			handlers["/loadconfig"] = &handler{handlerFunc: HandleLoadConfig, description: "load new logtail plugin configuration"}
 			handlers["/holdon"] = &handler{handlerFunc: HandleHoldOn, description: "hold on logtail plugin process"}
+			handlers["/config/rendered"] = &handler{handlerFunc: pluginmanager.HandleRenderedConfig, description: "dump the rendered pipeline configs"}
+			handlers["/metrics/history"] = &handler{handlerFunc: pluginmanager.HandleMetricsHistory, description: "dump the recent history of the go plugin metrics"}
+			handlers["/checkpoint/export"] = &handler{handlerFunc: pluginmanager.HandleExportCheckpoints, description: "export the checkpoints"}
+			handlers["/checkpoint/import"] = &handler{handlerFunc: HandleImportCheckpoints, description: "import the checkpoints"}
 		}
 		if *flags.HTTPProfFlag {
 			handlers["/mem"] = &handler{handlerFunc: HandleMem, description: "dump mem info"}
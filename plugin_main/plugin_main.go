@@ -30,6 +30,7 @@ import (
 	"github.com/alibaba/ilogtail/pkg/signals"
 	"github.com/alibaba/ilogtail/pkg/util"
 	_ "github.com/alibaba/ilogtail/plugin_main/wrapmemcpy"
+	"github.com/alibaba/ilogtail/pluginmanager"
 	_ "github.com/alibaba/ilogtail/plugins/all"
 )
 
@@ -134,6 +135,13 @@ func main() {
 		generatePluginDoc()
 		return
 	}
+	if *flags.Schema {
+		if err := renderFlusherSchema(); err != nil {
+			fmt.Fprintln(os.Stderr, "render flusher schema error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 	cpu := runtime.NumCPU()
 	procs := runtime.GOMAXPROCS(0)
 	fmt.Println("cpu num:", cpu, " GOMAXPROCS:", procs)
@@ -198,3 +206,31 @@ func generatePluginDoc() {
 	}
 	doc.Generate(*flags.DocPath)
 }
+
+func renderFlusherSchema() error {
+	pluginCfg, err := os.ReadFile(*flags.PluginConfig)
+	if err != nil {
+		return err
+	}
+	events, err := os.ReadFile(*flags.SchemaEvents)
+	if err != nil {
+		return err
+	}
+	schemas, err := pluginmanager.RenderFlusherSchemas(string(pluginCfg), events)
+	if err != nil {
+		return err
+	}
+	switch *flags.SchemaFormat {
+	case "markdown":
+		fmt.Print(pluginmanager.FlusherSchemasToMarkdown(schemas))
+	case "json":
+		b, err := json.MarshalIndent(schemas, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	default:
+		return fmt.Errorf("unknown schema format %s", *flags.SchemaFormat)
+	}
+	return nil
+}
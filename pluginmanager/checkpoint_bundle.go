@@ -0,0 +1,176 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	pkgutil "github.com/alibaba/ilogtail/pkg/util"
+)
+
+const checkpointBundleVersion = 1
+
+// CheckpointBundle is the portable form of the checkpoints, which could be imported on another host
+// to take over the collection without collecting the data again.
+type CheckpointBundle struct {
+	Version     int               `json:"version"`
+	Host        string            `json:"host"`
+	ExportTime  int64             `json:"export_time"`
+	Checkpoints []CheckpointEntry `json:"checkpoints"`
+}
+
+// CheckpointEntry is a checkpoint of a config, the value is encoded in base64.
+type CheckpointEntry struct {
+	ConfigName string `json:"config_name"`
+	Key        string `json:"key"`
+	Value      []byte `json:"value"`
+}
+
+// ExportCheckpoints exports the checkpoints of the config, or all the checkpoints if configName is empty.
+func (p *checkPointManager) ExportCheckpoints(configName string) (*CheckpointBundle, error) {
+	if p.db == nil {
+		return nil, ErrCheckPointNotInit
+	}
+	var slice *util.Range
+	if configName != "" {
+		slice = util.BytesPrefix([]byte(configName + "^"))
+	}
+	bundle := &CheckpointBundle{
+		Version:     checkpointBundleVersion,
+		Host:        pkgutil.GetHostName(),
+		ExportTime:  time.Now().Unix(),
+		Checkpoints: make([]CheckpointEntry, 0),
+	}
+	iter := p.db.NewIterator(slice, nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := string(iter.Key())
+		index := strings.IndexByte(key, '^')
+		if index <= 0 {
+			continue
+		}
+		value, err := decodeCheckpoint(iter.Value())
+		if err != nil {
+			logger.Warning(context.Background(), "CHECKPOINT_ALARM", "skip corrupted checkpoint when export", key)
+			continue
+		}
+		bundle.Checkpoints = append(bundle.Checkpoints, CheckpointEntry{
+			ConfigName: key[:index],
+			Key:        key[index+1:],
+			Value:      append([]byte(nil), value...),
+		})
+	}
+	return bundle, iter.Error()
+}
+
+// ImportCheckpoints writes the checkpoints of the bundle in a batch, the existing checkpoints are kept unless overwrite,
+// as they may be newer than the imported ones. It returns the count of the imported and the skipped checkpoints.
+func (p *checkPointManager) ImportCheckpoints(bundle *CheckpointBundle, overwrite bool) (imported, skipped int, err error) {
+	if p.db == nil {
+		return 0, 0, ErrCheckPointNotInit
+	}
+	if bundle.Version != checkpointBundleVersion {
+		return 0, 0, fmt.Errorf("unsupported checkpoint bundle version %d", bundle.Version)
+	}
+	batch := new(leveldb.Batch)
+	for _, entry := range bundle.Checkpoints {
+		if entry.ConfigName == "" || strings.IndexByte(entry.ConfigName, '^') >= 0 {
+			return 0, 0, fmt.Errorf("invalid config name of checkpoint %s: %q", entry.Key, entry.ConfigName)
+		}
+		key := []byte(entry.ConfigName + "^" + entry.Key)
+		if !overwrite {
+			exist, err := p.db.Has(key, nil)
+			if err != nil {
+				return 0, 0, err
+			}
+			if exist {
+				skipped++
+				continue
+			}
+		}
+		batch.Put(key, encodeCheckpoint(entry.Value))
+		imported++
+	}
+	if err = p.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return 0, 0, err
+	}
+	logger.Info(context.Background(), "import checkpoints from", bundle.Host, "imported", imported, "skipped", skipped)
+	return imported, skipped, nil
+}
+
+// HandleExportCheckpoints exports the checkpoints as a bundle, the config is specified by the name parameter.
+func HandleExportCheckpoints(res http.ResponseWriter, req *http.Request) {
+	bundle, err := CheckPointManager.ExportCheckpoints(req.URL.Query().Get("name"))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		_, _ = res.Write([]byte(err.Error()))
+		return
+	}
+	jsonBytes, err := json.Marshal(bundle)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		_, _ = res.Write([]byte(err.Error()))
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	if _, err = res.Write(jsonBytes); err != nil {
+		logger.Error(context.Background(), "write response err", err.Error())
+	}
+}
+
+// HandleImportCheckpoints imports the bundle in the request body, the existing checkpoints are replaced if the overwrite parameter is true.
+func HandleImportCheckpoints(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = res.Write([]byte("method not allowed, use POST"))
+		return
+	}
+	overwrite, _ := strconv.ParseBool(req.URL.Query().Get("overwrite"))
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		_, _ = res.Write([]byte("read body error"))
+		return
+	}
+	var bundle CheckpointBundle
+	if err = json.Unmarshal(body, &bundle); err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		_, _ = res.Write([]byte("parse body error: " + err.Error()))
+		return
+	}
+	imported, skipped, err := CheckPointManager.ImportCheckpoints(&bundle, overwrite)
+	if err != nil {
+		logger.Error(context.Background(), "CHECKPOINT_ALARM", "import checkpoints error", err)
+		res.WriteHeader(http.StatusBadRequest)
+		_, _ = res.Write([]byte(err.Error()))
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(res, `{"imported":%d,"skipped":%d}`, imported, skipped)
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointExportImport(t *testing.T) {
+	MkdirDataDir()
+	require.NoError(t, CheckPointManager.Init())
+	require.NoError(t, CheckPointManager.SaveCheckpoint("bundle_a", "file^1", []byte("offset-1")))
+	require.NoError(t, CheckPointManager.SaveCheckpoint("bundle_a", "file^2", []byte("offset-2")))
+	require.NoError(t, CheckPointManager.SaveCheckpoint("bundle_b", "binlog", []byte("pos")))
+
+	res := httptest.NewRecorder()
+	HandleExportCheckpoints(res, httptest.NewRequest(http.MethodGet, "/checkpoint/export?name=bundle_a", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var bundle CheckpointBundle
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &bundle))
+	assert.Equal(t, checkpointBundleVersion, bundle.Version)
+	assert.Equal(t, []CheckpointEntry{
+		{ConfigName: "bundle_a", Key: "file^1", Value: []byte("offset-1")},
+		{ConfigName: "bundle_a", Key: "file^2", Value: []byte("offset-2")},
+	}, bundle.Checkpoints)
+
+	// simulate the new host, which has collected file 2 further
+	require.NoError(t, CheckPointManager.DeleteCheckpoint("bundle_a", "file^1"))
+	require.NoError(t, CheckPointManager.SaveCheckpoint("bundle_a", "file^2", []byte("offset-3")))
+	body := res.Body.String()
+	res = httptest.NewRecorder()
+	HandleImportCheckpoints(res, httptest.NewRequest(http.MethodPost, "/checkpoint/import", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"imported":1,"skipped":1}`, res.Body.String())
+	data, err := CheckPointManager.GetCheckpoint("bundle_a", "file^1")
+	require.NoError(t, err)
+	assert.Equal(t, "offset-1", string(data))
+	data, err = CheckPointManager.GetCheckpoint("bundle_a", "file^2")
+	require.NoError(t, err)
+	assert.Equal(t, "offset-3", string(data))
+
+	res = httptest.NewRecorder()
+	HandleImportCheckpoints(res, httptest.NewRequest(http.MethodPost, "/checkpoint/import?overwrite=true", strings.NewReader(body)))
+	assert.JSONEq(t, `{"imported":2,"skipped":0}`, res.Body.String())
+	data, err = CheckPointManager.GetCheckpoint("bundle_a", "file^2")
+	require.NoError(t, err)
+	assert.Equal(t, "offset-2", string(data))
+
+	res = httptest.NewRecorder()
+	HandleImportCheckpoints(res, httptest.NewRequest(http.MethodPost, "/checkpoint/import", strings.NewReader(`{"version":2}`)))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	res = httptest.NewRecorder()
+	HandleImportCheckpoints(res, httptest.NewRequest(http.MethodGet, "/checkpoint/import", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+
+	for _, key := range []string{"file^1", "file^2"} {
+		_ = CheckPointManager.DeleteCheckpoint("bundle_a", key)
+	}
+	_ = CheckPointManager.DeleteCheckpoint("bundle_b", "binlog")
+}
@@ -15,15 +15,23 @@
 package pluginmanager
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"flag"
+	"fmt"
+	"hash/crc32"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	leveldbErrors "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	leveldbUtil "github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/alibaba/ilogtail/pkg/config"
 	"github.com/alibaba/ilogtail/pkg/logger"
@@ -33,6 +41,8 @@ import (
 var CheckPointFile = flag.String("CheckPointFile", "", "checkpoint file name, base dir(binary dir)")
 var CheckPointCleanInterval = flag.Int("CheckPointCleanInterval", 600, "checkpoint clean interval, second")
 var MaxCleanItemPerInterval = flag.Int("MaxCleanItemPerInterval", 1000, "max clean items per interval")
+var CheckPointCompactInterval = flag.Int("CheckPointCompactInterval", 3600, "checkpoint compact interval, second, 0 means never")
+var CheckPointSyncWrite = flag.Bool("CheckPointSyncWrite", false, "sync the checkpoint journal to the disk on every write")
 
 const DefaultCleanThreshold = 6 // one hour
 
@@ -43,17 +53,50 @@ type checkPointManager struct {
 	initFlag       bool
 	configCounter  map[string]int
 	cleanThreshold int
+	lastCompact    time.Time
 }
 
 var CheckPointManager checkPointManager
 
 var ErrCheckPointNotInit = errors.New("checkpoint db not init")
 
+// ErrCheckPointCorrupted means the checksum of the checkpoint mismatches, the checkpoint is dropped.
+var ErrCheckPointCorrupted = errors.New("checkpoint corrupted")
+
+// checkpointMagic marks the values with the crc32 checksum, the values saved by the old versions have no magic.
+var checkpointMagic = []byte{0, 'c', 'k', 1}
+
+var checkpointCrcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// encodeCheckpoint prepends the magic and the crc32 of the value, so that a torn or corrupted record is
+// detected when read, and only the record is dropped.
+func encodeCheckpoint(value []byte) []byte {
+	buf := make([]byte, len(checkpointMagic)+4+len(value))
+	copy(buf, checkpointMagic)
+	binary.BigEndian.PutUint32(buf[len(checkpointMagic):], crc32.Checksum(value, checkpointCrcTable))
+	copy(buf[len(checkpointMagic)+4:], value)
+	return buf
+}
+
+func decodeCheckpoint(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, checkpointMagic) {
+		return data, nil
+	}
+	if len(data) < len(checkpointMagic)+4 {
+		return nil, ErrCheckPointCorrupted
+	}
+	value := data[len(checkpointMagic)+4:]
+	if binary.BigEndian.Uint32(data[len(checkpointMagic):]) != crc32.Checksum(value, checkpointCrcTable) {
+		return nil, ErrCheckPointCorrupted
+	}
+	return value, nil
+}
+
 func (p *checkPointManager) SaveCheckpoint(configName, key string, value []byte) error {
 	if p.db == nil {
 		return ErrCheckPointNotInit
 	}
-	err := p.db.Put([]byte(configName+"^"+key), value, nil)
+	err := p.db.Put([]byte(configName+"^"+key), encodeCheckpoint(value), &opt.WriteOptions{Sync: *CheckPointSyncWrite})
 	if err != nil {
 		logger.Error(context.Background(), "CHECKPOINT_SAVE_ALARM", "save checkpoint error, key", key, "error", err)
 	}
@@ -64,9 +107,17 @@ func (p *checkPointManager) GetCheckpoint(configName, key string) ([]byte, error
 	if p.db == nil {
 		return nil, ErrCheckPointNotInit
 	}
-	val, err := p.db.Get([]byte(configName+"^"+key), nil)
-	if err != nil && err != leveldb.ErrNotFound {
-		logger.Error(context.Background(), "CHECKPOINT_GET_ALARM", "get checkpoint error, key", key, "error", err)
+	data, err := p.db.Get([]byte(configName+"^"+key), nil)
+	if err != nil {
+		if err != leveldb.ErrNotFound {
+			logger.Error(context.Background(), "CHECKPOINT_GET_ALARM", "get checkpoint error, key", key, "error", err)
+		}
+		return nil, err
+	}
+	val, err := decodeCheckpoint(data)
+	if err != nil {
+		logger.Error(context.Background(), "CHECKPOINT_GET_ALARM", "drop corrupted checkpoint, config", configName, "key", key)
+		_ = p.db.Delete([]byte(configName+"^"+key), nil)
 	}
 	return val, err
 }
@@ -100,21 +151,45 @@ func (p *checkPointManager) Init() error {
 		return err
 	}
 
-	p.db, err = leveldb.OpenFile(dbPath, nil)
-	if err != nil {
-		logger.Warning(context.Background(), "CHECKPOINT_ALARM", "open checkpoint error", err, "try recover db file", dbPath)
-		p.db, err = leveldb.RecoverFile(dbPath, nil)
-	}
-
+	p.db, err = openCheckpointDB(dbPath)
 	if err != nil {
-		logger.Error(context.Background(), "CHECKPOINT_ALARM", "recover db file error", err)
 		return err
 	}
+	p.lastCompact = time.Now()
 	p.initFlag = true
 	logger.Info(context.Background(), "init checkpoint", "success")
 	return nil
 }
 
+// checkpointDBOptions drops the corrupted journal chunks and tables instead of failing the whole db,
+// so that a partial write during power loss only loses the checkpoints in the torn records.
+var checkpointDBOptions = &opt.Options{
+	Strict: opt.StrictJournalChecksum | opt.StrictBlockChecksum | opt.StrictRecovery,
+}
+
+// openCheckpointDB opens the db, and recovers it if corrupted. The db is moved aside as the last resort if it
+// could not be recovered, so that the plugins could still save the checkpoints.
+func openCheckpointDB(dbPath string) (*leveldb.DB, error) {
+	db, err := leveldb.OpenFile(dbPath, checkpointDBOptions)
+	if err == nil {
+		return db, nil
+	}
+	logger.Warning(context.Background(), "CHECKPOINT_ALARM", "open checkpoint error", err, "try recover db file", dbPath)
+	if db, err = leveldb.RecoverFile(dbPath, checkpointDBOptions); err == nil {
+		return db, nil
+	}
+	logger.Error(context.Background(), "CHECKPOINT_ALARM", "recover db file error", err)
+	if !leveldbErrors.IsCorrupted(err) {
+		return nil, err
+	}
+	corruptedPath := fmt.Sprintf("%s.corrupted.%d", dbPath, time.Now().Unix())
+	if err = os.Rename(dbPath, corruptedPath); err != nil {
+		return nil, err
+	}
+	logger.Error(context.Background(), "CHECKPOINT_ALARM", "move the corrupted db to", corruptedPath, "and create a new one", dbPath)
+	return leveldb.OpenFile(dbPath, checkpointDBOptions)
+}
+
 func (p *checkPointManager) Stop() {
 	logger.Info(context.Background(), "checkpoint", "Stop")
 	if p.db == nil {
@@ -141,6 +216,19 @@ func (p *checkPointManager) run() {
 			return
 		}
 		p.check()
+		p.compact()
+	}
+}
+
+// compact compacts the whole db every CheckPointCompactInterval seconds, which purges the deleted checkpoints
+// and rewrites the journal into the tables.
+func (p *checkPointManager) compact() {
+	if p.db == nil || *CheckPointCompactInterval <= 0 || time.Since(p.lastCompact) < time.Second*time.Duration(*CheckPointCompactInterval) {
+		return
+	}
+	p.lastCompact = time.Now()
+	if err := p.db.CompactRange(leveldbUtil.Range{}); err != nil {
+		logger.Warning(context.Background(), "CHECKPOINT_ALARM", "compact checkpoint error", err)
 	}
 }
 
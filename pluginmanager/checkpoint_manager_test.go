@@ -15,11 +15,16 @@
 package pluginmanager
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/syndtr/goleveldb/leveldb"
+
 	"github.com/alibaba/ilogtail/pkg/config"
 )
 
@@ -140,3 +145,81 @@ func Test_checkPointManager_keyMatch(t *testing.T) {
 		LogtailConfigLock.Unlock()
 	})
 }
+
+func Test_checkPointManager_corruptedRecord(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+	if err := CheckPointManager.SaveCheckpoint("crc", "good", []byte("offset")); err != nil {
+		t.Fatalf("checkPointManager.SaveCheckpoint() error = %v", err)
+	}
+	data := encodeCheckpoint([]byte("offset"))
+	data[len(data)-1] ^= 0xff
+	_ = CheckPointManager.db.Put([]byte("crc^bad"), data, nil)
+	_ = CheckPointManager.db.Put([]byte("crc^legacy"), []byte("old"), nil)
+
+	if val, err := CheckPointManager.GetCheckpoint("crc", "good"); err != nil || string(val) != "offset" {
+		t.Errorf("checkPointManager.GetCheckpoint() error, %v %v", err, string(val))
+	}
+	if val, err := CheckPointManager.GetCheckpoint("crc", "legacy"); err != nil || string(val) != "old" {
+		t.Errorf("checkPointManager.GetCheckpoint() legacy error, %v %v", err, string(val))
+	}
+	if val, err := CheckPointManager.GetCheckpoint("crc", "bad"); err != ErrCheckPointCorrupted || val != nil {
+		t.Errorf("checkPointManager.GetCheckpoint() should drop the corrupted checkpoint, %v %v", err, string(val))
+	}
+	if _, err := CheckPointManager.db.Get([]byte("crc^bad"), nil); err != leveldb.ErrNotFound {
+		t.Errorf("the corrupted checkpoint should be deleted, %v", err)
+	}
+	_ = CheckPointManager.DeleteCheckpoint("crc", "good")
+	_ = CheckPointManager.DeleteCheckpoint("crc", "legacy")
+}
+
+func Test_openCheckpointDB_tornJournal(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "checkpoint")
+	db, err := openCheckpointDB(dbPath)
+	if err != nil {
+		t.Fatalf("openCheckpointDB() error = %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		_ = db.Put([]byte(fmt.Sprintf("c^%03d", i)), encodeCheckpoint(bytes.Repeat([]byte{'x'}, 1024)), nil)
+	}
+	_ = db.Close()
+
+	// simulate a partial write during power loss
+	journals, _ := filepath.Glob(filepath.Join(dbPath, "*.log"))
+	if len(journals) != 1 {
+		t.Fatalf("expect one journal, got %v", journals)
+	}
+	info, _ := os.Stat(journals[0])
+	if err = os.Truncate(journals[0], info.Size()-100); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = openCheckpointDB(dbPath)
+	if err != nil {
+		t.Fatalf("openCheckpointDB() should tolerate the torn journal, error = %v", err)
+	}
+	defer db.Close()
+	data, err := db.Get([]byte("c^000"), nil)
+	if err != nil {
+		t.Fatalf("the checkpoints before the torn record should be kept, error = %v", err)
+	}
+	if _, err = decodeCheckpoint(data); err != nil {
+		t.Errorf("decodeCheckpoint() error = %v", err)
+	}
+	if _, err = db.Get([]byte("c^099"), nil); err != leveldb.ErrNotFound {
+		t.Errorf("the torn record should be dropped, error = %v", err)
+	}
+}
+
+func Test_checkPointManager_compact(t *testing.T) {
+	MkdirDataDir()
+	CheckPointManager.Init()
+	interval := *CheckPointCompactInterval
+	defer func() { *CheckPointCompactInterval = interval }()
+	*CheckPointCompactInterval = 1
+	CheckPointManager.lastCompact = time.Now().Add(-time.Hour)
+	CheckPointManager.compact()
+	if time.Since(CheckPointManager.lastCompact) > time.Minute {
+		t.Errorf("checkPointManager.compact() should compact the db")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	pipelineTemplateDir = "pipeline_template"
+	extendsKey          = "Extends"
+)
+
+var pluginListKeys = map[string]bool{
+	"inputs":      true,
+	"processors":  true,
+	"aggregators": true,
+	"flushers":    true,
+	"extensions":  true,
+}
+
+// templateLoader returns the pipeline template with the name, it is replaced in the tests.
+var templateLoader = loadPipelineTemplate
+
+// loadPipelineTemplate reads the template from the pipeline_template directory under the conf dir,
+// the template is a partial pipeline config in json or yaml.
+func loadPipelineTemplate(name string) (map[string]interface{}, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	dir := filepath.Join(config.LoongcollectorGlobalConfig.LoongCollectorConfDir, pipelineTemplateDir)
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		data, err := os.ReadFile(filepath.Clean(filepath.Join(dir, name+ext)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		template := make(map[string]interface{})
+		if ext == ".json" {
+			err = json.Unmarshal(data, &template)
+		} else {
+			err = yaml.Unmarshal(data, &template)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s error: %v", name, err)
+		}
+		return normalizeTemplate(template), nil
+	}
+	return nil, fmt.Errorf("template %s not found in %s", name, dir)
+}
+
+// normalizeTemplate converts the plugins written as {"Type": "processor_regex", "SourceKey": "content"}
+// to the form of the pipeline config {"type": "processor_regex", "detail": {"SourceKey": "content"}}.
+func normalizeTemplate(template map[string]interface{}) map[string]interface{} {
+	for key, value := range template {
+		list, ok := value.([]interface{})
+		if !ok || !pluginListKeys[key] {
+			continue
+		}
+		for i, item := range list {
+			plugin, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pluginType, ok := plugin["Type"]
+			if !ok {
+				continue
+			}
+			detail := make(map[string]interface{}, len(plugin)-1)
+			for k, v := range plugin {
+				if k != "Type" {
+					detail[k] = v
+				}
+			}
+			list[i] = map[string]interface{}{"type": pluginType, "detail": detail}
+		}
+	}
+	return template
+}
+
+// extendsOf returns the template names in "global.Extends" and removes the field, which is a string or a list.
+func extendsOf(cfg map[string]interface{}) ([]string, error) {
+	global, ok := cfg["global"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	value, ok := global[extendsKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(global, extendsKey)
+	switch val := value.(type) {
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		names := make([]string, 0, len(val))
+		for _, item := range val {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("global.%s must be a string or a list of strings", extendsKey)
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	return nil, fmt.Errorf("global.%s must be a string or a list of strings", extendsKey)
+}
+
+// applyPipelineTemplates renders the config with the templates it extends. The templates are merged in order
+// and the config overrides them at last, a template may extend other templates but not itself.
+func applyPipelineTemplates(cfg map[string]interface{}) (map[string]interface{}, error) {
+	return extendPipeline(cfg, nil)
+}
+
+func extendPipeline(cfg map[string]interface{}, chain []string) (map[string]interface{}, error) {
+	names, err := extendsOf(cfg)
+	if err != nil || len(names) == 0 {
+		return cfg, err
+	}
+	base := make(map[string]interface{})
+	for _, name := range names {
+		for _, extended := range chain {
+			if extended == name {
+				return nil, fmt.Errorf("cyclic template extends: %s -> %s", strings.Join(chain, " -> "), name)
+			}
+		}
+		template, err := templateLoader(name)
+		if err != nil {
+			return nil, err
+		}
+		if template, err = extendPipeline(template, append(chain, name)); err != nil {
+			return nil, err
+		}
+		base = mergePipeline(base, template)
+	}
+	return mergePipeline(base, cfg), nil
+}
+
+// mergePipeline merges the override config into the base one. The global fields are merged recursively,
+// and the plugins with the same type (including the custom id) are merged, while the others are appended.
+func mergePipeline(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		baseValue, ok := base[key]
+		if !ok {
+			base[key] = value
+			continue
+		}
+		if pluginListKeys[key] {
+			baseList, baseOk := baseValue.([]interface{})
+			list, ok := value.([]interface{})
+			if baseOk && ok {
+				base[key] = mergePlugins(baseList, list)
+				continue
+			}
+		}
+		base[key] = mergeValue(baseValue, value)
+	}
+	return base
+}
+
+func mergePlugins(base, override []interface{}) []interface{} {
+	res := append([]interface{}{}, base...)
+	index := make(map[string]int, len(base))
+	for i, item := range base {
+		if pluginType, ok := pluginTypeOf(item); ok {
+			index[pluginType] = i
+		}
+	}
+	for _, item := range override {
+		pluginType, ok := pluginTypeOf(item)
+		if i, exists := index[pluginType]; ok && exists {
+			res[i] = mergeValue(res[i], item)
+			continue
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+func pluginTypeOf(item interface{}) (string, bool) {
+	plugin, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	pluginType, ok := plugin["type"].(string)
+	return pluginType, ok
+}
+
+// mergeValue merges the maps recursively, and the override value wins otherwise.
+func mergeValue(base, override interface{}) interface{} {
+	baseMap, baseOk := base.(map[string]interface{})
+	overrideMap, ok := override.(map[string]interface{})
+	if !baseOk || !ok {
+		return override
+	}
+	res := make(map[string]interface{}, len(baseMap)+len(overrideMap))
+	for k, v := range baseMap {
+		res[k] = v
+	}
+	for k, v := range overrideMap {
+		if baseValue, exists := res[k]; exists {
+			res[k] = mergeValue(baseValue, v)
+		} else {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// HandleRenderedConfig dumps the running pipeline configs after the templates are applied, the var references
+// are dumped as they are instead of the resolved values.
+// The config name without the suffix is selected by the "name" query parameter, all configs are dumped if empty.
+func HandleRenderedConfig(res http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	rendered := make(map[string]json.RawMessage)
+	LogtailConfigLock.RLock()
+	for nameWithSuffix, lc := range LogtailConfig {
+		if name == "" || lc.ConfigName == name {
+			rendered[nameWithSuffix] = json.RawMessage(lc.renderedConfig)
+		}
+	}
+	LogtailConfigLock.RUnlock()
+	if name != "" && len(rendered) == 0 {
+		res.WriteHeader(http.StatusNotFound)
+		_, _ = res.Write([]byte("config not found: " + name))
+		return
+	}
+	jsonBytes, err := json.MarshalIndent(rendered, "", "  ")
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		_, _ = res.Write([]byte(err.Error()))
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	if _, err = res.Write(jsonBytes); err != nil {
+		logger.Error(context.Background(), "write response err", err.Error())
+	}
+}
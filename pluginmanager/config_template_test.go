@@ -0,0 +1,165 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+func mockTemplates(t *testing.T, templates map[string]string) {
+	templateLoader = func(name string) (map[string]interface{}, error) {
+		str, ok := templates[name]
+		if !ok {
+			return nil, fmt.Errorf("template %s not found", name)
+		}
+		template := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(str), &template))
+		return template, nil
+	}
+	t.Cleanup(func() {
+		templateLoader = loadPipelineTemplate
+	})
+}
+
+func TestApplyPipelineTemplates(t *testing.T) {
+	mockTemplates(t, map[string]string{
+		"base": `{
+			"global": {"InputIntervalMs": 5000, "Vars": {"region": "cn-hangzhou", "logstore": "base"}},
+			"processors": [{"type": "processor_drop", "detail": {"DropKeys": ["debug"]}}],
+			"flushers": [{"type": "flusher_sls", "detail": {"Endpoint": "${vars.region}.log.aliyuncs.com", "Logstore": "${vars.logstore}"}}]
+		}`,
+		"parse": `{
+			"global": {"Extends": "base"},
+			"processors": [{"type": "processor_regex/parse", "detail": {"SourceKey": "content", "Regex": "(\\S+) (.*)", "Keys": ["level", "msg"]}}]
+		}`,
+	})
+	cfg := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"global": {"Extends": ["parse"], "Vars": {"logstore": "app"}},
+		"inputs": [{"type": "metric_mock", "detail": {}}],
+		"processors": [
+			{"type": "processor_regex/parse", "detail": {"Keys": ["level", "message"]}},
+			{"type": "processor_add_fields", "detail": {"Fields": {"app": "demo"}}}
+		]
+	}`), &cfg))
+	cfg, err := applyPipelineTemplates(cfg)
+	require.NoError(t, err)
+	require.NoError(t, resolveConfigVars(cfg, nil))
+
+	expected := `{
+		"global": {"InputIntervalMs": 5000, "Vars": {"region": "cn-hangzhou", "logstore": "app"}},
+		"inputs": [{"type": "metric_mock", "detail": {}}],
+		"processors": [
+			{"type": "processor_drop", "detail": {"DropKeys": ["debug"]}},
+			{"type": "processor_regex/parse", "detail": {"SourceKey": "content", "Regex": "(\\S+) (.*)", "Keys": ["level", "message"]}},
+			{"type": "processor_add_fields", "detail": {"Fields": {"app": "demo"}}}
+		],
+		"flushers": [{"type": "flusher_sls", "detail": {"Endpoint": "cn-hangzhou.log.aliyuncs.com", "Logstore": "app"}}]
+	}`
+	rendered, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(rendered))
+}
+
+func TestApplyPipelineTemplatesError(t *testing.T) {
+	mockTemplates(t, map[string]string{
+		"a": `{"global": {"Extends": "b"}}`,
+		"b": `{"global": {"Extends": ["c", "a"]}}`,
+		"c": `{}`,
+	})
+	_, err := applyPipelineTemplates(map[string]interface{}{"global": map[string]interface{}{"Extends": "a"}})
+	assert.EqualError(t, err, "cyclic template extends: a -> b -> a")
+
+	_, err = applyPipelineTemplates(map[string]interface{}{"global": map[string]interface{}{"Extends": "not_exist"}})
+	assert.EqualError(t, err, "template not_exist not found")
+
+	_, err = applyPipelineTemplates(map[string]interface{}{"global": map[string]interface{}{"Extends": 1.0}})
+	assert.EqualError(t, err, "global.Extends must be a string or a list of strings")
+}
+
+func TestLoadPipelineTemplate(t *testing.T) {
+	confDir := config.LoongcollectorGlobalConfig.LoongCollectorConfDir
+	config.LoongcollectorGlobalConfig.LoongCollectorConfDir = t.TempDir()
+	defer func() {
+		config.LoongcollectorGlobalConfig.LoongCollectorConfDir = confDir
+	}()
+	dir := filepath.Join(config.LoongcollectorGlobalConfig.LoongCollectorConfDir, pipelineTemplateDir)
+	require.NoError(t, os.MkdirAll(dir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shared.yaml"), []byte(`
+global:
+  InputIntervalMs: 5000
+flushers:
+  - Type: flusher_stdout
+    OnlyStdout: true
+  - type: flusher_sls
+    detail:
+      Logstore: app
+`), 0600))
+
+	template, err := loadPipelineTemplate("shared")
+	require.NoError(t, err)
+	rendered, err := json.Marshal(template)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"global": {"InputIntervalMs": 5000},
+		"flushers": [
+			{"type": "flusher_stdout", "detail": {"OnlyStdout": true}},
+			{"type": "flusher_sls", "detail": {"Logstore": "app"}}
+		]
+	}`, string(rendered))
+
+	_, err = loadPipelineTemplate("../shared")
+	assert.Error(t, err)
+	_, err = loadPipelineTemplate("missing")
+	assert.Error(t, err)
+}
+
+func TestHandleRenderedConfig(t *testing.T) {
+	LogtailConfigLock.Lock()
+	LogtailConfig = map[string]*LogstoreConfig{
+		"app/1": {ConfigName: "app", renderedConfig: []byte(`{"inputs":[]}`)},
+		"other": {ConfigName: "other", renderedConfig: []byte(`{}`)},
+	}
+	LogtailConfigLock.Unlock()
+	defer func() {
+		LogtailConfigLock.Lock()
+		LogtailConfig = make(map[string]*LogstoreConfig)
+		LogtailConfigLock.Unlock()
+	}()
+
+	res := httptest.NewRecorder()
+	HandleRenderedConfig(res, httptest.NewRequest(http.MethodGet, "/config/rendered?name=app", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"app/1": {"inputs": []}}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	HandleRenderedConfig(res, httptest.NewRequest(http.MethodGet, "/config/rendered", nil))
+	assert.JSONEq(t, `{"app/1": {"inputs": []}, "other": {}}`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	HandleRenderedConfig(res, httptest.NewRequest(http.MethodGet, "/config/rendered?name=missing", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// varPattern matches ${vars.name} and ${vars.name:-default}.
+var varPattern = regexp.MustCompile(`\$\{vars\.([A-Za-z0-9_.\-]+)(:-([^}]*))?\}`)
+
+// varEnvPrefix is the prefix of the environment variables that can be referenced as vars, so that the other
+// environment variables of the agent, e.g. the credentials, are never rendered into the configs.
+const varEnvPrefix = "LOGTAIL_VAR_"
+
+// configVars resolves the variables referenced in the plugin configurations.
+// A variable is looked up in the pipeline vars, the agent vars, the environment variable named by varEnvPrefix
+// and the name, and the one with the upper case name in order, and the default value is used if not found.
+type configVars struct {
+	pipeline map[string]string
+	agent    map[string]string
+}
+
+func (v *configVars) lookup(name string) (string, bool) {
+	if value, ok := v.pipeline[name]; ok {
+		return value, true
+	}
+	if value, ok := v.agent[name]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(varEnvPrefix + name); ok {
+		return value, true
+	}
+	return os.LookupEnv(varEnvPrefix + strings.ToUpper(name))
+}
+
+func (v *configVars) expand(s, path string) (string, error) {
+	if !strings.Contains(s, "${vars.") {
+		return s, nil
+	}
+	var err error
+	res := varPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		match := varPattern.FindStringSubmatch(ref)
+		if value, ok := v.lookup(match[1]); ok {
+			return value
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("variable %s referenced by %s is not defined", match[1], path)
+		}
+		return ref
+	})
+	return res, err
+}
+
+// resolve replaces the variable references in all the string values of the config recursively.
+func (v *configVars) resolve(value interface{}, path string) (interface{}, error) {
+	var err error
+	switch val := value.(type) {
+	case string:
+		return v.expand(val, path)
+	case map[string]interface{}:
+		for k, item := range val {
+			if val[k], err = v.resolve(item, path+"."+k); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if val[i], err = v.resolve(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// resolveConfigVars resolves the variables in the pipeline config at load time. The vars defined in "global.Vars"
+// are used as they are, and they override the agent vars with the same name.
+func resolveConfigVars(plugins map[string]interface{}, agentVars map[string]string) error {
+	vars := &configVars{agent: agentVars}
+	global, _ := plugins["global"].(map[string]interface{})
+	if pipelineVars, ok := global["Vars"].(map[string]interface{}); ok {
+		vars.pipeline = make(map[string]string, len(pipelineVars))
+		for name, value := range pipelineVars {
+			str, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("value of global.Vars.%s is not a string", name)
+			}
+			vars.pipeline[name] = str
+		}
+	}
+	var err error
+	for key, value := range plugins {
+		if key == "global" {
+			for k, item := range global {
+				if k == "Vars" {
+					continue
+				}
+				if global[k], err = vars.resolve(item, "global."+k); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if plugins[key], err = vars.resolve(value, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveConfigVars(t *testing.T) {
+	t.Setenv("LOGTAIL_VAR_zone", "cn-hangzhou-h")
+	t.Setenv("LOGTAIL_VAR_PROJECT", "env-project")
+	str := `{
+		"global": {
+			"Vars": {"region": "cn-shanghai"},
+			"PipelineMetaTagKey": {"HOST_NAME": "${vars.region}_host"}
+		},
+		"inputs": [{"type": "metric_mock", "detail": {"Tags": {"zone": "${vars.zone}", "n": 1}}}],
+		"flushers": [{"type": "flusher_sls", "detail": {
+			"Endpoint": "${vars.region}.log.aliyuncs.com",
+			"Project": "${vars.project}",
+			"Logstore": "${vars.logstore:-default-logstore}",
+			"Topic": "${vars.cluster}/${vars.region}"
+		}}]
+	}`
+	plugins := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(str), &plugins))
+	require.NoError(t, resolveConfigVars(plugins, map[string]string{"region": "cn-beijing", "cluster": "c1"}))
+
+	global := plugins["global"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"region": "cn-shanghai"}, global["Vars"])
+	assert.Equal(t, map[string]interface{}{"HOST_NAME": "cn-shanghai_host"}, global["PipelineMetaTagKey"])
+	input := plugins["inputs"].([]interface{})[0].(map[string]interface{})["detail"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"zone": "cn-hangzhou-h", "n": float64(1)}, input["Tags"])
+	flusher := plugins["flushers"].([]interface{})[0].(map[string]interface{})["detail"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"Endpoint": "cn-shanghai.log.aliyuncs.com",
+		"Project":  "env-project",
+		"Logstore": "default-logstore",
+		"Topic":    "c1/cn-shanghai",
+	}, flusher)
+}
+
+func TestResolveConfigVarsError(t *testing.T) {
+	plugins := map[string]interface{}{
+		"flushers": []interface{}{map[string]interface{}{"detail": map[string]interface{}{"Endpoint": "${vars.not_defined_var}"}}},
+	}
+	assert.EqualError(t, resolveConfigVars(plugins, nil), "variable not_defined_var referenced by flushers[0].detail.Endpoint is not defined")
+
+	// the environment variables without the prefix are never referenced
+	t.Setenv("ILOGTAIL_TEST_SECRET", "secret")
+	plugins = map[string]interface{}{
+		"flushers": []interface{}{map[string]interface{}{"detail": map[string]interface{}{"AccessKey": "${vars.ILOGTAIL_TEST_SECRET}"}}},
+	}
+	assert.EqualError(t, resolveConfigVars(plugins, nil), "variable ILOGTAIL_TEST_SECRET referenced by flushers[0].detail.AccessKey is not defined")
+
+	plugins = map[string]interface{}{"global": map[string]interface{}{"Vars": map[string]interface{}{"port": 80}}}
+	assert.EqualError(t, resolveConfigVars(plugins, nil), "value of global.Vars.port is not a string")
+}
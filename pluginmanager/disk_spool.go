@@ -0,0 +1,267 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const (
+	diskSpoolSegmentSuffix = ".seg"
+	diskSpoolPlain         = byte(0)
+	diskSpoolAESGCM        = byte(1)
+)
+
+// diskSpoolMagic starts each segment, followed by the byte of the encryption and, for AES-GCM, the nonce. The rest is
+// the LogGroupList, sealed with the header as the additional data if encrypted.
+var diskSpoolMagic = []byte("ILSP")
+
+var diskSpoolSeq atomic.Uint64
+
+// diskSpool keeps the log groups of a pipeline in the segment files of its own directory.
+type diskSpool struct {
+	dir      string
+	maxBytes int64
+	aead     cipher.AEAD // nil if the segments are not encrypted
+}
+
+// newDiskSpool returns nil if the disk spool is disabled. The key is resolved once, so a rotated key takes effect when
+// the pipeline is reloaded.
+func newDiskSpool(lc *LogstoreConfig) (*diskSpool, error) {
+	cfg := &lc.GlobalConfig.DiskSpool
+	if !cfg.Enable || lc.Version == v2 {
+		return nil, nil
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(config.LoongcollectorGlobalConfig.LoongCollectorDataDir, "disk_spool")
+	}
+	var key []byte
+	if cfg.Encrypt {
+		ext, err := lc.Context.GetExtension(cfg.SecretProvider, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get secret provider %s of disk spool error: %v", cfg.SecretProvider, err)
+		}
+		provider, ok := ext.(extensions.SecretProvider)
+		if !ok {
+			return nil, fmt.Errorf("secret provider(%s) not implement interface extensions.SecretProvider", cfg.SecretProvider)
+		}
+		secret, err := provider.GetSecret(cfg.KeySecret)
+		if err != nil {
+			return nil, fmt.Errorf("get key of disk spool error: %v", err)
+		}
+		if key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(secret)); err != nil {
+			return nil, fmt.Errorf("key of disk spool is not base64 encoded: %v", err)
+		}
+	}
+	return openDiskSpool(filepath.Join(dir, url.PathEscape(lc.ConfigName)), cfg.MaxBytes, key)
+}
+
+// checkDiskSpool rejects the v2 pipeline enabling the disk spool in its own global config, since only the v1 pipelines
+// spool. The disk spool enabled by the agent global config is ignored by the v2 pipelines.
+func checkDiskSpool(lc *LogstoreConfig, global interface{}) error {
+	if lc.Version != v2 || !lc.GlobalConfig.DiskSpool.Enable {
+		return nil
+	}
+	if globalMap, ok := global.(map[string]interface{}); ok {
+		for key := range globalMap {
+			if strings.EqualFold(key, "DiskSpool") {
+				return fmt.Errorf("global.DiskSpool is not supported by the v2 pipelines, disable it or use the v1 StructureType")
+			}
+		}
+	}
+	return nil
+}
+
+// openDiskSpool encrypts the segments with AES-GCM if the key is not empty.
+func openDiskSpool(dir string, maxBytes int64, key []byte) (*diskSpool, error) {
+	s := &diskSpool{dir: dir, maxBytes: maxBytes}
+	if len(key) > 0 {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key of disk spool: %v", err)
+		}
+		if s.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// write persists the log groups as a new segment, it is made visible by renaming so a crash leaves no partial segment.
+func (s *diskSpool) write(logGroups []*protocol.LogGroup) error {
+	payload, err := (&protocol.LogGroupList{LogGroupList: logGroups}).Marshal()
+	if err != nil {
+		return err
+	}
+	header := append([]byte{}, diskSpoolMagic...)
+	var data []byte
+	if s.aead == nil {
+		header = append(header, diskSpoolPlain)
+		data = append(header, payload...)
+	} else {
+		header = append(header, diskSpoolAESGCM)
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+			return err
+		}
+		header = append(header, nonce...)
+		data = s.aead.Seal(append([]byte{}, header...), nonce, payload, header)
+	}
+	segments, size, err := s.segments()
+	if err != nil {
+		return err
+	}
+	if s.maxBytes > 0 && size+int64(len(data)) > s.maxBytes {
+		return fmt.Errorf("disk spool is full, %d segments take %d bytes", len(segments), size)
+	}
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), diskSpoolSeq.Add(1)%1000000, diskSpoolSegmentSuffix)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// read returns the log groups of the segment.
+func (s *diskSpool) read(path string) ([]*protocol.LogGroup, error) {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= len(diskSpoolMagic) || !bytes.Equal(data[:len(diskSpoolMagic)], diskSpoolMagic) {
+		return nil, fmt.Errorf("invalid segment")
+	}
+	headerLen := len(diskSpoolMagic) + 1
+	payload := data[headerLen:]
+	switch data[headerLen-1] {
+	case diskSpoolPlain:
+	case diskSpoolAESGCM:
+		if s.aead == nil {
+			return nil, fmt.Errorf("segment is encrypted but no key is configured")
+		}
+		nonceSize := s.aead.NonceSize()
+		if len(payload) < nonceSize {
+			return nil, fmt.Errorf("invalid segment")
+		}
+		header := data[:headerLen+nonceSize]
+		if payload, err = s.aead.Open(nil, payload[:nonceSize], payload[nonceSize:], header); err != nil {
+			return nil, fmt.Errorf("decrypt segment error, the key may be rotated: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown encryption %d of segment", data[headerLen-1])
+	}
+	var list protocol.LogGroupList
+	if err = list.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+	return list.LogGroupList, nil
+}
+
+// segments returns the paths of the segments in the order they are written, and their total size.
+func (s *diskSpool) segments() ([]string, int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	var paths []string
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), diskSpoolSegmentSuffix) || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		paths = append(paths, filepath.Join(s.dir, entry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, size, nil
+}
+
+// replay sends the log groups of the segments to the flusher routine oldest first and removes each segment once all
+// its log groups are sent. The segment which cannot be read is kept, so a rotated key loses no data. When the pipeline
+// stops meanwhile, the unsent log groups of the current segment are written back as a new segment.
+func (s *diskSpool) replay(lc *LogstoreConfig, ch chan *protocol.LogGroup, cc *pipeline.AsyncControl) {
+	paths, _, err := s.segments()
+	if err != nil {
+		logger.Error(lc.Context.GetRuntimeContext(), "DISK_SPOOL_ALARM", "list segments error", err)
+		return
+	}
+	for _, path := range paths {
+		logGroups, err := s.read(path)
+		if err != nil {
+			logger.Error(lc.Context.GetRuntimeContext(), "DISK_SPOOL_ALARM", "read segment error, keep it", path, "error", err)
+			continue
+		}
+		lc.Statistics.Outcomes.receive(countFlushEvents(logGroups))
+		for i, logGroup := range logGroups {
+			select {
+			case ch <- logGroup:
+			case <-cc.CancelToken():
+				rest := logGroups[i:]
+				if err = s.write(rest); err != nil {
+					logger.Error(lc.Context.GetRuntimeContext(), "DISK_SPOOL_ALARM", "write back segment error, drop data", len(rest), "error", err)
+					lc.Statistics.Outcomes.drop(countFlushEvents(rest))
+				} else {
+					lc.Statistics.Outcomes.spool(countFlushEvents(rest))
+				}
+				_ = os.Remove(path)
+				return
+			}
+		}
+		_ = os.Remove(path)
+		logger.Info(lc.Context.GetRuntimeContext(), "replay segment", path, "loggroup count", len(logGroups))
+	}
+}
+
+// spillFlushOutStore writes the log groups of the store to the disk spool instead of dropping them.
+func spillFlushOutStore[T FlushData](lc *LogstoreConfig, store *FlushOutStore[T]) bool {
+	logGroups, ok := any(store.Get()).([]*protocol.LogGroup)
+	if !ok || lc.spool == nil {
+		return false
+	}
+	if err := lc.spool.write(logGroups); err != nil {
+		logger.Error(lc.Context.GetRuntimeContext(), "DISK_SPOOL_ALARM", "spill data to disk error", err)
+		return false
+	}
+	logger.Info(lc.Context.GetRuntimeContext(), "spill data to disk, loggroup count", len(logGroups))
+	lc.Statistics.Outcomes.spool(countFlushEvents(logGroups))
+	store.Reset()
+	return true
+}
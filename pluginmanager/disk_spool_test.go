@@ -0,0 +1,205 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	_ "github.com/alibaba/ilogtail/plugins/extension/secret_provider"
+)
+
+func newSpoolTestLogGroup(value string) *protocol.LogGroup {
+	return &protocol.LogGroup{
+		Logs:   []*protocol.Log{{Time: 1, Contents: []*protocol.Log_Content{{Key: "content", Value: value}}}},
+		Source: "127.0.0.1",
+	}
+}
+
+func TestDiskSpoolEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+	s, err := openDiskSpool(dir, 0, key)
+	require.NoError(t, err)
+	require.NoError(t, s.write([]*protocol.LogGroup{newSpoolTestLogGroup("card 4111-1111"), newSpoolTestLogGroup("b")}))
+	require.NoError(t, s.write([]*protocol.LogGroup{newSpoolTestLogGroup("c")}))
+
+	paths, _, err := s.segments()
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "4111-1111", "the segment is not plaintext")
+	info, err := os.Stat(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	logGroups, err := s.read(paths[0])
+	require.NoError(t, err)
+	require.Len(t, logGroups, 2)
+	assert.Equal(t, "card 4111-1111", logGroups[0].Logs[0].Contents[0].Value)
+	assert.Equal(t, "127.0.0.1", logGroups[0].Source)
+
+	// the segments cannot be read with another key, or without a key
+	other, err := openDiskSpool(dir, 0, bytes.Repeat([]byte{8}, 32))
+	require.NoError(t, err)
+	_, err = other.read(paths[0])
+	assert.Error(t, err)
+	plain, err := openDiskSpool(dir, 0, nil)
+	require.NoError(t, err)
+	_, err = plain.read(paths[0])
+	assert.Error(t, err)
+
+	// tampering is detected
+	data[len(data)-1] ^= 1
+	require.NoError(t, os.WriteFile(paths[0], data, 0600))
+	_, err = s.read(paths[0])
+	assert.Error(t, err)
+
+	_, err = openDiskSpool(dir, 0, []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestDiskSpoolPlainAndFull(t *testing.T) {
+	s, err := openDiskSpool(t.TempDir(), 100, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.write([]*protocol.LogGroup{newSpoolTestLogGroup("a")}))
+	paths, _, err := s.segments()
+	require.NoError(t, err)
+	logGroups, err := s.read(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "a", logGroups[0].Logs[0].Contents[0].Value)
+	// the segment exceeding the max bytes is not written
+	assert.Error(t, s.write([]*protocol.LogGroup{newSpoolTestLogGroup(string(bytes.Repeat([]byte("x"), 100)))}))
+	paths, _, err = s.segments()
+	require.NoError(t, err)
+	assert.Len(t, paths, 1)
+}
+
+func TestDiskSpoolSpillAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SPOOL_TEST_spool_key", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16)))
+	jsonStr := fmt.Sprintf(`{
+		"global": {
+			"DiskSpool": {
+				"Enable": true,
+				"Dir": %q,
+				"Encrypt": true,
+				"SecretProvider": "ext_secret_provider/spool",
+				"KeySecret": "spool_key"
+			}
+		},
+		"extensions": [
+			{
+				"type": "ext_secret_provider/spool",
+				"detail": {"EnvOnly": true, "EnvPrefix": "SPOOL_TEST_"}
+			}
+		],
+		"flushers": [
+			{
+				"type": "flusher_checker"
+			}
+		]
+	}`, dir)
+	lc, err := createLogstoreConfig("project", "logstore", "spool/config", 0, jsonStr)
+	require.NoError(t, err)
+	require.NotNil(t, lc.spool)
+	assert.Equal(t, filepath.Join(dir, "spool%2Fconfig"), lc.spool.dir)
+
+	store := NewFlushOutStore[protocol.LogGroup]()
+	store.Add(newSpoolTestLogGroup("a"), newSpoolTestLogGroup("b"))
+	require.True(t, spillFlushOutStore(lc, store))
+	assert.Equal(t, 0, store.Len())
+	assert.EqualValues(t, 2, lc.Statistics.Outcomes.spooledEvents.Collect().Value)
+
+	ch := make(chan *protocol.LogGroup)
+	cc := pipeline.NewAsyncControl()
+	cc.Run(func(cc *pipeline.AsyncControl) {
+		lc.spool.replay(lc, ch, cc)
+	})
+	// the pipeline stops after one log group is sent, the other is written back
+	assert.Equal(t, "a", (<-ch).Logs[0].Contents[0].Value)
+	cc.WaitCancel()
+	paths, _, err := lc.spool.segments()
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+
+	ch = make(chan *protocol.LogGroup, 1)
+	lc.spool.replay(lc, ch, pipeline.NewAsyncControl())
+	assert.Equal(t, "b", (<-ch).Logs[0].Contents[0].Value)
+	paths, _, err = lc.spool.segments()
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	// the v2 pipelines do not spool
+	v2Store := NewFlushOutStore[models.PipelineGroupEvents]()
+	v2Store.Add(&models.PipelineGroupEvents{})
+	assert.False(t, spillFlushOutStore(lc, v2Store))
+
+	_, err = createLogstoreConfig("project", "logstore", "spool_missing_key", 0, `{
+		"global": {
+			"DiskSpool": {"Enable": true, "Dir": "`+dir+`", "Encrypt": true, "SecretProvider": "ext_secret_provider/missing", "KeySecret": "spool_key"}
+		}
+	}`)
+	assert.Error(t, err)
+}
+
+func TestDiskSpoolV2(t *testing.T) {
+	dir := t.TempDir()
+	_, err := createLogstoreConfig("project", "logstore", "spool_v2", 0, `{
+		"global": {
+			"StructureType": "v2",
+			"DiskSpool": {"Enable": true, "Dir": "`+dir+`"}
+		},
+		"flushers": [
+			{
+				"type": "flusher_stdout"
+			}
+		]
+	}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported by the v2 pipelines")
+
+	// the disk spool enabled by the agent is ignored by the v2 pipelines
+	config.LoongcollectorGlobalConfig.DiskSpool.Enable = true
+	config.LoongcollectorGlobalConfig.DiskSpool.Dir = dir
+	defer func() {
+		config.LoongcollectorGlobalConfig.DiskSpool.Enable = false
+		config.LoongcollectorGlobalConfig.DiskSpool.Dir = ""
+	}()
+	lc, err := createLogstoreConfig("project", "logstore", "spool_v2", 0, `{
+		"global": {
+			"StructureType": "v2"
+		},
+		"flushers": [
+			{
+				"type": "flusher_stdout"
+			}
+		]
+	}`)
+	require.NoError(t, err)
+	assert.Nil(t, lc.spool)
+}
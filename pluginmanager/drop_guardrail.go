@@ -0,0 +1,122 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const filterProcessorPrefix = "processor_filter"
+
+// dropGuardrail watches the ratio of the events dropped by the processors to the events received by a pipeline.
+// When the ratio of a window exceeds the threshold, an error alarm is raised and the filter processor dropping
+// the most events is bypassed if configured, so that a wrong filter does not silently discard all the data.
+// It is only used by the processor routine, so no lock is needed.
+type dropGuardrail struct {
+	config     *config.DropGuardrailConfig
+	context    pipeline.Context
+	processors []*ProcessorWrapper
+
+	windowStart time.Time
+	received    int64
+	dropped     []int64 // the events dropped by each processor in the window
+	now         func() time.Time
+}
+
+// newDropGuardrail returns nil if the guardrail is disabled, and all methods of a nil guardrail are no-op.
+func newDropGuardrail(context pipeline.Context, cfg *config.DropGuardrailConfig, processors []*ProcessorWrapper) *dropGuardrail {
+	if !cfg.Enable || len(processors) == 0 {
+		return nil
+	}
+	if cfg.WindowSec <= 0 || cfg.MaxDropRatio <= 0 {
+		logger.Warning(context.GetRuntimeContext(), "DROP_GUARDRAIL_ALARM", "window", cfg.WindowSec,
+			"max drop ratio", cfg.MaxDropRatio, "action", "guardrail is disabled by the invalid config")
+		return nil
+	}
+	return &dropGuardrail{
+		config:      cfg,
+		context:     context,
+		processors:  processors,
+		windowStart: time.Now(),
+		dropped:     make([]int64, len(processors)),
+		now:         time.Now,
+	}
+}
+
+// receive counts the events entering the processor chain.
+func (g *dropGuardrail) receive(count int) {
+	if g == nil {
+		return
+	}
+	g.received += int64(count)
+}
+
+// settle counts the events dropped by the processor at @index, @in and @out are the event counts before and after processing.
+func (g *dropGuardrail) settle(index, in, out int) {
+	if g == nil || out >= in {
+		return
+	}
+	g.dropped[index] += int64(in - out)
+}
+
+// check closes the window if it is due, and alarms if too many events are dropped in it.
+func (g *dropGuardrail) check() {
+	if g == nil {
+		return
+	}
+	now := g.now()
+	if now.Sub(g.windowStart) < time.Duration(g.config.WindowSec)*time.Second {
+		return
+	}
+	received := g.received
+	var dropped int64
+	top := -1
+	for i, count := range g.dropped {
+		dropped += count
+		if count > 0 && (top < 0 || count > g.dropped[top]) {
+			top = i
+		}
+	}
+	defer g.reset(now)
+	if received == 0 || received < int64(g.config.MinEvents) {
+		return
+	}
+	ratio := float64(dropped) / float64(received)
+	if ratio <= g.config.MaxDropRatio {
+		return
+	}
+	offending := g.processors[top]
+	logger.Error(g.context.GetRuntimeContext(), "DROP_GUARDRAIL_ALARM", "reason", "too many events are dropped by processors",
+		"received", received, "dropped", dropped, "ratio", ratio, "max ratio", g.config.MaxDropRatio, "window", g.config.WindowSec,
+		"top processor", offending.pluginMeta.PluginTypeWithID, "dropped by it", g.dropped[top])
+	if g.config.DisableFilter && strings.HasPrefix(offending.pluginMeta.PluginType, filterProcessorPrefix) && !offending.bypassed.Load() {
+		offending.bypassed.Store(true)
+		logger.Error(g.context.GetRuntimeContext(), "DROP_GUARDRAIL_ALARM", "processor", offending.pluginMeta.PluginTypeWithID,
+			"action", "bypassed until the pipeline is reloaded")
+	}
+}
+
+func (g *dropGuardrail) reset(now time.Time) {
+	g.windowStart = now
+	g.received = 0
+	for i := range g.dropped {
+		g.dropped[i] = 0
+	}
+}
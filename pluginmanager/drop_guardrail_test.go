@@ -0,0 +1,135 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// mockDropAllProcessor drops all the logs.
+type mockDropAllProcessor struct{}
+
+func (p *mockDropAllProcessor) Init(pipeline.Context) error {
+	return nil
+}
+
+func (p *mockDropAllProcessor) Description() string {
+	return "mock processor dropping all logs"
+}
+
+func (p *mockDropAllProcessor) ProcessLogs([]*protocol.Log) []*protocol.Log {
+	return nil
+}
+
+func newGuardrailTestRunner(t *testing.T, guardrail config.DropGuardrailConfig, processorTypes ...string) *pluginv1Runner {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	globalConfig.DropGuardrail = guardrail
+	lc := &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}
+	lc.Statistics.Init(ctx)
+	p := &pluginv1Runner{LogstoreConfig: lc}
+	for i, processorType := range processorTypes {
+		meta := &pipeline.PluginMeta{PluginType: processorType, PluginTypeWithID: processorType + "/1", PluginID: "1"}
+		require.NoError(t, p.addProcessor(meta, &mockDropAllProcessor{}, i, nil))
+	}
+	return p
+}
+
+func guardedProcessors(p *pluginv1Runner) []*ProcessorWrapper {
+	processors := make([]*ProcessorWrapper, 0, len(p.ProcessorPlugins))
+	for _, processor := range p.ProcessorPlugins {
+		processors = append(processors, &processor.ProcessorWrapper)
+	}
+	return processors
+}
+
+func TestDropGuardrail_Disabled(t *testing.T) {
+	p := newGuardrailTestRunner(t, config.LoongcollectorGlobalConfig.DropGuardrail, "processor_filter_regex")
+	assert.Nil(t, newDropGuardrail(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.DropGuardrail, guardedProcessors(p)))
+	// all methods of the disabled guardrail are no-op
+	var guardrail *dropGuardrail
+	guardrail.receive(1)
+	guardrail.settle(0, 1, 0)
+	guardrail.check()
+}
+
+func TestDropGuardrail_DisableFilter(t *testing.T) {
+	p := newGuardrailTestRunner(t, config.DropGuardrailConfig{
+		Enable:        true,
+		WindowSec:     60,
+		MaxDropRatio:  0.5,
+		MinEvents:     10,
+		DisableFilter: true,
+	}, "processor_rate_limit", "processor_filter_regex")
+	guardrail := newDropGuardrail(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.DropGuardrail, guardedProcessors(p))
+	require.NotNil(t, guardrail)
+	now := time.Now()
+	guardrail.now = func() time.Time { return now }
+
+	process := func(n int) {
+		guardrail.receive(n)
+		guardrail.settle(0, n, n)
+		guardrail.settle(1, n, 0)
+		guardrail.check()
+	}
+	// the window is not due
+	process(100)
+	assert.False(t, p.ProcessorPlugins[1].bypassed.Load())
+
+	// too few events in the window
+	now = now.Add(time.Minute)
+	guardrail.reset(now)
+	process(5)
+	now = now.Add(time.Minute)
+	process(1)
+	assert.False(t, p.ProcessorPlugins[1].bypassed.Load())
+
+	process(100)
+	now = now.Add(time.Minute)
+	process(100)
+	assert.False(t, p.ProcessorPlugins[0].bypassed.Load())
+	assert.True(t, p.ProcessorPlugins[1].bypassed.Load())
+
+	// the bypassed processor passes the logs through
+	logs := []*protocol.Log{{}}
+	assert.Equal(t, logs, p.ProcessorPlugins[1].Process(logs))
+	assert.Empty(t, p.ProcessorPlugins[0].Process(logs))
+}
+
+func TestDropGuardrail_OnlyFilterIsDisabled(t *testing.T) {
+	p := newGuardrailTestRunner(t, config.DropGuardrailConfig{
+		Enable:        true,
+		WindowSec:     1,
+		MaxDropRatio:  0.5,
+		DisableFilter: true,
+	}, "processor_rate_limit")
+	guardrail := newDropGuardrail(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.DropGuardrail, guardedProcessors(p))
+	now := time.Now().Add(time.Minute)
+	guardrail.now = func() time.Time { return now }
+	guardrail.receive(10)
+	guardrail.settle(0, 10, 0)
+	guardrail.check()
+	assert.False(t, p.ProcessorPlugins[0].bypassed.Load())
+	assert.Zero(t, guardrail.received)
+}
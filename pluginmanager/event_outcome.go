@@ -0,0 +1,171 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync/atomic"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const (
+	metricOutcomeInEvents      = "outcome_in_events_total"
+	metricOutcomeSuccessEvents = "outcome_success_events_total"
+	metricOutcomeWaitedEvents  = "outcome_waited_events_total"
+	metricOutcomeDroppedEvents = "outcome_dropped_events_total"
+	metricOutcomeFailedEvents  = "outcome_failed_events_total"
+	metricOutcomeSpooledEvents = "outcome_spooled_events_total"
+)
+
+// eventOutcomes attributes every event of a pipeline to exactly one terminal outcome, so that the counters reconcile:
+//
+//	in = success + waited + dropped + failed + spooled + the events still in the pipeline
+//
+// The events generated by the processors or the aggregators, e.g. split from one event, and the ones replayed from
+// the disk spool are counted as in. An event is
+//   - success if all the flushers accept it once it reaches them.
+//   - waited if all the flushers accept it after waiting for them to be ready. The flush itself is never retried.
+//   - dropped if it is discarded by the processors or the aggregators, merged into another event by the aggregators,
+//     has no content, is stale or cannot be flushed out before timeout at exit.
+//   - failed if any flusher returns an error flushing it, it is discarded since there is no dead letter queue.
+//   - spooled if it is written to the disk spool since it cannot be flushed out before timeout at exit.
+type eventOutcomes struct {
+	inEvents      pipeline.CounterMetric
+	successEvents pipeline.CounterMetric
+	waitedEvents  pipeline.CounterMetric
+	droppedEvents pipeline.CounterMetric
+	failedEvents  pipeline.CounterMetric
+	spooledEvents pipeline.CounterMetric
+
+	aggregatingEvents atomic.Int64 // the events added to the aggregators since the last settlement
+	aggregatedEvents  atomic.Int64 // the events emitted by the aggregators since the last settlement
+}
+
+func (o *eventOutcomes) init(metricsRecord *pipeline.MetricsRecord) {
+	o.inEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeInEvents)
+	o.successEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeSuccessEvents)
+	o.waitedEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeWaitedEvents)
+	o.droppedEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeDroppedEvents)
+	o.failedEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeFailedEvents)
+	o.spooledEvents = helper.NewCumulativeCounterMetricAndRegister(metricsRecord, metricOutcomeSpooledEvents)
+}
+
+// receive counts the events entering the pipeline.
+func (o *eventOutcomes) receive(count int) {
+	o.inEvents.Add(int64(count))
+}
+
+// processed settles the events going through the processors, the missing ones are dropped and the extra ones are
+// generated.
+func (o *eventOutcomes) processed(inCount, outCount int) {
+	if outCount < inCount {
+		o.droppedEvents.Add(int64(inCount - outCount))
+	} else if outCount > inCount {
+		o.inEvents.Add(int64(outCount - inCount))
+	}
+}
+
+// aggregate counts the events added to the aggregators.
+func (o *eventOutcomes) aggregate(count int) {
+	o.aggregatingEvents.Add(int64(count))
+}
+
+// emit counts the events emitted by the aggregators.
+func (o *eventOutcomes) emit(count int) {
+	o.aggregatedEvents.Add(int64(count))
+}
+
+// settleAggregated settles the events going through the aggregators once they flush, like processed does for the
+// processors. The events added but not emitted are merged or dropped, and the extra ones are generated.
+func (o *eventOutcomes) settleAggregated() {
+	o.processed(int(o.aggregatingEvents.Swap(0)), int(o.aggregatedEvents.Swap(0)))
+}
+
+func (o *eventOutcomes) drop(count int) {
+	o.droppedEvents.Add(int64(count))
+}
+
+func (o *eventOutcomes) spool(count int) {
+	o.spooledEvents.Add(int64(count))
+}
+
+// flushed settles the events sent to all the flushers.
+func (o *eventOutcomes) flushed(count int, waited bool, failed bool) {
+	switch {
+	case failed:
+		o.failedEvents.Add(int64(count))
+	case waited:
+		o.waitedEvents.Add(int64(count))
+	default:
+		o.successEvents.Add(int64(count))
+	}
+}
+
+// countEmptyLogs counts the logs without content, which are skipped before the aggregators.
+func countEmptyLogs(logs []*protocol.Log) int {
+	count := 0
+	for _, log := range logs {
+		if len(log.Contents) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// countFlushEvents counts the events of the log groups or the group events.
+func countFlushEvents[T FlushData](data []*T) int {
+	count := 0
+	for _, item := range data {
+		switch d := any(item).(type) {
+		case *protocol.LogGroup:
+			count += len(d.Logs)
+		case *models.PipelineGroupEvents:
+			count += len(d.Events)
+		}
+	}
+	return count
+}
+
+// outcomeCollector counts the events emitted by the v2 aggregators into the flush queue.
+type outcomeCollector struct {
+	pipeline.PipelineCollector
+	outcomes *eventOutcomes
+}
+
+func (c *outcomeCollector) Collect(group *models.GroupInfo, events ...models.PipelineEvent) {
+	c.outcomes.emit(len(events))
+	c.PipelineCollector.Collect(group, events...)
+}
+
+func (c *outcomeCollector) CollectList(groups ...*models.PipelineGroupEvents) {
+	c.outcomes.emit(countFlushEvents(groups))
+	c.PipelineCollector.CollectList(groups...)
+}
+
+type outcomePipelineContext struct {
+	collector *outcomeCollector
+}
+
+func (c *outcomePipelineContext) Collector() pipeline.PipelineCollector {
+	return c.collector
+}
+
+// wrapAggregateContext returns the pipeline context of the v2 aggregators which counts the emitted events.
+func (o *eventOutcomes) wrapAggregateContext(context pipeline.PipelineContext) pipeline.PipelineContext {
+	return &outcomePipelineContext{collector: &outcomeCollector{PipelineCollector: context.Collector(), outcomes: o}}
+}
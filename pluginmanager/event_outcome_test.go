@@ -0,0 +1,247 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+type mockOutcomeFlusher struct {
+	err      error
+	notReady int // the number of the IsReady calls answered false
+}
+
+func (f *mockOutcomeFlusher) Init(*pipeline.PluginMeta) error {
+	return nil
+}
+
+func (f *mockOutcomeFlusher) IsReady(string, string, int64) bool {
+	if f.notReady > 0 {
+		f.notReady--
+		return false
+	}
+	return true
+}
+
+func newOutcomeTestConfig() *LogstoreConfig {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	lc := &LogstoreConfig{Context: ctx}
+	lc.Statistics.Init(ctx)
+	return lc
+}
+
+func outcomeValues(o *eventOutcomes) []float64 {
+	return []float64{
+		o.inEvents.Collect().Value,
+		o.successEvents.Collect().Value,
+		o.waitedEvents.Collect().Value,
+		o.droppedEvents.Collect().Value,
+		o.failedEvents.Collect().Value,
+	}
+}
+
+func TestEventOutcomes(t *testing.T) {
+	lc := newOutcomeTestConfig()
+	outcomes := &lc.Statistics.Outcomes
+
+	outcomes.receive(10)
+	// 3 of 10 events are dropped by the processors
+	outcomes.processed(10, 7)
+	outcomes.receive(1)
+	// 1 event is split to 3 events
+	outcomes.processed(1, 3)
+	outcomes.drop(1)
+	outcomes.flushed(5, false, false)
+	outcomes.flushed(2, true, false)
+	outcomes.flushed(2, true, true)
+	values := outcomeValues(outcomes)
+	assert.Equal(t, []float64{13, 5, 2, 4, 2}, values)
+	// the counters are cumulative and reconcile
+	assert.Equal(t, values[0], values[1]+values[2]+values[3]+values[4])
+	assert.Equal(t, []float64{13, 5, 2, 4, 2}, outcomeValues(outcomes))
+}
+
+func TestCountEvents(t *testing.T) {
+	logs := []*protocol.Log{
+		{Contents: []*protocol.Log_Content{{Key: "k", Value: "v"}}},
+		{},
+		{},
+	}
+	assert.Equal(t, 2, countEmptyLogs(logs))
+	assert.Equal(t, 4, countFlushEvents([]*protocol.LogGroup{{Logs: logs}, {}, {Logs: logs[:1]}}))
+	groups := []*models.PipelineGroupEvents{
+		{Events: []models.PipelineEvent{models.NewLog("", nil, "", "", "", models.NewTags(), 0)}},
+		{},
+	}
+	assert.Equal(t, 1, countFlushEvents(groups))
+}
+
+func TestFlushOutStoreOutcomes(t *testing.T) {
+	lc := newOutcomeTestConfig()
+	store := NewFlushOutStore[protocol.LogGroup]()
+	store.Add(&protocol.LogGroup{Logs: []*protocol.Log{{}, {}}}, &protocol.LogGroup{Logs: []*protocol.Log{{}}})
+	flush := func(_ *LogstoreConfig, f *mockOutcomeFlusher, _ *FlushOutStore[protocol.LogGroup]) error {
+		return f.err
+	}
+
+	assert.True(t, flushOutStore(lc, store, []*mockOutcomeFlusher{{}}, flush))
+	assert.Equal(t, []float64{0, 3, 0, 0, 0}, outcomeValues(&lc.Statistics.Outcomes))
+
+	// the events wait for any flusher to be ready
+	store.Add(&protocol.LogGroup{Logs: []*protocol.Log{{}, {}}})
+	assert.True(t, flushOutStore(lc, store, []*mockOutcomeFlusher{{}, {notReady: 2}}, flush))
+	assert.Equal(t, []float64{0, 3, 2, 0, 0}, outcomeValues(&lc.Statistics.Outcomes))
+
+	// the events fail once even if all the flushers fail
+	store.Add(&protocol.LogGroup{Logs: []*protocol.Log{{}}})
+	failed := &mockOutcomeFlusher{err: errors.New("flush error")}
+	assert.True(t, flushOutStore(lc, store, []*mockOutcomeFlusher{{}, failed, failed}, flush))
+	assert.Equal(t, []float64{0, 3, 2, 0, 1}, outcomeValues(&lc.Statistics.Outcomes))
+}
+
+// foldAggregator folds every 3 added events into 1 event, or generates 1 more event for each if generate is set.
+type foldAggregator struct {
+	generate bool
+	count    int
+}
+
+func (a *foldAggregator) Init(pipeline.Context, pipeline.LogGroupQueue) (int, error) {
+	return 0, nil
+}
+
+func (a *foldAggregator) Description() string {
+	return "fold aggregator for test"
+}
+
+func (a *foldAggregator) Reset() {
+	a.count = 0
+}
+
+func (a *foldAggregator) result() int {
+	defer a.Reset()
+	if a.generate {
+		return a.count * 2
+	}
+	return (a.count + 2) / 3
+}
+
+func (a *foldAggregator) Add(*protocol.Log, map[string]interface{}) error {
+	a.count++
+	return nil
+}
+
+func (a *foldAggregator) Flush() []*protocol.LogGroup {
+	logGroup := &protocol.LogGroup{}
+	for i := a.result(); i > 0; i-- {
+		logGroup.Logs = append(logGroup.Logs, &protocol.Log{Contents: []*protocol.Log_Content{{Key: "k", Value: "v"}}})
+	}
+	return []*protocol.LogGroup{logGroup}
+}
+
+func (a *foldAggregator) Record(group *models.PipelineGroupEvents, _ pipeline.PipelineContext) error {
+	a.count += len(group.Events)
+	return nil
+}
+
+func (a *foldAggregator) GetResult(context pipeline.PipelineContext) error {
+	events := make([]models.PipelineEvent, 0)
+	for i := a.result(); i > 0; i-- {
+		events = append(events, models.NewLog("", nil, "", "", "", models.NewTags(), 0))
+	}
+	context.Collector().Collect(models.NewGroup(models.NewMetadata(), models.NewTags()), events...)
+	return nil
+}
+
+func TestAggregatorOutcomesV1(t *testing.T) {
+	lc := newOutcomeTestConfig()
+	outcomes := &lc.Statistics.Outcomes
+	aggregator := &foldAggregator{}
+	wrapper := &AggregatorWrapperV1{LogGroupsChan: make(chan *protocol.LogGroup, 10), Aggregator: aggregator}
+	wrapper.Config = lc
+	wrapper.InitMetricRecord(&pipeline.PluginMeta{PluginType: "aggregator_fold"})
+	wrapper.Interval = time.Hour
+
+	outcomes.receive(7)
+	outcomes.processed(7, 7)
+	outcomes.aggregate(7)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, aggregator.Add(&protocol.Log{}, nil))
+	}
+	control := pipeline.NewAsyncControl()
+	control.Run(wrapper.Run)
+	control.WaitCancel()
+
+	// 7 events are folded into 3 events, the other 4 events are dropped.
+	logGroup := <-wrapper.LogGroupsChan
+	assert.Len(t, logGroup.Logs, 3)
+	outcomes.flushed(len(logGroup.Logs), false, false)
+	values := outcomeValues(outcomes)
+	assert.Equal(t, []float64{7, 3, 0, 4, 0}, values)
+	assert.Equal(t, values[0], values[1]+values[2]+values[3]+values[4])
+}
+
+func TestAggregatorOutcomesV2(t *testing.T) {
+	lc := newOutcomeTestConfig()
+	outcomes := &lc.Statistics.Outcomes
+	aggregator := &foldAggregator{}
+	runner := &pluginv2Runner{
+		LogstoreConfig:       lc,
+		AggregateControl:     pipeline.NewAsyncControl(),
+		AggregatePipeContext: outcomes.wrapAggregateContext(helper.NewObservePipelineConext(10)),
+		TimerRunner:          []*timerRunner{{state: aggregator, interval: time.Hour, context: lc.Context}},
+	}
+	group := &models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags())}
+	for i := 0; i < 8; i++ {
+		group.Events = append(group.Events, models.NewLog("", nil, "", "", "", models.NewTags(), 0))
+	}
+
+	outcomes.receive(8)
+	outcomes.processed(8, 8)
+	outcomes.aggregate(8)
+	require.NoError(t, aggregator.Record(group, runner.AggregatePipeContext))
+	runner.runAggregator()
+	runner.AggregateControl.WaitCancel()
+
+	// 8 events are folded into 3 events, the other 5 events are dropped.
+	result := <-runner.AggregatePipeContext.Collector().Observe()
+	assert.Len(t, result.Events, 3)
+	outcomes.flushed(len(result.Events), false, false)
+	assert.Equal(t, []float64{8, 3, 0, 5, 0}, outcomeValues(outcomes))
+
+	// 2 events are generated for each event.
+	aggregator.generate = true
+	outcomes.receive(2)
+	outcomes.aggregate(2)
+	require.NoError(t, aggregator.Record(&models.PipelineGroupEvents{Events: group.Events[:2]}, runner.AggregatePipeContext))
+	runner.runAggregator()
+	runner.AggregateControl.WaitCancel()
+	result = <-runner.AggregatePipeContext.Collector().Observe()
+	assert.Len(t, result.Events, 4)
+	outcomes.flushed(len(result.Events), false, false)
+	values := outcomeValues(outcomes)
+	assert.Equal(t, []float64{12, 7, 0, 5, 0}, values)
+	assert.Equal(t, values[0], values[1]+values[2]+values[3]+values[4])
+}
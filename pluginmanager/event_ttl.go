@@ -0,0 +1,82 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// dropStaleLogGroups drops the logs whose time is before the deadline in place, and the log groups emptied by it.
+// The logs without time are kept. It returns the remaining log groups and the number of the dropped logs.
+func dropStaleLogGroups(logGroups []*protocol.LogGroup, deadline time.Time) ([]*protocol.LogGroup, int) {
+	dropped := 0
+	deadlineSec := uint32(deadline.Unix())
+	nextGroup := 0
+	for _, logGroup := range logGroups {
+		if len(logGroup.Logs) == 0 {
+			logGroups[nextGroup] = logGroup
+			nextGroup++
+			continue
+		}
+		nextLog := 0
+		for _, log := range logGroup.Logs {
+			if log.Time != 0 && log.Time < deadlineSec {
+				dropped++
+				continue
+			}
+			logGroup.Logs[nextLog] = log
+			nextLog++
+		}
+		logGroup.Logs = logGroup.Logs[:nextLog]
+		if nextLog > 0 {
+			logGroups[nextGroup] = logGroup
+			nextGroup++
+		}
+	}
+	return logGroups[:nextGroup], dropped
+}
+
+// dropStaleGroupEvents drops the events whose timestamp is before the deadline in place, and the groups emptied by it.
+// The events without timestamp are kept. It returns the remaining groups and the number of the dropped events.
+func dropStaleGroupEvents(groups []*models.PipelineGroupEvents, deadline time.Time) ([]*models.PipelineGroupEvents, int) {
+	dropped := 0
+	deadlineNs := uint64(deadline.UnixNano())
+	nextGroup := 0
+	for _, group := range groups {
+		if len(group.Events) == 0 {
+			groups[nextGroup] = group
+			nextGroup++
+			continue
+		}
+		nextEvent := 0
+		for _, event := range group.Events {
+			if ts := event.GetTimestamp(); ts != 0 && ts < deadlineNs {
+				dropped++
+				continue
+			}
+			group.Events[nextEvent] = event
+			nextEvent++
+		}
+		group.Events = group.Events[:nextEvent]
+		if nextEvent > 0 {
+			groups[nextGroup] = group
+			nextGroup++
+		}
+	}
+	return groups[:nextGroup], dropped
+}
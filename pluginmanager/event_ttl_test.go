@@ -0,0 +1,58 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+func TestDropStaleLogGroups(t *testing.T) {
+	deadline := time.Unix(1000, 0)
+	logGroups := []*protocol.LogGroup{
+		{Logs: []*protocol.Log{{Time: 999}, {Time: 1000}, {Time: 0}, {Time: 1}}},
+		{Logs: []*protocol.Log{{Time: 500}}},
+		{},
+		{Logs: []*protocol.Log{{Time: 2000}}},
+	}
+	remaining, dropped := dropStaleLogGroups(logGroups, deadline)
+	assert.Equal(t, 3, dropped)
+	assert.Len(t, remaining, 3)
+	assert.Equal(t, []*protocol.Log{{Time: 1000}, {Time: 0}}, remaining[0].Logs)
+	assert.Empty(t, remaining[1].Logs, "the group empty before dropping is kept")
+	assert.Equal(t, uint32(2000), remaining[2].Logs[0].Time)
+}
+
+func TestDropStaleGroupEvents(t *testing.T) {
+	deadline := time.Unix(1000, 0)
+	newLog := func(sec int64) models.PipelineEvent {
+		return models.NewSimpleLog(nil, nil, uint64(sec*int64(time.Second)))
+	}
+	groups := []*models.PipelineGroupEvents{
+		{Events: []models.PipelineEvent{newLog(999), newLog(1000), newLog(0)}},
+		{Events: []models.PipelineEvent{models.NewSingleValueMetric("m", models.MetricTypeGauge, nil, int64(500*time.Second), 1)}},
+	}
+	remaining, dropped := dropStaleGroupEvents(groups, deadline)
+	assert.Equal(t, 2, dropped)
+	assert.Len(t, remaining, 1)
+	assert.Len(t, remaining[0].Events, 2)
+	assert.Equal(t, uint64(1000*time.Second), remaining[0].Events[0].GetTimestamp())
+	assert.Equal(t, uint64(0), remaining[0].Events[1].GetTimestamp())
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const flusherProjectionKey = "Projection"
+
+// fieldProjection trims the fields of the logs sent to a flusher, so that the same logs can be flushed to
+// multiple destinations in different shapes by one pipeline. The fields are the contents of the logs,
+// the other events and the group tags are not projected.
+// The data shared by the flushers is never modified, the projected logs are shallow copies.
+type fieldProjection struct {
+	Include []string // Only the listed fields are kept if not empty.
+	Exclude []string // The listed fields are removed.
+
+	include map[string]struct{}
+	exclude map[string]struct{}
+}
+
+// newFieldProjection parses the projection from the flusher config, returns nil if not configured.
+func newFieldProjection(configInterface interface{}) (*fieldProjection, error) {
+	config, ok := configInterface.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	projectionConfig, ok := config[flusherProjectionKey]
+	if !ok || projectionConfig == nil {
+		return nil, nil
+	}
+	var p fieldProjection
+	if err := applyPluginConfig(&p, projectionConfig); err != nil {
+		return nil, err
+	}
+	if len(p.Include) == 0 && len(p.Exclude) == 0 {
+		return nil, nil
+	}
+	p.include = toKeySet(p.Include)
+	p.exclude = toKeySet(p.Exclude)
+	return &p, nil
+}
+
+func toKeySet(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+func (p *fieldProjection) keep(key string) bool {
+	if p.include != nil {
+		if _, ok := p.include[key]; !ok {
+			return false
+		}
+	}
+	_, ok := p.exclude[key]
+	return !ok
+}
+
+func (p *fieldProjection) projectLogGroups(logGroups []*protocol.LogGroup) []*protocol.LogGroup {
+	projected := make([]*protocol.LogGroup, 0, len(logGroups))
+	for _, logGroup := range logGroups {
+		g := *logGroup
+		g.Logs = make([]*protocol.Log, 0, len(logGroup.Logs))
+		for _, log := range logGroup.Logs {
+			l := *log
+			l.Contents = make([]*protocol.Log_Content, 0, len(log.Contents))
+			for _, content := range log.Contents {
+				if p.keep(content.Key) {
+					l.Contents = append(l.Contents, content)
+				}
+			}
+			g.Logs = append(g.Logs, &l)
+		}
+		projected = append(projected, &g)
+	}
+	return projected
+}
+
+func (p *fieldProjection) projectGroupEvents(groups []*models.PipelineGroupEvents) []*models.PipelineGroupEvents {
+	projected := make([]*models.PipelineGroupEvents, 0, len(groups))
+	for _, group := range groups {
+		events := make([]models.PipelineEvent, 0, len(group.Events))
+		for _, event := range group.Events {
+			log, ok := event.(*models.Log)
+			if !ok || log.Contents == nil {
+				events = append(events, event)
+				continue
+			}
+			l := *log
+			l.Contents = models.NewLogContents()
+			for key, value := range log.Contents.Iterator() {
+				if p.keep(key) {
+					l.Contents.Add(key, value)
+				}
+			}
+			events = append(events, &l)
+		}
+		projected = append(projected, &models.PipelineGroupEvents{Group: group.Group, Events: events})
+	}
+	return projected
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+func TestNewFieldProjection(t *testing.T) {
+	p, err := newFieldProjection(map[string]interface{}{"Endpoint": "x"})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	p, err = newFieldProjection(map[string]interface{}{"Projection": map[string]interface{}{}})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	p, err = newFieldProjection(nil)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	_, err = newFieldProjection(map[string]interface{}{"Projection": map[string]interface{}{"Include": "a"}})
+	assert.Error(t, err)
+
+	p, err = newFieldProjection(map[string]interface{}{"Projection": map[string]interface{}{
+		"Include": []interface{}{"a", "b"},
+		"Exclude": []interface{}{"b"},
+	}})
+	require.NoError(t, err)
+	assert.True(t, p.keep("a"))
+	assert.False(t, p.keep("b"))
+	assert.False(t, p.keep("c"))
+}
+
+func TestFieldProjection_ProjectLogGroups(t *testing.T) {
+	p, err := newFieldProjection(map[string]interface{}{"Projection": map[string]interface{}{"Exclude": []interface{}{"body"}}})
+	require.NoError(t, err)
+	logGroups := []*protocol.LogGroup{{
+		Topic:   "topic",
+		LogTags: []*protocol.LogTag{{Key: "host", Value: "h"}},
+		Logs: []*protocol.Log{{Time: 1, Contents: []*protocol.Log_Content{
+			{Key: "level", Value: "INFO"},
+			{Key: "body", Value: "a long message"},
+		}}},
+	}}
+	projected := p.projectLogGroups(logGroups)
+	require.Len(t, projected, 1)
+	assert.Equal(t, "topic", projected[0].Topic)
+	assert.Equal(t, logGroups[0].LogTags, projected[0].LogTags)
+	assert.Equal(t, []*protocol.Log{{Time: 1, Contents: []*protocol.Log_Content{{Key: "level", Value: "INFO"}}}}, projected[0].Logs)
+	assert.Len(t, logGroups[0].Logs[0].Contents, 2, "the shared logs are not modified")
+}
+
+func TestFieldProjection_ProjectGroupEvents(t *testing.T) {
+	p, err := newFieldProjection(map[string]interface{}{"Projection": map[string]interface{}{"Include": []interface{}{"level"}}})
+	require.NoError(t, err)
+	log := models.NewLog("", nil, "", "", "", models.NewTags(), 1)
+	log.Contents.Add("level", "INFO")
+	log.Contents.Add("body", "a long message")
+	metric := models.NewSingleValueMetric("m", models.MetricTypeGauge, models.NewTags(), 1, 1)
+	group := models.NewGroup(models.NewMetadata(), models.NewTags())
+	groups := []*models.PipelineGroupEvents{{Group: group, Events: []models.PipelineEvent{log, metric}}}
+
+	projected := p.projectGroupEvents(groups)
+	require.Len(t, projected, 1)
+	assert.Same(t, group, projected[0].Group)
+	require.Len(t, projected[0].Events, 2)
+	assert.Equal(t, map[string]interface{}{"level": "INFO"}, projected[0].Events[0].(*models.Log).GetIndices().Iterator())
+	assert.Equal(t, uint64(1), projected[0].Events[0].GetTimestamp())
+	assert.Same(t, metric, projected[0].Events[1])
+	assert.Equal(t, 2, log.Contents.Len(), "the shared events are not modified")
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/flags"
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	converter "github.com/alibaba/ilogtail/pkg/protocol/converter"
+)
+
+const (
+	schemaConfigName = "flusher_schema"
+	// the flushers are initialized when the config is loaded, so they are replaced by one doing nothing
+	schemaPlaceholderFlusher = "flusher_blackhole"
+	slsFlusherType           = "flusher_sls"
+	sampleEventTimeKey       = "__time__"
+)
+
+// FlusherSchema is the output schema of a flusher, Error tells why it could not be inferred.
+type FlusherSchema struct {
+	Flusher  string                   `json:"flusher"`
+	Protocol string                   `json:"protocol,omitempty"`
+	Encoding string                   `json:"encoding,omitempty"`
+	Fields   []*converter.FieldSchema `json:"fields,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// RenderFlusherSchemas runs the sample events through the processors of the pipeline config, and infers the
+// schema of the output of each flusher from its field projection and converter, so that the downstream tables
+// and indexes could be designed before the pipeline is deployed. The sample events are json objects, one per line,
+// whose values are the contents of the logs except that __time__ is the time of the log.
+// Nothing is sent, the flushers are created from the config but never initialized.
+func RenderFlusherSchemas(jsonStr string, sampleEvents []byte) ([]*FlusherSchema, error) {
+	var plugins map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &plugins); err != nil {
+		return nil, err
+	}
+	flushers, err := schemaFlusherConfigs(plugins)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := parseSampleEvents(sampleEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	processingConfig := make(map[string]interface{})
+	for _, key := range []string{"global", "processors", "extensions"} {
+		if value, ok := plugins[key]; ok {
+			processingConfig[key] = value
+		}
+	}
+	processingConfig["flushers"] = []interface{}{map[string]interface{}{"type": schemaPlaceholderFlusher}}
+	processingJSON, err := json.Marshal(processingConfig)
+	if err != nil {
+		return nil, err
+	}
+	lc, err := createLogstoreConfig("", "", schemaConfigName, 0, string(processingJSON))
+	if err != nil {
+		return nil, err
+	}
+	runner, ok := lc.PluginRunner.(*pluginv1Runner)
+	if !ok {
+		return nil, fmt.Errorf("only the pipelines of version %s are supported", v1)
+	}
+	for _, processor := range runner.ProcessorPlugins {
+		logs = processor.Process(logs)
+	}
+	logGroup := &protocol.LogGroup{Logs: logs}
+
+	schemas := make([]*FlusherSchema, 0, len(flushers))
+	for _, flusher := range flushers {
+		schemas = append(schemas, inferFlusherSchema(lc, flusher, logGroup))
+	}
+	return schemas, nil
+}
+
+// schemaFlusherConfigs returns the flushers of the config, or the default flusher if there is none.
+func schemaFlusherConfigs(plugins map[string]interface{}) ([]map[string]interface{}, error) {
+	pluginConfig, ok := plugins["flushers"]
+	if !ok {
+		category, options := flags.GetFlusherConfiguration()
+		return []map[string]interface{}{{"type": category, "detail": options}}, nil
+	}
+	flusherList, ok := pluginConfig.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid flusher type : %s, not json array", "flushers")
+	}
+	flushers := make([]map[string]interface{}, 0, len(flusherList))
+	for _, flusherInterface := range flusherList {
+		flusher, ok := flusherInterface.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid flusher type")
+		}
+		if _, ok := flusher["type"].(string); !ok {
+			return nil, fmt.Errorf("invalid flusher type")
+		}
+		flushers = append(flushers, flusher)
+	}
+	return flushers, nil
+}
+
+func parseSampleEvents(data []byte) ([]*protocol.Log, error) {
+	var logs []*protocol.Log
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("invalid sample event %s: %v", line, err)
+		}
+		log := &protocol.Log{}
+		for key, value := range event {
+			str, ok := value.(string)
+			if !ok {
+				b, _ := json.Marshal(value)
+				str = string(b)
+			}
+			if key == sampleEventTimeKey {
+				t, err := strconv.ParseUint(str, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("invalid time of sample event %s: %v", line, err)
+				}
+				protocol.SetLogTime(log, uint32(t))
+				continue
+			}
+			log.Contents = append(log.Contents, &protocol.Log_Content{Key: key, Value: str})
+		}
+		logs = append(logs, log)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no sample event")
+	}
+	return logs, nil
+}
+
+func inferFlusherSchema(lc *LogstoreConfig, flusherConfig map[string]interface{}, logGroup *protocol.LogGroup) *FlusherSchema {
+	pluginTypeWithID := flusherConfig["type"].(string)
+	schema := &FlusherSchema{Flusher: pluginTypeWithID}
+	pluginType := getPluginType(pluginTypeWithID)
+	creator, ok := pipeline.Flushers[pluginType]
+	if !ok || creator == nil {
+		schema.Error = fmt.Sprintf("can't find plugin %s", pluginType)
+		return schema
+	}
+	flusher := creator()
+	if err := applyPluginConfig(flusher, flusherConfig["detail"]); err != nil {
+		schema.Error = err.Error()
+		return schema
+	}
+	projection, err := newFieldProjection(flusherConfig["detail"])
+	if err != nil {
+		schema.Error = err.Error()
+		return schema
+	}
+	if projection != nil {
+		logGroup = projection.projectLogGroups([]*protocol.LogGroup{logGroup})[0]
+	}
+
+	if pluginType == slsFlusherType {
+		schema.Protocol = "sls"
+		schema.Encoding = converter.EncodingProtobuf
+		schema.Fields, err = inferSLSSchema(logGroup)
+	} else if convertConfig, ok := getConvertConfig(flusher); ok {
+		schema.Protocol = convertConfig.Protocol
+		schema.Encoding = convertConfig.Encoding
+		var c *converter.Converter
+		c, err = converter.NewConverterWithSep(convertConfig.Protocol, convertConfig.Encoding, convertConfig.Separator, convertConfig.IgnoreUnExpectedData,
+			convertConfig.TagFieldsRename, convertConfig.ProtocolFieldsRename, lc.GlobalConfig)
+		if err == nil {
+			schema.Fields, err = c.InferSchema(logGroup)
+		}
+	} else {
+		err = fmt.Errorf("the output of the flusher is not converted by a known protocol")
+	}
+	if err != nil {
+		schema.Error = err.Error()
+	}
+	return schema
+}
+
+// getConvertConfig returns the Convert field of the flusher, the flushers using a converter declare it by
+// helper.ConvertConfig or a struct of the same fields.
+func getConvertConfig(flusher interface{}) (helper.ConvertConfig, bool) {
+	var convertConfig helper.ConvertConfig
+	v := reflect.Indirect(reflect.ValueOf(flusher))
+	if v.Kind() != reflect.Struct {
+		return convertConfig, false
+	}
+	field := v.FieldByName("Convert")
+	if !field.IsValid() || field.Kind() != reflect.Struct || !field.CanInterface() {
+		return convertConfig, false
+	}
+	if err := applyPluginConfig(&convertConfig, field.Interface()); err != nil || convertConfig.Protocol == "" {
+		return convertConfig, false
+	}
+	return convertConfig, true
+}
+
+// inferSLSSchema infers the schema of the logs as they are stored in SLS, the contents are strings and the tags
+// are stored as __tag__:key.
+func inferSLSSchema(logGroup *protocol.LogGroup) ([]*converter.FieldSchema, error) {
+	records := make([][]byte, 0, len(logGroup.Logs))
+	for _, log := range logGroup.Logs {
+		record := map[string]interface{}{sampleEventTimeKey: log.Time}
+		for _, content := range log.Contents {
+			record[content.Key] = content.Value
+		}
+		for _, tag := range logGroup.LogTags {
+			record["__tag__:"+tag.Key] = tag.Value
+		}
+		b, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, b)
+	}
+	return converter.InferRecordsSchema(records)
+}
+
+// FlusherSchemasToMarkdown renders the schemas as a markdown table for each flusher.
+func FlusherSchemasToMarkdown(schemas []*FlusherSchema) string {
+	var sb strings.Builder
+	for _, schema := range schemas {
+		sb.WriteString("# " + schema.Flusher + "\n\n")
+		if schema.Protocol != "" {
+			sb.WriteString(fmt.Sprintf("Protocol: %s, Encoding: %s\n\n", schema.Protocol, schema.Encoding))
+		}
+		if schema.Error != "" {
+			sb.WriteString("Unable to infer the schema: " + schema.Error + "\n\n")
+			continue
+		}
+		sb.WriteString("| field | type | required | example |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, field := range schema.Fields {
+			example := ""
+			if field.Example != nil {
+				b, _ := json.Marshal(field.Example)
+				example = string(b)
+			}
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %v | `%s` |\n", field.Name, field.Type, field.Required, example))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/alibaba/ilogtail/plugins/flusher/blackhole"
+	_ "github.com/alibaba/ilogtail/plugins/flusher/http"
+	_ "github.com/alibaba/ilogtail/plugins/flusher/sls"
+	_ "github.com/alibaba/ilogtail/plugins/flusher/stdout"
+	_ "github.com/alibaba/ilogtail/plugins/processor/regex"
+)
+
+const flusherSchemaTestConfig = `{
+	"processors": [
+		{
+			"type": "processor_regex",
+			"detail": {
+				"SourceKey": "content",
+				"Regex": "(\\S+) (\\d+)",
+				"Keys": ["method", "status"]
+			}
+		}
+	],
+	"flushers": [
+		{
+			"type": "flusher_sls"
+		},
+		{
+			"type": "flusher_http",
+			"detail": {
+				"RemoteURL": "http://127.0.0.1:8086/write",
+				"Convert": {
+					"Protocol": "custom_single_flatten",
+					"Encoding": "json"
+				},
+				"Projection": {
+					"Exclude": ["status"]
+				}
+			}
+		},
+		{
+			"type": "flusher_stdout"
+		}
+	]
+}`
+
+func TestRenderFlusherSchemas(t *testing.T) {
+	events := []byte(`{"content": "GET 200", "__time__": "1662434209"}
+{"content": "PUT 404", "__tag__:__path__": "/var/log/a.log"}`)
+	schemas, err := RenderFlusherSchemas(flusherSchemaTestConfig, events)
+	require.NoError(t, err)
+	require.Len(t, schemas, 3)
+
+	sls := schemas[0]
+	assert.Equal(t, "flusher_sls", sls.Flusher)
+	assert.Empty(t, sls.Error)
+	fields := make(map[string]bool)
+	for _, field := range sls.Fields {
+		fields[field.Name] = field.Required
+	}
+	assert.Equal(t, map[string]bool{"__time__": true, "method": true, "status": true, "__tag__:__path__": false}, fields)
+
+	http := schemas[1]
+	assert.Empty(t, http.Error)
+	assert.Equal(t, "custom_single_flatten", http.Protocol)
+	fields = make(map[string]bool)
+	for _, field := range http.Fields {
+		fields[field.Name] = field.Required
+	}
+	assert.Contains(t, fields, "method")
+	assert.NotContains(t, fields, "status")
+	assert.NotContains(t, fields, "content")
+
+	assert.NotEmpty(t, schemas[2].Error)
+
+	markdown := FlusherSchemasToMarkdown(schemas)
+	assert.Contains(t, markdown, "# flusher_http")
+	assert.Contains(t, markdown, "| `method` | string | true | `\"GET\"` |")
+}
+
+func TestRenderFlusherSchemasInvalidEvents(t *testing.T) {
+	_, err := RenderFlusherSchemas(flusherSchemaTestConfig, []byte(`not json`))
+	assert.Error(t, err)
+	_, err = RenderFlusherSchemas(flusherSchemaTestConfig, nil)
+	assert.Error(t, err)
+}
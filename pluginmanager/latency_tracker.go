@@ -0,0 +1,140 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// readTimeKey is the key of the read time stamped by the latency tracking, in the contents of the logs for v1 or in
+// the tags of the events for v2. It is removed before the events are flushed.
+const readTimeKey = "__read_time_ns__"
+
+var monotonicBase = time.Now()
+
+// monotonicNow returns the nanoseconds since the epoch measured by the monotonic clock, so the latency between two
+// of them is not affected by the adjustments of the wall clock.
+func monotonicNow() int64 {
+	return monotonicBase.UnixNano() + int64(time.Since(monotonicBase))
+}
+
+// stampReadTimeV1 stamps the logs leaving the processors with the time they entered the processor routine.
+// The logs split by the processors share the read time of the original one, and the empty logs are left untouched
+// since they are not aggregated.
+func stampReadTimeV1(logs []*protocol.Log, readTime int64) {
+	value := strconv.FormatInt(readTime, 10)
+	for _, log := range logs {
+		if len(log.Contents) == 0 {
+			continue
+		}
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: readTimeKey, Value: value})
+	}
+}
+
+func stampReadTimeV2(groups []*models.PipelineGroupEvents, readTime int64) {
+	value := strconv.FormatInt(readTime, 10)
+	for _, group := range groups {
+		for _, event := range group.Events {
+			if tags := eventTags(event); tags != nil {
+				tags.Add(readTimeKey, value)
+			}
+		}
+	}
+}
+
+// takeReadTimesV1 removes the read times from the logs and returns them, the logs without read time are skipped.
+func takeReadTimesV1(logGroups []*protocol.LogGroup) []int64 {
+	readTimes := make([]int64, 0)
+	for _, logGroup := range logGroups {
+		for _, log := range logGroup.Logs {
+			for i := len(log.Contents) - 1; i >= 0; i-- {
+				if log.Contents[i].Key != readTimeKey {
+					continue
+				}
+				if readTime, err := strconv.ParseInt(log.Contents[i].Value, 10, 64); err == nil {
+					readTimes = append(readTimes, readTime)
+				}
+				log.Contents = append(log.Contents[:i], log.Contents[i+1:]...)
+				break
+			}
+		}
+	}
+	return readTimes
+}
+
+func takeReadTimesV2(groups []*models.PipelineGroupEvents) []int64 {
+	readTimes := make([]int64, 0)
+	for _, group := range groups {
+		for _, event := range group.Events {
+			tags := event.GetTags()
+			if tags == nil || !tags.Contains(readTimeKey) {
+				continue
+			}
+			if readTime, err := strconv.ParseInt(tags.Get(readTimeKey), 10, 64); err == nil {
+				readTimes = append(readTimes, readTime)
+			}
+			tags.Delete(readTimeKey)
+		}
+	}
+	return readTimes
+}
+
+// latencyHistogram exports the distribution of the latencies in the last window as the delta counters of the buckets,
+// each of which counts the latencies not greater than its upper bound, along with the average and max latency.
+type latencyHistogram struct {
+	boundsMs []int64
+	buckets  []pipeline.CounterMetric // the last one is the +Inf bucket
+	avgMs    pipeline.CounterMetric
+	maxMs    pipeline.GaugeMetric
+}
+
+func newLatencyHistogram(record *pipeline.MetricsRecord, boundsMs []int64) *latencyHistogram {
+	h := &latencyHistogram{
+		boundsMs: append([]int64(nil), boundsMs...),
+	}
+	sort.Slice(h.boundsMs, func(i, j int) bool { return h.boundsMs[i] < h.boundsMs[j] })
+	for _, bound := range h.boundsMs {
+		boundStr := strconv.FormatInt(bound, 10)
+		h.buckets = append(h.buckets, newLatencyBucket(record, boundStr, "Number of events flushed within "+boundStr+" ms after entering the pipeline."))
+	}
+	h.buckets = append(h.buckets, newLatencyBucket(record, "inf", "Number of events flushed with the latency tracked."))
+	h.avgMs = helper.NewAverageMetricAndRegister(record, helper.MetricPluginEndToEndAvgLatencyMs)
+	h.maxMs = helper.NewMaxMetricAndRegister(record, helper.MetricPluginEndToEndMaxLatencyMs)
+	return h
+}
+
+func newLatencyBucket(record *pipeline.MetricsRecord, bound, help string) pipeline.CounterMetric {
+	name := helper.MetricPluginEndToEndLatencyBucket + "_le_" + bound
+	bucket := helper.NewCounterMetricAndRegister(record, name)
+	record.DescribeMetric(name, pipeline.MetricDescription{Unit: "events", Help: help})
+	return bucket
+}
+
+func (h *latencyHistogram) observe(latency time.Duration) {
+	latencyMs := latency.Milliseconds()
+	i := sort.Search(len(h.boundsMs), func(i int) bool { return h.boundsMs[i] >= latencyMs })
+	for ; i < len(h.buckets); i++ {
+		h.buckets[i].Add(1)
+	}
+	h.avgMs.Add(latencyMs)
+	h.maxMs.Set(float64(latencyMs))
+}
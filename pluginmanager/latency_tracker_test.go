@@ -0,0 +1,104 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+func TestReadTimeV1(t *testing.T) {
+	logs := []*protocol.Log{
+		{Contents: []*protocol.Log_Content{{Key: "content", Value: "a"}}},
+		{},
+		{Contents: []*protocol.Log_Content{{Key: "content", Value: "b"}}},
+	}
+	stampReadTimeV1(logs, 100)
+	assert.Equal(t, "100", logContent(logs[0], readTimeKey))
+	assert.Empty(t, logs[1].Contents)
+
+	readTimes := takeReadTimesV1([]*protocol.LogGroup{
+		{Logs: logs[:2]},
+		{Logs: []*protocol.Log{logs[2], {Contents: []*protocol.Log_Content{{Key: "content", Value: "c"}}}}},
+	})
+	assert.Equal(t, []int64{100, 100}, readTimes)
+	for _, log := range logs {
+		for _, content := range log.Contents {
+			assert.NotEqual(t, readTimeKey, content.Key)
+		}
+	}
+	assert.Equal(t, "b", logContent(logs[2], "content"))
+}
+
+func TestReadTimeV2(t *testing.T) {
+	group := &models.PipelineGroupEvents{
+		Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{&models.Log{}, models.ByteArray("raw"), &models.Log{Tags: models.NewTags()}},
+	}
+	stampReadTimeV2([]*models.PipelineGroupEvents{group}, 200)
+	assert.Equal(t, "200", group.Events[0].GetTags().Get(readTimeKey))
+	assert.Equal(t, "200", group.Events[2].GetTags().Get(readTimeKey))
+
+	readTimes := takeReadTimesV2([]*models.PipelineGroupEvents{group})
+	assert.Equal(t, []int64{200, 200}, readTimes)
+	assert.False(t, group.Events[0].GetTags().Contains(readTimeKey))
+	assert.False(t, group.Events[2].GetTags().Contains(readTimeKey))
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	record := &pipeline.MetricsRecord{}
+	h := newLatencyHistogram(record, []int64{100, 10})
+	for _, latency := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, time.Second} {
+		h.observe(latency)
+	}
+
+	exported := record.ExportMetricRecords()
+	counters := map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(exported[pipeline.MetricCounterPrefix]), &counters))
+	assert.Equal(t, "2.0000", counters["end_to_end_latency_ms_bucket_le_10"])
+	assert.Equal(t, "3.0000", counters["end_to_end_latency_ms_bucket_le_100"])
+	assert.Equal(t, "4.0000", counters["end_to_end_latency_ms_bucket_le_inf"])
+	gauges := map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(exported[pipeline.MetricGaugePrefix]), &gauges))
+	assert.Equal(t, "266.2500", gauges["end_to_end_avg_latency_ms"])
+	assert.Equal(t, "1000.0000", gauges["end_to_end_max_latency_ms"])
+	metadata := map[string]pipeline.MetricDescription{}
+	require.NoError(t, json.Unmarshal([]byte(exported[pipeline.MetricMetadataPrefix]), &metadata))
+	assert.Equal(t, "events", metadata["end_to_end_latency_ms_bucket_le_10"].Unit)
+	assert.Equal(t, "ms", metadata["end_to_end_avg_latency_ms"].Unit)
+}
+
+func TestFlusherWrapperObserveEndToEndLatency(t *testing.T) {
+	record := &pipeline.MetricsRecord{}
+	wrapper := &FlusherWrapper{}
+	// no-op if the latency tracking is disabled
+	wrapper.observeEndToEndLatency([]int64{monotonicNow()})
+
+	wrapper.endToEndLatency = newLatencyHistogram(record, []int64{1000})
+	now := monotonicNow()
+	wrapper.observeEndToEndLatency([]int64{now, now - int64(2*time.Second)})
+	counters := map[string]string{}
+	require.NoError(t, json.Unmarshal([]byte(record.ExportMetricRecords()[pipeline.MetricCounterPrefix]), &counters))
+	assert.Equal(t, "1.0000", counters["end_to_end_latency_ms_bucket_le_1000"])
+	assert.Equal(t, "2.0000", counters["end_to_end_latency_ms_bucket_le_inf"])
+}
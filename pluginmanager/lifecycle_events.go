@@ -0,0 +1,134 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+// The lifecycle events are reported by the alarm built-in config, so that the fleet operators could audit the
+// configs each agent has applied and the reason of the failed ones.
+const (
+	lifecycleConfigLoaded     = "config_loaded"
+	lifecycleConfigUpdated    = "config_updated"
+	lifecycleConfigRemoved    = "config_removed"
+	lifecycleConfigLoadFailed = "config_load_failed"
+	lifecyclePipelineStarted  = "pipeline_started"
+	lifecyclePipelineStopped  = "pipeline_stopped"
+	lifecyclePluginInitFailed = "plugin_init_failed"
+
+	// maxLifecycleEvents bounds the events kept between two collections, the oldest ones are dropped first.
+	maxLifecycleEvents = 1000
+)
+
+type lifecycleEvent struct {
+	event         string
+	configName    string
+	configVersion string
+	plugin        string
+	reason        string
+	time          time.Time
+}
+
+type lifecycleRecorder struct {
+	lock   sync.Mutex
+	events []*lifecycleEvent
+	// versions keeps the version of each loaded config to tell the updated configs from the new ones.
+	versions map[string]string
+}
+
+var lifecycleEvents = &lifecycleRecorder{versions: make(map[string]string)}
+
+func (r *lifecycleRecorder) record(event *lifecycleEvent) {
+	event.time = time.Now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.events) >= maxLifecycleEvents {
+		r.events = r.events[1:]
+	}
+	r.events = append(r.events, event)
+}
+
+// recordConfigLoaded records config_loaded for the config seen at the first time, and config_updated for the
+// config whose version differs from the last loaded one.
+func (r *lifecycleRecorder) recordConfigLoaded(lc *LogstoreConfig) {
+	r.lock.Lock()
+	lastVersion, ok := r.versions[lc.ConfigNameWithSuffix]
+	r.versions[lc.ConfigNameWithSuffix] = lc.configDetailHash
+	r.lock.Unlock()
+	event := lifecycleConfigLoaded
+	if ok && lastVersion != lc.configDetailHash {
+		event = lifecycleConfigUpdated
+	}
+	r.record(&lifecycleEvent{event: event, configName: lc.ConfigNameWithSuffix, configVersion: lc.configDetailHash})
+}
+
+func (r *lifecycleRecorder) recordConfigRemoved(configName string) {
+	r.lock.Lock()
+	version := r.versions[configName]
+	delete(r.versions, configName)
+	r.lock.Unlock()
+	r.record(&lifecycleEvent{event: lifecycleConfigRemoved, configName: configName, configVersion: version})
+}
+
+func (r *lifecycleRecorder) recordConfigLoadFailed(configName string, jsonStr string, err error) {
+	r.record(&lifecycleEvent{event: lifecycleConfigLoadFailed, configName: configName, configVersion: resolvedConfigVersion(jsonStr), reason: err.Error()})
+}
+
+func (r *lifecycleRecorder) recordPipeline(event string, lc *LogstoreConfig) {
+	r.record(&lifecycleEvent{event: event, configName: lc.ConfigNameWithSuffix, configVersion: lc.configDetailHash})
+}
+
+// recordPluginLoadError is deferred by the plugin loaders with their returned error.
+func (r *lifecycleRecorder) recordPluginLoadError(lc *LogstoreConfig, pluginMeta *pipeline.PluginMeta, err *error) {
+	if *err == nil {
+		return
+	}
+	r.record(&lifecycleEvent{
+		event:         lifecyclePluginInitFailed,
+		configName:    lc.ConfigNameWithSuffix,
+		configVersion: lc.configDetailHash,
+		plugin:        pluginMeta.PluginTypeWithID,
+		reason:        (*err).Error(),
+	})
+}
+
+// SerializeToPb moves the recorded events to the log group.
+func (r *lifecycleRecorder) SerializeToPb(logGroup *protocol.LogGroup) {
+	r.lock.Lock()
+	events := r.events
+	r.events = nil
+	r.lock.Unlock()
+	for _, event := range events {
+		log := &protocol.Log{}
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "event", Value: event.event})
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "config_name", Value: event.configName})
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "config_version", Value: event.configVersion})
+		if event.plugin != "" {
+			log.Contents = append(log.Contents, &protocol.Log_Content{Key: "plugin", Value: event.plugin})
+		}
+		if event.reason != "" {
+			log.Contents = append(log.Contents, &protocol.Log_Content{Key: "reason", Value: event.reason})
+		}
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "ip", Value: util.GetIPAddress()})
+		protocol.SetLogTime(log, uint32(event.time.Unix()))
+		logGroup.Logs = append(logGroup.Logs, log)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const lifecycleTestConfig = `{
+	"global": {"DefaultLogQueueSize": %d},
+	"flushers": [{"type": "%s"}]
+}`
+
+func collectLifecycleEvents() []map[string]string {
+	logGroup := &protocol.LogGroup{}
+	lifecycleEvents.SerializeToPb(logGroup)
+	events := make([]map[string]string, 0, len(logGroup.Logs))
+	for _, log := range logGroup.Logs {
+		event := make(map[string]string)
+		for _, content := range log.Contents {
+			event[content.Key] = content.Value
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	lifecycleEvents = &lifecycleRecorder{versions: make(map[string]string)}
+	configName := "lifecycle_config"
+	v1 := fmt.Sprintf(lifecycleTestConfig, 10, "flusher_checker")
+	v2 := fmt.Sprintf(lifecycleTestConfig, 20, "flusher_checker")
+
+	require.NoError(t, LoadLogstoreConfig("project", "logstore", configName, 0, v1))
+	require.NoError(t, Start(configName))
+	require.NoError(t, Stop(configName, false))
+	require.NoError(t, LoadLogstoreConfig("project", "logstore", configName, 0, v2))
+	require.NoError(t, Start(configName))
+	require.NoError(t, Stop(configName, true))
+	assert.Error(t, LoadLogstoreConfig("project", "logstore", configName, 0, fmt.Sprintf(lifecycleTestConfig, 10, "flusher_unknown")))
+
+	events := collectLifecycleEvents()
+	require.Len(t, events, 9)
+	expected := []struct{ event, version string }{
+		{lifecycleConfigLoaded, resolvedConfigVersion(v1)},
+		{lifecyclePipelineStarted, resolvedConfigVersion(v1)},
+		{lifecyclePipelineStopped, resolvedConfigVersion(v1)},
+		{lifecycleConfigUpdated, resolvedConfigVersion(v2)},
+		{lifecyclePipelineStarted, resolvedConfigVersion(v2)},
+		{lifecyclePipelineStopped, resolvedConfigVersion(v2)},
+		{lifecycleConfigRemoved, resolvedConfigVersion(v2)},
+		{lifecyclePluginInitFailed, ""},
+		{lifecycleConfigLoadFailed, ""},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.event, events[i]["event"])
+		assert.Equal(t, configName, events[i]["config_name"])
+		if e.version != "" {
+			assert.Equal(t, e.version, events[i]["config_version"])
+		}
+	}
+	assert.True(t, strings.HasPrefix(events[7]["plugin"], "flusher_unknown/"))
+	assert.Contains(t, events[7]["reason"], "can't find plugin flusher_unknown")
+	assert.Equal(t, events[7]["config_version"], events[8]["config_version"])
+	assert.NotEmpty(t, events[8]["reason"])
+	assert.Empty(t, collectLifecycleEvents())
+}
+
+func TestLifecycleEventsBounded(t *testing.T) {
+	recorder := &lifecycleRecorder{versions: make(map[string]string)}
+	for i := 0; i < maxLifecycleEvents+10; i++ {
+		recorder.record(&lifecycleEvent{event: lifecyclePipelineStarted, configName: fmt.Sprint(i)})
+	}
+	require.Len(t, recorder.events, maxLifecycleEvents)
+	assert.Equal(t, "10", recorder.events[0].configName)
+}
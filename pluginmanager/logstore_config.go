@@ -75,6 +75,15 @@ type LogstoreStatistics struct {
 	FlushLogGroupMetric  pipeline.CounterMetric
 	FlushReadyMetric     pipeline.CounterMetric
 	FlushLatencyMetric   pipeline.LatencyMetric
+	FlushStaleMetric     pipeline.CounterMetric
+	// ScheduleDeferredMetric counts the events held before the flushers because the schedule window is closed.
+	ScheduleDeferredMetric pipeline.CounterMetric
+	// ScheduleDeferLatencyMetric observes how long the deferred events are held until the next window opens.
+	ScheduleDeferLatencyMetric pipeline.LatencyMetric
+	// SchedulePausedMetric counts the collections skipped by the metric inputs outside the schedule windows.
+	SchedulePausedMetric pipeline.CounterMetric
+	// Outcomes attributes each event to its terminal outcome.
+	Outcomes eventOutcomes
 }
 
 type ConfigVersion string
@@ -102,12 +111,18 @@ type LogstoreConfig struct {
 	PluginRunner PluginRunner
 	// private fields
 	configDetailHash string
+	// renderedConfig is the config after the templates are applied, with the var references unresolved.
+	renderedConfig []byte
 
 	K8sLabelSet              map[string]struct{}
 	ContainerLabelSet        map[string]struct{}
 	EnvSet                   map[string]struct{}
 	CollectingContainersMeta bool
 	pluginID                 int32
+	// schedule is nil if the pipeline is always open.
+	schedule *pipelineSchedule
+	// spool is nil if the disk spool is disabled.
+	spool *diskSpool
 }
 
 func (p *LogstoreStatistics) Init(context pipeline.Context) {
@@ -120,6 +135,11 @@ func (p *LogstoreStatistics) Init(context pipeline.Context) {
 	p.FlushLogGroupMetric = helper.NewCounterMetricAndRegister(metricsRecord, "flush_loggroup")
 	p.FlushReadyMetric = helper.NewAverageMetricAndRegister(metricsRecord, "flush_ready")
 	p.FlushLatencyMetric = helper.NewLatencyMetricAndRegister(metricsRecord, "flush_latency")
+	p.FlushStaleMetric = helper.NewCounterMetricAndRegister(metricsRecord, "flush_stale_dropped")
+	p.ScheduleDeferredMetric = helper.NewCounterMetricAndRegister(metricsRecord, "schedule_deferred_events")
+	p.ScheduleDeferLatencyMetric = helper.NewLatencyMetricAndRegister(metricsRecord, "schedule_defer_latency")
+	p.SchedulePausedMetric = helper.NewCounterMetricAndRegister(metricsRecord, "schedule_paused_collects")
+	p.Outcomes.init(metricsRecord)
 }
 
 // Start initializes plugin instances in config and starts them.
@@ -338,7 +358,6 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 		ConfigNameWithSuffix: configName,
 		LogstoreKey:          logstoreKey,
 		Context:              contextImp,
-		configDetailHash:     fmt.Sprintf("%x", md5.Sum([]byte(jsonStr))), //nolint:gosec
 	}
 	contextImp.logstoreC = logstoreC
 
@@ -346,6 +365,22 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 	if err = json.Unmarshal([]byte(jsonStr), &plugins); err != nil {
 		return nil, err
 	}
+	if plugins, err = applyPipelineTemplates(plugins); err != nil {
+		return nil, err
+	}
+	// the var references are kept in the dumped config, for the resolved values may be secrets of the environment
+	if rendered, err := json.Marshal(plugins); err == nil { //nolint:govet
+		logstoreC.renderedConfig = rendered
+	}
+	if err = resolveConfigVars(plugins, config.LoongcollectorGlobalConfig.Vars); err != nil {
+		return nil, err
+	}
+	// the version is taken from the resolved config, so that the changes of the templates and the vars are recognized
+	resolved, err := json.Marshal(plugins)
+	if err != nil {
+		return nil, err
+	}
+	logstoreC.configDetailHash = configVersion(string(resolved))
 
 	logstoreC.Version = fetchPluginVersion(plugins)
 	if logstoreC.PluginRunner, err = initPluginRunner(logstoreC); err != nil {
@@ -429,6 +464,11 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 	if pluginConfigInterface, flag := plugins["global"]; flag {
 		pluginConfig := &config.GlobalConfig{}
 		*pluginConfig = config.LoongcollectorGlobalConfig
+		// the pipeline vars are merged into a copy, the agent vars are shared by all the pipelines.
+		pluginConfig.Vars = make(map[string]string, len(config.LoongcollectorGlobalConfig.Vars))
+		for k, v := range config.LoongcollectorGlobalConfig.Vars {
+			pluginConfig.Vars[k] = v
+		}
 		if flag {
 			configJSONStr, err := json.Marshal(pluginConfigInterface) //nolint:govet
 			if err != nil {
@@ -455,6 +495,13 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 		logger.Debug(contextImp.GetRuntimeContext(), "load plugin config", *logstoreC.GlobalConfig)
 	}
 
+	if err = checkDiskSpool(logstoreC, plugins["global"]); err != nil {
+		return nil, err
+	}
+	if logstoreC.schedule, err = newPipelineSchedule(&logstoreC.GlobalConfig.Schedule); err != nil {
+		return nil, err
+	}
+
 	logQueueSize := logstoreC.GlobalConfig.DefaultLogQueueSize
 	// Because the transferred data of the file MixProcessMode is quite large, we have to limit queue size to control memory usage here.
 	if checkMixProcessMode(plugins) == file {
@@ -616,9 +663,38 @@ func createLogstoreConfig(project string, logstore string, configName string, lo
 	if err = logstoreC.PluginRunner.AddDefaultFlusherIfEmpty(); err != nil {
 		return nil, err
 	}
+	if logstoreC.spool, err = newDiskSpool(logstoreC); err != nil {
+		return nil, err
+	}
 	return logstoreC, nil
 }
 
+// configVersion identifies the content of the config, it is not used for security.
+func configVersion(jsonStr string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(jsonStr))) //nolint:gosec
+}
+
+// resolvedConfigVersion returns the version of the config after the templates and the vars are applied as
+// createLogstoreConfig does, or the version of the raw config if they cannot be applied.
+func resolvedConfigVersion(jsonStr string) string {
+	var plugins = make(map[string]interface{})
+	if err := json.Unmarshal([]byte(jsonStr), &plugins); err != nil {
+		return configVersion(jsonStr)
+	}
+	plugins, err := applyPipelineTemplates(plugins)
+	if err != nil {
+		return configVersion(jsonStr)
+	}
+	if err = resolveConfigVars(plugins, config.LoongcollectorGlobalConfig.Vars); err != nil {
+		return configVersion(jsonStr)
+	}
+	resolved, err := json.Marshal(plugins)
+	if err != nil {
+		return configVersion(jsonStr)
+	}
+	return configVersion(string(resolved))
+}
+
 func fetchPluginVersion(config map[string]interface{}) ConfigVersion {
 	if v, ok := config["global"]; ok {
 		if global, ok := v.(map[string]interface{}); ok {
@@ -655,13 +731,16 @@ func LoadLogstoreConfig(project string, logstore string, configName string, logs
 		LogtailConfigLock.Lock()
 		delete(LogtailConfig, configName)
 		LogtailConfigLock.Unlock()
+		lifecycleEvents.recordConfigRemoved(configName)
 		return nil
 	}
 	logger.Info(context.Background(), "load config", configName, "logstore", logstore)
 	logstoreC, err := createLogstoreConfig(project, logstore, configName, logstoreKey, jsonStr)
 	if err != nil {
+		lifecycleEvents.recordConfigLoadFailed(configName, jsonStr, err)
 		return err
 	}
+	lifecycleEvents.recordConfigLoaded(logstoreC)
 	if logstoreC.PluginRunner.IsWithInputPlugin() {
 		ToStartPipelineConfigWithInput = logstoreC
 	} else {
@@ -695,6 +774,7 @@ func loadBuiltinConfig(name string, project string, logstore string,
 // @logstoreConfig: where to store the created metric plugin object.
 // It returns any error encountered.
 func loadMetric(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.MetricInputs[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		return fmt.Errorf("can't find plugin %s", pluginMeta.PluginType)
@@ -722,6 +802,7 @@ func loadMetric(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig,
 // @logstoreConfig: where to store the created service plugin object.
 // It returns any error encountered.
 func loadService(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.ServiceInputs[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		return fmt.Errorf("can't find plugin %s", pluginMeta.PluginType)
@@ -734,6 +815,7 @@ func loadService(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig
 }
 
 func loadProcessor(pluginMeta *pipeline.PluginMeta, priority int, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.Processors[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		logger.Error(logstoreConfig.Context.GetRuntimeContext(), "INVALID_PROCESSOR_TYPE", "invalid processor type, maybe type is wrong or logtail version is too old", pluginMeta.PluginType)
@@ -743,10 +825,25 @@ func loadProcessor(pluginMeta *pipeline.PluginMeta, priority int, logstoreConfig
 	if err = applyPluginConfig(processor, configInterface); err != nil {
 		return err
 	}
-	return logstoreConfig.PluginRunner.AddPlugin(pluginMeta, pluginProcessor, processor, map[string]interface{}{"priority": priority})
+	return logstoreConfig.PluginRunner.AddPlugin(pluginMeta, pluginProcessor, processor, map[string]interface{}{"priority": priority, "detail": configInterface})
+}
+
+// createProcessor creates a processor of the type from the plugin registry and applies the config to it, as
+// loadProcessor does. It is used to restart a crashed processor.
+func createProcessor(pluginType string, configInterface interface{}) (pipeline.Processor, error) {
+	creator, existFlag := pipeline.Processors[pluginType]
+	if !existFlag || creator == nil {
+		return nil, fmt.Errorf("invalid processor type %s", pluginType)
+	}
+	processor := creator()
+	if err := applyPluginConfig(processor, configInterface); err != nil {
+		return nil, err
+	}
+	return processor, nil
 }
 
 func loadAggregator(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.Aggregators[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		logger.Error(logstoreConfig.Context.GetRuntimeContext(), "INVALID_AGGREGATOR_TYPE", "invalid aggregator type, maybe type is wrong or logtail version is too old", pluginMeta.PluginType)
@@ -760,6 +857,7 @@ func loadAggregator(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreCon
 }
 
 func loadFlusher(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.Flushers[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		return fmt.Errorf("can't find plugin %s", pluginMeta.PluginType)
@@ -768,10 +866,15 @@ func loadFlusher(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig
 	if err = applyPluginConfig(flusher, configInterface); err != nil {
 		return err
 	}
-	return logstoreConfig.PluginRunner.AddPlugin(pluginMeta, pluginFlusher, flusher, map[string]interface{}{})
+	projection, err := newFieldProjection(configInterface)
+	if err != nil {
+		return err
+	}
+	return logstoreConfig.PluginRunner.AddPlugin(pluginMeta, pluginFlusher, flusher, map[string]interface{}{"projection": projection})
 }
 
 func loadExtension(pluginMeta *pipeline.PluginMeta, logstoreConfig *LogstoreConfig, configInterface interface{}) (err error) {
+	defer lifecycleEvents.recordPluginLoadError(logstoreConfig, pluginMeta, &err)
 	creator, existFlag := pipeline.Extensions[pluginMeta.PluginType]
 	if !existFlag || creator == nil {
 		return fmt.Errorf("can't find plugin %s", pluginMeta.PluginType)
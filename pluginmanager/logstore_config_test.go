@@ -120,6 +120,60 @@ func (s *logstoreConfigTestSuite) TestPluginGlobalConfig() {
 	s.Equal(config.PluginRunner.(*pluginv1Runner).FlusherPlugins[0].Interval, time.Duration(323)*time.Millisecond)
 }
 
+func (s *logstoreConfigTestSuite) TestPluginConfigVars() {
+	global_config.LoongcollectorGlobalConfig.Vars = map[string]string{"source": "agent_content", "keep": "true"}
+	defer func() {
+		global_config.LoongcollectorGlobalConfig.Vars = nil
+	}()
+	str := `{
+		"global": {
+			"Vars": {"source": "content"}
+		},
+		"processors" : [
+			{
+				"type" : "processor_regex",
+				"detail" : {
+					"SourceKey" : "${vars.source}",
+					"Regex" : "(\\d+) ${vars.suffix:-ms}",
+					"Keys" : ["${vars.key:-cost}"]
+				}
+			}
+		],
+		"flushers" : [
+			{
+				"type" : "flusher_stdout",
+				"detail" : {}
+			}
+		]
+	}`
+	s.NoError(LoadAndStartMockConfig("project", "logstore", "1", str), "load config fail")
+	config := LogtailConfig["1"]
+	reg, ok := config.PluginRunner.(*pluginv1Runner).ProcessorPlugins[0].Processor.(*regex.ProcessorRegex)
+	s.True(ok)
+	s.Equal("content", reg.SourceKey)
+	s.Equal("(\\d+) ms", reg.Regex)
+	s.Equal([]string{"cost"}, reg.Keys)
+	s.Contains(string(config.renderedConfig), `"SourceKey":"${vars.source}"`)
+	s.Equal(map[string]string{"source": "content", "keep": "true"}, config.GlobalConfig.Vars)
+	s.Equal(map[string]string{"source": "agent_content", "keep": "true"}, global_config.LoongcollectorGlobalConfig.Vars)
+
+	// the version changes with the resolved vars even if the config is the same
+	version := config.configDetailHash
+	global_config.LoongcollectorGlobalConfig.Vars = map[string]string{"key": "elapsed"}
+	s.NoError(LoadAndStartMockConfig("project", "logstore", "1", str), "load config fail")
+	s.NotEqual(version, LogtailConfig["1"].configDetailHash)
+
+	str = `{
+		"flushers" : [
+			{
+				"type" : "flusher_stdout",
+				"detail" : {"Tags": "${vars.undefined_var}"}
+			}
+		]
+	}`
+	s.Error(LoadAndStartMockConfig("project", "logstore", "2", str))
+}
+
 func (s *logstoreConfigTestSuite) TestLoadConfig() {
 	s.NoError(LoadAndStartMockConfig("project", "logstore", "1"))
 	s.NoError(LoadAndStartMockConfig("project", "logstore", "3"))
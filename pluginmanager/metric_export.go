@@ -17,6 +17,7 @@ import (
 	goruntimemetrics "runtime/metrics"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
@@ -58,6 +59,7 @@ func GetGoDirectMetrics() []map[string]string {
 	metrics = append(metrics, GetGoPluginMetrics()...)
 	// k8s meta metrics
 	metrics = append(metrics, k8smeta.GetMetaManagerMetrics()...)
+	metricHistory.record(time.Now(), metrics)
 	return metrics
 }
 
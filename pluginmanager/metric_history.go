@@ -0,0 +1,210 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const (
+	// metricHistoryRetention is how long the samples are kept. The resolution follows the
+	// interval the metrics are exported at, which is 60 seconds by default.
+	metricHistoryRetention = 30 * time.Minute
+	// metricHistoryCapacity bounds the samples kept in memory in case the metrics are
+	// exported more frequently, e.g. 30 minutes at 10 seconds resolution.
+	metricHistoryCapacity = 180
+)
+
+var metricHistory = newMetricHistoryRing(metricHistoryCapacity)
+
+type metricHistorySeries struct {
+	labels map[string]string
+	values map[string]float64
+}
+
+type metricHistorySample struct {
+	time   int64
+	series []metricHistorySeries
+}
+
+// metricHistoryRing keeps the recent exported records. Delta counters are reset when
+// they are exported, so the samples are taken from the export path rather than by a
+// separate ticker, which would steal the values from the exporter.
+type metricHistoryRing struct {
+	lock    sync.RWMutex
+	samples []metricHistorySample
+	next    int
+	full    bool
+}
+
+func newMetricHistoryRing(capacity int) *metricHistoryRing {
+	return &metricHistoryRing{samples: make([]metricHistorySample, capacity)}
+}
+
+func (h *metricHistoryRing) record(now time.Time, records []map[string]string) {
+	sample := metricHistorySample{time: now.Unix(), series: make([]metricHistorySeries, 0, len(records))}
+	for _, record := range records {
+		series := metricHistorySeries{labels: map[string]string{}, values: map[string]float64{}}
+		if labels, ok := record[pipeline.MetricLabelPrefix]; ok {
+			_ = json.Unmarshal([]byte(labels), &series.labels)
+		}
+		for _, kind := range []string{pipeline.MetricCounterPrefix, pipeline.MetricGaugePrefix} {
+			values := map[string]string{}
+			if err := json.Unmarshal([]byte(record[kind]), &values); err != nil {
+				continue
+			}
+			for name, value := range values {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					series.values[name] = v
+				}
+			}
+		}
+		if len(series.values) > 0 {
+			sample.series = append(sample.series, series)
+		}
+	}
+	h.lock.Lock()
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.lock.Unlock()
+}
+
+// snapshot returns the samples not older than since, from the oldest to the newest.
+func (h *metricHistoryRing) snapshot(since int64) []metricHistorySample {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	start, count := 0, h.next
+	if h.full {
+		start, count = h.next, len(h.samples)
+	}
+	samples := make([]metricHistorySample, 0, count)
+	for i := 0; i < count; i++ {
+		sample := h.samples[(start+i)%len(h.samples)]
+		if sample.time >= since {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// MetricHistorySeries is the time series of one metric record, each point is a pair of
+// the unix timestamp in seconds and the value.
+type MetricHistorySeries struct {
+	Labels  map[string]string       `json:"labels"`
+	Metrics map[string][][2]float64 `json:"metrics"`
+}
+
+// query groups the samples into series by the labels. The "name" filter selects the
+// metrics, and the labels filter selects the records whose labels contain all the pairs.
+func (h *metricHistoryRing) query(since int64, names map[string]struct{}, labelsFilter map[string]string) []*MetricHistorySeries {
+	index := make(map[string]*MetricHistorySeries)
+	result := make([]*MetricHistorySeries, 0)
+	for _, sample := range h.snapshot(since) {
+	nextSeries:
+		for _, series := range sample.series {
+			for k, v := range labelsFilter {
+				if series.labels[k] != v {
+					continue nextSeries
+				}
+			}
+			key := seriesKey(series.labels)
+			s, ok := index[key]
+			if !ok {
+				s = &MetricHistorySeries{Labels: series.labels, Metrics: map[string][][2]float64{}}
+			}
+			for name, value := range series.values {
+				if _, want := names[name]; len(names) > 0 && !want {
+					continue
+				}
+				s.Metrics[name] = append(s.Metrics[name], [2]float64{float64(sample.time), value})
+			}
+			if !ok && len(s.Metrics) > 0 {
+				index[key] = s
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+func seriesKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// HandleMetricsHistory dumps the recent history of the go plugin metrics as time series.
+// The "name" query parameter selects the metrics and could be repeated, "since" is the
+// unix timestamp in seconds of the oldest sample, and the other parameters are matched
+// against the labels, e.g. /metrics/history?name=proc_in_records_total&plugin_type=flusher_sls.
+func HandleMetricsHistory(res http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	var since int64
+	if s := query.Get("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			_, _ = res.Write([]byte("invalid since: " + s))
+			return
+		}
+	}
+	names := make(map[string]struct{})
+	for _, name := range query["name"] {
+		names[name] = struct{}{}
+	}
+	labelsFilter := make(map[string]string)
+	for k, v := range query {
+		if k != "name" && k != "since" && len(v) > 0 {
+			labelsFilter[k] = v[0]
+		}
+	}
+	if oldest := time.Now().Add(-metricHistoryRetention).Unix(); since < oldest {
+		since = oldest
+	}
+	jsonBytes, err := json.MarshalIndent(metricHistory.query(since, names, labelsFilter), "", "  ")
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		_, _ = res.Write([]byte(err.Error()))
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	if _, err = res.Write(jsonBytes); err != nil {
+		logger.Error(context.Background(), "write response err", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyRecord(pluginType, counter string) map[string]string {
+	return map[string]string{
+		"labels":   `{"metric_category":"plugin","plugin_type":"` + pluginType + `"}`,
+		"counters": `{"proc_in_records_total":"` + counter + `"}`,
+		"gauges":   `{}`,
+	}
+}
+
+func TestMetricHistoryRing(t *testing.T) {
+	h := newMetricHistoryRing(2)
+	h.record(time.Unix(10, 0), []map[string]string{historyRecord("flusher_sls", "1")})
+	h.record(time.Unix(20, 0), []map[string]string{historyRecord("flusher_sls", "2"), historyRecord("processor_regex", "5")})
+	h.record(time.Unix(30, 0), []map[string]string{historyRecord("flusher_sls", "3")})
+
+	series := h.query(0, nil, nil)
+	require.Len(t, series, 2)
+	assert.Equal(t, "flusher_sls", series[0].Labels["plugin_type"])
+	assert.Equal(t, [][2]float64{{20, 2}, {30, 3}}, series[0].Metrics["proc_in_records_total"])
+	assert.Equal(t, [][2]float64{{20, 5}}, series[1].Metrics["proc_in_records_total"])
+
+	series = h.query(25, nil, map[string]string{"plugin_type": "flusher_sls"})
+	require.Len(t, series, 1)
+	assert.Equal(t, [][2]float64{{30, 3}}, series[0].Metrics["proc_in_records_total"])
+
+	assert.Empty(t, h.query(0, map[string]struct{}{"missing": {}}, nil))
+}
+
+func TestHandleMetricsHistory(t *testing.T) {
+	old := metricHistory
+	metricHistory = newMetricHistoryRing(metricHistoryCapacity)
+	defer func() {
+		metricHistory = old
+	}()
+	now := time.Now()
+	metricHistory.record(now.Add(-time.Hour), []map[string]string{historyRecord("flusher_sls", "1")})
+	metricHistory.record(now, []map[string]string{historyRecord("flusher_sls", "2")})
+
+	res := httptest.NewRecorder()
+	HandleMetricsHistory(res, httptest.NewRequest(http.MethodGet, "/metrics/history?name=proc_in_records_total&plugin_type=flusher_sls", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	var series []MetricHistorySeries
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &series))
+	require.Len(t, series, 1)
+	assert.Equal(t, [][2]float64{{float64(now.Unix()), 2}}, series[0].Metrics["proc_in_records_total"])
+
+	res = httptest.NewRecorder()
+	HandleMetricsHistory(res, httptest.NewRequest(http.MethodGet, "/metrics/history?since=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+// panicIsolation recovers the panics of a plugin instance, so that a crashing plugin does not stop the routine it runs
+// in. After a crash the plugin is bypassed during the backoff and then restarted, and it is quarantined until the
+// pipeline is reloaded once it has crashed too many times.
+// Only the panics in the routine calling the plugin are recovered. A panic in a goroutine started by the plugin itself,
+// e.g. the server of a service input, cannot be recovered by go and still crashes the process.
+// It is only used by the routine running the plugin, so no lock is needed.
+type panicIsolation struct {
+	config     *config.PanicIsolationConfig
+	context    pipeline.Context
+	pluginMeta *pipeline.PluginMeta
+	// restart is called before the plugin is used again after a crash, nil if the plugin needs no restart.
+	restart func() error
+
+	crashes     int
+	backoff     time.Duration
+	restartAt   time.Time
+	crashed     bool
+	quarantined bool
+
+	panicsTotal     pipeline.CounterMetric
+	quarantinedFlag pipeline.GaugeMetric
+	now             func() time.Time
+}
+
+// newPanicIsolation returns nil if the isolation is disabled, and a nil isolation calls the plugin without recovering.
+func newPanicIsolation(context pipeline.Context, cfg *config.PanicIsolationConfig, pluginMeta *pipeline.PluginMeta,
+	metricRecord *pipeline.MetricsRecord, restart func() error) *panicIsolation {
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+	return &panicIsolation{
+		config:          cfg,
+		context:         context,
+		pluginMeta:      pluginMeta,
+		restart:         restart,
+		panicsTotal:     helper.NewCounterMetricAndRegister(metricRecord, helper.MetricPluginPanicsTotal),
+		quarantinedFlag: helper.NewGaugeMetricAndRegister(metricRecord, helper.MetricPluginQuarantined),
+		now:             time.Now,
+	}
+}
+
+// run calls the plugin in @fn and recovers its panic. False is returned if @fn panicked or is not called because the
+// plugin is backing off or quarantined, then the caller should pass the data through.
+func (g *panicIsolation) run(fn func()) (ok bool) {
+	if g == nil {
+		fn()
+		return true
+	}
+	if g.quarantined {
+		return false
+	}
+	if g.crashed {
+		if g.now().Before(g.restartAt) {
+			return false
+		}
+		if g.restart != nil {
+			if err := g.restart(); err != nil {
+				g.crash(err, "")
+				return false
+			}
+		}
+		g.crashed = false
+		logger.Info(g.context.GetRuntimeContext(), "plugin is restarted after crash", g.pluginMeta.PluginTypeWithID, "crashes", g.crashes)
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			trace := make([]byte, 2048)
+			trace = trace[:runtime.Stack(trace, false)]
+			g.crash(err, string(trace))
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// wait blocks until the plugin could be restarted, false is returned if it is quarantined or @cancel is closed.
+func (g *panicIsolation) wait(cancel <-chan struct{}) bool {
+	if g.quarantined {
+		return false
+	}
+	timer := time.NewTimer(g.restartAt.Sub(g.now()))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+func (g *panicIsolation) crash(err interface{}, stack string) {
+	g.crashes++
+	g.panicsTotal.Add(1)
+	logger.Error(g.context.GetRuntimeContext(), "PLUGIN_PANIC_ALARM", "plugin", g.pluginMeta.PluginTypeWithID, "crashed", err,
+		"crashes", g.crashes, "stack", stack)
+	if g.config.MaxCrashes > 0 && g.crashes >= g.config.MaxCrashes {
+		g.quarantined = true
+		g.quarantinedFlag.Set(1)
+		logger.Error(g.context.GetRuntimeContext(), "PLUGIN_QUARANTINE_ALARM", "plugin", g.pluginMeta.PluginTypeWithID,
+			"crashes", g.crashes, "action", "quarantined until the pipeline is reloaded")
+		return
+	}
+	if g.backoff == 0 {
+		g.backoff = time.Duration(g.config.InitialBackoffMs) * time.Millisecond
+	} else {
+		g.backoff *= 2
+	}
+	if maxBackoff := time.Duration(g.config.MaxBackoffMs) * time.Millisecond; maxBackoff > 0 && g.backoff > maxBackoff {
+		g.backoff = maxBackoff
+	}
+	g.crashed = true
+	g.restartAt = g.now().Add(g.backoff)
+}
+
+// trackingCollector records the events collected by a processor, so that only the rest of the batch is passed through
+// if the processor panics, and the collected events are not emitted twice.
+type trackingCollector struct {
+	pipeline.PipelineCollector
+	collected []models.PipelineEvent
+}
+
+func (c *trackingCollector) Collect(group *models.GroupInfo, events ...models.PipelineEvent) {
+	c.collected = append(c.collected, events...)
+	c.PipelineCollector.Collect(group, events...)
+}
+
+func (c *trackingCollector) CollectList(groups ...*models.PipelineGroupEvents) {
+	for _, group := range groups {
+		c.collected = append(c.collected, group.Events...)
+	}
+	c.PipelineCollector.CollectList(groups...)
+}
+
+// uncollected returns the events not collected yet in their order.
+func (c *trackingCollector) uncollected(events []models.PipelineEvent) []models.PipelineEvent {
+	if len(c.collected) == 0 {
+		return events
+	}
+	collected := make(map[interface{}]struct{}, len(c.collected))
+	for _, event := range c.collected {
+		collected[eventIdentity(event)] = struct{}{}
+	}
+	result := make([]models.PipelineEvent, 0, len(events))
+	for _, event := range events {
+		if _, ok := collected[eventIdentity(event)]; !ok {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+type trackingPipelineContext struct {
+	collector *trackingCollector
+}
+
+func (c *trackingPipelineContext) Collector() pipeline.PipelineCollector {
+	return c.collector
+}
+
+// eventIdentity returns a comparable identity of the event, the byte arrays are identified by their first byte.
+func eventIdentity(event models.PipelineEvent) interface{} {
+	if b, ok := event.(models.ByteArray); ok {
+		if len(b) == 0 {
+			return nil
+		}
+		return &b[0]
+	}
+	return event
+}
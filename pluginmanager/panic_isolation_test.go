@@ -0,0 +1,214 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// mockPanicProcessor panics when processing the logs if Panics is set.
+type mockPanicProcessor struct {
+	Panics bool
+	inits  int
+}
+
+func init() {
+	pipeline.Processors["processor_mock_panic"] = func() pipeline.Processor {
+		return &mockPanicProcessor{}
+	}
+}
+
+func (p *mockPanicProcessor) Init(pipeline.Context) error {
+	p.inits++
+	return nil
+}
+
+func (p *mockPanicProcessor) Description() string {
+	return "mock processor panicking"
+}
+
+func (p *mockPanicProcessor) ProcessLogs(logs []*protocol.Log) []*protocol.Log {
+	if p.Panics {
+		panic("mock panic")
+	}
+	return logs[:0]
+}
+
+// mockPanicProcessorV2 collects the first event and then panics.
+type mockPanicProcessorV2 struct{}
+
+func (p *mockPanicProcessorV2) Init(pipeline.Context) error {
+	return nil
+}
+
+func (p *mockPanicProcessorV2) Description() string {
+	return "mock v2 processor panicking"
+}
+
+func (p *mockPanicProcessorV2) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	context.Collector().Collect(in.Group, in.Events[0])
+	panic("mock panic")
+}
+
+func newPanicIsolationTestConfig(isolation config.PanicIsolationConfig) *LogstoreConfig {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	globalConfig.PanicIsolation = isolation
+	lc := &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}
+	lc.Statistics.Init(ctx)
+	return lc
+}
+
+func newPanicIsolationTestRunner(t *testing.T, isolation config.PanicIsolationConfig, processor pipeline.ProcessorV1, detail interface{}) *pluginv1Runner {
+	p := &pluginv1Runner{LogstoreConfig: newPanicIsolationTestConfig(isolation)}
+	meta := &pipeline.PluginMeta{PluginType: "processor_mock_panic", PluginTypeWithID: "processor_mock_panic/1", PluginID: "1"}
+	require.NoError(t, p.addProcessor(meta, processor, 0, detail))
+	return p
+}
+
+func TestPanicIsolation_Disabled(t *testing.T) {
+	p := newPanicIsolationTestRunner(t, config.LoongcollectorGlobalConfig.PanicIsolation, &mockPanicProcessor{Panics: true}, nil)
+	wrapper := p.ProcessorPlugins[0]
+	assert.Nil(t, wrapper.isolation)
+	assert.Panics(t, func() { wrapper.Process([]*protocol.Log{{}}) })
+}
+
+func TestPanicIsolation_RestartAndQuarantine(t *testing.T) {
+	processor := &mockPanicProcessor{Panics: true}
+	detail := map[string]interface{}{"Panics": true}
+	p := newPanicIsolationTestRunner(t, config.PanicIsolationConfig{
+		Enable:           true,
+		MaxCrashes:       3,
+		InitialBackoffMs: 1000,
+		MaxBackoffMs:     1500,
+	}, processor, detail)
+	wrapper := p.ProcessorPlugins[0]
+	isolation := wrapper.isolation
+	require.NotNil(t, isolation)
+	now := time.Now()
+	isolation.now = func() time.Time { return now }
+	logs := []*protocol.Log{{}}
+
+	// the logs are passed through when the processor panics and during the backoff
+	assert.Len(t, wrapper.Process(logs), 1)
+	assert.Equal(t, 1, isolation.crashes)
+	assert.Equal(t, time.Second, isolation.backoff)
+	assert.Len(t, wrapper.Process(logs), 1)
+	assert.Equal(t, 1, processor.inits)
+
+	// a fresh instance is created from the config after the backoff
+	now = now.Add(time.Second)
+	detail["Panics"] = false
+	assert.Len(t, wrapper.Process(logs), 0)
+	restarted := wrapper.Processor.(*mockPanicProcessor)
+	assert.NotSame(t, processor, restarted)
+	assert.Equal(t, 1, processor.inits)
+	assert.Equal(t, 1, restarted.inits)
+	assert.False(t, isolation.crashed)
+
+	restarted.Panics = true
+	assert.Len(t, wrapper.Process(logs), 1)
+	assert.Equal(t, 1500*time.Millisecond, isolation.backoff)
+	now = now.Add(1500 * time.Millisecond)
+	detail["Panics"] = true
+	assert.Len(t, wrapper.Process(logs), 1)
+	assert.True(t, isolation.quarantined)
+
+	// the quarantined processor is bypassed even if it would recover
+	detail["Panics"] = false
+	now = now.Add(time.Hour)
+	assert.Len(t, wrapper.Process(logs), 1)
+	assert.Equal(t, 3, isolation.crashes)
+}
+
+func TestPanicIsolation_ProcessV2(t *testing.T) {
+	p := &pluginv2Runner{LogstoreConfig: newPanicIsolationTestConfig(config.PanicIsolationConfig{Enable: true, InitialBackoffMs: 1000})}
+	meta := &pipeline.PluginMeta{PluginType: "processor_mock_panic_v2", PluginTypeWithID: "processor_mock_panic_v2/1", PluginID: "1"}
+	require.NoError(t, p.addProcessor(meta, &mockPanicProcessorV2{}, 0, nil))
+	wrapper := p.ProcessorPlugins[0]
+
+	events := []models.PipelineEvent{
+		models.NewSimpleLog([]byte("a"), nil, 0),
+		models.NewSimpleLog([]byte("b"), nil, 0),
+		models.NewSimpleLog([]byte("c"), nil, 0),
+	}
+	context := helper.NewGroupedPipelineConext()
+	wrapper.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: events}, context)
+	assert.Equal(t, 1, wrapper.isolation.crashes)
+
+	// the event collected before the panic is not passed through again
+	var bodies []string
+	for _, group := range context.Collector().ToArray() {
+		for _, event := range group.Events {
+			bodies = append(bodies, string(event.(*models.Log).GetBody()))
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, bodies)
+}
+
+func TestPanicIsolation_RunService(t *testing.T) {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	globalConfig.PanicIsolation = config.PanicIsolationConfig{Enable: true, MaxCrashes: 3, InitialBackoffMs: 1}
+	wrapper := &ServiceWrapper{InputWrapper: InputWrapper{Config: &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}}}
+	meta := &pipeline.PluginMeta{PluginType: "service_mock", PluginTypeWithID: "service_mock/1", PluginID: "1"}
+	wrapper.InitMetricRecord(meta)
+	wrapper.initPanicIsolation(meta)
+
+	// the service is started again after the panic until it returns
+	starts := 0
+	wrapper.runService(make(chan struct{}), func() error {
+		starts++
+		if starts < 2 {
+			panic("mock panic")
+		}
+		return errors.New("mock error")
+	})
+	assert.Equal(t, 2, starts)
+
+	// the service is not started again after it is quarantined
+	starts = 0
+	wrapper.runService(make(chan struct{}), func() error {
+		starts++
+		panic("mock panic")
+	})
+	assert.Equal(t, 2, starts)
+	assert.True(t, wrapper.isolation.quarantined)
+
+	// the service is not started again after the pipeline is cancelled
+	wrapper.initPanicIsolation(meta)
+	cancel := make(chan struct{})
+	close(cancel)
+	globalConfig.PanicIsolation.InitialBackoffMs = 60000
+	starts = 0
+	wrapper.runService(cancel, func() error {
+		starts++
+		panic("mock panic")
+	})
+	assert.Equal(t, 1, starts)
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+const (
+	scheduleModeBuffer = "buffer"
+	scheduleModePause  = "pause"
+
+	minutesPerDay = 24 * 60
+)
+
+// scheduleWindow is a time-of-day window in minutes, the end is exclusive and the window spans midnight if the end
+// is before the start.
+type scheduleWindow struct {
+	start int
+	end   int
+}
+
+func (w scheduleWindow) contains(minute int) bool {
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// pipelineSchedule decides whether the pipeline is in one of its time-of-day windows.
+// All methods of a nil pipelineSchedule treat the pipeline as always open.
+type pipelineSchedule struct {
+	windows  []scheduleWindow
+	location *time.Location
+	pause    bool
+	now      func() time.Time
+}
+
+// newPipelineSchedule returns nil if no window is configured.
+func newPipelineSchedule(cfg *config.ScheduleConfig) (*pipelineSchedule, error) {
+	if len(cfg.Windows) == 0 {
+		return nil, nil
+	}
+	s := &pipelineSchedule{
+		location: time.Local,
+		now:      time.Now,
+	}
+	switch cfg.Mode {
+	case "", scheduleModeBuffer:
+	case scheduleModePause:
+		s.pause = true
+	default:
+		return nil, fmt.Errorf("invalid schedule mode %q, must be %s or %s", cfg.Mode, scheduleModeBuffer, scheduleModePause)
+	}
+	if cfg.Location != "" {
+		location, err := time.LoadLocation(cfg.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule location %q: %v", cfg.Location, err)
+		}
+		s.location = location
+	}
+	for _, window := range cfg.Windows {
+		w, err := parseScheduleWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+// parseScheduleWindow parses the window formatted as "HH:MM-HH:MM", the end could be 24:00.
+func parseScheduleWindow(window string) (scheduleWindow, error) {
+	start, end, found := strings.Cut(window, "-")
+	if !found {
+		return scheduleWindow{}, fmt.Errorf("invalid schedule window %q, must be HH:MM-HH:MM", window)
+	}
+	var w scheduleWindow
+	var err error
+	if w.start, err = parseMinuteOfDay(strings.TrimSpace(start)); err != nil {
+		return scheduleWindow{}, fmt.Errorf("invalid schedule window %q: %v", window, err)
+	}
+	if w.end, err = parseMinuteOfDay(strings.TrimSpace(end)); err != nil {
+		return scheduleWindow{}, fmt.Errorf("invalid schedule window %q: %v", window, err)
+	}
+	if w.start == w.end || w.start == minutesPerDay {
+		return scheduleWindow{}, fmt.Errorf("invalid schedule window %q, the start must differ from the end", window)
+	}
+	if w.end == minutesPerDay {
+		w.end = 0
+		if w.start == 0 {
+			// 00:00-24:00 is the whole day
+			w.end = minutesPerDay
+		}
+	}
+	return w, nil
+}
+
+func parseMinuteOfDay(s string) (int, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	if hour < 0 || minute < 0 || minute >= 60 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// isOpen returns true if the pipeline is in one of the windows now.
+func (s *pipelineSchedule) isOpen() bool {
+	if s == nil {
+		return true
+	}
+	return s.openAt(s.now())
+}
+
+func (s *pipelineSchedule) openAt(t time.Time) bool {
+	t = t.In(s.location)
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range s.windows {
+		if w.contains(minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCollecting returns false if the metric inputs should skip the collection now.
+func (s *pipelineSchedule) isCollecting() bool {
+	return s == nil || !s.pause || s.isOpen()
+}
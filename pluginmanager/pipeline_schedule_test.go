@@ -0,0 +1,88 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+)
+
+func TestPipelineScheduleOpen(t *testing.T) {
+	s, err := newPipelineSchedule(&config.ScheduleConfig{
+		Windows:  []string{"22:00-06:00", "12:00-13:30"},
+		Location: "Asia/Shanghai",
+	})
+	require.NoError(t, err)
+	location, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, location)
+	}
+	assert.True(t, s.openAt(at(23, 0)))
+	assert.True(t, s.openAt(at(0, 0)))
+	assert.True(t, s.openAt(at(5, 59)))
+	assert.False(t, s.openAt(at(6, 0)), "the end is exclusive")
+	assert.False(t, s.openAt(at(11, 59)))
+	assert.True(t, s.openAt(at(13, 29)))
+	assert.False(t, s.openAt(at(13, 30)))
+	// 14:00 UTC is 22:00 in Shanghai
+	assert.True(t, s.openAt(time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)))
+	assert.True(t, s.isCollecting(), "the buffer mode always collects")
+
+	s.now = func() time.Time { return at(9, 0) }
+	assert.False(t, s.isOpen())
+	s.pause = true
+	assert.False(t, s.isCollecting())
+	s.now = func() time.Time { return at(22, 0) }
+	assert.True(t, s.isCollecting())
+
+	var always *pipelineSchedule
+	assert.True(t, always.isOpen())
+	assert.True(t, always.isCollecting())
+}
+
+func TestNewPipelineSchedule(t *testing.T) {
+	s, err := newPipelineSchedule(&config.ScheduleConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, s, "no window means always open")
+
+	s, err = newPipelineSchedule(&config.ScheduleConfig{Windows: []string{"00:00-24:00"}, Mode: scheduleModePause})
+	require.NoError(t, err)
+	assert.True(t, s.pause)
+	assert.True(t, s.openAt(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local)))
+
+	s, err = newPipelineSchedule(&config.ScheduleConfig{Windows: []string{"20:00-24:00"}})
+	require.NoError(t, err)
+	assert.True(t, s.openAt(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local)))
+	assert.False(t, s.openAt(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)))
+
+	for _, cfg := range []config.ScheduleConfig{
+		{Windows: []string{"22:00"}},
+		{Windows: []string{"22:00-22:00"}},
+		{Windows: []string{"7:00-08:00"}},
+		{Windows: []string{"24:00-01:00"}},
+		{Windows: []string{"23:60-01:00"}},
+		{Windows: []string{"01:00-02:00"}, Mode: "drop"},
+		{Windows: []string{"01:00-02:00"}, Location: "Nowhere/City"},
+	} {
+		_, err := newPipelineSchedule(&cfg)
+		assert.Error(t, err, cfg)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// processorV1Adapter runs a v1 processor in the v2 pipelines when global.AdaptV1Plugins is set.
+// The log events are converted to the v1 logs and back, and the other events are passed through.
+type processorV1Adapter struct {
+	processor pipeline.ProcessorV1
+}
+
+func (a *processorV1Adapter) Init(context pipeline.Context) error {
+	return a.processor.Init(context)
+}
+
+func (a *processorV1Adapter) Description() string {
+	return a.processor.Description()
+}
+
+func (a *processorV1Adapter) SetDropRecorder(recorder pipeline.DropRecorder) {
+	if reporter, ok := a.processor.(pipeline.DropReporter); ok {
+		reporter.SetDropRecorder(recorder)
+	}
+}
+
+// Process keeps the order of the events. The logs between the other events are processed as separate batches, so that
+// the dropped or split logs stay in place.
+func (a *processorV1Adapter) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	events := make([]models.PipelineEvent, 0, len(in.Events))
+	logs := make([]*protocol.Log, 0, len(in.Events))
+	processLogs := func() {
+		if len(logs) == 0 {
+			return
+		}
+		for _, log := range a.processor.ProcessLogs(logs) {
+			events = append(events, convertLogFromV1(log))
+		}
+		logs = make([]*protocol.Log, 0, len(in.Events))
+	}
+	for _, event := range in.Events {
+		if log, ok := event.(*models.Log); ok {
+			logs = append(logs, convertLogToV1(log))
+		} else {
+			processLogs()
+			events = append(events, event)
+		}
+	}
+	processLogs()
+	in.Events = events
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+// aggregatorV1Adapter runs a v1 aggregator in the v2 pipelines when global.AdaptV1Plugins is set.
+// The log groups flushed or pushed to the queue by the aggregator are collected as group events on GetResult.
+type aggregatorV1Adapter struct {
+	aggregator pipeline.AggregatorV1
+
+	lock sync.Mutex
+	// queued are the log groups pushed to the queue by the aggregator between two GetResult
+	queued []*protocol.LogGroup
+}
+
+// Init passes the adapter as the queue, the log groups pushed to the wrapper of v2 aggregator would be lost.
+func (a *aggregatorV1Adapter) Init(context pipeline.Context, _ pipeline.LogGroupQueue) (int, error) {
+	return a.aggregator.Init(context, a)
+}
+
+func (a *aggregatorV1Adapter) Description() string {
+	return a.aggregator.Description()
+}
+
+func (a *aggregatorV1Adapter) Reset() {
+	a.aggregator.Reset()
+}
+
+func (a *aggregatorV1Adapter) Record(in *models.PipelineGroupEvents, _ pipeline.PipelineContext) error {
+	ctx := make(map[string]interface{})
+	for k, v := range in.Group.GetMetadata().Iterator() {
+		ctx[k] = v
+	}
+	if in.Group.GetTags().Len() > 0 {
+		tags := make([]*protocol.LogTag, 0, in.Group.GetTags().Len())
+		for _, tag := range in.Group.GetTags().SortTo(nil) {
+			tags = append(tags, &protocol.LogTag{Key: tag.Key, Value: tag.Value})
+		}
+		ctx[ctxKeyTags] = tags
+	}
+	for _, event := range in.Events {
+		log, ok := event.(*models.Log)
+		if !ok {
+			continue
+		}
+		// same as the v1 pipelines, the logs without content are skipped and the logs without time are stamped now
+		v1Log := convertLogToV1(log)
+		if len(v1Log.Contents) == 0 {
+			continue
+		}
+		if v1Log.Time == 0 {
+			protocol.SetLogTime(v1Log, uint32(time.Now().Unix()))
+		}
+		if err := a.aggregator.Add(v1Log, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *aggregatorV1Adapter) GetResult(context pipeline.PipelineContext) error {
+	a.lock.Lock()
+	logGroups := a.queued
+	a.queued = nil
+	a.lock.Unlock()
+	logGroups = append(logGroups, a.aggregator.Flush()...)
+	for _, logGroup := range logGroups {
+		if len(logGroup.Logs) == 0 {
+			continue
+		}
+		metadata := models.NewMetadata()
+		if logGroup.Source != "" {
+			metadata.Add(ctxKeySource, logGroup.Source)
+		}
+		if logGroup.Topic != "" {
+			metadata.Add(ctxKeyTopic, logGroup.Topic)
+		}
+		tags := models.NewTags()
+		for _, tag := range logGroup.LogTags {
+			tags.Add(tag.Key, tag.Value)
+		}
+		events := make([]models.PipelineEvent, 0, len(logGroup.Logs))
+		for _, log := range logGroup.Logs {
+			events = append(events, convertLogFromV1(log))
+		}
+		context.Collector().Collect(models.NewGroup(metadata, tags), events...)
+	}
+	return nil
+}
+
+// Add is called by the v1 aggregator to push the full log groups before GetResult.
+func (a *aggregatorV1Adapter) Add(logGroup *protocol.LogGroup) error {
+	if len(logGroup.Logs) == 0 {
+		return nil
+	}
+	a.lock.Lock()
+	a.queued = append(a.queued, logGroup)
+	a.lock.Unlock()
+	return nil
+}
+
+func (a *aggregatorV1Adapter) AddWithWait(logGroup *protocol.LogGroup, _ time.Duration) error {
+	return a.Add(logGroup)
+}
+
+// convertLogToV1 converts the contents of the log event to the fields and its tags to the __tag__: prefixed fields,
+// the body is the content field in both versions. The name, level and trace of the event are not kept.
+func convertLogToV1(in *models.Log) *protocol.Log {
+	out := &protocol.Log{}
+	for _, content := range in.GetIndices().SortTo(nil) {
+		out.Contents = append(out.Contents, &protocol.Log_Content{Key: content.Key, Value: contentValueToString(content.Value)})
+	}
+	for _, tag := range in.GetTags().SortTo(nil) {
+		out.Contents = append(out.Contents, &protocol.Log_Content{Key: tagPrefix + tag.Key, Value: tag.Value})
+	}
+	if in.Offset > 0 {
+		out.Contents = append(out.Contents, &protocol.Log_Content{Key: fileOffsetKey, Value: strconv.FormatUint(in.Offset, 10)})
+	}
+	if in.Timestamp > 0 {
+		protocol.SetLogTimeWithNano(out, uint32(in.Timestamp/uint64(time.Second)), uint32(in.Timestamp%uint64(time.Second)))
+	}
+	return out
+}
+
+// convertLogFromV1 is the reverse of convertLogToV1.
+func convertLogFromV1(in *protocol.Log) *models.Log {
+	out := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	for _, content := range in.Contents {
+		switch {
+		case content.Key == fileOffsetKey:
+			if offset, err := strconv.ParseUint(content.Value, 10, 64); err == nil {
+				out.Offset = offset
+			}
+		case strings.HasPrefix(content.Key, tagPrefix):
+			out.Tags.Add(content.Key[len(tagPrefix):], content.Value)
+		default:
+			out.Contents.Add(content.Key, content.Value)
+		}
+	}
+	if in.Time > 0 {
+		out.Timestamp = uint64(in.Time) * uint64(time.Second)
+		if in.TimeNs != nil {
+			out.Timestamp += uint64(*in.TimeNs)
+		}
+	}
+	return out
+}
+
+func contentValueToString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
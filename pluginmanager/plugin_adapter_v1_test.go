@@ -0,0 +1,204 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// mockV1Processor drops the logs with the drop field and marks the others.
+type mockV1Processor struct{}
+
+func (p *mockV1Processor) Init(pipeline.Context) error {
+	return nil
+}
+
+func (p *mockV1Processor) Description() string {
+	return "mock v1 processor"
+}
+
+func (p *mockV1Processor) ProcessLogs(logs []*protocol.Log) []*protocol.Log {
+	out := logs[:0]
+	for _, log := range logs {
+		if log.Contents[0].Key == "drop" {
+			continue
+		}
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "processed", Value: "v1"})
+		out = append(out, log)
+	}
+	return out
+}
+
+// mockV1Aggregator pushes every 2 logs to the queue and flushes the rest.
+type mockV1Aggregator struct {
+	queue pipeline.LogGroupQueue
+	group *protocol.LogGroup
+}
+
+func (a *mockV1Aggregator) Init(_ pipeline.Context, queue pipeline.LogGroupQueue) (int, error) {
+	a.queue = queue
+	a.group = &protocol.LogGroup{}
+	return 0, nil
+}
+
+func (a *mockV1Aggregator) Description() string {
+	return "mock v1 aggregator"
+}
+
+func (a *mockV1Aggregator) Reset() {}
+
+func (a *mockV1Aggregator) Add(log *protocol.Log, ctx map[string]interface{}) error {
+	a.group.Topic, _ = ctx[ctxKeyTopic].(string)
+	if tags, ok := ctx[ctxKeyTags].([]*protocol.LogTag); ok {
+		a.group.LogTags = tags
+	}
+	a.group.Logs = append(a.group.Logs, log)
+	if len(a.group.Logs) == 2 {
+		_ = a.queue.Add(a.group)
+		a.group = &protocol.LogGroup{}
+	}
+	return nil
+}
+
+func (a *mockV1Aggregator) Flush() []*protocol.LogGroup {
+	group := a.group
+	a.group = &protocol.LogGroup{}
+	return []*protocol.LogGroup{group}
+}
+
+func newAdapterTestLog(key, value string) *models.Log {
+	tags := models.NewTags()
+	tags.Add("path", "/var/log/a.log")
+	log := models.NewLog("", nil, "", "", "", tags, uint64(time.Unix(1700000000, 5).UnixNano()))
+	log.GetIndices().Add(key, value)
+	return log
+}
+
+func TestConvertLogV1RoundTrip(t *testing.T) {
+	log := newAdapterTestLog("content", "hello")
+	log.SetBody([]byte("body"))
+	log.GetIndices().Add("count", 3)
+	log.Offset = 100
+
+	v1Log := convertLogToV1(log)
+	assert.Equal(t, uint32(1700000000), v1Log.Time)
+	assert.Equal(t, uint32(5), *v1Log.TimeNs)
+	contents := make(map[string]string)
+	for _, content := range v1Log.Contents {
+		contents[content.Key] = content.Value
+	}
+	assert.Equal(t, map[string]string{
+		"content":                 "body",
+		"count":                   "3",
+		"__tag__:path":            "/var/log/a.log",
+		"__tag__:__file_offset__": "100",
+	}, contents)
+
+	back := convertLogFromV1(v1Log)
+	assert.Equal(t, log.Timestamp, back.Timestamp)
+	assert.Equal(t, uint64(100), back.Offset)
+	assert.Equal(t, "body", string(back.GetBody()))
+	assert.Equal(t, "3", back.GetIndices().Get("count"))
+	assert.Equal(t, "/var/log/a.log", back.GetTags().Get("path"))
+}
+
+func TestProcessorV1Adapter(t *testing.T) {
+	adapter := &processorV1Adapter{processor: &mockV1Processor{}}
+	metric := models.NewSingleValueMetric("m", models.MetricTypeGauge, models.NewTags(), 0, 1)
+	group := &models.PipelineGroupEvents{
+		Group: models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{
+			newAdapterTestLog("content", "a"), metric, newAdapterTestLog("drop", "b"), newAdapterTestLog("content", "c"),
+		},
+	}
+	context := helper.NewGroupedPipelineConext()
+	adapter.Process(group, context)
+
+	// the other events are passed through in place of the processed logs
+	require.Len(t, group.Events, 3)
+	log := group.Events[0].(*models.Log)
+	assert.Equal(t, "a", string(log.GetBody()))
+	assert.Equal(t, "v1", log.GetIndices().Get("processed"))
+	assert.Equal(t, "/var/log/a.log", log.GetTags().Get("path"))
+	assert.Equal(t, metric, group.Events[1])
+	assert.Equal(t, "c", string(group.Events[2].(*models.Log).GetBody()))
+	result := context.Collector().ToArray()
+	require.Len(t, result, 1)
+	assert.Len(t, result[0].Events, 3)
+}
+
+func TestAggregatorV1Adapter(t *testing.T) {
+	adapter := &aggregatorV1Adapter{aggregator: &mockV1Aggregator{}}
+	_, err := adapter.Init(nil, nil)
+	require.NoError(t, err)
+	groupTags := models.NewTags()
+	groupTags.Add("host", "node-1")
+	metadata := models.NewMetadata()
+	metadata.Add(ctxKeyTopic, "topic-a")
+	group := &models.PipelineGroupEvents{
+		Group:  models.NewGroup(metadata, groupTags),
+		Events: []models.PipelineEvent{newAdapterTestLog("content", "1"), newAdapterTestLog("content", "2"), newAdapterTestLog("content", "3")},
+	}
+	context := helper.NewGroupedPipelineConext()
+	require.NoError(t, adapter.Record(group, context))
+	require.NoError(t, adapter.GetResult(context))
+
+	// one group is pushed to the queue and the other is flushed
+	result := context.Collector().ToArray()
+	require.Len(t, result, 2)
+	sort.Slice(result, func(i, j int) bool {
+		return len(result[i].Events) > len(result[j].Events)
+	})
+	assert.Len(t, result[0].Events, 2)
+	assert.Len(t, result[1].Events, 1)
+	assert.Equal(t, "3", string(result[1].Events[0].(*models.Log).GetBody()))
+	assert.Equal(t, "topic-a", result[0].Group.GetMetadata().Get(ctxKeyTopic))
+	assert.Equal(t, "node-1", result[0].Group.GetTags().Get("host"))
+
+	require.NoError(t, adapter.GetResult(context))
+	assert.Empty(t, context.Collector().ToArray())
+}
+
+func TestAddV1PluginToV2Runner(t *testing.T) {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	lc := &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}
+	lc.Statistics.Init(ctx)
+	runner := &pluginv2Runner{LogstoreConfig: lc, FlushOutStore: NewFlushOutStore[models.PipelineGroupEvents]()}
+	require.NoError(t, runner.Init(1, 1))
+	processorMeta := &pipeline.PluginMeta{PluginType: "processor_mock", PluginTypeWithID: "processor_mock/1", PluginID: "1"}
+	aggregatorMeta := &pipeline.PluginMeta{PluginType: "aggregator_mock", PluginTypeWithID: "aggregator_mock/2", PluginID: "2"}
+
+	assert.Error(t, runner.AddPlugin(processorMeta, pluginProcessor, &mockV1Processor{}, map[string]interface{}{"priority": 0}))
+	assert.Error(t, runner.AddPlugin(aggregatorMeta, pluginAggregator, &mockV1Aggregator{}, map[string]interface{}{}))
+
+	globalConfig.AdaptV1Plugins = true
+	require.NoError(t, runner.AddPlugin(processorMeta, pluginProcessor, &mockV1Processor{}, map[string]interface{}{"priority": 0}))
+	require.NoError(t, runner.AddPlugin(aggregatorMeta, pluginAggregator, &mockV1Aggregator{}, map[string]interface{}{}))
+	assert.Len(t, runner.ProcessorPlugins, 1)
+	assert.Len(t, runner.AggregatorPlugins, 1)
+}
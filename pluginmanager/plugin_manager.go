@@ -221,6 +221,10 @@ func Stop(configName string, removedFlag bool) error {
 		if !removedFlag {
 			LastUnsendBuffer[configName] = config.PluginRunner
 		}
+		lifecycleEvents.recordPipeline(lifecyclePipelineStopped, config)
+		if removedFlag {
+			lifecycleEvents.recordConfigRemoved(configName)
+		}
 		logger.Info(config.Context.GetRuntimeContext(), "Stop config now", configName)
 		LogtailConfigLock.Lock()
 		delete(LogtailConfig, configName)
@@ -236,6 +240,7 @@ func Start(configName string) error {
 	defer panicRecover("Run plugin")
 	if ToStartPipelineConfigWithInput != nil && ToStartPipelineConfigWithInput.ConfigNameWithSuffix == configName {
 		ToStartPipelineConfigWithInput.Start()
+		lifecycleEvents.recordPipeline(lifecyclePipelineStarted, ToStartPipelineConfigWithInput)
 		LogtailConfigLock.Lock()
 		LogtailConfig[ToStartPipelineConfigWithInput.ConfigNameWithSuffix] = ToStartPipelineConfigWithInput
 		LogtailConfigLock.Unlock()
@@ -243,6 +248,7 @@ func Start(configName string) error {
 		return nil
 	} else if ToStartPipelineConfigWithoutInput != nil && ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix == configName {
 		ToStartPipelineConfigWithoutInput.Start()
+		lifecycleEvents.recordPipeline(lifecyclePipelineStarted, ToStartPipelineConfigWithoutInput)
 		LogtailConfigLock.Lock()
 		LogtailConfig[ToStartPipelineConfigWithoutInput.ConfigNameWithSuffix] = ToStartPipelineConfigWithoutInput
 		LogtailConfigLock.Unlock()
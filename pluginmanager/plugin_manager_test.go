@@ -28,6 +28,7 @@ import (
 
 	// dependency packages
 	_ "github.com/alibaba/ilogtail/plugins/aggregator"
+	_ "github.com/alibaba/ilogtail/plugins/aggregator/rollup"
 	"github.com/alibaba/ilogtail/plugins/flusher/checker"
 	_ "github.com/alibaba/ilogtail/plugins/flusher/sls"
 	_ "github.com/alibaba/ilogtail/plugins/flusher/statistics"
@@ -87,6 +88,53 @@ func (s *managerTestSuite) TestPluginManager() {
 	}
 }
 
+func (s *managerTestSuite) TestMultiIntervalAggregator() {
+	configStr := `
+	{
+		"inputs": [
+			{
+				"type": "service_mock",
+				"detail": {
+					"LogsPerSecond": 100,
+					"MaxLogCount": 100,
+					"Fields": {
+						"level": "info"
+					}
+				}
+			}
+		],
+		"aggregators": [
+			{
+				"type": "aggregator_rollup",
+				"detail": {
+					"GroupKeys": ["level"],
+					"RawIntervalMs": 100,
+					"RollupIntervalMs": 600000
+				}
+			}
+		],
+		"flushers": [
+			{
+				"type": "flusher_checker"
+			}
+		]
+	}`
+	s.NoError(LoadAndStartMockConfig("test_prj", "test_logstore", "test_rollup_config", configStr))
+	config, ok := LogtailConfig["test_rollup_config"]
+	s.True(ok)
+	c, ok := GetConfigFlushers(config.PluginRunner)[0].(*checker.FlusherChecker)
+	s.True(ok)
+
+	// the raw logs are flushed with their own interval long before the rollup interval.
+	s.Eventually(func() bool { return c.GetLogCount() == 100 }, 5*time.Second, 50*time.Millisecond)
+	s.Error(c.CheckKeyValueAny("count", "100"))
+
+	// the rollup is flushed when stopping.
+	s.NoError(Stop("test_rollup_config", false))
+	s.Equal(101, c.GetLogCount())
+	s.NoError(c.CheckKeyValueAny("count", "100"))
+}
+
 func GetTestConfig(configName string) string {
 	fileName := "./test_config/" + configName + ".json"
 	byteStr, err := os.ReadFile(fileName)
@@ -71,10 +71,17 @@ func (p *timerRunner) execTask(task func(state interface{}) error) {
 }
 
 func flushOutStore[T FlushData, F FlusherWrapperInterface](lc *LogstoreConfig, store *FlushOutStore[T], flushers []F, flushFunc func(*LogstoreConfig, F, *FlushOutStore[T]) error) bool {
+	eventCount := countFlushEvents(store.Get())
+	waited, failed := false, false
 	for _, flusher := range flushers {
 		for waitCount := 0; !flusher.IsReady(lc.ProjectName, lc.LogstoreName, lc.LogstoreKey); waitCount++ {
+			waited = true
 			if waitCount > maxFlushOutTime*100 {
+				if spillFlushOutStore(lc, store) {
+					return false
+				}
 				logger.Error(lc.Context.GetRuntimeContext(), "DROP_DATA_ALARM", "flush out data timeout, drop data", store.Len())
+				lc.Statistics.Outcomes.drop(eventCount)
 				return false
 			}
 			lc.Statistics.FlushReadyMetric.Add(0)
@@ -84,10 +91,12 @@ func flushOutStore[T FlushData, F FlusherWrapperInterface](lc *LogstoreConfig, s
 		startTime := time.Now()
 		err := flushFunc(lc, flusher, store)
 		if err != nil {
+			failed = true
 			logger.Error(lc.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error", lc.ProjectName, lc.LogstoreName, err)
 		}
 		lc.Statistics.FlushLatencyMetric.Observe(float64(time.Since(startTime).Nanoseconds()))
 	}
+	lc.Statistics.Outcomes.flushed(eventCount, waited, failed)
 	store.Reset()
 	return true
 }
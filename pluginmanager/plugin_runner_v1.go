@@ -44,6 +44,8 @@ type pluginv1Runner struct {
 	ProcessControl   *pipeline.AsyncControl
 	AggregateControl *pipeline.AsyncControl
 	FlushControl     *pipeline.AsyncControl
+
+	sequence *sequencer
 }
 
 func (p *pluginv1Runner) Init(inputQueueSize int, flushQueueSize int) error {
@@ -58,6 +60,7 @@ func (p *pluginv1Runner) Init(inputQueueSize int, flushQueueSize int) error {
 	p.FlusherPlugins = make([]*FlusherWrapperV1, 0)
 	p.ExtensionPlugins = make(map[string]pipeline.Extension, 0)
 	p.LogsChan = make(chan *pipeline.LogWithContext, inputQueueSize)
+	p.sequence = newSequencer(&p.LogstoreConfig.GlobalConfig.Sequence, p.LogstoreConfig.ConfigName)
 	p.LogGroupsChan = make(chan *protocol.LogGroup, helper.Max(flushQueueSize, p.FlushOutStore.Len()))
 	p.FlushOutStore.Write(p.LogGroupsChan)
 	return nil
@@ -98,7 +101,7 @@ func (p *pluginv1Runner) AddPlugin(pluginMeta *pipeline.PluginMeta, category plu
 		}
 	case pluginProcessor:
 		if processor, ok := plugin.(pipeline.ProcessorV1); ok {
-			return p.addProcessor(pluginMeta, processor, config["priority"].(int))
+			return p.addProcessor(pluginMeta, processor, config["priority"].(int), config["detail"])
 		}
 	case pluginAggregator:
 		if aggregator, ok := plugin.(pipeline.AggregatorV1); ok {
@@ -106,7 +109,8 @@ func (p *pluginv1Runner) AddPlugin(pluginMeta *pipeline.PluginMeta, category plu
 		}
 	case pluginFlusher:
 		if flusher, ok := plugin.(pipeline.FlusherV1); ok {
-			return p.addFlusher(pluginMeta, flusher)
+			projection, _ := config["projection"].(*fieldProjection)
+			return p.addFlusher(pluginMeta, flusher, projection)
 		}
 	case pluginExtension:
 		if extension, ok := plugin.(pipeline.Extension); ok {
@@ -148,6 +152,7 @@ func (p *pluginv1Runner) addMetricInput(pluginMeta *pipeline.PluginMeta, input p
 	wrapper.Input = input
 
 	wrapper.LogsChan = p.LogsChan
+	wrapper.Sequence = p.sequence
 	wrapper.LatencyMetric = p.LogstoreConfig.Statistics.CollecLatencytMetric
 	p.MetricPlugins = append(p.MetricPlugins, &wrapper)
 	return wrapper.Init(pluginMeta, inputInterval)
@@ -158,14 +163,16 @@ func (p *pluginv1Runner) addServiceInput(pluginMeta *pipeline.PluginMeta, input
 	wrapper.Config = p.LogstoreConfig
 	wrapper.Input = input
 	wrapper.LogsChan = p.LogsChan
+	wrapper.Sequence = p.sequence
 	p.ServicePlugins = append(p.ServicePlugins, &wrapper)
 	return wrapper.Init(pluginMeta)
 }
 
-func (p *pluginv1Runner) addProcessor(pluginMeta *pipeline.PluginMeta, processor pipeline.ProcessorV1, priority int) error {
+func (p *pluginv1Runner) addProcessor(pluginMeta *pipeline.PluginMeta, processor pipeline.ProcessorV1, priority int, detail interface{}) error {
 	var wrapper ProcessorWrapperV1
 	wrapper.Config = p.LogstoreConfig
 	wrapper.Processor = processor
+	wrapper.detail = detail
 	wrapper.LogsChan = p.LogsChan
 	wrapper.Priority = priority
 	p.ProcessorPlugins = append(p.ProcessorPlugins, &wrapper)
@@ -181,10 +188,11 @@ func (p *pluginv1Runner) addAggregator(pluginMeta *pipeline.PluginMeta, aggregat
 	return wrapper.Init(pluginMeta)
 }
 
-func (p *pluginv1Runner) addFlusher(pluginMeta *pipeline.PluginMeta, flusher pipeline.FlusherV1) error {
+func (p *pluginv1Runner) addFlusher(pluginMeta *pipeline.PluginMeta, flusher pipeline.FlusherV1, projection *fieldProjection) error {
 	var wrapper FlusherWrapperV1
 	wrapper.Config = p.LogstoreConfig
 	wrapper.Flusher = flusher
+	wrapper.projection = projection
 	wrapper.LogGroupsChan = p.LogGroupsChan
 	wrapper.Interval = time.Millisecond * time.Duration(p.LogstoreConfig.GlobalConfig.FlushIntervalMs)
 	p.FlusherPlugins = append(p.FlusherPlugins, &wrapper)
@@ -217,6 +225,10 @@ func (p *pluginv1Runner) runMetricInput(async *pipeline.AsyncControl) {
 		}
 		async.Run(func(ac *pipeline.AsyncControl) {
 			runner.Run(func(state interface{}) error {
+				if !p.LogstoreConfig.schedule.isCollecting() {
+					p.LogstoreConfig.Statistics.SchedulePausedMetric.Add(1)
+					return nil
+				}
 				return m.Input.Collect(m)
 			}, ac)
 		})
@@ -246,6 +258,14 @@ func (p *pluginv1Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 	if globalConfig := p.LogstoreConfig.GlobalConfig; globalConfig.EnableProcessorTag {
 		processorTag = NewProcessorTag(globalConfig.PipelineMetaTagKey, globalConfig.AppendingAllEnvMetaTag, globalConfig.AgentEnvMetaTagKey)
 	}
+	processors := make([]*ProcessorWrapper, 0, len(p.ProcessorPlugins))
+	for _, processor := range p.ProcessorPlugins {
+		processors = append(processors, &processor.ProcessorWrapper)
+	}
+	guardrail := newDropGuardrail(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.DropGuardrail, processors)
+	profiler := newProcessingProfiler(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.ProcessingProfile, processors)
+	latencyTracking := p.LogstoreConfig.GlobalConfig.LatencyTracking.Enable
+	outcomes := &p.LogstoreConfig.Statistics.Outcomes
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -253,21 +273,38 @@ func (p *pluginv1Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 				return
 			}
 		case logCtx = <-p.LogsChan:
+			readTime := monotonicNow()
 			if processorTag != nil {
 				processorTag.ProcessV1(logCtx)
 			}
 			logs := []*protocol.Log{logCtx.Log}
 			p.LogstoreConfig.Statistics.RawLogMetric.Add(int64(len(logs)))
-			for _, processor := range p.ProcessorPlugins {
+			outcomes.receive(len(logs))
+			guardrail.receive(len(logs))
+			profiler.sample()
+			for i, processor := range p.ProcessorPlugins {
+				inLen := len(logs)
+				start := profiler.begin()
 				logs = processor.Process(logs)
+				profiler.end(i, start)
+				guardrail.settle(i, inLen, len(logs))
 				if len(logs) == 0 {
 					break
 				}
 			}
+			guardrail.check()
+			profiler.check()
+			outcomes.processed(1, len(logs))
+			if latencyTracking {
+				stampReadTimeV1(logs, readTime)
+			}
 			nowTime := time.Now()
 
 			if len(logs) > 0 {
 				p.LogstoreConfig.Statistics.SplitLogMetric.Add(int64(len(logs)))
+				emptyLogs := countEmptyLogs(logs)
+				outcomes.drop(emptyLogs)
+				outcomes.aggregate(len(logs) - emptyLogs)
 				for _, aggregator := range p.AggregatorPlugins {
 					for _, l := range logs {
 						if len(l.Contents) == 0 {
@@ -301,18 +338,35 @@ func (p *pluginv1Runner) runAggregator() {
 	p.AggregateControl.Reset()
 	for _, aggregator := range p.AggregatorPlugins {
 		a := aggregator
-		p.AggregateControl.Run(a.Run)
+		if len(a.OutputClasses) == 0 {
+			p.AggregateControl.Run(a.Run)
+			continue
+		}
+		for _, class := range a.OutputClasses {
+			c := class
+			p.AggregateControl.Run(func(cc *pipeline.AsyncControl) {
+				a.RunClass(c, cc)
+			})
+		}
 	}
 }
 
 func (p *pluginv1Runner) runFlusher() {
 	p.FlushControl.Reset()
 	p.FlushControl.Run(p.runFlusherInternal)
+	if spool := p.LogstoreConfig.spool; spool != nil {
+		p.FlushControl.Run(func(cc *pipeline.AsyncControl) {
+			spool.replay(p.LogstoreConfig, p.LogGroupsChan, cc)
+		})
+	}
 }
 
 func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 	defer panicRecover(p.LogstoreConfig.ConfigName)
 	var logGroup *protocol.LogGroup
+	sequence := p.sequence
+	latencyTracking := p.LogstoreConfig.GlobalConfig.LatencyTracking.Enable
+	outcomes := &p.LogstoreConfig.Statistics.Outcomes
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -331,6 +385,15 @@ func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 			for i := 1; i < listLen; i++ {
 				logGroups[i] = <-p.LogGroupsChan
 			}
+			if ttl := p.LogstoreConfig.GlobalConfig.EventTTLSec; ttl > 0 {
+				var dropped int
+				logGroups, dropped = dropStaleLogGroups(logGroups, time.Now().Add(-time.Duration(ttl)*time.Second))
+				p.LogstoreConfig.Statistics.FlushStaleMetric.Add(int64(dropped))
+				outcomes.drop(dropped)
+				if len(logGroups) == 0 {
+					continue
+				}
+			}
 			p.LogstoreConfig.Statistics.FlushLogGroupMetric.Add(int64(len(logGroups)))
 
 			for _, logGroup := range logGroups {
@@ -340,20 +403,35 @@ func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 				p.LogstoreConfig.Statistics.FlushLogMetric.Add(int64(len(logGroup.Logs)))
 				logGroup.Source = util.GetIPAddress()
 			}
+			sequence.orderLogGroups(logGroups)
+			var readTimes []int64
+			if latencyTracking {
+				readTimes = takeReadTimesV1(logGroups)
+			}
 
 			// Flush LogGroups to all flushers.
 			// Note: multiple flushers is unrecommended, because all flushers will
 			//   be blocked if one of them is unready.
-			for {
-				allReady := true
+			// The data is held here outside the schedule windows, so the upstream is backpressured.
+			var deferredAt time.Time
+			for waited := false; ; waited = true {
+				allReady := p.LogstoreConfig.schedule.isOpen()
+				if !allReady && deferredAt.IsZero() {
+					deferredAt = time.Now()
+					p.LogstoreConfig.Statistics.ScheduleDeferredMetric.Add(int64(countFlushEvents(logGroups)))
+				}
 				for _, flusher := range p.FlusherPlugins {
-					if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
+					if !allReady || !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
 						p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
 						allReady = false
 						break
 					}
 				}
 				if allReady {
+					if !deferredAt.IsZero() {
+						p.LogstoreConfig.Statistics.ScheduleDeferLatencyMetric.Observe(float64(time.Since(deferredAt)))
+					}
+					failed := false
 					for _, flusher := range p.FlusherPlugins {
 						p.LogstoreConfig.Statistics.FlushReadyMetric.Add(1)
 						begin := time.Now()
@@ -361,10 +439,14 @@ func (p *pluginv1Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 							p.LogstoreConfig.LogstoreName, p.LogstoreConfig.ConfigName, logGroups)
 						p.LogstoreConfig.Statistics.FlushLatencyMetric.Observe(float64(time.Since(begin)))
 						if err != nil {
+							failed = true
 							logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
 								p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
+						} else {
+							flusher.observeEndToEndLatency(readTimes)
 						}
 					}
+					outcomes.flushed(countFlushEvents(logGroups), waited, failed)
 					break
 				}
 				if !p.LogstoreConfig.FlushOutFlag.Load() {
@@ -400,6 +482,10 @@ func (p *pluginv1Runner) Stop(exit bool) error {
 	logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "aggregator plugins stop", "done")
 
 	p.FlushControl.WaitCancel()
+	// The replay of the disk spool may send log groups after the flusher routine exits.
+	for len(p.LogGroupsChan) > 0 {
+		p.FlushOutStore.Add(<-p.LogGroupsChan)
+	}
 
 	if exit && p.FlushOutStore.Len() > 0 {
 		flushers := make([]pipeline.FlusherV1, len(p.FlusherPlugins))
@@ -434,6 +520,7 @@ func (p *pluginv1Runner) Stop(exit bool) error {
 }
 
 func (p *pluginv1Runner) ReceiveRawLog(log *pipeline.LogWithContext) {
+	p.sequence.stampV1(log)
 	p.LogsChan <- log
 }
 
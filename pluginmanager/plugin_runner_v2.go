@@ -54,6 +54,8 @@ type pluginv2Runner struct {
 
 	FlushOutStore  *FlushOutStore[models.PipelineGroupEvents]
 	LogstoreConfig *LogstoreConfig
+
+	sequence *sequencer
 }
 
 func (p *pluginv2Runner) Init(inputQueueSize int, flushQueueSize int) error {
@@ -67,9 +69,10 @@ func (p *pluginv2Runner) Init(inputQueueSize int, flushQueueSize int) error {
 	p.AggregatorPlugins = make([]*AggregatorWrapperV2, 0)
 	p.FlusherPlugins = make([]*FlusherWrapperV2, 0)
 	p.ExtensionPlugins = make(map[string]pipeline.Extension, 0)
-	p.InputPipeContext = helper.NewObservePipelineConext(inputQueueSize)
+	p.sequence = newSequencer(&p.LogstoreConfig.GlobalConfig.Sequence, p.LogstoreConfig.ConfigName)
+	p.InputPipeContext = p.sequence.wrapInputContext(helper.NewObservePipelineConext(inputQueueSize))
 	p.ProcessPipeContext = helper.NewGroupedPipelineConext()
-	p.AggregatePipeContext = helper.NewObservePipelineConext(flushQueueSize)
+	p.AggregatePipeContext = p.LogstoreConfig.Statistics.Outcomes.wrapAggregateContext(helper.NewObservePipelineConext(flushQueueSize))
 	p.FlushPipeContext = helper.NewNoopPipelineConext()
 	p.FlushOutStore.Write(p.AggregatePipeContext.Collector().Observe())
 	return nil
@@ -102,15 +105,22 @@ func (p *pluginv2Runner) AddPlugin(pluginMeta *pipeline.PluginMeta, category plu
 		}
 	case pluginProcessor:
 		if processor, ok := plugin.(pipeline.ProcessorV2); ok {
-			return p.addProcessor(pluginMeta, processor, config["priority"].(int))
+			return p.addProcessor(pluginMeta, processor, config["priority"].(int), config["detail"])
+		}
+		if processor, ok := plugin.(pipeline.ProcessorV1); ok && p.adaptV1Plugins() {
+			return p.addProcessor(pluginMeta, &processorV1Adapter{processor: processor}, config["priority"].(int), config["detail"])
 		}
 	case pluginAggregator:
 		if aggregator, ok := plugin.(pipeline.AggregatorV2); ok {
 			return p.addAggregator(pluginMeta, aggregator)
 		}
+		if aggregator, ok := plugin.(pipeline.AggregatorV1); ok && p.adaptV1Plugins() {
+			return p.addAggregator(pluginMeta, &aggregatorV1Adapter{aggregator: aggregator})
+		}
 	case pluginFlusher:
 		if flusher, ok := plugin.(pipeline.FlusherV2); ok {
-			return p.addFlusher(pluginMeta, flusher)
+			projection, _ := config["projection"].(*fieldProjection)
+			return p.addFlusher(pluginMeta, flusher, projection)
 		}
 	case pluginExtension:
 		if extension, ok := plugin.(pipeline.Extension); ok {
@@ -122,6 +132,10 @@ func (p *pluginv2Runner) AddPlugin(pluginMeta *pipeline.PluginMeta, category plu
 	return pluginUnImplementError(category, v2, pluginMeta.PluginTypeWithID)
 }
 
+func (p *pluginv2Runner) adaptV1Plugins() bool {
+	return p.LogstoreConfig.GlobalConfig != nil && p.LogstoreConfig.GlobalConfig.AdaptV1Plugins
+}
+
 func (p *pluginv2Runner) GetExtension(name string) (pipeline.Extension, bool) {
 	extension, ok := p.ExtensionPlugins[name]
 	return extension, ok
@@ -174,10 +188,11 @@ func (p *pluginv2Runner) addServiceInput(pluginMeta *pipeline.PluginMeta, input
 	return err
 }
 
-func (p *pluginv2Runner) addProcessor(pluginMeta *pipeline.PluginMeta, processor pipeline.ProcessorV2, _ int) error {
+func (p *pluginv2Runner) addProcessor(pluginMeta *pipeline.PluginMeta, processor pipeline.ProcessorV2, _ int, detail interface{}) error {
 	var wrapper ProcessorWrapperV2
 	wrapper.Config = p.LogstoreConfig
 	wrapper.Processor = processor
+	wrapper.detail = detail
 	p.ProcessorPlugins = append(p.ProcessorPlugins, &wrapper)
 	return wrapper.Init(pluginMeta)
 }
@@ -192,20 +207,41 @@ func (p *pluginv2Runner) addAggregator(pluginMeta *pipeline.PluginMeta, aggregat
 		return err
 	}
 	p.AggregatorPlugins = append(p.AggregatorPlugins, &wrapper)
-	p.TimerRunner = append(p.TimerRunner, &timerRunner{
-		state:           aggregator,
-		initialMaxDelay: wrapper.Interval,
-		interval:        wrapper.Interval,
-		context:         p.LogstoreConfig.Context,
-		latencyMetric:   p.LogstoreConfig.Statistics.CollecLatencytMetric,
-	})
+	if len(wrapper.OutputClasses) == 0 {
+		p.TimerRunner = append(p.TimerRunner, &timerRunner{
+			state:           aggregator,
+			initialMaxDelay: wrapper.Interval,
+			interval:        wrapper.Interval,
+			context:         p.LogstoreConfig.Context,
+			latencyMetric:   p.LogstoreConfig.Statistics.CollecLatencytMetric,
+		})
+		return nil
+	}
+	// each output class is flushed by its own timer.
+	multi := aggregator.(pipeline.MultiIntervalAggregatorV2)
+	for _, class := range wrapper.OutputClasses {
+		p.TimerRunner = append(p.TimerRunner, &timerRunner{
+			state:           &aggregatorClassState{aggregator: multi, class: class.name},
+			initialMaxDelay: class.interval,
+			interval:        class.interval,
+			context:         p.LogstoreConfig.Context,
+			latencyMetric:   p.LogstoreConfig.Statistics.CollecLatencytMetric,
+		})
+	}
 	return nil
 }
 
-func (p *pluginv2Runner) addFlusher(pluginMeta *pipeline.PluginMeta, flusher pipeline.FlusherV2) error {
+// aggregatorClassState is the timerRunner state of an output class of pipeline.MultiIntervalAggregatorV2.
+type aggregatorClassState struct {
+	aggregator pipeline.MultiIntervalAggregatorV2
+	class      string
+}
+
+func (p *pluginv2Runner) addFlusher(pluginMeta *pipeline.PluginMeta, flusher pipeline.FlusherV2, projection *fieldProjection) error {
 	var wrapper FlusherWrapperV2
 	wrapper.Config = p.LogstoreConfig
 	wrapper.Flusher = flusher
+	wrapper.projection = projection
 	wrapper.Interval = time.Millisecond * time.Duration(p.LogstoreConfig.GlobalConfig.FlushIntervalMs)
 	p.FlusherPlugins = append(p.FlusherPlugins, &wrapper)
 	return wrapper.Init(pluginMeta)
@@ -224,9 +260,7 @@ func (p *pluginv2Runner) runInput() {
 		p.InputControl.Run(func(c *pipeline.AsyncControl) {
 			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "start run service", service)
 			defer panicRecover(service.Input.Description())
-			if err := service.StartService(p.InputPipeContext); err != nil {
-				logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "PLUGIN_ALARM", "start service error, err", err)
-			}
+			service.runService(c.CancelToken(), func() error { return service.StartService(p.InputPipeContext) })
 			logger.Info(p.LogstoreConfig.Context.GetRuntimeContext(), "service done", service.Input.Description())
 		})
 	}
@@ -239,6 +273,10 @@ func (p *pluginv2Runner) runMetricInput(control *pipeline.AsyncControl) {
 			timer := t
 			control.Run(func(cc *pipeline.AsyncControl) {
 				timer.Run(func(state interface{}) error {
+					if !p.LogstoreConfig.schedule.isCollecting() {
+						p.LogstoreConfig.Statistics.SchedulePausedMetric.Add(1)
+						return nil
+					}
 					return metric.Read(p.InputPipeContext)
 				}, cc)
 			})
@@ -261,6 +299,14 @@ func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 	if globalConfig := p.LogstoreConfig.GlobalConfig; globalConfig.EnableProcessorTag {
 		processorTag = NewProcessorTag(globalConfig.PipelineMetaTagKey, globalConfig.AppendingAllEnvMetaTag, globalConfig.AgentEnvMetaTagKey)
 	}
+	processors := make([]*ProcessorWrapper, 0, len(p.ProcessorPlugins))
+	for _, processor := range p.ProcessorPlugins {
+		processors = append(processors, &processor.ProcessorWrapper)
+	}
+	guardrail := newDropGuardrail(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.DropGuardrail, processors)
+	profiler := newProcessingProfiler(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.ProcessingProfile, processors)
+	latencyTracking := p.LogstoreConfig.GlobalConfig.LatencyTracking.Enable
+	outcomes := &p.LogstoreConfig.Statistics.Outcomes
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -268,23 +314,40 @@ func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 				return
 			}
 		case group := <-pipeChan:
+			readTime := monotonicNow()
 			if processorTag != nil {
 				processorTag.ProcessV2(group)
 			}
 			p.LogstoreConfig.Statistics.RawLogMetric.Add(int64(len(group.Events)))
+			outcomes.receive(len(group.Events))
+			guardrail.receive(len(group.Events))
+			rawLen := len(group.Events)
 			pipeEvents := []*models.PipelineGroupEvents{group}
-			for _, processor := range p.ProcessorPlugins {
+			profiler.sample()
+			for i, processor := range p.ProcessorPlugins {
+				inLen := countEvents(pipeEvents)
+				start := profiler.begin()
 				for _, in := range pipeEvents {
 					processor.Process(in, pipeContext)
 				}
-				pipeEvents = pipeContext.Collector().ToArray()
+				pipeEvents = removeEmptyGroups(pipeContext.Collector().ToArray())
+				profiler.end(i, start)
+				guardrail.settle(i, inLen, countEvents(pipeEvents))
 				if len(pipeEvents) == 0 {
+					// all events are dropped, short-circuit the rest of the processor chain.
 					break
 				}
 			}
+			guardrail.check()
+			profiler.check()
+			outcomes.processed(rawLen, countEvents(pipeEvents))
 			if len(pipeEvents) == 0 {
 				break
 			}
+			if latencyTracking {
+				stampReadTimeV2(pipeEvents, readTime)
+			}
+			outcomes.aggregate(countEvents(pipeEvents))
 			for _, aggregator := range p.AggregatorPlugins {
 				for _, pipeEvent := range pipeEvents {
 					if len(pipeEvent.Events) == 0 {
@@ -311,12 +374,25 @@ func (p *pluginv2Runner) runProcessorInternal(cc *pipeline.AsyncControl) {
 func (p *pluginv2Runner) runAggregator() {
 	p.AggregateControl.Reset()
 	for _, t := range p.TimerRunner {
-		if plugin, ok := t.state.(pipeline.AggregatorV2); ok {
-			aggregator := plugin
+		switch state := t.state.(type) {
+		case pipeline.AggregatorV2:
+			aggregator := state
+			timer := t
+			p.AggregateControl.Run(func(cc *pipeline.AsyncControl) {
+				timer.Run(func(state interface{}) error {
+					err := aggregator.GetResult(p.AggregatePipeContext)
+					p.LogstoreConfig.Statistics.Outcomes.settleAggregated()
+					return err
+				}, cc)
+			})
+		case *aggregatorClassState:
+			classState := state
 			timer := t
 			p.AggregateControl.Run(func(cc *pipeline.AsyncControl) {
 				timer.Run(func(state interface{}) error {
-					return aggregator.GetResult(p.AggregatePipeContext)
+					err := classState.aggregator.GetClassResult(classState.class, p.AggregatePipeContext)
+					p.LogstoreConfig.Statistics.Outcomes.settleAggregated()
+					return err
 				}, cc)
 			})
 		}
@@ -331,6 +407,9 @@ func (p *pluginv2Runner) runFlusher() {
 func (p *pluginv2Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 	defer panicRecover(p.LogstoreConfig.ConfigName)
 	pipeChan := p.AggregatePipeContext.Collector().Observe()
+	sequence := p.sequence
+	latencyTracking := p.LogstoreConfig.GlobalConfig.LatencyTracking.Enable
+	outcomes := &p.LogstoreConfig.Statistics.Outcomes
 	for {
 		select {
 		case <-cc.CancelToken():
@@ -349,6 +428,15 @@ func (p *pluginv2Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 			for i := 1; i < dataSize; i++ {
 				data[i] = <-pipeChan
 			}
+			if ttl := p.LogstoreConfig.GlobalConfig.EventTTLSec; ttl > 0 {
+				var dropped int
+				data, dropped = dropStaleGroupEvents(data, time.Now().Add(-time.Duration(ttl)*time.Second))
+				p.LogstoreConfig.Statistics.FlushStaleMetric.Add(int64(dropped))
+				outcomes.drop(dropped)
+				if len(data) == 0 {
+					continue
+				}
+			}
 			p.LogstoreConfig.Statistics.FlushLogGroupMetric.Add(int64(len(data)))
 
 			for _, item := range data {
@@ -357,30 +445,49 @@ func (p *pluginv2Runner) runFlusherInternal(cc *pipeline.AsyncControl) {
 				}
 				p.LogstoreConfig.Statistics.FlushLogMetric.Add(int64(len(item.Events)))
 			}
+			sequence.orderGroupEvents(data)
+			var readTimes []int64
+			if latencyTracking {
+				readTimes = takeReadTimesV2(data)
+			}
 
 			// Flush LogGroups to all flushers.
 			// Note: multiple flushers is unrecommended, because all flushers will
 			//   be blocked if one of them is unready.
-			for {
-				allReady := true
+			// The data is held here outside the schedule windows, so the upstream is backpressured.
+			var deferredAt time.Time
+			for waited := false; ; waited = true {
+				allReady := p.LogstoreConfig.schedule.isOpen()
+				if !allReady && deferredAt.IsZero() {
+					deferredAt = time.Now()
+					p.LogstoreConfig.Statistics.ScheduleDeferredMetric.Add(int64(countFlushEvents(data)))
+				}
 				for _, flusher := range p.FlusherPlugins {
-					if !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
+					if !allReady || !flusher.Flusher.IsReady(p.LogstoreConfig.ProjectName,
 						p.LogstoreConfig.LogstoreName, p.LogstoreConfig.LogstoreKey) {
 						allReady = false
 						break
 					}
 				}
 				if allReady {
+					if !deferredAt.IsZero() {
+						p.LogstoreConfig.Statistics.ScheduleDeferLatencyMetric.Observe(float64(time.Since(deferredAt)))
+					}
+					failed := false
 					for _, flusher := range p.FlusherPlugins {
 						p.LogstoreConfig.Statistics.FlushReadyMetric.Add(1)
 						begin := time.Now()
 						err := flusher.Export(data, p.FlushPipeContext)
 						p.LogstoreConfig.Statistics.FlushLatencyMetric.Observe(float64(time.Since(begin)))
 						if err != nil {
+							failed = true
 							logger.Error(p.LogstoreConfig.Context.GetRuntimeContext(), "FLUSH_DATA_ALARM", "flush data error",
 								p.LogstoreConfig.ProjectName, p.LogstoreConfig.LogstoreName, err)
+						} else {
+							flusher.observeEndToEndLatency(readTimes)
 						}
 					}
+					outcomes.flushed(countFlushEvents(data), waited, failed)
 					break
 				}
 				if !p.LogstoreConfig.FlushOutFlag.Load() {
@@ -539,3 +646,23 @@ func (p *pluginv2Runner) convertToPipelineEvent(in *protocol.Log) models.Pipelin
 	}
 	return log
 }
+
+// removeEmptyGroups removes the groups without any event in place.
+func removeEmptyGroups(groups []*models.PipelineGroupEvents) []*models.PipelineGroupEvents {
+	nextIdx := 0
+	for _, group := range groups {
+		if len(group.Events) > 0 {
+			groups[nextIdx] = group
+			nextIdx++
+		}
+	}
+	return groups[:nextIdx]
+}
+
+func countEvents(groups []*models.PipelineGroupEvents) int {
+	count := 0
+	for _, group := range groups {
+		count += len(group.Events)
+	}
+	return count
+}
@@ -15,11 +15,14 @@
 package pluginmanager
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/alibaba/ilogtail/pkg/config"
 	"github.com/alibaba/ilogtail/pkg/models"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -78,3 +81,67 @@ type mockPipelineCollector struct {
 func (m *mockPipelineCollector) Collect(groupInfo *models.GroupInfo, eventList ...models.PipelineEvent) {
 	m.ctx.logs = append(m.ctx.logs, models.PipelineGroupEvents{Group: groupInfo, Events: eventList})
 }
+
+type mockMultiIntervalAggregator struct {
+	mu      sync.Mutex
+	flushes map[string]int
+}
+
+func (a *mockMultiIntervalAggregator) Init(pipeline.Context, pipeline.LogGroupQueue) (int, error) {
+	a.flushes = make(map[string]int)
+	return 0, nil
+}
+
+func (a *mockMultiIntervalAggregator) Description() string {
+	return "mock aggregator with multiple flush intervals"
+}
+
+func (a *mockMultiIntervalAggregator) Reset() {}
+
+func (a *mockMultiIntervalAggregator) Record(*models.PipelineGroupEvents, pipeline.PipelineContext) error {
+	return nil
+}
+
+func (a *mockMultiIntervalAggregator) GetResult(pipeline.PipelineContext) error {
+	return a.GetClassResult("", nil)
+}
+
+func (a *mockMultiIntervalAggregator) OutputClasses() []pipeline.AggregatorOutputClass {
+	return []pipeline.AggregatorOutputClass{{Name: "raw", IntervalMs: 10}, {Name: "rollup"}}
+}
+
+func (a *mockMultiIntervalAggregator) GetClassResult(class string, _ pipeline.PipelineContext) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushes[class]++
+	return nil
+}
+
+func (a *mockMultiIntervalAggregator) count(class string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flushes[class]
+}
+
+func TestPluginV2Runner_MultiIntervalAggregator(t *testing.T) {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	globalConfig.AggregatIntervalMs = 60000
+	lc := &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}
+	lc.Statistics.Init(ctx)
+	p := &pluginv2Runner{LogstoreConfig: lc, AggregateControl: pipeline.NewAsyncControl()}
+
+	aggregator := &mockMultiIntervalAggregator{}
+	require.NoError(t, p.addAggregator(&pipeline.PluginMeta{PluginType: "aggregator_mock"}, aggregator))
+	assert.Len(t, p.TimerRunner, 2)
+	assert.Equal(t, 10*time.Millisecond, p.TimerRunner[0].interval)
+	assert.Equal(t, 60*time.Second, p.TimerRunner[1].interval)
+
+	p.runAggregator()
+	assert.Eventually(t, func() bool { return aggregator.count("raw") >= 3 }, 5*time.Second, 10*time.Millisecond)
+	p.AggregateControl.WaitCancel()
+	// the rollup class is flushed once when stopping, and GetResult is never called.
+	assert.Equal(t, 1, aggregator.count("rollup"))
+	assert.Equal(t, 0, aggregator.count(""))
+}
@@ -15,9 +15,12 @@
 package pluginmanager
 
 import (
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 )
 
@@ -49,6 +52,31 @@ func (wrapper *InputWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
 // The service plugin is an input plugin used for passively receiving data.
 type ServiceWrapper struct {
 	InputWrapper
+	// isolation is nil if the panic isolation is disabled.
+	isolation *panicIsolation
+}
+
+func (wrapper *ServiceWrapper) initPanicIsolation(pluginMeta *pipeline.PluginMeta) {
+	if globalConfig := wrapper.Config.GlobalConfig; globalConfig != nil {
+		wrapper.isolation = newPanicIsolation(wrapper.Config.Context, &globalConfig.PanicIsolation, pluginMeta, wrapper.MetricRecord, nil)
+	}
+}
+
+// runService calls @start until it returns, and calls it again after the backoff if it panics, unless the plugin is
+// quarantined or the pipeline is stopping.
+func (wrapper *ServiceWrapper) runService(cancel <-chan struct{}, start func() error) {
+	for {
+		var err error
+		if wrapper.isolation.run(func() { err = start() }) {
+			if err != nil {
+				logger.Error(wrapper.Config.Context.GetRuntimeContext(), "PLUGIN_ALARM", "start service error, err", err)
+			}
+			return
+		}
+		if !wrapper.isolation.wait(cancel) || wrapper.Config.FlushOutFlag.Load() {
+			return
+		}
+	}
 }
 
 // metric plugin is an input plugin used for actively pulling data.
@@ -71,6 +99,17 @@ type ProcessorWrapper struct {
 	outEventsTotal     pipeline.CounterMetric
 	outSizeBytes       pipeline.CounterMetric
 	totalProcessTimeMs pipeline.CounterMetric
+	dropRecorder       *processorDropRecorder
+	// processTimeShare is nil if the processing profile is disabled.
+	processTimeShare pipeline.GaugeMetric
+
+	pluginMeta *pipeline.PluginMeta
+	// bypassed is set by the drop guardrail to pass the events through without processing.
+	bypassed atomic.Bool
+	// isolation is nil if the panic isolation is disabled.
+	isolation *panicIsolation
+	// detail is the config of the processor, a fresh instance is created from it to restart the processor after a crash.
+	detail interface{}
 }
 
 func (wrapper *ProcessorWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
@@ -82,6 +121,39 @@ func (wrapper *ProcessorWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMet
 	wrapper.outEventsTotal = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginOutEventsTotal)
 	wrapper.outSizeBytes = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginOutSizeBytes)
 	wrapper.totalProcessTimeMs = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginTotalProcessTimeMs)
+	wrapper.dropRecorder = newProcessorDropRecorder(wrapper.Config.Context, pluginMeta)
+	wrapper.pluginMeta = pluginMeta
+	if globalConfig := wrapper.Config.GlobalConfig; globalConfig != nil && globalConfig.ProcessingProfile.Enable {
+		wrapper.processTimeShare = helper.NewGaugeMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginProcessTimeSharePercent)
+	}
+}
+
+// initDropReporter hands the drop recorder to the processor if it reports the reasons of dropped events.
+func (wrapper *ProcessorWrapper) initDropReporter(processor pipeline.Processor) {
+	if reporter, ok := processor.(pipeline.DropReporter); ok {
+		reporter.SetDropRecorder(wrapper.dropRecorder)
+	}
+}
+
+// initPanicIsolation must be called after the metric record is initialized. The crashed processor is restarted by
+// @restart, which replaces it with a fresh instance since its state may be corrupted by the panic.
+func (wrapper *ProcessorWrapper) initPanicIsolation(restart func() error) {
+	if globalConfig := wrapper.Config.GlobalConfig; globalConfig != nil {
+		wrapper.isolation = newPanicIsolation(wrapper.Config.Context, &globalConfig.PanicIsolation, wrapper.pluginMeta, wrapper.MetricRecord, restart)
+	}
+}
+
+// newInstance creates and initializes a fresh instance of the processor from the plugin registry and its config.
+func (wrapper *ProcessorWrapper) newInstance() (pipeline.Processor, error) {
+	processor, err := createProcessor(wrapper.pluginMeta.PluginType, wrapper.detail)
+	if err != nil {
+		return nil, err
+	}
+	if err = processor.Init(wrapper.Config.Context); err != nil {
+		return nil, err
+	}
+	wrapper.initDropReporter(processor)
+	return processor, nil
 }
 
 /*---------------------
@@ -93,6 +165,8 @@ type AggregatorWrapper struct {
 	pipeline.PluginContext
 	Config   *LogstoreConfig
 	Interval time.Duration
+	// OutputClasses are flushed independently with their own intervals, see pipeline.MultiIntervalAggregator.
+	OutputClasses []aggregatorOutputClass
 
 	outEventsTotal      pipeline.CounterMetric
 	outEventGroupsTotal pipeline.CounterMetric
@@ -108,6 +182,28 @@ func (wrapper *AggregatorWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMe
 	wrapper.outSizeBytes = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginOutSizeBytes)
 }
 
+type aggregatorOutputClass struct {
+	name     string
+	interval time.Duration
+}
+
+// initOutputClasses must be called after the Interval is set.
+func (wrapper *AggregatorWrapper) initOutputClasses(aggregator pipeline.MultiIntervalAggregator) error {
+	names := make(map[string]struct{})
+	for _, class := range aggregator.OutputClasses() {
+		if _, ok := names[class.Name]; ok {
+			return fmt.Errorf("duplicate aggregator output class %s", class.Name)
+		}
+		names[class.Name] = struct{}{}
+		interval := wrapper.Interval
+		if class.IntervalMs > 0 {
+			interval = time.Millisecond * time.Duration(class.IntervalMs)
+		}
+		wrapper.OutputClasses = append(wrapper.OutputClasses, aggregatorOutputClass{name: class.Name, interval: interval})
+	}
+	return nil
+}
+
 /*---------------------
 Plugin Flusher
 The flusher plugin is used for sending data.
@@ -122,11 +218,15 @@ type FlusherWrapper struct {
 	pipeline.PluginContext
 	Config   *LogstoreConfig
 	Interval time.Duration
+	// projection trims the fields of the data flushed by this flusher, nil means the data is flushed as is.
+	projection *fieldProjection
 
 	inEventsTotal      pipeline.CounterMetric
 	inEventGroupsTotal pipeline.CounterMetric
 	inSizeBytes        pipeline.CounterMetric
 	totalDelayTimeMs   pipeline.CounterMetric
+	// endToEndLatency is nil if the latency tracking is disabled.
+	endToEndLatency *latencyHistogram
 }
 
 func (wrapper *FlusherWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta) {
@@ -137,4 +237,18 @@ func (wrapper *FlusherWrapper) InitMetricRecord(pluginMeta *pipeline.PluginMeta)
 	wrapper.inEventGroupsTotal = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginInEventGroupsTotal)
 	wrapper.inSizeBytes = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginInSizeBytes)
 	wrapper.totalDelayTimeMs = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginTotalDelayMs)
+	if globalConfig := wrapper.Config.GlobalConfig; globalConfig != nil && globalConfig.LatencyTracking.Enable {
+		wrapper.endToEndLatency = newLatencyHistogram(wrapper.MetricRecord, globalConfig.LatencyTracking.BucketsMs)
+	}
+}
+
+// observeEndToEndLatency records the latencies from the read times to now, it is called after the flush is acknowledged.
+func (wrapper *FlusherWrapper) observeEndToEndLatency(readTimes []int64) {
+	if wrapper.endToEndLatency == nil || len(readTimes) == 0 {
+		return
+	}
+	now := monotonicNow()
+	for _, readTime := range readTimes {
+		wrapper.endToEndLatency.observe(time.Duration(now - readTime))
+	}
 }
@@ -49,6 +49,9 @@ func (wrapper *AggregatorWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error
 		interval = wrapper.Config.GlobalConfig.AggregatIntervalMs
 	}
 	wrapper.Interval = time.Millisecond * time.Duration(interval)
+	if multi, ok := wrapper.Aggregator.(pipeline.MultiIntervalAggregatorV1); ok {
+		return wrapper.initOutputClasses(multi)
+	}
 	return nil
 }
 
@@ -61,6 +64,7 @@ func (wrapper *AggregatorWrapperV1) Add(loggroup *protocol.LogGroup) error {
 	}
 	select {
 	case wrapper.LogGroupsChan <- loggroup:
+		wrapper.Config.Statistics.Outcomes.emit(len(loggroup.Logs))
 		return nil
 	default:
 		return errAggAdd
@@ -78,6 +82,7 @@ func (wrapper *AggregatorWrapperV1) AddWithWait(loggroup *protocol.LogGroup, dur
 	timer := time.NewTimer(duration)
 	select {
 	case wrapper.LogGroupsChan <- loggroup:
+		wrapper.Config.Statistics.Outcomes.emit(len(loggroup.Logs))
 		return nil
 	case <-timer.C:
 		return errAggAdd
@@ -87,10 +92,22 @@ func (wrapper *AggregatorWrapperV1) AddWithWait(loggroup *protocol.LogGroup, dur
 // Run calls periodically Aggregator.Flush to get log groups from associated aggregator and
 // pass them to LogstoreConfig through LogGroupsChan.
 func (wrapper *AggregatorWrapperV1) Run(control *pipeline.AsyncControl) {
+	wrapper.run(wrapper.Interval, wrapper.Aggregator.Flush, control)
+}
+
+// RunClass works like Run, but only flushes the output class with its own interval.
+func (wrapper *AggregatorWrapperV1) RunClass(class aggregatorOutputClass, control *pipeline.AsyncControl) {
+	multi := wrapper.Aggregator.(pipeline.MultiIntervalAggregatorV1)
+	wrapper.run(class.interval, func() []*protocol.LogGroup {
+		return multi.FlushClass(class.name)
+	}, control)
+}
+
+func (wrapper *AggregatorWrapperV1) run(interval time.Duration, flush func() []*protocol.LogGroup, control *pipeline.AsyncControl) {
 	defer panicRecover(wrapper.Aggregator.Description())
 	for {
-		exitFlag := util.RandomSleep(wrapper.Interval, 0.1, control.CancelToken())
-		logGroups := wrapper.Aggregator.Flush()
+		exitFlag := util.RandomSleep(interval, 0.1, control.CancelToken())
+		logGroups := flush()
 		for _, logGroup := range logGroups {
 			if len(logGroup.Logs) == 0 {
 				continue
@@ -99,7 +116,9 @@ func (wrapper *AggregatorWrapperV1) Run(control *pipeline.AsyncControl) {
 			wrapper.outEventGroupsTotal.Add(1)
 			wrapper.outSizeBytes.Add(int64(logGroup.Size()))
 			wrapper.LogGroupsChan <- logGroup
+			wrapper.Config.Statistics.Outcomes.emit(len(logGroup.Logs))
 		}
+		wrapper.Config.Statistics.Outcomes.settleAggregated()
 		if exitFlag {
 			return
 		}
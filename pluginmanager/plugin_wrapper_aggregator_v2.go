@@ -49,6 +49,9 @@ func (wrapper *AggregatorWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error
 		interval = wrapper.Config.GlobalConfig.AggregatIntervalMs
 	}
 	wrapper.Interval = time.Millisecond * time.Duration(interval)
+	if multi, ok := wrapper.Aggregator.(pipeline.MultiIntervalAggregatorV2); ok {
+		return wrapper.initOutputClasses(multi)
+	}
 	return nil
 }
 
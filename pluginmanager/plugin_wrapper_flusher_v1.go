@@ -39,6 +39,9 @@ func (wrapper *FlusherWrapperV1) IsReady(projectName string, logstoreName string
 
 func (wrapper *FlusherWrapperV1) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
 	startTime := time.Now()
+	if wrapper.projection != nil {
+		logGroupList = wrapper.projection.projectLogGroups(logGroupList)
+	}
 	for _, logGroup := range logGroupList {
 		wrapper.inEventsTotal.Add(int64(len(logGroup.Logs)))
 		wrapper.inEventGroupsTotal.Add(1)
@@ -38,6 +38,9 @@ func (wrapper *FlusherWrapperV2) IsReady(projectName string, logstoreName string
 
 func (wrapper *FlusherWrapperV2) Export(pipelineGroupEvents []*models.PipelineGroupEvents, pipelineContext pipeline.PipelineContext) error {
 	startTime := time.Now()
+	if wrapper.projection != nil {
+		pipelineGroupEvents = wrapper.projection.projectGroupEvents(pipelineGroupEvents)
+	}
 	for _, groups := range pipelineGroupEvents {
 		wrapper.inEventsTotal.Add(int64(len(groups.Events)))
 		wrapper.inEventGroupsTotal.Add(1)
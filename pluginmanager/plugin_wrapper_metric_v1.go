@@ -25,6 +25,7 @@ import (
 type MetricWrapperV1 struct {
 	MetricWrapper
 	LogsChan chan *pipeline.LogWithContext
+	Sequence *sequencer
 	Input    pipeline.MetricInputV1
 }
 
@@ -68,7 +69,7 @@ func (wrapper *MetricWrapperV1) AddDataWithContext(tags map[string]string, field
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(slsLog.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: slsLog, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: slsLog, Context: ctx})
 }
 
 func (wrapper *MetricWrapperV1) AddDataArrayWithContext(tags map[string]string,
@@ -86,12 +87,18 @@ func (wrapper *MetricWrapperV1) AddDataArrayWithContext(tags map[string]string,
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(slsLog.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: slsLog, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: slsLog, Context: ctx})
 }
 
 func (wrapper *MetricWrapperV1) AddRawLogWithContext(log *protocol.Log, ctx map[string]interface{}) {
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(log.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: log, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: log, Context: ctx})
+}
+
+// send stamps the log with the sequence number of its source before queuing it to the processors.
+func (wrapper *MetricWrapperV1) send(logCtx *pipeline.LogWithContext) {
+	wrapper.Sequence.stampV1(logCtx)
+	wrapper.LogsChan <- logCtx
 }
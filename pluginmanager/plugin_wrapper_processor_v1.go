@@ -29,18 +29,39 @@ type ProcessorWrapperV1 struct {
 
 func (wrapper *ProcessorWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
+	wrapper.initDropReporter(wrapper.Processor)
+	wrapper.initPanicIsolation(func() error {
+		processor, err := wrapper.newInstance()
+		if err != nil {
+			return err
+		}
+		processorV1, ok := processor.(pipeline.ProcessorV1)
+		if !ok {
+			return pluginUnImplementError(pluginProcessor, v1, pluginMeta.PluginType)
+		}
+		wrapper.Processor = processorV1
+		return nil
+	})
 
 	return wrapper.Processor.Init(wrapper.Config.Context)
 }
 
 func (wrapper *ProcessorWrapperV1) Process(logArray []*protocol.Log) []*protocol.Log {
+	if wrapper.bypassed.Load() {
+		return logArray
+	}
 	startTime := time.Now().UnixMilli()
 	wrapper.inEventsTotal.Add(int64(len(logArray)))
 	for _, log := range logArray {
 		wrapper.inSizeBytes.Add(int64(log.Size()))
 	}
 
-	result := wrapper.Processor.ProcessLogs(logArray)
+	inLen := len(logArray)
+	var result []*protocol.Log
+	if !wrapper.isolation.run(func() { result = wrapper.Processor.ProcessLogs(logArray) }) {
+		result = logArray
+	}
+	wrapper.dropRecorder.settle(inLen, len(result))
 
 	wrapper.outEventsTotal.Add(int64(len(result)))
 	for _, log := range result {
@@ -34,11 +34,32 @@ func (wrapper *ProcessorWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
 	wrapper.inEventGroupsTotal = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginInEventGroupsTotal)
 	wrapper.outEventGroupsTotal = helper.NewCounterMetricAndRegister(wrapper.MetricRecord, helper.MetricPluginOutEventGroupsTotal)
+	wrapper.initDropReporter(wrapper.Processor)
+	wrapper.initPanicIsolation(func() error {
+		processor, err := wrapper.newInstance()
+		if err != nil {
+			return err
+		}
+		switch p := processor.(type) {
+		case pipeline.ProcessorV2:
+			wrapper.Processor = p
+		case pipeline.ProcessorV1:
+			// only the v1 processors allowed by global.AdaptV1Plugins are loaded into the v2 pipelines
+			wrapper.Processor = &processorV1Adapter{processor: p}
+		default:
+			return pluginUnImplementError(pluginProcessor, v2, pluginMeta.PluginType)
+		}
+		return nil
+	})
 
 	return wrapper.Processor.Init(wrapper.Config.Context)
 }
 
 func (wrapper *ProcessorWrapperV2) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	if wrapper.bypassed.Load() {
+		context.Collector().Collect(in.Group, in.Events...)
+		return
+	}
 	startTime := time.Now().UnixMilli()
 
 	wrapper.inEventGroupsTotal.Add(1)
@@ -47,7 +68,20 @@ func (wrapper *ProcessorWrapperV2) Process(in *models.PipelineGroupEvents, conte
 		wrapper.inSizeBytes.Add(event.GetSize())
 	}
 
-	wrapper.Processor.Process(in, context)
+	inLen := len(in.Events)
+	if wrapper.isolation == nil {
+		wrapper.Processor.Process(in, context)
+	} else {
+		events := append(make([]models.PipelineEvent, 0, len(in.Events)), in.Events...)
+		tracking := &trackingPipelineContext{collector: &trackingCollector{PipelineCollector: context.Collector()}}
+		if !wrapper.isolation.run(func() { wrapper.Processor.Process(in, tracking) }) {
+			// only the events not collected before the panic are passed through
+			passed := tracking.collector.uncollected(events)
+			context.Collector().Collect(in.Group, passed...)
+			in.Events = append(tracking.collector.collected, passed...)
+		}
+	}
+	wrapper.dropRecorder.settle(inLen, len(in.Events))
 
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outEventsTotal.Add(int64(len(in.Events)))
@@ -26,11 +26,13 @@ import (
 type ServiceWrapperV1 struct {
 	ServiceWrapper
 	LogsChan chan *pipeline.LogWithContext
+	Sequence *sequencer
 	Input    pipeline.ServiceInputV1
 }
 
 func (wrapper *ServiceWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
+	wrapper.initPanicIsolation(pluginMeta)
 
 	_, err := wrapper.Input.Init(wrapper.Config.Context)
 	return err
@@ -39,12 +41,10 @@ func (wrapper *ServiceWrapperV1) Init(pluginMeta *pipeline.PluginMeta) error {
 func (wrapper *ServiceWrapperV1) Run(cc *pipeline.AsyncControl) {
 	logger.Info(wrapper.Config.Context.GetRuntimeContext(), "start run service", wrapper.Input)
 
+	cancel := cc.CancelToken()
 	go func() {
 		defer panicRecover(wrapper.Input.Description())
-		err := wrapper.Input.Start(wrapper)
-		if err != nil {
-			logger.Error(wrapper.Config.Context.GetRuntimeContext(), "PLUGIN_ALARM", "start service error, err", err)
-		}
+		wrapper.runService(cancel, func() error { return wrapper.Input.Start(wrapper) })
 		logger.Info(wrapper.Config.Context.GetRuntimeContext(), "service done", wrapper.Input.Description())
 	}()
 
@@ -84,7 +84,7 @@ func (wrapper *ServiceWrapperV1) AddDataWithContext(tags map[string]string, fiel
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(slsLog.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: slsLog, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: slsLog, Context: ctx})
 }
 
 func (wrapper *ServiceWrapperV1) AddDataArrayWithContext(tags map[string]string,
@@ -102,12 +102,18 @@ func (wrapper *ServiceWrapperV1) AddDataArrayWithContext(tags map[string]string,
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(slsLog.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: slsLog, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: slsLog, Context: ctx})
 }
 
 func (wrapper *ServiceWrapperV1) AddRawLogWithContext(log *protocol.Log, ctx map[string]interface{}) {
 	wrapper.outEventsTotal.Add(1)
 	wrapper.outEventGroupsTotal.Add(1)
 	wrapper.outSizeBytes.Add(int64(log.Size()))
-	wrapper.LogsChan <- &pipeline.LogWithContext{Log: log, Context: ctx}
+	wrapper.send(&pipeline.LogWithContext{Log: log, Context: ctx})
+}
+
+// send stamps the log with the sequence number of its source before queuing it to the processors.
+func (wrapper *ServiceWrapperV1) send(logCtx *pipeline.LogWithContext) {
+	wrapper.Sequence.stampV1(logCtx)
+	wrapper.LogsChan <- logCtx
 }
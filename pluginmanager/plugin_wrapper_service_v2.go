@@ -24,6 +24,7 @@ type ServiceWrapperV2 struct {
 
 func (wrapper *ServiceWrapperV2) Init(pluginMeta *pipeline.PluginMeta) error {
 	wrapper.InitMetricRecord(pluginMeta)
+	wrapper.initPanicIsolation(pluginMeta)
 
 	_, err := wrapper.Input.Init(wrapper.Config.Context)
 	return err
@@ -0,0 +1,141 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+// processingProfiler times each processor on one in every SampleRate batches entering the processor chain. Once per
+// window, the share of the sampled time taken by each processor is set to its process_time_share_percent metric and
+// the most expensive processors are logged, e.g. to find the grok processor taking 70% of the processing time.
+// The processors run on the processor routine, so the wall time of a processor approximates its CPU time.
+// It is only used by the processor routine, so no lock is needed.
+type processingProfiler struct {
+	config     *config.ProcessingProfileConfig
+	context    pipeline.Context
+	processors []*ProcessorWrapper
+
+	batches     int64
+	sampling    bool // whether the current batch is timed
+	windowStart time.Time
+	samples     int64
+	elapsed     []time.Duration // the sampled time of each processor in the window
+	now         func() time.Time
+}
+
+// newProcessingProfiler returns nil if the profile is disabled, and all methods of a nil profiler are no-op.
+func newProcessingProfiler(context pipeline.Context, cfg *config.ProcessingProfileConfig, processors []*ProcessorWrapper) *processingProfiler {
+	if !cfg.Enable || len(processors) == 0 {
+		return nil
+	}
+	if cfg.SampleRate <= 0 || cfg.WindowSec <= 0 {
+		logger.Warning(context.GetRuntimeContext(), "PROCESSING_PROFILE_ALARM", "sample rate", cfg.SampleRate,
+			"window", cfg.WindowSec, "action", "profile is disabled by the invalid config")
+		return nil
+	}
+	return &processingProfiler{
+		config:      cfg,
+		context:     context,
+		processors:  processors,
+		windowStart: time.Now(),
+		elapsed:     make([]time.Duration, len(processors)),
+		now:         time.Now,
+	}
+}
+
+// sample decides whether the batch entering the processor chain is timed.
+func (p *processingProfiler) sample() {
+	if p == nil {
+		return
+	}
+	p.batches++
+	p.sampling = p.batches%int64(p.config.SampleRate) == 0
+	if p.sampling {
+		p.samples++
+	}
+}
+
+// begin returns the time a processor starts on the batch, the zero time is returned if the batch is not timed.
+func (p *processingProfiler) begin() time.Time {
+	if p == nil || !p.sampling {
+		return time.Time{}
+	}
+	return p.now()
+}
+
+// end adds the time since @start to the processor at @index.
+func (p *processingProfiler) end(index int, start time.Time) {
+	if p == nil || start.IsZero() {
+		return
+	}
+	p.elapsed[index] += p.now().Sub(start)
+}
+
+// check closes the window if it is due, and reports the shares of the processors if any batch is timed in it.
+func (p *processingProfiler) check() {
+	if p == nil {
+		return
+	}
+	now := p.now()
+	if now.Sub(p.windowStart) < time.Duration(p.config.WindowSec)*time.Second {
+		return
+	}
+	defer p.reset(now)
+	var total time.Duration
+	for _, elapsed := range p.elapsed {
+		total += elapsed
+	}
+	if total <= 0 {
+		return
+	}
+	order := make([]int, len(p.processors))
+	for i, processor := range p.processors {
+		order[i] = i
+		if processor.processTimeShare != nil {
+			processor.processTimeShare.Set(p.share(i, total))
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return p.elapsed[order[i]] > p.elapsed[order[j]] })
+	topN := p.config.TopN
+	if topN <= 0 || topN > len(order) {
+		topN = len(order)
+	}
+	top := make([]string, 0, topN)
+	for _, i := range order[:topN] {
+		top = append(top, fmt.Sprintf("%s:%.1f%%", p.processors[i].pluginMeta.PluginTypeWithID, p.share(i, total)))
+	}
+	logger.Info(p.context.GetRuntimeContext(), "processing profile", strings.Join(top, ","),
+		"sampled batches", p.samples, "sampled time (ms)", total.Milliseconds(), "window", p.config.WindowSec)
+}
+
+func (p *processingProfiler) share(index int, total time.Duration) float64 {
+	return float64(p.elapsed[index]) * 100 / float64(total)
+}
+
+func (p *processingProfiler) reset(now time.Time) {
+	p.windowStart = now
+	p.samples = 0
+	for i := range p.elapsed {
+		p.elapsed[i] = 0
+	}
+}
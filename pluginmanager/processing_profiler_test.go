@@ -0,0 +1,99 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+func newProfileTestRunner(t *testing.T, profile config.ProcessingProfileConfig, processorTypes ...string) *pluginv1Runner {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	globalConfig := config.LoongcollectorGlobalConfig
+	globalConfig.ProcessingProfile = profile
+	lc := &LogstoreConfig{Context: ctx, GlobalConfig: &globalConfig}
+	lc.Statistics.Init(ctx)
+	p := &pluginv1Runner{LogstoreConfig: lc}
+	for i, processorType := range processorTypes {
+		meta := &pipeline.PluginMeta{PluginType: processorType, PluginTypeWithID: processorType + "/1", PluginID: "1"}
+		require.NoError(t, p.addProcessor(meta, &mockDropAllProcessor{}, i, nil))
+	}
+	return p
+}
+
+func TestProcessingProfiler_Disabled(t *testing.T) {
+	p := newProfileTestRunner(t, config.LoongcollectorGlobalConfig.ProcessingProfile, "processor_grok")
+	assert.Nil(t, newProcessingProfiler(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.ProcessingProfile, guardedProcessors(p)))
+	assert.Nil(t, p.ProcessorPlugins[0].processTimeShare)
+	// all methods of the disabled profiler are no-op
+	var profiler *processingProfiler
+	profiler.sample()
+	profiler.end(0, profiler.begin())
+	profiler.check()
+
+	p = newProfileTestRunner(t, config.ProcessingProfileConfig{Enable: true}, "processor_grok")
+	assert.Nil(t, newProcessingProfiler(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.ProcessingProfile, guardedProcessors(p)))
+}
+
+func TestProcessingProfiler_Shares(t *testing.T) {
+	p := newProfileTestRunner(t, config.ProcessingProfileConfig{
+		Enable:     true,
+		SampleRate: 2,
+		WindowSec:  60,
+		TopN:       1,
+	}, "processor_grok", "processor_json", "processor_rename")
+	processors := guardedProcessors(p)
+	profiler := newProcessingProfiler(p.LogstoreConfig.Context, &p.LogstoreConfig.GlobalConfig.ProcessingProfile, processors)
+	require.NotNil(t, profiler)
+	now := time.Now()
+	profiler.now = func() time.Time { return now }
+	costs := []time.Duration{70 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond}
+
+	process := func() {
+		profiler.sample()
+		for i, cost := range costs {
+			start := profiler.begin()
+			now = now.Add(cost)
+			profiler.end(i, start)
+		}
+		profiler.check()
+	}
+	// only the even batches are timed
+	for i := 0; i < 4; i++ {
+		process()
+	}
+	assert.Equal(t, int64(2), profiler.samples)
+	assert.Equal(t, 140*time.Millisecond, profiler.elapsed[0])
+
+	now = now.Add(time.Minute)
+	profiler.check()
+	assert.InDelta(t, 70, processors[0].processTimeShare.Collect().Value, 0.001)
+	assert.InDelta(t, 20, processors[1].processTimeShare.Collect().Value, 0.001)
+	assert.InDelta(t, 10, processors[2].processTimeShare.Collect().Value, 0.001)
+	assert.Equal(t, int64(0), profiler.samples)
+	assert.Equal(t, time.Duration(0), profiler.elapsed[0])
+
+	// the window without timed batches keeps the last shares
+	now = now.Add(time.Minute)
+	profiler.check()
+	assert.InDelta(t, 70, processors[0].processTimeShare.Collect().Value, 0.001)
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sync"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+// processorDropRecorder counts the events dropped by a processor per reason.
+// Every reason owns a metric record labeled with drop_reason, so that the exported
+// records tell "dropped by processor X for reason Y" directly.
+type processorDropRecorder struct {
+	context pipeline.Context
+	labels  []pipeline.LabelPair
+
+	mu       sync.Mutex
+	counters map[string]pipeline.CounterMetric
+	reported int64
+}
+
+func newProcessorDropRecorder(context pipeline.Context, pluginMeta *pipeline.PluginMeta) *processorDropRecorder {
+	return &processorDropRecorder{
+		context:  context,
+		labels:   helper.GetPluginCommonLabels(context, pluginMeta),
+		counters: make(map[string]pipeline.CounterMetric),
+	}
+}
+
+// Drop implements pipeline.DropRecorder.
+func (r *processorDropRecorder) Drop(reason string, count int) {
+	if count <= 0 {
+		return
+	}
+	if reason == "" {
+		reason = pipeline.DropReasonUnspecified
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counter(reason).Add(int64(count))
+	r.reported += int64(count)
+}
+
+// settle attributes the events dropped in one Process call but not reported by the processor.
+// @in and @out are the event counts before and after processing.
+func (r *processorDropRecorder) settle(in, out int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if unreported := int64(in-out) - r.reported; unreported > 0 {
+		r.counter(pipeline.DropReasonUnspecified).Add(unreported)
+	}
+	r.reported = 0
+}
+
+func (r *processorDropRecorder) counter(reason string) pipeline.CounterMetric {
+	if c, ok := r.counters[reason]; ok {
+		return c
+	}
+	labels := make([]pipeline.LabelPair, 0, len(r.labels)+1)
+	labels = append(labels, r.labels...)
+	labels = append(labels, pipeline.LabelPair{Key: helper.MetricLabelKeyDropReason, Value: reason})
+	record := r.context.RegisterMetricRecord(labels)
+	c := helper.NewCounterMetricAndRegister(record, helper.MetricPluginDroppedEventsTotal)
+	r.counters[reason] = c
+	return c
+}
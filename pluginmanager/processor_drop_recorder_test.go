@@ -0,0 +1,51 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+func TestProcessorDropRecorder(t *testing.T) {
+	ctx := &ContextImp{}
+	ctx.InitContext("project", "logstore", "config")
+	recorder := newProcessorDropRecorder(ctx, &pipeline.PluginMeta{PluginID: "1", PluginType: "processor_filter_regex"})
+
+	// 2 events reported by the processor, 1 dropped silently.
+	recorder.Drop("exclude_match", 2)
+	recorder.settle(10, 7)
+	// split processors output more events than input, nothing is dropped.
+	recorder.settle(1, 5)
+	recorder.Drop("exclude_match", 1)
+	recorder.settle(1, 0)
+
+	dropped := map[string]string{}
+	for _, record := range ctx.ExportMetricRecords() {
+		labels := map[string]string{}
+		require.NoError(t, json.Unmarshal([]byte(record[pipeline.MetricLabelPrefix]), &labels))
+		assert.Equal(t, "1", labels[helper.MetricLabelKeyPluginID])
+		counters := map[string]string{}
+		require.NoError(t, json.Unmarshal([]byte(record[pipeline.MetricCounterPrefix]), &counters))
+		dropped[labels[helper.MetricLabelKeyDropReason]] = counters[helper.MetricPluginDroppedEventsTotal]
+	}
+	assert.Equal(t, map[string]string{"exclude_match": "3.0000", pipeline.DropReasonUnspecified: "1.0000"}, dropped)
+}
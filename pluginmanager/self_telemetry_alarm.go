@@ -46,6 +46,7 @@ func (r *InputAlarm) Collect(collector pipeline.Collector) error {
 	}
 	LogtailConfigLock.RUnlock()
 	util.GlobalAlarm.SerializeToPb(loggroup)
+	lifecycleEvents.SerializeToPb(loggroup)
 	if len(loggroup.Logs) > 0 && AlarmConfig != nil {
 		for _, log := range loggroup.Logs {
 			AlarmConfig.PluginRunner.ReceiveRawLog(&pipeline.LogWithContext{Log: log})
@@ -0,0 +1,303 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+// sequencer stamps the events with the monotonically increasing sequence numbers of their sources when the
+// inputs emit them, so that the downstream consumers can detect the reordered or missing events.
+// The source is the "source" of the input context, which falls back to the pipeline if absent.
+// It is shared by the inputs of the pipeline, and the ordering before flushing only works within a batch.
+// The sources idle for sequenceSourceIdleTimeout are evicted, and their sequences restart from 1.
+type sequencer struct {
+	config        *config.SequenceConfig
+	defaultSource string
+	now           func() time.Time
+
+	lock      sync.Mutex
+	sequences map[string]*sourceSequence
+	lastSweep time.Time
+}
+
+const sequenceSourceIdleTimeout = time.Hour
+
+type sourceSequence struct {
+	seq      uint64
+	lastUsed time.Time
+}
+
+// newSequencer returns nil if the sequence is disabled, and all methods of a nil sequencer are no-op.
+func newSequencer(cfg *config.SequenceConfig, defaultSource string) *sequencer {
+	if !cfg.Enable || cfg.SequenceKey == "" {
+		return nil
+	}
+	return &sequencer{
+		config:        cfg,
+		defaultSource: defaultSource,
+		now:           time.Now,
+		sequences:     make(map[string]*sourceSequence),
+	}
+}
+
+func (s *sequencer) next(source string) (string, string) {
+	if source == "" {
+		source = s.defaultSource
+	}
+	now := s.now()
+	s.lock.Lock()
+	s.evictIdleSources(now)
+	sequence, ok := s.sequences[source]
+	if !ok {
+		sequence = &sourceSequence{}
+		s.sequences[source] = sequence
+	}
+	sequence.seq++
+	sequence.lastUsed = now
+	seq := sequence.seq
+	s.lock.Unlock()
+	return source, strconv.FormatUint(seq, 10)
+}
+
+// evictIdleSources removes the sources idle for sequenceSourceIdleTimeout, it sweeps at most once per timeout.
+func (s *sequencer) evictIdleSources(now time.Time) {
+	if now.Sub(s.lastSweep) < sequenceSourceIdleTimeout {
+		return
+	}
+	s.lastSweep = now
+	for source, sequence := range s.sequences {
+		if now.Sub(sequence.lastUsed) >= sequenceSourceIdleTimeout {
+			delete(s.sequences, source)
+		}
+	}
+}
+
+func (s *sequencer) stampV1(logCtx *pipeline.LogWithContext) {
+	if s == nil {
+		return
+	}
+	source, _ := logCtx.Context[ctxKeySource].(string)
+	source, seq := s.next(source)
+	logCtx.Log.Contents = append(logCtx.Log.Contents, &protocol.Log_Content{Key: s.config.SequenceKey, Value: seq})
+	if s.config.SourceKey != "" {
+		logCtx.Log.Contents = append(logCtx.Log.Contents, &protocol.Log_Content{Key: s.config.SourceKey, Value: source})
+	}
+}
+
+func (s *sequencer) stampV2(group *models.GroupInfo, events []models.PipelineEvent) {
+	if s == nil {
+		return
+	}
+	source := group.GetMetadata().Get(ctxKeySource)
+	for _, event := range events {
+		src, seq := s.next(source)
+		tags := eventTags(event)
+		if tags == nil {
+			continue
+		}
+		tags.Add(s.config.SequenceKey, seq)
+		if s.config.SourceKey != "" {
+			tags.Add(s.config.SourceKey, src)
+		}
+	}
+}
+
+// sequencedCollector stamps the events collected by the v2 inputs before they are queued to the processors.
+type sequencedCollector struct {
+	pipeline.PipelineCollector
+	sequence *sequencer
+}
+
+func (c *sequencedCollector) Collect(group *models.GroupInfo, events ...models.PipelineEvent) {
+	c.sequence.stampV2(group, events)
+	c.PipelineCollector.Collect(group, events...)
+}
+
+func (c *sequencedCollector) CollectList(groups ...*models.PipelineGroupEvents) {
+	for _, group := range groups {
+		c.sequence.stampV2(group.Group, group.Events)
+	}
+	c.PipelineCollector.CollectList(groups...)
+}
+
+type sequencedPipelineContext struct {
+	collector *sequencedCollector
+}
+
+func (c *sequencedPipelineContext) Collector() pipeline.PipelineCollector {
+	return c.collector
+}
+
+// wrapInputContext returns the pipeline context of the inputs which stamps the collected events, or the context
+// itself if the sequence is disabled.
+func (s *sequencer) wrapInputContext(context pipeline.PipelineContext) pipeline.PipelineContext {
+	if s == nil {
+		return context
+	}
+	return &sequencedPipelineContext{collector: &sequencedCollector{PipelineCollector: context.Collector(), sequence: s}}
+}
+
+// eventTags returns the tags of the event, which are created if nil.
+func eventTags(event models.PipelineEvent) models.Tags {
+	if tags := event.GetTags(); tags != nil {
+		return tags
+	}
+	switch e := event.(type) {
+	case *models.Log:
+		e.Tags = models.NewTags()
+		return e.Tags
+	case *models.Metric:
+		e.Tags = models.NewTags()
+		return e.Tags
+	case *models.Span:
+		e.Tags = models.NewTags()
+		return e.Tags
+	}
+	return nil
+}
+
+// sequenceSlot is the position of a stamped event in the flushed data.
+type sequenceSlot struct {
+	group, index int
+	seq          uint64
+}
+
+// groupKey identifies the groups with the same tags, the events are only moved between such groups since they
+// take the tags of the group they are moved to.
+func groupKey(fields ...map[string]string) string {
+	var b strings.Builder
+	for _, m := range fields {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(k)
+			b.WriteByte('=')
+			b.WriteString(m[k])
+			b.WriteByte(0)
+		}
+		b.WriteByte(1)
+	}
+	return b.String()
+}
+
+func logGroupKey(logGroup *protocol.LogGroup) string {
+	tags := make(map[string]string, len(logGroup.LogTags))
+	for _, tag := range logGroup.LogTags {
+		tags[tag.Key] = tag.Value
+	}
+	return groupKey(map[string]string{
+		"category":    logGroup.Category,
+		"topic":       logGroup.Topic,
+		"source":      logGroup.Source,
+		"machineUUID": logGroup.MachineUUID,
+	}, tags)
+}
+
+// sortSlots sorts the stamped events of each source by the sequence numbers, and calls move to place them back
+// into the slots the source occupied. The sources are keyed by the group key as well, so the events are never moved
+// between the groups with different tags. The unstamped events and the group boundaries are left untouched, and
+// the events sharing a sequence number, e.g. split from the same input, keep their relative order.
+func sortSlots(sources map[string][]sequenceSlot, move func(dst, src sequenceSlot)) bool {
+	moved := false
+	for _, slots := range sources {
+		if sort.SliceIsSorted(slots, func(i, j int) bool { return slots[i].seq < slots[j].seq }) {
+			continue
+		}
+		sorted := make([]sequenceSlot, len(slots))
+		copy(sorted, slots)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].seq < sorted[j].seq })
+		for i := range slots {
+			move(slots[i], sorted[i])
+		}
+		moved = true
+	}
+	return moved
+}
+
+// orderLogGroups is the ordered merge of the log groups of a batch before they are flushed, returns whether any log is moved.
+// The logs are not reordered without the source key, since the sequences of different sources are not comparable.
+func (s *sequencer) orderLogGroups(logGroups []*protocol.LogGroup) bool {
+	if s == nil || s.config.SourceKey == "" {
+		return false
+	}
+	sources := make(map[string][]sequenceSlot)
+	for g, logGroup := range logGroups {
+		key := logGroupKey(logGroup)
+		for i, log := range logGroup.Logs {
+			var source, seq string
+			for _, content := range log.Contents {
+				switch content.Key {
+				case s.config.SequenceKey:
+					seq = content.Value
+				case s.config.SourceKey:
+					source = content.Value
+				}
+			}
+			if n, err := strconv.ParseUint(seq, 10, 64); err == nil {
+				sources[key+source] = append(sources[key+source], sequenceSlot{group: g, index: i, seq: n})
+			}
+		}
+	}
+	original := make([][]*protocol.Log, len(logGroups))
+	for g, logGroup := range logGroups {
+		original[g] = append([]*protocol.Log(nil), logGroup.Logs...)
+	}
+	return sortSlots(sources, func(dst, src sequenceSlot) {
+		logGroups[dst.group].Logs[dst.index] = original[src.group][src.index]
+	})
+}
+
+// orderGroupEvents is the ordered merge of the group events of a batch before they are flushed, returns whether any event is moved.
+func (s *sequencer) orderGroupEvents(groups []*models.PipelineGroupEvents) bool {
+	if s == nil || s.config.SourceKey == "" {
+		return false
+	}
+	sources := make(map[string][]sequenceSlot)
+	for g, group := range groups {
+		key := groupKey(group.Group.GetTags().Iterator(), group.Group.GetMetadata().Iterator())
+		for i, event := range group.Events {
+			tags := event.GetTags()
+			if tags == nil {
+				continue
+			}
+			n, err := strconv.ParseUint(tags.Get(s.config.SequenceKey), 10, 64)
+			if err != nil {
+				continue
+			}
+			source := key + tags.Get(s.config.SourceKey)
+			sources[source] = append(sources[source], sequenceSlot{group: g, index: i, seq: n})
+		}
+	}
+	original := make([][]models.PipelineEvent, len(groups))
+	for g, group := range groups {
+		original[g] = append([]models.PipelineEvent(nil), group.Events...)
+	}
+	return sortSlots(sources, func(dst, src sequenceSlot) {
+		groups[dst.group].Events[dst.index] = original[src.group][src.index]
+	})
+}
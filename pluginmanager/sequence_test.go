@@ -0,0 +1,253 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginmanager
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/config"
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+func newTestSequencer() *sequencer {
+	cfg := config.LoongcollectorGlobalConfig.Sequence
+	cfg.Enable = true
+	return newSequencer(&cfg, "config")
+}
+
+func logContent(log *protocol.Log, key string) string {
+	for _, content := range log.Contents {
+		if content.Key == key {
+			return content.Value
+		}
+	}
+	return ""
+}
+
+func TestSequencer_Disabled(t *testing.T) {
+	assert.Nil(t, newSequencer(&config.LoongcollectorGlobalConfig.Sequence, "config"))
+	// all methods of the disabled sequencer are no-op
+	var s *sequencer
+	logCtx := &pipeline.LogWithContext{Log: &protocol.Log{}}
+	s.stampV1(logCtx)
+	assert.Empty(t, logCtx.Log.Contents)
+	s.stampV2(models.NewGroup(models.NewMetadata(), models.NewTags()), nil)
+	inputContext := helper.NewGroupedPipelineConext()
+	assert.Same(t, inputContext, s.wrapInputContext(inputContext))
+	assert.False(t, s.orderLogGroups(nil))
+	assert.False(t, s.orderGroupEvents(nil))
+}
+
+func TestSequencer_StampV1(t *testing.T) {
+	s := newTestSequencer()
+	stamp := func(source string) *protocol.Log {
+		logCtx := &pipeline.LogWithContext{Log: &protocol.Log{}, Context: map[string]interface{}{}}
+		if source != "" {
+			logCtx.Context[ctxKeySource] = source
+		}
+		s.stampV1(logCtx)
+		return logCtx.Log
+	}
+	for i, c := range []struct{ source, expectedSource, expectedSeq string }{
+		{"a", "a", "1"},
+		{"b", "b", "1"},
+		{"a", "a", "2"},
+		{"", "config", "1"},
+		{"a", "a", "3"},
+	} {
+		log := stamp(c.source)
+		assert.Equal(t, c.expectedSeq, logContent(log, "__seq__"), "case %d", i)
+		assert.Equal(t, c.expectedSource, logContent(log, "__seq_source__"), "case %d", i)
+	}
+}
+
+func TestSequencer_EvictIdleSources(t *testing.T) {
+	s := newTestSequencer()
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	next := func(source string) string {
+		_, seq := s.next(source)
+		return seq
+	}
+	assert.Equal(t, "1", next("a"))
+	assert.Equal(t, "2", next("a"))
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, "1", next("b"))
+	now = now.Add(31 * time.Minute)
+	// a is idle for an hour and evicted, b is kept
+	assert.Equal(t, "2", next("b"))
+	assert.Len(t, s.sequences, 1)
+	assert.Equal(t, "1", next("a"))
+	assert.Len(t, s.sequences, 2)
+}
+
+func TestSequencer_StampConcurrentInputs(t *testing.T) {
+	s := newTestSequencer()
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	seqs := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logCtx := &pipeline.LogWithContext{Log: &protocol.Log{}}
+				s.stampV1(logCtx)
+				lock.Lock()
+				seqs[logContent(logCtx.Log, "__seq__")] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seqs, 400)
+	assert.True(t, seqs["1"] && seqs["400"])
+}
+
+func TestSequencer_StampV2(t *testing.T) {
+	s := newTestSequencer()
+	md := models.NewMetadata()
+	md.Add(ctxKeySource, "a")
+	// the events are stamped when the inputs collect them
+	inputContext := s.wrapInputContext(helper.NewGroupedPipelineConext())
+	inputContext.Collector().Collect(models.NewGroup(md, models.NewTags()), models.NewSimpleLog(nil, nil, 0))
+	inputContext.Collector().CollectList(&models.PipelineGroupEvents{
+		Group:  models.NewGroup(md, models.NewTags()),
+		Events: []models.PipelineEvent{models.NewSingleValueMetric("m", models.MetricTypeGauge, nil, 0, 1)},
+	})
+	var events []models.PipelineEvent
+	for _, group := range inputContext.Collector().ToArray() {
+		events = append(events, group.Events...)
+	}
+	require.Len(t, events, 2)
+	// the grouped collector does not keep the order of the groups
+	sort.Slice(events, func(i, j int) bool { return events[i].GetTags().Get("__seq__") < events[j].GetTags().Get("__seq__") })
+	for i, event := range events {
+		assert.Equal(t, []string{"a", string(rune('1' + i))}, []string{event.GetTags().Get("__seq_source__"), event.GetTags().Get("__seq__")})
+	}
+}
+
+func TestSequencer_OrderLogGroups(t *testing.T) {
+	s := newTestSequencer()
+	newLog := func(source, seq string) *protocol.Log {
+		return &protocol.Log{Contents: []*protocol.Log_Content{{Key: "__seq__", Value: seq}, {Key: "__seq_source__", Value: source}}}
+	}
+	unstamped := &protocol.Log{Contents: []*protocol.Log_Content{{Key: "content", Value: "x"}}}
+	logGroups := []*protocol.LogGroup{
+		{Logs: []*protocol.Log{newLog("a", "3"), newLog("b", "1"), unstamped}},
+		{Logs: []*protocol.Log{newLog("a", "1"), newLog("b", "2"), newLog("a", "2")}},
+	}
+	require.True(t, s.orderLogGroups(logGroups))
+	var got [][]string
+	for _, logGroup := range logGroups {
+		var seqs []string
+		for _, log := range logGroup.Logs {
+			seqs = append(seqs, logContent(log, "__seq_source__")+logContent(log, "__seq__"))
+		}
+		got = append(got, seqs)
+	}
+	assert.Equal(t, [][]string{{"a1", "b1", ""}, {"a2", "b2", "a3"}}, got)
+	assert.Same(t, unstamped, logGroups[0].Logs[2])
+	// already ordered
+	assert.False(t, s.orderLogGroups(logGroups))
+}
+
+func TestSequencer_OrderGroupEvents(t *testing.T) {
+	s := newTestSequencer()
+	newEvent := func(source, seq string) models.PipelineEvent {
+		tags := models.NewTags()
+		tags.Add("__seq__", seq)
+		tags.Add("__seq_source__", source)
+		return models.NewLog("", nil, "", "", "", tags, 0)
+	}
+	groups := []*models.PipelineGroupEvents{
+		{Events: []models.PipelineEvent{newEvent("a", "2"), newEvent("b", "2")}},
+		{Events: []models.PipelineEvent{newEvent("b", "1"), newEvent("a", "1")}},
+	}
+	require.True(t, s.orderGroupEvents(groups))
+	var got [][]string
+	for _, group := range groups {
+		var seqs []string
+		for _, event := range group.Events {
+			seqs = append(seqs, event.GetTags().Get("__seq_source__")+event.GetTags().Get("__seq__"))
+		}
+		got = append(got, seqs)
+	}
+	assert.Equal(t, [][]string{{"a1", "b1"}, {"b2", "a2"}}, got)
+}
+
+func TestSequencer_OrderLogGroupsWithDifferentTags(t *testing.T) {
+	s := newTestSequencer()
+	newLog := func(seq string) *protocol.Log {
+		return &protocol.Log{Contents: []*protocol.Log_Content{{Key: "__seq__", Value: seq}, {Key: "__seq_source__", Value: "config"}}}
+	}
+	logGroups := []*protocol.LogGroup{
+		{Logs: []*protocol.Log{newLog("2")}, LogTags: []*protocol.LogTag{{Key: "host", Value: "x"}}},
+		{Logs: []*protocol.Log{newLog("1")}, LogTags: []*protocol.LogTag{{Key: "host", Value: "y"}}},
+	}
+	// the logs are not moved to the group with different tags
+	assert.False(t, s.orderLogGroups(logGroups))
+	logGroups[0].Logs = append(logGroups[0].Logs, newLog("1"))
+	logGroups = append(logGroups, &protocol.LogGroup{Logs: []*protocol.Log{newLog("0")}, LogTags: []*protocol.LogTag{{Key: "host", Value: "x"}}})
+	require.True(t, s.orderLogGroups(logGroups))
+	var got [][]string
+	for _, logGroup := range logGroups {
+		var seqs []string
+		for _, log := range logGroup.Logs {
+			seqs = append(seqs, logContent(log, "__seq__"))
+		}
+		got = append(got, seqs)
+	}
+	assert.Equal(t, [][]string{{"0", "1"}, {"1"}, {"2"}}, got)
+}
+
+func TestSequencer_OrderGroupEventsWithDifferentTags(t *testing.T) {
+	s := newTestSequencer()
+	newEvent := func(seq string) models.PipelineEvent {
+		tags := models.NewTags()
+		tags.Add("__seq__", seq)
+		tags.Add("__seq_source__", "config")
+		return models.NewLog("", nil, "", "", "", tags, 0)
+	}
+	newGroup := func(container string, events ...models.PipelineEvent) *models.PipelineGroupEvents {
+		tags := models.NewTags()
+		tags.Add("container", container)
+		return &models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), tags), Events: events}
+	}
+	groups := []*models.PipelineGroupEvents{
+		newGroup("x", newEvent("3"), newEvent("2")),
+		newGroup("y", newEvent("1")),
+	}
+	require.True(t, s.orderGroupEvents(groups))
+	var got [][]string
+	for _, group := range groups {
+		var seqs []string
+		for _, event := range group.Events {
+			seqs = append(seqs, event.GetTags().Get("__seq__"))
+		}
+		got = append(got, seqs)
+	}
+	// the events are only reordered within the groups with the same tags
+	assert.Equal(t, [][]string{{"2", "3"}, {"1"}}, got)
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/aggregator/baseagg"
+)
+
+const (
+	classRaw    = "raw"
+	classRollup = "rollup"
+
+	defaultRollupIntervalMs = 60000
+	defaultCountKey         = "count"
+	defaultMaxGroups        = 10000
+	groupKeyConnector       = "\x00"
+)
+
+type rollupGroup struct {
+	values []string
+	count  int64
+}
+
+// AggregatorRollup forwards the raw logs with the pipeline interval, and counts the logs by GroupKeys
+// which are flushed as rollup logs with a longer interval. The two output classes are flushed independently.
+type AggregatorRollup struct {
+	GroupKeys        []string // The content keys the rollup counts are grouped by
+	RawIntervalMs    int      // Flush interval of the raw logs, default is the interval of the pipeline
+	RollupIntervalMs int      // Flush interval of the rollup logs, default is 60000
+	CountKey         string   // Content key of the count in the rollup logs, default is count
+	RollupTopic      string   // Topic of the rollup log groups
+	DropRaw          bool     // If true, only the rollup logs are emitted
+	MaxGroups        int      // Max groups in a rollup window, logs of new groups are not counted once exceeded, default is 10000
+
+	raw         *baseagg.AggregatorBase
+	lock        sync.Mutex
+	groups      map[string]*rollupGroup
+	overflowed  bool
+	windowStart time.Time
+	context     pipeline.Context
+}
+
+// Description ...
+func (*AggregatorRollup) Description() string {
+	return "aggregator that forwards raw logs and emits counts grouped by keys with a separate interval"
+}
+
+func (r *AggregatorRollup) Init(context pipeline.Context, que pipeline.LogGroupQueue) (int, error) {
+	r.context = context
+	if len(r.GroupKeys) == 0 {
+		return 0, fmt.Errorf("must specify GroupKeys")
+	}
+	if r.RollupIntervalMs <= 0 {
+		r.RollupIntervalMs = defaultRollupIntervalMs
+	}
+	if r.CountKey == "" {
+		r.CountKey = defaultCountKey
+	}
+	if r.MaxGroups <= 0 {
+		r.MaxGroups = defaultMaxGroups
+	}
+	r.raw = baseagg.NewAggregatorBase()
+	if _, err := r.raw.Init(context, que); err != nil {
+		return 0, err
+	}
+	r.groups = make(map[string]*rollupGroup)
+	r.windowStart = time.Now()
+	return 0, nil
+}
+
+// OutputClasses flushes the raw logs and the rollup logs with different cadences.
+func (r *AggregatorRollup) OutputClasses() []pipeline.AggregatorOutputClass {
+	return []pipeline.AggregatorOutputClass{
+		{Name: classRaw, IntervalMs: r.RawIntervalMs},
+		{Name: classRollup, IntervalMs: r.RollupIntervalMs},
+	}
+}
+
+func (r *AggregatorRollup) Add(log *protocol.Log, ctx map[string]interface{}) error {
+	r.count(log)
+	if r.DropRaw {
+		return nil
+	}
+	return r.raw.Add(log, ctx)
+}
+
+func (r *AggregatorRollup) count(log *protocol.Log) {
+	values := make([]string, len(r.GroupKeys))
+	for i, key := range r.GroupKeys {
+		for _, cont := range log.Contents {
+			if cont.Key == key {
+				values[i] = cont.Value
+				break
+			}
+		}
+	}
+	groupKey := strings.Join(values, groupKeyConnector)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	group, ok := r.groups[groupKey]
+	if !ok {
+		if len(r.groups) >= r.MaxGroups {
+			r.overflowed = true
+			return
+		}
+		group = &rollupGroup{values: values}
+		r.groups[groupKey] = group
+	}
+	group.count++
+}
+
+// Flush flushes both classes, it is only used when the aggregator runs without output classes.
+func (r *AggregatorRollup) Flush() []*protocol.LogGroup {
+	return append(r.FlushClass(classRaw), r.FlushClass(classRollup)...)
+}
+
+// FlushClass ...
+func (r *AggregatorRollup) FlushClass(class string) []*protocol.LogGroup {
+	switch class {
+	case classRaw:
+		return r.raw.Flush()
+	case classRollup:
+		return r.flushRollup()
+	}
+	return nil
+}
+
+func (r *AggregatorRollup) flushRollup() []*protocol.LogGroup {
+	r.lock.Lock()
+	groups := r.groups
+	overflowed := r.overflowed
+	windowStart := r.windowStart
+	r.groups = make(map[string]*rollupGroup, len(groups))
+	r.overflowed = false
+	r.windowStart = time.Now()
+	r.lock.Unlock()
+
+	if overflowed {
+		logger.Warning(r.context.GetRuntimeContext(), "AGG_ROLLUP_ALARM", "rollup groups exceed the limit, logs of new groups are not counted, limit", r.MaxGroups)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	logGroup := &protocol.LogGroup{Topic: r.RollupTopic, Logs: make([]*protocol.Log, 0, len(groups))}
+	for _, key := range keys {
+		group := groups[key]
+		log := &protocol.Log{Contents: make([]*protocol.Log_Content, 0, len(r.GroupKeys)+2)}
+		protocol.SetLogTime(log, uint32(windowStart.Unix()))
+		for i, key := range r.GroupKeys {
+			log.Contents = append(log.Contents, &protocol.Log_Content{Key: key, Value: group.values[i]})
+		}
+		log.Contents = append(log.Contents,
+			&protocol.Log_Content{Key: r.CountKey, Value: strconv.FormatInt(group.count, 10)})
+		logGroup.Logs = append(logGroup.Logs, log)
+	}
+	return []*protocol.LogGroup{logGroup}
+}
+
+// Reset ...
+func (r *AggregatorRollup) Reset() {
+	r.raw.Reset()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.groups = make(map[string]*rollupGroup)
+	r.overflowed = false
+}
+
+func init() {
+	pipeline.Aggregators["aggregator_rollup"] = func() pipeline.Aggregator {
+		return &AggregatorRollup{}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+type mockQueue struct{}
+
+func (mockQueue) Add(*protocol.LogGroup) error { return nil }
+
+func (mockQueue) AddWithWait(*protocol.LogGroup, time.Duration) error { return nil }
+
+func newLog(kvs ...string) *protocol.Log {
+	log := &protocol.Log{}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: kvs[i], Value: kvs[i+1]})
+	}
+	return log
+}
+
+func contents(log *protocol.Log) map[string]string {
+	m := make(map[string]string)
+	for _, cont := range log.Contents {
+		m[cont.Key] = cont.Value
+	}
+	return m
+}
+
+func TestAggregatorRollupInit(t *testing.T) {
+	r := &AggregatorRollup{}
+	_, err := r.Init(mock.NewEmptyContext("p", "l", "c"), mockQueue{})
+	assert.Error(t, err)
+
+	r = &AggregatorRollup{GroupKeys: []string{"level"}, RawIntervalMs: 3000}
+	_, err = r.Init(mock.NewEmptyContext("p", "l", "c"), mockQueue{})
+	require.NoError(t, err)
+	assert.Equal(t, []pipeline.AggregatorOutputClass{
+		{Name: classRaw, IntervalMs: 3000},
+		{Name: classRollup, IntervalMs: defaultRollupIntervalMs},
+	}, r.OutputClasses())
+}
+
+func TestAggregatorRollupFlushClass(t *testing.T) {
+	r := &AggregatorRollup{GroupKeys: []string{"level", "host"}, RollupTopic: "rollup"}
+	_, err := r.Init(mock.NewEmptyContext("p", "l", "c"), mockQueue{})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Add(newLog("level", "info", "host", "a"), nil))
+	require.NoError(t, r.Add(newLog("level", "info", "host", "a"), nil))
+	require.NoError(t, r.Add(newLog("level", "error", "host", "a"), nil))
+	require.NoError(t, r.Add(newLog("msg", "no level"), nil))
+
+	// flushing the raw class does not touch the rollup counts.
+	raw := r.FlushClass(classRaw)
+	require.Len(t, raw, 1)
+	assert.Len(t, raw[0].Logs, 4)
+	assert.Empty(t, r.FlushClass(classRaw))
+
+	rollup := r.FlushClass(classRollup)
+	require.Len(t, rollup, 1)
+	assert.Equal(t, "rollup", rollup[0].Topic)
+	require.Len(t, rollup[0].Logs, 3)
+	assert.Equal(t, map[string]string{"level": "", "host": "", "count": "1"}, contents(rollup[0].Logs[0]))
+	assert.Equal(t, map[string]string{"level": "error", "host": "a", "count": "1"}, contents(rollup[0].Logs[1]))
+	assert.Equal(t, map[string]string{"level": "info", "host": "a", "count": "2"}, contents(rollup[0].Logs[2]))
+	assert.Empty(t, r.FlushClass(classRollup))
+}
+
+func TestAggregatorRollupDropRawAndMaxGroups(t *testing.T) {
+	r := &AggregatorRollup{GroupKeys: []string{"level"}, DropRaw: true, MaxGroups: 1, CountKey: "n"}
+	_, err := r.Init(mock.NewEmptyContext("p", "l", "c"), mockQueue{})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Add(newLog("level", "info"), nil))
+	require.NoError(t, r.Add(newLog("level", "error"), nil))
+	require.NoError(t, r.Add(newLog("level", "info"), nil))
+
+	logGroups := r.Flush()
+	require.Len(t, logGroups, 1)
+	require.Len(t, logGroups[0].Logs, 1)
+	assert.Equal(t, map[string]string{"level": "info", "n": "2"}, contents(logGroups[0].Logs[0]))
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const pluginType = "aggregator_service_graph"
+
+const (
+	roleClient = "client"
+	roleServer = "server"
+
+	overflowAlarmGap = time.Minute
+)
+
+// The tags set by the k8s metadata labeling of the network observer, which are used before looking up k8smeta.
+const (
+	tagNamespace        = "namespace"
+	tagWorkloadName     = "workloadName"
+	tagWorkloadKind     = "workloadKind"
+	tagPeerNamespace    = "peerNamespace"
+	tagPeerWorkloadName = "peerWorkloadName"
+	tagPeerWorkloadKind = "peerWorkloadKind"
+)
+
+// AggregatorServiceGraph builds the service dependency graph from the network flow events, e.g. the connection
+// statistics of the eBPF network observer. Both endpoints of each flow are resolved to the workloads, and the
+// edges from the callers to the callees are flushed as the cumulative counters of the bytes, connections and errors.
+//
+// The string fields of a flow are read from the tags, or the contents of the logs, and the numeric fields from
+// the contents of the logs, or the multi values of the metrics. Events without both ips are forwarded as is.
+type AggregatorServiceGraph struct {
+	LocalIPKey         string // The key of the local ip.
+	RemoteIPKey        string // The key of the remote ip.
+	RoleKey            string // The key of the role of the local endpoint, client or server, the local is the client if missing.
+	BytesSentKey       string // The key of the bytes sent by the local endpoint.
+	BytesReceivedKey   string // The key of the bytes received by the local endpoint.
+	ConnectionsKey     string // The key of the number of connections, each event is a connection if empty or missing.
+	ErrorsKey          string // The key of the number of errors, true is one error.
+	ObserveSide        string // Only count the flows observed at the side, client or server, both are counted if empty.
+	UnknownWorkload    string // The workload name of the endpoints which cannot be resolved.
+	Namespace          string // The prefix of the metric names.
+	IntervalMs         int    // The interval of flushing the metrics.
+	EdgeExpireSeconds  int    // The edge which has no flow in the period is no longer reported, 0 means never.
+	MaxEdges           int    // The max number of edges, the flows of new edges are not counted when exceeded.
+	ForwardEvents      bool   // Whether to forward the flow events after counting, the events are dropped if false.
+	DisableK8sMetaInfo bool   // Whether to skip looking up k8smeta for the endpoints without the workload tags.
+
+	context           pipeline.Context
+	edges             map[edgeKey]*edge
+	lastOverflowAlarm time.Time
+	podMetaGet        func(ip string) *k8smeta.PodMetadata
+	now               func() time.Time
+	lock              sync.Mutex
+}
+
+// Init ...
+func (a *AggregatorServiceGraph) Init(context pipeline.Context, que pipeline.LogGroupQueue) (int, error) {
+	a.context = context
+	if a.LocalIPKey == "" || a.RemoteIPKey == "" {
+		return 0, fmt.Errorf("empty LocalIPKey or RemoteIPKey")
+	}
+	if a.Namespace == "" {
+		return 0, fmt.Errorf("empty Namespace")
+	}
+	if a.IntervalMs <= 0 {
+		return 0, fmt.Errorf("invalid IntervalMs %d", a.IntervalMs)
+	}
+	switch a.ObserveSide {
+	case "", roleClient, roleServer:
+	default:
+		return 0, fmt.Errorf("invalid ObserveSide %s", a.ObserveSide)
+	}
+	if a.podMetaGet == nil && !a.DisableK8sMetaInfo {
+		a.podMetaGet = k8smeta.GetMetaManagerInstance().GetPodMetadataByIP
+	}
+	if a.now == nil {
+		a.now = time.Now
+	}
+	a.edges = make(map[edgeKey]*edge)
+	return a.IntervalMs, nil
+}
+
+// Description ...
+func (a *AggregatorServiceGraph) Description() string {
+	return "aggregator that builds the service graph from the network flows"
+}
+
+// Record counts the flows into the edges.
+func (a *AggregatorServiceGraph) Record(group *models.PipelineGroupEvents, ctx pipeline.PipelineContext) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	forwarded := make([]models.PipelineEvent, 0, len(group.Events))
+	for _, event := range group.Events {
+		if !a.count(event, now) || a.ForwardEvents {
+			forwarded = append(forwarded, event)
+		}
+	}
+	if len(forwarded) > 0 {
+		ctx.Collector().Collect(group.Group, forwarded...)
+	}
+	return nil
+}
+
+// count returns false if the event is not a flow.
+func (a *AggregatorServiceGraph) count(event models.PipelineEvent, now time.Time) bool {
+	localIP, remoteIP := stringField(event, a.LocalIPKey), stringField(event, a.RemoteIPKey)
+	if localIP == "" || remoteIP == "" {
+		return false
+	}
+	role := strings.ToLower(stringField(event, a.RoleKey))
+	if role != roleServer {
+		role = roleClient
+	}
+	if a.ObserveSide != "" && a.ObserveSide != role {
+		return true
+	}
+	local := a.resolve(event, localIP, tagNamespace, tagWorkloadName, tagWorkloadKind)
+	remote := a.resolve(event, remoteIP, tagPeerNamespace, tagPeerWorkloadName, tagPeerWorkloadKind)
+	sent, received := numberField(event, a.BytesSentKey), numberField(event, a.BytesReceivedKey)
+	key := edgeKey{client: local, server: remote}
+	if role == roleServer {
+		key = edgeKey{client: remote, server: local}
+		// the bytes are always from the view of the client
+		sent, received = received, sent
+	}
+	e, ok := a.edges[key]
+	if !ok {
+		if a.MaxEdges > 0 && len(a.edges) >= a.MaxEdges {
+			if now.Sub(a.lastOverflowAlarm) >= overflowAlarmGap {
+				a.lastOverflowAlarm = now
+				logger.Warning(a.context.GetRuntimeContext(), "SERVICE_GRAPH_ALARM", "too many edges, flows of new edges are not counted, max edges", a.MaxEdges)
+			}
+			return true
+		}
+		e = &edge{}
+		a.edges[key] = e
+	}
+	connections := 1.0
+	if a.ConnectionsKey != "" && hasField(event, a.ConnectionsKey) {
+		connections = numberField(event, a.ConnectionsKey)
+	}
+	e.observe(sent, received, connections, numberField(event, a.ErrorsKey), now)
+	return true
+}
+
+// resolve returns the workload of the endpoint from the tags, or from k8smeta by the ip.
+func (a *AggregatorServiceGraph) resolve(event models.PipelineEvent, ip, namespaceKey, nameKey, kindKey string) workload {
+	if name := stringField(event, nameKey); name != "" {
+		return workload{namespace: stringField(event, namespaceKey), name: name, kind: stringField(event, kindKey)}
+	}
+	if a.podMetaGet != nil {
+		if pod := a.podMetaGet(ip); pod != nil {
+			w := workload{namespace: pod.Namespace, name: pod.WorkloadName, kind: pod.WorkloadKind}
+			if w.name == "" {
+				w.name, w.kind = pod.PodName, "pod"
+			}
+			return w
+		}
+	}
+	return workload{name: a.UnknownWorkload}
+}
+
+// GetResult ...
+func (a *AggregatorServiceGraph) GetResult(ctx pipeline.PipelineContext) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	expire := time.Duration(a.EdgeExpireSeconds) * time.Second
+	keys := make([]edgeKey, 0, len(a.edges))
+	for key, e := range a.edges {
+		if a.EdgeExpireSeconds > 0 && now.Sub(e.lastSeen) >= expire {
+			delete(a.edges, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+	ts := now.UnixNano()
+	events := make([]models.PipelineEvent, 0, len(keys)*4)
+	for _, key := range keys {
+		events = a.edges[key].appendMetrics(events, a.Namespace, key, ts)
+	}
+	ctx.Collector().Collect(models.NewGroup(models.NewMetadata(), models.NewTags()), events...)
+	return nil
+}
+
+// Reset ...
+func (a *AggregatorServiceGraph) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.edges = make(map[edgeKey]*edge)
+}
+
+func hasField(event models.PipelineEvent, key string) bool {
+	if _, ok := logContent(event, key); ok {
+		return true
+	}
+	if metric, ok := event.(*models.Metric); ok && metric.GetValue().IsMultiValues() && metric.GetValue().GetMultiValues().Contains(key) {
+		return true
+	}
+	return event.GetTags() != nil && event.GetTags().Contains(key)
+}
+
+func logContent(event models.PipelineEvent, key string) (interface{}, bool) {
+	log, ok := event.(*models.Log)
+	if !ok || log.GetIndices() == nil || !log.GetIndices().Contains(key) {
+		return nil, false
+	}
+	return log.GetIndices().Get(key), true
+}
+
+func stringField(event models.PipelineEvent, key string) string {
+	if key == "" {
+		return ""
+	}
+	if tags := event.GetTags(); tags != nil && tags.Contains(key) {
+		return tags.Get(key)
+	}
+	if v, ok := logContent(event, key); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func numberField(event models.PipelineEvent, key string) float64 {
+	if key == "" {
+		return 0
+	}
+	if metric, ok := event.(*models.Metric); ok && metric.GetValue().IsMultiValues() && metric.GetValue().GetMultiValues().Contains(key) {
+		return metric.GetValue().GetMultiValues().Get(key)
+	}
+	raw, ok := logContent(event, key)
+	if !ok {
+		raw = stringField(event, key)
+	}
+	switch v := raw.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case string:
+		if v == "true" {
+			return 1
+		}
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func init() {
+	pipeline.Aggregators[pluginType] = func() pipeline.Aggregator {
+		return &AggregatorServiceGraph{
+			LocalIPKey:        "local_ip",
+			RemoteIPKey:       "remote_ip",
+			RoleKey:           "role",
+			BytesSentKey:      "send_bytes",
+			BytesReceivedKey:  "recv_bytes",
+			ErrorsKey:         "errors",
+			UnknownWorkload:   "unknown",
+			Namespace:         "service_graph",
+			IntervalMs:        15000,
+			EdgeExpireSeconds: 300,
+			MaxEdges:          10000,
+		}
+	}
+}
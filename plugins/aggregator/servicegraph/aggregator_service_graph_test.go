@@ -0,0 +1,178 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+var pods = map[string]*k8smeta.PodMetadata{
+	"10.0.0.1": {PodName: "frontend-abc", Namespace: "shop", WorkloadName: "frontend", WorkloadKind: "deployment"},
+	"10.0.0.2": {PodName: "cart-xyz", Namespace: "shop", WorkloadName: "cart", WorkloadKind: "deployment"},
+	"10.0.0.3": {PodName: "debug", Namespace: "default"},
+}
+
+func newAggregator() (*AggregatorServiceGraph, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	agg := pipeline.Aggregators[pluginType]().(*AggregatorServiceGraph)
+	agg.podMetaGet = func(ip string) *k8smeta.PodMetadata {
+		return pods[ip]
+	}
+	_, err := agg.Init(ctx, nil)
+	return agg, err
+}
+
+func newFlow(tags map[string]string, sent, received float64) *models.Metric {
+	values := models.NewMetricMultiValueWithMap(map[string]float64{"send_bytes": sent, "recv_bytes": received})
+	return models.NewMultiValuesMetric("conn_stats", models.MetricTypeGauge, models.NewTagsWithMap(tags), 0, values.Values)
+}
+
+func newGroup(events ...models.PipelineEvent) *models.PipelineGroupEvents {
+	return &models.PipelineGroupEvents{
+		Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: events,
+	}
+}
+
+func findMetric(groups []*models.PipelineGroupEvents, name string, labels map[string]string) *models.Metric {
+	for _, group := range groups {
+		for _, event := range group.Events {
+			metric, ok := event.(*models.Metric)
+			if !ok || metric.GetName() != name {
+				continue
+			}
+			matched := true
+			for k, v := range labels {
+				if metric.GetTags().Get(k) != v {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return metric
+			}
+		}
+	}
+	return nil
+}
+
+func TestAggregatorServiceGraph_Init(t *testing.T) {
+	for _, setup := range []func(a *AggregatorServiceGraph){
+		func(a *AggregatorServiceGraph) { a.RemoteIPKey = "" },
+		func(a *AggregatorServiceGraph) { a.Namespace = "" },
+		func(a *AggregatorServiceGraph) { a.IntervalMs = 0 },
+		func(a *AggregatorServiceGraph) { a.ObserveSide = "peer" },
+	} {
+		a, err := newAggregator()
+		require.NoError(t, err)
+		setup(a)
+		_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestAggregatorServiceGraph_Edges(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup(
+		newFlow(map[string]string{"local_ip": "10.0.0.1", "remote_ip": "10.0.0.2", "role": "client"}, 100, 1000),
+		// the same edge observed at the server side
+		newFlow(map[string]string{"local_ip": "10.0.0.2", "remote_ip": "10.0.0.1", "role": "server", "errors": "true"}, 2000, 200),
+		// the workload tags of the labeling are used before k8smeta
+		newFlow(map[string]string{"local_ip": "10.0.0.9", "remote_ip": "10.0.0.2", "namespace": "shop", "workloadName": "checkout", "workloadKind": "deployment"}, 1, 1),
+		newFlow(map[string]string{"local_ip": "10.0.0.3", "remote_ip": "8.8.8.8"}, 5, 5),
+		models.NewLog("log", nil, "", "", "", models.NewTags(), 0),
+	), ctx))
+	forwarded := ctx.Collector().ToArray()
+	require.Len(t, forwarded, 1)
+	require.Len(t, forwarded[0].Events, 1)
+	assert.Equal(t, models.EventTypeLogging, forwarded[0].Events[0].GetType())
+
+	ctx = helper.NewObservePipelineConext(100)
+	require.NoError(t, a.GetResult(ctx))
+	groups := ctx.Collector().ToArray()
+	frontendToCart := map[string]string{labelClientWorkload: "frontend", labelServerWorkload: "cart", labelServerNamespace: "shop"}
+	assert.Equal(t, 2.0, findMetric(groups, "service_graph_edge_connections_total", frontendToCart).GetValue().GetSingleValue())
+	assert.Equal(t, 300.0, findMetric(groups, "service_graph_edge_sent_bytes_total", frontendToCart).GetValue().GetSingleValue())
+	assert.Equal(t, 3000.0, findMetric(groups, "service_graph_edge_received_bytes_total", frontendToCart).GetValue().GetSingleValue())
+	assert.Equal(t, 1.0, findMetric(groups, "service_graph_edge_errors_total", frontendToCart).GetValue().GetSingleValue())
+	assert.NotNil(t, findMetric(groups, "service_graph_edge_connections_total", map[string]string{labelClientWorkload: "checkout", labelServerWorkload: "cart"}))
+	debug := findMetric(groups, "service_graph_edge_connections_total", map[string]string{labelClientWorkload: "debug", labelServerWorkload: "unknown"})
+	require.NotNil(t, debug)
+	assert.Equal(t, "pod", debug.GetTags().Get(labelClientWorkloadKind))
+	assert.False(t, debug.GetTags().Contains(labelServerNamespace))
+}
+
+func TestAggregatorServiceGraph_ObserveSideAndLogs(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.ObserveSide = roleClient
+	a.ForwardEvents = true
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	log := models.NewLog("flow", nil, "", "", "", models.NewTags(), 0)
+	log.Contents = models.NewLogContents()
+	log.Contents.Add("local_ip", "10.0.0.1")
+	log.Contents.Add("remote_ip", "10.0.0.2")
+	log.Contents.Add("send_bytes", "10")
+	log.Contents.Add("recv_bytes", int64(20))
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup(
+		log,
+		newFlow(map[string]string{"local_ip": "10.0.0.2", "remote_ip": "10.0.0.1", "role": "server"}, 2000, 200),
+	), ctx))
+	require.Len(t, ctx.Collector().ToArray()[0].Events, 2)
+
+	ctx = helper.NewObservePipelineConext(100)
+	require.NoError(t, a.GetResult(ctx))
+	groups := ctx.Collector().ToArray()
+	frontendToCart := map[string]string{labelClientWorkload: "frontend", labelServerWorkload: "cart"}
+	assert.Equal(t, 1.0, findMetric(groups, "service_graph_edge_connections_total", frontendToCart).GetValue().GetSingleValue())
+	assert.Equal(t, 10.0, findMetric(groups, "service_graph_edge_sent_bytes_total", frontendToCart).GetValue().GetSingleValue())
+	assert.Equal(t, 20.0, findMetric(groups, "service_graph_edge_received_bytes_total", frontendToCart).GetValue().GetSingleValue())
+}
+
+func TestAggregatorServiceGraph_ExpireAndLimit(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.MaxEdges = 1
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	clock := mock.NewClock(time.Unix(1700000000, 0))
+	a.now = clock.Now
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup(
+		newFlow(map[string]string{"local_ip": "10.0.0.1", "remote_ip": "10.0.0.2"}, 1, 1),
+		newFlow(map[string]string{"local_ip": "10.0.0.2", "remote_ip": "10.0.0.1"}, 1, 1),
+	), ctx))
+	assert.Len(t, a.edges, 1)
+
+	clock.Advance(10 * time.Minute)
+	ctx = helper.NewObservePipelineConext(100)
+	require.NoError(t, a.GetResult(ctx))
+	assert.Empty(t, ctx.Collector().ToArray())
+	assert.Empty(t, a.edges)
+}
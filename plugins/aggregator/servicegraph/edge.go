@@ -0,0 +1,103 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicegraph
+
+import (
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+const (
+	labelClientNamespace    = "client_namespace"
+	labelClientWorkload     = "client_workload"
+	labelClientWorkloadKind = "client_workload_kind"
+	labelServerNamespace    = "server_namespace"
+	labelServerWorkload     = "server_workload"
+	labelServerWorkloadKind = "server_workload_kind"
+)
+
+type workload struct {
+	namespace string
+	name      string
+	kind      string
+}
+
+func (w workload) less(o workload) bool {
+	if w.namespace != o.namespace {
+		return w.namespace < o.namespace
+	}
+	if w.name != o.name {
+		return w.name < o.name
+	}
+	return w.kind < o.kind
+}
+
+// edgeKey is the caller and the callee of an edge.
+type edgeKey struct {
+	client workload
+	server workload
+}
+
+func (k edgeKey) less(o edgeKey) bool {
+	if k.client != o.client {
+		return k.client.less(o.client)
+	}
+	return k.server.less(o.server)
+}
+
+// edge holds the cumulative statistics of the flows from the client to the server.
+type edge struct {
+	bytesSent     float64 // the bytes sent by the client
+	bytesReceived float64 // the bytes received by the client
+	connections   float64
+	errors        float64
+	lastSeen      time.Time
+}
+
+func (e *edge) observe(sent, received, connections, errors float64, now time.Time) {
+	e.bytesSent += sent
+	e.bytesReceived += received
+	e.connections += connections
+	e.errors += errors
+	e.lastSeen = now
+}
+
+// appendMetrics converts the edge to the counters labeled by both endpoints.
+func (e *edge) appendMetrics(events []models.PipelineEvent, namespace string, key edgeKey, ts int64) []models.PipelineEvent {
+	return append(events,
+		models.NewSingleValueMetric(namespace+"_edge_connections_total", models.MetricTypeCounter, key.tags(), ts, e.connections),
+		models.NewSingleValueMetric(namespace+"_edge_sent_bytes_total", models.MetricTypeCounter, key.tags(), ts, e.bytesSent),
+		models.NewSingleValueMetric(namespace+"_edge_received_bytes_total", models.MetricTypeCounter, key.tags(), ts, e.bytesReceived),
+		models.NewSingleValueMetric(namespace+"_edge_errors_total", models.MetricTypeCounter, key.tags(), ts, e.errors))
+}
+
+// tags creates new tags for each metric, as the tags are owned and may be modified by the following plugins.
+func (k edgeKey) tags() models.Tags {
+	tags := models.NewTags()
+	addLabel(tags, labelClientNamespace, k.client.namespace)
+	addLabel(tags, labelClientWorkload, k.client.name)
+	addLabel(tags, labelClientWorkloadKind, k.client.kind)
+	addLabel(tags, labelServerNamespace, k.server.namespace)
+	addLabel(tags, labelServerWorkload, k.server.name)
+	addLabel(tags, labelServerWorkloadKind, k.server.kind)
+	return tags
+}
+
+func addLabel(tags models.Tags, key, value string) {
+	if value != "" {
+		tags.Add(key, value)
+	}
+}
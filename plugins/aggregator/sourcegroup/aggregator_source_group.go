@@ -0,0 +1,194 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcegroup
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	pluginType = "aggregator_source_group"
+
+	connector = "_"
+
+	defaultSourceMetadataKey = "source"
+	maxEventsLength          = 1024
+	maxBytesLength           = 3 * 1024 * 1024
+	sourceTimeoutSec         = 24 * 3600
+)
+
+// sourceGroup packs the events of a source, every emitted group carries the next pack id of the source
+// so that the SLS console could chain the groups to show the context of a log.
+type sourceGroup struct {
+	group          *models.GroupInfo
+	events         []models.PipelineEvent
+	bytesLength    int64
+	packIDPrefix   string
+	packIDSeq      int64
+	lastUpdateTime time.Time
+}
+
+type AggregatorSourceGroup struct {
+	SourceMetadataKeys  []string `json:"SourceMetadataKeys,omitempty" comment:"the metadata keys identifying the source, such as the file path or the container"`
+	GroupMaxEventLength int      `json:"GroupMaxEventLength,omitempty" comment:"max count of events in a pipelineGroupEvents"`
+	GroupMaxByteLength  int      `json:"GroupMaxByteLength,omitempty" comment:"max sum of byte length of events in a pipelineGroupEvents"`
+	PackFlag            bool     `json:"PackFlag" comment:"whether to add __pack_id__ as a tag"`
+	SourceTimeoutSec    int      `json:"SourceTimeoutSec,omitempty" comment:"the pack id chain of a source without new events is dropped after the timeout"`
+
+	context      pipeline.Context
+	configPrefix string
+	lock         sync.Mutex
+	sources      map[string]*sourceGroup
+}
+
+func (g *AggregatorSourceGroup) Init(context pipeline.Context, que pipeline.LogGroupQueue) (int, error) {
+	g.context = context
+	if g.GroupMaxEventLength <= 0 {
+		return 0, fmt.Errorf("unknown GroupMaxEventLength")
+	}
+	if g.GroupMaxByteLength <= 0 {
+		return 0, fmt.Errorf("unknown GroupMaxByteLength")
+	}
+	if len(g.SourceMetadataKeys) == 0 {
+		g.SourceMetadataKeys = []string{defaultSourceMetadataKey}
+	}
+	if context != nil {
+		g.configPrefix = context.GetConfigName()
+	}
+	g.sources = make(map[string]*sourceGroup)
+	return 0, nil
+}
+
+func (g *AggregatorSourceGroup) Description() string {
+	return "aggregator that packs the groupEvents by source and keeps the pack id context of each source"
+}
+
+func (g *AggregatorSourceGroup) Reset() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for _, source := range g.sources {
+		source.events = nil
+		source.bytesLength = 0
+	}
+}
+
+func (g *AggregatorSourceGroup) Record(group *models.PipelineGroupEvents, ctx pipeline.PipelineContext) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	source := g.getOrCreateSource(group.Group)
+	// the tags are shared by the events of a pack, so a change of the tags starts a new pack
+	if len(source.events) > 0 && !sameTags(source.group.GetTags(), group.Group.GetTags()) {
+		g.emit(source, ctx)
+	}
+	if len(source.events) == 0 {
+		source.group = group.Group
+	}
+	for _, event := range group.Events {
+		size := event.GetSize()
+		if len(source.events) > 0 && (len(source.events) >= g.GroupMaxEventLength || source.bytesLength+size > int64(g.GroupMaxByteLength)) {
+			g.emit(source, ctx)
+			source.group = group.Group
+		}
+		source.events = append(source.events, event)
+		source.bytesLength += size
+	}
+	source.lastUpdateTime = time.Now()
+	return nil
+}
+
+func (g *AggregatorSourceGroup) GetResult(ctx pipeline.PipelineContext) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	now := time.Now()
+	for key, source := range g.sources {
+		if len(source.events) > 0 {
+			g.emit(source, ctx)
+			continue
+		}
+		if now.Sub(source.lastUpdateTime) > time.Duration(g.SourceTimeoutSec)*time.Second {
+			delete(g.sources, key)
+		}
+	}
+	return nil
+}
+
+func (g *AggregatorSourceGroup) getOrCreateSource(group *models.GroupInfo) *sourceGroup {
+	var sb strings.Builder
+	for index, key := range g.SourceMetadataKeys {
+		if index != 0 {
+			sb.WriteString(connector)
+		}
+		sb.WriteString(group.GetMetadata().Get(key))
+	}
+	key := sb.String()
+	source, ok := g.sources[key]
+	if !ok {
+		source = &sourceGroup{
+			packIDPrefix: util.NewPackIDPrefix(g.configPrefix + key),
+			packIDSeq:    1,
+		}
+		g.sources[key] = source
+	}
+	return source
+}
+
+func (g *AggregatorSourceGroup) emit(source *sourceGroup, ctx pipeline.PipelineContext) {
+	tags := models.NewTags()
+	for k, v := range source.group.GetTags().Iterator() {
+		tags.Add(k, v)
+	}
+	if g.PackFlag {
+		packID := util.NewLogTagForPackID(source.packIDPrefix, &source.packIDSeq)
+		tags.Add(packID.Key, packID.Value)
+	}
+	ctx.Collector().Collect(models.NewGroup(source.group.GetMetadata(), tags), source.events...)
+	source.events = make([]models.PipelineEvent, 0, len(source.events))
+	source.bytesLength = 0
+}
+
+func sameTags(a, b models.Tags) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for k, v := range a.Iterator() {
+		if !b.Contains(k) || b.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+func NewAggregatorSourceGroup() *AggregatorSourceGroup {
+	return &AggregatorSourceGroup{
+		SourceMetadataKeys:  []string{defaultSourceMetadataKey},
+		GroupMaxEventLength: maxEventsLength,
+		GroupMaxByteLength:  maxBytesLength,
+		PackFlag:            true,
+		SourceTimeoutSec:    sourceTimeoutSec,
+	}
+}
+
+func init() {
+	pipeline.Aggregators[pluginType] = func() pipeline.Aggregator {
+		return NewAggregatorSourceGroup()
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcegroup
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/util"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func generateLogEvents(count int, source string, tags map[string]string) *models.PipelineGroupEvents {
+	group := models.NewGroup(models.NewMetadataWithMap(map[string]string{"source": source}), models.NewTagsWithMap(tags))
+	events := make([]models.PipelineEvent, 0, count)
+	for i := 0; i < count; i++ {
+		events = append(events, models.NewSimpleLog([]byte("log"), nil, 0))
+	}
+	return &models.PipelineGroupEvents{Group: group, Events: events}
+}
+
+func packIDOf(group *models.PipelineGroupEvents) (string, string) {
+	packID := group.Group.GetTags().Get(util.PackIDTagKey)
+	index := strings.LastIndex(packID, "-")
+	return packID[:index], packID[index+1:]
+}
+
+func TestInitSourceGroupAggregator(t *testing.T) {
+	Convey("Given a source group aggregator with invalid GroupMaxEventLength", t, func() {
+		agg := NewAggregatorSourceGroup()
+		agg.GroupMaxEventLength = 0
+		_, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given a source group aggregator without SourceMetadataKeys", t, func() {
+		agg := NewAggregatorSourceGroup()
+		agg.SourceMetadataKeys = nil
+		_, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+		So(err, ShouldBeNil)
+		So(agg.SourceMetadataKeys, ShouldResemble, []string{defaultSourceMetadataKey})
+	})
+}
+
+func TestSourceGroupAggregatorRecord(t *testing.T) {
+	Convey("Given a source group aggregator", t, func() {
+		agg := NewAggregatorSourceGroup()
+		agg.GroupMaxEventLength = 3
+		_, err := agg.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+		So(err, ShouldBeNil)
+		ctx := helper.NewObservePipelineConext(100)
+
+		Convey("the events of each source should be packed with the pack id chain of the source", func() {
+			So(agg.Record(generateLogEvents(2, "/var/log/a.log", nil), ctx), ShouldBeNil)
+			So(agg.Record(generateLogEvents(2, "/var/log/b.log", nil), ctx), ShouldBeNil)
+			So(agg.Record(generateLogEvents(2, "/var/log/a.log", nil), ctx), ShouldBeNil)
+			// the first pack of a.log is full
+			result := ctx.Collector().ToArray()
+			So(result, ShouldHaveLength, 1)
+			So(result[0].Events, ShouldHaveLength, 3)
+			So(result[0].Group.GetMetadata().Get("source"), ShouldEqual, "/var/log/a.log")
+			aPrefix, aSeq := packIDOf(result[0])
+			So(aSeq, ShouldEqual, "1")
+
+			So(agg.GetResult(ctx), ShouldBeNil)
+			result = ctx.Collector().ToArray()
+			So(result, ShouldHaveLength, 2)
+			for _, group := range result {
+				prefix, seq := packIDOf(group)
+				if group.Group.GetMetadata().Get("source") == "/var/log/a.log" {
+					So(prefix, ShouldEqual, aPrefix)
+					So(seq, ShouldEqual, "2")
+					So(group.Events, ShouldHaveLength, 1)
+				} else {
+					So(prefix, ShouldNotEqual, aPrefix)
+					So(seq, ShouldEqual, "1")
+					So(group.Events, ShouldHaveLength, 2)
+				}
+			}
+		})
+
+		Convey("a change of the tags of a source should start a new pack", func() {
+			So(agg.Record(generateLogEvents(1, "/var/log/a.log", map[string]string{"host": "a"}), ctx), ShouldBeNil)
+			So(agg.Record(generateLogEvents(1, "/var/log/a.log", map[string]string{"host": "b"}), ctx), ShouldBeNil)
+			So(agg.GetResult(ctx), ShouldBeNil)
+			result := ctx.Collector().ToArray()
+			So(result, ShouldHaveLength, 2)
+			So(result[0].Group.GetTags().Get("host"), ShouldEqual, "a")
+			So(result[1].Group.GetTags().Get("host"), ShouldEqual, "b")
+			_, seq := packIDOf(result[1])
+			So(seq, ShouldEqual, "2")
+		})
+
+		Convey("the pack id chain of an idle source should be dropped after the timeout", func() {
+			agg.SourceTimeoutSec = -1
+			So(agg.Record(generateLogEvents(1, "/var/log/a.log", nil), ctx), ShouldBeNil)
+			So(agg.GetResult(ctx), ShouldBeNil)
+			So(agg.sources, ShouldHaveLength, 1)
+			So(agg.GetResult(ctx), ShouldBeNil)
+			So(agg.sources, ShouldHaveLength, 0)
+		})
+	})
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const pluginType = "aggregator_span_metrics"
+
+const (
+	classMetrics = "metrics"
+	classTraces  = "traces"
+
+	decisionTickMs   = 1000
+	unknownService   = "unknown_service"
+	overflowAlarmGap = time.Minute
+)
+
+var defaultLatencyBucketsMs = []float64{2, 4, 6, 8, 10, 50, 100, 200, 400, 800, 1000, 1400, 2000, 5000, 10000, 15000}
+
+// AggregatorSpanMetrics derives the RED metrics (rate, errors and duration) from the spans by service and operation,
+// and optionally samples the spans by trace after all the spans of a trace are expected to have arrived.
+// Events other than spans are forwarded as is.
+type AggregatorSpanMetrics struct {
+	ServiceKey          string    // The metadata or tag key of the service name, default is service.name.
+	Dimensions          []string  // The span tag keys added to the labels of the metrics besides service, operation, kind and status.
+	LatencyBucketsMs    []float64 // The upper bounds of the latency histogram buckets in milliseconds.
+	Namespace           string    // The prefix of the metric names.
+	MetricsIntervalMs   int       // The interval of flushing the metrics.
+	SeriesExpireSeconds int       // The series which has no span in the period is no longer reported, 0 means never.
+	MaxSeries           int       // The max number of series, the spans of new series are not counted when exceeded.
+	ForwardSpans        bool      // Whether to forward the spans after counting, the spans are dropped if false.
+	TailSampling        TailSampling
+
+	context           pipeline.Context
+	series            map[string]*series
+	sampler           *tailSampler
+	lastOverflowAlarm time.Time
+	now               func() time.Time
+	lock              sync.Mutex
+}
+
+// Init ...
+func (a *AggregatorSpanMetrics) Init(context pipeline.Context, que pipeline.LogGroupQueue) (int, error) {
+	a.context = context
+	if a.ServiceKey == "" {
+		return 0, fmt.Errorf("empty ServiceKey")
+	}
+	if a.Namespace == "" {
+		return 0, fmt.Errorf("empty Namespace")
+	}
+	if len(a.LatencyBucketsMs) == 0 {
+		a.LatencyBucketsMs = defaultLatencyBucketsMs
+	}
+	for i := 1; i < len(a.LatencyBucketsMs); i++ {
+		if a.LatencyBucketsMs[i] <= a.LatencyBucketsMs[i-1] {
+			return 0, fmt.Errorf("LatencyBucketsMs must be in increasing order")
+		}
+	}
+	if a.MetricsIntervalMs <= 0 {
+		return 0, fmt.Errorf("invalid MetricsIntervalMs %d", a.MetricsIntervalMs)
+	}
+	if a.ForwardSpans && a.TailSampling.Enable {
+		if a.TailSampling.DecisionWaitMs <= 0 || a.TailSampling.MaxTraces <= 0 || a.TailSampling.DecisionCacheSize <= 0 {
+			return 0, fmt.Errorf("DecisionWaitMs, MaxTraces and DecisionCacheSize of TailSampling must be positive")
+		}
+		if a.TailSampling.SamplingPercentage < 0 || a.TailSampling.SamplingPercentage > 100 {
+			return 0, fmt.Errorf("invalid SamplingPercentage %v of TailSampling", a.TailSampling.SamplingPercentage)
+		}
+		sampler, err := newTailSampler(&a.TailSampling)
+		if err != nil {
+			return 0, err
+		}
+		a.sampler = sampler
+	}
+	a.series = make(map[string]*series)
+	if a.now == nil {
+		a.now = time.Now
+	}
+	return a.MetricsIntervalMs, nil
+}
+
+// Description ...
+func (a *AggregatorSpanMetrics) Description() string {
+	return "aggregator that derives RED metrics from spans and samples traces"
+}
+
+// OutputClasses flushes the metrics and the sampled spans with different cadences.
+func (a *AggregatorSpanMetrics) OutputClasses() []pipeline.AggregatorOutputClass {
+	if a.sampler == nil {
+		return nil
+	}
+	tick := decisionTickMs
+	if a.TailSampling.DecisionWaitMs < tick {
+		tick = a.TailSampling.DecisionWaitMs
+	}
+	return []pipeline.AggregatorOutputClass{
+		{Name: classMetrics, IntervalMs: a.MetricsIntervalMs},
+		{Name: classTraces, IntervalMs: tick},
+	}
+}
+
+// Record counts the spans and forwards them, or buffers them until their traces are decided.
+func (a *AggregatorSpanMetrics) Record(group *models.PipelineGroupEvents, ctx pipeline.PipelineContext) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	now := a.now()
+	forwarded := make([]models.PipelineEvent, 0, len(group.Events))
+	var released []pendingSpan
+	for _, event := range group.Events {
+		span, ok := event.(*models.Span)
+		if !ok {
+			forwarded = append(forwarded, event)
+			continue
+		}
+		a.count(group.Group, span, now)
+		switch {
+		case !a.ForwardSpans:
+		case a.sampler == nil:
+			forwarded = append(forwarded, span)
+		default:
+			released = append(released, a.sampler.add(group.Group, span, now)...)
+		}
+	}
+	if len(forwarded) > 0 {
+		ctx.Collector().Collect(group.Group, forwarded...)
+	}
+	collectSpans(released, ctx)
+	return nil
+}
+
+func (a *AggregatorSpanMetrics) count(group *models.GroupInfo, span *models.Span, now time.Time) {
+	values := make([]string, 0, 4+len(a.Dimensions))
+	values = append(values,
+		a.serviceName(group, span),
+		span.GetName(),
+		string(models.SpanKindTexts[span.GetKind()]),
+		statusCodeTexts[span.GetStatus()])
+	for _, key := range a.Dimensions {
+		values = append(values, span.GetTags().Get(key))
+	}
+	key := strings.Join(values, "\x00")
+	s, ok := a.series[key]
+	if !ok {
+		if a.MaxSeries > 0 && len(a.series) >= a.MaxSeries {
+			if now.Sub(a.lastOverflowAlarm) >= overflowAlarmGap {
+				a.lastOverflowAlarm = now
+				logger.Warning(a.context.GetRuntimeContext(), "SPAN_METRICS_ALARM", "too many series, spans of new series are not counted, max series", a.MaxSeries)
+			}
+			return
+		}
+		labels := map[string]string{
+			labelService:    values[0],
+			labelSpanName:   values[1],
+			labelSpanKind:   values[2],
+			labelStatusCode: values[3],
+		}
+		for i, key := range a.Dimensions {
+			if values[4+i] != "" {
+				labels[key] = values[4+i]
+			}
+		}
+		s = newSeries(labels, len(a.LatencyBucketsMs))
+		a.series[key] = s
+	}
+	s.observe(a.LatencyBucketsMs, spanLatencyMs(span), now)
+}
+
+func (a *AggregatorSpanMetrics) serviceName(group *models.GroupInfo, span *models.Span) string {
+	if name := group.GetMetadata().Get(a.ServiceKey); name != "" {
+		return name
+	}
+	if name := span.GetTags().Get(a.ServiceKey); name != "" {
+		return name
+	}
+	if name := group.GetTags().Get(a.ServiceKey); name != "" {
+		return name
+	}
+	return unknownService
+}
+
+// GetResult flushes both the metrics and the sampled spans.
+func (a *AggregatorSpanMetrics) GetResult(ctx pipeline.PipelineContext) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.flushTraces(ctx)
+	a.flushMetrics(ctx)
+	return nil
+}
+
+// GetClassResult ...
+func (a *AggregatorSpanMetrics) GetClassResult(class string, ctx pipeline.PipelineContext) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	switch class {
+	case classMetrics:
+		a.flushMetrics(ctx)
+	case classTraces:
+		a.flushTraces(ctx)
+	default:
+		return fmt.Errorf("unknown output class %s", class)
+	}
+	return nil
+}
+
+func (a *AggregatorSpanMetrics) flushMetrics(ctx pipeline.PipelineContext) {
+	now := a.now()
+	expire := time.Duration(a.SeriesExpireSeconds) * time.Second
+	keys := make([]string, 0, len(a.series))
+	for key, s := range a.series {
+		if a.SeriesExpireSeconds > 0 && now.Sub(s.lastSeen) >= expire {
+			delete(a.series, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	ts := now.UnixNano()
+	events := make([]models.PipelineEvent, 0, len(keys)*(len(a.LatencyBucketsMs)+4))
+	for _, key := range keys {
+		events = a.series[key].appendMetrics(events, a.Namespace, a.LatencyBucketsMs, ts)
+	}
+	ctx.Collector().Collect(models.NewGroup(models.NewMetadata(), models.NewTags()), events...)
+}
+
+func (a *AggregatorSpanMetrics) flushTraces(ctx pipeline.PipelineContext) {
+	if a.sampler != nil {
+		collectSpans(a.sampler.flush(a.now()), ctx)
+	}
+}
+
+// collectSpans forwards the spans with their original groups, the spans of the same group are kept together.
+func collectSpans(spans []pendingSpan, ctx pipeline.PipelineContext) {
+	if len(spans) == 0 {
+		return
+	}
+	var groups []*models.GroupInfo
+	events := make(map[*models.GroupInfo][]models.PipelineEvent)
+	for _, s := range spans {
+		if _, ok := events[s.group]; !ok {
+			groups = append(groups, s.group)
+		}
+		events[s.group] = append(events[s.group], s.span)
+	}
+	for _, group := range groups {
+		ctx.Collector().Collect(group, events[group]...)
+	}
+}
+
+// Reset ...
+func (a *AggregatorSpanMetrics) Reset() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.series = make(map[string]*series)
+	if a.sampler != nil {
+		a.sampler.reset()
+	}
+}
+
+func init() {
+	pipeline.Aggregators[pluginType] = func() pipeline.Aggregator {
+		return &AggregatorSpanMetrics{
+			ServiceKey:          "service.name",
+			Namespace:           "traces_span_metrics",
+			MetricsIntervalMs:   15000,
+			SeriesExpireSeconds: 300,
+			MaxSeries:           10000,
+			ForwardSpans:        true,
+			TailSampling: TailSampling{
+				DecisionWaitMs:     10000,
+				MaxTraces:          50000,
+				KeepErrors:         true,
+				SamplingPercentage: 10,
+				DecisionCacheSize:  100000,
+			},
+		}
+	}
+}
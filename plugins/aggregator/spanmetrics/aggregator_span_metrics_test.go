@@ -0,0 +1,272 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newAggregator() (*AggregatorSpanMetrics, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	agg := pipeline.Aggregators[pluginType]().(*AggregatorSpanMetrics)
+	_, err := agg.Init(ctx, nil)
+	return agg, err
+}
+
+func newSpan(traceID, name string, status models.StatusCode, latency time.Duration, tags map[string]string) *models.Span {
+	start := uint64(time.Unix(1700000000, 0).UnixNano())
+	span := models.NewSpan(name, traceID, "span-"+name, models.SpanKindServer, start, start+uint64(latency),
+		models.NewTagsWithMap(tags), nil, nil)
+	span.Status = status
+	return span
+}
+
+func newGroup(service string, events ...models.PipelineEvent) *models.PipelineGroupEvents {
+	return &models.PipelineGroupEvents{
+		Group:  models.NewGroup(models.NewMetadataWithMap(map[string]string{"service.name": service}), models.NewTags()),
+		Events: events,
+	}
+}
+
+func findMetric(groups []*models.PipelineGroupEvents, name string, labels map[string]string) *models.Metric {
+	for _, group := range groups {
+		for _, event := range group.Events {
+			metric, ok := event.(*models.Metric)
+			if !ok || metric.GetName() != name {
+				continue
+			}
+			matched := true
+			for k, v := range labels {
+				if metric.GetTags().Get(k) != v {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return metric
+			}
+		}
+	}
+	return nil
+}
+
+func countSpans(groups []*models.PipelineGroupEvents) int {
+	count := 0
+	for _, group := range groups {
+		for _, event := range group.Events {
+			if event.GetType() == models.EventTypeSpan {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestAggregatorSpanMetrics_REDMetrics(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.LatencyBucketsMs = []float64{10, 100}
+	a.Dimensions = []string{"http.method"}
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup("cart",
+		newSpan("t1", "GET /cart", models.StatusCodeOK, 5*time.Millisecond, map[string]string{"http.method": "GET"}),
+		newSpan("t2", "GET /cart", models.StatusCodeOK, 50*time.Millisecond, map[string]string{"http.method": "GET"}),
+		newSpan("t3", "GET /cart", models.StatusCodeError, 500*time.Millisecond, map[string]string{"http.method": "GET"}),
+		models.NewLog("log", nil, "", "", "", models.NewTags(), 0),
+	), ctx))
+	forwarded := ctx.Collector().ToArray()
+	require.Len(t, forwarded, 1)
+	require.Len(t, forwarded[0].Events, 4)
+
+	require.NoError(t, a.GetResult(ctx))
+	result := ctx.Collector().ToArray()
+	ok := map[string]string{"service_name": "cart", "span_name": "GET /cart", "span_kind": "server", "status_code": "ok", "http.method": "GET"}
+	calls := findMetric(result, "traces_span_metrics_calls_total", ok)
+	require.NotNil(t, calls)
+	require.Equal(t, models.MetricTypeCounter, calls.GetMetricType())
+	require.Equal(t, 2.0, calls.GetValue().GetSingleValue())
+	errs := findMetric(result, "traces_span_metrics_calls_total", map[string]string{"status_code": "error"})
+	require.NotNil(t, errs)
+	require.Equal(t, 1.0, errs.GetValue().GetSingleValue())
+
+	for le, expected := range map[string]float64{"10": 1, "100": 2, "+Inf": 2} {
+		labels := map[string]string{"status_code": "ok", "le": le}
+		bucket := findMetric(result, "traces_span_metrics_duration_milliseconds_bucket", labels)
+		require.NotNil(t, bucket, le)
+		require.Equal(t, expected, bucket.GetValue().GetSingleValue(), le)
+	}
+	require.Equal(t, 55.0, findMetric(result, "traces_span_metrics_duration_milliseconds_sum", ok).GetValue().GetSingleValue())
+	require.Equal(t, 2.0, findMetric(result, "traces_span_metrics_duration_milliseconds_count", ok).GetValue().GetSingleValue())
+	errBucket := findMetric(result, "traces_span_metrics_duration_milliseconds_bucket", map[string]string{"status_code": "error", "le": "100"})
+	require.Equal(t, 0.0, errBucket.GetValue().GetSingleValue())
+
+	// the metrics are cumulative
+	require.NoError(t, a.Record(newGroup("cart",
+		newSpan("t4", "GET /cart", models.StatusCodeOK, time.Millisecond, map[string]string{"http.method": "GET"})), ctx))
+	ctx.Collector().ToArray()
+	require.NoError(t, a.GetClassResult(classMetrics, ctx))
+	calls = findMetric(ctx.Collector().ToArray(), "traces_span_metrics_calls_total", ok)
+	require.Equal(t, 3.0, calls.GetValue().GetSingleValue())
+}
+
+func TestAggregatorSpanMetrics_ServiceName(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	group := &models.PipelineGroupEvents{
+		Group: models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{
+			newSpan("t1", "a", models.StatusCodeUnSet, 0, map[string]string{"service.name": "from-tag"}),
+			newSpan("t2", "b", models.StatusCodeUnSet, 0, nil),
+		},
+	}
+	require.NoError(t, a.Record(group, ctx))
+	require.NoError(t, a.GetResult(ctx))
+	result := ctx.Collector().ToArray()
+	require.NotNil(t, findMetric(result, "traces_span_metrics_calls_total", map[string]string{"service_name": "from-tag", "span_name": "a"}))
+	require.NotNil(t, findMetric(result, "traces_span_metrics_calls_total", map[string]string{"service_name": "unknown_service", "span_name": "b"}))
+}
+
+func TestAggregatorSpanMetrics_SeriesLimit(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.MaxSeries = 1
+	a.SeriesExpireSeconds = 60
+	a.ForwardSpans = false
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	clock := mock.NewClock(time.Unix(1700000000, 0))
+	a.now = clock.Now
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup("svc",
+		newSpan("t1", "a", models.StatusCodeOK, 0, nil),
+		newSpan("t2", "b", models.StatusCodeOK, 0, nil)), ctx))
+	require.Empty(t, ctx.Collector().ToArray())
+	require.Len(t, a.series, 1)
+
+	clock.Advance(time.Minute)
+	require.NoError(t, a.GetResult(ctx))
+	require.Empty(t, ctx.Collector().ToArray())
+	require.Empty(t, a.series)
+}
+
+func TestAggregatorSpanMetrics_TailSampling(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.TailSampling.Enable = true
+	a.TailSampling.DecisionWaitMs = 5000
+	a.TailSampling.LatencyThresholdMs = 1000
+	a.TailSampling.SamplingPercentage = 0
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	clock := mock.NewClock(time.Unix(1700000000, 0))
+	a.now = clock.Now
+	require.Equal(t, []pipeline.AggregatorOutputClass{
+		{Name: classMetrics, IntervalMs: 15000},
+		{Name: classTraces, IntervalMs: 1000},
+	}, a.OutputClasses())
+
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup("svc",
+		newSpan("error", "a", models.StatusCodeOK, 0, nil),
+		newSpan("error", "b", models.StatusCodeError, 0, nil),
+		newSpan("slow", "a", models.StatusCodeOK, 2*time.Second, nil),
+		newSpan("normal", "a", models.StatusCodeOK, time.Millisecond, nil)), ctx))
+	require.Empty(t, ctx.Collector().ToArray())
+
+	clock.Advance(4 * time.Second)
+	require.NoError(t, a.GetClassResult(classTraces, ctx))
+	require.Empty(t, ctx.Collector().ToArray())
+
+	clock.Advance(time.Second)
+	require.NoError(t, a.GetClassResult(classTraces, ctx))
+	result := ctx.Collector().ToArray()
+	require.Len(t, result, 1)
+	require.Equal(t, "svc", result[0].Group.GetMetadata().Get("service.name"))
+	require.Len(t, result[0].Events, 3)
+	for _, event := range result[0].Events {
+		require.NotEqual(t, "normal", event.(*models.Span).GetTraceID())
+	}
+
+	// the late spans follow the decisions
+	require.NoError(t, a.Record(newGroup("svc",
+		newSpan("error", "c", models.StatusCodeOK, 0, nil),
+		newSpan("normal", "b", models.StatusCodeOK, 0, nil)), ctx))
+	result = ctx.Collector().ToArray()
+	require.Equal(t, 1, countSpans(result))
+	require.Equal(t, "c", result[0].Events[0].GetName())
+
+	// all spans are counted in the metrics regardless of sampling
+	require.NoError(t, a.GetClassResult(classMetrics, ctx))
+	calls := findMetric(ctx.Collector().ToArray(), "traces_span_metrics_calls_total", map[string]string{"span_name": "a", "status_code": "ok"})
+	require.Equal(t, 3.0, calls.GetValue().GetSingleValue())
+}
+
+func TestAggregatorSpanMetrics_MaxTraces(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.TailSampling.Enable = true
+	a.TailSampling.MaxTraces = 1
+	a.TailSampling.SamplingPercentage = 100
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, a.Record(newGroup("svc", newSpan("t1", "a", models.StatusCodeOK, 0, nil)), ctx))
+	require.Empty(t, ctx.Collector().ToArray())
+	// the oldest trace is decided early to make room for the new one
+	require.NoError(t, a.Record(newGroup("svc", newSpan("t2", "a", models.StatusCodeOK, 0, nil)), ctx))
+	result := ctx.Collector().ToArray()
+	require.Equal(t, 1, countSpans(result))
+	require.Equal(t, "t1", result[0].Events[0].(*models.Span).GetTraceID())
+}
+
+func TestTailSampler_Probabilistic(t *testing.T) {
+	sampler, err := newTailSampler(&TailSampling{SamplingPercentage: 25, DecisionCacheSize: 10})
+	require.NoError(t, err)
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		trace := &pendingTrace{id: time.Unix(int64(i), 0).String()}
+		if sampler.sample(trace) {
+			sampled++
+		}
+		require.Equal(t, sampler.sample(trace), sampler.sample(trace))
+	}
+	require.InDelta(t, 2500, sampled, 300)
+}
+
+func TestAggregatorSpanMetrics_InvalidConfig(t *testing.T) {
+	a, err := newAggregator()
+	require.NoError(t, err)
+	a.LatencyBucketsMs = []float64{10, 5}
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.Error(t, err)
+
+	a, err = newAggregator()
+	require.NoError(t, err)
+	a.TailSampling.Enable = true
+	a.TailSampling.SamplingPercentage = 101
+	_, err = a.Init(mock.NewEmptyContext("p", "l", "c"), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"hash/fnv"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+// TailSampling decides whether to keep a trace after all of its spans are expected to have arrived.
+// A trace is kept when it matches any of the policies.
+type TailSampling struct {
+	Enable             bool    // Whether to sample the spans by trace, all spans are forwarded if disabled.
+	DecisionWaitMs     int     // The time to wait for the spans of a trace since its first span arrived before deciding.
+	MaxTraces          int     // The max number of traces waiting for the decision, the oldest trace is decided early when exceeded.
+	KeepErrors         bool    // Keep the traces containing a span with the error status.
+	LatencyThresholdMs int     // Keep the traces whose duration is not less than the threshold, disabled if not positive.
+	SamplingPercentage float64 // The percentage of other traces to keep, decided by the hash of the trace id.
+	DecisionCacheSize  int     // The number of decided traces to remember, so that the late spans follow the decision.
+}
+
+type pendingSpan struct {
+	group *models.GroupInfo
+	span  *models.Span
+}
+
+type pendingTrace struct {
+	id        string
+	firstSeen time.Time
+	spans     []pendingSpan
+	hasError  bool
+	minStart  uint64
+	maxEnd    uint64
+}
+
+type tailSampler struct {
+	config    *TailSampling
+	traces    map[string]*pendingTrace
+	order     []*pendingTrace // ordered by the first seen time
+	decisions *lru.Cache[string, bool]
+}
+
+func newTailSampler(config *TailSampling) (*tailSampler, error) {
+	decisions, err := lru.New[string, bool](config.DecisionCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &tailSampler{
+		config:    config,
+		traces:    make(map[string]*pendingTrace),
+		decisions: decisions,
+	}, nil
+}
+
+// add buffers the span until its trace is decided, and returns the spans which could be forwarded now.
+func (t *tailSampler) add(group *models.GroupInfo, span *models.Span, now time.Time) []pendingSpan {
+	traceID := span.GetTraceID()
+	if sampled, ok := t.decisions.Get(traceID); ok {
+		if sampled {
+			return []pendingSpan{{group: group, span: span}}
+		}
+		return nil
+	}
+	var released []pendingSpan
+	trace, ok := t.traces[traceID]
+	if !ok {
+		if len(t.traces) >= t.config.MaxTraces && len(t.order) > 0 {
+			released = t.decide(t.order[0], released)
+			t.order = t.order[1:]
+		}
+		trace = &pendingTrace{id: traceID, firstSeen: now, minStart: span.GetStartTime(), maxEnd: span.GetEndTime()}
+		t.traces[traceID] = trace
+		t.order = append(t.order, trace)
+	}
+	trace.spans = append(trace.spans, pendingSpan{group: group, span: span})
+	if span.GetStatus() == models.StatusCodeError {
+		trace.hasError = true
+	}
+	if span.GetStartTime() < trace.minStart {
+		trace.minStart = span.GetStartTime()
+	}
+	if span.GetEndTime() > trace.maxEnd {
+		trace.maxEnd = span.GetEndTime()
+	}
+	return released
+}
+
+// flush decides the traces which have waited long enough, and returns the spans of the sampled ones.
+func (t *tailSampler) flush(now time.Time) []pendingSpan {
+	var released []pendingSpan
+	wait := time.Duration(t.config.DecisionWaitMs) * time.Millisecond
+	for len(t.order) > 0 && now.Sub(t.order[0].firstSeen) >= wait {
+		released = t.decide(t.order[0], released)
+		t.order = t.order[1:]
+	}
+	return released
+}
+
+func (t *tailSampler) decide(trace *pendingTrace, released []pendingSpan) []pendingSpan {
+	delete(t.traces, trace.id)
+	sampled := t.sample(trace)
+	t.decisions.Add(trace.id, sampled)
+	if sampled {
+		released = append(released, trace.spans...)
+	}
+	return released
+}
+
+func (t *tailSampler) sample(trace *pendingTrace) bool {
+	if t.config.KeepErrors && trace.hasError {
+		return true
+	}
+	if t.config.LatencyThresholdMs > 0 &&
+		trace.maxEnd >= trace.minStart+uint64(time.Duration(t.config.LatencyThresholdMs)*time.Millisecond) {
+		return true
+	}
+	if t.config.SamplingPercentage <= 0 {
+		return false
+	}
+	// hash the trace id so that all agents make the same decision for the same trace
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(trace.id))
+	return float64(h.Sum32()%10000) < t.config.SamplingPercentage*100
+}
+
+func (t *tailSampler) reset() {
+	t.traces = make(map[string]*pendingTrace)
+	t.order = nil
+	t.decisions.Purge()
+}
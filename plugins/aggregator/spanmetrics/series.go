@@ -0,0 +1,109 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+const (
+	labelService    = "service_name"
+	labelSpanName   = "span_name"
+	labelSpanKind   = "span_kind"
+	labelStatusCode = "status_code"
+	labelBucket     = "le"
+)
+
+var statusCodeTexts = map[models.StatusCode]string{
+	models.StatusCodeUnSet: "unset",
+	models.StatusCodeOK:    "ok",
+	models.StatusCodeError: "error",
+}
+
+// series holds the cumulative RED statistics of the spans sharing the same labels.
+type series struct {
+	labels   map[string]string
+	calls    float64
+	buckets  []uint64 // the count of each latency bucket, the last one is +Inf
+	sum      float64  // the sum of the latencies in milliseconds
+	lastSeen time.Time
+}
+
+func newSeries(labels map[string]string, bucketNum int) *series {
+	return &series{
+		labels:  labels,
+		buckets: make([]uint64, bucketNum+1),
+	}
+}
+
+func (s *series) observe(bounds []float64, latencyMs float64, now time.Time) {
+	s.calls++
+	s.sum += latencyMs
+	s.buckets[sort.SearchFloat64s(bounds, latencyMs)]++
+	s.lastSeen = now
+}
+
+// appendMetrics converts the series to the calls counter and the latency histogram in the prometheus style.
+func (s *series) appendMetrics(events []models.PipelineEvent, namespace string, bounds []float64, ts int64) []models.PipelineEvent {
+	events = append(events, models.NewSingleValueMetric(namespace+"_calls_total", models.MetricTypeCounter,
+		s.tags(), ts, s.calls))
+
+	var cumulative uint64
+	for i, count := range s.buckets {
+		cumulative += count
+		le := math.Inf(1)
+		if i < len(bounds) {
+			le = bounds[i]
+		}
+		tags := s.tags()
+		tags.Add(labelBucket, formatBound(le))
+		events = append(events, models.NewSingleValueMetric(namespace+"_duration_milliseconds_bucket", models.MetricTypeCounter,
+			tags, ts, float64(cumulative)))
+	}
+	events = append(events,
+		models.NewSingleValueMetric(namespace+"_duration_milliseconds_sum", models.MetricTypeCounter,
+			s.tags(), ts, s.sum),
+		models.NewSingleValueMetric(namespace+"_duration_milliseconds_count", models.MetricTypeCounter,
+			s.tags(), ts, float64(cumulative)))
+	return events
+}
+
+// tags copies the labels, as the tags of each metric are owned and may be modified by the following plugins.
+func (s *series) tags() models.Tags {
+	tags := models.NewTags()
+	for k, v := range s.labels {
+		tags.Add(k, v)
+	}
+	return tags
+}
+
+func formatBound(bound float64) string {
+	if math.IsInf(bound, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+func spanLatencyMs(span *models.Span) float64 {
+	if span.GetEndTime() <= span.GetStartTime() {
+		return 0
+	}
+	return float64(span.GetEndTime()-span.GetStartTime()) / float64(time.Millisecond)
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretprovider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
+)
+
+var _ extensions.SecretProvider = (*ExtensionSecretProvider)(nil)
+
+// ExtensionSecretProvider reads the secrets from the files in a directory, e.g. a mounted kubernetes secret,
+// and then from the environment variables. The secrets are read on each call so that the rotation takes effect.
+type ExtensionSecretProvider struct {
+	Directory string // the secret is the content of the file named by the secret name in the directory
+	EnvPrefix string // the secret is the value of the environment variable named by the prefix and the secret name
+	EnvOnly   bool   // ignore the directory
+}
+
+func (e *ExtensionSecretProvider) Description() string {
+	return "secret provider extension to resolve the credentials from files or environment variables"
+}
+
+func (e *ExtensionSecretProvider) Init(context pipeline.Context) error {
+	if e.Directory == "" && !e.EnvOnly {
+		return fmt.Errorf("directory must be set unless env only")
+	}
+	return nil
+}
+
+func (e *ExtensionSecretProvider) Stop() error {
+	return nil
+}
+
+func (e *ExtensionSecretProvider) GetSecret(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	if !e.EnvOnly {
+		data, err := os.ReadFile(filepath.Join(e.Directory, name))
+		if err == nil {
+			return strings.TrimRight(string(data), "\r\n"), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	if value, ok := os.LookupEnv(e.EnvPrefix + name); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s not found", name)
+}
+
+func init() {
+	pipeline.AddExtensionCreator("ext_secret_provider", func() pipeline.Extension {
+		return &ExtensionSecretProvider{}
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretprovider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecret(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("file-secret\n"), 0600))
+	t.Setenv("ILOGTAIL_SECRET_db_user", "env-user")
+	t.Setenv("ILOGTAIL_SECRET_db_password", "env-secret")
+
+	p := &ExtensionSecretProvider{Directory: dir, EnvPrefix: "ILOGTAIL_SECRET_"}
+	require.NoError(t, p.Init(nil))
+	secret, err := p.GetSecret("db_password")
+	require.NoError(t, err)
+	assert.Equal(t, "file-secret", secret)
+	secret, err = p.GetSecret("db_user")
+	require.NoError(t, err)
+	assert.Equal(t, "env-user", secret)
+	_, err = p.GetSecret("missing")
+	assert.Error(t, err)
+	_, err = p.GetSecret("../db_password")
+	assert.Error(t, err)
+
+	p = &ExtensionSecretProvider{EnvPrefix: "ILOGTAIL_SECRET_", EnvOnly: true}
+	require.NoError(t, p.Init(nil))
+	secret, err = p.GetSecret("db_password")
+	require.NoError(t, err)
+	assert.Equal(t, "env-secret", secret)
+
+	assert.Error(t, (&ExtensionSecretProvider{}).Init(nil))
+}
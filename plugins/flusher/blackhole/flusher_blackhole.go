@@ -0,0 +1,206 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackhole
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "flusher_blackhole"
+
+// FlusherBlackhole discards all the data, it only counts the flushed data and digests the contents of a sample of
+// the logs, so that the throughput of a pipeline can be tested end to end with service_loadgen, and the data
+// flushed by different runs can be verified by the digests.
+type FlusherBlackhole struct {
+	ReportIntervalSec int      // The interval the counters are logged, 0 means only logged on stop.
+	HashSampleEvery   int      // About 1/N logs are digested, chosen by the hash so that the order does not matter, 0 disables.
+	HashKeys          []string // The content keys digested, all the contents if empty, e.g. exclude the time fields.
+
+	context    pipeline.Context
+	hashKeys   map[string]struct{}
+	lastReport time.Time
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// Stats are the counters of the flushed data.
+type Stats struct {
+	Events       int64
+	Groups       int64
+	Bytes        int64
+	SampledLogs  int64
+	ContentsHash uint64 // The sum of the hashes of the sampled logs, which does not depend on the order.
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("events=%d groups=%d bytes=%d sampled=%d hash=%016x", s.Events, s.Groups, s.Bytes, s.SampledLogs, s.ContentsHash)
+}
+
+// Init ...
+func (f *FlusherBlackhole) Init(context pipeline.Context) error {
+	f.context = context
+	if f.HashSampleEvery < 0 {
+		return fmt.Errorf("HashSampleEvery must not be negative for plugin %v", pluginType)
+	}
+	if len(f.HashKeys) > 0 {
+		f.hashKeys = make(map[string]struct{}, len(f.HashKeys))
+		for _, k := range f.HashKeys {
+			f.hashKeys[k] = struct{}{}
+		}
+	}
+	f.lastReport = time.Now()
+	return nil
+}
+
+// Description ...
+func (f *FlusherBlackhole) Description() string {
+	return "blackhole flusher discarding the data with verification counters for logtail"
+}
+
+// Flush counts the log groups and discards them.
+func (f *FlusherBlackhole) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	f.mu.Lock()
+	for _, logGroup := range logGroupList {
+		f.stats.Groups++
+		f.stats.Events += int64(len(logGroup.Logs))
+		f.stats.Bytes += int64(logGroup.Size())
+		if f.HashSampleEvery == 0 {
+			continue
+		}
+		for _, log := range logGroup.Logs {
+			contents := make([][2]string, 0, len(log.Contents))
+			for _, content := range log.Contents {
+				contents = append(contents, [2]string{content.Key, content.Value})
+			}
+			f.digest(contents)
+		}
+	}
+	f.mu.Unlock()
+	f.report()
+	return nil
+}
+
+// Export counts the group events and discards them, only the contents of the logs, including the body, are digested.
+func (f *FlusherBlackhole) Export(groups []*models.PipelineGroupEvents, ctx pipeline.PipelineContext) error {
+	f.mu.Lock()
+	for _, group := range groups {
+		f.stats.Groups++
+		f.stats.Events += int64(len(group.Events))
+		for _, event := range group.Events {
+			log, ok := event.(*models.Log)
+			if !ok {
+				f.stats.Bytes += event.GetSize()
+				continue
+			}
+			contents := make([][2]string, 0, log.GetIndices().Len())
+			size := int64(0)
+			for k, v := range log.GetIndices().Iterator() {
+				value := formatValue(v)
+				contents = append(contents, [2]string{k, value})
+				size += int64(len(k) + len(value))
+			}
+			f.stats.Bytes += size
+			if f.HashSampleEvery > 0 {
+				f.digest(contents)
+			}
+		}
+	}
+	f.mu.Unlock()
+	f.report()
+	return nil
+}
+
+// digest adds the hash of the log to the contents hash if the log is sampled, it must be called with the lock held.
+func (f *FlusherBlackhole) digest(contents [][2]string) {
+	sort.Slice(contents, func(i, j int) bool { return contents[i][0] < contents[j][0] })
+	h := fnv.New64a()
+	for _, kv := range contents {
+		if f.hashKeys != nil {
+			if _, ok := f.hashKeys[kv[0]]; !ok {
+				continue
+			}
+		}
+		_, _ = h.Write([]byte(kv[0]))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(kv[1]))
+		_, _ = h.Write([]byte{0})
+	}
+	sum := h.Sum64()
+	if sum%uint64(f.HashSampleEvery) != 0 {
+		return
+	}
+	f.stats.SampledLogs++
+	f.stats.ContentsHash += sum
+}
+
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func (f *FlusherBlackhole) report() {
+	if f.ReportIntervalSec <= 0 || time.Since(f.lastReport) < time.Duration(f.ReportIntervalSec)*time.Second {
+		return
+	}
+	f.lastReport = time.Now()
+	logger.Info(f.context.GetRuntimeContext(), "blackhole flushed", f.Stats().String())
+}
+
+// Stats returns a snapshot of the counters.
+func (f *FlusherBlackhole) Stats() Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// SetUrgent ...
+func (f *FlusherBlackhole) SetUrgent(flag bool) {
+}
+
+// IsReady is ready to flush
+func (f *FlusherBlackhole) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return true
+}
+
+// Stop logs the final counters.
+func (f *FlusherBlackhole) Stop() error {
+	logger.Info(f.context.GetRuntimeContext(), "blackhole flushed", f.Stats().String())
+	return nil
+}
+
+func init() {
+	pipeline.Flushers[pluginType] = func() pipeline.Flusher {
+		return &FlusherBlackhole{
+			ReportIntervalSec: 60,
+			HashSampleEvery:   1,
+		}
+	}
+}
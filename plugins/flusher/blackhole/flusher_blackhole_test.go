@@ -0,0 +1,144 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blackhole
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newFlusher() (*FlusherBlackhole, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	flusher := pipeline.Flushers[pluginType]().(*FlusherBlackhole)
+	err := flusher.Init(ctx)
+	return flusher, err
+}
+
+func newLogGroups(n int, time string, reverse bool) []*protocol.LogGroup {
+	logGroups := make([]*protocol.LogGroup, 0, n)
+	for i := 0; i < n; i++ {
+		idx := i
+		if reverse {
+			idx = n - 1 - i
+		}
+		logGroups = append(logGroups, &protocol.LogGroup{Logs: []*protocol.Log{{
+			Time: 1700000000,
+			Contents: []*protocol.Log_Content{
+				{Key: "seq", Value: strconv.Itoa(idx)},
+				{Key: "time", Value: time},
+			},
+		}}})
+	}
+	return logGroups
+}
+
+func TestBlackholeFlush(t *testing.T) {
+	f, err := newFlusher()
+	require.NoError(t, err)
+	logGroups := newLogGroups(10, "t1", false)
+	require.NoError(t, f.Flush("p", "l", "c", logGroups[:4]))
+	require.NoError(t, f.Flush("p", "l", "c", logGroups[4:]))
+	stats := f.Stats()
+	assert.Equal(t, int64(10), stats.Events)
+	assert.Equal(t, int64(10), stats.Groups)
+	size := 0
+	for _, logGroup := range logGroups {
+		size += logGroup.Size()
+	}
+	assert.Equal(t, int64(size), stats.Bytes)
+	assert.Equal(t, int64(10), stats.SampledLogs)
+
+	// the hash does not depend on the order
+	reversed, err := newFlusher()
+	require.NoError(t, err)
+	require.NoError(t, reversed.Flush("p", "l", "c", newLogGroups(10, "t1", true)))
+	assert.Equal(t, stats.ContentsHash, reversed.Stats().ContentsHash)
+
+	// the hash depends on the contents
+	other, err := newFlusher()
+	require.NoError(t, err)
+	require.NoError(t, other.Flush("p", "l", "c", newLogGroups(10, "t2", false)))
+	assert.NotEqual(t, stats.ContentsHash, other.Stats().ContentsHash)
+
+	// the keys not in HashKeys are ignored
+	a, err := newFlusher()
+	require.NoError(t, err)
+	a.HashKeys = []string{"seq"}
+	require.NoError(t, a.Init(mock.NewEmptyContext("p", "l", "c")))
+	b, err := newFlusher()
+	require.NoError(t, err)
+	b.HashKeys = []string{"seq"}
+	require.NoError(t, b.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.NoError(t, a.Flush("p", "l", "c", newLogGroups(10, "t1", false)))
+	require.NoError(t, b.Flush("p", "l", "c", newLogGroups(10, "t2", false)))
+	assert.Equal(t, a.Stats().ContentsHash, b.Stats().ContentsHash)
+	require.NoError(t, a.Stop())
+}
+
+func TestBlackholeSample(t *testing.T) {
+	f, err := newFlusher()
+	require.NoError(t, err)
+	f.HashSampleEvery = 4
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.NoError(t, f.Flush("p", "l", "c", newLogGroups(1000, "t", false)))
+	stats := f.Stats()
+	assert.Equal(t, int64(1000), stats.Events)
+	assert.InDelta(t, 250, stats.SampledLogs, 75)
+
+	disabled, err := newFlusher()
+	require.NoError(t, err)
+	disabled.HashSampleEvery = 0
+	require.NoError(t, disabled.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.NoError(t, disabled.Flush("p", "l", "c", newLogGroups(10, "t", false)))
+	assert.Equal(t, int64(0), disabled.Stats().SampledLogs)
+	assert.Equal(t, uint64(0), disabled.Stats().ContentsHash)
+
+	invalid, err := newFlusher()
+	require.NoError(t, err)
+	invalid.HashSampleEvery = -1
+	assert.Error(t, invalid.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestBlackholeExport(t *testing.T) {
+	f, err := newFlusher()
+	require.NoError(t, err)
+	log := models.NewLog("", []byte("body"), "", "", "", models.NewTags(), 0)
+	log.Contents.Add("key", "value")
+	metric := models.NewSingleValueMetric("m", models.MetricTypeGauge, models.NewTags(), 0, 1)
+	groups := []*models.PipelineGroupEvents{{
+		Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{log, metric},
+	}}
+	require.NoError(t, f.Export(groups, nil))
+	stats := f.Stats()
+	assert.Equal(t, int64(2), stats.Events)
+	assert.Equal(t, int64(1), stats.Groups)
+	assert.Equal(t, int64(len(models.BodyKey)+len("body")+len("key")+len("value"))+metric.GetSize(), stats.Bytes)
+	assert.Equal(t, int64(1), stats.SampledLogs)
+	assert.NotZero(t, stats.ContentsHash)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 
+	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -46,6 +47,8 @@ type FlusherClickHouse struct {
 	MaxExecutionTime int
 	// DialTimeout Dial timeout, the default is 10s
 	DialTimeout time.Duration
+	// Dialer controls the preferred address family of the connections
+	Dialer helper.DialConfig
 	// MaxOpenConns Maximum number of connections, including long and short connections, the default is 5
 	MaxOpenConns int
 	// MaxIdleConns The maximum number of idle connections, that is the size of the connection pool, the default is 5
@@ -168,6 +171,10 @@ func (f *FlusherClickHouse) Validate() error {
 		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init clickhouse flusher error", err)
 		return err
 	}
+	if err := f.Dialer.Validate(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init clickhouse flusher error", err)
+		return err
+	}
 	return nil
 }
 
@@ -226,11 +233,11 @@ func newConn(f *FlusherClickHouse) (driver.Conn, error) {
 		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "init clickhouse flusher error", err)
 		return nil, err
 	}
+	dialContext := f.Dialer.DialContext(&net.Dialer{})
 	opt := &clickhouse.Options{
 		Addr: f.Addresses,
 		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
-			var d net.Dialer
-			return d.DialContext(ctx, "tcp", addr)
+			return dialContext(ctx, "tcp", addr)
 		},
 		Settings: clickhouse.Settings{
 			"max_execution_time": f.MaxExecutionTime,
@@ -16,6 +16,7 @@ package elasticsearch
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -74,6 +75,10 @@ func (config *Authentication) ConfigureAuthenticationAndHTTP(httpcfg *HTTPConfig
 			}
 			transport.ResponseHeaderTimeout = unit
 		}
+		if err := httpcfg.Dialer.Validate(); err != nil {
+			return err
+		}
+		transport.DialContext = httpcfg.Dialer.DialContext(&net.Dialer{})
 	}
 	opts.Transport = transport
 
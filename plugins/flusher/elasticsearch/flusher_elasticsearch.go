@@ -26,6 +26,7 @@ import (
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 
+	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -54,6 +55,8 @@ type FlusherElasticSearch struct {
 type HTTPConfig struct {
 	MaxIdleConnsPerHost   int
 	ResponseHeaderTimeout string
+	// Dialer controls the preferred address family of the connections
+	Dialer helper.DialConfig
 }
 
 type convertConfig struct {
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 
 	"github.com/alibaba/ilogtail/pkg/fmtstr"
 	"github.com/alibaba/ilogtail/pkg/helper"
@@ -40,11 +43,14 @@ import (
 )
 
 const (
-	defaultTimeout = time.Minute
+	defaultTimeout         = time.Minute
+	defaultMaxThrottleWait = 5 * time.Minute
 
 	contentTypeHeader     = "Content-Type"
 	defaultContentType    = "application/octet-stream"
 	contentEncodingHeader = "Content-Encoding"
+	// zstdDictionaryIDHeader carries the id of the uploaded zstd dictionary
+	zstdDictionaryIDHeader = "X-Zstd-Dictionary-Id"
 )
 
 var contentTypeMaps = map[string]string{
@@ -57,6 +63,7 @@ var contentTypeMaps = map[string]string{
 var supportedCompressionType = map[string]any{
 	"gzip":   nil,
 	"snappy": nil,
+	"zstd":   nil,
 }
 
 type retryConfig struct {
@@ -83,13 +90,17 @@ type FlusherHTTP struct {
 	MaxIdleConnsPerHost    int                          // MaxIdleConnsPerHost for http.Transport
 	IdleConnTimeout        time.Duration                // IdleConnTimeout for http.Transport
 	WriteBufferSize        int                          // WriteBufferSize for http.Transport
+	Dialer                 helper.DialConfig            // Dialer controls the preferred address family of the connections
 	Authenticator          *extensions.ExtensionConfig  // name and options of the extensions.ClientAuthenticator extension to use
 	FlushInterceptor       *extensions.ExtensionConfig  // name and options of the extensions.FlushInterceptor extension to use
 	AsyncIntercept         bool                         // intercept the event asynchronously
 	RequestInterceptors    []extensions.ExtensionConfig // custom request interceptor settings
 	QueueCapacity          int                          // capacity of channel
 	DropEventWhenQueueFull bool                         // If true, pipeline events will be dropped when the queue is full
-	Compression            string                       // Compression type, support gzip and snappy at this moment.
+	Compression            string                       // Compression type, support gzip, snappy and zstd at this moment.
+	ZstdDictionary         zstdDictionaryConfig         // Train a zstd dictionary from the payloads to compress the repetitive logs better
+	HonorQuotaHeaders      bool                         // If true, pause sending as the remote asked by quota headers (Retry-After, X-RateLimit-*), default is true
+	MaxThrottleWait        time.Duration                // Max wait time of a single throttle reported by the remote, default is 5m
 
 	varKeys []string
 
@@ -98,16 +109,23 @@ type FlusherHTTP struct {
 	converter   *converter.Converter
 	client      Client
 	interceptor extensions.FlushInterceptor
+	throttle    *helper.QuotaThrottle
+	zstdEncoder *zstd.Encoder
+	zstdDict    *zstdDictionary
 
 	queue   chan interface{}
 	counter sync.WaitGroup
+	// stopCh is closed by Stop to cancel the throttle waits and the retries of the queued requests.
+	stopCh chan struct{}
 }
 
 func NewHTTPFlusher() *FlusherHTTP {
 	return &FlusherHTTP{
-		QueueCapacity: 1024,
-		Timeout:       defaultTimeout,
-		Concurrency:   1,
+		QueueCapacity:     1024,
+		Timeout:           defaultTimeout,
+		Concurrency:       1,
+		HonorQuotaHeaders: true,
+		MaxThrottleWait:   defaultMaxThrottleWait,
 		Convert: helper.ConvertConfig{
 			Protocol:             converter.ProtocolCustomSingle,
 			Encoding:             converter.EncodingJSON,
@@ -141,6 +159,11 @@ func (f *FlusherHTTP) Init(context pipeline.Context) error {
 		return err
 	}
 
+	if err := f.Dialer.Validate(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "http flusher check dialer fail, error", err)
+		return err
+	}
+
 	var err error
 	if err = f.initEncoder(); err != nil {
 		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "http flusher init encoder fail, error", err)
@@ -173,10 +196,20 @@ func (f *FlusherHTTP) Init(context pipeline.Context) error {
 		return err
 	}
 
+	if f.HonorQuotaHeaders {
+		f.throttle = helper.NewQuotaThrottle(f.MaxThrottleWait)
+	}
+
+	if err = f.initZstd(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "http flusher init zstd fail, error", err)
+		return err
+	}
+
 	if f.QueueCapacity <= 0 {
 		f.QueueCapacity = 1024
 	}
 	f.queue = make(chan interface{}, f.QueueCapacity)
+	f.stopCh = make(chan struct{})
 	for i := 0; i < f.Concurrency; i++ {
 		go f.runFlushTask()
 	}
@@ -216,12 +249,21 @@ func (f *FlusherHTTP) SetUrgent(flag bool) {
 }
 
 func (f *FlusherHTTP) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	if f.throttle != nil && f.throttle.Throttled() {
+		return false
+	}
 	return f.client != nil
 }
 
 func (f *FlusherHTTP) Stop() error {
+	if f.stopCh != nil {
+		close(f.stopCh)
+	}
 	f.counter.Wait()
 	close(f.queue)
+	if f.zstdDict != nil {
+		f.zstdDict.stop()
+	}
 	return nil
 }
 
@@ -229,6 +271,48 @@ func (f *FlusherHTTP) SetHTTPClient(client Client) {
 	f.client = client
 }
 
+func (f *FlusherHTTP) initZstd() error {
+	if f.Compression != "zstd" {
+		return nil
+	}
+	if f.ZstdDictionary.Enable {
+		dict, err := newZstdDictionary(f.ZstdDictionary)
+		if err != nil {
+			return err
+		}
+		if f.ZstdDictionary.UploadURL != "" {
+			dict.upload = f.uploadZstdDict
+		}
+		f.zstdDict = dict
+		f.zstdDict.start()
+		return nil
+	}
+	var err error
+	f.zstdEncoder, err = zstd.NewWriter(nil)
+	return err
+}
+
+// uploadZstdDict puts the trained dictionary to ZstdDictionary.UploadURL, the receiver must keep it before the payloads
+// compressed with it arrive.
+func (f *FlusherHTTP) uploadZstdDict(id uint32, dict []byte) error {
+	req, err := http.NewRequest(http.MethodPut, f.ZstdDictionary.UploadURL, bytes.NewReader(dict))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(contentTypeHeader, defaultContentType)
+	req.Header.Set(zstdDictionaryIDHeader, strconv.FormatUint(uint64(id), 10))
+	response, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return nil
+}
+
 func (f *FlusherHTTP) initEncoder() error {
 	if f.Encoder == nil {
 		return nil
@@ -291,6 +375,10 @@ func (f *FlusherHTTP) initHTTPClient() error {
 		if f.WriteBufferSize > 0 {
 			dt.WriteBufferSize = f.WriteBufferSize
 		}
+		if f.Dialer.PreferredAddressFamily != "" || f.Dialer.FallbackDelay > 0 {
+			// the same dialer as the one of http.DefaultTransport
+			dt.DialContext = f.Dialer.DialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+		}
 		transport = dt
 	}
 
@@ -463,18 +551,42 @@ func (f *FlusherHTTP) convertAndFlush(data interface{}) error {
 func (f *FlusherHTTP) flushWithRetry(data []byte, varValues map[string]string) error {
 	var err error
 	for i := 0; i <= f.Retry.MaxRetryTimes; i++ {
+		if f.throttle != nil {
+			f.throttle.Wait(f.stopCh)
+			if f.throttle.Throttled() {
+				// the flusher is stopped, the remote is not flooded with the queued requests
+				err = errors.New("http flusher is stopped while throttled by remote")
+				break
+			}
+		}
 		ok, retryable, e := f.flush(data, varValues)
-		if ok || !retryable || !f.Retry.Enable {
-			err = e
+		err = e
+		if ok || !retryable || !f.Retry.Enable || f.stopped() {
 			break
 		}
-		err = e
-		<-time.After(f.getNextRetryDelay(i))
+		delay := f.getNextRetryDelay(i)
+		if f.throttle != nil && f.throttle.Remaining() > delay {
+			// the throttle wait is done at the beginning of next attempt.
+			continue
+		}
+		select {
+		case <-time.After(delay):
+		case <-f.stopCh:
+		}
 	}
 	converter.PutPooledByteBuf(&data)
 	return err
 }
 
+func (f *FlusherHTTP) stopped() bool {
+	select {
+	case <-f.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
 func (f *FlusherHTTP) getNextRetryDelay(retryTime int) time.Duration {
 	delay := f.Retry.InitialDelay * 1 << time.Duration(retryTime)
 	if delay > f.Retry.MaxDelay {
@@ -508,6 +620,12 @@ func (f *FlusherHTTP) compressData(data []byte) (io.Reader, error) {
 		case "snappy":
 			compressedData := snappy.Encode(nil, data)
 			reader = bytes.NewReader(compressedData)
+		case "zstd":
+			if f.zstdDict != nil {
+				reader = bytes.NewReader(f.zstdDict.compress(data))
+			} else {
+				reader = bytes.NewReader(f.zstdEncoder.EncodeAll(data, nil))
+			}
 		default:
 		}
 	}
@@ -582,6 +700,14 @@ func (f *FlusherHTTP) flush(data []byte, varValues map[string]string) (ok, retry
 		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "http flusher close response body fail, error", err)
 		return false, false, err
 	}
+	if f.throttle != nil {
+		if wait := f.throttle.Observe(response); wait > 0 {
+			logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "http flusher is throttled by remote, url", req.URL.String(), "status", response.Status, "wait", wait)
+		}
+		if response.StatusCode == http.StatusTooManyRequests {
+			return false, true, fmt.Errorf("err status returned: %v", response.Status)
+		}
+	}
 	switch response.StatusCode / 100 {
 	case 2:
 		return true, false, nil
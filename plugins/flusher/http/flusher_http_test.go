@@ -701,6 +701,74 @@ func TestGetNextRetryDelay(t *testing.T) {
 	}
 }
 
+func TestHttpFlusherHonorQuotaHeaders(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	requests := 0
+	httpmock.RegisterResponder("POST", "http://test.com/write", func(req *http.Request) (*http.Response, error) {
+		requests++
+		if requests == 1 {
+			resp := httpmock.NewStringResponse(http.StatusTooManyRequests, "throttled")
+			resp.Header.Set("Retry-After", "0.2")
+			return resp, nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, "ok"), nil
+	})
+
+	flusher := NewHTTPFlusher()
+	flusher.RemoteURL = "http://test.com/write"
+	flusher.Retry.InitialDelay = time.Millisecond
+	flusher.Retry.MaxDelay = time.Millisecond
+	assert.NoError(t, flusher.Init(mockContext{}))
+
+	ok, retryable, err := flusher.flush([]byte("data"), nil)
+	assert.False(t, ok)
+	assert.True(t, retryable)
+	assert.Error(t, err)
+	assert.False(t, flusher.IsReady("p", "l", 1))
+
+	start := time.Now()
+	assert.NoError(t, flusher.flushWithRetry([]byte("data"), nil))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, 2, requests)
+	assert.True(t, flusher.IsReady("p", "l", 1))
+}
+
+func TestHttpFlusherStopWhileThrottled(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	requests := 0
+	httpmock.RegisterResponder("POST", "http://test.com/write", func(req *http.Request) (*http.Response, error) {
+		requests++
+		resp := httpmock.NewStringResponse(http.StatusTooManyRequests, "throttled")
+		resp.Header.Set("Retry-After", "60")
+		return resp, nil
+	})
+
+	flusher := NewHTTPFlusher()
+	flusher.RemoteURL = "http://test.com/write"
+	assert.NoError(t, flusher.Init(mockContext{}))
+	_, _, err := flusher.flush([]byte("data"), nil)
+	assert.Error(t, err)
+	assert.False(t, flusher.IsReady("p", "l", 1))
+
+	logGroup := &protocol.LogGroup{Logs: []*protocol.Log{{Contents: []*protocol.Log_Content{{Key: "k", Value: "v"}}}}}
+	assert.NoError(t, flusher.Flush("p", "l", "c", []*protocol.LogGroup{logGroup}))
+	done := make(chan struct{})
+	go func() {
+		_ = flusher.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop is blocked by the throttle")
+	}
+	assert.Equal(t, 1, requests, "the queued request is dropped instead of sent while throttled")
+}
+
 func TestHttpFlusherFlushWithInterceptor(t *testing.T) {
 	Convey("Given a http flusher with sync intercepter", t, func() {
 		mockIntercepter := &mockInterceptor{}
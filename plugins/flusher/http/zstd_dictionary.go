@@ -0,0 +1,266 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	defaultDictTrainInterval = 10 * time.Minute
+	defaultDictMaxSamples    = 1000
+	defaultDictMaxSize       = 110 << 10
+	defaultMaxDictionaries   = 10
+	// a sampled payload is truncated to it, the head of a payload is the most representative
+	maxDictSampleSize   = 16 << 10
+	minDictTrainSamples = 10
+	// the ids below it are reserved by the zstd format
+	minDictID = 1 << 15
+)
+
+type zstdDictionaryConfig struct {
+	Enable          bool          // If enable, train a zstd dictionary from the sampled payloads periodically when Compression is zstd
+	Dir             string        // Directory to save the trained dictionaries as <id>.dict, it must be shared with the receivers if UploadURL is empty, required if enabled
+	UploadURL       string        // URL to put the trained dictionaries to, the id is in the X-Zstd-Dictionary-Id header
+	TrainInterval   time.Duration // Interval to train a new dictionary, default is 10m
+	MaxSamples      int           // Max payloads sampled for a training, default is 1000
+	MaxSize         int           // Max size of the dictionary in bytes, default is 110KB
+	MaxDictionaries int           // Max dictionaries kept in Dir, the oldest ones are removed, default is 10
+}
+
+// zstdDictionary samples the payloads of a flusher and trains a dictionary from them periodically. The payloads are
+// compressed with the latest dictionary, whose id is written in the frame header so that the receiver picks the
+// dictionary saved in the directory or uploaded to it.
+type zstdDictionary struct {
+	config zstdDictionaryConfig
+	// upload delivers the dictionary to the receivers before it is used, nil if they read the directory.
+	upload func(id uint32, dict []byte) error
+
+	sampleLock sync.Mutex
+	samples    [][]byte
+	next       int
+
+	encoderLock sync.RWMutex
+	encoder     *zstd.Encoder
+
+	stopCh chan struct{}
+}
+
+func newZstdDictionary(config zstdDictionaryConfig) (*zstdDictionary, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("dir of the zstd dictionary is empty")
+	}
+	if err := os.MkdirAll(config.Dir, 0750); err != nil {
+		return nil, err
+	}
+	if config.TrainInterval <= 0 {
+		config.TrainInterval = defaultDictTrainInterval
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultDictMaxSamples
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultDictMaxSize
+	}
+	if config.MaxDictionaries <= 0 {
+		config.MaxDictionaries = defaultMaxDictionaries
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdDictionary{
+		config:  config,
+		samples: make([][]byte, 0, config.MaxSamples),
+		encoder: encoder,
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+func (d *zstdDictionary) start() {
+	go func() {
+		ticker := time.NewTicker(d.config.TrainInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.train(); err != nil {
+					logger.Warning(context.Background(), "FLUSHER_FLUSH_ALARM", "http flusher train zstd dictionary fail, error", err)
+				}
+			case <-d.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (d *zstdDictionary) stop() {
+	close(d.stopCh)
+}
+
+// compress samples the payload and compresses it with the latest dictionary.
+func (d *zstdDictionary) compress(data []byte) []byte {
+	d.sample(data)
+	d.encoderLock.RLock()
+	defer d.encoderLock.RUnlock()
+	return d.encoder.EncodeAll(data, nil)
+}
+
+// sample keeps the latest payloads, the payload is copied since its buffer is reused after flushing.
+func (d *zstdDictionary) sample(data []byte) {
+	if len(data) > maxDictSampleSize {
+		data = data[:maxDictSampleSize]
+	}
+	d.sampleLock.Lock()
+	defer d.sampleLock.Unlock()
+	if len(d.samples) < d.config.MaxSamples {
+		d.samples = append(d.samples, append([]byte(nil), data...))
+		return
+	}
+	d.samples[d.next] = append(d.samples[d.next][:0], data...)
+	d.next = (d.next + 1) % len(d.samples)
+}
+
+// train builds a dictionary whose content is the latest samples, saves it and compresses the following payloads with it.
+func (d *zstdDictionary) train() error {
+	d.sampleLock.Lock()
+	if len(d.samples) < minDictTrainSamples {
+		d.sampleLock.Unlock()
+		return nil
+	}
+	// from the oldest to the latest sample, zstd prefers the content at the end of the dictionary
+	samples := make([][]byte, 0, len(d.samples))
+	samples = append(samples, d.samples[d.next:]...)
+	samples = append(samples, d.samples[:d.next]...)
+	start, size := len(samples), 0
+	for start > 0 && size+len(samples[start-1]) <= d.config.MaxSize {
+		start--
+		size += len(samples[start])
+	}
+	history := make([]byte, 0, size)
+	for _, sample := range samples[start:] {
+		history = append(history, sample...)
+	}
+	if len(history) == 0 {
+		// the latest sample is larger than the dictionary
+		latest := samples[len(samples)-1]
+		history = append(history, latest[len(latest)-d.config.MaxSize:]...)
+	}
+	d.samples = d.samples[:0]
+	d.next = 0
+	d.sampleLock.Unlock()
+
+	id := minDictID + crc32.ChecksumIEEE(history)%(1<<31-minDictID)
+	dict, err := buildZstdDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: samples,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+	})
+	if err != nil {
+		return err
+	}
+	if err = d.save(id, dict); err != nil {
+		return err
+	}
+	if d.upload != nil {
+		if err = d.upload(id, dict); err != nil {
+			return fmt.Errorf("upload zstd dictionary %d fail: %w", id, err)
+		}
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict))
+	if err != nil {
+		return err
+	}
+	d.encoderLock.Lock()
+	previous := d.encoder
+	d.encoder = encoder
+	d.encoderLock.Unlock()
+	_ = previous.Close()
+	logger.Info(context.Background(), "http flusher trained zstd dictionary, id", id, "size", len(dict), "samples", len(samples))
+	if err = d.prune(); err != nil {
+		logger.Warning(context.Background(), "FLUSHER_FLUSH_ALARM", "http flusher remove old zstd dictionaries fail, error", err)
+	}
+	return nil
+}
+
+// buildZstdDict recovers the panic of zstd.BuildDict, which divides by zero if the samples have too few sequences.
+func buildZstdDict(options zstd.BuildDictOptions) (dict []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("build zstd dictionary panic: %v", r)
+		}
+	}()
+	return zstd.BuildDict(options)
+}
+
+// save writes the dictionary before using it, so that the receivers never see a frame of an unknown dictionary.
+func (d *zstdDictionary) save(id uint32, dict []byte) error {
+	path := filepath.Join(d.config.Dir, strconv.FormatUint(uint64(id), 10)+".dict")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, dict, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// prune removes the oldest dictionaries beyond MaxDictionaries, the recent ones are kept for the payloads still being
+// retried or received.
+func (d *zstdDictionary) prune() error {
+	entries, err := os.ReadDir(d.config.Dir)
+	if err != nil {
+		return err
+	}
+	type dictFile struct {
+		name    string
+		modTime time.Time
+	}
+	files := make([]dictFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".dict") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, dictFile{name: entry.Name(), modTime: info.ModTime()})
+	}
+	if len(files) <= d.config.MaxDictionaries {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for _, file := range files[d.config.MaxDictionaries:] {
+		if err = os.Remove(filepath.Join(d.config.Dir, file.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dictTestPayload returns an access log payload repetitive enough to train a dictionary.
+func dictTestPayload(i int) []byte {
+	var buf bytes.Buffer
+	for j := 0; j < 20; j++ {
+		fmt.Fprintf(&buf, `{"remote_addr":"10.0.%d.%d","method":"GET","uri":"/api/v1/items/%d","status":200,`+
+			`"user_agent":"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36","request_time":0.0%d}`+"\n", i%256, j, i*20+j, j%10)
+	}
+	return buf.Bytes()
+}
+
+func TestZstdDictionary(t *testing.T) {
+	_, err := newZstdDictionary(zstdDictionaryConfig{Enable: true})
+	assert.Error(t, err, "dir is required")
+
+	dir := t.TempDir()
+	dict, err := newZstdDictionary(zstdDictionaryConfig{Enable: true, Dir: dir, MaxSamples: 100})
+	require.NoError(t, err)
+	payload := dictTestPayload
+	// not enough samples to train
+	for i := 0; i < minDictTrainSamples-1; i++ {
+		dict.compress(payload(i))
+	}
+	require.NoError(t, dict.train())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	for i := 0; i < 200; i++ {
+		dict.compress(payload(i))
+	}
+	assert.Len(t, dict.samples, 100)
+	plain := dict.compress(payload(1000))
+	require.NoError(t, dict.train())
+	assert.Empty(t, dict.samples)
+	compressed := dict.compress(payload(1000))
+	assert.Less(t, len(compressed), len(plain))
+
+	// the receiver decompresses it with the saved dictionary
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	saved, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(saved))
+	require.NoError(t, err)
+	defer decoder.Close()
+	data, err := decoder.DecodeAll(compressed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, payload(1000), data)
+	dict.stop()
+
+	// too few sequences to build a dictionary
+	tiny, err := newZstdDictionary(zstdDictionaryConfig{Enable: true, Dir: t.TempDir()})
+	require.NoError(t, err)
+	for i := 0; i < minDictTrainSamples; i++ {
+		tiny.compress([]byte("tiny payload"))
+	}
+	assert.Error(t, tiny.train())
+}
+
+func TestZstdDictionaryDistribution(t *testing.T) {
+	var uploaded []string
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.NotEmpty(t, body)
+		uploaded = append(uploaded, r.Header.Get(zstdDictionaryIDHeader))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	flusher := &FlusherHTTP{
+		Compression:    "zstd",
+		ZstdDictionary: zstdDictionaryConfig{Enable: true, Dir: dir, UploadURL: server.URL, MaxDictionaries: 2},
+		client:         server.Client(),
+	}
+	require.NoError(t, flusher.initZstd())
+	dict := flusher.zstdDict
+	defer dict.stop()
+	train := func(round int) error {
+		for i := 0; i < 100; i++ {
+			dict.compress(dictTestPayload(round*100 + i))
+		}
+		return dict.train()
+	}
+
+	// the dictionaries are uploaded before they are used, and only the latest ones are kept in the directory
+	for round := 0; round < 3; round++ {
+		require.NoError(t, train(round))
+	}
+	assert.Len(t, uploaded, 3)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// the previous dictionary is still used if the upload fails
+	fail = true
+	encoder := dict.encoder
+	assert.Error(t, train(3))
+	assert.Same(t, encoder, dict.encoder)
+	assert.Len(t, uploaded, 3)
+}
+
+func TestFlusherHTTPZstdCompression(t *testing.T) {
+	for _, config := range []zstdDictionaryConfig{{}, {Enable: true, Dir: t.TempDir()}} {
+		flusher := &FlusherHTTP{Compression: "zstd", ZstdDictionary: config}
+		require.NoError(t, flusher.initZstd())
+		flusher.fillRequestContentType()
+		assert.Equal(t, "zstd", flusher.Headers[contentEncodingHeader])
+		reader, err := flusher.compressData([]byte("hello zstd"))
+		require.NoError(t, err)
+		decoder, err := zstd.NewReader(reader)
+		require.NoError(t, err)
+		data, err := io.ReadAll(decoder)
+		require.NoError(t, err)
+		assert.Equal(t, "hello zstd", string(data))
+		decoder.Close()
+		if flusher.zstdDict != nil {
+			flusher.zstdDict.stop()
+		}
+	}
+}
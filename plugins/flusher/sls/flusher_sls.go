@@ -18,8 +18,13 @@
 package sls
 
 import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
+	"github.com/alibaba/ilogtail/pkg/helper"
 	"github.com/alibaba/ilogtail/pkg/logtail"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -30,13 +35,31 @@ import (
 type SlsFlusher struct { // nolint:revive
 	EnableShardHash bool
 	KeepShardHash   bool
+	// ShardHashKeys are the fields whose values derive the shard hash key of each log, e.g. trace_id, so that the
+	// related logs land in the same shard. The hash key in the tags is ignored if it is set.
+	ShardHashKeys []string
+	// ProjectQuota limits the aggregate write rate of all the sls flushers in the agent writing to the same project,
+	// so that the pipelines sharing a project do not exceed its quota together.
+	ProjectQuota ProjectQuotaConfig
 
 	context pipeline.Context
+	limiter *helper.DestinationRateLimiter
+	urgent  atomic.Bool
+}
+
+// ProjectQuotaConfig is disabled if neither rate is set.
+type ProjectQuotaConfig struct {
+	// Endpoint tells apart the projects of the same name in different regions, it only takes part in the limiter key.
+	Endpoint             string
+	MaxBytesPerSecond    int64
+	MaxRequestsPerSecond int
 }
 
 // Init ...
 func (p *SlsFlusher) Init(context pipeline.Context) error {
 	p.context = context
+	p.limiter = helper.AcquireDestinationRateLimiter(p.ProjectQuota.Endpoint+"/"+context.GetProject(),
+		float64(p.ProjectQuota.MaxBytesPerSecond), float64(p.ProjectQuota.MaxRequestsPerSecond))
 	return nil
 }
 
@@ -48,7 +71,11 @@ func (p *SlsFlusher) Description() string {
 // IsReady ...
 // There is a sending queue in Logtail, call LogtailIsValidToSend through cgo
 // to make sure if there is any space for coming data.
+// The project quota is not checked once urgent, so that the remaining data is flushed before quitting.
 func (p *SlsFlusher) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	if !p.urgent.Load() && !p.limiter.Ready() {
+		return false
+	}
 	return logtail.IsValidToSend(logstoreKey)
 }
 
@@ -62,6 +89,15 @@ func (p *SlsFlusher) Flush(projectName string, logstoreName string, configName s
 			continue
 		}
 
+		if len(p.ShardHashKeys) > 0 {
+			for _, shard := range p.splitByShardHash(logGroup) {
+				if err := p.send(configName, shard.logGroup, shard.hashKey, true); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
 		var shardHash string
 		if p.EnableShardHash {
 			for idx, tag := range logGroup.LogTags {
@@ -74,36 +110,97 @@ func (p *SlsFlusher) Flush(projectName string, logstoreName string, configName s
 				}
 			}
 		}
-		buf, err := logGroup.Marshal()
-		if err != nil {
-			return fmt.Errorf("loggroup marshal err %v", err)
+		if err := p.send(configName, logGroup, shardHash, p.EnableShardHash); err != nil {
+			return err
 		}
+	}
 
-		var rst int
-		if !p.EnableShardHash {
-			rst = logtail.SendPb(configName, logGroup.Category, buf, len(logGroup.Logs))
-		} else {
-			rst = logtail.SendPbV2(configName, logGroup.Category, buf, len(logGroup.Logs), shardHash)
-		}
-		if rst < 0 {
-			return fmt.Errorf("send error %d", rst)
-		}
+	return nil
+}
+
+func (p *SlsFlusher) send(configName string, logGroup *protocol.LogGroup, shardHash string, withShardHash bool) error {
+	buf, err := logGroup.Marshal()
+	if err != nil {
+		return fmt.Errorf("loggroup marshal err %v", err)
 	}
+	p.limiter.Take(len(buf))
 
+	var rst int
+	if !withShardHash {
+		rst = logtail.SendPb(configName, logGroup.Category, buf, len(logGroup.Logs))
+	} else {
+		rst = logtail.SendPbV2(configName, logGroup.Category, buf, len(logGroup.Logs), shardHash)
+	}
+	if rst < 0 {
+		return fmt.Errorf("send error %d", rst)
+	}
 	return nil
 }
 
+type shardLogGroup struct {
+	hashKey  string
+	logGroup *protocol.LogGroup
+}
+
+// splitByShardHash splits the log group by the values of ShardHashKeys in the order of the first log of each shard,
+// the hash key is the md5 of the values joined by "_", an absent field counts as empty. The hash key tag is stripped
+// since it is not the one the shards are routed by.
+func (p *SlsFlusher) splitByShardHash(logGroup *protocol.LogGroup) []*shardLogGroup {
+	shards := make([]*shardLogGroup, 0, 1)
+	tags := make([]*protocol.LogTag, 0, len(logGroup.LogTags))
+	for _, tag := range logGroup.LogTags {
+		if tag.Key != util.ShardHashTagKey {
+			tags = append(tags, tag)
+		}
+	}
+	shardIndex := make(map[string]int)
+	values := make([]string, len(p.ShardHashKeys))
+	for _, log := range logGroup.Logs {
+		for i, key := range p.ShardHashKeys {
+			values[i] = ""
+			for _, content := range log.Contents {
+				if content.Key == key {
+					values[i] = content.Value
+					break
+				}
+			}
+		}
+		value := strings.Join(values, "_")
+		idx, ok := shardIndex[value]
+		if !ok {
+			idx = len(shards)
+			shardIndex[value] = idx
+			// The hash key must be the hex md5 by the SLS protocol, it only routes the shard and is not for security.
+			hash := md5.Sum([]byte(value)) //nolint:gosec
+			shards = append(shards, &shardLogGroup{
+				hashKey: hex.EncodeToString(hash[:]),
+				logGroup: &protocol.LogGroup{
+					Category:    logGroup.Category,
+					Topic:       logGroup.Topic,
+					Source:      logGroup.Source,
+					MachineUUID: logGroup.MachineUUID,
+					LogTags:     tags,
+				},
+			})
+		}
+		shards[idx].logGroup.Logs = append(shards[idx].logGroup.Logs, log)
+	}
+	return shards
+}
+
 // SetUrgent ...
 // We do nothing here because necessary flag has already been set in Logtail
 // before this method is called. Any future call of IsReady will return
 // true so that remaining data can be flushed to Logtail (which will flush
 // data to local file system) before it quits.
-func (*SlsFlusher) SetUrgent(flag bool) {
+func (p *SlsFlusher) SetUrgent(flag bool) {
+	p.urgent.Store(flag)
 }
 
-// Stop ...
-// We do nothing here because SlsFlusher does not create any resources.
-func (*SlsFlusher) Stop() error {
+// Stop releases the project quota shared with the other flushers.
+func (p *SlsFlusher) Stop() error {
+	p.limiter.Release()
+	p.limiter = nil
 	return nil
 }
 
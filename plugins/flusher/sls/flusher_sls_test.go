@@ -0,0 +1,101 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux || windows
+// +build linux windows
+
+package sls
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newTestLog(contents ...string) *protocol.Log {
+	log := &protocol.Log{}
+	for i := 0; i+1 < len(contents); i += 2 {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: contents[i], Value: contents[i+1]})
+	}
+	return log
+}
+
+func md5Hex(s string) string {
+	hash := md5.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(hash[:])
+}
+
+func TestSplitByShardHash(t *testing.T) {
+	p := &SlsFlusher{ShardHashKeys: []string{"trace_id", "user_id"}}
+	tags := []*protocol.LogTag{{Key: "__hostname__", Value: "host"}}
+	logGroup := &protocol.LogGroup{
+		Category: "logstore",
+		Topic:    "topic",
+		Source:   "127.0.0.1",
+		LogTags:  append([]*protocol.LogTag{{Key: util.ShardHashTagKey, Value: "stale"}}, tags...),
+		Logs: []*protocol.Log{
+			newTestLog("trace_id", "t1", "user_id", "u1", "content", "a"),
+			newTestLog("trace_id", "t2", "content", "b"),
+			newTestLog("user_id", "u1", "trace_id", "t1", "content", "c"),
+		},
+	}
+
+	shards := p.splitByShardHash(logGroup)
+	require.Len(t, shards, 2)
+	assert.Equal(t, md5Hex("t1_u1"), shards[0].hashKey)
+	require.Len(t, shards[0].logGroup.Logs, 2)
+	assert.Equal(t, "a", shards[0].logGroup.Logs[0].Contents[2].Value)
+	assert.Equal(t, "c", shards[0].logGroup.Logs[1].Contents[2].Value)
+	assert.Equal(t, md5Hex("t2_"), shards[1].hashKey)
+	require.Len(t, shards[1].logGroup.Logs, 1)
+	for _, shard := range shards {
+		assert.Equal(t, "logstore", shard.logGroup.Category)
+		assert.Equal(t, "topic", shard.logGroup.Topic)
+		assert.Equal(t, "127.0.0.1", shard.logGroup.Source)
+		assert.Equal(t, tags, shard.logGroup.LogTags)
+	}
+}
+
+func TestProjectQuotaShared(t *testing.T) {
+	newFlusher := func(project string) *SlsFlusher {
+		p := &SlsFlusher{ProjectQuota: ProjectQuotaConfig{Endpoint: "cn-hangzhou", MaxBytesPerSecond: 1024}}
+		require.NoError(t, p.Init(mock.NewEmptyContext(project, "logstore", "config")))
+		return p
+	}
+	p1, p2, other := newFlusher("project"), newFlusher("project"), newFlusher("other")
+	require.NotNil(t, p1.limiter)
+	assert.Same(t, p1.limiter, p2.limiter)
+	assert.NotSame(t, p1.limiter, other.limiter)
+
+	// the write of one flusher holds back the others of the same project
+	p1.limiter.Take(4096)
+	assert.False(t, p2.IsReady("project", "logstore", 0))
+	assert.True(t, other.limiter.Ready())
+
+	for _, p := range []*SlsFlusher{p1, p2, other} {
+		require.NoError(t, p.Stop())
+		assert.Nil(t, p.limiter)
+	}
+
+	plain := &SlsFlusher{}
+	require.NoError(t, plain.Init(mock.NewEmptyContext("project", "logstore", "config")))
+	assert.Nil(t, plain.limiter, "no limiter without the quota")
+}
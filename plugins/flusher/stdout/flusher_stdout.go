@@ -51,15 +51,39 @@ type FlusherStdout struct {
 	KeyValuePairs bool
 	Tags          bool
 	OnlyStdout    bool
+	// RenderMode is one of json, pretty, compact and table, default is json.
+	// The pretty, compact and table modes print the flattened fields, and the table mode aligns the events of a flush.
+	RenderMode string
+	// Color prints the fields with ANSI colors in the pretty, compact and table modes, usually with OnlyStdout.
+	Color          bool
+	MaxColumnWidth int      // The max width of the table columns, longer values are truncated, default is 32.
+	Include        []string // Only print the content and tag keys in the list if not empty.
+	Exclude        []string // Do not print the content and tag keys in the list.
+	SampleEvery    int      // Only print 1 in N events, all events are printed if not greater than 1.
 
 	context   pipeline.Context
 	outLogger seelog.LoggerInterface
+	filter    *fieldFilter
+	renderer  *renderer
+	counter   int
 }
 
 // Init method would be trigger before working. For the plugin, init method choose the log output
 // channel.
 func (p *FlusherStdout) Init(context pipeline.Context) error {
 	p.context = context
+	switch p.RenderMode {
+	case "":
+		p.RenderMode = renderModeJSON
+	case renderModeJSON, renderModePretty, renderModeCompact, renderModeTable:
+	default:
+		return fmt.Errorf("invalid RenderMode %s, should be one of json, pretty, compact and table", p.RenderMode)
+	}
+	if p.MaxColumnWidth <= 0 {
+		p.MaxColumnWidth = 32
+	}
+	p.filter = newFieldFilter(p.Include, p.Exclude)
+	p.renderer = &renderer{mode: p.RenderMode, color: p.Color, maxColumnWidth: p.MaxColumnWidth}
 
 	pattern := ""
 	if p.OnlyStdout {
@@ -92,6 +116,7 @@ func (*FlusherStdout) Description() string {
 
 // Flush the logGroup list to stdout or files.
 func (p *FlusherStdout) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	var records []record
 	for _, logGroup := range logGroupList {
 		if p.Tags {
 			if p.outLogger != nil {
@@ -101,11 +126,21 @@ func (p *FlusherStdout) Flush(projectName string, logstoreName string, configNam
 			}
 		}
 
-		if p.KeyValuePairs {
-			for _, log := range logGroup.Logs {
+		for _, log := range logGroup.Logs {
+			if !p.sample() {
+				continue
+			}
+			if p.RenderMode != renderModeJSON {
+				records = append(records, logRecord(log, p.filter))
+				continue
+			}
+			if p.KeyValuePairs {
 				writer := jsoniter.NewStream(jsoniter.ConfigDefault, nil, 128)
 				writer.WriteObjectStart()
 				for _, c := range log.Contents {
+					if !p.filter.keep(c.Key) {
+						continue
+					}
 					writer.WriteObjectField(c.Key)
 					writer.WriteString(c.Value)
 					_, _ = writer.Write([]byte{','})
@@ -113,30 +148,19 @@ func (p *FlusherStdout) Flush(projectName string, logstoreName string, configNam
 				writer.WriteObjectField("__time__")
 				writer.WriteString(strconv.Itoa(int(log.Time)))
 				writer.WriteObjectEnd()
-
-				if p.outLogger != nil {
-					p.outLogger.Infof("%s", writer.Buffer())
-				} else {
-					logger.Info(p.context.GetRuntimeContext(), string(writer.Buffer()))
-				}
-			}
-
-		} else {
-			for _, log := range logGroup.Logs {
-				buf, _ := json.Marshal(log)
-				if p.outLogger != nil {
-					p.outLogger.Infof("%s", buf)
-				} else {
-					logger.Info(p.context.GetRuntimeContext(), string(buf))
-				}
+				p.print(writer.Buffer())
+			} else {
+				buf, _ := json.Marshal(p.filterLog(log))
+				p.print(buf)
 			}
 		}
-
 	}
+	p.printRecords(records)
 	return nil
 }
 
 func (p *FlusherStdout) Export(in []*models.PipelineGroupEvents, context pipeline.PipelineContext) error {
+	var records []record
 	for _, groupEvents := range in {
 
 		if p.Tags {
@@ -150,19 +174,17 @@ func (p *FlusherStdout) Export(in []*models.PipelineGroupEvents, context pipelin
 		}
 
 		for _, event := range groupEvents.Events {
+			if !p.sample() {
+				continue
+			}
+			if p.RenderMode != renderModeJSON {
+				records = append(records, eventRecord(event, p.filter))
+				continue
+			}
 			writer := jsoniter.NewStream(jsoniter.ConfigDefault, nil, 128)
 			writer.WriteObjectStart()
 			writer.WriteObjectField("eventType")
-			switch event.GetType() {
-			case models.EventTypeMetric:
-				writer.WriteString("metric")
-			case models.EventTypeSpan:
-				writer.WriteString("span")
-			case models.EventTypeLogging:
-				writer.WriteString("log")
-			case models.EventTypeByteArray:
-				writer.WriteString("byteArray")
-			}
+			writer.WriteString(eventTypeText(event.GetType()))
 			_, _ = writer.Write([]byte{','})
 			writer.WriteObjectField("name")
 			writer.WriteString(event.GetName())
@@ -175,14 +197,17 @@ func (p *FlusherStdout) Export(in []*models.PipelineGroupEvents, context pipelin
 			_, _ = writer.Write([]byte{','})
 			writer.WriteObjectField("tags")
 			writer.WriteObjectStart()
-			i := 0
+			first := true
 			for k, v := range event.GetTags().Iterator() {
-				writer.WriteObjectField(k)
-				writer.WriteString(v)
-				if i < event.GetTags().Len()-1 {
+				if !p.filter.keep(k) {
+					continue
+				}
+				if !first {
 					_, _ = writer.Write([]byte{','})
 				}
-				i++
+				writer.WriteObjectField(k)
+				writer.WriteString(v)
+				first = false
 			}
 			writer.WriteObjectEnd()
 			_, _ = writer.Write([]byte{','})
@@ -198,17 +223,53 @@ func (p *FlusherStdout) Export(in []*models.PipelineGroupEvents, context pipelin
 			}
 
 			writer.WriteObjectEnd()
-
-			if p.outLogger != nil {
-				p.outLogger.Infof("%s", writer.Buffer())
-			} else {
-				logger.Info(p.context.GetRuntimeContext(), string(writer.Buffer()))
-			}
+			p.print(writer.Buffer())
 		}
 	}
+	p.printRecords(records)
 	return nil
 }
 
+// sample returns true for 1 in SampleEvery events.
+func (p *FlusherStdout) sample() bool {
+	if p.SampleEvery <= 1 {
+		return true
+	}
+	p.counter++
+	if p.counter >= p.SampleEvery {
+		p.counter = 0
+	}
+	return p.counter == 1
+}
+
+// filterLog returns a copy of the log with the filtered contents.
+func (p *FlusherStdout) filterLog(log *protocol.Log) *protocol.Log {
+	if p.filter == nil {
+		return log
+	}
+	filtered := &protocol.Log{Time: log.Time, TimeNs: log.TimeNs, Contents: make([]*protocol.Log_Content, 0, len(log.Contents))}
+	for _, c := range log.Contents {
+		if p.filter.keep(c.Key) {
+			filtered.Contents = append(filtered.Contents, c)
+		}
+	}
+	return filtered
+}
+
+func (p *FlusherStdout) printRecords(records []record) {
+	for _, line := range p.renderer.render(records) {
+		p.print([]byte(line))
+	}
+}
+
+func (p *FlusherStdout) print(line []byte) {
+	if p.outLogger != nil {
+		p.outLogger.Infof("%s", line)
+	} else {
+		logger.Info(p.context.GetRuntimeContext(), string(line))
+	}
+}
+
 func (p *FlusherStdout) writeMetricValues(writer *jsoniter.Stream, metric *models.Metric) {
 	writer.WriteObjectField("metricType")
 	writer.WriteString(models.MetricTypeTexts[metric.GetMetricType()])
@@ -271,6 +332,9 @@ func (p *FlusherStdout) writeLogBody(writer *jsoniter.Stream, log *models.Log) {
 	writer.WriteString(log.GetSpanID())
 	contents := log.GetIndices()
 	for key, value := range contents.Iterator() {
+		if !p.filter.keep(key) {
+			continue
+		}
 		_, _ = writer.Write([]byte{','})
 		writer.WriteObjectField(key)
 		_, _ = writer.Write([]byte(fmt.Sprintf("%#v", value)))
@@ -0,0 +1,105 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newFlusher(t *testing.T, f *FlusherStdout) *FlusherStdout {
+	f.KeyValuePairs = true
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+	logger.ClearMemoryLog()
+	return f
+}
+
+func readLines() []string {
+	var lines []string
+	for i := 0; i < logger.GetMemoryLogCount(); i++ {
+		line, _ := logger.ReadMemoryLog(i + 1)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func newLogGroup(n int) *protocol.LogGroup {
+	group := &protocol.LogGroup{}
+	for i := 0; i < n; i++ {
+		group.Logs = append(group.Logs, &protocol.Log{Time: 1700000000, Contents: []*protocol.Log_Content{
+			{Key: "level", Value: "INFO"},
+			{Key: "message", Value: "hello world"},
+			{Key: "secret", Value: "x"},
+		}})
+	}
+	return group
+}
+
+func TestInvalidRenderMode(t *testing.T) {
+	f := &FlusherStdout{RenderMode: "yaml"}
+	assert.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestJSONFilterAndSample(t *testing.T) {
+	f := newFlusher(t, &FlusherStdout{Exclude: []string{"secret"}, SampleEvery: 2})
+	require.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup(3)}))
+	lines := readLines()
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Contains(t, line, `{"level":"INFO","message":"hello world","__time__":"1700000000"}`)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	f := newFlusher(t, &FlusherStdout{RenderMode: "compact", Include: []string{"message"}})
+	require.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup(1)}))
+	lines := readLines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `__time__=1700000000 message="hello world"`)
+}
+
+func TestTable(t *testing.T) {
+	f := newFlusher(t, &FlusherStdout{RenderMode: "table", MaxColumnWidth: 8})
+	require.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{newLogGroup(2)}))
+	lines := readLines()
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], "__time__   | level | message  | secret")
+	assert.Contains(t, lines[1], "-----------+-------+----------+-------")
+	assert.Contains(t, lines[2], "1700000000 | INFO  | hello w… | x     ")
+}
+
+func TestPrettyColorEvents(t *testing.T) {
+	f := newFlusher(t, &FlusherStdout{RenderMode: "pretty", Color: true, Exclude: []string{"host"}})
+	log := models.NewLog("", []byte("hello"), "", "", "", models.NewTagsWithKeyValues("host", "h1", "app", "a1"), 1)
+	group := &models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: []models.PipelineEvent{log}}
+	require.NoError(t, f.Export([]*models.PipelineGroupEvents{group}, nil))
+	lines := readLines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], colorCyan+`"__tag__:app"`+colorReset+": "+colorGreen+`"a1"`+colorReset)
+	assert.Contains(t, lines[0], colorCyan+`"content"`+colorReset+": "+colorGreen+`"hello"`+colorReset)
+	assert.Contains(t, lines[0], colorCyan+`"eventType"`+colorReset+": "+colorYellow+`"log"`+colorReset)
+	assert.NotContains(t, lines[0], "h1")
+}
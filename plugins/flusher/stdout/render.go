@@ -0,0 +1,301 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const (
+	renderModeJSON    = "json"
+	renderModePretty  = "pretty"
+	renderModeCompact = "compact"
+	renderModeTable   = "table"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// field is a printed key value pair, the system fields such as the time are never filtered.
+type field struct {
+	key    string
+	value  string
+	system bool
+}
+
+type record []field
+
+// fieldFilter keeps the fields in Include if not empty, and removes the fields in Exclude.
+type fieldFilter struct {
+	include map[string]struct{}
+	exclude map[string]struct{}
+}
+
+func newFieldFilter(include, exclude []string) *fieldFilter {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	f := &fieldFilter{}
+	if len(include) > 0 {
+		f.include = make(map[string]struct{}, len(include))
+		for _, k := range include {
+			f.include[k] = struct{}{}
+		}
+	}
+	f.exclude = make(map[string]struct{}, len(exclude))
+	for _, k := range exclude {
+		f.exclude[k] = struct{}{}
+	}
+	return f
+}
+
+func (f *fieldFilter) keep(key string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil {
+		if _, ok := f.include[key]; !ok {
+			return false
+		}
+	}
+	_, ok := f.exclude[key]
+	return !ok
+}
+
+func logRecord(log *protocol.Log, filter *fieldFilter) record {
+	r := make(record, 0, len(log.Contents)+1)
+	r = append(r, field{key: "__time__", value: strconv.FormatUint(uint64(log.Time), 10), system: true})
+	for _, c := range log.Contents {
+		if filter.keep(c.Key) {
+			r = append(r, field{key: c.Key, value: c.Value})
+		}
+	}
+	return r
+}
+
+// eventRecord flattens the event, the tags are prefixed with __tag__: and filtered by the tag keys.
+func eventRecord(event models.PipelineEvent, filter *fieldFilter) record {
+	r := record{
+		{key: "eventType", value: eventTypeText(event.GetType()), system: true},
+		{key: "name", value: event.GetName(), system: true},
+		{key: "timestamp", value: strconv.FormatUint(event.GetTimestamp(), 10), system: true},
+	}
+	if tags := event.GetTags(); tags != nil {
+		for _, kv := range tags.SortTo(nil) {
+			if filter.keep(kv.Key) {
+				r = append(r, field{key: "__tag__:" + kv.Key, value: kv.Value})
+			}
+		}
+	}
+	switch e := event.(type) {
+	case *models.Log:
+		for _, kv := range e.GetIndices().SortTo(nil) {
+			if filter.keep(kv.Key) {
+				r = append(r, field{key: kv.Key, value: formatValue(kv.Value)})
+			}
+		}
+	case *models.Metric:
+		r = append(r, field{key: "metricType", value: models.MetricTypeTexts[e.GetMetricType()], system: true})
+		if e.GetValue().IsSingleValue() {
+			r = append(r, field{key: "value", value: strconv.FormatFloat(e.GetValue().GetSingleValue(), 'g', -1, 64), system: true})
+		} else {
+			for _, kv := range e.GetValue().GetMultiValues().SortTo(nil) {
+				if filter.keep(kv.Key) {
+					r = append(r, field{key: kv.Key, value: strconv.FormatFloat(kv.Value, 'g', -1, 64)})
+				}
+			}
+		}
+	case models.ByteArray:
+		r = append(r, field{key: "byteArray", value: string(e), system: true})
+	}
+	return r
+}
+
+func eventTypeText(t models.EventType) string {
+	switch t {
+	case models.EventTypeMetric:
+		return "metric"
+	case models.EventTypeSpan:
+		return "span"
+	case models.EventTypeLogging:
+		return "log"
+	case models.EventTypeByteArray:
+		return "byteArray"
+	}
+	return ""
+}
+
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// renderer renders the records into the printed lines.
+type renderer struct {
+	mode           string
+	color          bool
+	maxColumnWidth int
+}
+
+func (r *renderer) paint(s, color string) string {
+	if !r.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (r *renderer) paintValue(f field) string {
+	if f.system {
+		return r.paint(f.value, colorYellow)
+	}
+	return r.paint(f.value, colorGreen)
+}
+
+// render returns the lines of the records, the table mode aligns all the records as the rows of a table.
+func (r *renderer) render(records []record) []string {
+	switch r.mode {
+	case renderModePretty:
+		lines := make([]string, 0, len(records))
+		for _, rec := range records {
+			lines = append(lines, r.renderPretty(rec))
+		}
+		return lines
+	case renderModeCompact:
+		lines := make([]string, 0, len(records))
+		for _, rec := range records {
+			lines = append(lines, r.renderCompact(rec))
+		}
+		return lines
+	case renderModeTable:
+		return r.renderTable(records)
+	}
+	return nil
+}
+
+func (r *renderer) renderPretty(rec record) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, f := range rec {
+		b.WriteString("  ")
+		b.WriteString(r.paint(strconv.Quote(f.key), colorCyan))
+		b.WriteString(": ")
+		b.WriteString(r.paintValue(field{value: strconv.Quote(f.value), system: f.system}))
+		if i < len(rec)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func (r *renderer) renderCompact(rec record) string {
+	var b strings.Builder
+	for i, f := range rec {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(r.paint(f.key, colorCyan))
+		b.WriteByte('=')
+		value := f.value
+		if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(r.paintValue(field{value: value, system: f.system}))
+	}
+	return b.String()
+}
+
+func (r *renderer) renderTable(records []record) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	var columns []string
+	index := map[string]int{}
+	widths := []int{}
+	for _, rec := range records {
+		for _, f := range rec {
+			i, ok := index[f.key]
+			if !ok {
+				i = len(columns)
+				index[f.key] = i
+				columns = append(columns, f.key)
+				widths = append(widths, r.cellWidth(f.key, f.system))
+			}
+			if w := r.cellWidth(f.value, f.system); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	lines := make([]string, 0, len(records)+2)
+	header := make([]string, len(columns))
+	separator := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = r.paint(r.pad(c, widths[i]), colorBold)
+		separator[i] = strings.Repeat("-", widths[i])
+	}
+	lines = append(lines, strings.Join(header, " | "), strings.Join(separator, "-+-"))
+	for _, rec := range records {
+		cells := make([]string, len(columns))
+		system := make([]bool, len(columns))
+		for _, f := range rec {
+			cells[index[f.key]] = f.value
+			system[index[f.key]] = f.system
+		}
+		for i := range cells {
+			cells[i] = r.paintValue(field{value: r.pad(cells[i], widths[i]), system: system[i]})
+		}
+		lines = append(lines, strings.Join(cells, " | "))
+	}
+	return lines
+}
+
+// cellWidth returns the width of the cell, the system fields are never truncated.
+func (r *renderer) cellWidth(s string, system bool) int {
+	w := len([]rune(s))
+	if !system && r.maxColumnWidth > 0 && w > r.maxColumnWidth {
+		return r.maxColumnWidth
+	}
+	return w
+}
+
+// pad truncates the cell to the width with an ellipsis, and pads it with spaces. The line breaks are escaped.
+func (r *renderer) pad(s string, width int) string {
+	s = strings.NewReplacer("\n", "\\n", "\r", "\\r", "\t", "\\t").Replace(s)
+	runes := []rune(s)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,420 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	converter "github.com/alibaba/ilogtail/pkg/protocol/converter"
+	"github.com/alibaba/ilogtail/pkg/tlscommon"
+)
+
+const (
+	defaultHandshakeTimeout = 10 * time.Second
+	defaultWriteTimeout     = 10 * time.Second
+	defaultPingInterval     = 30 * time.Second
+	defaultQueueCapacity    = 1024
+
+	authorizationHeader = "Authorization"
+)
+
+type reconnectConfig struct {
+	InitialDelay time.Duration // Delay time before the first reconnection, default is 1s
+	MaxDelay     time.Duration // Max delay time between reconnections, default is 30s
+}
+
+type FlusherWebSocket struct {
+	URL                    string               // URL of the websocket receiver, must start with ws:// or wss://
+	Headers                map[string]string    // Headers to append to the handshake request
+	Token                  string               // Token sent as `Authorization: Bearer <Token>` in the handshake request
+	TLS                    *tlscommon.TLSConfig // TLS config used when dialing wss:// endpoints
+	Convert                helper.ConvertConfig // Convert defines which protocol and format to convert to
+	MessageType            string               // Websocket message type, `text` or `binary`, default is text
+	HandshakeTimeout       time.Duration        // Handshake timeout, default is 10s
+	WriteTimeout           time.Duration        // Write deadline of every message, default is 10s
+	PingInterval           time.Duration        // Interval of keepalive ping frames, default is 30s, negative value disables ping
+	Reconnect              reconnectConfig      // Reconnect strategy, default is backoff from 1s to 30s
+	QueueCapacity          int                  // Capacity of the message queue, default is 1024
+	DropEventWhenQueueFull bool                 // If true, events will be dropped when the queue is full
+
+	context     pipeline.Context
+	converter   *converter.Converter
+	converterV2 *converter.Converter // converter used by Export, v2 pipelines only support the raw and influxdb protocols
+	dialer      *websocket.Dialer
+	header      http.Header
+	messageType int
+
+	conn       *websocket.Conn
+	connBroken chan struct{}
+	queue      chan []byte
+	queueLock  sync.RWMutex // guards queue against being closed while Flush or Export is adding messages
+	closed     bool
+	stopCh     chan struct{}
+	stopped    sync.WaitGroup
+}
+
+func NewWebSocketFlusher() *FlusherWebSocket {
+	return &FlusherWebSocket{
+		Convert: helper.ConvertConfig{
+			Protocol:             converter.ProtocolCustomSingle,
+			Encoding:             converter.EncodingJSON,
+			IgnoreUnExpectedData: true,
+		},
+		MessageType:      "text",
+		HandshakeTimeout: defaultHandshakeTimeout,
+		WriteTimeout:     defaultWriteTimeout,
+		PingInterval:     defaultPingInterval,
+		Reconnect: reconnectConfig{
+			InitialDelay: time.Second,
+			MaxDelay:     30 * time.Second,
+		},
+		QueueCapacity: defaultQueueCapacity,
+	}
+}
+
+func (f *FlusherWebSocket) Description() string {
+	return "websocket flusher for ilogtail, streaming events to a websocket receiver"
+}
+
+func (f *FlusherWebSocket) Init(context pipeline.Context) error {
+	f.context = context
+	if err := f.validate(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "websocket flusher init fail, error", err)
+		return err
+	}
+
+	conv, err := converter.NewConverterWithSep(f.Convert.Protocol, f.Convert.Encoding, f.Convert.Separator, f.Convert.IgnoreUnExpectedData,
+		f.Convert.TagFieldsRename, f.Convert.ProtocolFieldsRename, f.context.GetPipelineScopeConfig())
+	if err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "websocket flusher init converter fail, error", err)
+		return err
+	}
+	f.converter = conv
+	if f.converterV2, err = f.newConverterV2(); err != nil {
+		logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "websocket flusher init v2 converter fail, error", err)
+		return err
+	}
+
+	f.dialer = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: f.HandshakeTimeout,
+	}
+	if f.TLS != nil {
+		tlsConfig, err := f.TLS.LoadTLSConfig()
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_INIT_ALARM", "websocket flusher load tls config fail, error", err)
+			return err
+		}
+		f.dialer.TLSClientConfig = tlsConfig
+	}
+
+	f.header = make(http.Header, len(f.Headers)+1)
+	for k, v := range f.Headers {
+		f.header.Set(k, v)
+	}
+	if f.Token != "" {
+		f.header.Set(authorizationHeader, "Bearer "+f.Token)
+	}
+
+	if f.QueueCapacity <= 0 {
+		f.QueueCapacity = defaultQueueCapacity
+	}
+	f.queue = make(chan []byte, f.QueueCapacity)
+	f.stopCh = make(chan struct{})
+	f.stopped.Add(1)
+	go f.run()
+
+	logger.Info(f.context.GetRuntimeContext(), "websocket flusher init", "initialized", "url", f.URL)
+	return nil
+}
+
+// newConverterV2 returns the converter used by Export. ToByteStreamWithSelectedFieldsV2 only supports
+// the raw and influxdb protocols, so other protocols, including the default custom_single, fall back to raw.
+func (f *FlusherWebSocket) newConverterV2() (*converter.Converter, error) {
+	switch f.Convert.Protocol {
+	case converter.ProtocolRaw, converter.ProtocolInfluxdb:
+		return f.converter, nil
+	}
+	return converter.NewConverterWithSep(converter.ProtocolRaw, converter.EncodingCustom, f.Convert.Separator, f.Convert.IgnoreUnExpectedData,
+		f.Convert.TagFieldsRename, f.Convert.ProtocolFieldsRename, f.context.GetPipelineScopeConfig())
+}
+
+func (f *FlusherWebSocket) validate() error {
+	if f.URL == "" {
+		return errors.New("url is empty")
+	}
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", f.URL, err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("unsupported url scheme %s, only ws and wss are supported", u.Scheme)
+	}
+	switch f.MessageType {
+	case "", "text":
+		f.messageType = websocket.TextMessage
+	case "binary":
+		f.messageType = websocket.BinaryMessage
+	default:
+		return fmt.Errorf("unsupported message type %s", f.MessageType)
+	}
+	if f.HandshakeTimeout <= 0 {
+		f.HandshakeTimeout = defaultHandshakeTimeout
+	}
+	if f.WriteTimeout <= 0 {
+		f.WriteTimeout = defaultWriteTimeout
+	}
+	if f.Reconnect.InitialDelay <= 0 {
+		f.Reconnect.InitialDelay = time.Second
+	}
+	if f.Reconnect.MaxDelay < f.Reconnect.InitialDelay {
+		f.Reconnect.MaxDelay = f.Reconnect.InitialDelay
+	}
+	return nil
+}
+
+func (f *FlusherWebSocket) Flush(projectName string, logstoreName string, configName string, logGroupList []*protocol.LogGroup) error {
+	for _, logGroup := range logGroupList {
+		stream, err := f.converter.ToByteStream(logGroup)
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher convert log fail, error", err)
+			continue
+		}
+		f.addStream(stream)
+	}
+	return nil
+}
+
+func (f *FlusherWebSocket) Export(groupEventsArray []*models.PipelineGroupEvents, ctx pipeline.PipelineContext) error {
+	for _, groupEvents := range groupEventsArray {
+		stream, _, err := f.converterV2.ToByteStreamWithSelectedFieldsV2(groupEvents, nil)
+		if err != nil {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher convert events fail, error", err)
+			continue
+		}
+		f.addStream(stream)
+	}
+	return nil
+}
+
+func (f *FlusherWebSocket) SetUrgent(flag bool) {
+}
+
+func (f *FlusherWebSocket) IsReady(projectName string, logstoreName string, logstoreKey int64) bool {
+	return f.queue != nil
+}
+
+func (f *FlusherWebSocket) Stop() error {
+	// close stopCh first so that the blocked addMessage and the reconnecting run loop give up,
+	// then close the queue once no one is adding messages.
+	close(f.stopCh)
+	f.queueLock.Lock()
+	f.closed = true
+	close(f.queue)
+	f.queueLock.Unlock()
+	f.stopped.Wait()
+	return nil
+}
+
+func (f *FlusherWebSocket) addStream(stream interface{}) {
+	switch rows := stream.(type) {
+	case [][]byte:
+		for _, row := range rows {
+			f.addMessage(row)
+		}
+	case []byte:
+		f.addMessage(rows)
+	default:
+		logger.Errorf(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher got unsupported stream type %T", stream)
+	}
+}
+
+func (f *FlusherWebSocket) addMessage(data []byte) {
+	f.queueLock.RLock()
+	defer f.queueLock.RUnlock()
+	if f.closed {
+		converter.PutPooledByteBuf(&data)
+		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher dropped a message since the flusher is stopped")
+		return
+	}
+	if f.DropEventWhenQueueFull {
+		select {
+		case f.queue <- data:
+		default:
+			converter.PutPooledByteBuf(&data)
+			logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher dropped a message since the queue is full")
+		}
+		return
+	}
+	select {
+	case f.queue <- data:
+	case <-f.stopCh:
+		converter.PutPooledByteBuf(&data)
+		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher dropped a message since the flusher is stopping")
+	}
+}
+
+// run consumes the queue and writes every message to the current connection,
+// reconnecting with backoff when the connection is broken.
+func (f *FlusherWebSocket) run() {
+	defer f.stopped.Done()
+	defer f.closeConn()
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if f.PingInterval > 0 {
+		ticker = time.NewTicker(f.PingInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case data, ok := <-f.queue:
+			if !ok {
+				return
+			}
+			f.send(data)
+		case <-tick:
+			if f.conn == nil {
+				continue
+			}
+			if err := f.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(f.WriteTimeout)); err != nil {
+				logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher ping fail, error", err)
+				f.closeConn()
+			}
+		}
+	}
+}
+
+func (f *FlusherWebSocket) send(data []byte) {
+	defer converter.PutPooledByteBuf(&data)
+	for attempt := 0; ; attempt++ {
+		if f.conn != nil {
+			select {
+			case <-f.connBroken:
+				f.closeConn()
+			default:
+			}
+		}
+		if f.conn == nil && !f.connect() {
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher is stopping without connection, data dropped, url", f.URL)
+			return
+		}
+		_ = f.conn.SetWriteDeadline(time.Now().Add(f.WriteTimeout))
+		err := f.conn.WriteMessage(f.messageType, data)
+		if err == nil {
+			return
+		}
+		f.closeConn()
+		// back off before reconnecting, otherwise a receiver accepting connections but failing writes leads to a busy loop.
+		delay := f.getNextRetryDelay(attempt)
+		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher write message fail, error", err, "attempt", attempt, "retry after", delay)
+		select {
+		case <-f.stopCh:
+			logger.Error(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher is stopping after write failure, data dropped, url", f.URL)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// connect dials the receiver until success, and returns false only when the flusher is stopped.
+func (f *FlusherWebSocket) connect() bool {
+	for retry := 0; ; retry++ {
+		select {
+		case <-f.stopCh:
+			return false
+		default:
+		}
+		conn, resp, err := f.dialer.Dial(f.URL, f.header)
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		if err == nil {
+			f.conn = conn
+			f.connBroken = make(chan struct{})
+			go f.discardIncoming(conn, f.connBroken)
+			logger.Info(f.context.GetRuntimeContext(), "websocket flusher connected", f.URL)
+			return true
+		}
+		if resp != nil {
+			err = fmt.Errorf("%w, status: %s", err, resp.Status)
+		}
+		delay := f.getNextRetryDelay(retry)
+		if resp != nil {
+			// honor the quota headers of a throttled handshake, e.g. 429 with Retry-After.
+			if wait := helper.ParseQuotaHeaders(resp.StatusCode, resp.Header, time.Now()); wait > delay {
+				delay = wait
+			}
+		}
+		logger.Warning(f.context.GetRuntimeContext(), "FLUSHER_FLUSH_ALARM", "websocket flusher dial fail, error", err, "retry after", delay)
+		select {
+		case <-f.stopCh:
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// discardIncoming reads the connection so that control frames (pong, close) are processed,
+// and closes broken once the connection is found broken.
+func (f *FlusherWebSocket) discardIncoming(conn *websocket.Conn, broken chan struct{}) {
+	defer close(broken)
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *FlusherWebSocket) closeConn() {
+	if f.conn == nil {
+		return
+	}
+	_ = f.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	_ = f.conn.Close()
+	f.conn = nil
+	f.connBroken = nil
+}
+
+func (f *FlusherWebSocket) getNextRetryDelay(retry int) time.Duration {
+	if retry > 16 {
+		return f.Reconnect.MaxDelay
+	}
+	delay := f.Reconnect.InitialDelay << uint(retry)
+	if delay <= 0 || delay > f.Reconnect.MaxDelay {
+		delay = f.Reconnect.MaxDelay
+	}
+	return delay
+}
+
+func init() {
+	pipeline.Flushers["flusher_websocket"] = func() pipeline.Flusher {
+		return NewWebSocketFlusher()
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	converter "github.com/alibaba/ilogtail/pkg/protocol/converter"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newReceiver(t *testing.T, token string) (*httptest.Server, chan string) {
+	received := make(chan string, 16)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get(authorizationHeader) != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade fail: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}))
+	return server, received
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestFlusherWebSocketInit(t *testing.T) {
+	f := NewWebSocketFlusher()
+	assert.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	f = NewWebSocketFlusher()
+	f.URL = "http://127.0.0.1:8080"
+	assert.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	f = NewWebSocketFlusher()
+	f.URL = "ws://127.0.0.1:8080"
+	f.MessageType = "unknown"
+	assert.Error(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestFlusherWebSocketExport(t *testing.T) {
+	server, received := newReceiver(t, "secret")
+	defer server.Close()
+
+	f := NewWebSocketFlusher()
+	f.URL = wsURL(server)
+	f.Token = "secret"
+	f.Convert.Protocol = converter.ProtocolRaw
+	f.Convert.Encoding = converter.EncodingCustom
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	group := &models.PipelineGroupEvents{
+		Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{models.ByteArray("hello websocket")},
+	}
+	require.NoError(t, f.Export([]*models.PipelineGroupEvents{group}, nil))
+
+	select {
+	case msg := <-received:
+		assert.Contains(t, msg, "hello websocket")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	require.NoError(t, f.Stop())
+}
+
+func TestFlusherWebSocketExportWithDefaultConvert(t *testing.T) {
+	server, received := newReceiver(t, "")
+	defer server.Close()
+
+	f := NewWebSocketFlusher()
+	f.URL = wsURL(server)
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	group := &models.PipelineGroupEvents{
+		Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+		Events: []models.PipelineEvent{models.ByteArray("hello default")},
+	}
+	require.NoError(t, f.Export([]*models.PipelineGroupEvents{group}, nil))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "hello default", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	require.NoError(t, f.Stop())
+}
+
+func TestFlusherWebSocketReconnect(t *testing.T) {
+	server, received := newReceiver(t, "")
+	defer server.Close()
+
+	f := NewWebSocketFlusher()
+	f.URL = wsURL(server)
+	f.Reconnect.InitialDelay = 10 * time.Millisecond
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	logGroup := &protocol.LogGroup{Logs: []*protocol.Log{{Contents: []*protocol.Log_Content{{Key: "content", Value: "first"}}}}}
+	require.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{logGroup}))
+	select {
+	case msg := <-received:
+		assert.Contains(t, msg, "first")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	server.CloseClientConnections()
+	logGroup.Logs[0].Contents[0].Value = "second"
+	deadline := time.After(5 * time.Second)
+	for {
+		// messages written before the broken connection is detected may be lost, so keep sending.
+		require.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{logGroup}))
+		select {
+		case msg := <-received:
+			if strings.Contains(msg, "second") {
+				require.NoError(t, f.Stop())
+				return
+			}
+		case <-deadline:
+			t.Fatal("message is not delivered after reconnection")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestFlusherWebSocketFlushWhileStopping(t *testing.T) {
+	f := NewWebSocketFlusher()
+	// nothing listens on the port, so the queue fills up while the flusher keeps redialing.
+	f.URL = "ws://127.0.0.1:1"
+	f.QueueCapacity = 1
+	f.Reconnect.InitialDelay = 10 * time.Millisecond
+	require.NoError(t, f.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	logGroup := &protocol.LogGroup{Logs: []*protocol.Log{{Contents: []*protocol.Log_Content{{Key: "content", Value: "value"}}}}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = f.Flush("p", "l", "c", []*protocol.LogGroup{logGroup})
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, f.Stop())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush is blocked after stop")
+	}
+	assert.NoError(t, f.Flush("p", "l", "c", []*protocol.LogGroup{logGroup}))
+}
+
+func TestFlusherWebSocketConnectAfterStop(t *testing.T) {
+	server, _ := newReceiver(t, "")
+	defer server.Close()
+
+	f := NewWebSocketFlusher()
+	f.URL = wsURL(server)
+	f.dialer = &websocket.Dialer{}
+	f.context = mock.NewEmptyContext("p", "l", "c")
+	f.stopCh = make(chan struct{})
+	close(f.stopCh)
+	assert.False(t, f.connect())
+	assert.Nil(t, f.conn)
+}
+
+func TestGetNextRetryDelay(t *testing.T) {
+	f := NewWebSocketFlusher()
+	assert.Equal(t, time.Second, f.getNextRetryDelay(0))
+	assert.Equal(t, 4*time.Second, f.getNextRetryDelay(2))
+	assert.Equal(t, 30*time.Second, f.getNextRetryDelay(10))
+	assert.Equal(t, 30*time.Second, f.getNextRetryDelay(100))
+}
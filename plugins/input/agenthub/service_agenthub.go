@@ -0,0 +1,262 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agenthub
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_agenthub"
+
+const (
+	v1 = iota
+	v2
+)
+
+const gracefulStopTimeout = 5 * time.Second
+
+const (
+	ctxKeySource = "source"
+	ctxKeyTopic  = "topic"
+	ctxKeyTags   = "tags"
+)
+
+// ServiceAgentHub receives the log groups flushed by the downstream agents with flusher_grpc, so that one agent
+// can aggregate, process and forward the data of many edge agents in a two-tier collection topology.
+type ServiceAgentHub struct {
+	Address              string // The address to listen on, default is 0.0.0.0:8000.
+	SSLCert              string // Path to the cert file, TLS is enabled if set.
+	SSLKey               string // Path to the cert key file.
+	SSLCA                string // Path to the CA file to verify the client certs, the client certs are required if set.
+	MaxRecvMsgSizeMiB    int    // The max size of a received log group, default is 64MiB.
+	MaxConcurrentStreams int    // The max concurrent streams of a connection, no limit if not positive.
+	AgentSourceKey       string // The tag key to keep the source IP of the downstream agent, not kept if empty.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	server      *grpc.Server
+	done        chan struct{}
+}
+
+// logReportServer serves the LogReportService called by flusher_grpc.
+type logReportServer struct {
+	protocol.UnimplementedLogReportServiceServer
+	hub *ServiceAgentHub
+}
+
+// Init ...
+func (s *ServiceAgentHub) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if (s.SSLCert == "") != (s.SSLKey == "") {
+		return 0, errors.New("SSLCert and SSLKey must be specified together to enable TLS")
+	}
+	if s.SSLCA != "" && s.SSLCert == "" {
+		return 0, errors.New("SSLCA verifies the client certs, which requires TLS enabled by SSLCert and SSLKey")
+	}
+	if s.MaxRecvMsgSizeMiB <= 0 {
+		s.MaxRecvMsgSizeMiB = 64
+	}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceAgentHub) Description() string {
+	return "agent hub input plugin for logtail, receiving the log groups from the downstream agents"
+}
+
+// Collect ...
+func (s *ServiceAgentHub) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceAgentHub) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceAgentHub) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceAgentHub) start() error {
+	// the codec is forced on this server only, so that the other gRPC plugins in the process are not affected.
+	options := []grpc.ServerOption{
+		grpc.ForceServerCodec(protocol.Codec{}),
+		grpc.MaxRecvMsgSize(s.MaxRecvMsgSizeMiB * 1024 * 1024),
+	}
+	if s.MaxConcurrentStreams > 0 {
+		options = append(options, grpc.MaxConcurrentStreams(uint32(s.MaxConcurrentStreams)))
+	}
+	if s.SSLCert != "" {
+		tlsConfig, err := util.GetTLSConfig(s.SSLCert, s.SSLKey, s.SSLCA, false)
+		if err != nil {
+			return err
+		}
+		if tlsConfig.RootCAs != nil {
+			tlsConfig.ClientCAs = tlsConfig.RootCAs
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.server = grpc.NewServer(options...)
+	protocol.RegisterLogReportServiceServer(s.server, &logReportServer{hub: s})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			logger.Error(s.context.GetRuntimeContext(), "AGENT_HUB_ALARM", "serve error", err)
+		}
+	}()
+	logger.Info(s.context.GetRuntimeContext(), "agent hub server start", s.Address)
+	return nil
+}
+
+// Collect receives the log groups of a flush from a downstream agent.
+func (r *logReportServer) Collect(stream protocol.LogReportService_CollectServer) error {
+	s := r.hub
+	remote := ""
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		remote = p.Addr.String()
+	}
+	count := 0
+	for {
+		logGroup, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&protocol.Response{
+				Code:    protocol.ResponseCode_Success,
+				Message: "received " + strconv.Itoa(count) + " log groups",
+			})
+		}
+		if err != nil {
+			logger.Warning(s.context.GetRuntimeContext(), "AGENT_HUB_ALARM", "receive log group failed", err, "remote", remote)
+			return err
+		}
+		s.collectLogGroup(logGroup, remote)
+		count++
+	}
+}
+
+// collectLogGroup passes the logs to the pipeline as if they were collected by this agent, with the tags and the topic
+// of the downstream agent. The source of the logs is the pack of the downstream agent, so that the logs of different
+// packs are not aggregated into the same log group with the wrong tags.
+func (s *ServiceAgentHub) collectLogGroup(logGroup *protocol.LogGroup, remote string) {
+	source := remote
+	tags := make([]*protocol.LogTag, 0, len(logGroup.LogTags)+1)
+	for _, tag := range logGroup.LogTags {
+		if tag.Key == util.PackIDTagKey {
+			if idx := strings.LastIndexByte(tag.Value, '-'); idx != -1 {
+				source = tag.Value[:idx+1]
+			}
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if s.AgentSourceKey != "" {
+		agentSource := logGroup.Source
+		if agentSource == "" {
+			agentSource, _, _ = net.SplitHostPort(remote)
+		}
+		tags = append(tags, &protocol.LogTag{Key: s.AgentSourceKey, Value: agentSource})
+	}
+
+	switch s.version {
+	case v1:
+		for _, log := range logGroup.Logs {
+			s.collector.AddRawLogWithContext(log, map[string]interface{}{ctxKeySource: source, ctxKeyTopic: logGroup.Topic, ctxKeyTags: tags})
+		}
+	case v2:
+		md := models.NewMetadata()
+		md.Add(ctxKeySource, source)
+		md.Add(ctxKeyTopic, logGroup.Topic)
+		groupTags := models.NewTags()
+		for _, tag := range tags {
+			groupTags.Add(tag.Key, tag.Value)
+		}
+		group := &models.PipelineGroupEvents{
+			Group:  models.NewGroup(md, groupTags),
+			Events: make([]models.PipelineEvent, 0, len(logGroup.Logs)),
+		}
+		for _, log := range logGroup.Logs {
+			timestamp := uint64(log.Time) * uint64(time.Second)
+			if log.TimeNs != nil {
+				timestamp += uint64(*log.TimeNs)
+			}
+			event := models.NewLog("", nil, "", "", "", models.NewTags(), timestamp)
+			for _, content := range log.Contents {
+				event.Contents.Add(content.Key, content.Value)
+			}
+			group.Events = append(group.Events, event)
+		}
+		s.collectorV2.CollectList(group)
+	}
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceAgentHub) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	// the streams of the downstream agents are waited for a while, so that the received log groups are not lost.
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(gracefulStopTimeout):
+		s.server.Stop()
+	}
+	<-s.done
+	logger.Info(s.context.GetRuntimeContext(), "agent hub server stop", s.Address)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceAgentHub{
+			Address:           "0.0.0.0:8000",
+			MaxRecvMsgSizeMiB: 64,
+			AgentSourceKey:    "__agent_source__",
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agenthub
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+// contextCollector keeps the contexts of the raw logs.
+type contextCollector struct {
+	test.MockCollector
+	contexts []map[string]interface{}
+}
+
+func (c *contextCollector) AddRawLogWithContext(log *protocol.Log, ctx map[string]interface{}) {
+	c.MockCollector.AddRawLogWithContext(log, ctx)
+	c.contexts = append(c.contexts, ctx)
+}
+
+func newInput(t *testing.T) *ServiceAgentHub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceAgentHub)
+	s.Address = address
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	return s
+}
+
+func send(t *testing.T, s *ServiceAgentHub, logGroups ...*protocol.LogGroup) *protocol.Response {
+	conn, err := grpc.Dial(s.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(protocol.Codec{})))
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := protocol.NewLogReportServiceClient(conn).Collect(context.Background())
+	require.NoError(t, err)
+	for _, logGroup := range logGroups {
+		require.NoError(t, stream.Send(logGroup))
+	}
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	return resp
+}
+
+func newLogGroup(packID string, contents ...string) *protocol.LogGroup {
+	logGroup := &protocol.LogGroup{
+		Topic:   "topic",
+		Source:  "192.168.0.1",
+		LogTags: []*protocol.LogTag{{Key: "__hostname__", Value: "edge"}, {Key: "__pack_id__", Value: packID}},
+	}
+	for _, content := range contents {
+		logGroup.Logs = append(logGroup.Logs, &protocol.Log{
+			Time:     1700000000,
+			Contents: []*protocol.Log_Content{{Key: "content", Value: content}},
+		})
+	}
+	return logGroup
+}
+
+func TestAgentHubV1(t *testing.T) {
+	s := newInput(t)
+	collector := &contextCollector{}
+	require.NoError(t, s.Start(collector))
+	defer s.Stop()
+
+	resp := send(t, s, newLogGroup("ABC-1", "a", "b"), newLogGroup("DEF-5", "c"))
+	assert.Equal(t, protocol.ResponseCode_Success, resp.Code)
+
+	require.Len(t, collector.RawLogs, 3)
+	assert.Equal(t, "c", collector.RawLogs[2].Contents[0].Value)
+	assert.Equal(t, map[string]interface{}{
+		"source": "ABC-",
+		"topic":  "topic",
+		"tags":   []*protocol.LogTag{{Key: "__hostname__", Value: "edge"}, {Key: "__agent_source__", Value: "192.168.0.1"}},
+	}, collector.contexts[0])
+	assert.Equal(t, "DEF-", collector.contexts[2]["source"])
+}
+
+func TestAgentHubV2(t *testing.T) {
+	s := newInput(t)
+	s.AgentSourceKey = ""
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	resp := send(t, s, newLogGroup("ABC-1", "a", "b"))
+	assert.Equal(t, protocol.ResponseCode_Success, resp.Code)
+
+	groups := ctx.Collector().ToArray()
+	require.Len(t, groups, 1)
+	assert.Equal(t, "ABC-", groups[0].Group.GetMetadata().Get("source"))
+	assert.Equal(t, "topic", groups[0].Group.GetMetadata().Get("topic"))
+	assert.Equal(t, map[string]string{"__hostname__": "edge"}, groups[0].Group.GetTags().Iterator())
+	require.Len(t, groups[0].Events, 2)
+	assert.Equal(t, "b", groups[0].Events[1].(*models.Log).Contents.Get("content"))
+	assert.Equal(t, uint64(1700000000000000000), groups[0].Events[1].GetTimestamp())
+}
+
+func TestAgentHubInvalidTLS(t *testing.T) {
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceAgentHub)
+	s.SSLCert = "server.pem"
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+
+	s = newInput(t)
+	s.SSLCert = "not_exist.pem"
+	s.SSLKey = "not_exist.key"
+	assert.Error(t, s.Start(&test.MockCollector{}))
+}
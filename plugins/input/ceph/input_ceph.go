@@ -0,0 +1,189 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ceph
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const metricsPath = "/metrics"
+
+// InputCeph scrapes the prometheus module of the ceph manager. Only the active manager exports the metrics,
+// so the endpoints are tried in order until one of them returns samples.
+// The pool and osd samples are enriched with the pool name and the osd hostname from the metadata metrics.
+type InputCeph struct {
+	// Endpoints are the addresses of the manager daemons, e.g. "http://10.0.0.1:9283"
+	Endpoints            []string
+	MetricPattern        string // regex to select the metrics by name, all metrics are selected if empty
+	ExcludeMetricPattern string // regex to exclude the metrics by name
+	SSLCA                string
+	SSLCert              string
+	SSLKey               string
+	SkipInsecureVerify   bool
+	TimeoutMs            int
+	Labels               map[string]string
+
+	metricRegex        *regexp.Regexp
+	excludeMetricRegex *regexp.Regexp
+	client             *http.Client
+	context            pipeline.Context
+}
+
+func (c *InputCeph) Init(context pipeline.Context) (int, error) {
+	c.context = context
+	if len(c.Endpoints) == 0 {
+		return 0, fmt.Errorf("no ceph mgr endpoint configured")
+	}
+	if c.TimeoutMs <= 0 {
+		c.TimeoutMs = 10000
+	}
+	var err error
+	if c.MetricPattern != "" {
+		if c.metricRegex, err = regexp.Compile(c.MetricPattern); err != nil {
+			return 0, fmt.Errorf("invalid metric pattern: %v", err)
+		}
+	}
+	if c.ExcludeMetricPattern != "" {
+		if c.excludeMetricRegex, err = regexp.Compile(c.ExcludeMetricPattern); err != nil {
+			return 0, fmt.Errorf("invalid exclude metric pattern: %v", err)
+		}
+	}
+	tlsCfg, err := util.GetTLSConfig(c.SSLCert, c.SSLKey, c.SSLCA, c.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	c.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(c.TimeoutMs) * time.Millisecond,
+		// the standby managers may redirect to the active one, which is tried by the endpoints anyway
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return 0, nil
+}
+
+func (c *InputCeph) Description() string {
+	return "collect the cluster, pool and osd metrics of ceph from the prometheus module of the manager"
+}
+
+func (c *InputCeph) Collect(collector pipeline.Collector) error {
+	labels := &helper.MetricLabels{}
+	for k, v := range c.Labels {
+		labels.Append(k, v)
+	}
+	now := time.Now()
+	for _, endpoint := range c.Endpoints {
+		u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil {
+			logger.Warning(c.context.GetRuntimeContext(), "CEPH_COLLECT_ALARM", "endpoint", endpoint, "error", err)
+			continue
+		}
+		samples, err := c.scrape(u.String() + metricsPath)
+		if err != nil {
+			logger.Debug(c.context.GetRuntimeContext(), "endpoint", endpoint, "scrape error", err)
+			continue
+		}
+		if len(samples) == 0 {
+			// standby manager
+			continue
+		}
+		collector.AddRawLog(helper.NewMetricLog("ceph_up", now.UnixNano(), 1, labels))
+		c.export(samples, labels, collector)
+		return nil
+	}
+	logger.Warning(c.context.GetRuntimeContext(), "CEPH_COLLECT_ALARM", "no active ceph mgr found in endpoints", c.Endpoints)
+	collector.AddRawLog(helper.NewMetricLog("ceph_up", now.UnixNano(), 0, labels))
+	return nil
+}
+
+func (c *InputCeph) scrape(u string) (model.Vector, error) {
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	var samples model.Vector
+	if err = helper.DecodePrometheusText(resp.Body, time.Now(), func(sample *model.Sample) {
+		samples = append(samples, sample)
+	}); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// export adds the selected samples, the pool samples get the "pool_name" label from ceph_pool_metadata
+// and the osd samples get the "hostname" label from ceph_osd_metadata.
+func (c *InputCeph) export(samples model.Vector, labels *helper.MetricLabels, collector pipeline.Collector) {
+	poolNames := make(map[model.LabelValue]string)
+	osdHosts := make(map[model.LabelValue]string)
+	for _, sample := range samples {
+		switch sample.Metric[model.MetricNameLabel] {
+		case "ceph_pool_metadata":
+			poolNames[sample.Metric["pool_id"]] = string(sample.Metric["name"])
+		case "ceph_osd_metadata":
+			osdHosts[sample.Metric["ceph_daemon"]] = string(sample.Metric["hostname"])
+		}
+	}
+	for _, sample := range samples {
+		name := string(sample.Metric[model.MetricNameLabel])
+		if !c.selected(name) {
+			continue
+		}
+		sampleLabels := labels.Clone()
+		helper.AppendSampleLabels(sampleLabels, sample.Metric)
+		if poolID, ok := sample.Metric["pool_id"]; ok && name != "ceph_pool_metadata" {
+			if poolName, ok := poolNames[poolID]; ok {
+				sampleLabels.Append("pool_name", poolName)
+			}
+		}
+		if daemon, ok := sample.Metric["ceph_daemon"]; ok && name != "ceph_osd_metadata" && strings.HasPrefix(string(daemon), "osd.") {
+			if host, ok := osdHosts[daemon]; ok {
+				sampleLabels.Append("hostname", host)
+			}
+		}
+		collector.AddRawLog(helper.NewMetricLog(name, sample.Timestamp.UnixNano(), float64(sample.Value), sampleLabels))
+	}
+}
+
+func (c *InputCeph) selected(name string) bool {
+	if c.metricRegex != nil && !c.metricRegex.MatchString(name) {
+		return false
+	}
+	return c.excludeMetricRegex == nil || !c.excludeMetricRegex.MatchString(name)
+}
+
+func init() {
+	pipeline.MetricInputs["metric_ceph"] = func() pipeline.MetricInput {
+		return &InputCeph{
+			TimeoutMs: 10000,
+		}
+	}
+}
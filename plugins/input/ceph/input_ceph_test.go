@@ -0,0 +1,83 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ceph
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const mgrMetrics = `# HELP ceph_health_status Cluster health status
+# TYPE ceph_health_status untyped
+ceph_health_status 1.0
+# HELP ceph_pool_metadata POOL Metadata
+# TYPE ceph_pool_metadata untyped
+ceph_pool_metadata{pool_id="1",name="rbd",type="replicated"} 1.0
+# HELP ceph_pool_stored Data stored in pool
+# TYPE ceph_pool_stored untyped
+ceph_pool_stored{pool_id="1"} 1024.0
+# HELP ceph_osd_metadata OSD Metadata
+# TYPE ceph_osd_metadata untyped
+ceph_osd_metadata{ceph_daemon="osd.0",hostname="node-1"} 1.0
+# HELP ceph_osd_up OSD status up
+# TYPE ceph_osd_up untyped
+ceph_osd_up{ceph_daemon="osd.0"} 1.0
+# HELP ceph_mon_quorum_status Monitors in quorum
+# TYPE ceph_mon_quorum_status gauge
+ceph_mon_quorum_status{ceph_daemon="mon.a"} 1.0
+`
+
+func TestCephCollect(t *testing.T) {
+	standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer standby.Close()
+	active := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(mgrMetrics))
+	}))
+	defer active.Close()
+
+	input := &InputCeph{
+		Endpoints:            []string{"http://127.0.0.1:1", standby.URL, active.URL},
+		ExcludeMetricPattern: "^ceph_mon_",
+		Labels:               map[string]string{"cluster": "c1"},
+	}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{
+		"ceph_health_status{cluster#$#c1} 1",
+		"ceph_osd_metadata{ceph_daemon#$#osd.0|cluster#$#c1|hostname#$#node-1} 1",
+		"ceph_osd_up{ceph_daemon#$#osd.0|cluster#$#c1|hostname#$#node-1} 1",
+		"ceph_pool_metadata{cluster#$#c1|name#$#rbd|pool_id#$#1|type#$#replicated} 1",
+		"ceph_pool_stored{cluster#$#c1|pool_id#$#1|pool_name#$#rbd} 1024",
+		"ceph_up{cluster#$#c1} 1",
+	}, test.MetricLines(collector.Logs))
+
+	input.Endpoints = []string{standby.URL}
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{"ceph_up{cluster#$#c1} 0"}, test.MetricLines(collector.Logs))
+}
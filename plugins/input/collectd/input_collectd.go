@@ -0,0 +1,248 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_collectd"
+
+const (
+	v1 = iota
+	v2
+)
+
+const maxPacketSize = 65535
+
+// ServiceCollectd receives the metrics sent by the network plugin of collectd.
+type ServiceCollectd struct {
+	Address       string            // The udp address to listen on, default is 127.0.0.1:25826.
+	SecurityLevel string            // None, Sign or Encrypt, the packets below the level are dropped, default is None.
+	Users         map[string]string // The username and password pairs to verify the signed and decrypt the encrypted packets.
+	TypesDB       []string          // The types.db files to name the values of the data sets, such as /usr/share/collectd/types.db.
+	Separator     string            // The separator to join the plugin, type and data source name into the metric name, default is _.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	parser      *parser
+	types       map[string][]string
+	conn        net.PacketConn
+	done        chan struct{}
+	wg          sync.WaitGroup
+	lastAlarm   time.Time
+}
+
+// Init ...
+func (s *ServiceCollectd) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	switch s.SecurityLevel {
+	case "":
+		s.SecurityLevel = securityLevelNone
+	case securityLevelNone:
+	case securityLevelSign, securityLevelEncrypt:
+		if len(s.Users) == 0 {
+			return 0, fmt.Errorf("users are required with the security level %v", s.SecurityLevel)
+		}
+	default:
+		return 0, fmt.Errorf("unknown security level %v", s.SecurityLevel)
+	}
+	var err error
+	if s.types, err = loadTypesDB(s.TypesDB); err != nil {
+		return 0, err
+	}
+	s.parser = &parser{securityLevel: s.SecurityLevel, users: s.Users}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceCollectd) Description() string {
+	return "collectd binary protocol input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceCollectd) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceCollectd) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceCollectd) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceCollectd) start() error {
+	conn, err := net.ListenPacket("udp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.read()
+	logger.Info(s.context.GetRuntimeContext(), "collectd server start", s.Address)
+	return nil
+}
+
+func (s *ServiceCollectd) read() {
+	defer s.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "COLLECTD_ALARM", "read packet error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		lists, err := s.parser.parse(buf[:n])
+		if err != nil {
+			s.alarm(err, addr)
+		}
+		s.collect(lists)
+	}
+}
+
+// metricName joins the plugin, the type when it differs from the plugin, and the data source name except "value".
+func (s *ServiceCollectd) metricName(list *valueList, index int) string {
+	name := list.plugin
+	if list.typ != list.plugin {
+		name += s.Separator + list.typ
+	}
+	var dsName string
+	if names, ok := s.types[list.typ]; ok && len(names) == len(list.values) {
+		dsName = names[index]
+	} else if len(list.values) > 1 {
+		dsName = strconv.Itoa(index)
+	}
+	if dsName != "" && dsName != "value" {
+		name += s.Separator + dsName
+	}
+	return name
+}
+
+func labels(list *valueList) map[string]string {
+	tags := make(map[string]string, 3)
+	if list.host != "" {
+		tags["host"] = list.host
+	}
+	if list.pluginInstance != "" {
+		tags["plugin_instance"] = list.pluginInstance
+	}
+	if list.typeInstance != "" {
+		tags["type_instance"] = list.typeInstance
+	}
+	return tags
+}
+
+func metricType(dsType byte) models.MetricType {
+	switch dsType {
+	case dsTypeCounter, dsTypeDerive:
+		return models.MetricTypeCounter
+	default:
+		return models.MetricTypeGauge
+	}
+}
+
+func (s *ServiceCollectd) collect(lists []*valueList) {
+	if len(lists) == 0 {
+		return
+	}
+	now := time.Now()
+	var group *models.PipelineGroupEvents
+	if s.version == v2 {
+		group = &models.PipelineGroupEvents{
+			Group: models.NewGroup(models.NewMetadata(), models.NewTags()),
+		}
+	}
+	for _, list := range lists {
+		timestamp := list.time
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		tags := labels(list)
+		for i, value := range list.values {
+			name := s.metricName(list, i)
+			switch s.version {
+			case v1:
+				var metricLabels helper.MetricLabels
+				metricLabels.AppendMap(tags)
+				s.collector.AddRawLog(helper.NewMetricLog(name, timestamp.UnixNano(), value, &metricLabels))
+			case v2:
+				metric := models.NewSingleValueMetric(name, metricType(list.dsTypes[i]), models.NewTagsWithMap(tags), timestamp.UnixNano(), value)
+				group.Events = append(group.Events, metric)
+			}
+		}
+	}
+	if group != nil && len(group.Events) > 0 {
+		s.collectorV2.CollectList(group)
+	}
+}
+
+func (s *ServiceCollectd) alarm(err error, addr net.Addr) {
+	logger.Debug(s.context.GetRuntimeContext(), "parse collectd packet error", err, "remote", addr.String())
+	if time.Since(s.lastAlarm) > 10*time.Second {
+		logger.Warning(s.context.GetRuntimeContext(), "COLLECTD_PARSE_ALARM", "parse err", err, "remote", addr.String())
+		s.lastAlarm = time.Now()
+	}
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceCollectd) Stop() error {
+	if s.conn == nil {
+		return nil
+	}
+	close(s.done)
+	_ = s.conn.Close()
+	s.wg.Wait()
+	logger.Info(s.context.GetRuntimeContext(), "collectd server stop", s.Address)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceCollectd{
+			Address:       "127.0.0.1:25826",
+			SecurityLevel: securityLevelNone,
+			Separator:     "_",
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newInput() (*ServiceCollectd, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceCollectd)
+	s.Address = "127.0.0.1:0"
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+func TestCollectdV2(t *testing.T) {
+	typesDB := filepath.Join(t.TempDir(), "types.db")
+	require.NoError(t, os.WriteFile(typesDB, []byte("load shortterm:GAUGE:0:5000, midterm:GAUGE:0:5000, longterm:GAUGE:0:5000\n"), 0600))
+	s, err := newInput()
+	require.NoError(t, err)
+	s.SecurityLevel = securityLevelEncrypt
+	s.Users = map[string]string{"alice": "secret"}
+	s.TypesDB = []string{typesDB}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(10)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	// the unencrypted packet is dropped
+	_, err = conn.Write(samplePacket())
+	require.NoError(t, err)
+	_, err = conn.Write(encrypt("alice", "secret", samplePacket()))
+	require.NoError(t, err)
+
+	var group *models.PipelineGroupEvents
+	select {
+	case group = <-ctx.Collector().Observe():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "receive metrics timeout")
+	}
+	require.Len(t, group.Events, 4)
+	cpu := group.Events[0].(*models.Metric)
+	assert.Equal(t, "cpu", cpu.GetName())
+	assert.Equal(t, models.MetricTypeCounter, cpu.GetMetricType())
+	assert.Equal(t, -100.0, cpu.GetValue().GetSingleValue())
+	assert.Equal(t, uint64(1700000000500000000), cpu.GetTimestamp())
+	assert.Equal(t, map[string]string{"host": "node-1", "plugin_instance": "0", "type_instance": "user"}, cpu.GetTags().Iterator())
+	var names []string
+	for _, e := range group.Events[1:] {
+		names = append(names, e.GetName())
+		assert.Equal(t, models.MetricTypeGauge, e.(*models.Metric).GetMetricType())
+	}
+	assert.Equal(t, []string{"load_shortterm", "load_midterm", "load_longterm"}, names)
+	assert.Empty(t, ctx.Collector().ToArray())
+}
+
+func TestCollectdV1(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.version = v1
+	lists, err := s.parser.parse(concat(samplePacket(),
+		stringPart(partPlugin, "interface"), stringPart(partPluginInstance, "eth0"), stringPart(partType, "if_octets"),
+		valuesPart([]byte{dsTypeDerive, dsTypeDerive}, []float64{1, 2})))
+	require.NoError(t, err)
+	s.collect(lists)
+
+	require.Len(t, collector.RawLogs, 6)
+	var names []string
+	for _, log := range collector.RawLogs {
+		names = append(names, test.ReadLogVal(log, "__name__"))
+	}
+	assert.Equal(t, []string{"cpu", "load_0", "load_1", "load_2", "interface_if_octets_0", "interface_if_octets_1"}, names)
+	assert.Equal(t, "host#$#node-1|plugin_instance#$#0|type_instance#$#user", test.ReadLogVal(collector.RawLogs[0], "__labels__"))
+	assert.Equal(t, "1.25", test.ReadLogVal(collector.RawLogs[2], "__value__"))
+	assert.Equal(t, "host#$#node-1|plugin_instance#$#eth0", test.ReadLogVal(collector.RawLogs[4], "__labels__"))
+}
+
+func TestInit(t *testing.T) {
+	for _, s := range []*ServiceCollectd{
+		{SecurityLevel: "Unknown"},
+		{SecurityLevel: securityLevelSign},
+		{TypesDB: []string{"/not/exist/types.db"}},
+	} {
+		_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The part types of the collectd binary protocol, see https://collectd.org/wiki/index.php/Binary_protocol.
+const (
+	partHost           = 0x0000
+	partTime           = 0x0001
+	partPlugin         = 0x0002
+	partPluginInstance = 0x0003
+	partType           = 0x0004
+	partTypeInstance   = 0x0005
+	partValues         = 0x0006
+	partInterval       = 0x0007
+	partTimeHR         = 0x0008
+	partIntervalHR     = 0x0009
+	partMessage        = 0x0100
+	partSeverity       = 0x0101
+	partSignature      = 0x0200
+	partEncryption     = 0x0210
+)
+
+// The data source types of the values.
+const (
+	dsTypeCounter  = 0
+	dsTypeGauge    = 1
+	dsTypeDerive   = 2
+	dsTypeAbsolute = 3
+)
+
+const (
+	securityLevelNone    = "None"
+	securityLevelSign    = "Sign"
+	securityLevelEncrypt = "Encrypt"
+)
+
+const (
+	partHeaderSize = 4
+	hashSize       = sha256.Size
+	checksumSize   = sha1.Size
+)
+
+var (
+	errUnsigned      = errors.New("packet is neither signed nor encrypted")
+	errUnencrypted   = errors.New("packet is not encrypted")
+	errUnknownUser   = errors.New("unknown user")
+	errSignature     = errors.New("signature mismatch")
+	errChecksum      = errors.New("checksum mismatch of the decrypted packet")
+	errInvalidLength = errors.New("invalid part length")
+)
+
+// valueList is the values of a data set reported by a collectd plugin.
+type valueList struct {
+	host           string
+	plugin         string
+	pluginInstance string
+	typ            string
+	typeInstance   string
+	time           time.Time
+	interval       time.Duration
+	dsTypes        []byte
+	values         []float64
+}
+
+// parser decodes the packets with the security level, the users are the username and password pairs.
+type parser struct {
+	securityLevel string
+	users         map[string]string
+}
+
+// parse decodes the value lists of a packet, the notifications are ignored.
+func (p *parser) parse(packet []byte) ([]*valueList, error) {
+	return p.parseParts(packet, false)
+}
+
+func (p *parser) parseParts(packet []byte, verified bool) ([]*valueList, error) {
+	var lists []*valueList
+	state := &valueList{}
+	for len(packet) > 0 {
+		if len(packet) < partHeaderSize {
+			return lists, errInvalidLength
+		}
+		kind := binary.BigEndian.Uint16(packet)
+		partLen := int(binary.BigEndian.Uint16(packet[2:]))
+		if partLen < partHeaderSize || partLen > len(packet) {
+			return lists, errInvalidLength
+		}
+		body := packet[partHeaderSize:partLen]
+
+		switch kind {
+		case partSignature:
+			if verified {
+				break
+			}
+			if p.securityLevel == securityLevelEncrypt {
+				return lists, errUnencrypted
+			}
+			if err := p.verify(body, packet[partLen:]); err != nil {
+				return lists, err
+			}
+			more, err := p.parseParts(packet[partLen:], true)
+			return append(lists, more...), err
+		case partEncryption:
+			decrypted, err := p.decrypt(body)
+			if err != nil {
+				return lists, err
+			}
+			more, err := p.parseParts(decrypted, true)
+			lists = append(lists, more...)
+			if err != nil {
+				return lists, err
+			}
+			packet = packet[partLen:]
+			continue
+		}
+
+		if !verified {
+			switch p.securityLevel {
+			case securityLevelSign:
+				return lists, errUnsigned
+			case securityLevelEncrypt:
+				return lists, errUnencrypted
+			}
+		}
+
+		var err error
+		switch kind {
+		case partHost:
+			state.host, err = parseString(body)
+		case partPlugin:
+			state.plugin, err = parseString(body)
+		case partPluginInstance:
+			state.pluginInstance, err = parseString(body)
+		case partType:
+			state.typ, err = parseString(body)
+		case partTypeInstance:
+			state.typeInstance, err = parseString(body)
+		case partTime, partTimeHR:
+			var n uint64
+			if n, err = parseNumber(body); err == nil {
+				state.time = toTime(n, kind == partTimeHR)
+			}
+		case partInterval, partIntervalHR:
+			var n uint64
+			if n, err = parseNumber(body); err == nil {
+				state.interval = toDuration(n, kind == partIntervalHR)
+			}
+		case partValues:
+			var list *valueList
+			if list, err = parseValues(state, body); err == nil {
+				lists = append(lists, list)
+			}
+		}
+		if err != nil {
+			return lists, err
+		}
+		packet = packet[partLen:]
+	}
+	return lists, nil
+}
+
+// verify checks the HMAC-SHA256 of the username and the rest of the packet.
+func (p *parser) verify(body, rest []byte) error {
+	if len(body) < hashSize {
+		return errInvalidLength
+	}
+	username := string(body[hashSize:])
+	password, ok := p.users[username]
+	if !ok {
+		return fmt.Errorf("%w %s", errUnknownUser, username)
+	}
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(body[hashSize:])
+	mac.Write(rest)
+	if !hmac.Equal(mac.Sum(nil), body[:hashSize]) {
+		return errSignature
+	}
+	return nil
+}
+
+// decrypt decrypts the AES-256-OFB encrypted parts, which are prefixed with the SHA1 checksum.
+func (p *parser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 2 {
+		return nil, errInvalidLength
+	}
+	userLen := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+userLen+aes.BlockSize+checksumSize {
+		return nil, errInvalidLength
+	}
+	username := string(body[2 : 2+userLen])
+	password, ok := p.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", errUnknownUser, username)
+	}
+	iv := body[2+userLen : 2+userLen+aes.BlockSize]
+	encrypted := body[2+userLen+aes.BlockSize:]
+
+	key := sha256.Sum256([]byte(password))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewOFB(block, iv).XORKeyStream(decrypted, encrypted)
+	checksum := sha1.Sum(decrypted[checksumSize:]) //nolint:gosec
+	if !bytes.Equal(checksum[:], decrypted[:checksumSize]) {
+		return nil, errChecksum
+	}
+	return decrypted[checksumSize:], nil
+}
+
+func parseString(body []byte) (string, error) {
+	if len(body) == 0 || body[len(body)-1] != 0 {
+		return "", errors.New("string part is not null terminated")
+	}
+	return string(body[:len(body)-1]), nil
+}
+
+func parseNumber(body []byte) (uint64, error) {
+	if len(body) != 8 {
+		return 0, errInvalidLength
+	}
+	return binary.BigEndian.Uint64(body), nil
+}
+
+func parseValues(state *valueList, body []byte) (*valueList, error) {
+	if len(body) < 2 {
+		return nil, errInvalidLength
+	}
+	count := int(binary.BigEndian.Uint16(body))
+	if len(body) != 2+count*9 {
+		return nil, errInvalidLength
+	}
+	list := *state
+	list.dsTypes = make([]byte, count)
+	list.values = make([]float64, count)
+	copy(list.dsTypes, body[2:2+count])
+	data := body[2+count:]
+	for i := 0; i < count; i++ {
+		raw := data[i*8 : i*8+8]
+		switch list.dsTypes[i] {
+		case dsTypeGauge:
+			// gauges are the only little-endian values
+			list.values[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw))
+		case dsTypeDerive:
+			list.values[i] = float64(int64(binary.BigEndian.Uint64(raw)))
+		case dsTypeCounter, dsTypeAbsolute:
+			list.values[i] = float64(binary.BigEndian.Uint64(raw))
+		default:
+			return nil, fmt.Errorf("unknown data source type %d", list.dsTypes[i])
+		}
+	}
+	return &list, nil
+}
+
+// toTime converts the time in seconds, or in 2^-30 seconds for the high resolution time.
+func toTime(n uint64, highResolution bool) time.Time {
+	if !highResolution {
+		return time.Unix(int64(n), 0)
+	}
+	return time.Unix(int64(n>>30), int64((n&(1<<30-1))*uint64(time.Second)>>30))
+}
+
+func toDuration(n uint64, highResolution bool) time.Duration {
+	if !highResolution {
+		return time.Duration(n) * time.Second
+	}
+	return time.Duration(n>>30)*time.Second + time.Duration((n&(1<<30-1))*uint64(time.Second)>>30)
+}
+
+// loadTypesDB reads the data source names of the types from the types.db files,
+// whose lines are like "if_octets  rx:DERIVE:0:U, tx:DERIVE:0:U".
+func loadTypesDB(paths []string) (map[string][]string, error) {
+	types := make(map[string][]string)
+	for _, path := range paths {
+		if err := readTypesDB(path, types); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
+
+func readTypesDB(path string, types map[string][]string) error {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+	return parseTypesDB(f, types)
+}
+
+func parseTypesDB(r io.Reader, types map[string][]string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("invalid types.db line %q", line)
+		}
+		var names []string
+		for _, ds := range strings.Split(strings.Join(fields[1:], ""), ",") {
+			spec := strings.Split(ds, ":")
+			if len(spec) != 4 {
+				return fmt.Errorf("invalid data source %q of type %s", ds, fields[0])
+			}
+			names = append(names, spec[0])
+		}
+		types[fields[0]] = names
+	}
+	return scanner.Err()
+}
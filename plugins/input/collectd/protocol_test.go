@@ -0,0 +1,222 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func part(partType uint16, body []byte) []byte {
+	b := make([]byte, partHeaderSize, partHeaderSize+len(body))
+	binary.BigEndian.PutUint16(b, partType)
+	binary.BigEndian.PutUint16(b[2:], uint16(partHeaderSize+len(body)))
+	return append(b, body...)
+}
+
+func stringPart(partType uint16, s string) []byte {
+	return part(partType, append([]byte(s), 0))
+}
+
+func numberPart(partType uint16, n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return part(partType, b)
+}
+
+func valuesPart(dsTypes []byte, values []float64) []byte {
+	b := make([]byte, 2, 2+len(values)*9)
+	binary.BigEndian.PutUint16(b, uint16(len(values)))
+	b = append(b, dsTypes...)
+	for i, v := range values {
+		raw := make([]byte, 8)
+		switch dsTypes[i] {
+		case dsTypeGauge:
+			binary.LittleEndian.PutUint64(raw, math.Float64bits(v))
+		case dsTypeDerive:
+			binary.BigEndian.PutUint64(raw, uint64(int64(v)))
+		default:
+			binary.BigEndian.PutUint64(raw, uint64(v))
+		}
+		b = append(b, raw...)
+	}
+	return part(partValues, b)
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func sign(username, password string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(username))
+	mac.Write(payload)
+	return append(part(partSignature, append(mac.Sum(nil), username...)), payload...)
+}
+
+func encrypt(username, password string, payload []byte) []byte {
+	checksum := sha1.Sum(payload) //nolint:gosec
+	plain := append(checksum[:], payload...)
+	iv := []byte("0123456789abcdef")
+	key := sha256.Sum256([]byte(password))
+	block, _ := aes.NewCipher(key[:])
+	encrypted := make([]byte, len(plain))
+	cipher.NewOFB(block, iv).XORKeyStream(encrypted, plain)
+	body := make([]byte, 2)
+	binary.BigEndian.PutUint16(body, uint16(len(username)))
+	return part(partEncryption, concat(body, []byte(username), iv, encrypted))
+}
+
+// samplePacket is a cpu value list and a load value list with the high resolution time 1700000000.5.
+func samplePacket() []byte {
+	return concat(
+		stringPart(partHost, "node-1"),
+		numberPart(partTimeHR, 1700000000<<30|1<<29),
+		numberPart(partIntervalHR, 10<<30),
+		stringPart(partPlugin, "cpu"),
+		stringPart(partPluginInstance, "0"),
+		stringPart(partType, "cpu"),
+		stringPart(partTypeInstance, "user"),
+		valuesPart([]byte{dsTypeDerive}, []float64{-100}),
+		stringPart(partPlugin, "load"),
+		stringPart(partPluginInstance, ""),
+		stringPart(partType, "load"),
+		stringPart(partTypeInstance, ""),
+		valuesPart([]byte{dsTypeGauge, dsTypeGauge, dsTypeGauge}, []float64{0.5, 1.25, 2}),
+		stringPart(partMessage, "notification is ignored"),
+		numberPart(partSeverity, 4),
+	)
+}
+
+func TestParse(t *testing.T) {
+	p := &parser{securityLevel: securityLevelNone}
+	lists, err := p.parse(samplePacket())
+	require.NoError(t, err)
+	require.Len(t, lists, 2)
+
+	assert.Equal(t, "node-1", lists[0].host)
+	assert.Equal(t, "cpu", lists[0].plugin)
+	assert.Equal(t, "0", lists[0].pluginInstance)
+	assert.Equal(t, "cpu", lists[0].typ)
+	assert.Equal(t, "user", lists[0].typeInstance)
+	assert.Equal(t, time.Unix(1700000000, 500000000), lists[0].time)
+	assert.Equal(t, 10*time.Second, lists[0].interval)
+	assert.Equal(t, []byte{dsTypeDerive}, lists[0].dsTypes)
+	assert.Equal(t, []float64{-100}, lists[0].values)
+
+	assert.Equal(t, "load", lists[1].plugin)
+	assert.Equal(t, "", lists[1].pluginInstance)
+	assert.Equal(t, []float64{0.5, 1.25, 2}, lists[1].values)
+
+	lists, err = p.parse(concat(numberPart(partTime, 1700000000), stringPart(partPlugin, "uptime"), stringPart(partType, "uptime"),
+		valuesPart([]byte{dsTypeCounter}, []float64{42}), numberPart(partInterval, 10)))
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+	assert.Equal(t, time.Unix(1700000000, 0), lists[0].time)
+	assert.Equal(t, []float64{42}, lists[0].values)
+
+	for name, packet := range map[string][]byte{
+		"truncated header": {0, 2, 0},
+		"truncated part":   samplePacket()[:30],
+		"short length":     {0, 2, 0, 2},
+		"not terminated":   part(partHost, []byte("node")),
+		"invalid number":   part(partTime, []byte{1, 2, 3}),
+		"invalid values":   part(partValues, []byte{0, 2, dsTypeGauge, dsTypeGauge, 1}),
+		"unknown ds type":  valuesPart([]byte{9}, []float64{1}),
+	} {
+		_, err = p.parse(packet)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseSecurity(t *testing.T) {
+	users := map[string]string{"alice": "secret"}
+	packet := samplePacket()
+	signed := sign("alice", "secret", packet)
+	encrypted := encrypt("alice", "secret", packet)
+
+	p := &parser{securityLevel: securityLevelNone, users: users}
+	for _, data := range [][]byte{packet, signed, encrypted} {
+		lists, err := p.parse(data)
+		require.NoError(t, err)
+		assert.Len(t, lists, 2)
+	}
+
+	p.securityLevel = securityLevelSign
+	_, err := p.parse(packet)
+	assert.ErrorIs(t, err, errUnsigned)
+	for _, data := range [][]byte{signed, encrypted} {
+		lists, err := p.parse(data)
+		require.NoError(t, err)
+		assert.Len(t, lists, 2)
+	}
+	_, err = p.parse(sign("alice", "wrong", packet))
+	assert.ErrorIs(t, err, errSignature)
+	_, err = p.parse(sign("bob", "secret", packet))
+	assert.ErrorIs(t, err, errUnknownUser)
+	tampered := append([]byte{}, signed...)
+	tampered[len(tampered)-1]++
+	_, err = p.parse(tampered)
+	assert.ErrorIs(t, err, errSignature)
+
+	p.securityLevel = securityLevelEncrypt
+	_, err = p.parse(signed)
+	assert.ErrorIs(t, err, errUnencrypted)
+	lists, err := p.parse(encrypted)
+	require.NoError(t, err)
+	assert.Len(t, lists, 2)
+	// the unencrypted parts after the encrypted ones are rejected
+	lists, err = p.parse(concat(encrypted, packet))
+	assert.ErrorIs(t, err, errUnencrypted)
+	assert.Len(t, lists, 2)
+	_, err = p.parse(encrypt("alice", "wrong", packet))
+	assert.ErrorIs(t, err, errChecksum)
+	_, err = p.parse(encrypt("bob", "secret", packet))
+	assert.ErrorIs(t, err, errUnknownUser)
+}
+
+func TestParseTypesDB(t *testing.T) {
+	types := make(map[string][]string)
+	err := parseTypesDB(strings.NewReader(`
+# comment
+load			shortterm:GAUGE:0:5000, midterm:GAUGE:0:5000, longterm:GAUGE:0:5000
+if_octets		rx:DERIVE:0:U, tx:DERIVE:0:U
+uptime			value:GAUGE:0:U
+`), types)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"load":      {"shortterm", "midterm", "longterm"},
+		"if_octets": {"rx", "tx"},
+		"uptime":    {"value"},
+	}, types)
+
+	assert.Error(t, parseTypesDB(strings.NewReader("load"), types))
+	assert.Error(t, parseTypesDB(strings.NewReader("load shortterm:GAUGE"), types))
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package dnscapture
+
+import (
+	"fmt"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// readTimeoutMs bounds the blocking read so that the capture loop can notice the shutdown.
+const readTimeoutMs = 500
+
+// dnsFilter is the classic bpf program of "udp port 53" for ipv4 and ipv6 over ethernet,
+// so that only the dns traffic is copied to the user space.
+var dnsFilter = []unix.SockFilter{
+	{Code: 0x28, Jt: 0, Jf: 0, K: 0x0000000c},   // ldh [12]
+	{Code: 0x15, Jt: 0, Jf: 6, K: 0x000086dd},   // jeq #0x86dd, ipv6, next
+	{Code: 0x30, Jt: 0, Jf: 0, K: 0x00000014},   // ldb [20]
+	{Code: 0x15, Jt: 0, Jf: 15, K: 0x00000011},  // jeq #udp, next, drop
+	{Code: 0x28, Jt: 0, Jf: 0, K: 0x00000036},   // ldh [54]
+	{Code: 0x15, Jt: 12, Jf: 0, K: 0x00000035},  // jeq #53, accept
+	{Code: 0x28, Jt: 0, Jf: 0, K: 0x00000038},   // ldh [56]
+	{Code: 0x15, Jt: 10, Jf: 11, K: 0x00000035}, // jeq #53, accept, drop
+	{Code: 0x15, Jt: 0, Jf: 10, K: 0x00000800},  // jeq #0x800, ipv4, drop
+	{Code: 0x30, Jt: 0, Jf: 0, K: 0x00000017},   // ldb [23]
+	{Code: 0x15, Jt: 0, Jf: 8, K: 0x00000011},   // jeq #udp, next, drop
+	{Code: 0x28, Jt: 0, Jf: 0, K: 0x00000014},   // ldh [20]
+	{Code: 0x45, Jt: 6, Jf: 0, K: 0x00001fff},   // jset #0x1fff, drop
+	{Code: 0xb1, Jt: 0, Jf: 0, K: 0x0000000e},   // ldxb 4*([14]&0xf)
+	{Code: 0x48, Jt: 0, Jf: 0, K: 0x0000000e},   // ldh [x+14]
+	{Code: 0x15, Jt: 2, Jf: 0, K: 0x00000035},   // jeq #53, accept
+	{Code: 0x48, Jt: 0, Jf: 0, K: 0x00000010},   // ldh [x+16]
+	{Code: 0x15, Jt: 0, Jf: 1, K: 0x00000035},   // jeq #53, accept, drop
+	{Code: 0x06, Jt: 0, Jf: 0, K: 0x00040000},   // accept
+	{Code: 0x06, Jt: 0, Jf: 0, K: 0x00000000},   // drop
+}
+
+type afPacketSource struct {
+	fd int
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// newPacketSource opens an AF_PACKET socket bound to the interface, or all interfaces if iface is empty.
+func newPacketSource(iface string) (packetSource, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ALL)))
+	if err != nil {
+		return nil, fmt.Errorf("create packet socket error: %w", err)
+	}
+	source := &afPacketSource{fd: fd}
+	if err = source.setup(iface); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	return source, nil
+}
+
+func (s *afPacketSource) setup(iface string) error {
+	prog := unix.SockFprog{Len: uint16(len(dnsFilter)), Filter: (*unix.SockFilter)(unsafe.Pointer(&dnsFilter[0]))}
+	if err := unix.SetsockoptSockFprog(s.fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &prog); err != nil {
+		return fmt.Errorf("attach dns filter error: %w", err)
+	}
+	tv := unix.NsecToTimeval(readTimeoutMs * 1000 * 1000)
+	if err := unix.SetsockoptTimeval(s.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("set read timeout error: %w", err)
+	}
+	if iface == "" {
+		return nil
+	}
+	netIface, err := net.InterfaceByName(iface)
+	if err != nil {
+		return fmt.Errorf("find interface %s error: %w", iface, err)
+	}
+	addr := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: netIface.Index}
+	if err = unix.Bind(s.fd, addr); err != nil {
+		return fmt.Errorf("bind interface %s error: %w", iface, err)
+	}
+	return nil
+}
+
+func (s *afPacketSource) ReadPacket(buf []byte) (int, error) {
+	n, _, err := unix.Recvfrom(s.fd, buf, 0)
+	if err != nil {
+		if err == unix.EAGAIN || err == unix.EINTR {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *afPacketSource) Close() error {
+	return unix.Close(s.fd)
+}
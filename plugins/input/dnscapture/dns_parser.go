@@ -0,0 +1,248 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnscapture
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	dnsPort = 53
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	protocolUDP   = 17
+
+	ethernetHeaderLen = 14
+	ipv6HeaderLen     = 40
+	udpHeaderLen      = 8
+	dnsHeaderLen      = 12
+
+	// the max count of pointers to follow when decoding a compressed name.
+	maxNamePointers = 16
+)
+
+var (
+	errTruncated   = errors.New("packet truncated")
+	errNotDNS      = errors.New("not a dns packet over udp")
+	errBadName     = errors.New("invalid dns name")
+	errNoQuestions = errors.New("dns message without question")
+)
+
+var queryTypeNames = map[uint16]string{
+	1:   "A",
+	2:   "NS",
+	5:   "CNAME",
+	6:   "SOA",
+	12:  "PTR",
+	15:  "MX",
+	16:  "TXT",
+	28:  "AAAA",
+	33:  "SRV",
+	35:  "NAPTR",
+	43:  "DS",
+	46:  "RRSIG",
+	48:  "DNSKEY",
+	64:  "SVCB",
+	65:  "HTTPS",
+	255: "ANY",
+}
+
+var rcodeNames = map[uint8]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+func queryTypeName(t uint16) string {
+	if name, ok := queryTypeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+func rcodeName(rcode uint8) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return "RCODE" + strconv.Itoa(int(rcode))
+}
+
+// dnsPacket is a dns message carried by udp, only the fields used for observability are decoded.
+type dnsPacket struct {
+	srcIP   net.IP
+	dstIP   net.IP
+	srcPort uint16
+	dstPort uint16
+
+	id          uint16
+	response    bool
+	rcode       uint8
+	queryName   string
+	queryType   uint16
+	answerCount uint16
+}
+
+// decodeEthernet decodes the dns message from an ethernet frame.
+func decodeEthernet(frame []byte) (*dnsPacket, error) {
+	if len(frame) < ethernetHeaderLen {
+		return nil, errTruncated
+	}
+	etherType := binary.BigEndian.Uint16(frame[12:14])
+	offset := ethernetHeaderLen
+	for etherType == etherTypeVLAN {
+		if len(frame) < offset+4 {
+			return nil, errTruncated
+		}
+		etherType = binary.BigEndian.Uint16(frame[offset+2 : offset+4])
+		offset += 4
+	}
+	switch etherType {
+	case etherTypeIPv4:
+		return decodeIPv4(frame[offset:])
+	case etherTypeIPv6:
+		return decodeIPv6(frame[offset:])
+	}
+	return nil, errNotDNS
+}
+
+func decodeIPv4(data []byte) (*dnsPacket, error) {
+	if len(data) < 20 {
+		return nil, errTruncated
+	}
+	headerLen := int(data[0]&0x0f) * 4
+	if headerLen < 20 || len(data) < headerLen {
+		return nil, errTruncated
+	}
+	// fragments other than the first one carry no udp header.
+	if binary.BigEndian.Uint16(data[6:8])&0x1fff != 0 || data[9] != protocolUDP {
+		return nil, errNotDNS
+	}
+	return decodeUDP(net.IP(data[12:16]), net.IP(data[16:20]), data[headerLen:])
+}
+
+func decodeIPv6(data []byte) (*dnsPacket, error) {
+	if len(data) < ipv6HeaderLen {
+		return nil, errTruncated
+	}
+	// extension headers are rarely used by dns traffic, so they are not followed.
+	if data[6] != protocolUDP {
+		return nil, errNotDNS
+	}
+	return decodeUDP(net.IP(data[8:24]), net.IP(data[24:40]), data[ipv6HeaderLen:])
+}
+
+func decodeUDP(srcIP, dstIP net.IP, data []byte) (*dnsPacket, error) {
+	if len(data) < udpHeaderLen {
+		return nil, errTruncated
+	}
+	srcPort := binary.BigEndian.Uint16(data[0:2])
+	dstPort := binary.BigEndian.Uint16(data[2:4])
+	if srcPort != dnsPort && dstPort != dnsPort {
+		return nil, errNotDNS
+	}
+	packet, err := decodeDNS(data[udpHeaderLen:])
+	if err != nil {
+		return nil, err
+	}
+	packet.srcIP = append(net.IP(nil), srcIP...)
+	packet.dstIP = append(net.IP(nil), dstIP...)
+	packet.srcPort = srcPort
+	packet.dstPort = dstPort
+	return packet, nil
+}
+
+// decodeDNS decodes the header and the first question of a dns message.
+func decodeDNS(data []byte) (*dnsPacket, error) {
+	if len(data) < dnsHeaderLen {
+		return nil, errTruncated
+	}
+	flags := binary.BigEndian.Uint16(data[2:4])
+	if binary.BigEndian.Uint16(data[4:6]) == 0 {
+		return nil, errNoQuestions
+	}
+	packet := &dnsPacket{
+		id:          binary.BigEndian.Uint16(data[0:2]),
+		response:    flags&0x8000 != 0,
+		rcode:       uint8(flags & 0x000f),
+		answerCount: binary.BigEndian.Uint16(data[6:8]),
+	}
+	name, offset, err := decodeName(data, dnsHeaderLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < offset+4 {
+		return nil, errTruncated
+	}
+	packet.queryName = name
+	packet.queryType = binary.BigEndian.Uint16(data[offset : offset+2])
+	return packet, nil
+}
+
+// decodeName decodes the possibly compressed name at offset,
+// and returns the name and the offset following the name.
+func decodeName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for pointers := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errTruncated
+		}
+		length := int(msg[offset])
+		switch length & 0xc0 {
+		case 0x00:
+			if length == 0 {
+				if next < 0 {
+					next = offset + 1
+				}
+				if len(labels) == 0 {
+					return ".", next, nil
+				}
+				return strings.Join(labels, "."), next, nil
+			}
+			if offset+1+length > len(msg) {
+				return "", 0, errTruncated
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		case 0xc0:
+			if offset+2 > len(msg) {
+				return "", 0, errTruncated
+			}
+			if pointers++; pointers > maxNamePointers {
+				return "", 0, errBadName
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3fff)
+		default:
+			return "", 0, errBadName
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnscapture
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const (
+	pluginType = "service_dns_capture"
+
+	rcodeTimeout     = "TIMEOUT"
+	maxFrameSize     = 65536
+	expireIntervalMs = 1000
+)
+
+// packetSource reads the raw link layer frames captured on the node.
+type packetSource interface {
+	// ReadPacket reads one frame into buf, a zero length with nil error means no frame arrived before the read timeout.
+	ReadPacket(buf []byte) (int, error)
+	Close() error
+}
+
+type transactionKey struct {
+	clientIP   string
+	clientPort uint16
+	serverIP   string
+	id         uint16
+}
+
+type pendingQuery struct {
+	packet *dnsPacket
+	start  time.Time
+}
+
+// ServiceDNSCapture captures the dns traffic on the node with raw sockets, matches the queries with the
+// responses and emits one event per dns transaction, which is attributed to the client pod by its ip.
+type ServiceDNSCapture struct {
+	Interface         string // the interface to capture, empty means all interfaces
+	QueryTimeoutMs    int    // queries without response in the time are emitted with the TIMEOUT rcode
+	MaxPendingQueries int    // the max count of queries waiting for response, newer queries are ignored when exceeded
+	AttachPodMeta     bool   // attach the metadata of the client pod found in k8smeta
+
+	context   pipeline.Context
+	collector pipeline.Collector
+	source    packetSource
+	pending   map[transactionKey]*pendingQuery
+	lock      sync.Mutex
+	shutdown  chan struct{}
+	wg        sync.WaitGroup
+
+	newSource  func(iface string) (packetSource, error)
+	podMetaGet func(ip string) *k8smeta.PodMetadata
+	now        func() time.Time
+}
+
+func (s *ServiceDNSCapture) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if s.QueryTimeoutMs <= 0 {
+		s.QueryTimeoutMs = 5000
+	}
+	if s.MaxPendingQueries <= 0 {
+		s.MaxPendingQueries = 10000
+	}
+	if s.newSource == nil {
+		s.newSource = newPacketSource
+	}
+	if s.podMetaGet == nil {
+		s.podMetaGet = k8smeta.GetMetaManagerInstance().GetPodMetadataByIP
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	return 0, nil
+}
+
+func (s *ServiceDNSCapture) Description() string {
+	return "capture dns queries and responses on the node and emit one event per dns transaction"
+}
+
+func (s *ServiceDNSCapture) Start(collector pipeline.Collector) error {
+	source, err := s.newSource(s.Interface)
+	if err != nil {
+		logger.Error(s.context.GetRuntimeContext(), "SERVICE_DNS_CAPTURE_ALARM", "open capture socket error", err, "interface", s.Interface)
+		return err
+	}
+	s.collector = collector
+	s.source = source
+	s.pending = make(map[transactionKey]*pendingQuery)
+	s.shutdown = make(chan struct{})
+	s.wg.Add(2)
+	go s.capture()
+	go s.expire()
+	return nil
+}
+
+func (s *ServiceDNSCapture) Stop() error {
+	close(s.shutdown)
+	s.wg.Wait()
+	return s.source.Close()
+}
+
+func (s *ServiceDNSCapture) capture() {
+	defer s.wg.Done()
+	buf := make([]byte, maxFrameSize)
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		default:
+		}
+		n, err := s.source.ReadPacket(buf)
+		if err != nil {
+			logger.Warning(s.context.GetRuntimeContext(), "SERVICE_DNS_CAPTURE_ALARM", "read packet error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		packet, err := decodeEthernet(buf[:n])
+		if err != nil {
+			continue
+		}
+		s.handlePacket(packet)
+	}
+}
+
+func (s *ServiceDNSCapture) expire() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(expireIntervalMs * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+			s.expirePending()
+		}
+	}
+}
+
+func (s *ServiceDNSCapture) handlePacket(packet *dnsPacket) {
+	now := s.now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !packet.response {
+		key := transactionKey{clientIP: packet.srcIP.String(), clientPort: packet.srcPort, serverIP: packet.dstIP.String(), id: packet.id}
+		// retransmitted queries keep the first start time.
+		if _, ok := s.pending[key]; !ok && len(s.pending) < s.MaxPendingQueries {
+			s.pending[key] = &pendingQuery{packet: packet, start: now}
+		}
+		return
+	}
+	key := transactionKey{clientIP: packet.dstIP.String(), clientPort: packet.dstPort, serverIP: packet.srcIP.String(), id: packet.id}
+	query, ok := s.pending[key]
+	if !ok {
+		// the query is sent before the capture starts or has been expired.
+		return
+	}
+	delete(s.pending, key)
+	s.emit(query, rcodeName(packet.rcode), packet.answerCount, now.Sub(query.start), now)
+}
+
+func (s *ServiceDNSCapture) expirePending() {
+	now := s.now()
+	timeout := time.Duration(s.QueryTimeoutMs) * time.Millisecond
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, query := range s.pending {
+		if now.Sub(query.start) >= timeout {
+			delete(s.pending, key)
+			s.emit(query, rcodeTimeout, 0, timeout, now)
+		}
+	}
+}
+
+func (s *ServiceDNSCapture) emit(query *pendingQuery, rcode string, answerCount uint16, latency time.Duration, now time.Time) {
+	packet := query.packet
+	clientIP := packet.srcIP.String()
+	fields := map[string]string{
+		"query_name":   packet.queryName,
+		"query_type":   queryTypeName(packet.queryType),
+		"rcode":        rcode,
+		"answer_count": strconv.Itoa(int(answerCount)),
+		"latency_us":   strconv.FormatInt(latency.Microseconds(), 10),
+		"client_ip":    clientIP,
+		"client_port":  strconv.Itoa(int(packet.srcPort)),
+		"server_ip":    packet.dstIP.String(),
+		"server_port":  strconv.Itoa(int(packet.dstPort)),
+	}
+	if s.AttachPodMeta {
+		if pod := s.podMetaGet(clientIP); pod != nil {
+			fields["_pod_name_"] = pod.PodName
+			fields["_namespace_"] = pod.Namespace
+			fields["_workload_name_"] = pod.WorkloadName
+			fields["_workload_kind_"] = pod.WorkloadKind
+		}
+	}
+	s.collector.AddData(nil, fields, now)
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceDNSCapture{
+			QueryTimeoutMs:    5000,
+			MaxPendingQueries: 10000,
+			AttachPodMeta:     true,
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnscapture
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper/k8smeta"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+// buildDNS builds a dns message with one question, the name of the answer is compressed if answered.
+func buildDNS(id uint16, response bool, rcode uint8, name string, qtype uint16, answered bool) []byte {
+	msg := make([]byte, dnsHeaderLen)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	flags := uint16(0x0100) | uint16(rcode)
+	if response {
+		flags |= 0x8000
+	}
+	binary.BigEndian.PutUint16(msg[2:4], flags)
+	binary.BigEndian.PutUint16(msg[4:6], 1)
+	if answered {
+		binary.BigEndian.PutUint16(msg[6:8], 1)
+	}
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, byte(qtype>>8), byte(qtype), 0, 1)
+	if answered {
+		// pointer to the question name, type, class, ttl, rdlength and an ipv4 address
+		msg = append(msg, 0xc0, dnsHeaderLen, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 10, 0, 0, 10)
+	}
+	return msg
+}
+
+func buildIPv4Frame(src, dst string, srcPort, dstPort uint16, payload []byte) []byte {
+	frame := make([]byte, ethernetHeaderLen+20+udpHeaderLen)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+	ip := frame[ethernetHeaderLen:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+udpHeaderLen+len(payload)))
+	ip[8] = 64
+	ip[9] = protocolUDP
+	copy(ip[12:16], net.ParseIP(src).To4())
+	copy(ip[16:20], net.ParseIP(dst).To4())
+	udp := ip[20:]
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(payload)))
+	return append(frame, payload...)
+}
+
+func buildIPv6Frame(src, dst string, srcPort, dstPort uint16, payload []byte) []byte {
+	frame := make([]byte, ethernetHeaderLen+ipv6HeaderLen+udpHeaderLen)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv6)
+	ip := frame[ethernetHeaderLen:]
+	ip[0] = 0x60
+	binary.BigEndian.PutUint16(ip[4:6], uint16(udpHeaderLen+len(payload)))
+	ip[6] = protocolUDP
+	copy(ip[8:24], net.ParseIP(src).To16())
+	copy(ip[24:40], net.ParseIP(dst).To16())
+	udp := ip[ipv6HeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	return append(frame, payload...)
+}
+
+func TestDecodeEthernet(t *testing.T) {
+	packet, err := decodeEthernet(buildIPv4Frame("172.20.0.5", "10.96.0.10", 40000, 53, buildDNS(0x1234, false, 0, "example.com", 1, false)))
+	require.NoError(t, err)
+	assert.False(t, packet.response)
+	assert.Equal(t, uint16(0x1234), packet.id)
+	assert.Equal(t, "example.com", packet.queryName)
+	assert.Equal(t, "A", queryTypeName(packet.queryType))
+	assert.Equal(t, "172.20.0.5", packet.srcIP.String())
+	assert.Equal(t, uint16(53), packet.dstPort)
+
+	packet, err = decodeEthernet(buildIPv6Frame("fd00::10", "fd00::5", 53, 40000, buildDNS(7, true, 3, "missing.svc.cluster.local", 28, true)))
+	require.NoError(t, err)
+	assert.True(t, packet.response)
+	assert.Equal(t, "NXDOMAIN", rcodeName(packet.rcode))
+	assert.Equal(t, "AAAA", queryTypeName(packet.queryType))
+	assert.Equal(t, "missing.svc.cluster.local", packet.queryName)
+	assert.Equal(t, uint16(1), packet.answerCount)
+
+	_, err = decodeEthernet(buildIPv4Frame("172.20.0.5", "10.96.0.10", 40000, 8080, buildDNS(1, false, 0, "example.com", 1, false)))
+	assert.Equal(t, errNotDNS, err)
+	_, err = decodeEthernet(buildIPv4Frame("172.20.0.5", "10.96.0.10", 40000, 53, []byte{1, 2, 3}))
+	assert.Equal(t, errTruncated, err)
+}
+
+func TestDecodeNameLoop(t *testing.T) {
+	msg := make([]byte, dnsHeaderLen)
+	msg = append(msg, 0xc0, dnsHeaderLen)
+	_, _, err := decodeName(msg, dnsHeaderLen)
+	assert.Equal(t, errBadName, err)
+}
+
+func TestServiceDNSCaptureTransactions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := &ServiceDNSCapture{
+		AttachPodMeta: true,
+		podMetaGet: func(ip string) *k8smeta.PodMetadata {
+			if ip == "172.20.0.5" {
+				return &k8smeta.PodMetadata{PodName: "web-0", Namespace: "default", WorkloadName: "web", WorkloadKind: "statefulset"}
+			}
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.pending = make(map[transactionKey]*pendingQuery)
+
+	handle := func(frame []byte) {
+		packet, err := decodeEthernet(frame)
+		require.NoError(t, err)
+		s.handlePacket(packet)
+	}
+	handle(buildIPv4Frame("172.20.0.5", "10.96.0.10", 40000, 53, buildDNS(1, false, 0, "example.com", 1, false)))
+	handle(buildIPv4Frame("172.20.0.6", "10.96.0.10", 40001, 53, buildDNS(2, false, 0, "slow.example.com", 1, false)))
+	// a response without query is ignored
+	handle(buildIPv4Frame("10.96.0.10", "172.20.0.5", 53, 40000, buildDNS(3, true, 0, "other.com", 1, true)))
+	now = now.Add(1500 * time.Microsecond)
+	handle(buildIPv4Frame("10.96.0.10", "172.20.0.5", 53, 40000, buildDNS(1, true, 0, "example.com", 1, true)))
+
+	require.Len(t, collector.Logs, 1)
+	fields := collector.Logs[0].Fields
+	assert.Equal(t, "example.com", fields["query_name"])
+	assert.Equal(t, "A", fields["query_type"])
+	assert.Equal(t, "NOERROR", fields["rcode"])
+	assert.Equal(t, "1", fields["answer_count"])
+	assert.Equal(t, "1500", fields["latency_us"])
+	assert.Equal(t, "172.20.0.5", fields["client_ip"])
+	assert.Equal(t, "10.96.0.10", fields["server_ip"])
+	assert.Equal(t, "web-0", fields["_pod_name_"])
+	assert.Equal(t, "default", fields["_namespace_"])
+
+	now = now.Add(5 * time.Second)
+	s.expirePending()
+	require.Len(t, collector.Logs, 2)
+	fields = collector.Logs[1].Fields
+	assert.Equal(t, "slow.example.com", fields["query_name"])
+	assert.Equal(t, rcodeTimeout, fields["rcode"])
+	assert.NotContains(t, fields, "_pod_name_")
+	assert.Empty(t, s.pending)
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdout
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+)
+
+const (
+	completionTriggerExit     = "exit"
+	completionTriggerSentinel = "sentinel"
+
+	// completionContainerIDPlaceholder in the sentinel file path is replaced by the id of the container.
+	completionContainerIDPlaceholder = "{container_id}"
+
+	completionEventKey = "_collection_complete_"
+)
+
+func (sds *ServiceDockerStdout) initCompletion() error {
+	switch sds.CompletionTrigger {
+	case "", completionTriggerExit:
+	case completionTriggerSentinel:
+		if sds.CompletionSentinelFile == "" {
+			return fmt.Errorf("CompletionSentinelFile is required by the %s completion trigger", completionTriggerSentinel)
+		}
+	default:
+		return fmt.Errorf("invalid CompletionTrigger %s, should be %s or %s", sds.CompletionTrigger, completionTriggerExit, completionTriggerSentinel)
+	}
+	sds.completedMap = make(map[string]struct{})
+	return nil
+}
+
+// containerStatuses returns the status of each known container, the removed containers are absent.
+func containerStatuses() map[string]string {
+	statuses := make(map[string]string)
+	helper.ProcessContainerAllInfo(func(info *helper.DockerInfoDetail) {
+		statuses[info.ContainerInfo.ID] = info.Status()
+	})
+	return statuses
+}
+
+// checkCompletion completes the containers whose completion is triggered and whose log files are read to the end.
+// @statuses is the status of each known container, and only used by the exit trigger.
+func (sds *ServiceDockerStdout) checkCompletion(statuses map[string]string) {
+	for id, syner := range sds.synerMap {
+		if !sds.completionTriggered(id, statuses) || !readToEnd(syner) {
+			continue
+		}
+		sds.complete(id, syner)
+	}
+}
+
+func (sds *ServiceDockerStdout) completionTriggered(id string, statuses map[string]string) bool {
+	switch sds.CompletionTrigger {
+	case completionTriggerExit:
+		status, ok := statuses[id]
+		return !ok || status == helper.ContainerStatusExited
+	case completionTriggerSentinel:
+		path := strings.ReplaceAll(sds.CompletionSentinelFile, completionContainerIDPlaceholder, id)
+		if sds.LogtailInDocker {
+			path = helper.GetMountedFilePath(path)
+		}
+		_, err := os.Stat(path)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// readToEnd returns true if all the content of the log file has been processed, or the file is gone.
+func readToEnd(syner *DockerFileSyner) bool {
+	checkpoint, _ := syner.dockerFileReader.GetCheckpoint()
+	stat, err := os.Stat(checkpoint.Path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return checkpoint.Offset >= stat.Size()
+}
+
+// complete stops reading the log file and emits the collection complete log. The log file is owned by the container
+// runtime, so it is left untouched, and the checkpoint is kept so that it is not collected again while the container
+// is still listed.
+func (sds *ServiceDockerStdout) complete(id string, syner *DockerFileSyner) {
+	sds.stopSyner(syner)
+	checkpoint, _ := syner.dockerFileReader.GetCheckpoint()
+	sds.checkpointMap[id] = checkpoint
+	delete(sds.synerMap, id)
+	sds.completedMap[id] = struct{}{}
+	sds.completeMetric.Add(1)
+
+	fields := syner.info.GetExternalTags(sds.ExternalEnvTag, sds.ExternalK8sLabelTag)
+	for k, v := range syner.info.ContainerNameTag {
+		fields[k] = v
+	}
+	fields[completionEventKey] = "true"
+	fields["_container_id_"] = id
+	fields["_path_"] = syner.filePath
+	fields["_offset_"] = strconv.FormatInt(checkpoint.Offset, 10)
+	sds.collector.AddData(nil, fields, time.Now())
+	logger.Info(sds.context.GetRuntimeContext(), "docker stdout", "collection complete", "id", helper.GetShortID(id),
+		"name", syner.info.ContainerInfo.Name, "file", syner.filePath, "offset", checkpoint.Offset)
+}
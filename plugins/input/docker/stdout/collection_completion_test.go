@@ -0,0 +1,97 @@
+// Copyright 2021 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/input"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func TestCompletionInit(t *testing.T) {
+	for _, sds := range []*ServiceDockerStdout{
+		{CompletionTrigger: "done"},
+		{CompletionTrigger: completionTriggerSentinel},
+	} {
+		assert.Error(t, sds.initCompletion(), sds)
+	}
+	sds := &ServiceDockerStdout{CompletionTrigger: completionTriggerExit}
+	require.NoError(t, sds.initCompletion())
+
+	assert.True(t, sds.completionTriggered("c1", map[string]string{"c1": helper.ContainerStatusExited}))
+	assert.True(t, sds.completionTriggered("c1", map[string]string{}), "the removed container has exited")
+	assert.False(t, sds.completionTriggered("c1", map[string]string{"c1": helper.ContainerStatusRunning}))
+}
+
+func TestCompletionSentinel(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "c1-json.log")
+	require.NoError(t, os.WriteFile(logPath, []byte(`{"log":"job done\n","stream":"stdout","time":"2024-01-01T00:00:00.000000000Z"}`+"\n"), 0600))
+	info := &helper.DockerInfoDetail{
+		ContainerInfo: types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:      "c1",
+				Name:    "c1",
+				LogPath: logPath,
+			},
+		},
+	}
+	sds := pipeline.ServiceInputs[input.ServiceDockerStdoutPluginName]().(*ServiceDockerStdout)
+	sds.LogtailInDocker = false
+	sds.ReadIntervalMs = 10
+	sds.CompletionTrigger = completionTriggerSentinel
+	sds.CompletionSentinelFile = filepath.Join(dir, "{container_id}.done")
+	_, err := sds.Init(mock.NewEmptyContext("project", "store", "completion_config"))
+	require.NoError(t, err)
+	sds.checkpointMap = make(map[string]helper.LogFileReaderCheckPoint)
+	collector := &helper.LocalCollector{}
+	sds.collector = collector
+
+	sds.startSyner("c1", info)
+	require.Contains(t, sds.synerMap, "c1")
+	require.Eventually(t, func() bool { return readToEnd(sds.synerMap["c1"]) }, 5*time.Second, 10*time.Millisecond)
+	// not completed before the sentinel file exists
+	sds.checkCompletion(nil)
+	require.Contains(t, sds.synerMap, "c1")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c1.done"), nil, 0600))
+	sds.checkCompletion(nil)
+	assert.NotContains(t, sds.synerMap, "c1")
+	assert.Contains(t, sds.completedMap, "c1")
+	assert.Contains(t, sds.checkpointMap, "c1", "the checkpoint is kept to not collect the file again")
+
+	require.Len(t, collector.Logs, 2)
+	assert.Equal(t, "job done", collector.Logs[0].Contents[0].Value)
+	fields := make(map[string]string)
+	for _, content := range collector.Logs[1].Contents {
+		fields[content.Key] = content.Value
+	}
+	assert.Equal(t, "true", fields[completionEventKey])
+	assert.Equal(t, "c1", fields["_container_id_"])
+
+	// the log file is owned by the container runtime and left untouched
+	_, err = os.Stat(logPath)
+	assert.NoError(t, err)
+}
@@ -34,6 +34,9 @@ var (
 	lineSuffix        = []byte{'\n'}
 )
 
+// truncatedKey is the field key tagging the logs whose merged line exceeds the max merged line size.
+const truncatedKey = "_truncated_"
+
 type DockerJSONLog struct {
 	LogContent string `json:"log"`
 	StreamType string `json:"stream"`
@@ -45,6 +48,9 @@ type LogMessage struct {
 	StreamType string
 	Content    []byte
 	Safe       bool
+	// Partial means the line is split by the container runtime and continued by the next line,
+	// such as the containerd 'P' tag or the docker json log without the trailing '\n'.
+	Partial bool
 }
 
 // safeContent allocate self memory for content to avoid modifying.
@@ -64,38 +70,57 @@ func (l *LogMessage) safeContent() {
 // }
 
 type DockerStdoutProcessor struct {
-	beginLineReg         *regexp.Regexp
-	beginLineTimeout     time.Duration
-	beginLineCheckLength int
-	maxLogSize           int
-	stdout               bool
-	stderr               bool
-	context              pipeline.Context
-	collector            pipeline.Collector
+	maxLogSize  int
+	maxLineSize int
+	stdout      bool
+	stderr      bool
+	context     pipeline.Context
+	collector   pipeline.Collector
 
 	needCheckStream bool
 	source          string
 	tags            []protocol.Log_Content
 	fieldNum        int
 
+	// the streams buffered separately, the other streams share the default one
+	defaultStream *streamState
+	streams       map[string]*streamState
+	streamOrder   []*streamState
+	streamTags    map[string][]protocol.Log_Content
+}
+
+// streamState holds the multi line settings and the last parsed logs of a stream.
+type streamState struct {
+	beginLineReg         *regexp.Regexp
+	beginLineTimeout     time.Duration
+	beginLineCheckLength int
+
 	// save last parsed logs
 	lastLogs      []*LogMessage
 	lastLogsCount int
+
+	// the state of the line being merged from the partial lines
+	merging   bool
+	lineSize  int
+	truncated bool
 }
 
 func NewDockerStdoutProcessor(beginLineReg *regexp.Regexp, beginLineTimeout time.Duration, beginLineCheckLength int,
 	maxLogSize int, stdout bool, stderr bool, context pipeline.Context, collector pipeline.Collector,
 	tags map[string]string, source string) *DockerStdoutProcessor {
 	processor := &DockerStdoutProcessor{
-		beginLineReg:         beginLineReg,
-		beginLineTimeout:     beginLineTimeout,
-		beginLineCheckLength: beginLineCheckLength,
-		maxLogSize:           maxLogSize,
-		stdout:               stdout,
-		stderr:               stderr,
-		context:              context,
-		collector:            collector,
-		source:               source,
+		maxLogSize:  maxLogSize,
+		maxLineSize: maxLogSize,
+		stdout:      stdout,
+		stderr:      stderr,
+		context:     context,
+		collector:   collector,
+		source:      source,
+		defaultStream: &streamState{
+			beginLineReg:         beginLineReg,
+			beginLineTimeout:     beginLineTimeout,
+			beginLineCheckLength: beginLineCheckLength,
+		},
 	}
 
 	if stdout && stderr {
@@ -110,6 +135,54 @@ func NewDockerStdoutProcessor(beginLineReg *regexp.Regexp, beginLineTimeout time
 	return processor
 }
 
+// SeparateStream buffers the multi line logs of the stream separately with its own settings, so the lines of
+// the stream are never merged with the interleaved lines of the other streams.
+func (p *DockerStdoutProcessor) SeparateStream(streamType string, beginLineReg *regexp.Regexp, beginLineTimeout time.Duration, beginLineCheckLength int) {
+	if p.streams == nil {
+		p.streams = make(map[string]*streamState)
+	}
+	state := &streamState{
+		beginLineReg:         beginLineReg,
+		beginLineTimeout:     beginLineTimeout,
+		beginLineCheckLength: beginLineCheckLength,
+	}
+	p.streams[streamType] = state
+	p.streamOrder = append(p.streamOrder, state)
+}
+
+// SetMaxLineSize sets the maximum size of a line merged from the partial lines, the exceeded part is discarded
+// and the log is tagged with _truncated_.
+func (p *DockerStdoutProcessor) SetMaxLineSize(maxLineSize int) {
+	if maxLineSize > 0 {
+		p.maxLineSize = maxLineSize
+	}
+}
+
+// SetStreamTags sets the extra fields appended to the logs of the stream.
+func (p *DockerStdoutProcessor) SetStreamTags(streamType string, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	if p.streamTags == nil {
+		p.streamTags = make(map[string][]protocol.Log_Content)
+	}
+	contents := make([]protocol.Log_Content, 0, len(tags))
+	for k, v := range tags {
+		contents = append(contents, protocol.Log_Content{Key: k, Value: v})
+	}
+	p.streamTags[streamType] = contents
+	if n := len(p.tags) + len(contents) + 3; n > p.fieldNum {
+		p.fieldNum = n
+	}
+}
+
+func (p *DockerStdoutProcessor) stream(streamType string) *streamState {
+	if state, ok := p.streams[streamType]; ok {
+		return state
+	}
+	return p.defaultStream
+}
+
 // parseCRILog parses logs in CRI log format.
 // CRI log format example :
 // 2017-09-12T22:32:21.212861448Z stdout 2017-09-12 22:32:21.212 [INFO][88] table.go 710: Invalidating dataplane cache
@@ -151,6 +224,7 @@ func parseCRILog(line []byte) (*LogMessage, error) {
 				Content: line,
 			}, errors.New("invalid CRI log, log content not found")
 		}
+		log.Partial = true
 		if bytes.HasSuffix(temp, lineSuffix) {
 			log.Content = temp[i+1 : len(temp)-1]
 		} else {
@@ -177,6 +251,8 @@ func parseDockerJSONLog(line []byte) (*LogMessage, error) {
 		StreamType: dockerLog.StreamType,
 		Content:    util.ZeroCopyStringToBytes(dockerLog.LogContent),
 		Safe:       true,
+		// docker splits the long line into 16K parts, and only the last part ends with '\n'
+		Partial: len(dockerLog.LogContent) > 0 && dockerLog.LogContent[len(dockerLog.LogContent)-1] != '\n',
 	}
 	dockerLog.LogContent = ""
 	return l, nil
@@ -221,41 +297,34 @@ func (p *DockerStdoutProcessor) Process(fileBlock []byte, noChangeInterval time.
 		nextIndex += nowIndex
 		thisLog := p.ParseContainerLogLine(fileBlock[nowIndex : nextIndex+1])
 		if p.StreamAllowed(thisLog) {
-			// last char
-			lastChar := uint8('\n')
-			if contentLen := len(thisLog.Content); contentLen > 0 {
-				lastChar = thisLog.Content[contentLen-1]
-			}
+			s := p.stream(thisLog.StreamType)
 			switch {
-			case p.beginLineReg == nil && len(p.lastLogs) == 0 && lastChar == '\n':
+			case s.beginLineReg == nil && len(s.lastLogs) == 0 && !thisLog.Partial:
 				// collect single line
 				p.collector.AddRawLogWithContext(p.newRawLogBySingleLine(thisLog), map[string]interface{}{"source": p.source})
-			case p.beginLineReg == nil:
+			case s.beginLineReg == nil:
 				// collect spilt multi lines, such as containerd.
-				if lastChar != '\n' {
-					thisLog.safeContent()
-				}
-				p.lastLogs = append(p.lastLogs, thisLog)
-				p.lastLogsCount += len(thisLog.Content) + 24
-				if lastChar == '\n' {
-					p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(), map[string]interface{}{"source": p.source})
+				p.appendLine(s, thisLog)
+				if !thisLog.Partial {
+					p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(s), map[string]interface{}{"source": p.source})
 				}
 			default:
-				// collect user multi lines.
-				var checkLine []byte
-				if len(thisLog.Content) > p.beginLineCheckLength {
-					checkLine = thisLog.Content[0:p.beginLineCheckLength]
-				} else {
-					checkLine = thisLog.Content
-				}
-				if p.beginLineReg.Match(checkLine) {
-					if len(p.lastLogs) != 0 {
-						p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(), map[string]interface{}{"source": p.source})
+				// collect user multi lines, only the first part of a split line is checked.
+				if !s.merging {
+					var checkLine []byte
+					if len(thisLog.Content) > s.beginLineCheckLength {
+						checkLine = thisLog.Content[0:s.beginLineCheckLength]
+					} else {
+						checkLine = thisLog.Content
+					}
+					if s.beginLineReg.Match(checkLine) {
+						if len(s.lastLogs) != 0 {
+							p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(s), map[string]interface{}{"source": p.source})
+						}
 					}
 				}
 				thisLog.safeContent()
-				p.lastLogs = append(p.lastLogs, thisLog)
-				p.lastLogsCount += len(thisLog.Content) + 24
+				p.appendLine(s, thisLog)
 			}
 		}
 
@@ -266,8 +335,9 @@ func (p *DockerStdoutProcessor) Process(fileBlock []byte, noChangeInterval time.
 	}
 
 	// last line and multi line timeout expired
-	if len(p.lastLogs) > 0 && (noChangeInterval > p.beginLineTimeout || p.lastLogsCount > p.maxLogSize) {
-		p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(), map[string]interface{}{"source": p.source})
+	p.flushExpired(p.defaultStream, noChangeInterval)
+	for _, s := range p.streamOrder {
+		p.flushExpired(s, noChangeInterval)
 	}
 
 	// no new line
@@ -279,6 +349,50 @@ func (p *DockerStdoutProcessor) Process(fileBlock []byte, noChangeInterval time.
 	return processedCount
 }
 
+// appendLine appends the log to the last logs of the stream. The partial lines are merged up to maxLineSize,
+// and the exceeded part is discarded with the merged line tagged as truncated.
+func (p *DockerStdoutProcessor) appendLine(s *streamState, log *LogMessage) {
+	if log.Partial || s.merging {
+		remain := p.maxLineSize - s.lineSize
+		if remain < 0 {
+			remain = 0
+		}
+		if len(log.Content) > remain {
+			s.truncated = true
+			content := log.Content[:remain]
+			if !log.Partial && log.Content[len(log.Content)-1] == '\n' {
+				// keep the line end to separate the following lines of user multi lines
+				content = append(append(make([]byte, 0, remain+1), content...), '\n')
+				log.Safe = true
+			}
+			log.Content = content
+		}
+		s.lineSize += len(log.Content)
+		s.merging = log.Partial
+		if !log.Partial {
+			s.lineSize = 0
+		}
+		if log.Partial && len(log.Content) == 0 {
+			return
+		}
+	}
+	if log.Partial {
+		log.safeContent()
+	}
+	s.lastLogs = append(s.lastLogs, log)
+	s.lastLogsCount += len(log.Content) + 24
+}
+
+func (p *DockerStdoutProcessor) flushExpired(s *streamState, noChangeInterval time.Duration) {
+	if len(s.lastLogs) == 0 {
+		return
+	}
+	// the merging line is not flushed by size since its size is limited by maxLineSize
+	if noChangeInterval > s.beginLineTimeout || (s.lastLogsCount > p.maxLogSize && !s.merging) {
+		p.collector.AddRawLogWithContext(p.newRawLogByMultiLine(s), map[string]interface{}{"source": p.source})
+	}
+}
+
 // newRawLogBySingleLine convert single line log to protocol.Log.
 func (p *DockerStdoutProcessor) newRawLogBySingleLine(msg *LogMessage) *protocol.Log {
 	nowTime := time.Now()
@@ -302,29 +416,26 @@ func (p *DockerStdoutProcessor) newRawLogBySingleLine(msg *LogMessage) *protocol
 		Key:   "_source_",
 		Value: msg.StreamType,
 	})
-	for i := range p.tags {
-		copy := p.tags[i]
-		log.Contents = append(log.Contents, &copy)
-	}
+	p.appendTags(log, msg.StreamType)
 	return log
 }
 
 // newRawLogByMultiLine convert last logs to protocol.Log.
-func (p *DockerStdoutProcessor) newRawLogByMultiLine() *protocol.Log {
-	lastOne := p.lastLogs[len(p.lastLogs)-1]
+func (p *DockerStdoutProcessor) newRawLogByMultiLine(s *streamState) *protocol.Log {
+	lastOne := s.lastLogs[len(s.lastLogs)-1]
 	if len(lastOne.Content) > 0 && lastOne.Content[len(lastOne.Content)-1] == '\n' {
 		lastOne.Content = lastOne.Content[:len(lastOne.Content)-1]
 	}
 	var multiLine strings.Builder
 	var sum int
-	for _, log := range p.lastLogs {
+	for _, log := range s.lastLogs {
 		sum += len(log.Content)
 	}
 	multiLine.Grow(sum)
-	for index, log := range p.lastLogs {
+	for index, log := range s.lastLogs {
 		multiLine.Write(log.Content)
 		// @note force set lastLog's content nil to let GC recycle this logs
-		p.lastLogs[index] = nil
+		s.lastLogs[index] = nil
 	}
 
 	nowTime := time.Now()
@@ -344,12 +455,27 @@ func (p *DockerStdoutProcessor) newRawLogByMultiLine() *protocol.Log {
 		Key:   "_source_",
 		Value: lastOne.StreamType,
 	})
+	if s.truncated {
+		log.Contents = append(log.Contents, &protocol.Log_Content{
+			Key:   truncatedKey,
+			Value: "true",
+		})
+		s.truncated = false
+	}
+	p.appendTags(log, lastOne.StreamType)
+	// reset multiline cache
+	s.lastLogs = s.lastLogs[:0]
+	s.lastLogsCount = 0
+	return log
+}
+
+func (p *DockerStdoutProcessor) appendTags(log *protocol.Log, streamType string) {
 	for i := range p.tags {
 		copy := p.tags[i]
 		log.Contents = append(log.Contents, &copy)
 	}
-	// reset multiline cache
-	p.lastLogs = p.lastLogs[:0]
-	p.lastLogsCount = 0
-	return log
+	for _, tag := range p.streamTags[streamType] {
+		copy := tag
+		log.Contents = append(log.Contents, &copy)
+	}
 }
@@ -212,11 +212,11 @@ func (s *inputProcessorTestSuite) TestSplitedLine(c *check.C) {
 	str3 := util.ZeroCopyBytesToString(splited3og1Bytes)
 
 	n1 := processor.Process(splitedlog1Bytes, time.Duration(0))
-	c.Assert(util.IsSafeString(str1, util.ZeroCopyBytesToString(processor.lastLogs[0].Content)), check.IsTrue)
+	c.Assert(util.IsSafeString(str1, util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[0].Content)), check.IsTrue)
 	c.Assert(n1, check.Equals, len(splitedlog1))
 
 	n2 := processor.Process(splited2og1Bytes, time.Duration(0))
-	c.Assert(util.IsSafeString(str2, util.ZeroCopyBytesToString(processor.lastLogs[1].Content)), check.IsTrue)
+	c.Assert(util.IsSafeString(str2, util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[1].Content)), check.IsTrue)
 	c.Assert(n2, check.Equals, len(splitedlog2))
 
 	n3 := processor.Process(splited3og1Bytes, time.Duration(0))
@@ -387,7 +387,7 @@ func (s *inputProcessorTestSuite) TestMultiLineMaxLength(c *check.C) {
 		c.Assert(n, check.Equals, len(realLine))
 		if i != 512 {
 			c.Assert(len(s.collector.Logs), check.Equals, 0)
-			c.Assert(util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[i].Content), str), check.IsTrue)
+			c.Assert(util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[i].Content), str), check.IsTrue)
 		}
 	}
 
@@ -462,7 +462,7 @@ func TestParseCRILine(t *testing.T) {
 		single = "2021-07-13T16:32:21.212861448Z stdout P partial line:\n"
 		copy(bytes, single)
 		processor.Process(bytes[:len(single)], duration)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[0].Content), str))
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[0].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout F full line of partial line\n"
 		copy(bytes, single)
@@ -487,13 +487,13 @@ func TestParseCRILine(t *testing.T) {
 		copy(bytes, single)
 
 		processor.Process(bytes[:len(single)], duration)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[0].Content), str))
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[0].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout F   full line line end\n"
 		copy(bytes, single)
 		processor.Process(bytes[:len(single)], duration)
 		require.Equal(t, len(collector.Logs), 0)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[1].Content), str))
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[1].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout P 2021-07-13 partial line line 1 partial\n"
 		copy(bytes, single)
@@ -501,26 +501,26 @@ func TestParseCRILine(t *testing.T) {
 		require.Equal(t, len(collector.Logs), 1)
 		assertKeyValue(collector.Logs[0], "content", "2021-07-13 full line line 1\n  full line line end")
 		assert.True(t, util.IsSafeString(collector.Logs[0].Contents[0].GetValue(), str))
-		assert.Equal(t, len(processor.lastLogs), 1)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[0].Content), str))
+		assert.Equal(t, len(processor.defaultStream.lastLogs), 1)
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[0].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout F   partial line line 1 full\n"
 		copy(bytes, single)
 		processor.Process(bytes[:len(single)], duration)
-		assert.Equal(t, len(processor.lastLogs), 2)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[1].Content), str))
+		assert.Equal(t, len(processor.defaultStream.lastLogs), 2)
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[1].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout P   partial line line 2 partial\n"
 		copy(bytes, single)
 		processor.Process(bytes[:len(single)], duration)
-		assert.Equal(t, len(processor.lastLogs), 3)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[2].Content), str))
+		assert.Equal(t, len(processor.defaultStream.lastLogs), 3)
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[2].Content), str))
 
 		single = "2021-07-13T16:32:21.212861448Z stdout F   partial line line 2 full\n"
 		copy(bytes, single)
 		processor.Process(bytes[:len(single)], duration)
-		assert.Equal(t, len(processor.lastLogs), 4)
-		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.lastLogs[3].Content), str))
+		assert.Equal(t, len(processor.defaultStream.lastLogs), 4)
+		assert.True(t, util.IsSafeString(util.ZeroCopyBytesToString(processor.defaultStream.lastLogs[3].Content), str))
 
 		require.Equal(t, len(collector.Logs), 1)
 
@@ -569,7 +569,7 @@ func TestSingleLineChangeBlock(t *testing.T) {
 		part1 := []byte("2021-07-13T16:32:21.212861448Z stdout P partial line:\n")
 		str := util.ZeroCopyBytesToString(part1)
 		assert.Equal(t, processor.Process(part1, 0), len(part1))
-		assert.Equal(t, string(processor.lastLogs[0].Content), "partial line:")
+		assert.Equal(t, string(processor.defaultStream.lastLogs[0].Content), "partial line:")
 		assert.Equal(t, len(collector.Logs), 0)
 		part1[38] -= 10
 		assert.Equal(t, processor.Process(part1, 0), len(part1))
@@ -623,3 +623,127 @@ func TestSingleLineChangeBlock(t *testing.T) {
 	}
 
 }
+
+func TestSeparateStreams(t *testing.T) {
+	var context helper.LocalContext
+	var collector helper.LocalCollector
+	processor := NewDockerStdoutProcessor(nil, time.Second, 10*1024, 512*1024, true, true, &context, &collector, nil, "source")
+	processor.SeparateStream("stdout", nil, time.Second, 10*1024)
+	processor.SeparateStream("stderr", regexp.MustCompile(`^panic:.*`), time.Second, 10*1024)
+	processor.SetStreamTags("stderr", map[string]string{"stream": "err"})
+
+	lines := "2021-07-13T16:32:21.212861448Z stderr F panic: runtime error\n" +
+		"2021-07-13T16:32:21.212861448Z stdout F {\"level\":\"info\",\"msg\":\"a\"}\n" +
+		"2021-07-13T16:32:21.212861448Z stderr F goroutine 1 [running]:\n" +
+		"2021-07-13T16:32:21.212861448Z stdout F {\"level\":\"info\",\"msg\":\"b\"}\n" +
+		"2021-07-13T16:32:21.212861448Z stderr F main.main()\n"
+	block := []byte(lines)
+	assert.Equal(t, len(block), processor.Process(block, 0))
+	require.Len(t, collector.Logs, 2)
+	assert.Equal(t, `{"level":"info","msg":"a"}`, collector.Logs[0].Contents[0].GetValue())
+	assert.Equal(t, `{"level":"info","msg":"b"}`, collector.Logs[1].Contents[0].GetValue())
+	assert.Len(t, collector.Logs[0].Contents, 3)
+
+	// the stderr panic is flushed after timeout
+	assert.Equal(t, 0, processor.Process(nil, 2*time.Second))
+	require.Len(t, collector.Logs, 3)
+	panicLog := collector.Logs[2]
+	assert.Equal(t, "panic: runtime error\ngoroutine 1 [running]:\nmain.main()", panicLog.Contents[0].GetValue())
+	assert.Equal(t, "stderr", panicLog.Contents[2].GetValue())
+	assert.Equal(t, "stream", panicLog.Contents[3].GetKey())
+	assert.Equal(t, "err", panicLog.Contents[3].GetValue())
+}
+
+func TestPartialLines(t *testing.T) {
+	var context helper.LocalContext
+	var collector helper.LocalCollector
+	getValue := func(log *protocol.Log, key string) (string, bool) {
+		for _, c := range log.Contents {
+			if c.GetKey() == key {
+				return c.GetValue(), true
+			}
+		}
+		return "", false
+	}
+
+	// the continued parts of a split line are never checked by the begin line regex
+	{
+		processor := NewDockerStdoutProcessor(regexp.MustCompile(`^\{`), time.Second, 10*1024, 512*1024, true, true, &context, &collector, nil, "source")
+		lines := "2021-07-13T16:32:21.212861448Z stdout P {\"level\":\"info\",\"msg\":\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F {\"a\":1}}\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F {\"level\":\"warn\"}\n"
+		block := []byte(lines)
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		require.Len(t, collector.Logs, 1)
+		assert.Equal(t, `{"level":"info","msg":{"a":1}}`, collector.Logs[0].Contents[0].GetValue())
+		collector.Logs = nil
+	}
+
+	// docker json logs split into 16K parts
+	{
+		processor := NewDockerStdoutProcessor(nil, time.Second, 10*1024, 512*1024, true, true, &context, &collector, nil, "source")
+		lines := "{\"log\":\"{\\\"msg\\\":\",\"stream\":\"stdout\",\"time\":\"2018-05-16T06:28:41.2195434Z\"}\n" +
+			"{\"log\":\"\\\"hello\\\"}\\n\",\"stream\":\"stdout\",\"time\":\"2018-05-16T06:28:41.2195434Z\"}\n"
+		block := []byte(lines)
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		require.Len(t, collector.Logs, 1)
+		assert.Equal(t, `{"msg":"hello"}`, collector.Logs[0].Contents[0].GetValue())
+		_, ok := getValue(collector.Logs[0], truncatedKey)
+		assert.False(t, ok)
+		collector.Logs = nil
+	}
+
+	// the merged line exceeding the max line size is truncated and tagged
+	{
+		processor := NewDockerStdoutProcessor(nil, time.Second, 10*1024, 1024, true, true, &context, &collector, nil, "source")
+		processor.SetMaxLineSize(8)
+		lines := "2021-07-13T16:32:21.212861448Z stdout P 12345\n" +
+			"2021-07-13T16:32:21.212861448Z stdout P 67890\n" +
+			"2021-07-13T16:32:21.212861448Z stdout P abcde\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F fghij\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F next line\n"
+		block := []byte(lines)
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		require.Len(t, collector.Logs, 2)
+		assert.Equal(t, "12345678", collector.Logs[0].Contents[0].GetValue())
+		value, ok := getValue(collector.Logs[0], truncatedKey)
+		assert.True(t, ok)
+		assert.Equal(t, "true", value)
+		assert.Equal(t, "next line", collector.Logs[1].Contents[0].GetValue())
+		_, ok = getValue(collector.Logs[1], truncatedKey)
+		assert.False(t, ok)
+		collector.Logs = nil
+	}
+
+	// the truncated line keeps its line end within user multi lines
+	{
+		processor := NewDockerStdoutProcessor(regexp.MustCompile(`^start`), time.Second, 10*1024, 1024, true, true, &context, &collector, nil, "source")
+		processor.SetMaxLineSize(8)
+		lines := "2021-07-13T16:32:21.212861448Z stdout F start\n" +
+			"2021-07-13T16:32:21.212861448Z stdout P 12345\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F 67890\n" +
+			"2021-07-13T16:32:21.212861448Z stdout F end\n"
+		block := []byte(lines)
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		assert.Equal(t, 0, processor.Process(nil, 2*time.Second))
+		require.Len(t, collector.Logs, 1)
+		assert.Equal(t, "start\n12345678\nend", collector.Logs[0].Contents[0].GetValue())
+		_, ok := getValue(collector.Logs[0], truncatedKey)
+		assert.True(t, ok)
+		collector.Logs = nil
+	}
+
+	// the merging line is not flushed by the max log size
+	{
+		processor := NewDockerStdoutProcessor(nil, time.Second, 10*1024, 10, true, true, &context, &collector, nil, "source")
+		processor.SetMaxLineSize(1024)
+		block := []byte("2021-07-13T16:32:21.212861448Z stdout P {\"msg\":\"0123456789\n")
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		assert.Len(t, collector.Logs, 0)
+		block = []byte("2021-07-13T16:32:21.212861448Z stdout F 0123456789\"}\n")
+		assert.Equal(t, len(block), processor.Process(block, 0))
+		require.Len(t, collector.Logs, 1)
+		assert.Equal(t, `{"msg":"01234567890123456789"}`, collector.Logs[0].Contents[0].GetValue())
+		collector.Logs = nil
+	}
+}
@@ -17,6 +17,7 @@ package stdout
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -44,14 +45,26 @@ func logDriverSupported(container types.ContainerJSON) bool {
 	}
 }
 
+const (
+	duplicateFilePolicyWarn = "warn"
+	duplicateFilePolicySkip = "skip"
+)
+
 func logPathEmpty(container types.ContainerJSON) bool {
 	return len(container.LogPath) == 0
 }
 
+// skippedFile is the log file of a container left to another config by the skip policy of duplicate files.
+type skippedFile struct {
+	info     *helper.DockerInfoDetail
+	filePath string
+}
+
 type DockerFileSyner struct {
 	dockerFileReader    *helper.LogFileReader
 	dockerFileProcessor *DockerStdoutProcessor
 	info                *helper.DockerInfoDetail
+	filePath            string
 }
 
 func NewDockerFileSynerByFile(sds *ServiceDockerStdout, filePath string) *DockerFileSyner {
@@ -80,6 +93,19 @@ func NewDockerFileSyner(sds *ServiceDockerStdout,
 		tags[k] = v
 	}
 	processor := NewDockerStdoutProcessor(reg, time.Duration(sds.BeginLineTimeoutMs)*time.Millisecond, sds.BeginLineCheckLength, sds.MaxLogSize, sds.Stdout, sds.Stderr, sds.context, sds.collector, tags, source)
+	if sds.SeparateStreams {
+		var stderrReg *regexp.Regexp
+		if len(sds.StderrBeginLineRegex) > 0 {
+			if stderrReg, err = regexp.Compile(sds.StderrBeginLineRegex); err != nil {
+				logger.Warning(sds.context.GetRuntimeContext(), "DOCKER_REGEX_COMPILE_ALARM", "compile stderr begin line regex error, regex", sds.StderrBeginLineRegex, "error", err)
+			}
+		}
+		processor.SeparateStream("stdout", reg, time.Duration(sds.BeginLineTimeoutMs)*time.Millisecond, sds.BeginLineCheckLength)
+		processor.SeparateStream("stderr", stderrReg, time.Duration(sds.StderrBeginLineTimeoutMs)*time.Millisecond, sds.StderrBeginLineCheckLength)
+	}
+	processor.SetMaxLineSize(sds.MaxMergedLineSize)
+	processor.SetStreamTags("stdout", sds.StdoutTags)
+	processor.SetStreamTags("stderr", sds.StderrTags)
 
 	checkpoint, ok := checkpointMap[info.ContainerInfo.ID]
 	if !ok {
@@ -126,6 +152,7 @@ func NewDockerFileSyner(sds *ServiceDockerStdout,
 		dockerFileReader:    reader,
 		info:                info,
 		dockerFileProcessor: processor,
+		filePath:            checkpoint.Path,
 	}
 }
 
@@ -147,6 +174,7 @@ type ServiceDockerStdout struct {
 	BeginLineTimeoutMs    int               `comment:"the maximum timeout milliseconds for begin line match. Default value is 3000."`
 	BeginLineCheckLength  int               `comment:"the prefix length of log line to match the first line. Default value is 10240."`
 	MaxLogSize            int               `comment:"the maximum log size. Default value is 512*1024, a.k.a 512K."`
+	MaxMergedLineSize     int               `comment:"the maximum size of a line merged from the partial lines split by the container runtime, the exceeded part is discarded and the log is tagged with _truncated_. Default value is MaxLogSize."`
 	CloseUnChangedSec     int               `comment:"the reading file would be close when the interval between last read operation is over {CloseUnChangedSec} seconds. Default value is 60."`
 	StartLogMaxOffset     int64             `comment:"the first read operation would read {StartLogMaxOffset} size history logs. Default value is 128*1024, a.k.a 128K."`
 	Stdout                bool              `comment:"collect stdout log. Default is true."`
@@ -155,6 +183,19 @@ type ServiceDockerStdout struct {
 	K8sNamespaceRegex     string            `comment:"the regular expression of kubernetes namespace to match containers."`
 	K8sPodRegex           string            `comment:"the regular expression of kubernetes pod to match containers."`
 	K8sContainerRegex     string            `comment:"the regular expression of kubernetes container to match containers."`
+	DuplicateFilePolicy   string            `comment:"the policy when the log file of a container is also collected by other configs, 'warn' collects it and raises DUPLICATE_FILE_ALARM, 'skip' leaves it to the config collecting it first and takes it over once released. Default value is warn."`
+
+	// separate the streams with their own multi line settings and tags
+	SeparateStreams            bool              `comment:"buffer the multi line logs of stdout and stderr separately, so the interleaved lines of the two streams are never merged together. Default is false."`
+	StderrBeginLineRegex       string            `comment:"the regular expression of begin line for the multi line stderr log when SeparateStreams is true, and the stderr logs are single line if empty. BeginLineRegex is used for stdout."`
+	StderrBeginLineTimeoutMs   int               `comment:"the maximum timeout milliseconds for stderr begin line match when SeparateStreams is true. Default value is BeginLineTimeoutMs."`
+	StderrBeginLineCheckLength int               `comment:"the prefix length of stderr log line to match the first line when SeparateStreams is true. Default value is BeginLineCheckLength."`
+	StdoutTags                 map[string]string `comment:"the extra fields appended to the stdout logs, such as a stream label to route the logs."`
+	StderrTags                 map[string]string `comment:"the extra fields appended to the stderr logs, such as a stream label to route the logs."`
+
+	// complete the collection of the batch jobs
+	CompletionTrigger      string `comment:"emit a log with _collection_complete_ once the log file of a container is read to the end after the trigger, 'exit' triggers when the container exits, 'sentinel' triggers when CompletionSentinelFile exists. Default value is empty, which never completes."`
+	CompletionSentinelFile string `comment:"the path of the sentinel file for the sentinel trigger, {container_id} in it is replaced by the id of the container."`
 
 	// export from ilogtail-trace component
 	IncludeLabelRegex map[string]*regexp.Regexp
@@ -168,8 +209,11 @@ type ServiceDockerStdout struct {
 	avgInstanceMetric pipeline.CounterMetric
 	addMetric         pipeline.CounterMetric
 	deleteMetric      pipeline.CounterMetric
+	completeMetric    pipeline.CounterMetric
 
 	synerMap      map[string]*DockerFileSyner
+	skippedMap    map[string]*skippedFile
+	completedMap  map[string]struct{}
 	checkpointMap map[string]helper.LogFileReaderCheckPoint
 	shutdown      chan struct {
 	}
@@ -190,6 +234,7 @@ func (sds *ServiceDockerStdout) Init(context pipeline.Context) (int, error) {
 	sds.fullList = make(map[string]bool)
 	sds.matchList = make(map[string]*helper.DockerInfoDetail)
 	sds.synerMap = make(map[string]*DockerFileSyner)
+	sds.skippedMap = make(map[string]*skippedFile)
 
 	if sds.MaxLogSize < 1024 {
 		sds.MaxLogSize = 1024
@@ -197,6 +242,28 @@ func (sds *ServiceDockerStdout) Init(context pipeline.Context) (int, error) {
 	if sds.MaxLogSize > 1024*1024*20 {
 		sds.MaxLogSize = 1024 * 1024 * 20
 	}
+	if sds.MaxMergedLineSize <= 0 {
+		sds.MaxMergedLineSize = sds.MaxLogSize
+	}
+	if sds.MaxMergedLineSize > 1024*1024*20 {
+		sds.MaxMergedLineSize = 1024 * 1024 * 20
+	}
+	switch sds.DuplicateFilePolicy {
+	case duplicateFilePolicyWarn, duplicateFilePolicySkip:
+	case "":
+		sds.DuplicateFilePolicy = duplicateFilePolicyWarn
+	default:
+		return 0, fmt.Errorf("invalid DuplicateFilePolicy %s, should be %s or %s", sds.DuplicateFilePolicy, duplicateFilePolicyWarn, duplicateFilePolicySkip)
+	}
+	if err := sds.initCompletion(); err != nil {
+		return 0, err
+	}
+	if sds.StderrBeginLineTimeoutMs <= 0 {
+		sds.StderrBeginLineTimeoutMs = sds.BeginLineTimeoutMs
+	}
+	if sds.StderrBeginLineCheckLength <= 0 {
+		sds.StderrBeginLineCheckLength = sds.BeginLineCheckLength
+	}
 
 	metricsRecord := sds.context.GetMetricRecord()
 	sds.tracker = helper.NewReaderMetricTracker(metricsRecord)
@@ -204,6 +271,7 @@ func (sds *ServiceDockerStdout) Init(context pipeline.Context) (int, error) {
 	sds.avgInstanceMetric = helper.NewAverageMetricAndRegister(metricsRecord, helper.MetricPluginContainerTotal)
 	sds.addMetric = helper.NewCounterMetricAndRegister(metricsRecord, helper.MetricPluginAddContainerTotal)
 	sds.deleteMetric = helper.NewCounterMetricAndRegister(metricsRecord, helper.MetricPluginRemoveContainerTotal)
+	sds.completeMetric = helper.NewCounterMetricAndRegister(metricsRecord, "container_collection_complete")
 
 	var err error
 	sds.IncludeEnv, sds.IncludeEnvRegex, err = helper.SplitRegexFromMap(sds.IncludeEnv)
@@ -313,29 +381,93 @@ func (sds *ServiceDockerStdout) FlushAll(c pipeline.Collector, firstStart bool)
 		if logPathEmpty(info.ContainerInfo) {
 			continue
 		}
+		if _, completed := sds.completedMap[id]; completed {
+			continue
+		}
 		if _, ok := sds.synerMap[id]; !ok || firstStart {
-			syner := NewDockerFileSyner(sds, info, sds.checkpointMap)
-			logger.Info(sds.context.GetRuntimeContext(), "docker stdout", "added", "source host path", info.ContainerInfo.LogPath,
-				"id", info.IDPrefix(), "name", info.ContainerInfo.Name, "created", info.ContainerInfo.Created, "status", info.Status())
-			sds.addMetric.Add(1)
-			sds.synerMap[id] = syner
-			syner.dockerFileReader.Start()
+			sds.startSyner(id, info)
 		}
 	}
 
 	// delete container
 	for id, syner := range sds.synerMap {
 		if _, ok := dockerInfos[id]; !ok {
+			// the removed container is completed once its log file is read to the end
+			if sds.CompletionTrigger == completionTriggerExit {
+				continue
+			}
 			logger.Info(sds.context.GetRuntimeContext(), "docker stdout", "deleted", "id", helper.GetShortID(id), "name", syner.info.ContainerInfo.Name)
-			syner.dockerFileReader.Stop()
+			sds.stopSyner(syner)
 			delete(sds.synerMap, id)
 			sds.deleteMetric.Add(1)
 		}
 	}
+	for id := range sds.skippedMap {
+		if _, ok := dockerInfos[id]; !ok {
+			delete(sds.skippedMap, id)
+		}
+	}
+	for id := range sds.completedMap {
+		if _, ok := dockerInfos[id]; !ok {
+			delete(sds.completedMap, id)
+		}
+	}
 
 	return err
 }
 
+// startSyner starts reading the log file of the container unless the file is left to another config by the skip
+// policy of duplicate files.
+func (sds *ServiceDockerStdout) startSyner(id string, info *helper.DockerInfoDetail) {
+	syner := NewDockerFileSyner(sds, info, sds.checkpointMap)
+	others, ok := helper.GetFileRegistry().Register(syner.filePath, sds.context.GetConfigName(), sds.DuplicateFilePolicy == duplicateFilePolicySkip)
+	if !ok {
+		if _, skipped := sds.skippedMap[id]; !skipped {
+			logger.Warning(sds.context.GetRuntimeContext(), "DUPLICATE_FILE_ALARM", "skip the file collected by other configs, file", syner.filePath,
+				"configs", duplicateFileConfigs(others), "id", info.IDPrefix(), "name", info.ContainerInfo.Name)
+		}
+		sds.skippedMap[id] = &skippedFile{info: info, filePath: syner.filePath}
+		return
+	}
+	if len(others) > 0 {
+		logger.Warning(sds.context.GetRuntimeContext(), "DUPLICATE_FILE_ALARM", "the file is also collected by other configs, file", syner.filePath,
+			"configs", duplicateFileConfigs(others), "id", info.IDPrefix(), "name", info.ContainerInfo.Name)
+	}
+	delete(sds.skippedMap, id)
+	logger.Info(sds.context.GetRuntimeContext(), "docker stdout", "added", "source host path", info.ContainerInfo.LogPath,
+		"id", info.IDPrefix(), "name", info.ContainerInfo.Name, "created", info.ContainerInfo.Created, "status", info.Status())
+	sds.addMetric.Add(1)
+	sds.synerMap[id] = syner
+	syner.dockerFileReader.Start()
+}
+
+func (sds *ServiceDockerStdout) stopSyner(syner *DockerFileSyner) {
+	syner.dockerFileReader.Stop()
+	helper.GetFileRegistry().Unregister(syner.filePath, sds.context.GetConfigName())
+}
+
+// retrySkipped takes over the skipped files once the other configs release them.
+func (sds *ServiceDockerStdout) retrySkipped() {
+	for id, skipped := range sds.skippedMap {
+		if _, ok := sds.synerMap[id]; ok {
+			delete(sds.skippedMap, id)
+			continue
+		}
+		if len(helper.GetFileRegistry().Readers(skipped.filePath)) > 0 {
+			continue
+		}
+		sds.startSyner(id, skipped.info)
+	}
+}
+
+func duplicateFileConfigs(registrations []helper.FileRegistration) string {
+	names := make([]string, 0, len(registrations))
+	for _, registration := range registrations {
+		names = append(names, registration.ConfigName)
+	}
+	return strings.Join(names, ",")
+}
+
 func (sds *ServiceDockerStdout) SaveCheckPoint(force bool) error {
 	checkpointChanged := false
 	for id, syner := range sds.synerMap {
@@ -366,6 +498,9 @@ func (sds *ServiceDockerStdout) ClearUselessCheckpoint() {
 		return
 	}
 	for id := range sds.checkpointMap {
+		if _, completed := sds.completedMap[id]; completed {
+			continue
+		}
 		if _, ok := sds.synerMap[id]; !ok {
 			logger.Info(sds.context.GetRuntimeContext(), "delete checkpoint, id", id)
 			delete(sds.checkpointMap, id)
@@ -391,7 +526,7 @@ func (sds *ServiceDockerStdout) Start(c pipeline.Collector) error {
 		case <-sds.shutdown:
 			logger.Info(sds.context.GetRuntimeContext(), "docker stdout main runtime stop", "begin")
 			for _, syner := range sds.synerMap {
-				syner.dockerFileReader.Stop()
+				sds.stopSyner(syner)
 			}
 			logger.Info(sds.context.GetRuntimeContext(), "docker stdout main runtime stop", "success")
 			return nil
@@ -402,6 +537,13 @@ func (sds *ServiceDockerStdout) Start(c pipeline.Collector) error {
 				sds.ClearUselessCheckpoint()
 			}
 			_ = sds.FlushAll(c, false)
+			sds.retrySkipped()
+			switch sds.CompletionTrigger {
+			case completionTriggerExit:
+				sds.checkCompletion(containerStatuses())
+			case completionTriggerSentinel:
+				sds.checkCompletion(nil)
+			}
 		}
 	}
 }
@@ -15,9 +15,18 @@
 package stdout
 
 import (
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/input"
 	"github.com/alibaba/ilogtail/plugins/test/mock"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"regexp"
 	"testing"
@@ -120,3 +129,57 @@ func TestServiceDockerStdout_Init(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestDuplicateFilePolicy(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "c1-json.log")
+	require.NoError(t, os.WriteFile(logPath, nil, 0600))
+	realPath, _ := helper.TryGetRealPath(logPath)
+	info := &helper.DockerInfoDetail{
+		ContainerInfo: types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:      "c1",
+				Name:    "c1",
+				LogPath: logPath,
+			},
+		},
+	}
+	newInput := func(configName, policy string) *ServiceDockerStdout {
+		sds := pipeline.ServiceInputs[input.ServiceDockerStdoutPluginName]().(*ServiceDockerStdout)
+		sds.LogtailInDocker = false
+		sds.DuplicateFilePolicy = policy
+		_, err := sds.Init(mock.NewEmptyContext("project", "store", configName))
+		require.NoError(t, err)
+		sds.checkpointMap = make(map[string]helper.LogFileReaderCheckPoint)
+		sds.collector = &helper.LocalCollector{}
+		return sds
+	}
+	first := newInput("duplicate_config_a", "")
+	skip := newInput("duplicate_config_b", duplicateFilePolicySkip)
+	warn := newInput("duplicate_config_c", duplicateFilePolicyWarn)
+	assert.Equal(t, duplicateFilePolicyWarn, first.DuplicateFilePolicy)
+
+	first.startSyner("c1", info)
+	require.Contains(t, first.synerMap, "c1")
+	skip.startSyner("c1", info)
+	assert.NotContains(t, skip.synerMap, "c1")
+	assert.Contains(t, skip.skippedMap, "c1")
+	warn.startSyner("c1", info)
+	require.Contains(t, warn.synerMap, "c1")
+	assert.Len(t, helper.GetFileRegistry().Readers(realPath), 2)
+
+	// the skipped file is taken over once all the other configs release it
+	first.stopSyner(first.synerMap["c1"])
+	skip.retrySkipped()
+	assert.NotContains(t, skip.synerMap, "c1")
+	warn.stopSyner(warn.synerMap["c1"])
+	skip.retrySkipped()
+	require.Contains(t, skip.synerMap, "c1")
+	assert.Empty(t, skip.skippedMap)
+	skip.stopSyner(skip.synerMap["c1"])
+	assert.Empty(t, helper.GetFileRegistry().Readers(realPath))
+
+	sds := pipeline.ServiceInputs[input.ServiceDockerStdoutPluginName]().(*ServiceDockerStdout)
+	sds.DuplicateFilePolicy = "fanout"
+	_, err := sds.Init(mock.NewEmptyContext("project", "store", "config"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	statsPath        = "/stats/prometheus"
+	envoyLabelPrefix = "envoy_"
+)
+
+// labelRenames normalizes the envoy labels to the labels shared with the other proxy inputs.
+var labelRenames = map[string]string{
+	"envoy_cluster_name":             "cluster",
+	"envoy_listener_address":         "listener",
+	"envoy_http_conn_manager_prefix": "http_conn_manager",
+}
+
+// InputEnvoy polls the /stats/prometheus admin endpoint of envoy and normalizes the stats
+// to metrics with cluster/listener labels.
+type InputEnvoy struct {
+	// Endpoints are the admin addresses of envoy, e.g. "http://127.0.0.1:9901"
+	Endpoints          []string
+	UsedOnly           bool   // only export the stats that have been updated
+	Filter             string // regex to filter the stats by name
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	ResponseTimeoutMs  int
+	Labels             map[string]string
+
+	client  *http.Client
+	context pipeline.Context
+}
+
+func (e *InputEnvoy) Init(context pipeline.Context) (int, error) {
+	e.context = context
+	if len(e.Endpoints) == 0 {
+		return 0, fmt.Errorf("no envoy admin endpoint configured")
+	}
+	if e.ResponseTimeoutMs <= 0 {
+		e.ResponseTimeoutMs = 5000
+	}
+	tlsCfg, err := util.GetTLSConfig(e.SSLCert, e.SSLKey, e.SSLCA, e.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	e.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(e.ResponseTimeoutMs) * time.Millisecond,
+	}
+	return 0, nil
+}
+
+func (e *InputEnvoy) Description() string {
+	return "collect the stats of envoy from the admin endpoint"
+}
+
+func (e *InputEnvoy) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, endpoint := range e.Endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			if err := e.gatherEndpoint(endpoint, collector); err != nil {
+				logger.Error(e.context.GetRuntimeContext(), "ENVOY_COLLECT_ALARM", "endpoint", endpoint, "error", err)
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (e *InputEnvoy) statsURL(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, statsPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + statsPath
+	}
+	query := u.Query()
+	if e.UsedOnly {
+		query.Set("usedonly", "")
+	}
+	if e.Filter != "" {
+		query.Set("filter", e.Filter)
+	}
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+func (e *InputEnvoy) gatherEndpoint(endpoint string, collector pipeline.Collector) error {
+	u, err := e.statsURL(endpoint)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u.String(), resp.Status)
+	}
+	return e.parseStats(resp.Body, u.Host, time.Now(), collector)
+}
+
+func (e *InputEnvoy) parseStats(r io.Reader, instance string, now time.Time, collector pipeline.Collector) error {
+	return helper.DecodePrometheusText(r, now, func(sample *model.Sample) {
+		var name string
+		labels := &helper.MetricLabels{}
+		labels.Append("instance", instance)
+		for k, v := range sample.Metric {
+			if k == model.MetricNameLabel {
+				name = string(v)
+				continue
+			}
+			labels.Append(normalizeLabel(string(k)), string(v))
+		}
+		for k, v := range e.Labels {
+			labels.Append(k, v)
+		}
+		collector.AddRawLog(helper.NewMetricLog(name, sample.Timestamp.UnixNano(), float64(sample.Value), labels))
+	})
+}
+
+func normalizeLabel(key string) string {
+	if renamed, ok := labelRenames[key]; ok {
+		return renamed
+	}
+	return strings.TrimPrefix(key, envoyLabelPrefix)
+}
+
+func init() {
+	pipeline.MetricInputs["metric_envoy"] = func() pipeline.MetricInput {
+		return &InputEnvoy{
+			ResponseTimeoutMs: 5000,
+		}
+	}
+}
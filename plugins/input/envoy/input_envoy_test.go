@@ -0,0 +1,81 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const stats = `# TYPE envoy_cluster_upstream_rq_total counter
+envoy_cluster_upstream_rq_total{envoy_cluster_name="backend"} 42
+# TYPE envoy_listener_downstream_cx_active gauge
+envoy_listener_downstream_cx_active{envoy_listener_address="0.0.0.0_10000"} 3
+# TYPE envoy_cluster_upstream_rq_time histogram
+envoy_cluster_upstream_rq_time_bucket{envoy_cluster_name="backend",le="10"} 5
+envoy_cluster_upstream_rq_time_bucket{envoy_cluster_name="backend",le="+Inf"} 6
+envoy_cluster_upstream_rq_time_sum{envoy_cluster_name="backend"} 40
+envoy_cluster_upstream_rq_time_count{envoy_cluster_name="backend"} 6
+`
+
+func TestEnvoyCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, statsPath, r.URL.Path)
+		assert.Equal(t, "cluster", r.URL.Query().Get("filter"))
+		_, _ = w.Write([]byte(stats))
+	}))
+	defer server.Close()
+
+	e := &InputEnvoy{Endpoints: []string{server.URL}, Filter: "cluster"}
+	_, err := e.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, e.Collect(collector))
+
+	instance := server.Listener.Addr().String()
+	metrics := make(map[string]string)
+	for _, log := range collector.Logs {
+		var name, labels, value string
+		for _, c := range log.Contents {
+			switch c.Key {
+			case "__name__":
+				name = c.Value
+			case "__labels__":
+				labels = c.Value
+			case "__value__":
+				value = c.Value
+			}
+		}
+		metrics[name+"{"+labels+"}"] = value
+	}
+	assert.Len(t, metrics, 6)
+	assert.Equal(t, "42", metrics["envoy_cluster_upstream_rq_total{cluster#$#backend|instance#$#"+instance+"}"])
+	assert.Equal(t, "3", metrics["envoy_listener_downstream_cx_active{instance#$#"+instance+"|listener#$#0.0.0.0_10000}"])
+	assert.Equal(t, "6", metrics["envoy_cluster_upstream_rq_time_bucket{cluster#$#backend|instance#$#"+instance+"|le#$#+Inf}"])
+}
+
+func TestEnvoyStatsURL(t *testing.T) {
+	e := &InputEnvoy{UsedOnly: true}
+	u, err := e.statsURL("http://127.0.0.1:9901/")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:9901/stats/prometheus?usedonly=", u.String())
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	healthPath  = "/health"
+	metricsPath = "/metrics"
+)
+
+// defaultMetrics are the metric families about the leader, proposals, disk latency and peer network,
+// which are enough to tell the health of the cluster.
+var defaultMetrics = []string{
+	"etcd_server_has_leader",
+	"etcd_server_is_leader",
+	"etcd_server_leader_changes_seen_total",
+	"etcd_server_proposals_committed_total",
+	"etcd_server_proposals_applied_total",
+	"etcd_server_proposals_pending",
+	"etcd_server_proposals_failed_total",
+	"etcd_server_slow_apply_total",
+	"etcd_server_slow_read_indexes_total",
+	"etcd_server_heartbeat_send_failures_total",
+	"etcd_server_quota_backend_bytes",
+	"etcd_mvcc_db_total_size_in_bytes",
+	"etcd_mvcc_db_total_size_in_use_in_bytes",
+	"etcd_disk_wal_fsync_duration_seconds",
+	"etcd_disk_backend_commit_duration_seconds",
+	"etcd_network_peer_round_trip_time_seconds",
+	"etcd_network_peer_sent_failures_total",
+	"etcd_network_peer_received_failures_total",
+}
+
+// InputEtcd polls the /health and /metrics endpoints of etcd, and exports the health and the selected metrics.
+type InputEtcd struct {
+	// Endpoints are the client or metrics addresses of etcd, e.g. "https://127.0.0.1:2379"
+	Endpoints          []string
+	Metrics            []string // the metric families to export, the default families are used if empty
+	ExportBuckets      bool     // export the buckets of the histograms besides the sum and count
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	ResponseTimeoutMs  int
+	Labels             map[string]string
+
+	metrics map[string]bool
+	client  *http.Client
+	context pipeline.Context
+}
+
+func (e *InputEtcd) Init(context pipeline.Context) (int, error) {
+	e.context = context
+	if len(e.Endpoints) == 0 {
+		return 0, fmt.Errorf("no etcd endpoint configured")
+	}
+	if e.ResponseTimeoutMs <= 0 {
+		e.ResponseTimeoutMs = 5000
+	}
+	if len(e.Metrics) == 0 {
+		e.Metrics = defaultMetrics
+	}
+	e.metrics = make(map[string]bool, len(e.Metrics))
+	for _, name := range e.Metrics {
+		e.metrics[name] = true
+	}
+	tlsCfg, err := util.GetTLSConfig(e.SSLCert, e.SSLKey, e.SSLCA, e.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	e.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(e.ResponseTimeoutMs) * time.Millisecond,
+	}
+	return 0, nil
+}
+
+func (e *InputEtcd) Description() string {
+	return "collect the health and metrics of etcd"
+}
+
+func (e *InputEtcd) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, endpoint := range e.Endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			if err := e.gatherEndpoint(endpoint, collector); err != nil {
+				logger.Warning(e.context.GetRuntimeContext(), "ETCD_COLLECT_ALARM", "endpoint", endpoint, "error", err)
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (e *InputEtcd) gatherEndpoint(endpoint string, collector pipeline.Collector) error {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return err
+	}
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", u.Host)
+	for k, v := range e.Labels {
+		labels.Append(k, v)
+	}
+	now := time.Now()
+
+	healthy, healthErr := e.health(u.String() + healthPath)
+	metricsErr := e.gatherMetrics(u.String()+metricsPath, labels, now, collector)
+	up := 0.
+	if healthErr == nil || metricsErr == nil {
+		up = 1
+	}
+	collector.AddRawLog(helper.NewMetricLog("etcd_up", now.UnixNano(), up, labels))
+	if healthErr != nil {
+		return healthErr
+	}
+	health := 0.
+	if healthy {
+		health = 1
+	}
+	collector.AddRawLog(helper.NewMetricLog("etcd_health", now.UnixNano(), health, labels))
+	return metricsErr
+}
+
+// health returns whether etcd reports healthy, etcd answers 503 with the health false when it is unhealthy.
+func (e *InputEtcd) health(u string) (bool, error) {
+	resp, err := e.client.Get(u)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return false, fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	var health struct {
+		Health string `json:"health"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&health); err != nil {
+		return false, err
+	}
+	return health.Health == "true", nil
+}
+
+func (e *InputEtcd) gatherMetrics(u string, labels *helper.MetricLabels, now time.Time, collector pipeline.Collector) error {
+	resp, err := e.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	return helper.DecodePrometheusText(resp.Body, now, func(sample *model.Sample) {
+		name := string(sample.Metric[model.MetricNameLabel])
+		if !e.selected(name) {
+			return
+		}
+		sampleLabels := labels.Clone()
+		helper.AppendSampleLabels(sampleLabels, sample.Metric)
+		collector.AddRawLog(helper.NewMetricLog(name, sample.Timestamp.UnixNano(), float64(sample.Value), sampleLabels))
+	})
+}
+
+// selected returns whether the sample belongs to a selected family, the buckets are only exported if configured.
+func (e *InputEtcd) selected(name string) bool {
+	if e.metrics[name] {
+		return true
+	}
+	if strings.HasSuffix(name, "_bucket") {
+		return e.ExportBuckets && e.metrics[strings.TrimSuffix(name, "_bucket")]
+	}
+	return e.metrics[strings.TrimSuffix(name, "_sum")] || e.metrics[strings.TrimSuffix(name, "_count")]
+}
+
+func init() {
+	pipeline.MetricInputs["metric_etcd"] = func() pipeline.MetricInput {
+		return &InputEtcd{
+			ResponseTimeoutMs: 5000,
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const etcdMetrics = `# HELP etcd_server_has_leader Whether or not a leader exists. 1 is existence, 0 is not.
+# TYPE etcd_server_has_leader gauge
+etcd_server_has_leader 1
+# HELP etcd_server_is_leader Whether or not this member is a leader. 1 if is, 0 otherwise.
+# TYPE etcd_server_is_leader gauge
+etcd_server_is_leader 0
+# HELP etcd_disk_wal_fsync_duration_seconds The latency distributions of fsync called by WAL.
+# TYPE etcd_disk_wal_fsync_duration_seconds histogram
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.001"} 2
+etcd_disk_wal_fsync_duration_seconds_bucket{le="+Inf"} 3
+etcd_disk_wal_fsync_duration_seconds_sum 0.012
+etcd_disk_wal_fsync_duration_seconds_count 3
+# HELP etcd_network_peer_round_trip_time_seconds Round-Trip-Time histogram between peers
+# TYPE etcd_network_peer_round_trip_time_seconds histogram
+etcd_network_peer_round_trip_time_seconds_bucket{To="8211f1d0f64f3269",le="+Inf"} 1
+etcd_network_peer_round_trip_time_seconds_sum{To="8211f1d0f64f3269"} 0.002
+etcd_network_peer_round_trip_time_seconds_count{To="8211f1d0f64f3269"} 1
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 42
+`
+
+func newEtcdServer(healthStatus int, health string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case healthPath:
+			w.WriteHeader(healthStatus)
+			_, _ = w.Write([]byte(`{"health":"` + health + `","reason":""}`))
+		case metricsPath:
+			_, _ = w.Write([]byte(etcdMetrics))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestEtcdCollect(t *testing.T) {
+	server := newEtcdServer(http.StatusOK, "true")
+	defer server.Close()
+
+	input := &InputEtcd{Endpoints: []string{server.URL}}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	labels := "instance#$#" + strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []string{
+		"etcd_disk_wal_fsync_duration_seconds_count{" + labels + "} 3",
+		"etcd_disk_wal_fsync_duration_seconds_sum{" + labels + "} 0.012",
+		"etcd_health{" + labels + "} 1",
+		"etcd_network_peer_round_trip_time_seconds_count{To#$#8211f1d0f64f3269|" + labels + "} 1",
+		"etcd_network_peer_round_trip_time_seconds_sum{To#$#8211f1d0f64f3269|" + labels + "} 0.002",
+		"etcd_server_has_leader{" + labels + "} 1",
+		"etcd_server_is_leader{" + labels + "} 0",
+		"etcd_up{" + labels + "} 1",
+	}, test.MetricLines(collector.Logs))
+}
+
+func TestEtcdUnhealthy(t *testing.T) {
+	server := newEtcdServer(http.StatusServiceUnavailable, "false")
+	defer server.Close()
+
+	input := &InputEtcd{Endpoints: []string{server.URL}, Metrics: []string{"etcd_disk_wal_fsync_duration_seconds"}, ExportBuckets: true}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	labels := "instance#$#" + strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []string{
+		"etcd_disk_wal_fsync_duration_seconds_bucket{" + labels + "|le#$#+Inf} 3",
+		"etcd_disk_wal_fsync_duration_seconds_bucket{" + labels + "|le#$#0.001} 2",
+		"etcd_disk_wal_fsync_duration_seconds_count{" + labels + "} 3",
+		"etcd_disk_wal_fsync_duration_seconds_sum{" + labels + "} 0.012",
+		"etcd_health{" + labels + "} 0",
+		"etcd_up{" + labels + "} 1",
+	}, test.MetricLines(collector.Logs))
+
+	server.Close()
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{"etcd_up{" + labels + "} 0"}, test.MetricLines(collector.Logs))
+}
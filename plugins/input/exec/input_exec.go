@@ -0,0 +1,293 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	pluginType = "service_exec"
+
+	parserLine = "line"
+	parserJSON = "json"
+	parserCSV  = "csv"
+
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+
+	statusSuccess = "success"
+	statusFailed  = "failed"
+	statusTimeout = "timeout"
+	statusError   = "error"
+
+	fieldExitCode = "exit_code"
+	fieldStatus   = "status"
+	fieldStream   = "stream"
+	fieldCommand  = "command"
+)
+
+// ServiceExec runs the configured command on a cron schedule and parses its output to events.
+// Each event is tagged with the exit code and the status of the run.
+type ServiceExec struct {
+	Schedule       string   // cron expression, e.g. "*/5 * * * *", "0 */10 * * * *", "@hourly", "@every 30s"
+	Command        []string // the executable and its arguments, no shell is involved
+	Environments   []string // extra environment variables, e.g. "KEY=value"
+	WorkDir        string
+	Name           string // the name of the command in the events, the executable is used if empty
+	TimeoutMs      int    // the command is killed when timed out
+	MaxConcurrency int    // the max count of concurrent runs, the run is skipped if the limit is reached
+	Parser         string // line, json or csv
+	CSVHeader      []string
+	CSVDelimiter   string
+	CaptureStderr  bool // emit the stderr lines as events with stream=stderr
+	MaxOutputBytes int  // the output beyond the limit is discarded
+
+	context   pipeline.Context
+	collector pipeline.Collector
+	schedule  *cronSchedule
+	parser    outputParser
+	slots     chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func (s *ServiceExec) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if len(s.Command) == 0 {
+		return 0, fmt.Errorf("no command configured")
+	}
+	schedule, err := parseSchedule(s.Schedule)
+	if err != nil {
+		return 0, err
+	}
+	s.schedule = schedule
+	if s.parser, err = newOutputParser(s.Parser, s.CSVHeader, s.CSVDelimiter); err != nil {
+		return 0, err
+	}
+	if s.Name == "" {
+		s.Name = s.Command[0]
+	}
+	if s.TimeoutMs <= 0 {
+		s.TimeoutMs = 60000
+	}
+	if s.MaxConcurrency <= 0 {
+		s.MaxConcurrency = 1
+	}
+	if s.MaxOutputBytes <= 0 {
+		s.MaxOutputBytes = 1024 * 1024
+	}
+	s.slots = make(chan struct{}, s.MaxConcurrency)
+	return 0, nil
+}
+
+func (s *ServiceExec) Description() string {
+	return "run the command on a cron schedule and parse its output to events"
+}
+
+func (s *ServiceExec) Start(collector pipeline.Collector) error {
+	s.collector = collector
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// Stop kills the running commands and waits for them to exit.
+func (s *ServiceExec) Stop() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *ServiceExec) loop() {
+	defer s.wg.Done()
+	for {
+		next := s.schedule.next(time.Now())
+		if next.IsZero() {
+			logger.Warning(s.context.GetRuntimeContext(), util.CategoryConfigAlarm, "the schedule never fires", s.Schedule)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		select {
+		case s.slots <- struct{}{}:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer func() { <-s.slots }()
+				s.runOnce()
+			}()
+		default:
+			logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "skip the run of command", s.Name,
+				"reason", "max concurrency reached", "max concurrency", s.MaxConcurrency)
+		}
+	}
+}
+
+func (s *ServiceExec) runOnce() {
+	result := s.run()
+	if result.err != nil && result.status == statusError {
+		logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "run command error", result.err, "command", s.Name)
+	}
+	for _, output := range []struct {
+		stream string
+		data   *limitedBuffer
+	}{{streamStdout, result.stdout}, {streamStderr, result.stderr}} {
+		if output.stream == streamStderr && !s.CaptureStderr {
+			continue
+		}
+		if output.data.truncated {
+			logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "output of command is truncated", s.Name,
+				"stream", output.stream, "limit", s.MaxOutputBytes)
+		}
+		parser := s.parser
+		if output.stream == streamStderr {
+			// stderr is rarely structured
+			parser = lineParser{}
+		}
+		records, err := parser.Parse(output.data.Bytes())
+		if err != nil {
+			logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "parse output of command error", err,
+				"command", s.Name, "stream", output.stream)
+		}
+		for _, record := range records {
+			s.emit(record, output.stream, result)
+		}
+		result.emitted += len(records)
+	}
+	// make sure the failures are visible even if nothing is printed.
+	if result.emitted == 0 && result.status != statusSuccess {
+		s.emit(map[string]string{}, "", result)
+	}
+}
+
+func (s *ServiceExec) emit(fields map[string]string, stream string, result *runResult) {
+	fields[fieldCommand] = s.Name
+	fields[fieldExitCode] = strconv.Itoa(result.exitCode)
+	fields[fieldStatus] = result.status
+	if stream != "" {
+		fields[fieldStream] = stream
+	}
+	s.collector.AddData(nil, fields, result.start)
+}
+
+type runResult struct {
+	start    time.Time
+	stdout   *limitedBuffer
+	stderr   *limitedBuffer
+	exitCode int
+	status   string
+	err      error
+	emitted  int
+}
+
+func (s *ServiceExec) run() *runResult {
+	result := &runResult{
+		start:  time.Now(),
+		stdout: &limitedBuffer{limit: s.MaxOutputBytes},
+		stderr: &limitedBuffer{limit: s.MaxOutputBytes},
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, time.Duration(s.TimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	//nolint:gosec
+	cmd := osexec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Dir = s.WorkDir
+	cmd.Stdout = result.stdout
+	cmd.Stderr = result.stderr
+	if len(s.Environments) > 0 {
+		cmd.Env = append(cmd.Environ(), s.Environments...)
+	}
+	prepareCommand(cmd)
+	if err := cmd.Start(); err != nil {
+		result.exitCode, result.status, result.err = -1, statusError, err
+		return result
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		killCommand(cmd)
+		err = <-done
+		result.exitCode, result.status, result.err = -1, statusTimeout, ctx.Err()
+		return result
+	}
+	var exitErr *osexec.ExitError
+	switch {
+	case err == nil:
+		result.status = statusSuccess
+	case errors.As(err, &exitErr):
+		result.exitCode, result.status = exitErr.ExitCode(), statusFailed
+	default:
+		result.exitCode, result.status, result.err = -1, statusError, err
+	}
+	return result
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest.
+type limitedBuffer struct {
+	strings.Builder
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if left := b.limit - b.Len(); left < len(p) {
+		b.truncated = true
+		if left > 0 {
+			b.Builder.Write(p[:left])
+		}
+		// report all bytes as written, otherwise the command would fail with a broken pipe.
+		return len(p), nil
+	}
+	return b.Builder.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return []byte(b.String())
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceExec{
+			TimeoutMs:      60000,
+			MaxConcurrency: 1,
+			Parser:         parserLine,
+			MaxOutputBytes: 1024 * 1024,
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 5, 31, 10, 17, 30, 0, time.UTC) // friday
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 5, 31, 10, 20, 0, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2024, 5, 31, 10, 17, 40, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2024, 5, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2024, 6, 2, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 5, 31, 10, 19, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 0 13 * fri", time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := parseSchedule(c.spec)
+		require.NoError(t, err, c.spec)
+		assert.Equal(t, c.next, s.next(base), c.spec)
+	}
+
+	s, err := parseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.next(base).IsZero())
+
+	for _, spec := range []string{"", "* * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@every 10ms"} {
+		_, err := parseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestOutputParser(t *testing.T) {
+	p, err := newOutputParser(parserJSON, nil, "")
+	require.NoError(t, err)
+	records, err := p.Parse([]byte(`{"a":1,"b":"x"}` + "\n" + `{"a":2.5,"c":{"d":true}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"a": "1", "b": "x"}, {"a": "2.5", "c": `{"d":true}`}}, records)
+	records, err = p.Parse([]byte(`[{"a":1},{"a":2}]`))
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	p, err = newOutputParser(parserCSV, nil, "")
+	require.NoError(t, err)
+	records, err = p.Parse([]byte("name,size\nsda, 100\nsdb,200,extra\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"name": "sda", "size": "100"}, {"name": "sdb", "size": "200", "column_2": "extra"}}, records)
+
+	p, err = newOutputParser(parserCSV, []string{"k", "v"}, ";")
+	require.NoError(t, err)
+	records, err = p.Parse([]byte("a;1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"k": "a", "v": "1"}}, records)
+
+	p, err = newOutputParser("", nil, "")
+	require.NoError(t, err)
+	records, err = p.Parse([]byte("line1\r\n\nline2"))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"content": "line1"}, {"content": "line2"}}, records)
+
+	_, err = newOutputParser("xml", nil, "")
+	assert.Error(t, err)
+	_, err = newOutputParser(parserCSV, nil, "||")
+	assert.Error(t, err)
+}
+
+func newServiceExec(t *testing.T, command ...string) (*ServiceExec, *test.MockCollector) {
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceExec)
+	s.Schedule = "@every 1h"
+	s.Command = command
+	return s, &test.MockCollector{}
+}
+
+func runOnce(t *testing.T, s *ServiceExec, collector *test.MockCollector) {
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	s.collector = collector
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	defer s.cancel()
+	s.runOnce()
+}
+
+func TestServiceExecRun(t *testing.T) {
+	s, collector := newServiceExec(t, "/bin/sh", "-c", `echo '{"disk":"sda","used":10}'; echo oops >&2; exit 3`)
+	s.Parser = parserJSON
+	s.CaptureStderr = true
+	s.Name = "disk"
+	runOnce(t, s, collector)
+	require.Len(t, collector.Logs, 2)
+	assert.Equal(t, map[string]string{"disk": "sda", "used": "10", "command": "disk", "exit_code": "3", "status": "failed", "stream": "stdout"}, collector.Logs[0].Fields)
+	assert.Equal(t, "oops", collector.Logs[1].Fields["content"])
+	assert.Equal(t, "stderr", collector.Logs[1].Fields["stream"])
+}
+
+func TestServiceExecTimeout(t *testing.T) {
+	s, collector := newServiceExec(t, "/bin/sh", "-c", "sleep 10 & sleep 10")
+	s.TimeoutMs = 100
+	start := time.Now()
+	runOnce(t, s, collector)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, "timeout", collector.Logs[0].Fields["status"])
+	assert.Equal(t, "-1", collector.Logs[0].Fields["exit_code"])
+}
+
+func TestServiceExecOutputLimit(t *testing.T) {
+	s, collector := newServiceExec(t, "/bin/sh", "-c", "echo 0123456789; echo abcdef")
+	s.MaxOutputBytes = 5
+	runOnce(t, s, collector)
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, "01234", collector.Logs[0].Fields["content"])
+	assert.Equal(t, "success", collector.Logs[0].Fields["status"])
+}
+
+func TestServiceExecSchedule(t *testing.T) {
+	s, collector := newServiceExec(t, "/bin/sh", "-c", "echo tick")
+	s.Schedule = "* * * * * *"
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	require.NoError(t, s.Start(collector))
+	time.Sleep(2500 * time.Millisecond)
+	require.NoError(t, s.Stop())
+	assert.GreaterOrEqual(t, len(collector.Logs), 1)
+}
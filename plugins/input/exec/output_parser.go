@@ -0,0 +1,158 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+)
+
+// outputParser converts the output of a command to records, each record becomes an event.
+type outputParser interface {
+	Parse(data []byte) ([]map[string]string, error)
+}
+
+func newOutputParser(name string, csvHeader []string, csvDelimiter string) (outputParser, error) {
+	switch strings.ToLower(name) {
+	case "", parserLine:
+		return lineParser{}, nil
+	case parserJSON:
+		return jsonParser{}, nil
+	case parserCSV:
+		p := csvParser{header: csvHeader, delimiter: ','}
+		if csvDelimiter != "" {
+			r, size := utf8.DecodeRuneInString(csvDelimiter)
+			if size != len(csvDelimiter) {
+				return nil, fmt.Errorf("csv delimiter must be a single character: %s", csvDelimiter)
+			}
+			p.delimiter = r
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unsupported parser %s", name)
+}
+
+// lineParser emits each non empty line as the content.
+type lineParser struct{}
+
+func (lineParser) Parse(data []byte) ([]map[string]string, error) {
+	var records []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		records = append(records, map[string]string{models.ContentKey: line})
+	}
+	return records, scanner.Err()
+}
+
+// jsonParser accepts either a JSON array of objects or one JSON object per line.
+// Nested values are kept as JSON strings.
+type jsonParser struct{}
+
+func (jsonParser) Parse(data []byte) ([]map[string]string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if data[0] == '[' {
+		if err := decoder.Decode(&objects); err != nil {
+			return nil, err
+		}
+	} else {
+		for {
+			var object map[string]interface{}
+			if err := decoder.Decode(&object); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return toRecords(objects), err
+			}
+			objects = append(objects, object)
+		}
+	}
+	return toRecords(objects), nil
+}
+
+func toRecords(objects []map[string]interface{}) []map[string]string {
+	records := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for k, v := range object {
+			switch val := v.(type) {
+			case string:
+				record[k] = val
+			case json.Number:
+				record[k] = val.String()
+			default:
+				b, _ := json.Marshal(val)
+				record[k] = string(b)
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// csvParser uses the configured header as the keys, or the first row if no header is configured.
+type csvParser struct {
+	header    []string
+	delimiter rune
+}
+
+func (p csvParser) Parse(data []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = p.delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	header := p.header
+	if len(header) == 0 {
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		header, rows = rows[0], rows[1:]
+	}
+	records := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		record := make(map[string]string, len(row))
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = value
+			} else {
+				record[fmt.Sprintf("column_%d", i)] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
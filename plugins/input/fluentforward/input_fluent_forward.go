@@ -0,0 +1,361 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_fluent_forward"
+
+const (
+	v1 = iota
+	v2
+)
+
+// ServiceFluentForward receives the events forwarded by fluentd or fluent-bit with the forward protocol.
+type ServiceFluentForward struct {
+	Address            string            // The address to listen on, default is 127.0.0.1:24224.
+	SharedKey          string            // The shared key of the handshake, no handshake if empty.
+	SelfHostname       string            // The hostname sent to the clients in the handshake, default is the hostname.
+	Users              map[string]string // The username and password pairs to authenticate the clients in the handshake.
+	SSLCert            string            // Path to the cert file, TLS is enabled if set.
+	SSLKey             string            // Path to the cert key file.
+	SSLCA              string            // Path to the CA file to verify the client certs.
+	InsecureSkipVerify bool
+	MaxConnections     int    // Max connections, no limit if not positive.
+	TimeoutSeconds     int    // The number of seconds of inactivity before a connection is closed, 0 means never.
+	MaxMessageSize     int    // The max size in bytes of a message, including the decompressed entries.
+	TagKey             string // The key of the fluent tag in the logs, or in the group tags for v2.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	listener    net.Listener
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+}
+
+// Init ...
+func (s *ServiceFluentForward) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if s.SharedKey == "" && len(s.Users) > 0 {
+		return 0, errors.New("users are authenticated in the handshake, which requires the shared key")
+	}
+	if s.SelfHostname == "" {
+		s.SelfHostname = util.GetHostName()
+	}
+	if s.MaxMessageSize <= 0 {
+		s.MaxMessageSize = 16 * 1024 * 1024
+	}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceFluentForward) Description() string {
+	return "fluent forward protocol input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceFluentForward) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceFluentForward) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceFluentForward) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceFluentForward) start() error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	if s.SSLCert != "" || s.SSLKey != "" || s.SSLCA != "" {
+		var tlsConfig *tls.Config
+		if tlsConfig, err = util.GetTLSConfig(s.SSLCert, s.SSLKey, s.SSLCA, s.InsecureSkipVerify); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s.listener = listener
+	s.done = make(chan struct{})
+	s.connections = make(map[net.Conn]struct{})
+	s.wg.Add(1)
+	go s.accept()
+	logger.Info(s.context.GetRuntimeContext(), "fluent forward server start", s.Address)
+	return nil
+}
+
+func (s *ServiceFluentForward) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "FLUENT_FORWARD_ALARM", "accept error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		s.connectionsMu.Lock()
+		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
+			s.connectionsMu.Unlock()
+			logger.Warning(s.context.GetRuntimeContext(), "FLUENT_FORWARD_ALARM", "too many connections, reject", conn.RemoteAddr().String())
+			_ = conn.Close()
+			continue
+		}
+		s.connections[conn] = struct{}{}
+		s.connectionsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *ServiceFluentForward) handle(conn net.Conn) {
+	defer func() {
+		s.connectionsMu.Lock()
+		delete(s.connections, conn)
+		s.connectionsMu.Unlock()
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+	reader := &limitedReader{r: bufio.NewReader(conn)}
+	dec := msgpack.NewDecoder(reader)
+	enc := msgpack.NewEncoder(conn)
+	if s.SharedKey != "" {
+		s.resetTimeout(conn)
+		reader.remaining = int64(s.MaxMessageSize)
+		if err := s.handshake(dec, enc, conn.RemoteAddr().String()); err != nil {
+			logger.Warning(s.context.GetRuntimeContext(), "FLUENT_FORWARD_ALARM", "handshake failed", err, "remote", conn.RemoteAddr().String())
+			return
+		}
+	}
+	for {
+		s.resetTimeout(conn)
+		reader.remaining = int64(s.MaxMessageSize)
+		msg, err := s.readMessage(dec)
+		if err != nil {
+			var netErr net.Error
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+				logger.Warning(s.context.GetRuntimeContext(), "FLUENT_FORWARD_ALARM", "read message failed", err, "remote", conn.RemoteAddr().String())
+			}
+			return
+		}
+		s.collect(msg)
+		if msg.chunk != "" {
+			if err = enc.Encode(map[string]string{"ack": msg.chunk}); err != nil {
+				logger.Warning(s.context.GetRuntimeContext(), "FLUENT_FORWARD_ALARM", "send ack failed", err, "remote", conn.RemoteAddr().String())
+				return
+			}
+		}
+	}
+}
+
+func (s *ServiceFluentForward) resetTimeout(conn net.Conn) {
+	if s.TimeoutSeconds > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Duration(s.TimeoutSeconds) * time.Second))
+	}
+}
+
+func (s *ServiceFluentForward) readMessage(dec *msgpack.Decoder) (*message, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessage(dec, n, tag, int64(s.MaxMessageSize))
+}
+
+// handshake authenticates the client by the shared key and the optional user.
+func (s *ServiceFluentForward) handshake(dec *msgpack.Decoder, enc *msgpack.Encoder, remote string) error {
+	nonce := string(randomBytes(16))
+	var authSalt string
+	if len(s.Users) > 0 {
+		authSalt = string(randomBytes(16))
+	}
+	helo := []interface{}{handshakeHelo, map[string]interface{}{
+		"nonce":     []byte(nonce),
+		"auth":      []byte(authSalt),
+		"keepalive": true,
+	}}
+	if err := enc.Encode(helo); err != nil {
+		return err
+	}
+
+	var ping []interface{}
+	if err := dec.Decode(&ping); err != nil {
+		return err
+	}
+	if len(ping) != 6 {
+		return fmt.Errorf("invalid PING with %d elements", len(ping))
+	}
+	fields := make([]string, len(ping))
+	for i, v := range ping {
+		switch t := v.(type) {
+		case string:
+			fields[i] = t
+		case []byte:
+			fields[i] = string(t)
+		default:
+			return fmt.Errorf("invalid PING element %T", v)
+		}
+	}
+	if fields[0] != handshakePing {
+		return fmt.Errorf("expect PING but got %s", fields[0])
+	}
+	hostname, salt, sharedKeyDigest, username, passwordDigest := fields[1], fields[2], fields[3], fields[4], fields[5]
+
+	reason := ""
+	if sharedKeyDigest != digest(salt, hostname, nonce, s.SharedKey) {
+		reason = "shared key mismatch"
+	} else if len(s.Users) > 0 {
+		password, ok := s.Users[username]
+		if !ok || passwordDigest != digest(authSalt, username, password) {
+			reason = "username/password mismatch"
+		}
+	}
+	pong := []interface{}{handshakePong, reason == "", reason, s.SelfHostname, digest(salt, s.SelfHostname, nonce, s.SharedKey)}
+	if err := enc.Encode(pong); err != nil {
+		return err
+	}
+	if reason != "" {
+		return fmt.Errorf("%s, client hostname %s", reason, hostname)
+	}
+	logger.Debug(s.context.GetRuntimeContext(), "handshake succeeded", remote, "hostname", hostname)
+	return nil
+}
+
+func (s *ServiceFluentForward) collect(msg *message) {
+	switch s.version {
+	case v1:
+		for _, e := range msg.entries {
+			fields := make(map[string]string, len(e.record)+1)
+			for k, v := range e.record {
+				fields[k] = formatValue(v)
+			}
+			if s.TagKey != "" {
+				fields[s.TagKey] = msg.tag
+			}
+			s.collector.AddData(nil, fields, e.time)
+		}
+	case v2:
+		tags := models.NewTags()
+		if s.TagKey != "" {
+			tags.Add(s.TagKey, msg.tag)
+		}
+		group := &models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), tags),
+			Events: make([]models.PipelineEvent, 0, len(msg.entries)),
+		}
+		for _, e := range msg.entries {
+			log := models.NewLog("", nil, "", "", "", models.NewTags(), uint64(e.time.UnixNano()))
+			for k, v := range e.record {
+				log.Contents.Add(k, v)
+			}
+			group.Events = append(group.Events, log)
+		}
+		s.collectorV2.CollectList(group)
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'g', -1, 32)
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceFluentForward) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	close(s.done)
+	_ = s.listener.Close()
+	s.connectionsMu.Lock()
+	for conn := range s.connections {
+		_ = conn.Close()
+	}
+	s.connectionsMu.Unlock()
+	s.wg.Wait()
+	logger.Info(s.context.GetRuntimeContext(), "fluent forward server stop", s.Address)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceFluentForward{
+			Address:        "127.0.0.1:24224",
+			MaxConnections: 1000,
+			MaxMessageSize: 16 * 1024 * 1024,
+			TagKey:         "_tag_",
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newInput() (*ServiceFluentForward, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceFluentForward)
+	s.Address = "127.0.0.1:0"
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+type client struct {
+	conn net.Conn
+	enc  *msgpack.Encoder
+	dec  *msgpack.Decoder
+}
+
+func dial(t *testing.T, s *ServiceFluentForward) *client {
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{conn: conn, enc: msgpack.NewEncoder(conn), dec: msgpack.NewDecoder(conn)}
+}
+
+func (c *client) send(t *testing.T, msg ...interface{}) {
+	require.NoError(t, c.enc.Encode(msg))
+}
+
+func (c *client) expectAck(t *testing.T, chunk string) {
+	var ack map[string]string
+	require.NoError(t, c.dec.Decode(&ack))
+	assert.Equal(t, chunk, ack["ack"])
+}
+
+func packEntries(t *testing.T, compress bool, entries ...[]interface{}) []byte {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, e := range entries {
+		require.NoError(t, enc.Encode(e))
+	}
+	if !compress {
+		return buf.Bytes()
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(buf.Bytes())
+	require.NoError(t, w.Close())
+	return gz.Bytes()
+}
+
+func TestForwardModes(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	c := dial(t, s)
+	defer c.conn.Close()
+	ts := &eventTime{sec: 1700000000, nsec: 123}
+	// Message mode
+	c.send(t, "app.message", ts, map[string]interface{}{"log": "message"}, map[string]interface{}{"chunk": "c1"})
+	c.expectAck(t, "c1")
+	// Forward mode
+	c.send(t, "app.forward", []interface{}{
+		[]interface{}{1700000001, map[string]interface{}{"log": "forward1"}},
+		[]interface{}{ts, map[string]interface{}{"log": "forward2"}},
+	}, map[string]interface{}{"chunk": "c2", "size": 2})
+	c.expectAck(t, "c2")
+	// PackedForward mode
+	c.send(t, "app.packed", packEntries(t, false, []interface{}{ts, map[string]interface{}{"log": "packed"}}),
+		map[string]interface{}{"chunk": "c3"})
+	c.expectAck(t, "c3")
+	// CompressedPackedForward mode without ack
+	c.send(t, "app.compressed", packEntries(t, true,
+		[]interface{}{ts, map[string]interface{}{"log": "compressed1"}},
+		[]interface{}{ts, map[string]interface{}{"log": "compressed2", "nested": map[string]interface{}{"a": 1}}}),
+		map[string]interface{}{"compressed": "gzip", "chunk": "c4"})
+	c.expectAck(t, "c4")
+
+	groups := ctx.Collector().ToArray()
+	require.Len(t, groups, 4)
+	var tags, lines []string
+	for _, g := range groups {
+		for _, e := range g.Events {
+			tags = append(tags, g.Group.Tags.Get("_tag_"))
+			lines = append(lines, e.(*models.Log).Contents.Get("log").(string))
+		}
+	}
+	assert.Equal(t, []string{"app.message", "app.forward", "app.forward", "app.packed", "app.compressed", "app.compressed"}, tags)
+	assert.Equal(t, []string{"message", "forward1", "forward2", "packed", "compressed1", "compressed2"}, lines)
+	assert.Equal(t, uint64(1700000000000000123), groups[0].Events[0].GetTimestamp())
+	assert.Equal(t, uint64(1700000001000000000), groups[1].Events[0].GetTimestamp())
+}
+
+func TestForwardV1(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	require.NoError(t, s.Start(collector))
+	defer s.Stop()
+
+	c := dial(t, s)
+	defer c.conn.Close()
+	c.send(t, "app", 1700000000, map[string]interface{}{
+		"log": "hello", "code": 200, "ok": true, "nested": map[string]interface{}{"a": "b"}, "raw": []byte("bin"),
+	}, map[string]interface{}{"chunk": "c1"})
+	c.expectAck(t, "c1")
+
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, map[string]string{
+		"_tag_": "app", "log": "hello", "code": "200", "ok": "true", "nested": `{"a":"b"}`, "raw": "bin",
+	}, collector.Logs[0].Fields)
+}
+
+func handshake(t *testing.T, c *client, sharedKey, username, password string) []interface{} {
+	var helo []interface{}
+	require.NoError(t, c.dec.Decode(&helo))
+	require.Equal(t, "HELO", helo[0])
+	options := helo[1].(map[string]interface{})
+	nonce := string(options["nonce"].([]byte))
+	authSalt := string(options["auth"].([]byte))
+	salt := "salt"
+	c.send(t, "PING", "client", salt, digest(salt, "client", nonce, sharedKey), username, digest(authSalt, username, password))
+	var pong []interface{}
+	require.NoError(t, c.dec.Decode(&pong))
+	require.Equal(t, "PONG", pong[0])
+	if pong[1] == true {
+		assert.Equal(t, digest(salt, "server", nonce, sharedKey), pong[4])
+	}
+	return pong
+}
+
+func TestForwardHandshake(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.SharedKey = "secret"
+	s.SelfHostname = "server"
+	s.Users = map[string]string{"alice": "pass"}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	c := dial(t, s)
+	pong := handshake(t, c, "secret", "alice", "pass")
+	assert.Equal(t, true, pong[1])
+	c.send(t, "app", 1700000000, map[string]interface{}{"log": "hello"}, map[string]interface{}{"chunk": "c1"})
+	c.expectAck(t, "c1")
+	c.conn.Close()
+
+	for _, auth := range [][]string{{"wrong", "alice", "pass"}, {"secret", "alice", "wrong"}, {"secret", "bob", "pass"}} {
+		c = dial(t, s)
+		pong = handshake(t, c, auth[0], auth[1], auth[2])
+		assert.Equal(t, false, pong[1])
+		assert.NotEmpty(t, pong[2])
+		_, err := c.conn.Read(make([]byte, 1))
+		assert.Error(t, err)
+		c.conn.Close()
+	}
+	assert.Len(t, ctx.Collector().ToArray(), 1)
+
+	invalid := &ServiceFluentForward{Users: map[string]string{"alice": "pass"}}
+	_, err = invalid.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+}
+
+func TestForwardMessageTooLarge(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.MaxMessageSize = 1024
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	// the compressed entries are limited too
+	c := dial(t, s)
+	large := string(bytes.Repeat([]byte("a"), 4096))
+	c.send(t, "app", packEntries(t, true, []interface{}{1700000000, map[string]interface{}{"log": large}}),
+		map[string]interface{}{"compressed": "gzip", "chunk": "c1"})
+	var ack map[string]string
+	assert.Error(t, c.dec.Decode(&ack))
+	c.conn.Close()
+
+	c = dial(t, s)
+	c.send(t, "app", 1700000000, map[string]interface{}{"log": large})
+	_, err = c.conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	c.conn.Close()
+	assert.Empty(t, ctx.Collector().ToArray())
+}
@@ -0,0 +1,308 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// The forward protocol, ref: https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+
+const (
+	eventTimeExtType = 0
+
+	optionSize       = "size"
+	optionChunk      = "chunk"
+	optionCompressed = "compressed"
+	compressedGzip   = "gzip"
+
+	handshakeHelo = "HELO"
+	handshakePing = "PING"
+	handshakePong = "PONG"
+)
+
+var errMessageTooLarge = errors.New("message too large")
+
+// eventTime is the EventTime ext type, which is the seconds and nanoseconds in big endian.
+type eventTime struct {
+	sec  uint32
+	nsec uint32
+}
+
+func (t *eventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, t.sec)
+	binary.BigEndian.PutUint32(b[4:], t.nsec)
+	return b, nil
+}
+
+func (t *eventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid event time length %d", len(b))
+	}
+	t.sec = binary.BigEndian.Uint32(b)
+	t.nsec = binary.BigEndian.Uint32(b[4:])
+	return nil
+}
+
+func init() {
+	msgpack.RegisterExt(eventTimeExtType, (*eventTime)(nil))
+}
+
+// entry is an event of the forward protocol.
+type entry struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// message is the decoded message in any of the Message, Forward, PackedForward and CompressedPackedForward modes.
+type message struct {
+	tag     string
+	entries []entry
+	chunk   string
+}
+
+// limitedReader fails the read when more than the remaining bytes are read, so that a message could not
+// exhaust the memory. The remaining bytes are reset before each message.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, errMessageTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func decodeTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case *eventTime:
+		return time.Unix(int64(t.sec), int64(t.nsec)), nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case int8, int16, int32, uint8, uint16, uint32:
+		return time.Unix(toInt64(t), 0), nil
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case float32:
+		sec, frac := math.Modf(float64(t))
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid event time %T", v)
+	}
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	}
+	return 0
+}
+
+func decodeRecord(dec *msgpack.Decoder) (map[string]interface{}, error) {
+	v, err := dec.DecodeInterfaceLoose()
+	if err != nil {
+		return nil, err
+	}
+	record, ok := normalize(v).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid record %T", v)
+	}
+	return record, nil
+}
+
+// normalize converts the maps with non-string keys and the binaries, so that the values could be marshaled to json.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case map[string]interface{}:
+		for k, value := range t {
+			t[k] = normalize(value)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, value := range t {
+			m[fmt.Sprint(normalize(k))] = normalize(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range t {
+			t[i] = normalize(value)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// decodeEntry decodes the [time, record] array.
+func decodeEntry(dec *msgpack.Decoder) (entry, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return entry{}, err
+	}
+	if n != 2 {
+		return entry{}, fmt.Errorf("invalid entry with %d elements", n)
+	}
+	v, err := dec.DecodeInterfaceLoose()
+	if err != nil {
+		return entry{}, err
+	}
+	t, err := decodeTime(v)
+	if err != nil {
+		return entry{}, err
+	}
+	record, err := decodeRecord(dec)
+	if err != nil {
+		return entry{}, err
+	}
+	return entry{time: t, record: record}, nil
+}
+
+// decodeMessage decodes the message after its array header and tag have been read.
+func decodeMessage(dec *msgpack.Decoder, n int, tag string, maxMessageSize int64) (*message, error) {
+	if n < 2 || n > 4 {
+		return nil, fmt.Errorf("invalid message with %d elements", n)
+	}
+	msg := &message{tag: tag}
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	var packed []byte
+	switch {
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		// Forward mode
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < count; i++ {
+			e, err := decodeEntry(dec)
+			if err != nil {
+				return nil, err
+			}
+			msg.entries = append(msg.entries, e)
+		}
+	case msgpcode.IsBin(code) || msgpcode.IsString(code):
+		// PackedForward mode
+		if packed, err = dec.DecodeBytes(); err != nil {
+			return nil, err
+		}
+	default:
+		// Message mode
+		if n < 3 {
+			return nil, fmt.Errorf("invalid message with %d elements", n)
+		}
+		v, err := dec.DecodeInterfaceLoose()
+		if err != nil {
+			return nil, err
+		}
+		t, err := decodeTime(v)
+		if err != nil {
+			return nil, err
+		}
+		record, err := decodeRecord(dec)
+		if err != nil {
+			return nil, err
+		}
+		msg.entries = append(msg.entries, entry{time: t, record: record})
+		n--
+	}
+
+	var option map[string]interface{}
+	if n > 2 {
+		v, err := dec.DecodeInterfaceLoose()
+		if err != nil {
+			return nil, err
+		}
+		option, _ = normalize(v).(map[string]interface{})
+	}
+	msg.chunk, _ = option[optionChunk].(string)
+
+	if packed != nil {
+		var r io.Reader = bytes.NewReader(packed)
+		if compressed, _ := option[optionCompressed].(string); compressed == compressedGzip {
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			defer gr.Close()
+			r = &limitedReader{r: gr, remaining: maxMessageSize}
+		}
+		entriesDec := msgpack.NewDecoder(r)
+		for {
+			e, err := decodeEntry(entriesDec)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			msg.entries = append(msg.entries, e)
+		}
+	}
+	return msg, nil
+}
+
+func digest(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		_, _ = io.WriteString(h, p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
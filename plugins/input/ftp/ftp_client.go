@@ -0,0 +1,86 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"crypto/tls"
+	"io"
+	"path"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// ftpClient polls files with github.com/jlaffaye/ftp in passive mode, it lists by MLSD if the server
+// supports it and falls back to LIST otherwise.
+type ftpClient struct {
+	conn *ftp.ServerConn
+}
+
+// dialFTP connects and logs in the server, tlsConfig enables FTPS, which is implicit TLS if explicitTLS is false.
+func dialFTP(address, username, password string, timeout time.Duration, tlsConfig *tls.Config, explicitTLS bool) (*ftpClient, error) {
+	options := []ftp.DialOption{ftp.DialWithTimeout(timeout)}
+	if tlsConfig != nil {
+		if explicitTLS {
+			options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+		} else {
+			options = append(options, ftp.DialWithTLS(tlsConfig))
+		}
+	}
+	conn, err := ftp.Dial(address, options...)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		username = "anonymous"
+	}
+	if err = conn.Login(username, password); err != nil {
+		_ = conn.Quit()
+		return nil, err
+	}
+	return &ftpClient{conn: conn}, nil
+}
+
+func (c *ftpClient) List(dir string) ([]remoteFile, error) {
+	entries, err := c.conn.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]remoteFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type != ftp.EntryTypeFile {
+			continue
+		}
+		files = append(files, remoteFile{Path: path.Join(dir, entry.Name), Size: int64(entry.Size), ModTime: entry.Time})
+	}
+	return files, nil
+}
+
+// Open returns the content of the file, it must be closed before issuing other commands.
+func (c *ftpClient) Open(filePath string) (io.ReadCloser, error) {
+	return c.conn.Retr(filePath)
+}
+
+func (c *ftpClient) Rename(from, to string) error {
+	return c.conn.Rename(from, to)
+}
+
+func (c *ftpClient) Remove(filePath string) error {
+	return c.conn.Delete(filePath)
+}
+
+func (c *ftpClient) Close() error {
+	return c.conn.Quit()
+}
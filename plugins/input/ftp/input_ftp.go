@@ -0,0 +1,459 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/tlscommon"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	pluginType = "service_ftp"
+
+	schemeFTP  = "ftp"
+	schemeFTPS = "ftps"
+	schemeSFTP = "sftp"
+
+	afterReadNone   = "none"
+	afterReadRename = "rename"
+	afterReadDelete = "delete"
+
+	tagPath = "__path__"
+)
+
+type remoteFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// remoteClient is the operations needed to poll files, implemented by ftp(s) and sftp.
+type remoteClient interface {
+	List(dir string) ([]remoteFile, error)
+	Open(filePath string) (io.ReadCloser, error)
+	Rename(from, to string) error
+	Remove(filePath string) error
+	Close() error
+}
+
+// fileState is the checkpoint of a read file, the file is read again only if it is modified.
+type fileState struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+// ServiceFTP polls the new files in a directory of FTP/FTPS/SFTP servers and emits their lines.
+type ServiceFTP struct {
+	// URL is the server and the directory to poll, e.g. "ftp://10.0.0.1:21/drops", "ftps://ftp.example.com/drops",
+	// "sftp://sftp.example.com/upload". The ftps scheme uses implicit TLS.
+	URL                string
+	Username           string
+	Password           string
+	ExplicitTLS        bool                 // upgrade the ftp connection to TLS by AUTH TLS
+	TLS                *tlscommon.TLSConfig // the TLS config of ftps and explicit TLS, e.g. the CA of the server
+	PrivateKeyFile     string               // the private key for sftp
+	HostKeyFingerprint string               // the SHA256 fingerprint of the sftp host key, e.g. "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"
+	SkipHostKeyVerify  bool                 // skip the host key verification of sftp, insecure
+	FilePatterns       []string             // the glob patterns of the file names, default is "*"
+	IntervalMs         int
+	TimeoutMs          int
+	MinFileAgeMs       int    // the files modified recently are skipped, to avoid reading the files being uploaded
+	MaxFileSizeBytes   int64  // the larger files are skipped, 0 means no limit
+	MaxLineBytes       int    // the longer lines are truncated
+	AfterRead          string // none, rename or delete
+	RenameSuffix       string
+
+	context   pipeline.Context
+	collector pipeline.Collector
+	scheme    string
+	address   string
+	dir       string
+	sshConfig *ssh.ClientConfig
+	tlsConfig *tls.Config
+	states    map[string]fileState
+	shutdown  chan struct{}
+	wg        sync.WaitGroup
+
+	dial func() (remoteClient, error)
+	now  func() time.Time
+}
+
+func (s *ServiceFTP) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid url %s: %v", s.URL, err)
+	}
+	if u.User != nil && s.Username == "" {
+		s.Username = u.User.Username()
+		s.Password, _ = u.User.Password()
+	}
+	if s.IntervalMs <= 0 {
+		s.IntervalMs = 60000
+	}
+	if s.TimeoutMs <= 0 {
+		s.TimeoutMs = 30000
+	}
+	if s.MaxLineBytes <= 0 {
+		s.MaxLineBytes = 512 * 1024
+	}
+	s.scheme = strings.ToLower(u.Scheme)
+	port := u.Port()
+	switch s.scheme {
+	case schemeFTP:
+		if port == "" {
+			port = "21"
+		}
+		if s.ExplicitTLS {
+			if s.tlsConfig, err = s.newTLSConfig(u.Hostname()); err != nil {
+				return 0, err
+			}
+		}
+	case schemeFTPS:
+		if port == "" {
+			port = "990"
+		}
+		if s.tlsConfig, err = s.newTLSConfig(u.Hostname()); err != nil {
+			return 0, err
+		}
+	case schemeSFTP:
+		if port == "" {
+			port = "22"
+		}
+		if s.sshConfig, err = s.newSSHConfig(); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported scheme %s, must be ftp, ftps or sftp", u.Scheme)
+	}
+	s.address = net.JoinHostPort(u.Hostname(), port)
+	s.dir = u.Path
+	if s.dir == "" {
+		s.dir = "/"
+	}
+	if len(s.FilePatterns) == 0 {
+		s.FilePatterns = []string{"*"}
+	}
+	for _, pattern := range s.FilePatterns {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return 0, fmt.Errorf("invalid file pattern %s: %v", pattern, err)
+		}
+	}
+	switch s.AfterRead {
+	case "":
+		s.AfterRead = afterReadNone
+	case afterReadNone, afterReadDelete:
+	case afterReadRename:
+		if s.RenameSuffix == "" {
+			s.RenameSuffix = ".done"
+		}
+	default:
+		return 0, fmt.Errorf("unsupported AfterRead %s, must be none, rename or delete", s.AfterRead)
+	}
+	if s.dial == nil {
+		s.dial = s.dialServer
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	s.states = make(map[string]fileState)
+	s.context.GetCheckPointObject(s.checkpointKey(), &s.states)
+	return 0, nil
+}
+
+func (s *ServiceFTP) newTLSConfig(host string) (*tls.Config, error) {
+	tlsConfig := tlscommon.TLSConfig{}
+	if s.TLS != nil {
+		tlsConfig = *s.TLS
+	}
+	tlsConfig.Enabled = true
+	config, err := tlsConfig.LoadTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load tls config error: %v", err)
+	}
+	config.ServerName = host
+	// the data connections resume the session of the control connection, which is required by most ftps servers.
+	config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	return config, nil
+}
+
+func (s *ServiceFTP) newSSHConfig() (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User:    s.Username,
+		Timeout: time.Duration(s.TimeoutMs) * time.Millisecond,
+	}
+	if s.PrivateKeyFile != "" {
+		key, err := os.ReadFile(filepath.Clean(s.PrivateKeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("read private key error: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key error: %v", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if s.Password != "" {
+		config.Auth = append(config.Auth, ssh.Password(s.Password))
+	}
+	switch {
+	case s.HostKeyFingerprint != "":
+		config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if fingerprint := ssh.FingerprintSHA256(key); fingerprint != s.HostKeyFingerprint {
+				return fmt.Errorf("host key fingerprint mismatch, got %s", fingerprint)
+			}
+			return nil
+		}
+	case s.SkipHostKeyVerify:
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey() //nolint:gosec
+	default:
+		return nil, fmt.Errorf("either HostKeyFingerprint or SkipHostKeyVerify must be set for sftp")
+	}
+	return config, nil
+}
+
+func (s *ServiceFTP) dialServer() (remoteClient, error) {
+	if s.scheme == schemeSFTP {
+		return dialSFTP(s.address, s.sshConfig)
+	}
+	return dialFTP(s.address, s.Username, s.Password, time.Duration(s.TimeoutMs)*time.Millisecond, s.tlsConfig, s.ExplicitTLS)
+}
+
+func (s *ServiceFTP) checkpointKey() string {
+	return pluginType + "_" + s.URL
+}
+
+func (s *ServiceFTP) Description() string {
+	return "poll the new files on ftp/ftps/sftp servers and emit their lines"
+}
+
+func (s *ServiceFTP) Start(collector pipeline.Collector) error {
+	s.collector = collector
+	s.shutdown = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Duration(s.IntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if err := s.poll(); err != nil {
+				logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "poll files error", err, "url", s.URL)
+			}
+			select {
+			case <-s.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *ServiceFTP) Stop() error {
+	close(s.shutdown)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *ServiceFTP) stopping() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// poll reads the new or modified files in the directory, in the order of the modified time.
+func (s *ServiceFTP) poll() error {
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	files, err := client.List(s.dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime.Before(files[j].ModTime)
+	})
+	now := s.now()
+	listed := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		listed[file.Path] = struct{}{}
+		if !s.shouldRead(file, now) {
+			continue
+		}
+		if s.stopping() {
+			break
+		}
+		if err = s.readFile(client, file); err != nil {
+			logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "read file error", err, "file", file.Path)
+			continue
+		}
+		s.states[file.Path] = fileState{Size: file.Size, ModTime: file.ModTime.Unix()}
+		s.afterRead(client, file)
+	}
+	// forget the files which are gone, so that the checkpoint does not grow forever
+	for name := range s.states {
+		if _, ok := listed[name]; !ok {
+			delete(s.states, name)
+		}
+	}
+	return s.context.SaveCheckPointObject(s.checkpointKey(), s.states)
+}
+
+func (s *ServiceFTP) shouldRead(file remoteFile, now time.Time) bool {
+	name := path.Base(file.Path)
+	if s.AfterRead == afterReadRename && strings.HasSuffix(name, s.RenameSuffix) {
+		return false
+	}
+	matched := false
+	for _, pattern := range s.FilePatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	if s.MaxFileSizeBytes > 0 && file.Size > s.MaxFileSizeBytes {
+		return false
+	}
+	if s.MinFileAgeMs > 0 && !file.ModTime.IsZero() && now.Sub(file.ModTime) < time.Duration(s.MinFileAgeMs)*time.Millisecond {
+		return false
+	}
+	if state, ok := s.states[file.Path]; ok && state.Size == file.Size && state.ModTime == file.ModTime.Unix() {
+		return false
+	}
+	return true
+}
+
+func (s *ServiceFTP) readFile(client remoteClient, file remoteFile) error {
+	rc, err := client.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	reader, err := decompress(file.Path, rc)
+	if err != nil {
+		_ = rc.Close()
+		return err
+	}
+	defer reader.Close()
+	tags := map[string]string{tagPath: file.Path}
+	br := bufio.NewReaderSize(reader, 64*1024)
+	for {
+		line, readErr := readLine(br, s.MaxLineBytes)
+		if len(line) > 0 {
+			s.collector.AddData(tags, map[string]string{models.ContentKey: line})
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				break
+			}
+			_ = rc.Close()
+			return readErr
+		}
+	}
+	return rc.Close()
+}
+
+func (s *ServiceFTP) afterRead(client remoteClient, file remoteFile) {
+	var err error
+	switch s.AfterRead {
+	case afterReadRename:
+		err = client.Rename(file.Path, file.Path+s.RenameSuffix)
+	case afterReadDelete:
+		err = client.Remove(file.Path)
+	default:
+		return
+	}
+	if err != nil {
+		logger.Warning(s.context.GetRuntimeContext(), util.InputCollectAlarm, "handle file after read error", err,
+			"file", file.Path, "action", s.AfterRead)
+		return
+	}
+	delete(s.states, file.Path)
+}
+
+// readLine reads a line without the line break, the bytes beyond the limit are discarded.
+func readLine(br *bufio.Reader, limit int) (string, error) {
+	var line []byte
+	for {
+		fragment, isPrefix, err := br.ReadLine()
+		if len(line) < limit {
+			if left := limit - len(line); len(fragment) > left {
+				fragment = fragment[:left]
+			}
+			line = append(line, fragment...)
+		}
+		if err != nil || !isPrefix {
+			return string(line), err
+		}
+	}
+}
+
+// decompress wraps the reader according to the extension of the file,
+// closing the returned reader does not close the underlying one.
+func decompress(name string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case ".zst":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceFTP{
+			IntervalMs:   60000,
+			TimeoutMs:    30000,
+			MaxLineBytes: 512 * 1024,
+			AfterRead:    afterReadNone,
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/tlscommon"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const mdtmLayout = "20060102150405"
+
+type fakeFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// fakeFTPServer serves the files in memory with the minimal commands used by the client, it lists by
+// MLSD if the MLST feature is announced, and by LIST in the unix format otherwise.
+type fakeFTPServer struct {
+	listener net.Listener
+	lock     sync.Mutex
+	files    map[string]*fakeFile
+	noMLSD   bool
+}
+
+func newFakeFTPServer(t *testing.T) *fakeFTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeFTPServer{listener: listener, files: make(map[string]*fakeFile)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeFTPServer) put(name string, data []byte, modTime time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[name] = &fakeFile{data: data, modTime: modTime}
+}
+
+func (s *fakeFTPServer) names() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var names []string
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *fakeFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		_, _ = fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 ready")
+	var dataListener net.Listener
+	var renameFrom string
+	withData := func(write func(c net.Conn)) {
+		if dataListener == nil {
+			reply("425 no data connection")
+			return
+		}
+		reply("150 opening data connection")
+		dataConn, err := dataListener.Accept()
+		if err == nil {
+			write(dataConn)
+			dataConn.Close()
+		}
+		dataListener.Close()
+		dataListener = nil
+		reply("226 transfer complete")
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		s.lock.Lock()
+		file := s.files[arg]
+		s.lock.Unlock()
+		switch cmd {
+		case "USER":
+			reply("331 password required")
+		case "PASS":
+			if arg != "secret" {
+				reply("530 login incorrect")
+				continue
+			}
+			reply("230 logged in")
+		case "FEAT":
+			if s.noMLSD {
+				reply("211 no features")
+				continue
+			}
+			_, _ = fmt.Fprintf(conn, "211-features\r\n MLST type*;size*;modify*;\r\n211 end\r\n")
+		case "TYPE":
+			reply("200 ok")
+		case "EPSV":
+			dataListener, _ = net.Listen("tcp", "127.0.0.1:0")
+			reply("229 Entering Extended Passive Mode (|||%d|)", dataListener.Addr().(*net.TCPAddr).Port)
+		case "MLSD":
+			if s.noMLSD {
+				reply("500 unknown command")
+				continue
+			}
+			withData(func(c net.Conn) {
+				_, _ = fmt.Fprintf(c, "type=cdir;modify=20240101000000; .\r\n")
+				_, _ = fmt.Fprintf(c, "type=dir;modify=20240101000000; sub\r\n")
+				s.lock.Lock()
+				for name, f := range s.files {
+					_, _ = fmt.Fprintf(c, "type=file;size=%d;modify=%s; %s\r\n", len(f.data), f.modTime.UTC().Format(mdtmLayout), path.Base(name))
+				}
+				s.lock.Unlock()
+			})
+		case "LIST":
+			withData(func(c net.Conn) {
+				_, _ = fmt.Fprintf(c, "drwxr-xr-x 2 ftp ftp 4096 Jan  1  2024 sub\r\n")
+				s.lock.Lock()
+				for name, f := range s.files {
+					_, _ = fmt.Fprintf(c, "-rw-r--r-- 1 ftp ftp %d %s %s\r\n", len(f.data), f.modTime.UTC().Format("Jan _2  2006"), path.Base(name))
+				}
+				s.lock.Unlock()
+			})
+		case "SIZE", "MDTM", "RETR", "RNFR", "DELE":
+			if file == nil {
+				reply("550 no such file")
+				continue
+			}
+			switch cmd {
+			case "SIZE":
+				reply("213 %d", len(file.data))
+			case "MDTM":
+				reply("213 %s", file.modTime.UTC().Format(mdtmLayout))
+			case "RETR":
+				withData(func(c net.Conn) { _, _ = c.Write(file.data) })
+			case "RNFR":
+				renameFrom = arg
+				reply("350 ready for RNTO")
+			case "DELE":
+				s.lock.Lock()
+				delete(s.files, arg)
+				s.lock.Unlock()
+				reply("250 deleted")
+			}
+		case "RNTO":
+			s.lock.Lock()
+			s.files[arg] = s.files[renameFrom]
+			delete(s.files, renameFrom)
+			s.lock.Unlock()
+			reply("250 renamed")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newServiceFTP(t *testing.T, server *fakeFTPServer) (*ServiceFTP, *test.MockCollector) {
+	s := &ServiceFTP{
+		URL:          "ftp://partner:secret@" + server.listener.Addr().String() + "/drops",
+		FilePatterns: []string{"*.log", "*.log.gz"},
+	}
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.shutdown = make(chan struct{})
+	return s, collector
+}
+
+func contents(collector *test.MockCollector) []string {
+	var res []string
+	for _, log := range collector.Logs {
+		res = append(res, log.Tags[tagPath]+":"+log.Fields["content"])
+	}
+	return res
+}
+
+func TestServiceFTPPoll(t *testing.T) {
+	for _, noMLSD := range []bool{false, true} {
+		server := newFakeFTPServer(t)
+		server.noMLSD = noMLSD
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		server.put("/drops/a.log", []byte("a1\na2\n"), base)
+		server.put("/drops/b.log.gz", gzipData(t, "b1\r\nb2"), base.Add(time.Minute))
+		server.put("/drops/c.csv", []byte("ignored"), base)
+
+		s, collector := newServiceFTP(t, server)
+		require.NoError(t, s.poll())
+		assert.Equal(t, []string{"/drops/a.log:a1", "/drops/a.log:a2", "/drops/b.log.gz:b1", "/drops/b.log.gz:b2"}, contents(collector))
+
+		// unchanged files are not read again, the modified ones are
+		collector.Logs = nil
+		server.put("/drops/a.log", []byte("a1\na2\na3\n"), base.Add(2*time.Minute))
+		require.NoError(t, s.poll())
+		assert.Equal(t, []string{"/drops/a.log:a1", "/drops/a.log:a2", "/drops/a.log:a3"}, contents(collector))
+		server.listener.Close()
+	}
+}
+
+func TestServiceFTPAfterRead(t *testing.T) {
+	server := newFakeFTPServer(t)
+	defer server.listener.Close()
+	now := time.Now()
+	server.put("/drops/a.log", []byte("a1\n"), now.Add(-time.Hour))
+	server.put("/drops/b.log", []byte("b1\n"), now.Add(-time.Hour))
+	server.put("/drops/uploading.log", []byte("partial"), now)
+
+	s, collector := newServiceFTP(t, server)
+	s.AfterRead = afterReadRename
+	s.RenameSuffix = ".done"
+	s.MinFileAgeMs = 60000
+	require.NoError(t, s.poll())
+	assert.Len(t, collector.Logs, 2)
+	assert.Equal(t, []string{"/drops/a.log.done", "/drops/b.log.done", "/drops/uploading.log"}, server.names())
+
+	// renamed files are never read again
+	require.NoError(t, s.poll())
+	assert.Len(t, collector.Logs, 2)
+
+	s.AfterRead = afterReadDelete
+	s.FilePatterns = []string{"*.done"}
+	s.MinFileAgeMs = 0
+	require.NoError(t, s.poll())
+	assert.Len(t, collector.Logs, 4)
+	assert.Equal(t, []string{"/drops/uploading.log"}, server.names())
+}
+
+func TestServiceFTPInit(t *testing.T) {
+	for _, url := range []string{"http://127.0.0.1/", "sftp://127.0.0.1/upload", "::"} {
+		s := &ServiceFTP{URL: url}
+		_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+		assert.Error(t, err, url)
+	}
+	s := &ServiceFTP{URL: "sftp://user@127.0.0.1/upload", Password: "pass", SkipHostKeyVerify: true}
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:22", s.address)
+	assert.Equal(t, "user", s.sshConfig.User)
+	assert.Equal(t, "/upload", s.dir)
+	assert.Nil(t, s.tlsConfig)
+
+	s = &ServiceFTP{URL: "ftps://ftp.example.com/drops"}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "ftp.example.com:990", s.address)
+	require.NotNil(t, s.tlsConfig)
+	assert.Equal(t, "ftp.example.com", s.tlsConfig.ServerName)
+
+	s = &ServiceFTP{URL: "ftp://ftp.example.com/drops", ExplicitTLS: true, TLS: &tlscommon.TLSConfig{InsecureSkipVerify: true}}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "ftp.example.com:21", s.address)
+	require.NotNil(t, s.tlsConfig)
+	assert.True(t, s.tlsConfig.InsecureSkipVerify)
+}
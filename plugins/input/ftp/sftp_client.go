@@ -0,0 +1,79 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"io"
+	"path"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpClient polls files with github.com/pkg/sftp over an ssh connection.
+type sftpClient struct {
+	sshClient *ssh.Client
+	client    *sftp.Client
+}
+
+func dialSFTP(address string, config *ssh.ClientConfig) (*sftpClient, error) {
+	sshClient, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, err
+	}
+	return &sftpClient{sshClient: sshClient, client: client}, nil
+}
+
+func (c *sftpClient) List(dir string) ([]remoteFile, error) {
+	infos, err := c.client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]remoteFile, 0, len(infos))
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, remoteFile{Path: path.Join(dir, info.Name()), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+func (c *sftpClient) Open(filePath string) (io.ReadCloser, error) {
+	return c.client.Open(filePath)
+}
+
+func (c *sftpClient) Rename(from, to string) error {
+	return c.client.Rename(from, to)
+}
+
+func (c *sftpClient) Remove(filePath string) error {
+	return c.client.Remove(filePath)
+}
+
+func (c *sftpClient) Close() error {
+	err := c.client.Close()
+	if c.sshClient != nil {
+		if closeErr := c.sshClient.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ftp
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInMemorySFTPClient connects a client to an in-memory sftp server through a pipe.
+func newInMemorySFTPClient(t *testing.T) *sftpClient {
+	clientConn, serverConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go func() { _ = server.Serve() }()
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Close() })
+	return &sftpClient{client: client}
+}
+
+func putSFTPFile(t *testing.T, c *sftpClient, name, data string) {
+	f, err := c.client.Create(name)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestSFTPClient(t *testing.T) {
+	c := newInMemorySFTPClient(t)
+	defer c.Close()
+	require.NoError(t, c.client.Mkdir("/upload"))
+	require.NoError(t, c.client.Mkdir("/upload/sub"))
+	putSFTPFile(t, c, "/upload/a.log", "a1\na2\n")
+
+	list, err := c.List("/upload")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "/upload/a.log", list[0].Path)
+	assert.Equal(t, int64(6), list[0].Size)
+	assert.False(t, list[0].ModTime.IsZero())
+
+	rc, err := c.Open("/upload/a.log")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "a1\na2\n", string(data))
+
+	_, err = c.Open("/upload/missing.log")
+	assert.Error(t, err)
+
+	require.NoError(t, c.Rename("/upload/a.log", "/upload/a.log.done"))
+	list, err = c.List("/upload")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "/upload/a.log.done", list[0].Path)
+	require.NoError(t, c.Remove("/upload/a.log.done"))
+	list, err = c.List("/upload")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
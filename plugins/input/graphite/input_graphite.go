@@ -0,0 +1,502 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers/graphite"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_graphite"
+
+const (
+	v1 = iota
+	v2
+)
+
+const (
+	formatPlaintext = "plaintext"
+	formatPickle    = "pickle"
+)
+
+const maxDatagramSize = 65535
+
+// ServiceGraphite receives the metrics sent with the graphite plaintext or pickle protocol.
+type ServiceGraphite struct {
+	Address        string            // The address to listen on, default is 127.0.0.1:2003.
+	Network        string            // tcp or udp, default is tcp. The pickle protocol only supports tcp.
+	Format         string            // plaintext or pickle, default is plaintext.
+	Separator      string            // The separator to join the template parts of the metric name, default is ".".
+	Templates      []string          // The templates to parse the metric paths into the names and tags, such as "servers.* .host.measurement*".
+	DefaultTags    map[string]string // The tags added to all metrics, the tags parsed from the paths take precedence.
+	MaxConnections int               // Max tcp connections, no limit if not positive.
+	TimeoutSeconds int               // The number of seconds of inactivity before a tcp connection is closed, 0 means never.
+	MaxMessageSize int               // The max size in bytes of a plaintext line or a pickle payload.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	parser      *graphite.GraphiteParser
+	listener    net.Listener
+	packetConn  net.PacketConn
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+	lastAlarm     time.Time
+	alarmMu       sync.Mutex
+}
+
+type point struct {
+	name      string
+	tags      map[string]string
+	value     float64
+	timestamp time.Time
+}
+
+// Init ...
+func (s *ServiceGraphite) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	switch s.Network {
+	case "":
+		s.Network = "tcp"
+	case "tcp", "udp":
+	default:
+		return 0, fmt.Errorf("unsupported network %v", s.Network)
+	}
+	switch s.Format {
+	case "":
+		s.Format = formatPlaintext
+	case formatPlaintext:
+	case formatPickle:
+		if s.Network != "tcp" {
+			return 0, errors.New("pickle protocol only supports tcp")
+		}
+	default:
+		return 0, fmt.Errorf("unsupported format %v", s.Format)
+	}
+	if s.MaxMessageSize <= 0 {
+		s.MaxMessageSize = 1024 * 1024
+	}
+	var err error
+	if s.parser, err = graphite.NewGraphiteParser(s.Separator, s.Templates, s.DefaultTags); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceGraphite) Description() string {
+	return "graphite plaintext and pickle protocol input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceGraphite) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceGraphite) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceGraphite) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceGraphite) start() error {
+	s.done = make(chan struct{})
+	if s.Network == "udp" {
+		conn, err := net.ListenPacket("udp", s.Address)
+		if err != nil {
+			return err
+		}
+		s.packetConn = conn
+		s.wg.Add(1)
+		go s.readPackets()
+	} else {
+		listener, err := net.Listen("tcp", s.Address)
+		if err != nil {
+			return err
+		}
+		s.listener = listener
+		s.connections = make(map[net.Conn]struct{})
+		s.wg.Add(1)
+		go s.accept()
+	}
+	logger.Info(s.context.GetRuntimeContext(), "graphite server start", s.Address, "network", s.Network, "format", s.Format)
+	return nil
+}
+
+func (s *ServiceGraphite) readPackets() {
+	defer s.wg.Done()
+	size := s.MaxMessageSize
+	if size > maxDatagramSize {
+		size = maxDatagramSize
+	}
+	buf := make([]byte, size)
+	for {
+		n, _, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "GRAPHITE_ALARM", "read packet error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		var points []*point
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if p := s.parseLine(line); p != nil {
+				points = append(points, p)
+			}
+		}
+		s.collect(points)
+	}
+}
+
+func (s *ServiceGraphite) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "GRAPHITE_ALARM", "accept error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		s.connectionsMu.Lock()
+		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
+			s.connectionsMu.Unlock()
+			logger.Warning(s.context.GetRuntimeContext(), "GRAPHITE_ALARM", "too many connections, reject", conn.RemoteAddr().String())
+			_ = conn.Close()
+			continue
+		}
+		s.connections[conn] = struct{}{}
+		s.connectionsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *ServiceGraphite) handle(conn net.Conn) {
+	defer func() {
+		s.connectionsMu.Lock()
+		delete(s.connections, conn)
+		s.connectionsMu.Unlock()
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+	var err error
+	if s.Format == formatPickle {
+		err = s.readPickle(conn)
+	} else {
+		err = s.readPlaintext(conn)
+	}
+	var netErr net.Error
+	if err != nil && err != io.EOF && !errors.Is(err, net.ErrClosed) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		logger.Warning(s.context.GetRuntimeContext(), "GRAPHITE_ALARM", "read failed", err, "remote", conn.RemoteAddr().String())
+	}
+}
+
+// readPlaintext reads the lines of "<path> <value> [timestamp]", and collects the points once the buffered lines are consumed.
+func (s *ServiceGraphite) readPlaintext(conn net.Conn) error {
+	reader := bufio.NewReaderSize(conn, s.MaxMessageSize)
+	var points []*point
+	for {
+		s.resetTimeout(conn)
+		line, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			s.collect(points)
+			return fmt.Errorf("line exceeds the max message size %d", s.MaxMessageSize)
+		}
+		if p := s.parseLine(string(line)); p != nil {
+			points = append(points, p)
+		}
+		if err != nil {
+			s.collect(points)
+			return err
+		}
+		if reader.Buffered() == 0 {
+			s.collect(points)
+			points = nil
+		}
+	}
+}
+
+// readPickle reads the pickle payloads prefixed with the 4 bytes big-endian length.
+func (s *ServiceGraphite) readPickle(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	header := make([]byte, 4)
+	for {
+		s.resetTimeout(conn)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return err
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > uint32(s.MaxMessageSize) {
+			return fmt.Errorf("pickle payload size %d exceeds the max message size %d", size, s.MaxMessageSize)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return err
+		}
+		points, err := s.parsePickle(payload)
+		if err != nil {
+			return err
+		}
+		s.collect(points)
+	}
+}
+
+func (s *ServiceGraphite) resetTimeout(conn net.Conn) {
+	if s.TimeoutSeconds > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Duration(s.TimeoutSeconds) * time.Second))
+	}
+}
+
+func (s *ServiceGraphite) parseLine(line string) *point {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	p, err := s.parsePlaintext(fields)
+	if err != nil {
+		s.alarm(err)
+		return nil
+	}
+	return p
+}
+
+func (s *ServiceGraphite) parsePlaintext(fields []string) (*point, error) {
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("invalid line %q, expect <path> <value> [timestamp]", strings.Join(fields, " "))
+	}
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of %s: %v", fields[0], err)
+	}
+	timestamp := time.Now()
+	if len(fields) == 3 {
+		unixTime, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp of %s: %v", fields[0], err)
+		}
+		timestamp = toTime(unixTime)
+	}
+	return s.newPoint(fields[0], value, timestamp)
+}
+
+// parsePickle parses the payload of [(path, (timestamp, value)), ...], the invalid datapoints are skipped.
+func (s *ServiceGraphite) parsePickle(payload []byte) ([]*point, error) {
+	obj, err := unpickle(payload)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := obj.(*pickleList)
+	if !ok {
+		return nil, fmt.Errorf("invalid pickle payload %T, expect list", obj)
+	}
+	points := make([]*point, 0, len(list.items))
+	for _, item := range list.items {
+		p, err := s.parseDatapoint(item)
+		if err != nil {
+			s.alarm(err)
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func (s *ServiceGraphite) parseDatapoint(item interface{}) (*point, error) {
+	metric := sequence(item)
+	if len(metric) != 2 {
+		return nil, fmt.Errorf("invalid pickle datapoint %v", item)
+	}
+	path, ok := metric[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid pickle metric path %v", metric[0])
+	}
+	datapoint := sequence(metric[1])
+	if len(datapoint) != 2 {
+		return nil, fmt.Errorf("invalid pickle datapoint of %s", path)
+	}
+	unixTime, err := toFloat(datapoint[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp of %s: %v", path, err)
+	}
+	value, err := toFloat(datapoint[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of %s: %v", path, err)
+	}
+	return s.newPoint(path, value, toTime(unixTime))
+}
+
+// newPoint applies the templates to the path, and parses the tags of the graphite tagged series, such as "cpu;host=a".
+func (s *ServiceGraphite) newPoint(path string, value float64, timestamp time.Time) (*point, error) {
+	parts := strings.Split(path, ";")
+	measurement, tags, field, err := s.parser.ApplyTemplate(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if measurement == "" {
+		measurement = parts[0]
+	}
+	if field != "" {
+		measurement += s.parser.Separator + field
+	}
+	for _, tag := range parts[1:] {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" || strings.ContainsAny(kv[0], "!^") || strings.HasPrefix(kv[1], "~") {
+			continue
+		}
+		tags[kv[0]] = kv[1]
+	}
+	return &point{name: measurement, tags: tags, value: value, timestamp: timestamp}, nil
+}
+
+func (s *ServiceGraphite) collect(points []*point) {
+	if len(points) == 0 {
+		return
+	}
+	switch s.version {
+	case v1:
+		for _, p := range points {
+			var labels helper.MetricLabels
+			labels.AppendMap(p.tags)
+			s.collector.AddRawLog(helper.NewMetricLog(p.name, p.timestamp.UnixNano(), p.value, &labels))
+		}
+	case v2:
+		group := &models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+			Events: make([]models.PipelineEvent, 0, len(points)),
+		}
+		for _, p := range points {
+			metric := models.NewSingleValueMetric(p.name, models.MetricTypeGauge, models.NewTagsWithMap(p.tags), p.timestamp.UnixNano(), p.value)
+			group.Events = append(group.Events, metric)
+		}
+		s.collectorV2.CollectList(group)
+	}
+}
+
+func (s *ServiceGraphite) alarm(err error) {
+	logger.Debug(s.context.GetRuntimeContext(), "parse graphite metric error", err)
+	s.alarmMu.Lock()
+	defer s.alarmMu.Unlock()
+	if time.Since(s.lastAlarm) > 10*time.Second {
+		logger.Warning(s.context.GetRuntimeContext(), "GRAPHITE_PARSE_ALARM", "parse err", err)
+		s.lastAlarm = time.Now()
+	}
+}
+
+// toTime converts the unix seconds with the fractional part, -1 means now.
+func toTime(unixTime float64) time.Time {
+	if unixTime == -1 {
+		return time.Now()
+	}
+	sec := int64(unixTime)
+	return time.Unix(sec, int64((unixTime-float64(sec))*float64(time.Second)))
+}
+
+func sequence(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case *pickleList:
+		return t.items
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int64:
+		return float64(t), nil
+	case string:
+		return strconv.ParseFloat(t, 64)
+	}
+	return 0, fmt.Errorf("unexpected type %T", v)
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceGraphite) Stop() error {
+	if s.done == nil {
+		return nil
+	}
+	close(s.done)
+	if s.packetConn != nil {
+		_ = s.packetConn.Close()
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+		s.connectionsMu.Lock()
+		for conn := range s.connections {
+			_ = conn.Close()
+		}
+		s.connectionsMu.Unlock()
+	}
+	s.wg.Wait()
+	logger.Info(s.context.GetRuntimeContext(), "graphite server stop", s.Address)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceGraphite{
+			Address:        "127.0.0.1:2003",
+			Network:        "tcp",
+			Format:         formatPlaintext,
+			Separator:      graphite.DefaultSeparator,
+			MaxConnections: 1000,
+			MaxMessageSize: 1024 * 1024,
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+// generated by pickle.dumps([('servers.web01.cpu.load', (1700000000, 1.5)), ('disk.used;host=db01;dc=hz', (1700000000.25, 42)), ('bad', 'x')])
+var pickles = map[string]string{
+	"protocol 0": "286c70300a2856736572766572732e77656230312e6370752e6c6f61640a70310a2849313730303030303030300a46312e350a7470320a74" +
+		"70330a6128566469736b2e757365643b686f73743d646230313b64633d687a0a70340a2846313730303030303030302e32350a4934320a7470350a" +
+		"7470360a6128566261640a70370a56780a70380a7470390a612e",
+	"protocol 2": "80025d7100285816000000736572766572732e77656230312e6370752e6c6f616471014a00f15365473ff80000000000008671028671035819" +
+		"0000006469736b2e757365643b686f73743d646230313b64633d687a71044741d954fc401000004b2a867105867106580300000062616471075801" +
+		"000000787108867109652e",
+	"protocol 4": "80049567000000000000005d94288c16736572766572732e77656230312e6370752e6c6f6164944a00f15365473ff8000000000000869486948c" +
+		"196469736b2e757365643b686f73743d646230313b64633d687a944741d954fc401000004b2a869486948c03626164948c0178948694652e",
+}
+
+func newInput() (*ServiceGraphite, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceGraphite)
+	s.Address = "127.0.0.1:0"
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+func receive(t *testing.T, ctx pipeline.PipelineContext, count int) []*models.Metric {
+	var metrics []*models.Metric
+	timeout := time.After(5 * time.Second)
+	for len(metrics) < count {
+		select {
+		case group := <-ctx.Collector().Observe():
+			for _, e := range group.Events {
+				metrics = append(metrics, e.(*models.Metric))
+			}
+		case <-timeout:
+			require.FailNow(t, "receive metrics timeout", "expect %d, got %d", count, len(metrics))
+		}
+	}
+	return metrics
+}
+
+func TestParsePlaintext(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Templates = []string{"servers.* .host.measurement*", "measurement* region=hz"}
+	s.DefaultTags = map[string]string{"env": "prod", "host": "default"}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+
+	p := s.parseLine("servers.web01.cpu.load 1.5 1700000000\n")
+	require.NotNil(t, p)
+	assert.Equal(t, "cpu.load", p.name)
+	assert.Equal(t, map[string]string{"host": "web01", "env": "prod"}, p.tags)
+	assert.Equal(t, 1.5, p.value)
+	assert.Equal(t, time.Unix(1700000000, 0), p.timestamp)
+
+	p = s.parseLine("disk.used;host=db01;dc=hz;=x;bad 42 1700000000.25")
+	require.NotNil(t, p)
+	assert.Equal(t, "disk.used", p.name)
+	assert.Equal(t, map[string]string{"host": "db01", "dc": "hz", "env": "prod", "region": "hz"}, p.tags)
+	assert.Equal(t, time.Unix(1700000000, 250000000), p.timestamp)
+
+	p = s.parseLine("mem.free 3 -1")
+	require.NotNil(t, p)
+	assert.WithinDuration(t, time.Now(), p.timestamp, time.Minute)
+	p = s.parseLine("mem.free 3")
+	require.NotNil(t, p)
+	assert.WithinDuration(t, time.Now(), p.timestamp, time.Minute)
+
+	for _, line := range []string{"", "  \r\n", "mem.free", "mem.free abc", "mem.free 1 abc", "mem.free 1 2 3"} {
+		assert.Nil(t, s.parseLine(line), line)
+	}
+}
+
+func TestParsePickle(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	for name, data := range pickles {
+		payload, err := hex.DecodeString(data)
+		require.NoError(t, err)
+		points, err := s.parsePickle(payload)
+		require.NoError(t, err, name)
+		require.Len(t, points, 2, name)
+		assert.Equal(t, "servers.web01.cpu.load", points[0].name, name)
+		assert.Equal(t, 1.5, points[0].value, name)
+		assert.Equal(t, time.Unix(1700000000, 0), points[0].timestamp, name)
+		assert.Equal(t, "disk.used", points[1].name, name)
+		assert.Equal(t, map[string]string{"host": "db01", "dc": "hz"}, points[1].tags, name)
+		assert.Equal(t, 42.0, points[1].value, name)
+		assert.Equal(t, time.Unix(1700000000, 250000000), points[1].timestamp, name)
+	}
+
+	// the python 2 strings and the longs
+	points, err := s.parsePickle([]byte("(lp0\n(S\"it's\"\np1\n(L1700000000L\nS'2.5'\ntp2\ntp3\na(S'b'\n(I1\nI-3\nttag3\na."))
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, "it's", points[0].name)
+	assert.Equal(t, 2.5, points[0].value)
+	assert.Equal(t, "b", points[1].name)
+	assert.Equal(t, -3.0, points[1].value)
+	assert.Equal(t, "it's", points[2].name)
+	assert.Equal(t, 2.5, points[2].value)
+	payload, _ := hex.DecodeString("80025d71002858010000006171014a00f153658a0600000000000186710286710358010000006271044a00f153654ad4feffff867105867106652e")
+	points, err = s.parsePickle(payload)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, float64(1<<40), points[0].value)
+	assert.Equal(t, -300.0, points[1].value)
+
+	for _, invalid := range []string{
+		"cos\nsystem\n(S'echo'\ntR.",
+		"(lp0\n(S'a'\n",
+		"(S'a'\nI1\nt.",
+		"\x80\x02]q\x00X\xff\xff\xff\x7fabc",
+		"a.",
+	} {
+		_, err = s.parsePickle([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPlaintextTCP(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Templates = []string{"servers.* .host.measurement*"}
+	s.MaxMessageSize = 64
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("servers.web01.cpu 1 1700000000\ninvalid\nservers.web02.cpu 2 1700000000\n"))
+	require.NoError(t, err)
+	metrics := receive(t, ctx, 2)
+	assert.Equal(t, "cpu", metrics[0].GetName())
+	assert.Equal(t, "web01", metrics[0].GetTags().Get("host"))
+	assert.Equal(t, 1.0, metrics[0].GetValue().GetSingleValue())
+	assert.Equal(t, uint64(1700000000000000000), metrics[0].GetTimestamp())
+	assert.Equal(t, "web02", metrics[1].GetTags().Get("host"))
+
+	// the line longer than MaxMessageSize closes the connection
+	_, err = conn.Write([]byte("servers.web01.cpu 1 1700000000 " + string(make([]byte, 64)) + "\n"))
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	_ = conn.Close()
+}
+
+func TestPickleTCP(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Format = formatPickle
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	payload, _ := hex.DecodeString(pickles["protocol 2"])
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	for i := 0; i < 2; i++ {
+		_, err = conn.Write(append(header, payload...))
+		require.NoError(t, err)
+	}
+	metrics := receive(t, ctx, 4)
+	assert.Equal(t, "servers.web01.cpu.load", metrics[2].GetName())
+	assert.Equal(t, "db01", metrics[3].GetTags().Get("host"))
+
+	// the payload larger than MaxMessageSize closes the connection
+	binary.BigEndian.PutUint32(header, uint32(s.MaxMessageSize+1))
+	_, err = conn.Write(header)
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestCollectV1(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Format = formatPickle
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.version = v1
+	payload, _ := hex.DecodeString(pickles["protocol 2"])
+	points, err := s.parsePickle(payload)
+	require.NoError(t, err)
+	s.collect(points)
+
+	require.Len(t, collector.RawLogs, 2)
+	assert.Equal(t, "servers.web01.cpu.load", test.ReadLogVal(collector.RawLogs[0], "__name__"))
+	assert.Equal(t, "1.5", test.ReadLogVal(collector.RawLogs[0], "__value__"))
+	assert.Equal(t, "dc#$#hz|host#$#db01", test.ReadLogVal(collector.RawLogs[1], "__labels__"))
+	assert.Equal(t, "1700000000250000000", test.ReadLogVal(collector.RawLogs[1], "__time_nano__"))
+}
+
+func TestPlaintextUDP(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Network = "udp"
+	s.DefaultTags = map[string]string{"env": "prod"}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.packetConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("a.b 1 1700000000\nc.d;x=y 2 1700000000"))
+	require.NoError(t, err)
+	metrics := receive(t, ctx, 2)
+	assert.Equal(t, "a.b", metrics[0].GetName())
+	assert.Equal(t, "prod", metrics[0].GetTags().Get("env"))
+	assert.Equal(t, "c.d", metrics[1].GetName())
+	assert.Equal(t, "y", metrics[1].GetTags().Get("x"))
+}
+
+func TestInit(t *testing.T) {
+	for _, s := range []*ServiceGraphite{
+		{Network: "unix"},
+		{Format: "json"},
+		{Network: "udp", Format: formatPickle},
+		{Templates: []string{"a.b.c host.region"}},
+	} {
+		_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+		assert.Error(t, err)
+	}
+	s := &ServiceGraphite{}
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", s.Network)
+	assert.Equal(t, formatPlaintext, s.Format)
+	assert.Equal(t, 1024*1024, s.MaxMessageSize)
+}
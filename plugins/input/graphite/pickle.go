@@ -0,0 +1,442 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// The opcodes of the pickle protocols used to serialize the graphite datapoints,
+// the opcodes to build arbitrary objects, such as GLOBAL and REDUCE, are never supported.
+const (
+	opMark            = '('
+	opStop            = '.'
+	opPop             = '0'
+	opDup             = '2'
+	opFloat           = 'F'
+	opInt             = 'I'
+	opBinInt          = 'J'
+	opBinInt1         = 'K'
+	opLong            = 'L'
+	opBinInt2         = 'M'
+	opNone            = 'N'
+	opString          = 'S'
+	opBinString       = 'T'
+	opShortBinString  = 'U'
+	opUnicode         = 'V'
+	opBinUnicode      = 'X'
+	opAppend          = 'a'
+	opGet             = 'g'
+	opBinGet          = 'h'
+	opLongBinGet      = 'j'
+	opList            = 'l'
+	opPut             = 'p'
+	opBinPut          = 'q'
+	opLongBinPut      = 'r'
+	opTuple           = 't'
+	opEmptyList       = ']'
+	opAppends         = 'e'
+	opEmptyTuple      = ')'
+	opBinFloat        = 'G'
+	opBinBytes        = 'B'
+	opShortBinBytes   = 'C'
+	opProto           = 0x80
+	opTuple1          = 0x85
+	opTuple2          = 0x86
+	opTuple3          = 0x87
+	opNewTrue         = 0x88
+	opNewFalse        = 0x89
+	opLong1           = 0x8a
+	opShortBinUnicode = 0x8c
+	opBinUnicode8     = 0x8d
+	opMemoize         = 0x94
+	opFrame           = 0x95
+)
+
+var errPickleTruncated = errors.New("pickle data is truncated")
+
+// pickleList is a python list, which is referenced by the memo and modified by the append opcodes.
+type pickleList struct {
+	items []interface{}
+}
+
+type mark struct{}
+
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []interface{}
+	memo  map[int]interface{}
+}
+
+// unpickle decodes the lists, tuples, strings and numbers serialized with the pickle protocols 0 to 5.
+func unpickle(data []byte) (interface{}, error) {
+	u := &unpickler{data: data, memo: make(map[int]interface{})}
+	for {
+		op, err := u.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if op == opStop {
+			if len(u.stack) != 1 {
+				return nil, errors.New("invalid pickle stack at stop")
+			}
+			return u.stack[0], nil
+		}
+		if err = u.dispatch(op); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (u *unpickler) dispatch(op byte) error {
+	switch op {
+	case opProto:
+		_, err := u.read(1)
+		return err
+	case opFrame:
+		_, err := u.read(8)
+		return err
+	case opMark:
+		u.push(mark{})
+	case opPop:
+		_, err := u.pop()
+		return err
+	case opDup:
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.push(top)
+	case opNone:
+		u.push(nil)
+	case opNewTrue:
+		u.push(true)
+	case opNewFalse:
+		u.push(false)
+	case opInt, opLong:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		return u.pushInt(op, line)
+	case opFloat:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return err
+		}
+		u.push(f)
+	case opBinInt:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		u.push(int64(int32(binary.LittleEndian.Uint32(b))))
+	case opBinInt1:
+		b, err := u.read(1)
+		if err != nil {
+			return err
+		}
+		u.push(int64(b[0]))
+	case opBinInt2:
+		b, err := u.read(2)
+		if err != nil {
+			return err
+		}
+		u.push(int64(binary.LittleEndian.Uint16(b)))
+	case opLong1:
+		n, err := u.readByte()
+		if err != nil {
+			return err
+		}
+		b, err := u.read(int(n))
+		if err != nil {
+			return err
+		}
+		v, err := decodeLong(b)
+		if err != nil {
+			return err
+		}
+		u.push(v)
+	case opBinFloat:
+		b, err := u.read(8)
+		if err != nil {
+			return err
+		}
+		u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	case opString:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		s, err := unquote(line)
+		if err != nil {
+			return err
+		}
+		u.push(s)
+	case opUnicode:
+		line, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		u.push(line)
+	case opShortBinString, opShortBinBytes, opShortBinUnicode:
+		return u.pushString(1)
+	case opBinString, opBinBytes, opBinUnicode:
+		return u.pushString(4)
+	case opBinUnicode8:
+		return u.pushString(8)
+	case opEmptyList:
+		u.push(&pickleList{})
+	case opEmptyTuple:
+		u.push([]interface{}{})
+	case opList:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(&pickleList{items: items})
+	case opTuple:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(items)
+	case opTuple1, opTuple2, opTuple3:
+		n := int(op-opTuple1) + 1
+		if len(u.stack) < n {
+			return errors.New("pickle stack underflow")
+		}
+		items := make([]interface{}, n)
+		copy(items, u.stack[len(u.stack)-n:])
+		u.stack = u.stack[:len(u.stack)-n]
+		u.push(items)
+	case opAppend:
+		item, err := u.pop()
+		if err != nil {
+			return err
+		}
+		return u.appendItems(item)
+	case opAppends:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		return u.appendItems(items...)
+	case opPut, opBinPut, opLongBinPut, opMemoize:
+		idx, err := u.memoIndex(op)
+		if err != nil {
+			return err
+		}
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.memo[idx] = top
+	case opGet, opBinGet, opLongBinGet:
+		idx, err := u.memoIndex(op)
+		if err != nil {
+			return err
+		}
+		v, ok := u.memo[idx]
+		if !ok {
+			return fmt.Errorf("pickle memo %d not found", idx)
+		}
+		u.push(v)
+	default:
+		return fmt.Errorf("unsupported pickle opcode 0x%x", op)
+	}
+	return nil
+}
+
+func (u *unpickler) memoIndex(op byte) (int, error) {
+	switch op {
+	case opMemoize:
+		return len(u.memo), nil
+	case opPut, opGet:
+		line, err := u.readLine()
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(line)
+	case opBinPut, opBinGet:
+		b, err := u.read(1)
+		if err != nil {
+			return 0, err
+		}
+		return int(b[0]), nil
+	default:
+		b, err := u.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.LittleEndian.Uint32(b)), nil
+	}
+}
+
+func (u *unpickler) pushInt(op byte, line string) error {
+	if op == opInt {
+		switch line {
+		case "00":
+			u.push(false)
+			return nil
+		case "01":
+			u.push(true)
+			return nil
+		}
+	}
+	v, err := strconv.ParseInt(strings.TrimSuffix(line, "L"), 10, 64)
+	if err != nil {
+		return err
+	}
+	u.push(v)
+	return nil
+}
+
+func (u *unpickler) pushString(lengthSize int) error {
+	b, err := u.read(lengthSize)
+	if err != nil {
+		return err
+	}
+	var n uint64
+	switch lengthSize {
+	case 1:
+		n = uint64(b[0])
+	case 4:
+		n = uint64(binary.LittleEndian.Uint32(b))
+	default:
+		n = binary.LittleEndian.Uint64(b)
+	}
+	if n > uint64(len(u.data)-u.pos) {
+		return errPickleTruncated
+	}
+	s, err := u.read(int(n))
+	if err != nil {
+		return err
+	}
+	u.push(string(s))
+	return nil
+}
+
+func (u *unpickler) appendItems(items ...interface{}) error {
+	top, err := u.top()
+	if err != nil {
+		return err
+	}
+	list, ok := top.(*pickleList)
+	if !ok {
+		return errors.New("pickle append to non-list")
+	}
+	list.items = append(list.items, items...)
+	return nil
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, errors.New("pickle stack underflow")
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	v, err := u.top()
+	if err == nil {
+		u.stack = u.stack[:len(u.stack)-1]
+	}
+	return v, err
+}
+
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(mark); ok {
+			items := make([]interface{}, len(u.stack)-i-1)
+			copy(items, u.stack[i+1:])
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("pickle mark not found")
+}
+
+func (u *unpickler) readByte() (byte, error) {
+	b, err := u.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n < 0 || u.pos+n > len(u.data) {
+		return nil, errPickleTruncated
+	}
+	b := u.data[u.pos : u.pos+n]
+	u.pos += n
+	return b, nil
+}
+
+func (u *unpickler) readLine() (string, error) {
+	idx := bytes.IndexByte(u.data[u.pos:], '\n')
+	if idx < 0 {
+		return "", errPickleTruncated
+	}
+	line := string(u.data[u.pos : u.pos+idx])
+	u.pos += idx + 1
+	return line, nil
+}
+
+// decodeLong decodes the little-endian two's complement integers no longer than 8 bytes.
+func decodeLong(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if len(b) > 8 {
+		return 0, errors.New("pickle long overflows int64")
+	}
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	if b[len(b)-1]&0x80 != 0 && len(b) < 8 {
+		v |= math.MaxUint64 << (8 * uint(len(b)))
+	}
+	return int64(v), nil
+}
+
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+		if !strings.Contains(s, "\\") {
+			return s, nil
+		}
+		s = strings.ReplaceAll(s, "\\'", "'")
+		return strconv.Unquote("\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\"")
+	}
+	return "", fmt.Errorf("invalid pickle string %q", s)
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package haproxy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	metricPrefix = "haproxy_"
+
+	showStatCommand = "show stat\n"
+)
+
+// proxy types in the type column of the stats CSV.
+var proxyTypes = map[string]string{
+	"0": "frontend",
+	"1": "backend",
+	"2": "server",
+	"3": "listener",
+}
+
+// identityColumns are not exported as metrics.
+var identityColumns = map[string]struct{}{
+	"pxname": {},
+	"svname": {},
+	"type":   {},
+	"iid":    {},
+	"sid":    {},
+	"pid":    {},
+}
+
+// InputHAProxy polls the HAProxy stats, either from the HTTP stats page in CSV format or from the stats socket,
+// and normalizes them to metrics with frontend/backend/server labels.
+type InputHAProxy struct {
+	// Servers are the stats endpoints, e.g. "http://127.0.0.1:8404/stats;csv", "unix:///var/run/haproxy.sock", "tcp://127.0.0.1:9999"
+	Servers            []string
+	Username           string
+	Password           string
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	ResponseTimeoutMs  int
+	Labels             map[string]string
+
+	client  *http.Client
+	context pipeline.Context
+}
+
+func (h *InputHAProxy) Init(context pipeline.Context) (int, error) {
+	h.context = context
+	if len(h.Servers) == 0 {
+		return 0, fmt.Errorf("no haproxy stats server configured")
+	}
+	if h.ResponseTimeoutMs <= 0 {
+		h.ResponseTimeoutMs = 5000
+	}
+	tlsCfg, err := util.GetTLSConfig(h.SSLCert, h.SSLKey, h.SSLCA, h.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	h.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   h.timeout(),
+	}
+	return 0, nil
+}
+
+func (h *InputHAProxy) Description() string {
+	return "collect the stats of haproxy from the stats page or the stats socket"
+}
+
+func (h *InputHAProxy) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, server := range h.Servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if err := h.gatherServer(server, collector); err != nil {
+				logger.Error(h.context.GetRuntimeContext(), "HAPROXY_COLLECT_ALARM", "server", server, "error", err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (h *InputHAProxy) timeout() time.Duration {
+	return time.Duration(h.ResponseTimeoutMs) * time.Millisecond
+}
+
+func (h *InputHAProxy) gatherServer(server string, collector pipeline.Collector) error {
+	u, err := url.Parse(server)
+	if err != nil {
+		return err
+	}
+	var body io.ReadCloser
+	switch u.Scheme {
+	case "http", "https":
+		body, err = h.fetchHTTP(u)
+	case "unix":
+		body, err = h.fetchSocket("unix", u.Path)
+	case "tcp":
+		body, err = h.fetchSocket("tcp", u.Host)
+	case "":
+		// a bare path is regarded as the unix stats socket.
+		body, err = h.fetchSocket("unix", u.Path)
+	default:
+		return fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return h.parseStats(body, instanceOf(u), time.Now(), collector)
+}
+
+func (h *InputHAProxy) fetchHTTP(u *url.URL) (io.ReadCloser, error) {
+	// the CSV export is requested by the ";csv" suffix, e.g. "/stats;csv" or "/haproxy?stats;csv"
+	switch {
+	case u.RawQuery != "":
+		if !strings.HasSuffix(u.RawQuery, ";csv") {
+			u.RawQuery += ";csv"
+		}
+	case u.Path == "":
+		u.Path = "/;csv"
+	case !strings.HasSuffix(u.Path, ";csv"):
+		u.Path += ";csv"
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.Username != "" || h.Password != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned HTTP status %s", u.String(), resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (h *InputHAProxy) fetchSocket(network, address string) (io.ReadCloser, error) {
+	conn, err := net.DialTimeout(network, address, h.timeout())
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(h.timeout()))
+	if _, err = conn.Write([]byte(showStatCommand)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (h *InputHAProxy) parseStats(r io.Reader, instance string, now time.Time, collector pipeline.Collector) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	if len(header) == 0 || !strings.HasPrefix(header[0], "#") {
+		return fmt.Errorf("invalid stats header %v", header)
+	}
+	header[0] = strings.TrimSpace(strings.TrimPrefix(header[0], "#"))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		h.addRecord(header, record, instance, now, collector)
+	}
+}
+
+func (h *InputHAProxy) addRecord(header, record []string, instance string, now time.Time, collector pipeline.Collector) {
+	row := make(map[string]string, len(header))
+	for i, column := range header {
+		if i < len(record) && column != "" {
+			row[column] = record[i]
+		}
+	}
+	proxyType, ok := proxyTypes[row["type"]]
+	if !ok {
+		return
+	}
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", instance)
+	labels.Append("type", proxyType)
+	switch proxyType {
+	case "frontend":
+		labels.Append("frontend", row["pxname"])
+	case "backend":
+		labels.Append("backend", row["pxname"])
+	default:
+		labels.Append("proxy", row["pxname"])
+		labels.Append("server", row["svname"])
+	}
+	for k, v := range h.Labels {
+		labels.Append(k, v)
+	}
+
+	timestamp := now.UnixNano()
+	if status, ok := row["status"]; ok && status != "" {
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", timestamp, statusValue(status), labels))
+	}
+	for _, column := range header {
+		if _, skip := identityColumns[column]; skip {
+			continue
+		}
+		value, err := strconv.ParseFloat(row[column], 64)
+		if err != nil {
+			// empty or textual columns, e.g. status and check_status
+			continue
+		}
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+column, timestamp, value, labels))
+	}
+}
+
+// statusValue returns 1 for the healthy status, e.g. "UP", "UP 1/3", "OPEN" and "no check".
+func statusValue(status string) float64 {
+	if strings.HasPrefix(status, "UP") || status == "OPEN" || status == "no check" {
+		return 1
+	}
+	return 0
+}
+
+func instanceOf(u *url.URL) string {
+	if u.Host != "" {
+		return u.Host
+	}
+	return u.Path
+}
+
+func init() {
+	pipeline.MetricInputs["metric_haproxy"] = func() pipeline.MetricInput {
+		return &InputHAProxy{
+			ResponseTimeoutMs: 5000,
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package haproxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const statsCSV = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,status,weight,pid,iid,sid,type,check_status
+http-in,FRONTEND,,,3,10,2000,120,1024,2048,OPEN,,1,2,0,0,
+servers,web1,0,0,1,5,,60,512,1024,UP,1,1,3,1,2,L4OK
+servers,web2,0,0,0,5,,60,512,1024,DOWN,1,1,3,2,2,L4CON
+servers,BACKEND,0,0,1,10,200,120,1024,2048,UP,2,1,3,0,1,
+`
+
+func TestHAProxyHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/stats;csv", r.URL.Path)
+		_, _ = w.Write([]byte(statsCSV))
+	}))
+	defer server.Close()
+
+	h := &InputHAProxy{Servers: []string{server.URL + "/stats"}, Username: "admin", Password: "pass", Labels: map[string]string{"cluster": "c1"}}
+	_, err := h.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, h.Collect(collector))
+
+	metrics := test.MetricSamples(collector.Logs)
+	require.Len(t, metrics["haproxy_scur"], 4)
+	assert.NotContains(t, metrics, "haproxy_pid")
+	assert.NotContains(t, metrics, "haproxy_status")
+	instance := server.Listener.Addr().String()
+	assert.Contains(t, metrics["haproxy_scur"], test.MetricSample{Labels: "cluster#$#c1|frontend#$#http-in|instance#$#" + instance + "|type#$#frontend", Value: "3"})
+	assert.Contains(t, metrics["haproxy_up"], test.MetricSample{Labels: "cluster#$#c1|instance#$#" + instance + "|proxy#$#servers|server#$#web2|type#$#server", Value: "0"})
+	assert.Contains(t, metrics["haproxy_up"], test.MetricSample{Labels: "backend#$#servers|cluster#$#c1|instance#$#" + instance + "|type#$#backend", Value: "1"})
+}
+
+func TestHAProxySocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "haproxy.sock")
+	listener, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line == showStatCommand {
+			_, _ = conn.Write([]byte(statsCSV))
+		}
+	}()
+
+	h := &InputHAProxy{Servers: []string{"unix://" + sock}}
+	_, err = h.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, h.Collect(collector))
+	assert.Len(t, test.MetricSamples(collector.Logs)["haproxy_stot"], 4)
+}
+
+func TestHAProxyInit(t *testing.T) {
+	h := &InputHAProxy{}
+	_, err := h.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+}
@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"net/url"
 	"strings"
 	"sync"
 	"syscall"
@@ -52,27 +51,34 @@ type ServiceHTTP struct {
 	version     int8
 	paramCount  int
 	dumper      *helper.Dumper
+	shared      *sharedServer
 
-	DumpDataKeepFiles  int
-	DumpData           bool   // would dump the received data to a local file, which is only used to valid data by the developers.
-	Decoder            string // the decoder to use, default is "ext_default_decoder"
-	Format             string
-	Address            string
-	Path               string
-	ReadTimeoutSec     int
-	ShutdownTimeoutSec int
-	MaxBodySize        int64
-	UnlinkUnixSock     bool
-	FieldsExtend       bool
-	DisableUncompress  bool
-	AllowUnsafeMode    bool
-	Tags               map[string]string // todo for v2
+	DumpDataKeepFiles   int
+	DumpData            bool   // would dump the received data to a local file, which is only used to valid data by the developers.
+	Decoder             string // the decoder to use, default is "ext_default_decoder"
+	Format              string
+	Address             string
+	Path                string
+	ReadTimeoutSec      int
+	ShutdownTimeoutSec  int
+	MaxBodySize         int64
+	MaxDecompressedSize int64 // the max size of the body decompressed by the Content-Encoding header, default is MaxBodySize
+	UnlinkUnixSock      bool
+	FieldsExtend        bool
+	DisableUncompress   bool
+	AllowUnsafeMode     bool
+	Tags                map[string]string // todo for v2
 
 	// params below works only for version v2
 	QueryParams       []string
 	HeaderParams      []string
 	QueryParamPrefix  string
 	HeaderParamPrefix string
+
+	// Tenants route the requests by the API key in the APIKeyHeader, the requests without a valid key are rejected.
+	// The inputs with tenants in different pipelines can listen on the same address.
+	Tenants      []Tenant
+	APIKeyHeader string
 }
 
 // Init ...
@@ -81,15 +87,17 @@ func (s *ServiceHTTP) Init(context pipeline.Context) (int, error) {
 	var err error
 
 	options := &struct {
-		Format            string
-		FieldsExtend      bool
-		DisableUncompress bool
-		AllowUnsafeMode   bool
+		Format              string
+		FieldsExtend        bool
+		DisableUncompress   bool
+		AllowUnsafeMode     bool
+		MaxDecompressedSize int64
 	}{
-		Format:            s.Format,
-		FieldsExtend:      s.FieldsExtend,
-		DisableUncompress: s.DisableUncompress,
-		AllowUnsafeMode:   s.AllowUnsafeMode,
+		Format:              s.Format,
+		FieldsExtend:        s.FieldsExtend,
+		DisableUncompress:   s.DisableUncompress,
+		AllowUnsafeMode:     s.AllowUnsafeMode,
+		MaxDecompressedSize: s.MaxDecompressedSize,
 	}
 	ext, err := context.GetExtension(s.Decoder, options)
 	if err != nil {
@@ -118,6 +126,10 @@ func (s *ServiceHTTP) Init(context pipeline.Context) (int, error) {
 
 	s.paramCount = len(s.QueryParams) + len(s.HeaderParams)
 
+	if err = s.initTenants(); err != nil {
+		return 0, err
+	}
+
 	if s.DumpData {
 		s.dumper = helper.NewDumper(strings.Join([]string{name, context.GetProject(), context.GetConfigName()}, "-"), s.DumpDataKeepFiles)
 		s.dumper.Init()
@@ -136,11 +148,42 @@ func (s *ServiceHTTP) Collect(pipeline.Collector) error {
 	return nil
 }
 
+func (s *ServiceHTTP) initTenants() error {
+	if len(s.Tenants) == 0 {
+		return nil
+	}
+	if s.APIKeyHeader == "" {
+		s.APIKeyHeader = defaultAPIKeyHeader
+	}
+	keys := make(map[string]bool)
+	for _, tenant := range s.Tenants {
+		if tenant.Name == "" || len(tenant.APIKeys) == 0 {
+			return fmt.Errorf("tenant must have a name and at least one api key")
+		}
+		for _, key := range tenant.APIKeys {
+			if key == "" || keys[key] {
+				return fmt.Errorf("empty or duplicated api key in tenant %s", tenant.Name)
+			}
+			keys[key] = true
+		}
+	}
+	return nil
+}
+
 func (s *ServiceHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, nil)
+}
+
+// serve handles the request, the route is not nil if the input serves tenants.
+func (s *ServiceHTTP) serve(w http.ResponseWriter, r *http.Request, route *tenantRoute) {
 	if r.ContentLength > s.MaxBodySize {
 		TooLarge(w)
 		return
 	}
+	if route != nil && !route.requests.allow(1, time.Now()) {
+		TooManyRequests(w)
+		return
+	}
 	data, statusCode, err := s.decoder.ParseRequest(w, r, s.MaxBodySize)
 	logger.Debugf(s.context.GetRuntimeContext(), "request [method] %v; [header] %v; [url] %v; [body len] %d", r.Method, r.Header, r.URL, len(data))
 	switch statusCode {
@@ -148,6 +191,8 @@ func (s *ServiceHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		BadRequest(w)
 	case http.StatusRequestEntityTooLarge:
 		TooLarge(w)
+	case http.StatusUnsupportedMediaType:
+		UnsupportedMediaType(w)
 	case http.StatusInternalServerError:
 		InternalServerError(w)
 	case http.StatusMethodNotAllowed:
@@ -158,6 +203,12 @@ func (s *ServiceHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if route != nil && !route.bytes.allow(float64(len(data)), time.Now()) {
+		logger.Warning(s.context.GetRuntimeContext(), "TENANT_QUOTA_ALARM", "tenant", route.tenant.Name, "request rejected", "bytes quota exceeded")
+		TooManyRequests(w)
+		return
+	}
+
 	if s.dumper != nil {
 		s.dumper.InputChannel() <- &helper.DumpData{
 			Req: helper.DumpDataReq{
@@ -169,7 +220,17 @@ func (s *ServiceHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	switch s.version {
 	case v1:
-		logs, err := s.decoder.Decode(data, r, s.Tags)
+		tags := s.Tags
+		if route != nil && len(route.tenant.Tags) > 0 {
+			tags = make(map[string]string, len(s.Tags)+len(route.tenant.Tags))
+			for k, v := range s.Tags {
+				tags[k] = v
+			}
+			for k, v := range route.tenant.Tags {
+				tags[k] = v
+			}
+		}
+		logs, err := s.decoder.Decode(data, r, tags)
 		if err != nil {
 			logger.Warning(s.context.GetRuntimeContext(), "DECODE_BODY_FAIL_ALARM", "decode body failed", err, "request", r.URL.String())
 			BadRequest(w)
@@ -190,6 +251,11 @@ func (s *ServiceHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				g.Group.Metadata.Merge(models.NewMetadataWithMap(reqParams))
 			}
 		}
+		if route != nil && len(route.tenant.Tags) > 0 {
+			for _, g := range groups {
+				g.Group.Tags.Merge(models.NewTagsWithMap(route.tenant.Tags))
+			}
+		}
 		s.collectorV2.CollectList(groups...)
 	}
 
@@ -210,6 +276,13 @@ func TooLarge(res http.ResponseWriter) {
 	_, _ = res.Write([]byte(`{"error":"http: request body too large"}`))
 }
 
+func UnsupportedMediaType(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Accept-Encoding", strings.Join(common.SupportedEncodings, ", "))
+	res.WriteHeader(http.StatusUnsupportedMediaType)
+	_, _ = res.Write([]byte(`{"error":"http: unsupported content encoding"}`))
+}
+
 func MethodNotAllowed(res http.ResponseWriter) {
 	res.Header().Set("Content-Type", "application/json")
 	res.WriteHeader(http.StatusMethodNotAllowed)
@@ -242,6 +315,16 @@ func (s *ServiceHTTP) StartService(context pipeline.PipelineContext) error {
 }
 
 func (s *ServiceHTTP) start() error {
+	if len(s.Tenants) > 0 {
+		if err := s.startShared(); err != nil {
+			return err
+		}
+		logger.Info(s.context.GetRuntimeContext(), "http server with tenants start", s.Address, "listener", s.listener.Addr().String())
+		if s.dumper != nil {
+			s.dumper.Start()
+		}
+		return nil
+	}
 	s.wg.Add(1)
 
 	server := &http.Server{
@@ -249,26 +332,14 @@ func (s *ServiceHTTP) start() error {
 		Handler:     s,
 		ReadTimeout: time.Duration(s.ReadTimeoutSec) * time.Second,
 	}
-	var listener net.Listener
-	var err error
-	switch {
-	case strings.HasPrefix(s.Address, "unix"):
-		sockPath := strings.Replace(s.Address, "unix://", "", 1)
-		if s.UnlinkUnixSock {
-			_ = syscall.Unlink(sockPath)
-		}
-		listener, err = net.Listen("unix", sockPath)
-	case strings.HasPrefix(s.Address, "http") ||
-		strings.HasPrefix(s.Address, "https") ||
-		strings.HasPrefix(s.Address, "tcp"):
-		configURL, errAddr := url.Parse(s.Address)
-		if errAddr != nil {
-			return errAddr
-		}
-		listener, err = net.Listen("tcp", configURL.Host)
-	default:
-		listener, err = net.Listen("tcp", s.Address)
+	network, address, err := s.listenAddress()
+	if err != nil {
+		return err
+	}
+	if network == "unix" && s.UnlinkUnixSock {
+		_ = syscall.Unlink(address)
 	}
+	listener, err := net.Listen(network, address)
 	if err != nil {
 		return err
 	}
@@ -292,6 +363,15 @@ func (s *ServiceHTTP) start() error {
 	return nil
 }
 
+func (s *ServiceHTTP) listenAddress() (network string, address string, err error) {
+	switch {
+	case strings.HasPrefix(s.Address, "unix"):
+		return "unix", strings.Replace(s.Address, "unix://", "", 1), nil
+	default:
+		return helper.ParseListenAddress(s.Address)
+	}
+}
+
 func (s *ServiceHTTP) extractRequestParams(req *http.Request) map[string]string {
 	keyValues := make(map[string]string, s.paramCount)
 	for _, key := range s.QueryParams {
@@ -325,7 +405,10 @@ func (s *ServiceHTTP) extractRequestParams(req *http.Request) map[string]string
 
 // Stop stops the services and closes any necessary channels and connections
 func (s *ServiceHTTP) Stop() error {
-	if s.listener != nil {
+	if s.shared != nil {
+		s.stopShared()
+		logger.Info(s.context.GetRuntimeContext(), "http server with tenants stop", s.Address)
+	} else if s.listener != nil {
 		_ = s.listener.Close()
 		logger.Info(s.context.GetRuntimeContext(), "http server stop", s.Address)
 		s.wg.Wait()
@@ -25,10 +25,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
 
 	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
 	pluginmanager "github.com/alibaba/ilogtail/pluginmanager"
@@ -416,3 +418,35 @@ func TestInputWithRequestParamsWithoutPrefix(t *testing.T) {
 	}
 
 }
+
+func TestInputWithCompressedBody(t *testing.T) {
+	input, err := newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.MaxDecompressedSize = 1024
+	})
+	require.NoError(t, err)
+	inputCtx := helper.NewObservePipelineConext(10)
+	require.NoError(t, input.StartService(inputCtx))
+	defer input.Stop()
+	port := input.listener.Addr().(*net.TCPAddr).Port
+
+	post := func(data []byte, encoding string) int {
+		var buf bytes.Buffer
+		w, _ := zstd.NewWriter(&buf)
+		_, _ = w.Write(data)
+		_ = w.Close()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/", port), &buf)
+		req.Header.Set("Content-Encoding", encoding)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusNoContent, post([]byte("hello"), "zstd"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(bytes.Repeat([]byte("a"), 2048), "zstd"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, post([]byte("hello"), "br"))
+
+	time.Sleep(time.Millisecond * 100)
+	res := inputCtx.Collector().ToArray()
+	require.Len(t, res, 1)
+	assert.Equal(t, "hello", string(res[0].Events[0].(models.ByteArray)))
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultAPIKeyHeader = "X-API-Key"
+
+// Tenant maps the API keys to the tags added to the received data, and limits the requests of the tenant.
+type Tenant struct {
+	Name              string
+	APIKeys           []string
+	Tags              map[string]string
+	MaxRequestsPerSec float64 // no limit if not positive
+	MaxBytesPerSec    float64 // no limit if not positive, the size is counted after the body is decompressed
+}
+
+// tenantRoute is the target of an API key, i.e. the input of the pipeline and the tenant.
+type tenantRoute struct {
+	input    *ServiceHTTP
+	tenant   *Tenant
+	requests *quota
+	bytes    *quota
+}
+
+// quota is a token bucket allowing one second burst. A request is allowed if the tokens are enough for it or
+// the bucket is full, and the tokens may become negative after it, so the requests larger than the rate are
+// not always rejected.
+type quota struct {
+	lock    sync.Mutex
+	rate    float64
+	tokens  float64
+	updated time.Time
+}
+
+func newQuota(rate float64) *quota {
+	if rate <= 0 {
+		return nil
+	}
+	return &quota{rate: rate, tokens: rate, updated: time.Now()}
+}
+
+func (q *quota) allow(n float64, now time.Time) bool {
+	if q == nil {
+		return true
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if now.After(q.updated) {
+		q.tokens += now.Sub(q.updated).Seconds() * q.rate
+		if q.tokens > q.rate {
+			q.tokens = q.rate
+		}
+		q.updated = now
+	}
+	if q.tokens < math.Min(n, q.rate) {
+		return false
+	}
+	q.tokens -= n
+	return true
+}
+
+// sharedServer listens on an address for all the inputs with tenants on it, and dispatches the requests
+// to the input of the pipeline by the API key, so that many pipelines can share one port.
+type sharedServer struct {
+	key      string
+	server   *http.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	lock    sync.RWMutex
+	routes  map[string]*tenantRoute
+	headers map[string]int // the API key headers of the inputs with reference counts
+	inputs  int
+}
+
+var (
+	sharedServersLock sync.Mutex
+	sharedServers     = make(map[string]*sharedServer)
+)
+
+func (s *sharedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := s.route(r)
+	if route == nil {
+		Unauthorized(w)
+		return
+	}
+	route.input.serve(w, r, route)
+}
+
+func (s *sharedServer) route(r *http.Request) *tenantRoute {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for header := range s.headers {
+		key := strings.TrimSpace(strings.TrimPrefix(r.Header.Get(header), "Bearer "))
+		if key == "" {
+			continue
+		}
+		if route, ok := s.routes[key]; ok {
+			return route
+		}
+	}
+	return nil
+}
+
+func (s *sharedServer) register(input *ServiceHTTP) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, tenant := range input.Tenants {
+		for _, key := range tenant.APIKeys {
+			if route, ok := s.routes[key]; ok {
+				return fmt.Errorf("api key of tenant %s is already used by tenant %s of config %s",
+					tenant.Name, route.tenant.Name, route.input.context.GetConfigName())
+			}
+		}
+	}
+	for i := range input.Tenants {
+		tenant := &input.Tenants[i]
+		route := &tenantRoute{
+			input:    input,
+			tenant:   tenant,
+			requests: newQuota(tenant.MaxRequestsPerSec),
+			bytes:    newQuota(tenant.MaxBytesPerSec),
+		}
+		for _, key := range tenant.APIKeys {
+			s.routes[key] = route
+		}
+	}
+	s.headers[input.APIKeyHeader]++
+	s.inputs++
+	return nil
+}
+
+// unregister removes the routes of the input, and returns whether no input is left.
+func (s *sharedServer) unregister(input *ServiceHTTP) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, route := range s.routes {
+		if route.input == input {
+			delete(s.routes, key)
+		}
+	}
+	if s.headers[input.APIKeyHeader]--; s.headers[input.APIKeyHeader] <= 0 {
+		delete(s.headers, input.APIKeyHeader)
+	}
+	s.inputs--
+	return s.inputs == 0
+}
+
+// startShared registers the input to the shared server of its address, the server is started by the first input.
+func (s *ServiceHTTP) startShared() error {
+	network, address, err := s.listenAddress()
+	if err != nil {
+		return err
+	}
+	key := network + "://" + address
+	sharedServersLock.Lock()
+	defer sharedServersLock.Unlock()
+	shared, ok := sharedServers[key]
+	if !ok {
+		if network == "unix" && s.UnlinkUnixSock {
+			_ = syscall.Unlink(address)
+		}
+		listener, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		shared = &sharedServer{
+			key:      key,
+			listener: listener,
+			routes:   make(map[string]*tenantRoute),
+			headers:  make(map[string]int),
+		}
+		shared.server = &http.Server{
+			Handler:     shared,
+			ReadTimeout: time.Duration(s.ReadTimeoutSec) * time.Second,
+		}
+		shared.wg.Add(1)
+		go func() {
+			defer shared.wg.Done()
+			_ = shared.server.Serve(listener)
+		}()
+		sharedServers[key] = shared
+	}
+	if err = shared.register(s); err != nil {
+		if !ok {
+			shared.close(s.ShutdownTimeoutSec)
+			delete(sharedServers, key)
+		}
+		return err
+	}
+	s.shared = shared
+	s.listener = shared.listener
+	return nil
+}
+
+func (s *ServiceHTTP) stopShared() {
+	sharedServersLock.Lock()
+	defer sharedServersLock.Unlock()
+	if s.shared.unregister(s) {
+		s.shared.close(s.ShutdownTimeoutSec)
+		delete(sharedServers, s.shared.key)
+	}
+	s.shared = nil
+}
+
+func (s *sharedServer) close(timeoutSec int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+	_ = s.server.Shutdown(ctx)
+	s.wg.Wait()
+}
+
+func Unauthorized(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusUnauthorized)
+	_, _ = res.Write([]byte(`{"error":"http: invalid api key"}`))
+}
+
+func TooManyRequests(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Retry-After", "1")
+	res.WriteHeader(http.StatusTooManyRequests)
+	_, _ = res.Write([]byte(`{"error":"http: tenant quota exceeded"}`))
+}
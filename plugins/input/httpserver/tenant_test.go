@@ -0,0 +1,117 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+)
+
+func postWithKey(t *testing.T, port int, header, key string) int {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/", port), bytes.NewBufferString("hello"))
+	require.NoError(t, err)
+	if key != "" {
+		req.Header.Set(header, key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestInputWithTenants(t *testing.T) {
+	teamA, err := newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.Address = "127.0.0.1:0"
+		input.Tenants = []Tenant{{Name: "a", APIKeys: []string{"key-a"}, Tags: map[string]string{"team": "a"}, MaxRequestsPerSec: 1}}
+	})
+	require.NoError(t, err)
+	teamB, err := newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.Address = "127.0.0.1:0"
+		input.APIKeyHeader = "Authorization"
+		input.Tenants = []Tenant{{Name: "b", APIKeys: []string{"key-b1", "key-b2"}, Tags: map[string]string{"team": "b"}}}
+	})
+	require.NoError(t, err)
+	ctxA := helper.NewObservePipelineConext(10)
+	ctxB := helper.NewObservePipelineConext(10)
+	require.NoError(t, teamA.StartService(ctxA))
+	require.NoError(t, teamB.StartService(ctxB))
+	assert.Equal(t, teamA.listener, teamB.listener)
+	port := teamA.listener.Addr().(*net.TCPAddr).Port
+
+	assert.Equal(t, http.StatusUnauthorized, postWithKey(t, port, defaultAPIKeyHeader, ""))
+	assert.Equal(t, http.StatusUnauthorized, postWithKey(t, port, defaultAPIKeyHeader, "unknown"))
+	assert.Equal(t, http.StatusNoContent, postWithKey(t, port, defaultAPIKeyHeader, "key-a"))
+	assert.Equal(t, http.StatusTooManyRequests, postWithKey(t, port, defaultAPIKeyHeader, "key-a"))
+	assert.Equal(t, http.StatusNoContent, postWithKey(t, port, "Authorization", "Bearer key-b1"))
+	assert.Equal(t, http.StatusNoContent, postWithKey(t, port, "Authorization", "key-b2"))
+
+	time.Sleep(time.Millisecond * 100)
+	groupsA := ctxA.Collector().ToArray()
+	require.Len(t, groupsA, 1)
+	assert.Equal(t, "a", groupsA[0].Group.Tags.Get("team"))
+	groupsB := ctxB.Collector().ToArray()
+	require.Len(t, groupsB, 2)
+	for _, g := range groupsB {
+		assert.Equal(t, "b", g.Group.Tags.Get("team"))
+	}
+
+	// the key is already used by another pipeline
+	conflict, err := newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.Address = "127.0.0.1:0"
+		input.Tenants = []Tenant{{Name: "c", APIKeys: []string{"key-a"}}}
+	})
+	require.NoError(t, err)
+	assert.Error(t, conflict.StartService(helper.NewObservePipelineConext(10)))
+
+	// the port is kept until the last input stops
+	require.NoError(t, teamA.Stop())
+	assert.Equal(t, http.StatusUnauthorized, postWithKey(t, port, defaultAPIKeyHeader, "key-a"))
+	assert.Equal(t, http.StatusNoContent, postWithKey(t, port, "Authorization", "key-b1"))
+	require.NoError(t, teamB.Stop())
+	_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.Error(t, err)
+	assert.Empty(t, sharedServers)
+}
+
+func TestInitTenants(t *testing.T) {
+	_, err := newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.Tenants = []Tenant{{Name: "a"}}
+	})
+	assert.Error(t, err)
+	_, err = newInputWithOpts("raw", func(input *ServiceHTTP) {
+		input.Tenants = []Tenant{{Name: "a", APIKeys: []string{"k"}}, {Name: "b", APIKeys: []string{"k"}}}
+	})
+	assert.Error(t, err)
+}
+
+func TestQuota(t *testing.T) {
+	now := time.Now()
+	q := newQuota(100)
+	assert.True(t, q.allow(300, now))
+	assert.False(t, q.allow(1, now))
+	assert.False(t, q.allow(1, now.Add(time.Second)))
+	assert.True(t, q.allow(1, now.Add(time.Second*3)))
+	assert.Nil(t, newQuota(0))
+	assert.True(t, newQuota(0).allow(1, now))
+}
@@ -44,8 +44,8 @@ func (i *InstanceInner) Hash() string {
 }
 
 func NewInstanceInner(port int32, host, user, passowrd string, tags map[string]string, defaultJvmMetrics bool) *InstanceInner {
-	var hostname, instance string
-	_ = util.InitFromEnvString("_node_name_", &hostname, util.GetHostName())
+	var instance string
+	hostname := util.GetHostName()
 	tags["hostname"] = hostname
 
 	if host == "localhost" || host == "127.0.0.1" {
@@ -0,0 +1,320 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkametrics
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const metricPrefix = "kafka_"
+
+// kafkaClient is the subset of sarama.Client used by the input.
+type kafkaClient interface {
+	Brokers() []*sarama.Broker
+	Topics() ([]string, error)
+	Partitions(topic string) ([]int32, error)
+	Replicas(topic string, partitionID int32) ([]int32, error)
+	InSyncReplicas(topic string, partitionID int32) ([]int32, error)
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+}
+
+// kafkaAdmin is the subset of sarama.ClusterAdmin used by the input, closing it closes the client too.
+type kafkaAdmin interface {
+	ListConsumerGroups() (map[string]string, error)
+	DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error)
+	ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error)
+	Close() error
+}
+
+func newSaramaClients(brokers []string, config *sarama.Config) (kafkaClient, kafkaAdmin, error) {
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	return client, admin, nil
+}
+
+// InputKafkaMetrics collects the broker, topic offset and consumer group lag metrics of kafka by the admin client.
+type InputKafkaMetrics struct {
+	Brokers            []string
+	Version            string
+	ClientID           string
+	SASLUsername       string
+	SASLPassword       string
+	EnableTLS          bool
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	TopicPattern       string // regex to select the topics, the internal topics starting with "__" are excluded if empty
+	GroupPattern       string // regex to select the consumer groups, all groups are selected if empty
+	PartitionMetrics   bool   // export the metrics of each partition besides the topic and group totals
+	TimeoutMs          int
+	Labels             map[string]string
+
+	topicRegex *regexp.Regexp
+	groupRegex *regexp.Regexp
+	config     *sarama.Config
+	newClients func(brokers []string, config *sarama.Config) (kafkaClient, kafkaAdmin, error)
+	context    pipeline.Context
+}
+
+func (k *InputKafkaMetrics) Init(context pipeline.Context) (int, error) {
+	k.context = context
+	if len(k.Brokers) == 0 {
+		return 0, fmt.Errorf("must specify Brokers for plugin metric_kafka")
+	}
+	if k.TimeoutMs <= 0 {
+		k.TimeoutMs = 10000
+	}
+	var err error
+	if k.TopicPattern != "" {
+		if k.topicRegex, err = regexp.Compile(k.TopicPattern); err != nil {
+			return 0, fmt.Errorf("invalid topic pattern: %v", err)
+		}
+	}
+	if k.GroupPattern != "" {
+		if k.groupRegex, err = regexp.Compile(k.GroupPattern); err != nil {
+			return 0, fmt.Errorf("invalid group pattern: %v", err)
+		}
+	}
+
+	config := sarama.NewConfig()
+	if k.Version != "" {
+		if config.Version, err = sarama.ParseKafkaVersion(k.Version); err != nil {
+			return 0, err
+		}
+	}
+	if k.ClientID != "" {
+		config.ClientID = k.ClientID
+	}
+	timeout := time.Duration(k.TimeoutMs) * time.Millisecond
+	config.Net.DialTimeout = timeout
+	config.Net.ReadTimeout = timeout
+	config.Net.WriteTimeout = timeout
+	config.Admin.Timeout = timeout
+	config.Metadata.Retry.Max = 1
+	if k.SASLUsername != "" && k.SASLPassword != "" {
+		config.Net.SASL.User = k.SASLUsername
+		config.Net.SASL.Password = k.SASLPassword
+		config.Net.SASL.Enable = true
+	}
+	if k.EnableTLS {
+		if config.Net.TLS.Config, err = util.GetTLSConfig(k.SSLCert, k.SSLKey, k.SSLCA, k.SkipInsecureVerify); err != nil {
+			return 0, err
+		}
+		config.Net.TLS.Enable = true
+	}
+	k.config = config
+	if k.newClients == nil {
+		k.newClients = newSaramaClients
+	}
+	return 0, nil
+}
+
+func (k *InputKafkaMetrics) Description() string {
+	return "collect the broker, topic and consumer group lag metrics of kafka"
+}
+
+// Collect connects to the cluster on each collection, because the metric inputs are never stopped.
+func (k *InputKafkaMetrics) Collect(collector pipeline.Collector) error {
+	labels := &helper.MetricLabels{}
+	labels.Append("brokers", strings.Join(k.Brokers, ","))
+	for key, v := range k.Labels {
+		labels.Append(key, v)
+	}
+	now := time.Now().UnixNano()
+	client, admin, err := k.newClients(k.Brokers, k.config)
+	if err != nil {
+		logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "connect error", err)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 0, labels))
+		return nil
+	}
+	defer admin.Close() //nolint:errcheck
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 1, labels))
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"brokers", now, float64(len(client.Brokers())), labels))
+
+	offsets := &newestOffsets{client: client, offsets: make(map[string]map[int32]int64)}
+	k.collectTopics(client, offsets, labels, now, collector)
+	k.collectGroups(admin, offsets, labels, now, collector)
+	return nil
+}
+
+func (k *InputKafkaMetrics) selectTopic(topic string) bool {
+	if k.topicRegex == nil {
+		return !strings.HasPrefix(topic, "__")
+	}
+	return k.topicRegex.MatchString(topic)
+}
+
+func (k *InputKafkaMetrics) collectTopics(client kafkaClient, offsets *newestOffsets, labels *helper.MetricLabels, now int64, collector pipeline.Collector) {
+	topics, err := client.Topics()
+	if err != nil {
+		logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "list topics error", err)
+		return
+	}
+	for _, topic := range topics {
+		if !k.selectTopic(topic) {
+			continue
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "topic", topic, "list partitions error", err)
+			continue
+		}
+		topicLabels := labels.Clone()
+		topicLabels.Append("topic", topic)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"topic_partitions", now, float64(len(partitions)), topicLabels))
+		underReplicated := 0
+		for _, partition := range partitions {
+			replicas, replicasErr := client.Replicas(topic, partition)
+			isr, isrErr := client.InSyncReplicas(topic, partition)
+			if replicasErr == nil && isrErr == nil && len(isr) < len(replicas) {
+				underReplicated++
+			}
+			if !k.PartitionMetrics {
+				continue
+			}
+			partitionLabels := topicLabels.Clone()
+			partitionLabels.Append("partition", strconv.Itoa(int(partition)))
+			if newest, err := offsets.get(topic, partition); err == nil {
+				collector.AddRawLog(helper.NewMetricLog(metricPrefix+"topic_partition_current_offset", now, float64(newest), partitionLabels))
+			}
+			if oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest); err == nil {
+				collector.AddRawLog(helper.NewMetricLog(metricPrefix+"topic_partition_oldest_offset", now, float64(oldest), partitionLabels))
+			}
+			if isrErr == nil {
+				collector.AddRawLog(helper.NewMetricLog(metricPrefix+"topic_partition_in_sync_replicas", now, float64(len(isr)), partitionLabels))
+			}
+		}
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"topic_under_replicated_partitions", now, float64(underReplicated), topicLabels))
+	}
+}
+
+func (k *InputKafkaMetrics) collectGroups(admin kafkaAdmin, offsets *newestOffsets, labels *helper.MetricLabels, now int64, collector pipeline.Collector) {
+	allGroups, err := admin.ListConsumerGroups()
+	if err != nil {
+		logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "list consumer groups error", err)
+		return
+	}
+	var groups []string
+	for group := range allGroups {
+		if k.groupRegex == nil || k.groupRegex.MatchString(group) {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return
+	}
+	descriptions, err := admin.DescribeConsumerGroups(groups)
+	if err != nil {
+		logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "describe consumer groups error", err)
+	}
+	for _, description := range descriptions {
+		groupLabels := labels.Clone()
+		groupLabels.Append("group", description.GroupId)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"consumergroup_members", now, float64(len(description.Members)), groupLabels))
+		stateLabels := groupLabels.Clone()
+		stateLabels.Append("state", description.State)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"consumergroup_state", now, 1, stateLabels))
+	}
+
+	for _, group := range groups {
+		resp, err := admin.ListConsumerGroupOffsets(group, nil)
+		if err != nil {
+			logger.Warning(k.context.GetRuntimeContext(), "KAFKA_METRICS_COLLECT_ALARM", "group", group, "list offsets error", err)
+			continue
+		}
+		for topic, partitions := range resp.Blocks {
+			topicLabels := labels.Clone()
+			topicLabels.Append("group", group)
+			topicLabels.Append("topic", topic)
+			var lagSum int64
+			committed := false
+			for partition, block := range partitions {
+				// the offset is -1 if the group has not committed for the partition
+				if block == nil || block.Err != sarama.ErrNoError || block.Offset < 0 {
+					continue
+				}
+				newest, err := offsets.get(topic, partition)
+				if err != nil {
+					continue
+				}
+				lag := newest - block.Offset
+				if lag < 0 {
+					lag = 0
+				}
+				lagSum += lag
+				committed = true
+				if k.PartitionMetrics {
+					partitionLabels := topicLabels.Clone()
+					partitionLabels.Append("partition", strconv.Itoa(int(partition)))
+					collector.AddRawLog(helper.NewMetricLog(metricPrefix+"consumergroup_current_offset", now, float64(block.Offset), partitionLabels))
+					collector.AddRawLog(helper.NewMetricLog(metricPrefix+"consumergroup_lag", now, float64(lag), partitionLabels))
+				}
+			}
+			if committed {
+				collector.AddRawLog(helper.NewMetricLog(metricPrefix+"consumergroup_lag_sum", now, float64(lagSum), topicLabels))
+			}
+		}
+	}
+}
+
+// newestOffsets caches the newest offsets within a collection, so that each partition is queried once.
+type newestOffsets struct {
+	client  kafkaClient
+	offsets map[string]map[int32]int64
+}
+
+func (o *newestOffsets) get(topic string, partition int32) (int64, error) {
+	if offset, ok := o.offsets[topic][partition]; ok {
+		return offset, nil
+	}
+	offset, err := o.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+	if o.offsets[topic] == nil {
+		o.offsets[topic] = make(map[int32]int64)
+	}
+	o.offsets[topic][partition] = offset
+	return offset, nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_kafka"] = func() pipeline.MetricInput {
+		return &InputKafkaMetrics{
+			ClientID:         "ilogtail_kafka_metrics",
+			PartitionMetrics: true,
+			TimeoutMs:        10000,
+		}
+	}
+}
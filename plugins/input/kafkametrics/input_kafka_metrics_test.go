@@ -0,0 +1,145 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkametrics
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+type fakeClient struct {
+	partitions map[string][]int32
+	newest     map[string]int64
+	closed     bool
+}
+
+func (c *fakeClient) Brokers() []*sarama.Broker {
+	return []*sarama.Broker{sarama.NewBroker("b1:9092"), sarama.NewBroker("b2:9092")}
+}
+
+func (c *fakeClient) Topics() ([]string, error) {
+	var topics []string
+	for topic := range c.partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+func (c *fakeClient) Partitions(topic string) ([]int32, error) {
+	return c.partitions[topic], nil
+}
+
+func (c *fakeClient) Replicas(topic string, partitionID int32) ([]int32, error) {
+	return []int32{1, 2}, nil
+}
+
+func (c *fakeClient) InSyncReplicas(topic string, partitionID int32) ([]int32, error) {
+	if topic == "orders" && partitionID == 1 {
+		return []int32{1}, nil
+	}
+	return []int32{1, 2}, nil
+}
+
+func (c *fakeClient) GetOffset(topic string, partitionID int32, time int64) (int64, error) {
+	if time == sarama.OffsetOldest {
+		return 0, nil
+	}
+	offset, ok := c.newest[fmt.Sprintf("%s-%d", topic, partitionID)]
+	if !ok {
+		return 0, sarama.ErrUnknownTopicOrPartition
+	}
+	return offset, nil
+}
+
+func (c *fakeClient) ListConsumerGroups() (map[string]string, error) {
+	return map[string]string{"billing": "consumer", "audit": "consumer"}, nil
+}
+
+func (c *fakeClient) DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error) {
+	var res []*sarama.GroupDescription
+	for _, group := range groups {
+		res = append(res, &sarama.GroupDescription{GroupId: group, State: "Stable", Members: map[string]*sarama.GroupMemberDescription{"m1": {}}})
+	}
+	return res, nil
+}
+
+func (c *fakeClient) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	resp := &sarama.OffsetFetchResponse{}
+	resp.AddBlock("orders", 0, &sarama.OffsetFetchResponseBlock{Offset: 90})
+	resp.AddBlock("orders", 1, &sarama.OffsetFetchResponseBlock{Offset: -1})
+	resp.AddBlock("__consumer_offsets", 0, &sarama.OffsetFetchResponseBlock{Offset: 5})
+	return resp, nil
+}
+
+func (c *fakeClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestKafkaMetricsCollect(t *testing.T) {
+	client := &fakeClient{
+		partitions: map[string][]int32{"orders": {0, 1}, "__consumer_offsets": {0}},
+		newest:     map[string]int64{"orders-0": 100, "orders-1": 50, "__consumer_offsets-0": 10},
+	}
+	input := &InputKafkaMetrics{Brokers: []string{"b1:9092"}, GroupPattern: "^bill", PartitionMetrics: true}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	input.newClients = func(brokers []string, config *sarama.Config) (kafkaClient, kafkaAdmin, error) {
+		return client, client, nil
+	}
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.True(t, client.closed)
+
+	labels := "brokers#$#b1:9092"
+	orders := labels + "|topic#$#orders"
+	billing := labels + "|group#$#billing"
+	assert.Equal(t, []string{
+		"kafka_brokers{" + labels + "} 2",
+		"kafka_consumergroup_current_offset{" + billing + "|partition#$#0|topic#$#__consumer_offsets} 5",
+		"kafka_consumergroup_current_offset{" + billing + "|partition#$#0|topic#$#orders} 90",
+		"kafka_consumergroup_lag_sum{" + billing + "|topic#$#__consumer_offsets} 5",
+		"kafka_consumergroup_lag_sum{" + billing + "|topic#$#orders} 10",
+		"kafka_consumergroup_lag{" + billing + "|partition#$#0|topic#$#__consumer_offsets} 5",
+		"kafka_consumergroup_lag{" + billing + "|partition#$#0|topic#$#orders} 10",
+		"kafka_consumergroup_members{" + billing + "} 1",
+		"kafka_consumergroup_state{" + billing + "|state#$#Stable} 1",
+		"kafka_topic_partition_current_offset{" + labels + "|partition#$#0|topic#$#orders} 100",
+		"kafka_topic_partition_current_offset{" + labels + "|partition#$#1|topic#$#orders} 50",
+		"kafka_topic_partition_in_sync_replicas{" + labels + "|partition#$#0|topic#$#orders} 2",
+		"kafka_topic_partition_in_sync_replicas{" + labels + "|partition#$#1|topic#$#orders} 1",
+		"kafka_topic_partition_oldest_offset{" + labels + "|partition#$#0|topic#$#orders} 0",
+		"kafka_topic_partition_oldest_offset{" + labels + "|partition#$#1|topic#$#orders} 0",
+		"kafka_topic_partitions{" + orders + "} 2",
+		"kafka_topic_under_replicated_partitions{" + orders + "} 1",
+		"kafka_up{" + labels + "} 1",
+	}, test.MetricLines(collector.Logs))
+
+	input.newClients = func(brokers []string, config *sarama.Config) (kafkaClient, kafkaAdmin, error) {
+		return nil, nil, sarama.ErrOutOfBrokers
+	}
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{"kafka_up{" + labels + "} 0"}, test.MetricLines(collector.Logs))
+}
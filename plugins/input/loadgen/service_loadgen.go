@@ -0,0 +1,259 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const pluginType = "service_loadgen"
+
+const (
+	distributionUniform = "uniform"
+	distributionZipf    = "zipf"
+	zipfS               = 1.1
+)
+
+const (
+	v1 = iota
+	v2
+)
+
+// tickInterval is the interval the events are generated in, the events of a second are spread over the ticks.
+const tickInterval = 100 * time.Millisecond
+
+// reportInterval is the interval the achieved rate is logged.
+const reportInterval = time.Minute
+
+// ServiceLoadGen generates the synthetic events from the templates at the configured rate, so that the processors
+// and the flushers can be benchmarked on the target hardware before rollout.
+type ServiceLoadGen struct {
+	EventsPerSecond int               // The target rate of the events, default is 1000.
+	MaxEvents       int64             // The generation stops after the number of events, 0 means never.
+	Template        map[string]string // The fields of the events, the values are the templates with the placeholders.
+	Tags            map[string]string // The tags of the events, the values are the templates with the placeholders.
+	Cardinality     map[string]int    // The number of the distinct values of each ${card:NAME} placeholder.
+	Distribution    string            // The distribution the ${card:NAME} values are picked by, uniform or zipf.
+	PayloadMinBytes int               // The min size of the ${payload} placeholder.
+	PayloadMaxBytes int               // The max size of the ${payload} placeholder.
+	Seed            int64             // The seed of the random values, 0 means seeded by the time.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	keys        []string
+	fields      []template
+	tagKeys     []string
+	tags        []template
+	generator   *generator
+	now         func() time.Time
+	shutdown    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// Init ...
+func (s *ServiceLoadGen) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if s.EventsPerSecond <= 0 {
+		return 0, fmt.Errorf("EventsPerSecond must be positive for plugin %v", pluginType)
+	}
+	if len(s.Template) == 0 {
+		return 0, fmt.Errorf("must specify Template for plugin %v", pluginType)
+	}
+	if s.Distribution != distributionUniform && s.Distribution != distributionZipf {
+		return 0, fmt.Errorf("invalid Distribution %q, must be %s or %s", s.Distribution, distributionUniform, distributionZipf)
+	}
+	if s.PayloadMinBytes < 0 || s.PayloadMaxBytes < s.PayloadMinBytes {
+		return 0, fmt.Errorf("invalid payload size [%d, %d]", s.PayloadMinBytes, s.PayloadMaxBytes)
+	}
+	var err error
+	if s.keys, s.fields, err = compileTemplates(s.Template, s.Cardinality); err != nil {
+		return 0, err
+	}
+	if s.tagKeys, s.tags, err = compileTemplates(s.Tags, s.Cardinality); err != nil {
+		return 0, err
+	}
+	seed := s.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s.generator = newGenerator(seed, s.Distribution, s.PayloadMinBytes, s.PayloadMaxBytes)
+	s.now = time.Now
+	return 0, nil
+}
+
+func compileTemplates(templates map[string]string, cardinality map[string]int) ([]string, []template, error) {
+	keys := make([]string, 0, len(templates))
+	for k := range templates {
+		keys = append(keys, k)
+	}
+	// sorted, so that the random values are reproducible with the same seed.
+	sort.Strings(keys)
+	compiled := make([]template, 0, len(keys))
+	for _, k := range keys {
+		t, err := compileTemplate(templates[k], cardinality)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid template of %s: %v", k, err)
+		}
+		compiled = append(compiled, t)
+	}
+	return keys, compiled, nil
+}
+
+// Description ...
+func (s *ServiceLoadGen) Description() string {
+	return "synthetic load generator input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceLoadGen) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceLoadGen) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceLoadGen) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceLoadGen) start() error {
+	s.shutdown = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+// run generates the events of each tick to catch up with the target rate since the start. The backlog is
+// dropped when the pipeline cannot keep up for more than a second, so that the load is not bursty after a stall.
+func (s *ServiceLoadGen) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	begin := s.now()
+	reportBegin, reportCount := begin, int64(0)
+	var expected, generated, skipped int64
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+		now := s.now()
+		expected = int64(now.Sub(begin).Seconds()*float64(s.EventsPerSecond)) - skipped
+		if lag := expected - generated; lag > int64(s.EventsPerSecond) {
+			skipped += lag - int64(s.EventsPerSecond)
+			expected = generated + int64(s.EventsPerSecond)
+			logger.Warning(s.context.GetRuntimeContext(), "LOADGEN_ALARM", "the pipeline cannot keep up with the target rate, events skipped", lag-int64(s.EventsPerSecond))
+		}
+		n := expected - generated
+		if s.MaxEvents > 0 && generated+n > s.MaxEvents {
+			n = s.MaxEvents - generated
+		}
+		s.generate(int(n), now)
+		generated += n
+		reportCount += n
+		if elapsed := now.Sub(reportBegin); elapsed >= reportInterval {
+			logger.Info(s.context.GetRuntimeContext(), "loadgen events", reportCount, "eps", float64(reportCount)/elapsed.Seconds())
+			reportBegin, reportCount = now, 0
+		}
+		if s.MaxEvents > 0 && generated >= s.MaxEvents {
+			logger.Info(s.context.GetRuntimeContext(), "loadgen events", generated, "done")
+			return
+		}
+	}
+}
+
+func (s *ServiceLoadGen) generate(n int, now time.Time) {
+	if n <= 0 {
+		return
+	}
+	g := s.generator
+	g.now = now
+	var group *models.PipelineGroupEvents
+	if s.version == v2 {
+		group = &models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+			Events: make([]models.PipelineEvent, 0, n),
+		}
+	}
+	for i := 0; i < n; i++ {
+		g.seq++
+		switch s.version {
+		case v1:
+			fields := make(map[string]string, len(s.keys))
+			for j, k := range s.keys {
+				fields[k] = g.render(s.fields[j])
+			}
+			var tags map[string]string
+			if len(s.tagKeys) > 0 {
+				tags = make(map[string]string, len(s.tagKeys))
+				for j, k := range s.tagKeys {
+					tags[k] = g.render(s.tags[j])
+				}
+			}
+			s.collector.AddData(tags, fields, now)
+		case v2:
+			tags := models.NewTags()
+			for j, k := range s.tagKeys {
+				tags.Add(k, g.render(s.tags[j]))
+			}
+			log := models.NewLog("", nil, "", "", "", tags, uint64(now.UnixNano()))
+			for j, k := range s.keys {
+				log.Contents.Add(k, g.render(s.fields[j]))
+			}
+			group.Events = append(group.Events, log)
+		}
+	}
+	if group != nil {
+		s.collectorV2.CollectList(group)
+	}
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceLoadGen) Stop() error {
+	if s.shutdown == nil {
+		return nil
+	}
+	close(s.shutdown)
+	s.wg.Wait()
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceLoadGen{
+			EventsPerSecond: 1000,
+			Distribution:    distributionUniform,
+			PayloadMinBytes: 64,
+			PayloadMaxBytes: 512,
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newInput() (*ServiceLoadGen, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceLoadGen)
+	s.Seed = 1
+	s.Template = map[string]string{
+		"seq":     "${seq}",
+		"level":   "${choice:INFO|WARN|ERROR}",
+		"latency": "${int:10:20}ms",
+		"user":    "${card:user}",
+		"msg":     "request ${seq}: ${payload}",
+	}
+	s.Cardinality = map[string]int{"user": 5}
+	s.PayloadMinBytes = 8
+	s.PayloadMaxBytes = 16
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+func TestLoadGenInit(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		modify func(s *ServiceLoadGen)
+	}{
+		{"no template", func(s *ServiceLoadGen) { s.Template = nil }},
+		{"zero rate", func(s *ServiceLoadGen) { s.EventsPerSecond = 0 }},
+		{"invalid distribution", func(s *ServiceLoadGen) { s.Distribution = "normal" }},
+		{"invalid payload", func(s *ServiceLoadGen) { s.PayloadMinBytes, s.PayloadMaxBytes = 10, 5 }},
+		{"unknown placeholder", func(s *ServiceLoadGen) { s.Template = map[string]string{"a": "${unknown}"} }},
+		{"unclosed placeholder", func(s *ServiceLoadGen) { s.Template = map[string]string{"a": "${seq"} }},
+		{"invalid int", func(s *ServiceLoadGen) { s.Template = map[string]string{"a": "${int:5:1}"} }},
+		{"unknown cardinality", func(s *ServiceLoadGen) { s.Tags = map[string]string{"a": "${card:host}"} }},
+	} {
+		s, err := newInput()
+		require.NoError(t, err)
+		c.modify(s)
+		_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+		assert.Error(t, err, c.name)
+	}
+}
+
+func TestLoadGenTemplate(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.generate(100, time.Unix(1700000000, 0))
+	require.Len(t, collector.Logs, 100)
+	users := map[string]bool{}
+	for i, log := range collector.Logs {
+		fields := log.Fields
+		assert.Equal(t, strconv.Itoa(i+1), fields["seq"])
+		assert.Contains(t, []string{"INFO", "WARN", "ERROR"}, fields["level"])
+		latency, err := strconv.Atoi(strings.TrimSuffix(fields["latency"], "ms"))
+		require.NoError(t, err)
+		assert.True(t, latency >= 10 && latency <= 20)
+		users[fields["user"]] = true
+		prefix := "request " + fields["seq"] + ": "
+		require.True(t, strings.HasPrefix(fields["msg"], prefix))
+		payload := len(fields["msg"]) - len(prefix)
+		assert.True(t, payload >= 8 && payload <= 16, payload)
+	}
+	assert.Equal(t, map[string]bool{"user-0": true, "user-1": true, "user-2": true, "user-3": true, "user-4": true}, users)
+
+	// reproducible with the same seed
+	other, err := newInput()
+	require.NoError(t, err)
+	otherCollector := &test.MockCollector{}
+	other.collector = otherCollector
+	other.generate(100, time.Unix(1700000000, 0))
+	assert.Equal(t, collector.Logs, otherCollector.Logs)
+}
+
+func TestLoadGenZipf(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.Template = map[string]string{"user": "${card:user}"}
+	s.Cardinality = map[string]int{"user": 100}
+	s.Distribution = distributionZipf
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.generate(10000, time.Now())
+	counts := map[string]int{}
+	for _, log := range collector.Logs {
+		counts[log.Fields["user"]]++
+	}
+	assert.Greater(t, counts["user-0"], counts["user-50"]*10)
+}
+
+func TestLoadGenRate(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.EventsPerSecond = 2000
+	s.MaxEvents = 500
+	s.Tags = map[string]string{"host": "host-${int:1:3}"}
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	begin := time.Now()
+	// stops itself after MaxEvents
+	s.wg.Wait()
+	elapsed := time.Since(begin)
+	require.NoError(t, s.Stop())
+	assert.True(t, elapsed >= 200*time.Millisecond, elapsed)
+
+	count := 0
+	for _, group := range ctx.Collector().ToArray() {
+		for _, event := range group.Events {
+			log := event.(*models.Log)
+			assert.Contains(t, []string{"host-1", "host-2", "host-3"}, log.GetTags().Get("host"))
+			assert.NotEmpty(t, log.Contents.Get("msg"))
+			count++
+		}
+	}
+	assert.Equal(t, 500, count)
+}
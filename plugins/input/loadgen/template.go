@@ -0,0 +1,193 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	placeholderBegin = "${"
+	placeholderEnd   = "}"
+)
+
+// segment is a part of a template, either a literal or a placeholder generating a value.
+type segment struct {
+	literal  string
+	generate func(g *generator, b *strings.Builder)
+}
+
+type template []segment
+
+// compileTemplate parses the placeholders of the template:
+//
+//	${seq}             the sequence number of the event, starting from 1
+//	${time}            the generation time in RFC3339 with nanoseconds
+//	${int:MIN:MAX}     a uniformly distributed integer in [MIN, MAX]
+//	${choice:A|B|C}    one of the options picked uniformly
+//	${card:NAME}       one of the values of the cardinality NAME, picked by the distribution
+//	${payload}         a random text sized between PayloadMinBytes and PayloadMaxBytes
+func compileTemplate(text string, cardinality map[string]int) (template, error) {
+	var t template
+	for len(text) > 0 {
+		begin := strings.Index(text, placeholderBegin)
+		if begin == -1 {
+			t = append(t, segment{literal: text})
+			break
+		}
+		if begin > 0 {
+			t = append(t, segment{literal: text[:begin]})
+		}
+		end := strings.Index(text[begin:], placeholderEnd)
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed placeholder in %q", text)
+		}
+		generate, err := compilePlaceholder(text[begin+len(placeholderBegin):begin+end], cardinality)
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, segment{generate: generate})
+		text = text[begin+end+len(placeholderEnd):]
+	}
+	return t, nil
+}
+
+func compilePlaceholder(placeholder string, cardinality map[string]int) (func(g *generator, b *strings.Builder), error) {
+	name, arg, _ := strings.Cut(placeholder, ":")
+	switch name {
+	case "seq":
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(strconv.FormatInt(g.seq, 10))
+		}, nil
+	case "time":
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(g.now.Format(time.RFC3339Nano))
+		}, nil
+	case "int":
+		minText, maxText, ok := strings.Cut(arg, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid placeholder %q, expect ${int:MIN:MAX}", placeholder)
+		}
+		minValue, err := strconv.ParseInt(minText, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid placeholder %q: %v", placeholder, err)
+		}
+		maxValue, err := strconv.ParseInt(maxText, 10, 64)
+		if err != nil || maxValue < minValue {
+			return nil, fmt.Errorf("invalid placeholder %q, MAX must be an integer not less than MIN", placeholder)
+		}
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(strconv.FormatInt(minValue+g.rand.Int63n(maxValue-minValue+1), 10))
+		}, nil
+	case "choice":
+		options := strings.Split(arg, "|")
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(options[g.rand.Intn(len(options))])
+		}, nil
+	case "card":
+		n, ok := cardinality[arg]
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("invalid placeholder %q, the cardinality %q must be configured positive", placeholder, arg)
+		}
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(arg)
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(g.pick(arg, n)))
+		}, nil
+	case "payload":
+		return func(g *generator, b *strings.Builder) {
+			b.WriteString(g.payload())
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown placeholder %q", placeholder)
+}
+
+// generator renders the templates of an event, it is not thread-safe.
+type generator struct {
+	rand         *rand.Rand
+	zipf         map[string]*rand.Zipf
+	distribution string
+	text         string // the random text the payloads are cut from
+	minPayload   int
+	maxPayload   int
+
+	seq int64
+	now time.Time
+	b   strings.Builder
+}
+
+const payloadChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+func newGenerator(seed int64, distribution string, minPayload, maxPayload int) *generator {
+	g := &generator{
+		rand:         rand.New(rand.NewSource(seed)), //nolint:gosec
+		zipf:         make(map[string]*rand.Zipf),
+		distribution: distribution,
+		minPayload:   minPayload,
+		maxPayload:   maxPayload,
+	}
+	if maxPayload > 0 {
+		text := make([]byte, maxPayload*2)
+		for i := range text {
+			text[i] = payloadChars[g.rand.Intn(len(payloadChars))]
+		}
+		g.text = string(text)
+	}
+	return g
+}
+
+func (g *generator) pick(name string, n int) int {
+	if g.distribution != distributionZipf || n == 1 {
+		return g.rand.Intn(n)
+	}
+	z, ok := g.zipf[name]
+	if !ok {
+		z = rand.NewZipf(g.rand, zipfS, 1, uint64(n-1))
+		g.zipf[name] = z
+	}
+	return int(z.Uint64())
+}
+
+// payload cuts a random text from the pre-generated one, so that no allocation is needed.
+func (g *generator) payload() string {
+	if g.maxPayload <= 0 {
+		return ""
+	}
+	size := g.minPayload
+	if g.maxPayload > g.minPayload {
+		size += g.rand.Intn(g.maxPayload - g.minPayload + 1)
+	}
+	offset := g.rand.Intn(len(g.text) - size + 1)
+	return g.text[offset : offset+size]
+}
+
+func (g *generator) render(t template) string {
+	if len(t) == 1 && t[0].generate == nil {
+		return t[0].literal
+	}
+	g.b.Reset()
+	for _, s := range t {
+		if s.generate == nil {
+			g.b.WriteString(s.literal)
+		} else {
+			s.generate(g, &g.b)
+		}
+	}
+	return g.b.String()
+}
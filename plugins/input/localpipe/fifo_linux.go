@@ -0,0 +1,44 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package localpipe
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// openFifo creates the named pipe if not exists. It is opened for reading and writing,
+// so that the reading does not end when all the producers close the pipe.
+func openFifo(path string, mode os.FileMode) (*os.File, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			return nil, fmt.Errorf("%v exists and is not a named pipe", path)
+		}
+	} else if os.IsNotExist(err) {
+		if err = syscall.Mkfifo(path, uint32(mode)); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR, 0) //nolint:gosec
+}
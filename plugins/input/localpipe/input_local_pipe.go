@@ -0,0 +1,344 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package localpipe receives the structured events written by the local processes into a unix socket or a named pipe.
+// Each frame carries the token of the producer, which is mapped to the tags of the events.
+package localpipe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_local_pipe"
+
+const (
+	modeUnix = "unix"
+	modeFifo = "fifo"
+)
+
+const (
+	v1 = iota
+	v2
+)
+
+const contentKey = "content"
+
+// frameHeaderSize is the size of the big endian uint32 length of a frame. The length covers the token length byte,
+// the token and the payload.
+const frameHeaderSize = 4
+
+var errFrameTooLarge = errors.New("frame too large")
+
+// ServiceLocalPipe receives the length-prefixed frames from multiple local producers.
+type ServiceLocalPipe struct {
+	Path           string                       // The path of the unix socket or the named pipe.
+	Mode           string                       // unix or fifo, default is unix.
+	Permission     string                       // The octal permission of the socket or the pipe file, default is 0660.
+	Tokens         map[string]map[string]string // The tokens of the producers and the tags added to their events.
+	MaxFrameSize   int                          // The max size in bytes of a frame, default is 1MiB.
+	MaxConnections int                          // Max connections in unix mode, no limit if not positive.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	fileMode    os.FileMode
+	listener    net.Listener
+	fifo        *os.File
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+}
+
+// Init ...
+func (s *ServiceLocalPipe) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if s.Path == "" {
+		return 0, errors.New("path is required")
+	}
+	if s.Mode != modeUnix && s.Mode != modeFifo {
+		return 0, fmt.Errorf("unknown mode %v", s.Mode)
+	}
+	if len(s.Tokens) == 0 {
+		return 0, errors.New("at least one producer token is required")
+	}
+	for token := range s.Tokens {
+		if token == "" || len(token) > 255 {
+			return 0, fmt.Errorf("the length of token %q must be between 1 and 255", token)
+		}
+	}
+	perm, err := strconv.ParseUint(s.Permission, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid permission %v: %v", s.Permission, err)
+	}
+	s.fileMode = os.FileMode(perm) & os.ModePerm
+	if s.MaxFrameSize <= 0 {
+		s.MaxFrameSize = 1024 * 1024
+	}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceLocalPipe) Description() string {
+	return "local unix socket and named pipe input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceLocalPipe) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceLocalPipe) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceLocalPipe) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceLocalPipe) start() error {
+	s.done = make(chan struct{})
+	if s.Mode == modeFifo {
+		fifo, err := openFifo(s.Path, s.fileMode)
+		if err != nil {
+			return err
+		}
+		s.fifo = fifo
+		s.wg.Add(1)
+		go s.readFifo()
+	} else {
+		// the socket file left by the last run blocks the listening
+		if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		listener, err := net.Listen("unix", s.Path)
+		if err != nil {
+			return err
+		}
+		if err = os.Chmod(s.Path, s.fileMode); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		s.listener = listener
+		s.connections = make(map[net.Conn]struct{})
+		s.wg.Add(1)
+		go s.accept()
+	}
+	logger.Info(s.context.GetRuntimeContext(), "local pipe server start", s.Path, "mode", s.Mode)
+	return nil
+}
+
+func (s *ServiceLocalPipe) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "accept error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		s.connectionsMu.Lock()
+		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
+			s.connectionsMu.Unlock()
+			logger.Warning(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "too many connections, reject", s.Path)
+			_ = conn.Close()
+			continue
+		}
+		s.connections[conn] = struct{}{}
+		s.connectionsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle reads the frames of a connection, the connection is closed on the invalid frames.
+func (s *ServiceLocalPipe) handle(conn net.Conn) {
+	defer func() {
+		s.connectionsMu.Lock()
+		delete(s.connections, conn)
+		s.connectionsMu.Unlock()
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+	reader := bufio.NewReader(conn)
+	for {
+		token, payload, err := readFrame(reader, s.MaxFrameSize)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				logger.Warning(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "read frame failed", err, "path", s.Path)
+			}
+			return
+		}
+		s.collect(token, payload)
+	}
+}
+
+// readFifo reads the frames written into the named pipe. The frames of the producers could only be separated
+// when each of them is written atomically, so the buffered data is discarded on the invalid frames.
+func (s *ServiceLocalPipe) readFifo() {
+	defer s.wg.Done()
+	reader := bufio.NewReader(s.fifo)
+	for {
+		token, payload, err := readFrame(reader, s.MaxFrameSize)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			if errors.Is(err, errFrameTooLarge) || errors.Is(err, io.ErrUnexpectedEOF) {
+				logger.Warning(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "read frame failed, discard the buffered data", err, "path", s.Path)
+				reader.Reset(s.fifo)
+				continue
+			}
+			logger.Error(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "read fifo error", err, "path", s.Path)
+			return
+		}
+		s.collect(token, payload)
+	}
+}
+
+// readFrame reads a frame, which is a big endian uint32 length followed by the token length byte, the token and the payload.
+func readFrame(reader *bufio.Reader, maxFrameSize int) (string, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return "", nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || int64(length) > int64(maxFrameSize) {
+		return "", nil, fmt.Errorf("%w: %d bytes", errFrameTooLarge, length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", nil, err
+	}
+	tokenLength := int(frame[0])
+	if 1+tokenLength > len(frame) {
+		return "", nil, fmt.Errorf("%w: token length %d exceeds the frame", io.ErrUnexpectedEOF, tokenLength)
+	}
+	return string(frame[1 : 1+tokenLength]), frame[1+tokenLength:], nil
+}
+
+// parsePayload expands the json object payload into fields, the other payloads are kept in the content field.
+func parsePayload(payload []byte) map[string]string {
+	var object map[string]interface{}
+	if err := json.Unmarshal(payload, &object); err != nil || object == nil {
+		return map[string]string{contentKey: string(payload)}
+	}
+	fields := make(map[string]string, len(object))
+	for k, v := range object {
+		switch t := v.(type) {
+		case string:
+			fields[k] = t
+		case nil:
+			fields[k] = ""
+		default:
+			b, _ := json.Marshal(t)
+			fields[k] = string(b)
+		}
+	}
+	return fields
+}
+
+func (s *ServiceLocalPipe) collect(token string, payload []byte) {
+	tags, ok := s.Tokens[token]
+	if !ok {
+		logger.Warning(s.context.GetRuntimeContext(), "LOCAL_PIPE_ALARM", "unknown producer token, drop the frame", s.Path)
+		return
+	}
+	fields := parsePayload(payload)
+	switch s.version {
+	case v1:
+		s.collector.AddData(tags, fields)
+	case v2:
+		groupTags := models.NewTags()
+		for k, v := range tags {
+			groupTags.Add(k, v)
+		}
+		log := models.NewLog("", nil, "", "", "", models.NewTags(), uint64(time.Now().UnixNano()))
+		for k, v := range fields {
+			log.Contents.Add(k, v)
+		}
+		s.collectorV2.CollectList(&models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), groupTags),
+			Events: []models.PipelineEvent{log},
+		})
+	}
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceLocalPipe) Stop() error {
+	if s.done == nil {
+		return nil
+	}
+	close(s.done)
+	if s.fifo != nil {
+		_ = s.fifo.Close()
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+		s.connectionsMu.Lock()
+		for conn := range s.connections {
+			_ = conn.Close()
+		}
+		s.connectionsMu.Unlock()
+	}
+	s.wg.Wait()
+	logger.Info(s.context.GetRuntimeContext(), "local pipe server stop", s.Path)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceLocalPipe{
+			Mode:           modeUnix,
+			Permission:     "0660",
+			MaxFrameSize:   1024 * 1024,
+			MaxConnections: 100,
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package localpipe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func TestFifoMultipleProducers(t *testing.T) {
+	s, err := newInput(filepath.Join(t.TempDir(), "ingest.fifo"))
+	require.NoError(t, err)
+	s.Mode = modeFifo
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+	info, err := os.Stat(s.Path)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeNamedPipe)
+
+	for _, token := range []string{"token-a", "token-b"} {
+		producer, err := os.OpenFile(s.Path, os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = producer.Write(frame(token, `{"msg":"`+token+`"}`))
+		require.NoError(t, err)
+		// the reading goes on after the producer closes the pipe
+		require.NoError(t, producer.Close())
+	}
+	groups := receive(t, ctx, 2)
+	assert.Equal(t, "a", groups[0].Group.Tags.Get("producer"))
+	assert.Equal(t, "token-a", groups[0].Events[0].(*models.Log).Contents.Get("msg"))
+	assert.Equal(t, "b", groups[1].Group.Tags.Get("producer"))
+	assert.Equal(t, "token-b", groups[1].Events[0].(*models.Log).Contents.Get("msg"))
+}
+
+func TestFifoPathIsNotPipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regular")
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	_, err := openFifo(path, 0600)
+	assert.Error(t, err)
+}
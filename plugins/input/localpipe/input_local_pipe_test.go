@@ -0,0 +1,169 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localpipe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newInput(path string) (*ServiceLocalPipe, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceLocalPipe)
+	s.Path = path
+	s.Tokens = map[string]map[string]string{
+		"token-a": {"producer": "a"},
+		"token-b": {"producer": "b", "team": "web"},
+	}
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+func frame(token, payload string) []byte {
+	buf := make([]byte, frameHeaderSize+1+len(token)+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(1+len(token)+len(payload)))
+	buf[frameHeaderSize] = byte(len(token))
+	copy(buf[frameHeaderSize+1:], token)
+	copy(buf[frameHeaderSize+1+len(token):], payload)
+	return buf
+}
+
+func receive(t *testing.T, ctx pipeline.PipelineContext, count int) []*models.PipelineGroupEvents {
+	groups := make([]*models.PipelineGroupEvents, 0, count)
+	for len(groups) < count {
+		select {
+		case g := <-ctx.Collector().Observe():
+			groups = append(groups, g)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for events", "received %d of %d", len(groups), count)
+		}
+	}
+	return groups
+}
+
+func TestInit(t *testing.T) {
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceLocalPipe)
+	_, err := s.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+	s.Path = "/tmp/ingest.sock"
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+	s.Tokens = map[string]map[string]string{"token": nil}
+	s.Permission = "rw"
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+	s.Permission = "0600"
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), s.fileMode)
+}
+
+func TestReadFrame(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(frame("token-a", `{"msg":"hello"}`))
+	buf.Write(frame("", "empty token"))
+	buf.Write([]byte{0, 0, 1, 0})
+	reader := bufio.NewReader(&buf)
+
+	token, payload, err := readFrame(reader, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "token-a", token)
+	assert.Equal(t, `{"msg":"hello"}`, string(payload))
+	token, payload, err = readFrame(reader, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "", token)
+	assert.Equal(t, "empty token", string(payload))
+	_, _, err = readFrame(reader, 16)
+	assert.ErrorIs(t, err, errFrameTooLarge)
+
+	_, _, err = readFrame(bufio.NewReader(bytes.NewReader([]byte{0, 0, 0, 3, 5, 'a', 'b'})), 1024)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, _, err = readFrame(bufio.NewReader(bytes.NewReader([]byte{0, 0, 0, 3, 5})), 1024)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestParsePayload(t *testing.T) {
+	assert.Equal(t, map[string]string{"msg": "hello", "code": "200", "ok": "true", "nested": `{"a":"b"}`, "null": ""},
+		parsePayload([]byte(`{"msg":"hello","code":200,"ok":true,"nested":{"a":"b"},"null":null}`)))
+	assert.Equal(t, map[string]string{contentKey: "plain text"}, parsePayload([]byte("plain text")))
+	assert.Equal(t, map[string]string{contentKey: "[1,2]"}, parsePayload([]byte("[1,2]")))
+}
+
+func TestUnixMultipleProducers(t *testing.T) {
+	s, err := newInput(filepath.Join(t.TempDir(), "ingest.sock"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(100)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+	info, err := os.Stat(s.Path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	producerA, err := net.Dial("unix", s.Path)
+	require.NoError(t, err)
+	defer producerA.Close()
+	producerB, err := net.Dial("unix", s.Path)
+	require.NoError(t, err)
+	defer producerB.Close()
+	_, err = producerA.Write(append(frame("unknown", `{"msg":"dropped"}`), frame("token-a", `{"msg":"from a"}`)...))
+	require.NoError(t, err)
+	groups := receive(t, ctx, 1)
+	_, err = producerB.Write(frame("token-b", "plain line"))
+	require.NoError(t, err)
+	groups = append(groups, receive(t, ctx, 1)...)
+
+	assert.Equal(t, "a", groups[0].Group.Tags.Get("producer"))
+	assert.Equal(t, "from a", groups[0].Events[0].(*models.Log).Contents.Get("msg"))
+	assert.Equal(t, "b", groups[1].Group.Tags.Get("producer"))
+	assert.Equal(t, "web", groups[1].Group.Tags.Get("team"))
+	assert.Equal(t, "plain line", groups[1].Events[0].(*models.Log).Contents.Get(contentKey))
+
+	// the connection is closed on the invalid frame
+	_, err = producerB.Write([]byte{0, 0, 0, 0})
+	require.NoError(t, err)
+	_ = producerB.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = producerB.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestCollectV1(t *testing.T) {
+	s, err := newInput(filepath.Join(t.TempDir(), "ingest.sock"))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.version = v1
+	s.collect("token-b", []byte(`{"msg":"hello"}`))
+	s.collect("unknown", []byte(`{"msg":"dropped"}`))
+
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, map[string]string{"producer": "b", "team": "web"}, collector.Logs[0].Tags)
+	assert.Equal(t, map[string]string{"msg": "hello"}, collector.Logs[0].Fields)
+}
@@ -12,30 +12,47 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Lumberjack server test tool.
-//
-// Create lumberjack server endpoint ACKing all received batches only. The
-// server supports all lumberjack protocol versions, which must be explicitly enabled
-// from command line. For printing list of known command line flags run:
-//
-//	tst-lj -h
+// Package lumberjack receives the events shipped by the beats, such as Filebeat and Winlogbeat,
+// with the lumberjack protocol, which is used by the beats to ship events to Logstash.
 package lumberjack
 
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/buger/jsonparser"
 	lumberlog "github.com/elastic/go-lumber/log"
 	"github.com/elastic/go-lumber/server"
 
 	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/util"
 )
 
+const (
+	v1 = iota
+	v2
+)
+
+const (
+	verifyModeNone      = "none"
+	verifyModePeer      = "peer"
+	verifyModeForcePeer = "force_peer"
+)
+
+const (
+	contentKey   = "content"
+	timestampKey = "@timestamp"
+)
+
 var errDecode = errors.New("decode error")
 
 var rawJSONDecoder = func(input []byte, out interface{}) error {
@@ -62,11 +79,12 @@ func (defaultLogger) Print(args ...interface{}) {
 	logger.Info(context.Background(), args...)
 }
 
+// ServiceLumber receives the events with the lumberjack v1 and v2 protocols.
 type ServiceLumber struct {
 	BindAddress string
 	V1          bool
 	V2          bool
-	// Path to CA file
+	// Path to CA file, used to verify the client certs
 	SSLCA string
 	// Path to host cert file
 	SSLCert string
@@ -74,18 +92,81 @@ type ServiceLumber struct {
 	SSLKey string
 	// Use SSL but skip chain & host verification
 	InsecureSkipVerify bool
+	// How to verify the client certs, none, peer or force_peer, default is none
+	SSLVerifyMode string
+	// Expand the json events of the v2 protocol into fields instead of putting them into the content field
+	ExpandJSON bool
+	// The connector of the nested keys when expanding json, default is .
+	ExpandConnector string
+	// The interval in seconds to send the keepalive signals while the batch is being processed
+	KeepaliveSeconds int
+	// The number of seconds to wait for the clients before closing the connection
+	TimeoutSeconds int
 
-	server    server.Server
-	shutdown  chan struct{}
-	waitGroup sync.WaitGroup
-	context   pipeline.Context
+	server      server.Server
+	shutdown    chan struct{}
+	waitGroup   sync.WaitGroup
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	tlsConfig   *tls.Config
 }
 
 func (p *ServiceLumber) Init(context pipeline.Context) (int, error) {
 	p.context = context
+	if !p.V1 && !p.V2 {
+		return 0, errors.New("no lumberjack protocol version is enabled")
+	}
+	if p.TimeoutSeconds < 0 || p.KeepaliveSeconds < 0 {
+		return 0, errors.New("timeout and keepalive must not be negative")
+	}
+	var err error
+	if p.tlsConfig, err = p.getTLSConfig(); err != nil {
+		return 0, err
+	}
+	p.shutdown = make(chan struct{})
 	return 0, nil
 }
 
+func (p *ServiceLumber) getTLSConfig() (*tls.Config, error) {
+	if p.SSLVerifyMode == "" {
+		p.SSLVerifyMode = verifyModeNone
+	}
+	switch p.SSLVerifyMode {
+	case verifyModeNone, verifyModePeer, verifyModeForcePeer:
+	default:
+		return nil, fmt.Errorf("unknown ssl verify mode %v", p.SSLVerifyMode)
+	}
+	tlsConfig, err := util.GetTLSConfig(p.SSLCert, p.SSLKey, p.SSLCA, p.InsecureSkipVerify)
+	if err != nil || tlsConfig == nil {
+		return nil, err
+	}
+	if len(tlsConfig.Certificates) == 0 {
+		return nil, errors.New("both SSLCert and SSLKey are required to enable tls")
+	}
+	if p.SSLVerifyMode == verifyModeNone {
+		return tlsConfig, nil
+	}
+	if p.SSLCA == "" {
+		return nil, fmt.Errorf("SSLCA is required to verify the client certs with mode %v", p.SSLVerifyMode)
+	}
+	caCert, err := os.ReadFile(filepath.Clean(p.SSLCA))
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS CA: %v", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no cert is found in TLS CA %v", p.SSLCA)
+	}
+	if p.SSLVerifyMode == verifyModePeer {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	} else {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
 func (p *ServiceLumber) Description() string {
 	return "lumberjack input plugin for logtail"
 }
@@ -98,33 +179,39 @@ func (p *ServiceLumber) Collect(pipeline.Collector) error {
 
 // Start starts the ServiceInput's service, whatever that may be
 func (p *ServiceLumber) Start(c pipeline.Collector) error {
-	p.shutdown = make(chan struct{})
+	p.collector = c
+	p.version = v1
+	return p.run()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (p *ServiceLumber) StartService(context pipeline.PipelineContext) error {
+	p.collectorV2 = context.Collector()
+	p.version = v2
+	return p.run()
+}
+
+func (p *ServiceLumber) run() error {
 	p.waitGroup.Add(1)
 	defer p.waitGroup.Done()
+	opts := []server.Option{
+		server.V1(p.V1),
+		server.V2(p.V2),
+		server.JSONDecoder(rawJSONDecoder),
+	}
+	if p.tlsConfig != nil {
+		opts = append(opts, server.TLS(p.tlsConfig))
+	}
+	if p.KeepaliveSeconds > 0 {
+		opts = append(opts, server.Keepalive(time.Duration(p.KeepaliveSeconds)*time.Second))
+	}
+	if p.TimeoutSeconds > 0 {
+		opts = append(opts, server.Timeout(time.Duration(p.TimeoutSeconds)*time.Second))
+	}
 	var err error
 	for {
 		logger.Info(p.context.GetRuntimeContext(), "start listen lumber, address", p.BindAddress)
-
-		if p.SSLCert == "" && p.SSLKey == "" && p.SSLCA == "" && !p.InsecureSkipVerify {
-			p.server, err = server.ListenAndServe(p.BindAddress,
-				server.V1(p.V1),
-				server.V2(p.V2),
-				server.JSONDecoder(rawJSONDecoder),
-			)
-		} else {
-			var tlsConfig *tls.Config
-			tlsConfig, err = util.GetTLSConfig(p.SSLCert, p.SSLKey, p.SSLCA, p.InsecureSkipVerify)
-			if err != nil {
-				logger.Error(p.context.GetRuntimeContext(), "LUMBER_LISTEN_ALARM", "init tls error", err)
-			}
-			p.server, err = server.ListenAndServe(p.BindAddress,
-				server.V1(p.V1),
-				server.V2(p.V2),
-				server.JSONDecoder(rawJSONDecoder),
-				server.TLS(tlsConfig),
-			)
-		}
-
+		p.server, err = server.ListenAndServe(p.BindAddress, opts...)
 		if err != nil {
 			logger.Error(p.context.GetRuntimeContext(), "LUMBER_LISTEN_ALARM", "listen init error", err, "sleep 10 seconds and retry")
 			if util.RandomSleep(time.Second*10, 0.1, p.shutdown) {
@@ -134,9 +221,6 @@ func (p *ServiceLumber) Start(c pipeline.Collector) error {
 		}
 
 		recvChan := p.server.ReceiveChan()
-
-		keys := []string{"content"}
-		values := []string{""}
 	ForBlock:
 		for {
 			select {
@@ -146,28 +230,107 @@ func (p *ServiceLumber) Start(c pipeline.Collector) error {
 					logger.Error(p.context.GetRuntimeContext(), "LUMBER_CONNECTION_ALARM", "lumber server error", "chan closed", "close server, err", err)
 					break ForBlock
 				}
-				for _, event := range batch.Events {
-					if p.V2 {
-						strValue, _ := event.(string)
-						values[0] = strValue
-						c.AddDataArray(nil, keys, values)
-						logger.Debug(p.context.GetRuntimeContext(), "received event", strValue)
-					} else {
-						strMap, ok := event.(map[string]string)
-						if ok && strMap != nil {
-							c.AddData(nil, strMap)
-						} else {
-							logger.Debug(p.context.GetRuntimeContext(), "received invalid event")
-						}
-					}
-
-				}
+				p.collect(batch.Events)
 				batch.ACK()
 			case <-p.shutdown:
 				return p.server.Close()
 			}
 		}
+	}
+}
+
+type event struct {
+	fields    map[string]string
+	timestamp time.Time
+}
+
+// parseEvent converts the events of v1 protocol, which are string maps, and the raw json events of v2 protocol.
+func (p *ServiceLumber) parseEvent(raw interface{}) (*event, bool) {
+	switch e := raw.(type) {
+	case map[string]string:
+		return &event{fields: e}, true
+	case string:
+		if p.ExpandJSON {
+			if evt, err := p.expand([]byte(e)); err == nil {
+				return evt, true
+			}
+			logger.Debug(p.context.GetRuntimeContext(), "expand json event error, keep it as content", e)
+		}
+		return &event{fields: map[string]string{contentKey: e}}, true
+	}
+	return nil, false
+}
 
+func (p *ServiceLumber) expand(data []byte) (*event, error) {
+	evt := &event{fields: make(map[string]string)}
+	if err := p.expandObject(evt, "", data); err != nil {
+		return nil, err
+	}
+	if ts, ok := evt.fields[timestampKey]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			evt.timestamp = t
+			delete(evt.fields, timestampKey)
+		}
+	}
+	return evt, nil
+}
+
+func (p *ServiceLumber) expandObject(evt *event, prefix string, data []byte) error {
+	return jsonparser.ObjectEach(data, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		k := string(key)
+		if prefix != "" {
+			k = prefix + p.ExpandConnector + k
+		}
+		switch dataType {
+		case jsonparser.Object:
+			return p.expandObject(evt, k, value)
+		case jsonparser.String:
+			str, err := jsonparser.ParseString(value)
+			if err != nil {
+				return err
+			}
+			evt.fields[k] = str
+		default:
+			evt.fields[k] = string(value)
+		}
+		return nil
+	})
+}
+
+func (p *ServiceLumber) collect(rawEvents []interface{}) {
+	var group *models.PipelineGroupEvents
+	if p.version == v2 {
+		group = &models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+			Events: make([]models.PipelineEvent, 0, len(rawEvents)),
+		}
+	}
+	for _, raw := range rawEvents {
+		evt, ok := p.parseEvent(raw)
+		if !ok {
+			logger.Debug(p.context.GetRuntimeContext(), "received invalid event")
+			continue
+		}
+		switch p.version {
+		case v1:
+			if evt.timestamp.IsZero() {
+				p.collector.AddData(nil, evt.fields)
+			} else {
+				p.collector.AddData(nil, evt.fields, evt.timestamp)
+			}
+		case v2:
+			if evt.timestamp.IsZero() {
+				evt.timestamp = time.Now()
+			}
+			log := models.NewLog("", nil, "", "", "", models.NewTags(), uint64(evt.timestamp.UnixNano()))
+			for k, v := range evt.fields {
+				log.Contents.Add(k, v)
+			}
+			group.Events = append(group.Events, log)
+		}
+	}
+	if group != nil && len(group.Events) > 0 {
+		p.collectorV2.CollectList(group)
 	}
 }
 
@@ -181,9 +344,11 @@ func (p *ServiceLumber) Stop() error {
 func init() {
 	pipeline.ServiceInputs["service_lumberjack"] = func() pipeline.ServiceInput {
 		return &ServiceLumber{
-			BindAddress: "127.0.0.1:5044",
-			V2:          true,
-			V1:          false,
+			BindAddress:     "127.0.0.1:5044",
+			V2:              true,
+			V1:              false,
+			SSLVerifyMode:   verifyModeNone,
+			ExpandConnector: ".",
 		}
 	}
 	lumberlog.Logger = defaultLogger{}
@@ -0,0 +1,253 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lumberjack
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	client "github.com/elastic/go-lumber/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newInput(address string) (*ServiceLumber, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	p := pipeline.ServiceInputs["service_lumberjack"]().(*ServiceLumber)
+	p.BindAddress = address
+	_, err := p.Init(ctx)
+	return p, err
+}
+
+func dial(t *testing.T, address string, tlsConfig *tls.Config) (*client.SyncClient, error) {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	if tlsConfig != nil {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	return client.NewSyncClientWithConn(conn, client.Timeout(5*time.Second))
+}
+
+var events = []interface{}{
+	map[string]interface{}{
+		"@timestamp": "2024-01-02T03:04:05.123Z",
+		"message":    "hello",
+		"host":       map[string]interface{}{"name": "node-1"},
+		"log":        map[string]interface{}{"offset": 100},
+		"tags":       []string{"a", "b"},
+	},
+	map[string]interface{}{"message": "world"},
+}
+
+func TestLumberV2Expand(t *testing.T) {
+	p, err := newInput(test.GetAvailableLocalAddress(t))
+	require.NoError(t, err)
+	p.ExpandJSON = true
+	_, err = p.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(10)
+	go func() {
+		_ = p.StartService(ctx)
+	}()
+	defer p.Stop()
+
+	c, err := dial(t, p.BindAddress, nil)
+	require.NoError(t, err)
+	defer c.Close()
+	n, err := c.Send(events)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	groups := ctx.Collector().ToArray()
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Events, 2)
+	log := groups[0].Events[0].(*models.Log)
+	assert.Equal(t, map[string]interface{}{
+		"message":    "hello",
+		"host.name":  "node-1",
+		"log.offset": "100",
+		"tags":       `["a","b"]`,
+	}, log.Contents.Iterator())
+	assert.Equal(t, uint64(time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC).UnixNano()), log.GetTimestamp())
+	assert.Equal(t, "world", groups[0].Events[1].(*models.Log).Contents.Get("message"))
+}
+
+func TestLumberV1Content(t *testing.T) {
+	p, err := newInput(test.GetAvailableLocalAddress(t))
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	go func() {
+		_ = p.Start(collector)
+	}()
+	defer p.Stop()
+
+	c, err := dial(t, p.BindAddress, nil)
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.Send(events[1:])
+	require.NoError(t, err)
+
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, map[string]string{"content": `{"message":"world"}`}, collector.Logs[0].Fields)
+}
+
+type certs struct {
+	ca, serverCert, serverKey string
+	client                    tls.Certificate
+	pool                      *x509.CertPool
+}
+
+func generateCerts(t *testing.T) *certs {
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+
+	c := &certs{pool: x509.NewCertPool()}
+	c.pool.AddCert(caCert)
+	c.ca = write("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	serverCert, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	c.serverCert = write("server.pem", serverCert)
+	c.serverKey = write("server.key", serverKey)
+	clientCert, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+	c.client, err = tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	return c
+}
+
+func TestLumberTLS(t *testing.T) {
+	certs := generateCerts(t)
+	p, err := newInput(test.GetAvailableLocalAddress(t))
+	require.NoError(t, err)
+	p.SSLCA = certs.ca
+	p.SSLCert = certs.serverCert
+	p.SSLKey = certs.serverKey
+	p.SSLVerifyMode = verifyModeForcePeer
+	_, err = p.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(10)
+	go func() {
+		_ = p.StartService(ctx)
+	}()
+	defer p.Stop()
+
+	// the client without cert is rejected
+	c, err := dial(t, p.BindAddress, &tls.Config{ServerName: "127.0.0.1", RootCAs: certs.pool, MinVersion: tls.VersionTLS12})
+	if err == nil {
+		_, err = c.Send(events)
+		_ = c.Close()
+	}
+	assert.Error(t, err)
+	assert.Empty(t, ctx.Collector().ToArray())
+
+	c, err = dial(t, p.BindAddress, &tls.Config{
+		ServerName:   "127.0.0.1",
+		RootCAs:      certs.pool,
+		Certificates: []tls.Certificate{certs.client},
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+	n, err := c.Send(events)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, ctx.Collector().ToArray(), 1)
+}
+
+func TestLumberInit(t *testing.T) {
+	certs := generateCerts(t)
+	for _, p := range []*ServiceLumber{
+		{},
+		{V2: true, TimeoutSeconds: -1},
+		{V2: true, SSLVerifyMode: "unknown"},
+		{V2: true, SSLCA: certs.ca},
+		{V2: true, SSLCert: certs.serverCert, SSLKey: certs.serverKey, SSLVerifyMode: verifyModePeer},
+	} {
+		_, err := p.Init(mock.NewEmptyContext("p", "l", "c"))
+		assert.Error(t, err)
+	}
+
+	p := &ServiceLumber{V2: true, SSLCA: certs.ca, SSLCert: certs.serverCert, SSLKey: certs.serverKey, SSLVerifyMode: verifyModePeer}
+	_, err := p.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, p.tlsConfig.ClientAuth)
+	assert.NotNil(t, p.tlsConfig.ClientCAs)
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package minio
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	clusterPath = "/minio/v2/metrics/cluster"
+	nodePath    = "/minio/v2/metrics/node"
+	bucketPath  = "/minio/v2/metrics/bucket"
+
+	tokenExpiry = time.Hour
+)
+
+// InputMinio scrapes the prometheus endpoints of MinIO, including the cluster capacity and health,
+// the bucket usage and traffic, and optionally the metrics of each node.
+type InputMinio struct {
+	// Endpoints are the addresses of the MinIO servers, e.g. "http://10.0.0.1:9000"
+	Endpoints []string
+	// BearerToken is the token generated by "mc admin prometheus generate",
+	// the token is signed with the AccessKey and the SecretKey if empty.
+	BearerToken        string
+	AccessKey          string
+	SecretKey          string
+	NodeMetrics        bool   // scrape the node endpoint of each server besides the cluster endpoint
+	BucketMetrics      bool   // scrape the bucket endpoint, which requires RELEASE.2023-07-18 or later
+	BucketPattern      string // regex to select the buckets by name, all buckets are selected if empty
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	TimeoutMs          int
+	Labels             map[string]string
+
+	bucketRegex *regexp.Regexp
+	client      *http.Client
+	context     pipeline.Context
+}
+
+func (m *InputMinio) Init(context pipeline.Context) (int, error) {
+	m.context = context
+	if len(m.Endpoints) == 0 {
+		return 0, fmt.Errorf("no minio endpoint configured")
+	}
+	if m.BearerToken == "" && (m.AccessKey == "") != (m.SecretKey == "") {
+		return 0, fmt.Errorf("AccessKey and SecretKey must be set together")
+	}
+	if m.TimeoutMs <= 0 {
+		m.TimeoutMs = 10000
+	}
+	var err error
+	if m.BucketPattern != "" {
+		if m.bucketRegex, err = regexp.Compile(m.BucketPattern); err != nil {
+			return 0, fmt.Errorf("invalid bucket pattern: %v", err)
+		}
+	}
+	tlsCfg, err := util.GetTLSConfig(m.SSLCert, m.SSLKey, m.SSLCA, m.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	m.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(m.TimeoutMs) * time.Millisecond,
+	}
+	return 0, nil
+}
+
+func (m *InputMinio) Description() string {
+	return "collect the cluster, bucket and node metrics of minio"
+}
+
+func (m *InputMinio) Collect(collector pipeline.Collector) error {
+	labels := &helper.MetricLabels{}
+	for k, v := range m.Labels {
+		labels.Append(k, v)
+	}
+	now := time.Now()
+	token := m.token(now)
+
+	// the cluster and bucket metrics are the same on every server, so they are scraped from the first available one.
+	up := 0.
+	for _, endpoint := range m.Endpoints {
+		base := strings.TrimSuffix(endpoint, "/")
+		if err := m.scrape(base+clusterPath, token, labels, collector); err != nil {
+			logger.Warning(m.context.GetRuntimeContext(), "MINIO_COLLECT_ALARM", "endpoint", endpoint, "cluster metrics error", err)
+			continue
+		}
+		up = 1
+		if m.BucketMetrics {
+			if err := m.scrape(base+bucketPath, token, labels, collector); err != nil {
+				logger.Warning(m.context.GetRuntimeContext(), "MINIO_COLLECT_ALARM", "endpoint", endpoint, "bucket metrics error", err)
+			}
+		}
+		break
+	}
+	collector.AddRawLog(helper.NewMetricLog("minio_up", now.UnixNano(), up, labels))
+
+	if !m.NodeMetrics {
+		return nil
+	}
+	var wg sync.WaitGroup
+	for _, endpoint := range m.Endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+			if err != nil {
+				logger.Warning(m.context.GetRuntimeContext(), "MINIO_COLLECT_ALARM", "endpoint", endpoint, "error", err)
+				return
+			}
+			nodeLabels := labels.Clone()
+			nodeLabels.Append("instance", u.Host)
+			if err = m.scrape(u.String()+nodePath, token, nodeLabels, collector); err != nil {
+				logger.Warning(m.context.GetRuntimeContext(), "MINIO_COLLECT_ALARM", "endpoint", endpoint, "node metrics error", err)
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	return nil
+}
+
+// token returns the bearer token for the metrics api, which is a JWT signed by the secret key
+// in the same way as "mc admin prometheus generate".
+func (m *InputMinio) token(now time.Time) string {
+	if m.BearerToken != "" || m.AccessKey == "" {
+		return m.BearerToken
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS512","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": now.Add(tokenExpiry).Unix(),
+		"sub": m.AccessKey,
+		"iss": "prometheus",
+	})
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha512.New, []byte(m.SecretKey))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *InputMinio) scrape(u, token string, labels *helper.MetricLabels, collector pipeline.Collector) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	return helper.DecodePrometheusText(resp.Body, time.Now(), func(sample *model.Sample) {
+		if bucket, ok := sample.Metric["bucket"]; ok && m.bucketRegex != nil && !m.bucketRegex.MatchString(string(bucket)) {
+			return
+		}
+		sampleLabels := labels.Clone()
+		helper.AppendSampleLabels(sampleLabels, sample.Metric)
+		collector.AddRawLog(helper.NewMetricLog(string(sample.Metric[model.MetricNameLabel]), sample.Timestamp.UnixNano(), float64(sample.Value), sampleLabels))
+	})
+}
+
+func init() {
+	pipeline.MetricInputs["metric_minio"] = func() pipeline.MetricInput {
+		return &InputMinio{
+			BucketMetrics: true,
+			TimeoutMs:     10000,
+		}
+	}
+}
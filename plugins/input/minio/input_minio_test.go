@@ -0,0 +1,105 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package minio
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+var minioMetrics = map[string]string{
+	clusterPath: "minio_cluster_nodes_online_total{server=\"127.0.0.1:9000\"} 4\n",
+	bucketPath: "minio_bucket_usage_total_bytes{bucket=\"logs\",server=\"127.0.0.1:9000\"} 2048\n" +
+		"minio_bucket_usage_total_bytes{bucket=\"tmp\",server=\"127.0.0.1:9000\"} 10\n",
+	nodePath: "minio_node_drive_free_bytes{drive=\"/data\",server=\"127.0.0.1:9000\"} 100\n",
+}
+
+// verifyToken checks the signature of the token and returns the claims.
+func verifyToken(token, secret string) (map[string]interface{}, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) != parts[2] {
+		return nil, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	claims := make(map[string]interface{})
+	if err = json.Unmarshal(data, &claims); err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+func TestMinioCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := verifyToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), "minio-secret")
+		if !ok || claims["sub"] != "minio-admin" || claims["iss"] != "prometheus" || claims["exp"].(float64) < float64(time.Now().Unix()) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, ok := minioMetrics[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	input := &InputMinio{
+		Endpoints:     []string{"http://127.0.0.1:1", server.URL},
+		AccessKey:     "minio-admin",
+		SecretKey:     "minio-secret",
+		NodeMetrics:   true,
+		BucketMetrics: true,
+		BucketPattern: "^logs$",
+	}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	instance := strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []string{
+		"minio_bucket_usage_total_bytes{bucket#$#logs|server#$#127.0.0.1:9000} 2048",
+		"minio_cluster_nodes_online_total{server#$#127.0.0.1:9000} 4",
+		"minio_node_drive_free_bytes{drive#$#/data|instance#$#" + instance + "|server#$#127.0.0.1:9000} 100",
+		"minio_up{} 1",
+	}, test.MetricLines(collector.Logs))
+
+	input.SecretKey = "wrong"
+	input.NodeMetrics = false
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{"minio_up{} 0"}, test.MetricLines(collector.Logs))
+}
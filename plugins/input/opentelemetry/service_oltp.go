@@ -20,8 +20,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"net/url"
-	"strings"
 	"sync"
 	"time"
 
@@ -161,10 +159,11 @@ func (s *Server) start() error {
 	if s.Protocals.HTTP != nil {
 		httpMux := http.NewServeMux()
 		maxBodySize := int64(s.Protocals.HTTP.MaxRequestBodySizeMiB) * 1024 * 1024
+		maxDecompressedSize := int64(s.Protocals.HTTP.MaxDecompressedSizeMiB) * 1024 * 1024
 
-		s.registerHTTPLogsComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPLogV1}, maxBodySize, "/v1/logs")
-		s.registerHTTPMetricsComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPMetricV1}, maxBodySize, "/v1/metrics")
-		s.registerHTTPTracesComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPTraceV1}, maxBodySize, "/v1/traces")
+		s.registerHTTPLogsComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPLogV1, MaxDecompressedSize: maxDecompressedSize}, maxBodySize, "/v1/logs")
+		s.registerHTTPMetricsComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPMetricV1, MaxDecompressedSize: maxDecompressedSize}, maxBodySize, "/v1/metrics")
+		s.registerHTTPTracesComsumer(httpMux, &opentelemetry.Decoder{Format: common.ProtocolOTLPTraceV1, MaxDecompressedSize: maxDecompressedSize}, maxBodySize, "/v1/traces")
 		logger.Info(s.context.GetRuntimeContext(), "otlp http receiver for logs/metrics/traces", "initialized")
 
 		httpServer := &http.Server{
@@ -315,21 +314,11 @@ func (s *Server) registerHTTPTracesComsumer(serveMux *http.ServeMux, decoder ext
 }
 
 func getNetListener(endpoint string) (net.Listener, error) {
-	var listener net.Listener
-	var err error
-	switch {
-	case strings.HasPrefix(endpoint, "http") ||
-		strings.HasPrefix(endpoint, "https") ||
-		strings.HasPrefix(endpoint, "tcp"):
-		configURL, errAddr := url.Parse(endpoint)
-		if errAddr != nil {
-			return nil, errAddr
-		}
-		listener, err = net.Listen("tcp", configURL.Host)
-	default:
-		listener, err = net.Listen("tcp", endpoint)
+	network, address, err := helper.ParseListenAddress(endpoint)
+	if err != nil {
+		return nil, err
 	}
-	return listener, err
+	return net.Listen(network, address)
 }
 
 func marshalResp[
@@ -370,6 +359,8 @@ func handleInvalidRequest(w http.ResponseWriter, r *http.Request, maxBodySize in
 		httpserver.BadRequest(w)
 	case http.StatusRequestEntityTooLarge:
 		httpserver.TooLarge(w)
+	case http.StatusUnsupportedMediaType:
+		httpserver.UnsupportedMediaType(w)
 	case http.StatusInternalServerError:
 		httpserver.InternalServerError(w)
 	case http.StatusMethodNotAllowed:
@@ -397,10 +388,11 @@ type Protocals struct {
 }
 
 type HTTPServerSettings struct {
-	Endpoint              string
-	MaxRequestBodySizeMiB int
-	ReadTimeoutSec        int
-	ShutdownTimeoutSec    int
+	Endpoint               string
+	MaxRequestBodySizeMiB  int
+	MaxDecompressedSizeMiB int // default is MaxRequestBodySizeMiB
+	ReadTimeoutSec         int
+	ShutdownTimeoutSec     int
 }
 
 func init() {
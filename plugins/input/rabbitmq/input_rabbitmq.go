@@ -0,0 +1,285 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	metricPrefix = "rabbitmq_"
+
+	maxBodySize  = 64 * 1024 * 1024
+	queueColumns = "name,vhost,state,messages,messages_ready,messages_unacknowledged,consumers,consumer_utilisation,memory," +
+		"message_stats.publish,message_stats.deliver_get,message_stats.ack,message_stats.redeliver"
+)
+
+type messageStats struct {
+	Publish    *float64 `json:"publish"`
+	DeliverGet *float64 `json:"deliver_get"`
+	Ack        *float64 `json:"ack"`
+	Redeliver  *float64 `json:"redeliver"`
+}
+
+type overview struct {
+	ClusterName  string                 `json:"cluster_name"`
+	ObjectTotals map[string]interface{} `json:"object_totals"`
+	QueueTotals  map[string]interface{} `json:"queue_totals"`
+	MessageStats messageStats           `json:"message_stats"`
+}
+
+type node struct {
+	Name          string   `json:"name"`
+	Running       bool     `json:"running"`
+	MemUsed       *float64 `json:"mem_used"`
+	MemLimit      *float64 `json:"mem_limit"`
+	MemAlarm      bool     `json:"mem_alarm"`
+	DiskFree      *float64 `json:"disk_free"`
+	DiskFreeLimit *float64 `json:"disk_free_limit"`
+	DiskFreeAlarm bool     `json:"disk_free_alarm"`
+	FdUsed        *float64 `json:"fd_used"`
+	FdTotal       *float64 `json:"fd_total"`
+	SocketsUsed   *float64 `json:"sockets_used"`
+	ProcUsed      *float64 `json:"proc_used"`
+	Uptime        *float64 `json:"uptime"`
+}
+
+type queue struct {
+	Name                   string       `json:"name"`
+	Vhost                  string       `json:"vhost"`
+	State                  string       `json:"state"`
+	Messages               *float64     `json:"messages"`
+	MessagesReady          *float64     `json:"messages_ready"`
+	MessagesUnacknowledged *float64     `json:"messages_unacknowledged"`
+	Consumers              *float64     `json:"consumers"`
+	ConsumerUtilisation    *float64     `json:"consumer_utilisation"`
+	Memory                 *float64     `json:"memory"`
+	MessageStats           messageStats `json:"message_stats"`
+}
+
+// InputRabbitMQ polls the management API of RabbitMQ, and exports the overview, node and queue metrics
+// such as the queue depths and consumer counts.
+type InputRabbitMQ struct {
+	// URL is the address of the management plugin, e.g. "http://127.0.0.1:15672"
+	URL                 string
+	Username            string
+	Password            string
+	QueuePattern        string // regex to select the queues by name, all queues are selected if empty
+	ExcludeQueuePattern string // regex to exclude the queues by name
+	Vhosts              []string
+	SSLCA               string
+	SSLCert             string
+	SSLKey              string
+	SkipInsecureVerify  bool
+	TimeoutMs           int
+	Labels              map[string]string
+
+	queueRegex        *regexp.Regexp
+	excludeQueueRegex *regexp.Regexp
+	vhosts            map[string]bool
+	client            *http.Client
+	context           pipeline.Context
+}
+
+func (r *InputRabbitMQ) Init(context pipeline.Context) (int, error) {
+	r.context = context
+	if r.URL == "" {
+		return 0, fmt.Errorf("url is required")
+	}
+	r.URL = strings.TrimSuffix(r.URL, "/")
+	if r.TimeoutMs <= 0 {
+		r.TimeoutMs = 5000
+	}
+	var err error
+	if r.QueuePattern != "" {
+		if r.queueRegex, err = regexp.Compile(r.QueuePattern); err != nil {
+			return 0, fmt.Errorf("invalid queue pattern: %v", err)
+		}
+	}
+	if r.ExcludeQueuePattern != "" {
+		if r.excludeQueueRegex, err = regexp.Compile(r.ExcludeQueuePattern); err != nil {
+			return 0, fmt.Errorf("invalid exclude queue pattern: %v", err)
+		}
+	}
+	if len(r.Vhosts) > 0 {
+		r.vhosts = make(map[string]bool, len(r.Vhosts))
+		for _, vhost := range r.Vhosts {
+			r.vhosts[vhost] = true
+		}
+	}
+	tlsCfg, err := util.GetTLSConfig(r.SSLCert, r.SSLKey, r.SSLCA, r.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	r.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(r.TimeoutMs) * time.Millisecond,
+	}
+	return 0, nil
+}
+
+func (r *InputRabbitMQ) Description() string {
+	return "collect the queue, node and overview metrics of rabbitmq from the management api"
+}
+
+func (r *InputRabbitMQ) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, r.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(r.Username, r.Password)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: unexpected status %s", path, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(v)
+}
+
+func (r *InputRabbitMQ) Collect(collector pipeline.Collector) error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", u.Host)
+	for k, v := range r.Labels {
+		labels.Append(k, v)
+	}
+	now := time.Now().UnixNano()
+	add := func(name string, value *float64, labels *helper.MetricLabels) {
+		if value != nil {
+			collector.AddRawLog(helper.NewMetricLog(metricPrefix+name, now, *value, labels))
+		}
+	}
+
+	var o overview
+	if err = r.get("/api/overview", &o); err != nil {
+		logger.Warning(r.context.GetRuntimeContext(), "RABBITMQ_COLLECT_ALARM", "get overview error", err)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 0, labels))
+		return nil
+	}
+	labels.Append("cluster", o.ClusterName)
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 1, labels))
+	for _, totals := range []map[string]interface{}{o.ObjectTotals, o.QueueTotals} {
+		for k, v := range totals {
+			// the rates are in the nested details objects
+			if value, ok := v.(float64); ok {
+				add("overview_"+k, &value, labels)
+			}
+		}
+	}
+	addMessageStats(add, "overview_", o.MessageStats, labels)
+
+	var nodes []node
+	if err = r.get("/api/nodes", &nodes); err != nil {
+		logger.Warning(r.context.GetRuntimeContext(), "RABBITMQ_COLLECT_ALARM", "get nodes error", err)
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		nodeLabels := labels.Clone()
+		nodeLabels.Append("node", n.Name)
+		running := boolValue(n.Running)
+		add("node_running", &running, nodeLabels)
+		if !n.Running {
+			continue
+		}
+		memAlarm, diskAlarm := boolValue(n.MemAlarm), boolValue(n.DiskFreeAlarm)
+		add("node_mem_alarm", &memAlarm, nodeLabels)
+		add("node_disk_free_alarm", &diskAlarm, nodeLabels)
+		add("node_mem_used_bytes", n.MemUsed, nodeLabels)
+		add("node_mem_limit_bytes", n.MemLimit, nodeLabels)
+		add("node_disk_free_bytes", n.DiskFree, nodeLabels)
+		add("node_disk_free_limit_bytes", n.DiskFreeLimit, nodeLabels)
+		add("node_fd_used", n.FdUsed, nodeLabels)
+		add("node_fd_total", n.FdTotal, nodeLabels)
+		add("node_sockets_used", n.SocketsUsed, nodeLabels)
+		add("node_proc_used", n.ProcUsed, nodeLabels)
+		add("node_uptime_ms", n.Uptime, nodeLabels)
+	}
+
+	var queues []queue
+	if err = r.get("/api/queues?columns="+url.QueryEscape(queueColumns), &queues); err != nil {
+		logger.Warning(r.context.GetRuntimeContext(), "RABBITMQ_COLLECT_ALARM", "get queues error", err)
+	}
+	for i := range queues {
+		q := &queues[i]
+		if !r.selectQueue(q) {
+			continue
+		}
+		queueLabels := labels.Clone()
+		queueLabels.Append("vhost", q.Vhost)
+		queueLabels.Append("queue", q.Name)
+		running := boolValue(q.State == "running" || q.State == "")
+		add("queue_running", &running, queueLabels)
+		add("queue_messages", q.Messages, queueLabels)
+		add("queue_messages_ready", q.MessagesReady, queueLabels)
+		add("queue_messages_unacknowledged", q.MessagesUnacknowledged, queueLabels)
+		add("queue_consumers", q.Consumers, queueLabels)
+		add("queue_consumer_utilisation", q.ConsumerUtilisation, queueLabels)
+		add("queue_memory_bytes", q.Memory, queueLabels)
+		addMessageStats(add, "queue_", q.MessageStats, queueLabels)
+	}
+	return nil
+}
+
+func (r *InputRabbitMQ) selectQueue(q *queue) bool {
+	if r.vhosts != nil && !r.vhosts[q.Vhost] {
+		return false
+	}
+	if r.queueRegex != nil && !r.queueRegex.MatchString(q.Name) {
+		return false
+	}
+	return r.excludeQueueRegex == nil || !r.excludeQueueRegex.MatchString(q.Name)
+}
+
+// addMessageStats exports the cumulative message counters, the counters are absent before any message flows.
+func addMessageStats(add func(string, *float64, *helper.MetricLabels), prefix string, stats messageStats, labels *helper.MetricLabels) {
+	add(prefix+"messages_published_total", stats.Publish, labels)
+	add(prefix+"messages_delivered_total", stats.DeliverGet, labels)
+	add(prefix+"messages_acked_total", stats.Ack, labels)
+	add(prefix+"messages_redelivered_total", stats.Redeliver, labels)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	pipeline.MetricInputs["metric_rabbitmq"] = func() pipeline.MetricInput {
+		return &InputRabbitMQ{
+			URL:       "http://127.0.0.1:15672",
+			TimeoutMs: 5000,
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+var managementResponses = map[string]string{
+	"/api/overview": `{"cluster_name":"rabbit@node1","object_totals":{"connections":3,"consumers":2,"queues":2},` +
+		`"queue_totals":{"messages":15,"messages_details":{"rate":0.0}},"message_stats":{"publish":100,"publish_details":{"rate":1.0}}}`,
+	"/api/nodes": `[{"name":"rabbit@node1","running":true,"mem_used":1024,"mem_limit":4096,"mem_alarm":false,"disk_free_alarm":true},` +
+		`{"name":"rabbit@node2","running":false}]`,
+	"/api/queues": `[{"name":"orders","vhost":"/","state":"running","messages":10,"messages_ready":8,"messages_unacknowledged":2,"consumers":1,` +
+		`"message_stats":{"publish":60,"ack":50}},{"name":"amq.gen-1","vhost":"/","messages":5},{"name":"audit","vhost":"other","messages":1}]`,
+}
+
+func TestRabbitMQCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "guest" || password != "guest" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/api/queues" && r.URL.Query().Get("columns") != queueColumns {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(managementResponses[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	input := &InputRabbitMQ{URL: server.URL + "/", Username: "guest", Password: "guest", ExcludeQueuePattern: "^amq\\.", Vhosts: []string{"/"}}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	labels := "cluster#$#rabbit@node1|instance#$#" + strings.TrimPrefix(server.URL, "http://")
+	node1 := labels + "|node#$#rabbit@node1"
+	node2 := labels + "|node#$#rabbit@node2"
+	orders := labels + "|queue#$#orders|vhost#$#/"
+	assert.Equal(t, []string{
+		"rabbitmq_node_disk_free_alarm{" + node1 + "} 1",
+		"rabbitmq_node_mem_alarm{" + node1 + "} 0",
+		"rabbitmq_node_mem_limit_bytes{" + node1 + "} 4096",
+		"rabbitmq_node_mem_used_bytes{" + node1 + "} 1024",
+		"rabbitmq_node_running{" + node1 + "} 1",
+		"rabbitmq_node_running{" + node2 + "} 0",
+		"rabbitmq_overview_connections{" + labels + "} 3",
+		"rabbitmq_overview_consumers{" + labels + "} 2",
+		"rabbitmq_overview_messages_published_total{" + labels + "} 100",
+		"rabbitmq_overview_messages{" + labels + "} 15",
+		"rabbitmq_overview_queues{" + labels + "} 2",
+		"rabbitmq_queue_consumers{" + orders + "} 1",
+		"rabbitmq_queue_messages_acked_total{" + orders + "} 50",
+		"rabbitmq_queue_messages_published_total{" + orders + "} 60",
+		"rabbitmq_queue_messages_ready{" + orders + "} 8",
+		"rabbitmq_queue_messages_unacknowledged{" + orders + "} 2",
+		"rabbitmq_queue_messages{" + orders + "} 10",
+		"rabbitmq_queue_running{" + orders + "} 1",
+		"rabbitmq_up{" + labels + "} 1",
+	}, test.MetricLines(collector.Logs))
+
+	input.Password = "wrong"
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []string{"rabbitmq_up{instance#$#" + strings.TrimPrefix(server.URL, "http://") + "} 0"}, test.MetricLines(collector.Logs))
+}
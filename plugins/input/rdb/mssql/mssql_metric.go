@@ -0,0 +1,166 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mssql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/input/rdb/rdbmetric"
+)
+
+// benignWaits are the idle waits of the background tasks, which are excluded from the wait stats.
+var benignWaits = []string{
+	"BROKER_EVENTHANDLER", "BROKER_RECEIVE_WAITFOR", "BROKER_TASK_STOP", "BROKER_TO_FLUSH", "CHECKPOINT_QUEUE",
+	"CLR_AUTO_EVENT", "CLR_MANUAL_EVENT", "DIRTY_PAGE_POLL", "FT_IFTS_SCHEDULER_IDLE_WAIT",
+	"HADR_FILESTREAM_IOMGR_IOCOMPLETION", "LAZYWRITER_SLEEP", "LOGMGR_QUEUE", "QDS_PERSIST_TASK_MAIN_LOOP_SLEEP",
+	"QDS_CLEANUP_STALE_QUERIES_TASK_MAIN_LOOP_SLEEP", "REQUEST_FOR_DEADLOCK_SEARCH", "SP_SERVER_DIAGNOSTICS_SLEEP",
+	"SQLTRACE_BUFFER_FLUSH", "SQLTRACE_INCREMENTAL_FLUSH_SLEEP", "WAITFOR", "XE_DISPATCHER_WAIT", "XE_TIMER_EVENT",
+}
+
+var performanceCounters = []string{
+	"Batch Requests/sec", "SQL Compilations/sec", "SQL Re-Compilations/sec", "User Connections", "Lock Waits/sec",
+	"Number of Deadlocks/sec", "Page life expectancy", "Transactions/sec", "Processes blocked", "Memory Grants Pending",
+}
+
+// MssqlMetric collects the wait stats, performance counters, sessions, database sizes and the slowest queries
+// of SQL Server from the dynamic management views.
+type MssqlMetric struct {
+	rdbmetric.Base
+	Address              string
+	Port                 int
+	DataBase             string
+	Encrypt              string // disable, false or true
+	WaitStatsTopN        int
+	SlowQueryTopN        int
+	SlowQueryThresholdMs int
+
+	queries []rdbmetric.Query
+}
+
+func (m *MssqlMetric) Init(context pipeline.Context) (int, error) {
+	if err := m.Base.Init(context, "sqlserver"); err != nil {
+		return 0, err
+	}
+	if m.Port == 0 {
+		m.Port = 1433
+	}
+	m.queries = m.buildQueries()
+	return 0, nil
+}
+
+func (m *MssqlMetric) Description() string {
+	return "sql server dmv metrics input plugin for logtail"
+}
+
+func (m *MssqlMetric) buildQueries() []rdbmetric.Query {
+	queries := []rdbmetric.Query{
+		{
+			Name: "mssql_performance",
+			SQL: "SELECT RTRIM(counter_name) AS counter_name, RTRIM(instance_name) AS instance_name, cntr_value " +
+				"FROM sys.dm_os_performance_counters WHERE counter_name IN (" + quoteList(performanceCounters) + ")",
+			Labels:       []string{"instance_name"},
+			Values:       []string{"cntr_value"},
+			MetricColumn: "counter_name",
+		},
+		{
+			Name:   "mssql",
+			SQL:    "SELECT status, COUNT(*) AS sessions FROM sys.dm_exec_sessions WHERE is_user_process = 1 GROUP BY status",
+			Labels: []string{"status"},
+			Values: []string{"sessions"},
+		},
+		{
+			Name: "mssql_database",
+			SQL: "SELECT DB_NAME(database_id) AS database_name, type_desc AS file_type, SUM(CAST(size AS BIGINT)) * 8192 AS size_bytes " +
+				"FROM sys.master_files GROUP BY database_id, type_desc",
+			Labels: []string{"database_name", "file_type"},
+			Values: []string{"size_bytes"},
+		},
+	}
+	if m.WaitStatsTopN > 0 {
+		queries = append(queries, rdbmetric.Query{
+			Name: "mssql_wait",
+			SQL: fmt.Sprintf("SELECT TOP %d wait_type, waiting_tasks_count, wait_time_ms, signal_wait_time_ms FROM sys.dm_os_wait_stats "+
+				"WHERE waiting_tasks_count > 0 AND wait_type NOT LIKE 'SLEEP%%' AND wait_type NOT IN (%s) ORDER BY wait_time_ms DESC",
+				m.WaitStatsTopN, quoteList(benignWaits)),
+			Labels: []string{"wait_type"},
+			Values: []string{"waiting_tasks_count", "wait_time_ms", "signal_wait_time_ms"},
+		})
+	}
+	if m.SlowQueryTopN > 0 {
+		queries = append(queries, rdbmetric.Query{
+			Name: "mssql_slow_query",
+			SQL: fmt.Sprintf("SELECT TOP %d CONVERT(VARCHAR(64), qs.query_hash, 1) AS query_hash, SUBSTRING(st.text, 1, 256) AS sql_text, "+
+				"qs.execution_count, qs.total_elapsed_time / qs.execution_count / 1000.0 AS avg_elapsed_ms, "+
+				"qs.total_worker_time / qs.execution_count / 1000.0 AS avg_cpu_ms, qs.total_logical_reads / qs.execution_count AS avg_logical_reads "+
+				"FROM sys.dm_exec_query_stats qs CROSS APPLY sys.dm_exec_sql_text(qs.sql_handle) st "+
+				"WHERE qs.execution_count > 0 AND qs.total_elapsed_time / qs.execution_count >= %d "+
+				"ORDER BY qs.total_elapsed_time / qs.execution_count DESC",
+				m.SlowQueryTopN, m.SlowQueryThresholdMs*1000),
+			Labels: []string{"query_hash", "sql_text"},
+			Values: []string{"execution_count", "avg_elapsed_ms", "avg_cpu_ms", "avg_logical_reads"},
+		})
+	}
+	return queries
+}
+
+func (m *MssqlMetric) dsn(user, password string) string {
+	query := url.Values{}
+	if m.DataBase != "" {
+		query.Set("database", m.DataBase)
+	}
+	query.Set("encrypt", m.Encrypt)
+	query.Set("dial timeout", strconv.Itoa(m.TimeoutMs/1000))
+	u := &url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(user, password),
+		Host:     net.JoinHostPort(m.Address, strconv.Itoa(m.Port)),
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+func (m *MssqlMetric) Collect(collector pipeline.Collector) error {
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", net.JoinHostPort(m.Address, strconv.Itoa(m.Port)))
+	return m.Base.Collect(collector, "mssql", m.dsn, m.queries, labels)
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func init() {
+	pipeline.MetricInputs["metric_mssql"] = func() pipeline.MetricInput {
+		return &MssqlMetric{
+			Base:                 rdbmetric.Base{TimeoutMs: 10000},
+			Address:              "127.0.0.1",
+			Port:                 1433,
+			Encrypt:              "disable",
+			WaitStatsTopN:        20,
+			SlowQueryTopN:        10,
+			SlowQueryThresholdMs: 1000,
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oracle
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	goora "github.com/sijms/go-ora/v2"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/input/rdb/rdbmetric"
+)
+
+const sysstatNames = "'user commits', 'user rollbacks', 'user calls', 'execute count', 'parse count (total)', 'parse count (hard)', " +
+	"'session logical reads', 'physical reads', 'physical writes', 'redo size', " +
+	"'bytes sent via SQL*Net to client', 'bytes received via SQL*Net from client'"
+
+const sysmetricNames = "'Host CPU Utilization (%)', 'Database CPU Time Ratio', 'Database Wait Time Ratio', 'Average Active Sessions', " +
+	"'Executions Per Sec', 'User Transaction Per Sec', 'Buffer Cache Hit Ratio', 'SQL Service Response Time'"
+
+// OracleMetric collects the AWR-like statistics of Oracle from the dynamic performance views, including the
+// system statistics and metrics, wait classes, sessions, tablespace usage and the slowest sql.
+type OracleMetric struct {
+	rdbmetric.Base
+	Address              string
+	Port                 int
+	ServiceName          string
+	SlowQueryTopN        int
+	SlowQueryThresholdMs int
+
+	queries []rdbmetric.Query
+}
+
+func (o *OracleMetric) Init(context pipeline.Context) (int, error) {
+	if o.ServiceName == "" {
+		return 0, fmt.Errorf("service name is required")
+	}
+	if err := o.Base.Init(context, "oracle"); err != nil {
+		return 0, err
+	}
+	if o.Port == 0 {
+		o.Port = 1521
+	}
+	o.queries = o.buildQueries()
+	return 0, nil
+}
+
+func (o *OracleMetric) Description() string {
+	return "oracle performance metrics input plugin for logtail"
+}
+
+func (o *OracleMetric) buildQueries() []rdbmetric.Query {
+	queries := []rdbmetric.Query{
+		{
+			Name:         "oracle_sysstat",
+			SQL:          "SELECT name, value FROM v$sysstat WHERE name IN (" + sysstatNames + ")",
+			Values:       []string{"value"},
+			MetricColumn: "name",
+		},
+		{
+			Name:         "oracle_sysmetric",
+			SQL:          "SELECT metric_name, value FROM v$sysmetric WHERE group_id = 2 AND metric_name IN (" + sysmetricNames + ")",
+			Values:       []string{"value"},
+			MetricColumn: "metric_name",
+		},
+		{
+			Name:   "oracle_wait_class",
+			SQL:    "SELECT wait_class, total_waits, time_waited_micro FROM v$system_wait_class WHERE wait_class <> 'Idle'",
+			Labels: []string{"wait_class"},
+			Values: []string{"total_waits", "time_waited_micro"},
+		},
+		{
+			Name:   "oracle",
+			SQL:    "SELECT status, type, COUNT(*) AS sessions FROM v$session GROUP BY status, type",
+			Labels: []string{"status", "type"},
+			Values: []string{"sessions"},
+		},
+		{
+			Name:   "oracle_tablespace",
+			SQL:    "SELECT tablespace_name, used_percent FROM dba_tablespace_usage_metrics",
+			Labels: []string{"tablespace_name"},
+			Values: []string{"used_percent"},
+		},
+	}
+	if o.SlowQueryTopN > 0 {
+		queries = append(queries, rdbmetric.Query{
+			Name: "oracle_slow_query",
+			SQL: fmt.Sprintf("SELECT * FROM (SELECT sql_id, SUBSTR(sql_text, 1, 256) AS sql_text, executions, "+
+				"elapsed_time / executions / 1000 AS avg_elapsed_ms, cpu_time / executions / 1000 AS avg_cpu_ms, "+
+				"buffer_gets / executions AS avg_buffer_gets, disk_reads / executions AS avg_disk_reads "+
+				"FROM v$sql WHERE executions > 0 AND elapsed_time / executions >= %d ORDER BY elapsed_time / executions DESC) "+
+				"WHERE ROWNUM <= %d", o.SlowQueryThresholdMs*1000, o.SlowQueryTopN),
+			Labels: []string{"sql_id", "sql_text"},
+			Values: []string{"executions", "avg_elapsed_ms", "avg_cpu_ms", "avg_buffer_gets", "avg_disk_reads"},
+		})
+	}
+	return queries
+}
+
+func (o *OracleMetric) dsn(user, password string) string {
+	return goora.BuildUrl(o.Address, o.Port, o.ServiceName, user, password, map[string]string{
+		"CONNECTION TIMEOUT": strconv.Itoa(o.TimeoutMs / 1000),
+	})
+}
+
+func (o *OracleMetric) Collect(collector pipeline.Collector) error {
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", net.JoinHostPort(o.Address, strconv.Itoa(o.Port)))
+	labels.Append("service_name", o.ServiceName)
+	return o.Base.Collect(collector, "oracle", o.dsn, o.queries, labels)
+}
+
+func init() {
+	pipeline.MetricInputs["metric_oracle"] = func() pipeline.MetricInput {
+		return &OracleMetric{
+			Base:                 rdbmetric.Base{TimeoutMs: 10000},
+			Address:              "127.0.0.1",
+			Port:                 1521,
+			SlowQueryTopN:        10,
+			SlowQueryThresholdMs: 1000,
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oracle
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+// fakeDriver answers the queries of the plugin with the rows of the view they select from.
+type fakeDriver struct{}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var fakeResults = map[string]fakeResult{
+	"FROM v$sysstat ": {
+		columns: []string{"NAME", "VALUE"},
+		rows:    [][]driver.Value{{"user commits", int64(42)}, {"parse count (hard)", int64(7)}},
+	},
+	"FROM v$sysmetric ": {
+		columns: []string{"METRIC_NAME", "VALUE"},
+		rows:    [][]driver.Value{{"Host CPU Utilization (%)", 12.5}, {"Average Active Sessions", "0.75"}},
+	},
+	"FROM v$system_wait_class ": {
+		columns: []string{"WAIT_CLASS", "TOTAL_WAITS", "TIME_WAITED_MICRO"},
+		rows:    [][]driver.Value{{"User I/O", int64(100), int64(2500)}},
+	},
+	"FROM v$session ": {
+		columns: []string{"STATUS", "TYPE", "SESSIONS"},
+		rows:    [][]driver.Value{{"ACTIVE", "USER", int64(3)}, {"INACTIVE", "BACKGROUND", nil}},
+	},
+	"FROM dba_tablespace_usage_metrics": {
+		columns: []string{"TABLESPACE_NAME", "USED_PERCENT"},
+		rows:    [][]driver.Value{{"SYSTEM", []byte("81.25")}},
+	},
+	"FROM v$sql ": {
+		columns: []string{"SQL_ID", "SQL_TEXT", "EXECUTIONS", "AVG_ELAPSED_MS", "AVG_CPU_MS", "AVG_BUFFER_GETS", "AVG_DISK_READS"},
+		rows:    [][]driver.Value{{"abc123", "SELECT * FROM orders ", int64(4), 1500.5, 20.0, int64(300), int64(0)}},
+	},
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type fakeStmt struct {
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 0 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	for view, result := range fakeResults {
+		if strings.Contains(s.query, view) {
+			return &fakeRows{result: result}, nil
+		}
+	}
+	return nil, fmt.Errorf("table or view does not exist")
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+func init() {
+	sql.Register("oracle_fake", fakeDriver{})
+}
+
+func TestOracleMetricInit(t *testing.T) {
+	o := pipeline.MetricInputs["metric_oracle"]().(*OracleMetric)
+	_, err := o.Init(mock.NewEmptyContext("p", "l", "c"))
+	assert.Error(t, err)
+
+	o.Address = "db.example.com"
+	o.ServiceName = "ORCLPDB1"
+	o.SlowQueryTopN = 0
+	_, err = o.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, "oracle://system:p@ss@db.example.com:1521/ORCLPDB1?CONNECTION TIMEOUT=10", o.dsn("system", "p@ss"))
+
+	var names []string
+	for _, q := range o.queries {
+		names = append(names, q.Name)
+	}
+	assert.Equal(t, []string{"oracle_sysstat", "oracle_sysmetric", "oracle_wait_class", "oracle", "oracle_tablespace"}, names)
+}
+
+func TestOracleMetricCollect(t *testing.T) {
+	o := pipeline.MetricInputs["metric_oracle"]().(*OracleMetric)
+	o.Address = "db.example.com"
+	o.ServiceName = "ORCLPDB1"
+	o.Labels = map[string]string{"cluster": "c1"}
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	_, err := o.Init(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, o.queries[5].SQL, "elapsed_time / executions >= 1000000 ")
+	assert.Contains(t, o.queries[5].SQL, "WHERE ROWNUM <= 10")
+	// answer the queries by the fake driver instead of go-ora
+	require.NoError(t, o.Base.Init(ctx, "oracle_fake"))
+
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, o.Collect(collector))
+	labels := "cluster#$#c1|instance#$#db.example.com:1521|service_name#$#ORCLPDB1"
+	assert.Equal(t, []string{
+		"oracle_sessions{" + labels + "|status#$#ACTIVE|type#$#USER} 3",
+		"oracle_slow_query_avg_buffer_gets{" + labels + "|sql_id#$#abc123|sql_text#$#SELECT * FROM orders} 300",
+		"oracle_slow_query_avg_cpu_ms{" + labels + "|sql_id#$#abc123|sql_text#$#SELECT * FROM orders} 20",
+		"oracle_slow_query_avg_disk_reads{" + labels + "|sql_id#$#abc123|sql_text#$#SELECT * FROM orders} 0",
+		"oracle_slow_query_avg_elapsed_ms{" + labels + "|sql_id#$#abc123|sql_text#$#SELECT * FROM orders} 1500.5",
+		"oracle_slow_query_executions{" + labels + "|sql_id#$#abc123|sql_text#$#SELECT * FROM orders} 4",
+		"oracle_sysmetric_average_active_sessions{" + labels + "} 0.75",
+		"oracle_sysmetric_host_cpu_utilization{" + labels + "} 12.5",
+		"oracle_sysstat_parse_count_hard{" + labels + "} 7",
+		"oracle_sysstat_user_commits{" + labels + "} 42",
+		"oracle_tablespace_used_percent{" + labels + "|tablespace_name#$#SYSTEM} 81.25",
+		"oracle_up{" + labels + "} 1",
+		"oracle_wait_class_time_waited_micro{" + labels + "|wait_class#$#User I/O} 2500",
+		"oracle_wait_class_total_waits{" + labels + "|wait_class#$#User I/O} 100",
+	}, test.MetricLines(collector.Logs))
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbmetric
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
+)
+
+// Query turns the rows of a statement into metrics. Each column in Values becomes a metric named
+// <Name>_<column>, or <Name>_<value of MetricColumn> when MetricColumn is set, and the columns in Labels
+// become the labels. The column names are matched case-insensitively.
+type Query struct {
+	Name         string
+	SQL          string
+	Labels       []string
+	Values       []string
+	MetricColumn string
+}
+
+// DSNFunc builds the data source name with the resolved credentials.
+type DSNFunc func(user, password string) string
+
+// Base holds the connection and credential settings shared by the database metric inputs.
+type Base struct {
+	User           string
+	Password       string
+	SecretProvider *extensions.ExtensionConfig // resolve the credentials by the secret provider extension
+	UserSecret     string                      // the secret name of the user
+	PasswordSecret string                      // the secret name of the password
+	TimeoutMs      int
+	Labels         map[string]string
+	CustomQueries  []Query
+
+	context  pipeline.Context
+	driver   string
+	alarm    string
+	provider extensions.SecretProvider
+}
+
+// Init checks the config and initializes the secret provider, the driver must be registered to database/sql.
+func (b *Base) Init(context pipeline.Context, driver string) error {
+	b.context = context
+	b.driver = driver
+	b.alarm = strings.ToUpper(driver) + "_COLLECT_ALARM"
+	if b.TimeoutMs <= 0 {
+		b.TimeoutMs = 10000
+	}
+	for i := range b.CustomQueries {
+		if err := b.CustomQueries[i].check(); err != nil {
+			return err
+		}
+	}
+	if b.SecretProvider == nil {
+		if b.UserSecret != "" || b.PasswordSecret != "" {
+			return fmt.Errorf("secret provider is required to resolve the secrets")
+		}
+		return nil
+	}
+	ext, err := context.GetExtension(b.SecretProvider.Type, b.SecretProvider.Options)
+	if err != nil {
+		return fmt.Errorf("get secret provider %s error: %v", b.SecretProvider.Type, err)
+	}
+	provider, ok := ext.(extensions.SecretProvider)
+	if !ok {
+		return fmt.Errorf("secret provider(%s) not implement interface extensions.SecretProvider", b.SecretProvider.Type)
+	}
+	b.provider = provider
+	return nil
+}
+
+// credentials returns the user and password, the secrets take precedence over the plain config.
+func (b *Base) credentials() (user, password string, err error) {
+	user, password = b.User, b.Password
+	if b.provider == nil {
+		return
+	}
+	if b.UserSecret != "" {
+		if user, err = b.provider.GetSecret(b.UserSecret); err != nil {
+			return "", "", err
+		}
+	}
+	if b.PasswordSecret != "" {
+		if password, err = b.provider.GetSecret(b.PasswordSecret); err != nil {
+			return "", "", err
+		}
+	}
+	return
+}
+
+// connect opens the database with the credentials resolved at each collection, so that the rotated secrets
+// take effect. The metric inputs are never stopped, so the database is not kept open between collections.
+func (b *Base) connect(ctx context.Context, dsn DSNFunc) (*sql.DB, error) {
+	user, password, err := b.credentials()
+	if err != nil {
+		return nil, fmt.Errorf("resolve credentials error: %v", err)
+	}
+	db, err := sql.Open(b.driver, dsn(user, password))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Collect runs the queries and the custom queries, and exports <prefix>_up for the connectivity.
+func (b *Base) Collect(collector pipeline.Collector, prefix string, dsn DSNFunc, queries []Query, labels *helper.MetricLabels) error {
+	for k, v := range b.Labels {
+		labels.Append(k, v)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(b.TimeoutMs)*time.Millisecond)
+	defer cancel()
+	db, err := b.connect(ctx, dsn)
+	if err != nil {
+		logger.Warning(b.context.GetRuntimeContext(), b.alarm, "connect error", err)
+		collector.AddRawLog(helper.NewMetricLog(prefix+"_up", time.Now().UnixNano(), 0, labels))
+		return nil
+	}
+	defer db.Close() //nolint:errcheck
+	collector.AddRawLog(helper.NewMetricLog(prefix+"_up", time.Now().UnixNano(), 1, labels))
+	for _, queryList := range [][]Query{queries, b.CustomQueries} {
+		for i := range queryList {
+			if err := queryList[i].collect(ctx, db, labels, collector); err != nil {
+				logger.Warning(b.context.GetRuntimeContext(), b.alarm, "query", queryList[i].Name, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (q *Query) check() error {
+	if q.Name == "" || q.SQL == "" || len(q.Values) == 0 {
+		return fmt.Errorf("query must have name, sql and values")
+	}
+	if q.MetricColumn != "" && len(q.Values) != 1 {
+		return fmt.Errorf("query %s with metric column must have exactly one value column", q.Name)
+	}
+	return nil
+}
+
+func (q *Query) collect(ctx context.Context, db *sql.DB, labels *helper.MetricLabels, collector pipeline.Collector) error {
+	rows, err := db.QueryContext(ctx, q.SQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	columnIndex := make(map[string]int, len(columns))
+	for i, column := range columns {
+		columnIndex[strings.ToLower(column)] = i
+	}
+	indexOf := func(names []string) ([]int, error) {
+		res := make([]int, len(names))
+		for i, name := range names {
+			index, ok := columnIndex[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("column %s not found", name)
+			}
+			res[i] = index
+		}
+		return res, nil
+	}
+	labelIndexes, err := indexOf(q.Labels)
+	if err != nil {
+		return err
+	}
+	valueIndexes, err := indexOf(q.Values)
+	if err != nil {
+		return err
+	}
+	metricIndex := -1
+	if q.MetricColumn != "" {
+		indexes, err := indexOf([]string{q.MetricColumn})
+		if err != nil {
+			return err
+		}
+		metricIndex = indexes[0]
+	}
+
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	now := time.Now().UnixNano()
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return err
+		}
+		rowLabels := labels.Clone()
+		for i, index := range labelIndexes {
+			rowLabels.Append(strings.ToLower(q.Labels[i]), strings.TrimSpace(values[index].String))
+		}
+		for i, index := range valueIndexes {
+			if !values[index].Valid {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(values[index].String), 64)
+			if err != nil {
+				continue
+			}
+			name := q.Name + "_" + sanitizeName(q.Values[i])
+			if metricIndex >= 0 {
+				name = q.Name + "_" + sanitizeName(values[metricIndex].String)
+			}
+			collector.AddRawLog(helper.NewMetricLog(name, now, value, rowLabels))
+		}
+	}
+	return rows.Err()
+}
+
+// sanitizeName lowercases the name and replaces the runs of characters other than letters and digits with
+// a single underscore, e.g. "Parse Count (Total)" becomes "parse_count_total".
+func sanitizeName(name string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && sb.Len() > 0 {
+				sb.WriteByte('_')
+			}
+			underscore = false
+			sb.WriteRune(r)
+		} else {
+			underscore = true
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdbmetric
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/pipeline/extensions"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+// fakeDriver accepts the dsn "user:password" and answers the queries with the rows in fakeResults.
+type fakeDriver struct{}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var fakeResults = map[string]fakeResult{
+	"SELECT name, value FROM stats": {
+		columns: []string{"NAME", "VALUE"},
+		rows:    [][]driver.Value{{"Parse Count (Total)", int64(10)}, {"user commits", 3.5}, {"bad", "x"}},
+	},
+	"SELECT wait_type, wait_time_ms, waiting_tasks_count FROM waits": {
+		columns: []string{"wait_type", "wait_time_ms", "waiting_tasks_count"},
+		rows:    [][]driver.Value{{"LCK_M_X", []byte("1.5"), nil}},
+	},
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn != "root:secret" {
+		return nil, fmt.Errorf("access denied for %s", dsn)
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type fakeStmt struct {
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 0 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	result, ok := fakeResults[s.query]
+	if !ok {
+		return nil, fmt.Errorf("table not found")
+	}
+	return &fakeRows{result: result}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+type fakeSecretProvider struct {
+	secrets map[string]string
+}
+
+func (p *fakeSecretProvider) Description() string                 { return "" }
+func (p *fakeSecretProvider) Init(context pipeline.Context) error { return nil }
+func (p *fakeSecretProvider) Stop() error                         { return nil }
+func (p *fakeSecretProvider) GetSecret(name string) (string, error) {
+	if secret, ok := p.secrets[name]; ok {
+		return secret, nil
+	}
+	return "", fmt.Errorf("secret %s not found", name)
+}
+
+var secrets = map[string]string{"db_user": "root"}
+
+func init() {
+	sql.Register("rdbmetric_fake", fakeDriver{})
+	pipeline.AddExtensionCreator("ext_fake_secret_provider", func() pipeline.Extension {
+		return &fakeSecretProvider{secrets: secrets}
+	})
+}
+
+func TestCollect(t *testing.T) {
+	b := &Base{
+		SecretProvider: &extensions.ExtensionConfig{Type: "ext_fake_secret_provider"},
+		UserSecret:     "db_user",
+		PasswordSecret: "db_password",
+		Labels:         map[string]string{"cluster": "c1"},
+		CustomQueries: []Query{{
+			Name:   "fake_wait",
+			SQL:    "SELECT wait_type, wait_time_ms, waiting_tasks_count FROM waits",
+			Labels: []string{"WAIT_TYPE"},
+			Values: []string{"wait_time_ms", "waiting_tasks_count"},
+		}},
+	}
+	require.NoError(t, b.Init(mock.NewEmptyContext("p", "l", "c"), "rdbmetric_fake"))
+	queries := []Query{
+		{Name: "fake_sysstat", SQL: "SELECT name, value FROM stats", Values: []string{"value"}, MetricColumn: "name"},
+		{Name: "fake_missing", SQL: "SELECT a FROM missing", Values: []string{"a"}},
+	}
+	dsn := func(user, password string) string {
+		return user + ":" + password
+	}
+	newLabels := func() *helper.MetricLabels {
+		labels := &helper.MetricLabels{}
+		labels.Append("instance", "db1")
+		return labels
+	}
+
+	// the password secret is missing
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, b.Collect(collector, "fake", dsn, queries, newLabels()))
+	assert.Equal(t, []string{"fake_up{cluster#$#c1|instance#$#db1} 0"}, test.MetricLines(collector.Logs))
+
+	// the secret is rotated in
+	secrets["db_password"] = "secret"
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, b.Collect(collector, "fake", dsn, queries, newLabels()))
+	assert.Equal(t, []string{
+		"fake_sysstat_parse_count_total{cluster#$#c1|instance#$#db1} 10",
+		"fake_sysstat_user_commits{cluster#$#c1|instance#$#db1} 3.5",
+		"fake_up{cluster#$#c1|instance#$#db1} 1",
+		"fake_wait_wait_time_ms{cluster#$#c1|instance#$#db1|wait_type#$#LCK_M_X} 1.5",
+	}, test.MetricLines(collector.Logs))
+}
+
+func TestInitCheck(t *testing.T) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	b := &Base{PasswordSecret: "db_password"}
+	assert.Error(t, b.Init(ctx, "rdbmetric_fake"))
+	b = &Base{SecretProvider: &extensions.ExtensionConfig{Type: "ext_basicauth_missing"}}
+	assert.Error(t, b.Init(ctx, "rdbmetric_fake"))
+	b = &Base{CustomQueries: []Query{{Name: "q", SQL: "SELECT 1", Values: []string{"a", "b"}, MetricColumn: "name"}}}
+	assert.Error(t, b.Init(ctx, "rdbmetric_fake"))
+	b = &Base{}
+	require.NoError(t, b.Init(ctx, "rdbmetric_fake"))
+	assert.Equal(t, 10000, b.TimeoutMs)
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "parse_count_total", sanitizeName("Parse Count (Total)"))
+	assert.Equal(t, "batch_requests_sec", sanitizeName("Batch Requests/sec  "))
+	assert.Equal(t, "host_cpu_utilization", sanitizeName("Host CPU Utilization (%)"))
+	assert.Equal(t, "", sanitizeName("()"))
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redfish
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	metricPrefix = "redfish_"
+
+	serviceRoot = "/redfish/v1"
+	maxBodySize = 16 * 1024 * 1024
+)
+
+// healthValues encodes the redfish health, unknown health is not exported.
+var healthValues = map[string]float64{
+	"OK":       0,
+	"Warning":  1,
+	"Critical": 2,
+}
+
+type odataLink struct {
+	ID string `json:"@odata.id"`
+}
+
+type collection struct {
+	Members []odataLink `json:"Members"`
+}
+
+type status struct {
+	State  string `json:"State"`
+	Health string `json:"Health"`
+}
+
+type chassis struct {
+	ID      string    `json:"Id"`
+	Status  status    `json:"Status"`
+	Thermal odataLink `json:"Thermal"`
+	Power   odataLink `json:"Power"`
+}
+
+type system struct {
+	ID         string `json:"Id"`
+	PowerState string `json:"PowerState"`
+	Status     status `json:"Status"`
+}
+
+type thermal struct {
+	Temperatures []struct {
+		Name           string   `json:"Name"`
+		ReadingCelsius *float64 `json:"ReadingCelsius"`
+		Status         status   `json:"Status"`
+	} `json:"Temperatures"`
+	Fans []struct {
+		Name         string   `json:"Name"`
+		FanName      string   `json:"FanName"`
+		Reading      *float64 `json:"Reading"`
+		ReadingUnits string   `json:"ReadingUnits"`
+		Status       status   `json:"Status"`
+	} `json:"Fans"`
+}
+
+type power struct {
+	PowerControl []struct {
+		Name               string   `json:"Name"`
+		PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+	} `json:"PowerControl"`
+	PowerSupplies []struct {
+		Name                 string   `json:"Name"`
+		LastPowerOutputWatts *float64 `json:"LastPowerOutputWatts"`
+		LineInputVoltage     *float64 `json:"LineInputVoltage"`
+		Status               status   `json:"Status"`
+	} `json:"PowerSupplies"`
+	Voltages []struct {
+		Name         string   `json:"Name"`
+		ReadingVolts *float64 `json:"ReadingVolts"`
+		Status       status   `json:"Status"`
+	} `json:"Voltages"`
+}
+
+// InputRedfish collects the temperatures, fan speeds, power draw and health status from BMCs through the
+// Redfish API, and falls back to ipmitool for the BMCs without Redfish support.
+type InputRedfish struct {
+	// Servers are the BMC addresses, e.g. "https://10.0.0.10"
+	Servers            []string
+	Username           string
+	Password           string
+	SSLCA              string
+	SkipInsecureVerify bool
+	TimeoutMs          int
+	IPMIFallback       bool   // collect the sensors by ipmitool over lanplus if the redfish API is unavailable
+	LocalIPMI          bool   // collect the sensors of the local BMC by ipmitool in-band
+	IPMIToolPath       string // the path of ipmitool
+	Labels             map[string]string
+
+	client  *http.Client
+	context pipeline.Context
+	ipmi    *ipmiTool
+}
+
+func (r *InputRedfish) Init(context pipeline.Context) (int, error) {
+	r.context = context
+	if len(r.Servers) == 0 && !r.LocalIPMI {
+		return 0, fmt.Errorf("neither servers nor local ipmi is configured")
+	}
+	if r.TimeoutMs <= 0 {
+		r.TimeoutMs = 10000
+	}
+	tlsCfg, err := util.GetTLSConfig("", "", r.SSLCA, r.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	r.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   time.Duration(r.TimeoutMs) * time.Millisecond,
+	}
+	if r.IPMIFallback || r.LocalIPMI {
+		if r.IPMIToolPath == "" {
+			r.IPMIToolPath = "ipmitool"
+		}
+		r.ipmi = &ipmiTool{path: r.IPMIToolPath, timeout: time.Duration(r.TimeoutMs) * time.Millisecond}
+	}
+	return 0, nil
+}
+
+func (r *InputRedfish) Description() string {
+	return "collect the hardware telemetry from BMCs by redfish or ipmi"
+}
+
+func (r *InputRedfish) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, server := range r.Servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			r.collectServer(collector, server)
+		}(server)
+	}
+	if r.LocalIPMI {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.collectIPMI(collector, "localhost", nil); err != nil {
+				logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "collect local ipmi error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (r *InputRedfish) baseLabels(instance string) *helper.MetricLabels {
+	labels := &helper.MetricLabels{}
+	labels.Append("instance", instance)
+	for k, v := range r.Labels {
+		labels.Append(k, v)
+	}
+	return labels
+}
+
+func (r *InputRedfish) collectServer(collector pipeline.Collector, server string) {
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "invalid server", server)
+		return
+	}
+	instance := u.Host
+	labels := r.baseLabels(instance)
+	now := time.Now().UnixNano()
+	err = r.collectRedfish(collector, strings.TrimSuffix(server, "/"), labels)
+	if err == nil {
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 1, labels))
+		return
+	}
+	logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "collect redfish error", err, "server", server)
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 0, labels))
+	if r.IPMIFallback {
+		args := []string{"-I", "lanplus", "-H", u.Hostname(), "-U", r.Username, "-P", r.Password}
+		if err = r.collectIPMI(collector, instance, args); err != nil {
+			logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "collect ipmi error", err, "server", server)
+		}
+	}
+}
+
+func (r *InputRedfish) get(base, path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, base+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: unexpected status %s", path, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(v)
+}
+
+func (r *InputRedfish) collectRedfish(collector pipeline.Collector, base string, labels *helper.MetricLabels) error {
+	var chassisList collection
+	if err := r.get(base, serviceRoot+"/Chassis", &chassisList); err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for _, member := range chassisList.Members {
+		var c chassis
+		if err := r.get(base, member.ID, &c); err != nil {
+			logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "get chassis error", err, "chassis", member.ID)
+			continue
+		}
+		chassisLabels := labels.Clone()
+		chassisLabels.Append("chassis", c.ID)
+		addHealth(collector, metricPrefix+"chassis_health", now, c.Status, chassisLabels)
+		if c.Thermal.ID != "" {
+			var t thermal
+			if err := r.get(base, c.Thermal.ID, &t); err != nil {
+				logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "get thermal error", err, "chassis", c.ID)
+			} else {
+				exportThermal(collector, now, &t, chassisLabels)
+			}
+		}
+		if c.Power.ID != "" {
+			var p power
+			if err := r.get(base, c.Power.ID, &p); err != nil {
+				logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "get power error", err, "chassis", c.ID)
+			} else {
+				exportPower(collector, now, &p, chassisLabels)
+			}
+		}
+	}
+
+	var systemList collection
+	if err := r.get(base, serviceRoot+"/Systems", &systemList); err != nil {
+		// some BMCs only expose the chassis
+		logger.Debug(r.context.GetRuntimeContext(), "get systems error", err)
+		return nil
+	}
+	for _, member := range systemList.Members {
+		var s system
+		if err := r.get(base, member.ID, &s); err != nil {
+			logger.Warning(r.context.GetRuntimeContext(), "REDFISH_COLLECT_ALARM", "get system error", err, "system", member.ID)
+			continue
+		}
+		systemLabels := labels.Clone()
+		systemLabels.Append("system", s.ID)
+		addHealth(collector, metricPrefix+"system_health", now, s.Status, systemLabels)
+		powerOn := 0.
+		if s.PowerState == "On" {
+			powerOn = 1
+		}
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"system_power_on", now, powerOn, systemLabels))
+	}
+	return nil
+}
+
+func exportThermal(collector pipeline.Collector, now int64, t *thermal, labels *helper.MetricLabels) {
+	for _, temp := range t.Temperatures {
+		if temp.ReadingCelsius == nil || temp.Status.State == "Absent" {
+			continue
+		}
+		sensorLabels := readingLabels(labels, temp.Name, temp.Status)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"temperature_celsius", now, *temp.ReadingCelsius, sensorLabels))
+	}
+	for _, fan := range t.Fans {
+		if fan.Reading == nil || fan.Status.State == "Absent" {
+			continue
+		}
+		name := fan.Name
+		if name == "" {
+			name = fan.FanName
+		}
+		sensorLabels := readingLabels(labels, name, fan.Status)
+		unit := fan.ReadingUnits
+		if unit == "" {
+			unit = "RPM"
+		}
+		sensorLabels.Append("unit", unit)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"fan_speed", now, *fan.Reading, sensorLabels))
+	}
+}
+
+func exportPower(collector pipeline.Collector, now int64, p *power, labels *helper.MetricLabels) {
+	for _, control := range p.PowerControl {
+		if control.PowerConsumedWatts == nil {
+			continue
+		}
+		sensorLabels := labels.Clone()
+		sensorLabels.Append("name", control.Name)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"power_consumed_watts", now, *control.PowerConsumedWatts, sensorLabels))
+	}
+	for _, supply := range p.PowerSupplies {
+		if supply.Status.State == "Absent" {
+			continue
+		}
+		sensorLabels := labels.Clone()
+		sensorLabels.Append("name", supply.Name)
+		addHealth(collector, metricPrefix+"power_supply_health", now, supply.Status, sensorLabels)
+		if supply.LastPowerOutputWatts != nil {
+			collector.AddRawLog(helper.NewMetricLog(metricPrefix+"power_supply_output_watts", now, *supply.LastPowerOutputWatts, sensorLabels))
+		}
+		if supply.LineInputVoltage != nil {
+			collector.AddRawLog(helper.NewMetricLog(metricPrefix+"power_supply_input_volts", now, *supply.LineInputVoltage, sensorLabels))
+		}
+	}
+	for _, voltage := range p.Voltages {
+		if voltage.ReadingVolts == nil || voltage.Status.State == "Absent" {
+			continue
+		}
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"voltage_volts", now, *voltage.ReadingVolts, readingLabels(labels, voltage.Name, voltage.Status)))
+	}
+}
+
+func readingLabels(labels *helper.MetricLabels, name string, s status) *helper.MetricLabels {
+	sensorLabels := labels.Clone()
+	sensorLabels.Append("name", name)
+	if s.Health != "" {
+		sensorLabels.Append("health", s.Health)
+	}
+	return sensorLabels
+}
+
+func addHealth(collector pipeline.Collector, name string, now int64, s status, labels *helper.MetricLabels) {
+	if value, ok := healthValues[s.Health]; ok {
+		collector.AddRawLog(helper.NewMetricLog(name, now, value, labels))
+	}
+}
+
+func (r *InputRedfish) collectIPMI(collector pipeline.Collector, instance string, args []string) error {
+	sensors, err := r.ipmi.sensors(args)
+	if err != nil {
+		return err
+	}
+	labels := r.baseLabels(instance)
+	now := time.Now().UnixNano()
+	for _, sensor := range sensors {
+		sensorLabels := labels.Clone()
+		sensorLabels.Append("name", sensor.name)
+		sensorLabels.Append("unit", sensor.unit)
+		sensorLabels.Append("status", sensor.status)
+		collector.AddRawLog(helper.NewMetricLog("ipmi_sensor", now, sensor.value, sensorLabels))
+	}
+	return nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_redfish"] = func() pipeline.MetricInput {
+		return &InputRedfish{
+			TimeoutMs:    10000,
+			IPMIToolPath: "ipmitool",
+		}
+	}
+}
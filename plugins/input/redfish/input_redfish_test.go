@@ -0,0 +1,93 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redfish
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+var redfishResources = map[string]string{
+	"/redfish/v1/Chassis":             `{"Members":[{"@odata.id":"/redfish/v1/Chassis/1U"}]}`,
+	"/redfish/v1/Chassis/1U":          `{"Id":"1U","Status":{"State":"Enabled","Health":"Warning"},"Thermal":{"@odata.id":"/redfish/v1/Chassis/1U/Thermal"},"Power":{"@odata.id":"/redfish/v1/Chassis/1U/Power"}}`,
+	"/redfish/v1/Chassis/1U/Thermal":  `{"Temperatures":[{"Name":"CPU1 Temp","ReadingCelsius":45,"Status":{"State":"Enabled","Health":"OK"}},{"Name":"CPU2 Temp","ReadingCelsius":null,"Status":{"State":"Absent"}}],"Fans":[{"FanName":"Fan1","Reading":6000,"Status":{"State":"Enabled","Health":"OK"}}]}`,
+	"/redfish/v1/Chassis/1U/Power":    `{"PowerControl":[{"Name":"System Power Control","PowerConsumedWatts":344}],"PowerSupplies":[{"Name":"PSU1","LastPowerOutputWatts":170,"LineInputVoltage":230,"Status":{"State":"Enabled","Health":"Critical"}}],"Voltages":[{"Name":"VRM1","ReadingVolts":12.1,"Status":{"State":"Enabled","Health":"OK"}}]}`,
+	"/redfish/v1/Systems":             `{"Members":[{"@odata.id":"/redfish/v1/Systems/437XR1138R2"}]}`,
+	"/redfish/v1/Systems/437XR1138R2": `{"Id":"437XR1138R2","PowerState":"On","Status":{"State":"Enabled","Health":"OK"}}`,
+}
+
+func TestRedfishCollect(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "root" || password != "calvin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := redfishResources[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	input := &InputRedfish{Servers: []string{server.URL}, Username: "root", Password: "calvin", SkipInsecureVerify: true}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	instance := strings.TrimPrefix(server.URL, "https://")
+	metrics := test.MetricSamples(collector.Logs)
+	assert.Equal(t, []test.MetricSample{{Labels: "instance#$#" + instance, Value: "1"}}, metrics["redfish_up"])
+	assert.Equal(t, []test.MetricSample{{Labels: "chassis#$#1U|instance#$#" + instance, Value: "1"}}, metrics["redfish_chassis_health"])
+	assert.Equal(t, []test.MetricSample{{Labels: "chassis#$#1U|health#$#OK|instance#$#" + instance + "|name#$#CPU1 Temp", Value: "45"}}, metrics["redfish_temperature_celsius"])
+	assert.Equal(t, []test.MetricSample{{Labels: "chassis#$#1U|health#$#OK|instance#$#" + instance + "|name#$#Fan1|unit#$#RPM", Value: "6000"}}, metrics["redfish_fan_speed"])
+	assert.Equal(t, "344", metrics["redfish_power_consumed_watts"][0].Value)
+	assert.Equal(t, "2", metrics["redfish_power_supply_health"][0].Value)
+	assert.Equal(t, "170", metrics["redfish_power_supply_output_watts"][0].Value)
+	assert.Equal(t, "230", metrics["redfish_power_supply_input_volts"][0].Value)
+	assert.Equal(t, "12.1", metrics["redfish_voltage_volts"][0].Value)
+	assert.Equal(t, []test.MetricSample{{Labels: "instance#$#" + instance + "|system#$#437XR1138R2", Value: "0"}}, metrics["redfish_system_health"])
+	assert.Equal(t, "1", metrics["redfish_system_power_on"][0].Value)
+
+	// wrong credentials
+	input.Password = "wrong"
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, []test.MetricSample{{Labels: "instance#$#" + instance, Value: "0"}}, test.MetricSamples(collector.Logs)["redfish_up"])
+}
+
+func TestParseIPMISensors(t *testing.T) {
+	output := `CPU1 Temp        | 45.000     | degrees C  | ok    | 0.000     | 0.000     | 0.000     | 95.000    | 100.000   | 105.000
+FAN1             | 6000.000   | RPM        | ok    | na        | 300.000   | 500.000   | na        | na        | na
+PS1 Status       | 0x1        | discrete   | 0x0100| na        | na        | na        | na        | na        | na
+Inlet Temp       | na         |            | na    | na        | na        | na        | na        | na        | na
+`
+	sensors := parseIPMISensors([]byte(output))
+	assert.Equal(t, []ipmiSensor{
+		{name: "CPU1 Temp", value: 45, unit: "degrees C", status: "ok"},
+		{name: "FAN1", value: 6000, unit: "RPM", status: "ok"},
+	}, sensors)
+}
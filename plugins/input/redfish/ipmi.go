@@ -0,0 +1,82 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redfish
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ipmiSensor struct {
+	name   string
+	value  float64
+	unit   string
+	status string
+}
+
+// ipmiTool reads the sensors by "ipmitool sensor".
+type ipmiTool struct {
+	path    string
+	timeout time.Duration
+}
+
+func (t *ipmiTool) sensors(args []string) ([]ipmiSensor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, t.path, append(args, "sensor")...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run ipmitool error: %v, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseIPMISensors(stdout.Bytes()), nil
+}
+
+// parseIPMISensors parses the output of "ipmitool sensor", e.g.
+// "CPU1 Temp        | 45.000     | degrees C  | ok    | 0.000     | 0.000     | ..."
+// The discrete sensors and the sensors without reading are skipped.
+func parseIPMISensors(output []byte) []ipmiSensor {
+	var sensors []ipmiSensor
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		columns := strings.Split(scanner.Text(), "|")
+		if len(columns) < 4 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(columns[1]), 64)
+		if err != nil {
+			continue
+		}
+		unit := strings.TrimSpace(columns[2])
+		if unit == "discrete" {
+			continue
+		}
+		sensors = append(sensors, ipmiSensor{
+			name:   strings.TrimSpace(columns[0]),
+			value:  value,
+			unit:   unit,
+			status: strings.TrimSpace(columns[3]),
+		})
+	}
+	return sensors
+}
@@ -195,18 +195,12 @@ func getAddressParts(a string) (string, string, error) {
 		return parts[0], parts[1], nil
 	}
 
-	var host string
-	if u.Hostname() != "" {
-		host = u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "6514"
 	}
-	host += ":"
-	if u.Port() == "" {
-		host += "6514"
-	} else {
-		host += u.Port()
-	}
-
-	return u.Scheme, host, nil
+	// JoinHostPort brackets the ipv6 host, e.g. tcp://[::]:514
+	return u.Scheme, net.JoinHostPort(u.Hostname(), port), nil
 }
 
 func (s *Syslog) resetTimeout(c net.Conn) {
@@ -422,7 +416,11 @@ func (s *Syslog) parse(b []byte, clientIP string, collector pipeline.Collector)
 			fields["_hostname_"] = rst.hostname
 		}
 		if len(clientIP) > 0 {
-			fields["_client_ip_"] = strings.Split(clientIP, ":")[0]
+			if host, _, err := net.SplitHostPort(clientIP); err == nil {
+				fields["_client_ip_"] = host
+			} else {
+				fields["_client_ip_"] = clientIP
+			}
 		} else {
 			fields["_client_ip_"] = ""
 		}
@@ -259,3 +259,21 @@ func TestMockUnixGram(t *testing.T) {
 
 	mockRun(t, syslog, collector)
 }
+
+func TestGetAddressParts(t *testing.T) {
+	cases := []struct {
+		address, scheme, host string
+	}{
+		{"tcp://127.0.0.1:514", "tcp", "127.0.0.1:514"},
+		{"udp://:514", "udp", ":514"},
+		{"tcp6://[::1]:514", "tcp6", "[::1]:514"},
+		{"udp://[::]", "udp", "[::]:6514"},
+		{"unixgram:///tmp/syslog.sock", "unixgram", "/tmp/syslog.sock"},
+	}
+	for _, c := range cases {
+		scheme, host, err := getAddressParts(c.address)
+		require.NoError(t, err, c.address)
+		require.Equal(t, c.scheme, scheme, c.address)
+		require.Equal(t, c.host, host, c.address)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+)
+
+const (
+	metricPrefix = "tls_cert_"
+
+	sourceTypeEndpoint = "endpoint"
+	sourceTypeFile     = "file"
+
+	defaultTLSPort = "443"
+)
+
+// InputTLSCert checks the certificates served by the endpoints or stored in the local files,
+// and emits the expiry and the chain validity of them so that the certificates could be renewed before they lapse.
+type InputTLSCert struct {
+	// Endpoints are the TLS addresses to check, e.g. "example.com:443", "https://example.com", "tcp://10.0.0.1:8443"
+	Endpoints []string
+	// Files are the glob patterns of the local PEM encoded certificate files, e.g. "/etc/nginx/certs/*.pem",
+	// "**" matches any levels of directories and the symbolic links are followed, e.g. "/etc/ssl/**/*.crt"
+	Files                  []string
+	ExcludeFiles           []string // the glob patterns of the files or directories to skip
+	MaxDepth               int      // the max levels of directories matched by "**", 0 means unlimited
+	FileRefreshIntervalSec int      // the interval to rediscover the files, the files are rediscovered on every collection if 0
+	ServerName             string   // overrides the server name used by SNI and the hostname verification of endpoints
+	SSLCA                  string   // the CA file to verify the chains, the system roots are used if empty
+	TimeoutMs              int
+	Labels                 map[string]string
+
+	roots     *x509.CertPool
+	discovery *helper.FileDiscovery
+	context   pipeline.Context
+	now       func() time.Time
+}
+
+func (t *InputTLSCert) Init(context pipeline.Context) (int, error) {
+	t.context = context
+	if len(t.Endpoints) == 0 && len(t.Files) == 0 {
+		return 0, fmt.Errorf("neither endpoints nor files are configured")
+	}
+	if t.TimeoutMs <= 0 {
+		t.TimeoutMs = 5000
+	}
+	if len(t.Files) > 0 {
+		var err error
+		refreshInterval := time.Duration(t.FileRefreshIntervalSec) * time.Second
+		if t.discovery, err = helper.NewFileDiscovery(t.Files, t.ExcludeFiles, t.MaxDepth, refreshInterval, context.GetMetricRecord()); err != nil {
+			return 0, err
+		}
+	}
+	if t.SSLCA != "" {
+		caCert, err := os.ReadFile(filepath.Clean(t.SSLCA))
+		if err != nil {
+			return 0, fmt.Errorf("load CA file error: %v", err)
+		}
+		t.roots = x509.NewCertPool()
+		if !t.roots.AppendCertsFromPEM(caCert) {
+			return 0, fmt.Errorf("no certificate found in CA file %s", t.SSLCA)
+		}
+	}
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return 0, nil
+}
+
+func (t *InputTLSCert) Description() string {
+	return "check the expiry and the chain validity of the certificates served by endpoints or stored in files"
+}
+
+func (t *InputTLSCert) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, endpoint := range t.Endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			certs, serverName, err := t.fetchEndpoint(endpoint)
+			if err != nil {
+				logger.Warning(t.context.GetRuntimeContext(), "TLS_CERT_COLLECT_ALARM", "endpoint", endpoint, "error", err)
+			}
+			t.export(collector, sourceTypeEndpoint, endpoint, serverName, certs, err)
+		}(endpoint)
+	}
+	wg.Wait()
+
+	if t.discovery == nil {
+		return nil
+	}
+	for _, file := range t.discovery.Files() {
+		certs, err := readCertFile(file)
+		if err != nil {
+			logger.Warning(t.context.GetRuntimeContext(), "TLS_CERT_COLLECT_ALARM", "file", file, "error", err)
+		}
+		t.export(collector, sourceTypeFile, file, "", certs, err)
+	}
+	return nil
+}
+
+// fetchEndpoint returns the certificates presented by the endpoint, the leaf is the first one.
+// The handshake never verifies the chain, so that the invalid certificates could also be reported.
+func (t *InputTLSCert) fetchEndpoint(endpoint string) ([]*x509.Certificate, string, error) {
+	address, host, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	serverName := t.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	dialer := &net.Dialer{Timeout: time.Duration(t.TimeoutMs) * time.Millisecond}
+	//nolint:gosec
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err != nil {
+		return nil, serverName, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates, serverName, nil
+}
+
+func (t *InputTLSCert) export(collector pipeline.Collector, sourceType, source, serverName string, certs []*x509.Certificate, fetchErr error) {
+	timestamp := t.now()
+	labels := &helper.MetricLabels{}
+	labels.Append("source", source)
+	labels.Append("source_type", sourceType)
+	for k, v := range t.Labels {
+		labels.Append(k, v)
+	}
+	success := 0.
+	if fetchErr == nil && len(certs) > 0 {
+		success = 1
+	}
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"probe_success", timestamp.UnixNano(), success, labels))
+	if success == 0 {
+		return
+	}
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"chain_valid", timestamp.UnixNano(), t.verifyChain(certs, serverName, timestamp), labels))
+	for i, cert := range certs {
+		certLabels := labels.Clone()
+		certLabels.Append("chain_index", strconv.Itoa(i))
+		certLabels.Append("subject", cert.Subject.CommonName)
+		certLabels.Append("issuer", cert.Issuer.CommonName)
+		certLabels.Append("serial", cert.SerialNumber.Text(16))
+		certLabels.Append("san", strings.Join(subjectAltNames(cert), ","))
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"expiry_seconds", timestamp.UnixNano(), cert.NotAfter.Sub(timestamp).Seconds(), certLabels))
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"not_after", timestamp.UnixNano(), float64(cert.NotAfter.Unix()), certLabels))
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"not_before", timestamp.UnixNano(), float64(cert.NotBefore.Unix()), certLabels))
+	}
+}
+
+// verifyChain returns 1 if the leaf could be verified with the rest of the certificates as intermediates.
+// The hostname is verified only if serverName is not empty.
+func (t *InputTLSCert) verifyChain(certs []*x509.Certificate, serverName string, now time.Time) float64 {
+	opts := x509.VerifyOptions{
+		Roots:         t.roots,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if serverName != "" {
+		opts.DNSName = serverName
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		logger.Debug(t.context.GetRuntimeContext(), "verify chain error", err, "subject", certs[0].Subject.CommonName)
+		return 0
+	}
+	return 1
+}
+
+func parseEndpoint(endpoint string) (address string, host string, err error) {
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", "", err
+		}
+		host, port := u.Hostname(), u.Port()
+		if port == "" {
+			port = defaultTLSPort
+		}
+		return net.JoinHostPort(host, port), host, nil
+	}
+	host, _, err = net.SplitHostPort(endpoint)
+	if err != nil {
+		// no port in the address
+		return net.JoinHostPort(endpoint, defaultTLSPort), endpoint, nil
+	}
+	return endpoint, host, nil
+}
+
+// readCertFile reads all the PEM encoded certificates in the file, other PEM blocks like private keys are skipped.
+func readCertFile(file string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+	return names
+}
+
+func init() {
+	pipeline.MetricInputs["metric_tls_cert"] = func() pipeline.MetricInput {
+		return &InputTLSCert{
+			TimeoutMs: 5000,
+		}
+	}
+}
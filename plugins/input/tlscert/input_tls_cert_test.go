@@ -0,0 +1,137 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscert
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func newServerWithCA(t *testing.T) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, data, 0600))
+	return server, caFile
+}
+
+func TestTLSCertEndpoint(t *testing.T) {
+	server, caFile := newServerWithCA(t)
+	defer server.Close()
+
+	input := &InputTLSCert{Endpoints: []string{server.URL, "127.0.0.1:1"}, ServerName: "example.com", SSLCA: caFile}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	metrics := test.MetricSamples(collector.Logs)
+	require.Len(t, metrics["tls_cert_probe_success"], 2)
+	for _, m := range metrics["tls_cert_probe_success"] {
+		if strings.Contains(m.Labels, "127.0.0.1:1") {
+			assert.Equal(t, "0", m.Value)
+		} else {
+			assert.Equal(t, "1", m.Value)
+		}
+	}
+	require.Len(t, metrics["tls_cert_chain_valid"], 1)
+	assert.Equal(t, "1", metrics["tls_cert_chain_valid"][0].Value)
+	require.Len(t, metrics["tls_cert_expiry_seconds"], 1)
+	assert.Contains(t, metrics["tls_cert_expiry_seconds"][0].Labels, "san#$#example.com,*.example.com,127.0.0.1,::1")
+	assert.Contains(t, metrics["tls_cert_expiry_seconds"][0].Labels, "source_type#$#endpoint")
+
+	// the hostname does not match the certificate
+	input.ServerName = "other.com"
+	collector = &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+	assert.Equal(t, "0", test.MetricSamples(collector.Logs)["tls_cert_chain_valid"][0].Value)
+}
+
+func TestTLSCertFile(t *testing.T) {
+	server, caFile := newServerWithCA(t)
+	server.Close()
+
+	input := &InputTLSCert{Files: []string{filepath.Join(filepath.Dir(caFile), "*.pem")}}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	metrics := test.MetricSamples(collector.Logs)
+	require.Len(t, metrics["tls_cert_probe_success"], 1)
+	assert.Equal(t, "1", metrics["tls_cert_probe_success"][0].Value)
+	// the self signed certificate is not trusted by the system roots
+	assert.Equal(t, "0", metrics["tls_cert_chain_valid"][0].Value)
+	assert.Len(t, metrics["tls_cert_not_after"], 1)
+	assert.Contains(t, metrics["tls_cert_not_after"][0].Labels, "source_type#$#file")
+}
+
+func TestTLSCertFileDiscovery(t *testing.T) {
+	server, caFile := newServerWithCA(t)
+	server.Close()
+	dir := filepath.Dir(caFile)
+	data, err := os.ReadFile(caFile)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nginx", "certs"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nginx", "certs", "server.pem"), data, 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "backup"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backup", "old.pem"), data, 0600))
+
+	input := &InputTLSCert{
+		Files:        []string{filepath.Join(dir, "**", "*.pem")},
+		ExcludeFiles: []string{filepath.Join(dir, "backup")},
+	}
+	_, err = input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	metrics := test.MetricSamples(collector.Logs)
+	require.Len(t, metrics["tls_cert_probe_success"], 2)
+	sources := []string{metrics["tls_cert_probe_success"][0].Labels, metrics["tls_cert_probe_success"][1].Labels}
+	assert.Contains(t, strings.Join(sources, ","), "ca.pem")
+	assert.Contains(t, strings.Join(sources, ","), "server.pem")
+	assert.NotContains(t, strings.Join(sources, ","), "old.pem")
+}
+
+func TestParseEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		address  string
+		host     string
+	}{
+		{"example.com", "example.com:443", "example.com"},
+		{"example.com:8443", "example.com:8443", "example.com"},
+		{"https://example.com/path", "example.com:443", "example.com"},
+		{"tcp://10.0.0.1:636", "10.0.0.1:636", "10.0.0.1"},
+	}
+	for _, c := range cases {
+		address, host, err := parseEndpoint(c.endpoint)
+		require.NoError(t, err)
+		assert.Equal(t, c.address, address, c.endpoint)
+		assert.Equal(t, c.host, host, c.endpoint)
+	}
+}
@@ -61,25 +61,20 @@ func (u *UDPServer) Init(context pipeline.Context) (int, error) {
 		logger.Error(u.context.GetRuntimeContext(), "UDP_SERVER_ALARM", "illegal udp listening addr", u.Address, "err", err)
 		return 0, err
 	}
-	if host == "" {
-		host = "0.0.0.0"
-	}
-
-	ip, err := net.ResolveIPAddr("ip", host)
-	if err != nil {
-		logger.Error(u.context.GetRuntimeContext(), "UDP_SERVER_ALARM", "unable resolve addr", u.Address, "err", err)
-		return 0, err
-	}
-
 	port, err := strconv.Atoi(portStr)
 	if err != nil || port < 0 || port > 65535 {
 		logger.Error(u.context.GetRuntimeContext(), "UDP_SERVER_ALARM", "illegal port", portStr, "err", err)
 	}
-
-	u.addr = &net.UDPAddr{
-		IP:   ip.IP,
-		Port: port,
-		Zone: ip.Zone,
+	u.addr = &net.UDPAddr{Port: port}
+
+	// an empty host listens on all the interfaces of both ipv4 and ipv6
+	if host != "" {
+		ip, err := net.ResolveIPAddr("ip", host)
+		if err != nil {
+			logger.Error(u.context.GetRuntimeContext(), "UDP_SERVER_ALARM", "unable resolve addr", u.Address, "err", err)
+			return 0, err
+		}
+		u.addr.IP, u.addr.Zone = ip.IP, ip.Zone
 	}
 	return 0, nil
 }
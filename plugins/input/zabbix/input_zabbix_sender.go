@@ -0,0 +1,322 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "service_zabbix_sender"
+
+const (
+	v1 = iota
+	v2
+)
+
+const (
+	requestSenderData = "sender data"
+	requestAgentData  = "agent data"
+)
+
+// ServiceZabbixSender receives the trapper items sent by zabbix_sender or the active zabbix agents.
+type ServiceZabbixSender struct {
+	Address        string // The address to listen on, default is 127.0.0.1:10051.
+	MaxConnections int    // Max connections, no limit if not positive.
+	TimeoutSeconds int    // The number of seconds to wait for a request, default is 3.
+	MaxMessageSize int    // The max size in bytes of a request, including the decompressed data.
+	KeepTextValues bool   // Collect the non-numeric values as logs instead of counting them as failed.
+
+	context     pipeline.Context
+	collector   pipeline.Collector
+	collectorV2 pipeline.PipelineCollector
+	version     int8
+	listener    net.Listener
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	connections   map[net.Conn]struct{}
+	connectionsMu sync.Mutex
+}
+
+type request struct {
+	Request string  `json:"request"`
+	Data    []*item `json:"data"`
+	Clock   *int64  `json:"clock"`
+	NS      *int64  `json:"ns"`
+}
+
+type item struct {
+	Host  string      `json:"host"`
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	Clock *int64      `json:"clock"`
+	NS    *int64      `json:"ns"`
+}
+
+type response struct {
+	Response string `json:"response"`
+	Info     string `json:"info"`
+}
+
+// Init ...
+func (s *ServiceZabbixSender) Init(context pipeline.Context) (int, error) {
+	s.context = context
+	if s.TimeoutSeconds <= 0 {
+		s.TimeoutSeconds = 3
+	}
+	if s.MaxMessageSize <= 0 {
+		s.MaxMessageSize = 16 * 1024 * 1024
+	}
+	return 0, nil
+}
+
+// Description ...
+func (s *ServiceZabbixSender) Description() string {
+	return "zabbix sender protocol input plugin for logtail"
+}
+
+// Collect ...
+func (s *ServiceZabbixSender) Collect(pipeline.Collector) error {
+	return nil
+}
+
+// Start starts the ServiceInput's service, whatever that may be
+func (s *ServiceZabbixSender) Start(c pipeline.Collector) error {
+	s.collector = c
+	s.version = v1
+	return s.start()
+}
+
+// StartService start the ServiceInput's service by plugin runner v2
+func (s *ServiceZabbixSender) StartService(context pipeline.PipelineContext) error {
+	s.collectorV2 = context.Collector()
+	s.version = v2
+	return s.start()
+}
+
+func (s *ServiceZabbixSender) start() error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.done = make(chan struct{})
+	s.connections = make(map[net.Conn]struct{})
+	s.wg.Add(1)
+	go s.accept()
+	logger.Info(s.context.GetRuntimeContext(), "zabbix sender server start", s.Address)
+	return nil
+}
+
+func (s *ServiceZabbixSender) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			logger.Error(s.context.GetRuntimeContext(), "ZABBIX_SENDER_ALARM", "accept error", err)
+			if util.RandomSleep(time.Second, 0.1, s.done) {
+				return
+			}
+			continue
+		}
+		s.connectionsMu.Lock()
+		if s.MaxConnections > 0 && len(s.connections) >= s.MaxConnections {
+			s.connectionsMu.Unlock()
+			logger.Warning(s.context.GetRuntimeContext(), "ZABBIX_SENDER_ALARM", "too many connections, reject", conn.RemoteAddr().String())
+			_ = conn.Close()
+			continue
+		}
+		s.connections[conn] = struct{}{}
+		s.connectionsMu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle serves a request per connection like the zabbix trapper.
+func (s *ServiceZabbixSender) handle(conn net.Conn) {
+	defer func() {
+		s.connectionsMu.Lock()
+		delete(s.connections, conn)
+		s.connectionsMu.Unlock()
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(time.Duration(s.TimeoutSeconds) * time.Second))
+	data, err := readPacket(bufio.NewReader(conn), int64(s.MaxMessageSize))
+	if err != nil {
+		var netErr net.Error
+		if err != io.EOF && !errors.Is(err, net.ErrClosed) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			logger.Warning(s.context.GetRuntimeContext(), "ZABBIX_SENDER_ALARM", "read request failed", err, "remote", conn.RemoteAddr().String())
+		}
+		return
+	}
+	resp := s.process(data)
+	body, _ := json.Marshal(resp)
+	if err = writePacket(conn, body); err != nil {
+		logger.Warning(s.context.GetRuntimeContext(), "ZABBIX_SENDER_ALARM", "send response failed", err, "remote", conn.RemoteAddr().String())
+	}
+}
+
+func (s *ServiceZabbixSender) process(data []byte) *response {
+	start := time.Now()
+	var req request
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&req); err != nil {
+		logger.Warning(s.context.GetRuntimeContext(), "ZABBIX_SENDER_ALARM", "invalid request", err)
+		return &response{Response: "failed", Info: "cannot parse request: " + err.Error()}
+	}
+	if req.Request != requestSenderData && req.Request != requestAgentData {
+		return &response{Response: "failed", Info: fmt.Sprintf("unsupported request %q", req.Request)}
+	}
+	now := time.Now()
+	var events []models.PipelineEvent
+	failed := 0
+	for _, it := range req.Data {
+		timestamp := now
+		if it.Clock != nil {
+			timestamp = toTime(*it.Clock, it.NS)
+		} else if req.Clock != nil {
+			timestamp = toTime(*req.Clock, req.NS)
+		}
+		if !s.collectItem(it, timestamp, &events) {
+			failed++
+		}
+	}
+	if s.version == v2 && len(events) > 0 {
+		s.collectorV2.CollectList(&models.PipelineGroupEvents{
+			Group:  models.NewGroup(models.NewMetadata(), models.NewTags()),
+			Events: events,
+		})
+	}
+	return &response{
+		Response: "success",
+		Info: fmt.Sprintf("processed: %d; failed: %d; total: %d; seconds spent: %.6f",
+			len(req.Data)-failed, failed, len(req.Data), time.Since(start).Seconds()),
+	}
+}
+
+// collectItem collects the numeric value as a metric, or the text value as a log if KeepTextValues.
+func (s *ServiceZabbixSender) collectItem(it *item, timestamp time.Time, events *[]models.PipelineEvent) bool {
+	if it.Host == "" || it.Key == "" {
+		return false
+	}
+	var value string
+	switch v := it.Value.(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	default:
+		return false
+	}
+	name, params := splitKey(it.Key)
+	tags := map[string]string{"host": it.Host}
+	if params != "" {
+		tags["params"] = params
+	}
+
+	if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		switch s.version {
+		case v1:
+			var labels helper.MetricLabels
+			labels.AppendMap(tags)
+			s.collector.AddRawLog(helper.NewMetricLog(name, timestamp.UnixNano(), number, &labels))
+		case v2:
+			*events = append(*events, models.NewSingleValueMetric(name, models.MetricTypeGauge, models.NewTagsWithMap(tags), timestamp.UnixNano(), number))
+		}
+		return true
+	}
+	if !s.KeepTextValues {
+		return false
+	}
+	switch s.version {
+	case v1:
+		s.collector.AddData(nil, map[string]string{"host": it.Host, "key": it.Key, "value": value}, timestamp)
+	case v2:
+		log := models.NewLog("", nil, "", "", "", models.NewTags(), uint64(timestamp.UnixNano()))
+		log.Contents.Add("host", it.Host)
+		log.Contents.Add("key", it.Key)
+		log.Contents.Add("value", value)
+		*events = append(*events, log)
+	}
+	return true
+}
+
+// splitKey splits the item key like "system.cpu.load[all,avg1]" into the name and the parameters.
+func splitKey(key string) (string, string) {
+	idx := strings.IndexByte(key, '[')
+	if idx <= 0 || !strings.HasSuffix(key, "]") {
+		return key, ""
+	}
+	return key[:idx], key[idx+1 : len(key)-1]
+}
+
+func toTime(clock int64, ns *int64) time.Time {
+	if ns == nil {
+		return time.Unix(clock, 0)
+	}
+	return time.Unix(clock, *ns)
+}
+
+// Stop stops the services and closes any necessary channels and connections
+func (s *ServiceZabbixSender) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	close(s.done)
+	_ = s.listener.Close()
+	s.connectionsMu.Lock()
+	for conn := range s.connections {
+		_ = conn.Close()
+	}
+	s.connectionsMu.Unlock()
+	s.wg.Wait()
+	logger.Info(s.context.GetRuntimeContext(), "zabbix sender server stop", s.Address)
+	return nil
+}
+
+func init() {
+	pipeline.ServiceInputs[pluginType] = func() pipeline.ServiceInput {
+		return &ServiceZabbixSender{
+			Address:        "127.0.0.1:10051",
+			MaxConnections: 1000,
+			TimeoutSeconds: 3,
+			MaxMessageSize: 16 * 1024 * 1024,
+		}
+	}
+}
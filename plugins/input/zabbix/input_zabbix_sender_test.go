@@ -0,0 +1,198 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const senderRequest = `{"request":"sender data","data":[
+{"host":"web01","key":"system.cpu.load[all,avg1]","value":"1.5","clock":1700000000,"ns":500},
+{"host":"web01","key":"custom.count","value":42},
+{"host":"web01","key":"app.status","value":"OK"},
+{"host":"","key":"invalid","value":"1"}
+],"clock":1700000001,"ns":0}`
+
+func newInput() (*ServiceZabbixSender, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	s := pipeline.ServiceInputs[pluginType]().(*ServiceZabbixSender)
+	s.Address = "127.0.0.1:0"
+	_, err := s.Init(ctx)
+	return s, err
+}
+
+func packet(flags byte, data []byte, reserved uint64) []byte {
+	b := append([]byte{}, protocolMagic...)
+	b = append(b, flags)
+	if flags&protocolFlagLarge != 0 {
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(data)))
+		b = binary.LittleEndian.AppendUint64(b, reserved)
+	} else {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(reserved))
+	}
+	return append(b, data...)
+}
+
+func compress(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestReadPacket(t *testing.T) {
+	data := []byte(senderRequest)
+	compressed := compress(t, data)
+	for name, p := range map[string][]byte{
+		"plain":            packet(protocolFlagZabbix, data, 0),
+		"large":            packet(protocolFlagZabbix|protocolFlagLarge, data, 0),
+		"compressed":       packet(protocolFlagZabbix|protocolFlagCompression, compressed, uint64(len(data))),
+		"compressed large": packet(protocolFlagZabbix|protocolFlagCompression|protocolFlagLarge, compressed, uint64(len(data))),
+	} {
+		got, err := readPacket(bytes.NewReader(p), 1024)
+		require.NoError(t, err, name)
+		assert.Equal(t, data, got, name)
+	}
+
+	for name, p := range map[string][]byte{
+		"invalid magic":     append([]byte("ZBXX"), packet(protocolFlagZabbix, data, 0)[4:]...),
+		"invalid flags":     packet(0, data, 0),
+		"truncated":         packet(protocolFlagZabbix, data, 0)[:20],
+		"too large":         packet(protocolFlagZabbix, data, 0),
+		"decompressed size": packet(protocolFlagZabbix|protocolFlagCompression, compressed, 10),
+		"too large zip":     packet(protocolFlagZabbix|protocolFlagCompression, compress(t, make([]byte, 4096)), 4096),
+		"invalid zip":       packet(protocolFlagZabbix|protocolFlagCompression, data, uint64(len(data))),
+	} {
+		maxSize := int64(1024)
+		if name == "too large" {
+			maxSize = 16
+		}
+		_, err := readPacket(bytes.NewReader(p), maxSize)
+		assert.Error(t, err, name)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePacket(&buf, data))
+	got, err := readPacket(&buf, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+func send(t *testing.T, address string, data []byte) *response {
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+	body, err := readPacket(conn, 1024)
+	require.NoError(t, err)
+	var resp response
+	require.NoError(t, json.Unmarshal(body, &resp))
+	return &resp
+}
+
+func TestZabbixSenderV2(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	s.KeepTextValues = true
+	_, err = s.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	ctx := helper.NewObservePipelineConext(10)
+	require.NoError(t, s.StartService(ctx))
+	defer s.Stop()
+
+	resp := send(t, s.listener.Addr().String(), packet(protocolFlagZabbix, []byte(senderRequest), 0))
+	assert.Equal(t, "success", resp.Response)
+	assert.True(t, strings.HasPrefix(resp.Info, "processed: 3; failed: 1; total: 4; seconds spent: "), resp.Info)
+
+	groups := ctx.Collector().ToArray()
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Events, 3)
+	load := groups[0].Events[0].(*models.Metric)
+	assert.Equal(t, "system.cpu.load", load.GetName())
+	assert.Equal(t, map[string]string{"host": "web01", "params": "all,avg1"}, load.GetTags().Iterator())
+	assert.Equal(t, 1.5, load.GetValue().GetSingleValue())
+	assert.Equal(t, uint64(1700000000000000500), load.GetTimestamp())
+	count := groups[0].Events[1].(*models.Metric)
+	assert.Equal(t, "custom.count", count.GetName())
+	assert.Equal(t, 42.0, count.GetValue().GetSingleValue())
+	assert.Equal(t, uint64(1700000001000000000), count.GetTimestamp())
+	status := groups[0].Events[2].(*models.Log)
+	assert.Equal(t, "OK", status.Contents.Get("value"))
+	assert.Equal(t, "app.status", status.Contents.Get("key"))
+
+	resp = send(t, s.listener.Addr().String(), packet(protocolFlagZabbix, []byte(`{"request":"active checks","host":"web01"}`), 0))
+	assert.Equal(t, "failed", resp.Response)
+	resp = send(t, s.listener.Addr().String(), packet(protocolFlagZabbix, []byte(`{"request":`), 0))
+	assert.Equal(t, "failed", resp.Response)
+}
+
+func TestZabbixSenderV1(t *testing.T) {
+	s, err := newInput()
+	require.NoError(t, err)
+	collector := &test.MockCollector{}
+	s.collector = collector
+	s.version = v1
+
+	resp := s.process([]byte(`{"request":"agent data","data":[
+{"host":"web01","key":"vfs.fs.size[/,used]","value":"1024","clock":1700000000,"ns":0},
+{"host":"web01","key":"app.status","value":"OK","clock":1700000000,"ns":0}]}`))
+	assert.Equal(t, "success", resp.Response)
+	assert.True(t, strings.HasPrefix(resp.Info, "processed: 1; failed: 1; total: 2;"), resp.Info)
+	require.Len(t, collector.RawLogs, 1)
+	assert.Equal(t, "vfs.fs.size", test.ReadLogVal(collector.RawLogs[0], "__name__"))
+	assert.Equal(t, "host#$#web01|params#$#/,used", test.ReadLogVal(collector.RawLogs[0], "__labels__"))
+	assert.Equal(t, "1024", test.ReadLogVal(collector.RawLogs[0], "__value__"))
+	assert.Empty(t, collector.Logs)
+
+	s.KeepTextValues = true
+	resp = s.process([]byte(`{"request":"sender data","data":[{"host":"web01","key":"app.status","value":"OK"}]}`))
+	assert.Equal(t, "success", resp.Response)
+	require.Len(t, collector.Logs, 1)
+	assert.Equal(t, map[string]string{"host": "web01", "key": "app.status", "value": "OK"}, collector.Logs[0].Fields)
+}
+
+func TestSplitKey(t *testing.T) {
+	for key, expected := range map[string][2]string{
+		"system.cpu.load[all,avg1]": {"system.cpu.load", "all,avg1"},
+		"custom.count":              {"custom.count", ""},
+		"[a]":                       {"[a]", ""},
+		"net.if.in[eth0":            {"net.if.in[eth0", ""},
+	} {
+		name, params := splitKey(key)
+		assert.Equal(t, expected[0], name, key)
+		assert.Equal(t, expected[1], params, key)
+	}
+}
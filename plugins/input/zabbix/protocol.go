@@ -0,0 +1,104 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zabbix
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The header of the zabbix protocol is "ZBXD", the flags, the data length and the reserved length,
+// the lengths are 8 bytes for the large packets and 4 bytes otherwise, see
+// https://www.zabbix.com/documentation/current/en/manual/appendix/protocols/header_datalen.
+const (
+	protocolFlagZabbix      = 0x01
+	protocolFlagCompression = 0x02
+	protocolFlagLarge       = 0x04
+)
+
+var protocolMagic = []byte("ZBXD")
+
+var errPacketTooLarge = errors.New("packet exceeds the max message size")
+
+// readPacket reads a packet and decompresses the data if compressed.
+func readPacket(r io.Reader, maxSize int64) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], protocolMagic) {
+		return nil, fmt.Errorf("invalid protocol header %q", header[:4])
+	}
+	flags := header[4]
+	if flags&protocolFlagZabbix == 0 {
+		return nil, fmt.Errorf("unsupported protocol flags 0x%x", flags)
+	}
+	var dataLen, reserved uint64
+	if flags&protocolFlagLarge != 0 {
+		lengths := make([]byte, 16)
+		if _, err := io.ReadFull(r, lengths); err != nil {
+			return nil, err
+		}
+		dataLen, reserved = binary.LittleEndian.Uint64(lengths), binary.LittleEndian.Uint64(lengths[8:])
+	} else {
+		lengths := make([]byte, 8)
+		if _, err := io.ReadFull(r, lengths); err != nil {
+			return nil, err
+		}
+		dataLen, reserved = uint64(binary.LittleEndian.Uint32(lengths)), uint64(binary.LittleEndian.Uint32(lengths[4:]))
+	}
+	if dataLen > uint64(maxSize) {
+		return nil, errPacketTooLarge
+	}
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if flags&protocolFlagCompression == 0 {
+		return data, nil
+	}
+	// the reserved length is the size of the uncompressed data
+	if reserved > uint64(maxSize) {
+		return nil, errPacketTooLarge
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close() //nolint:errcheck
+	decompressed, err := io.ReadAll(io.LimitReader(zr, int64(reserved)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(decompressed)) != reserved {
+		return nil, fmt.Errorf("decompressed size %d mismatches the expected size %d", len(decompressed), reserved)
+	}
+	return decompressed, nil
+}
+
+// writePacket writes the uncompressed data with the zabbix protocol header.
+func writePacket(w io.Writer, data []byte) error {
+	packet := make([]byte, 13, 13+len(data))
+	copy(packet, protocolMagic)
+	packet[4] = protocolFlagZabbix
+	binary.LittleEndian.PutUint32(packet[5:], uint32(len(data)))
+	packet = append(packet, data...)
+	_, err := w.Write(packet)
+	return err
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const (
+	metricPrefix = "zookeeper_"
+
+	monitorPath    = "/commands/monitor"
+	maxCommandSize = 4 * 1024 * 1024
+)
+
+// InputZookeeper polls the mntr and ruok four letter word commands, or the monitor command of the AdminServer,
+// and exports the leader status, latency and quorum metrics.
+type InputZookeeper struct {
+	// Servers are "host:port" for the four letter word commands, or "http://host:8080" for the AdminServer.
+	Servers            []string
+	TimeoutMs          int
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SkipInsecureVerify bool
+	Labels             map[string]string
+
+	client  *http.Client
+	context pipeline.Context
+}
+
+func (z *InputZookeeper) Init(context pipeline.Context) (int, error) {
+	z.context = context
+	if len(z.Servers) == 0 {
+		return 0, fmt.Errorf("no zookeeper server configured")
+	}
+	if z.TimeoutMs <= 0 {
+		z.TimeoutMs = 5000
+	}
+	tlsCfg, err := util.GetTLSConfig(z.SSLCert, z.SSLKey, z.SSLCA, z.SkipInsecureVerify)
+	if err != nil {
+		return 0, err
+	}
+	z.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		Timeout:   z.timeout(),
+	}
+	return 0, nil
+}
+
+func (z *InputZookeeper) Description() string {
+	return "collect the health and latency metrics of zookeeper"
+}
+
+func (z *InputZookeeper) timeout() time.Duration {
+	return time.Duration(z.TimeoutMs) * time.Millisecond
+}
+
+func (z *InputZookeeper) Collect(collector pipeline.Collector) error {
+	var wg sync.WaitGroup
+	for _, server := range z.Servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			z.collectServer(server, collector)
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (z *InputZookeeper) collectServer(server string, collector pipeline.Collector) {
+	isAdminServer := strings.HasPrefix(server, "http://") || strings.HasPrefix(server, "https://")
+	labels := &helper.MetricLabels{}
+	if isAdminServer {
+		labels.Append("instance", strings.TrimPrefix(strings.TrimPrefix(server, "http://"), "https://"))
+	} else {
+		labels.Append("instance", server)
+	}
+	for k, v := range z.Labels {
+		labels.Append(k, v)
+	}
+	var stats map[string]string
+	var err error
+	if isAdminServer {
+		stats, err = z.adminMonitor(server)
+	} else {
+		stats, err = z.fourLetterMonitor(server, labels, collector)
+	}
+	now := time.Now().UnixNano()
+	if err != nil {
+		logger.Warning(z.context.GetRuntimeContext(), "ZOOKEEPER_COLLECT_ALARM", "server", server, "error", err)
+		collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 0, labels))
+		return
+	}
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"up", now, 1, labels))
+	for key, value := range stats {
+		switch key {
+		case "version":
+		case "server_state":
+			stateLabels := labels.Clone()
+			stateLabels.Append("state", value)
+			collector.AddRawLog(helper.NewMetricLog(metricPrefix+"server_state", now, 1, stateLabels))
+			isLeader := 0.
+			if value == "leader" {
+				isLeader = 1
+			}
+			collector.AddRawLog(helper.NewMetricLog(metricPrefix+"is_leader", now, isLeader, labels))
+		default:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				collector.AddRawLog(helper.NewMetricLog(metricPrefix+key, now, f, labels))
+			}
+		}
+	}
+}
+
+// command sends the four letter word command and returns the whole response.
+func (z *InputZookeeper) command(server, cmd string) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", server, z.timeout())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close() //nolint:errcheck
+	_ = conn.SetDeadline(time.Now().Add(z.timeout()))
+	if _, err = conn.Write([]byte(cmd)); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(conn, maxCommandSize))
+}
+
+// fourLetterMonitor exports zookeeper_ruok, and returns the mntr stats without the "zk_" prefix.
+func (z *InputZookeeper) fourLetterMonitor(server string, labels *helper.MetricLabels, collector pipeline.Collector) (map[string]string, error) {
+	resp, err := z.command(server, "ruok")
+	if err != nil {
+		return nil, err
+	}
+	ruok := 0.
+	if string(resp) == "imok" {
+		ruok = 1
+	}
+	collector.AddRawLog(helper.NewMetricLog(metricPrefix+"ruok", time.Now().UnixNano(), ruok, labels))
+
+	resp, err = z.command(server, "mntr")
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(resp, []byte("is not executed because it is not in the whitelist")) {
+		return nil, fmt.Errorf("mntr is not in the 4lw.commands.whitelist")
+	}
+	stats := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(resp))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		stats[strings.TrimPrefix(parts[0], "zk_")] = strings.TrimSpace(parts[1])
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("empty mntr response: %s", util.CutString(string(resp), 256))
+	}
+	return stats, nil
+}
+
+// adminMonitor returns the flat stats of the monitor command, the nested values are ignored.
+func (z *InputZookeeper) adminMonitor(server string) (map[string]string, error) {
+	url := strings.TrimSuffix(server, "/")
+	if !strings.HasSuffix(url, monitorPath) {
+		url += monitorPath
+	}
+	resp, err := z.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+	var monitor map[string]interface{}
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxCommandSize))
+	decoder.UseNumber()
+	if err = decoder.Decode(&monitor); err != nil {
+		return nil, err
+	}
+	if errMsg, ok := monitor["error"].(string); ok && errMsg != "" {
+		return nil, fmt.Errorf("monitor command error: %s", errMsg)
+	}
+	stats := make(map[string]string, len(monitor))
+	for key, value := range monitor {
+		switch v := value.(type) {
+		case json.Number:
+			stats[key] = v.String()
+		case string:
+			if key == "server_state" || key == "version" {
+				stats[key] = v
+			}
+		}
+	}
+	return stats, nil
+}
+
+func init() {
+	pipeline.MetricInputs["metric_zookeeper"] = func() pipeline.MetricInput {
+		return &InputZookeeper{
+			TimeoutMs: 5000,
+		}
+	}
+}
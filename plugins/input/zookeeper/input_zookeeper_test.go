@@ -0,0 +1,109 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/plugins/test"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+const mntrOutput = "zk_version\t3.8.1-74db005175a4ec545697012f9069cb9dcc8cdda7, built on 2023-01-25 16:31 UTC\n" +
+	"zk_server_state\tleader\n" +
+	"zk_avg_latency\t0.5\n" +
+	"zk_max_latency\t12\n" +
+	"zk_followers\t2\n" +
+	"zk_synced_followers\t2\n"
+
+// serveFourLetterWords answers the commands like zookeeper and closes the connection.
+func serveFourLetterWords(t *testing.T, responses map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			cmd := make([]byte, 4)
+			if _, err = conn.Read(cmd); err == nil {
+				_, _ = conn.Write([]byte(responses[string(cmd)]))
+			}
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestFourLetterWords(t *testing.T) {
+	server := serveFourLetterWords(t, map[string]string{"ruok": "imok", "mntr": mntrOutput})
+	notWhitelisted := serveFourLetterWords(t, map[string]string{"ruok": "imok", "mntr": "mntr is not executed because it is not in the whitelist.\n"})
+
+	input := &InputZookeeper{Servers: []string{server, notWhitelisted}, Labels: map[string]string{"cluster": "c1"}}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	labels := "cluster#$#c1|instance#$#" + server
+	assert.ElementsMatch(t, []string{
+		"zookeeper_avg_latency{" + labels + "} 0.5",
+		"zookeeper_followers{" + labels + "} 2",
+		"zookeeper_is_leader{" + labels + "} 1",
+		"zookeeper_max_latency{" + labels + "} 12",
+		"zookeeper_ruok{cluster#$#c1|instance#$#" + notWhitelisted + "} 1",
+		"zookeeper_ruok{" + labels + "} 1",
+		"zookeeper_server_state{" + labels + "|state#$#leader} 1",
+		"zookeeper_synced_followers{" + labels + "} 2",
+		"zookeeper_up{cluster#$#c1|instance#$#" + notWhitelisted + "} 0",
+		"zookeeper_up{" + labels + "} 1",
+	}, test.MetricLines(collector.Logs))
+}
+
+func TestAdminServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != monitorPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"version":"3.8.1","avg_latency":1.5,"server_state":"follower","znode_count":5,` +
+			`"command":"monitor","error":null,"local_sessions":{"count":1}}`))
+	}))
+	defer server.Close()
+
+	input := &InputZookeeper{Servers: []string{server.URL}}
+	_, err := input.Init(mock.NewEmptyContext("p", "l", "c"))
+	require.NoError(t, err)
+	collector := &test.MockMetricCollector{}
+	require.NoError(t, input.Collect(collector))
+
+	labels := "instance#$#" + strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []string{
+		"zookeeper_avg_latency{" + labels + "} 1.5",
+		"zookeeper_is_leader{" + labels + "} 0",
+		"zookeeper_server_state{" + labels + "|state#$#follower} 1",
+		"zookeeper_up{" + labels + "} 1",
+		"zookeeper_znode_count{" + labels + "} 5",
+	}, test.MetricLines(collector.Logs))
+}
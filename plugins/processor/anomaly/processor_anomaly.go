@@ -0,0 +1,259 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_anomaly"
+
+const (
+	directionBoth = "both"
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+// ProcessorAnomaly keeps the streaming statistics of a numeric field per key, and flags the events whose values
+// deviate from the mean by more than Threshold standard deviations.
+type ProcessorAnomaly struct {
+	SourceKey  string   // The numeric field of the logs, or the name of the metrics whose values are checked.
+	KeyFields  []string // The fields of the logs, or the tags of the metrics, to group the statistics by.
+	Method     string   // The statistics, ewma or zscore.
+	Alpha      float64  // The smoothing factor of ewma in (0, 1], the larger the faster the baseline follows the changes.
+	Threshold  float64  // The number of standard deviations beyond which the value is anomalous.
+	Direction  string   // Which deviations are anomalous, both, up or down.
+	MinSamples int      // The number of values to learn from before flagging anomalies.
+	MaxKeys    int      // The max number of keys, the least recently seen key is evicted when exceeded.
+	FlagKey    string   // The key set to true on the anomalous events.
+	ScoreKey   string   // The key of the deviation score added to each checked event, not added if empty.
+	NoKeyError bool     // Whether to alarm when the source field is missing or not numeric.
+
+	stats   *lru.Cache[string, statistics]
+	context pipeline.Context
+}
+
+// Init ...
+func (p *ProcessorAnomaly) Init(context pipeline.Context) error {
+	p.context = context
+	if p.SourceKey == "" {
+		return fmt.Errorf("must specify SourceKey for plugin %v", pluginType)
+	}
+	if p.FlagKey == "" {
+		return fmt.Errorf("must specify FlagKey for plugin %v", pluginType)
+	}
+	switch p.Method {
+	case methodEWMA:
+		if p.Alpha <= 0 || p.Alpha > 1 {
+			return fmt.Errorf("invalid Alpha %v for plugin %v", p.Alpha, pluginType)
+		}
+	case methodZScore:
+	default:
+		return fmt.Errorf("unknown Method %v for plugin %v", p.Method, pluginType)
+	}
+	switch p.Direction {
+	case directionBoth, directionUp, directionDown:
+	default:
+		return fmt.Errorf("unknown Direction %v for plugin %v", p.Direction, pluginType)
+	}
+	if p.Threshold <= 0 {
+		return fmt.Errorf("invalid Threshold %v for plugin %v", p.Threshold, pluginType)
+	}
+	var err error
+	p.stats, err = lru.New[string, statistics](p.MaxKeys)
+	return err
+}
+
+// Description ...
+func (*ProcessorAnomaly) Description() string {
+	return "anomaly processor to flag the values deviating from the streaming statistics"
+}
+
+// ProcessLogs ...
+func (p *ProcessorAnomaly) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	for _, log := range logArray {
+		p.processLog(log)
+	}
+	return logArray
+}
+
+func (p *ProcessorAnomaly) processLog(log *protocol.Log) {
+	var raw string
+	found := false
+	values := make([]string, len(p.KeyFields))
+	for _, content := range log.Contents {
+		if content.Key == p.SourceKey {
+			raw = content.Value
+			found = true
+		}
+		for i, field := range p.KeyFields {
+			if content.Key == field {
+				values[i] = content.Value
+			}
+		}
+	}
+	value, ok := p.parse(raw, found)
+	if !ok {
+		return
+	}
+	anomalous, score, ok := p.check(strings.Join(values, "\x00"), value)
+	if !ok {
+		return
+	}
+	if p.ScoreKey != "" {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: p.ScoreKey, Value: formatScore(score)})
+	}
+	if anomalous {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: p.FlagKey, Value: "true"})
+	}
+}
+
+// Process ...
+func (p *ProcessorAnomaly) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	for _, event := range in.Events {
+		switch event.GetType() {
+		case models.EventTypeLogging:
+			p.processLogEvent(event.(*models.Log))
+		case models.EventTypeMetric:
+			p.processMetricEvent(event.(*models.Metric))
+		}
+	}
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+func (p *ProcessorAnomaly) processLogEvent(log *models.Log) {
+	contents := log.GetIndices()
+	found := contents.Contains(p.SourceKey)
+	var raw string
+	if found {
+		raw = fmt.Sprint(contents.Get(p.SourceKey))
+	}
+	value, ok := p.parse(raw, found)
+	if !ok {
+		return
+	}
+	values := make([]string, len(p.KeyFields))
+	for i, field := range p.KeyFields {
+		if contents.Contains(field) {
+			values[i] = fmt.Sprint(contents.Get(field))
+		}
+	}
+	anomalous, score, ok := p.check(strings.Join(values, "\x00"), value)
+	if !ok {
+		return
+	}
+	if p.ScoreKey != "" {
+		contents.Add(p.ScoreKey, formatScore(score))
+	}
+	if anomalous {
+		contents.Add(p.FlagKey, "true")
+	}
+}
+
+func (p *ProcessorAnomaly) processMetricEvent(metric *models.Metric) {
+	if metric.GetName() != p.SourceKey || !metric.GetValue().IsSingleValue() {
+		return
+	}
+	tags := metric.GetTags()
+	values := make([]string, len(p.KeyFields))
+	for i, field := range p.KeyFields {
+		values[i] = tags.Get(field)
+	}
+	anomalous, score, ok := p.check(strings.Join(values, "\x00"), metric.GetValue().GetSingleValue())
+	if !ok {
+		return
+	}
+	if p.ScoreKey != "" {
+		tags.Add(p.ScoreKey, formatScore(score))
+	}
+	if anomalous {
+		tags.Add(p.FlagKey, "true")
+	}
+}
+
+func (p *ProcessorAnomaly) parse(raw string, found bool) (float64, bool) {
+	if !found {
+		if p.NoKeyError {
+			logger.Warningf(p.context.GetRuntimeContext(), "ANOMALY_FIND_ALARM", "cannot find key %v", p.SourceKey)
+		}
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		if p.NoKeyError {
+			logger.Warningf(p.context.GetRuntimeContext(), "ANOMALY_FIND_ALARM", "value %v of key %v is not a number", raw, p.SourceKey)
+		}
+		return 0, false
+	}
+	return value, true
+}
+
+// check scores the value against the statistics of the key before learning from it.
+func (p *ProcessorAnomaly) check(key string, value float64) (anomalous bool, score float64, ok bool) {
+	stats, exists := p.stats.Get(key)
+	if !exists {
+		stats = p.newStatistics()
+		p.stats.Add(key, stats)
+	}
+	score, ok = stats.score(value)
+	stats.update(value)
+	if !ok {
+		return false, 0, false
+	}
+	switch p.Direction {
+	case directionUp:
+		anomalous = score > p.Threshold
+	case directionDown:
+		anomalous = score < -p.Threshold
+	default:
+		anomalous = math.Abs(score) > p.Threshold
+	}
+	return anomalous, score, true
+}
+
+func (p *ProcessorAnomaly) newStatistics() statistics {
+	if p.Method == methodZScore {
+		return &zscoreStatistics{minSamples: int64(p.MinSamples)}
+	}
+	return &ewmaStatistics{alpha: p.Alpha, minSamples: int64(p.MinSamples)}
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 3, 64)
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorAnomaly{
+			Method:     methodEWMA,
+			Alpha:      0.1,
+			Threshold:  3,
+			Direction:  directionBoth,
+			MinSamples: 10,
+			MaxKeys:    10000,
+			FlagKey:    "anomaly",
+		}
+	}
+}
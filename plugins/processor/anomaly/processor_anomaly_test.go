@@ -0,0 +1,212 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anomaly
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorAnomaly, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorAnomaly)
+	processor.SourceKey = "latency"
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func newLog(kvs ...string) *protocol.Log {
+	log := &protocol.Log{}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: kvs[i], Value: kvs[i+1]})
+	}
+	return log
+}
+
+func getContent(log *protocol.Log, key string) (string, bool) {
+	for _, content := range log.Contents {
+		if content.Key == key {
+			return content.Value, true
+		}
+	}
+	return "", false
+}
+
+// baseline alternates between 99 and 101, so the mean is 100 and the standard deviation is about 1.
+func baseline(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = strconv.Itoa(99 + 2*(i%2))
+	}
+	return values
+}
+
+func TestInit(t *testing.T) {
+	for _, setup := range []func(p *ProcessorAnomaly){
+		func(p *ProcessorAnomaly) { p.SourceKey = "" },
+		func(p *ProcessorAnomaly) { p.Method = "mad" },
+		func(p *ProcessorAnomaly) { p.Alpha = 1.5 },
+		func(p *ProcessorAnomaly) { p.Direction = "left" },
+		func(p *ProcessorAnomaly) { p.Threshold = 0 },
+		func(p *ProcessorAnomaly) { p.MaxKeys = 0 },
+	} {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		setup(p)
+		require.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	}
+}
+
+func TestProcessLogs(t *testing.T) {
+	for _, method := range []string{methodEWMA, methodZScore} {
+		t.Run(method, func(t *testing.T) {
+			p, err := newProcessor()
+			require.NoError(t, err)
+			p.Method = method
+			p.ScoreKey = "score"
+			require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+			logs := make([]*protocol.Log, 0)
+			for _, v := range baseline(20) {
+				logs = append(logs, newLog("latency", v))
+			}
+			logs = append(logs, newLog("latency", "100.5"), newLog("latency", "150"), newLog("latency", "oops"), newLog("other", "1"))
+			logs = p.ProcessLogs(logs)
+			require.Len(t, logs, 24)
+
+			// warming up
+			_, ok := getContent(logs[0], "score")
+			require.False(t, ok)
+			for _, log := range logs[:20] {
+				_, ok = getContent(log, "anomaly")
+				require.False(t, ok)
+			}
+			_, ok = getContent(logs[20], "anomaly")
+			require.False(t, ok)
+			score, ok := getContent(logs[20], "score")
+			require.True(t, ok)
+			s, _ := strconv.ParseFloat(score, 64)
+			require.Less(t, math.Abs(s), 3.0)
+
+			flag, ok := getContent(logs[21], "anomaly")
+			require.True(t, ok)
+			require.Equal(t, "true", flag)
+			score, _ = getContent(logs[21], "score")
+			s, _ = strconv.ParseFloat(score, 64)
+			require.Greater(t, s, 3.0)
+
+			require.Len(t, logs[22].Contents, 1)
+			require.Len(t, logs[23].Contents, 1)
+		})
+	}
+}
+
+func TestKeyFields(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.KeyFields = []string{"host"}
+	p.MinSamples = 4
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	for _, v := range baseline(10) {
+		p.ProcessLogs([]*protocol.Log{newLog("host", "a", "latency", v), newLog("host", "b", "latency", "1"+v)})
+	}
+	// 150 is normal for host b whose values are around 1100, but not for host a
+	logs := p.ProcessLogs([]*protocol.Log{newLog("host", "a", "latency", "150"), newLog("host", "b", "latency", "1100")})
+	_, ok := getContent(logs[0], "anomaly")
+	require.True(t, ok)
+	_, ok = getContent(logs[1], "anomaly")
+	require.False(t, ok)
+}
+
+func TestDirection(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Direction = directionUp
+	p.Method = methodZScore
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	for _, v := range baseline(20) {
+		p.ProcessLogs([]*protocol.Log{newLog("latency", v)})
+	}
+	logs := p.ProcessLogs([]*protocol.Log{newLog("latency", "50"), newLog("latency", "150")})
+	_, ok := getContent(logs[0], "anomaly")
+	require.False(t, ok)
+	_, ok = getContent(logs[1], "anomaly")
+	require.True(t, ok)
+}
+
+func TestConstantValues(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.ScoreKey = "score"
+	p.MinSamples = 3
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := p.ProcessLogs([]*protocol.Log{newLog("latency", "5"), newLog("latency", "5"), newLog("latency", "5"),
+		newLog("latency", "5"), newLog("latency", "6")})
+	score, _ := getContent(logs[3], "score")
+	require.Equal(t, "0.000", score)
+	_, ok := getContent(logs[3], "anomaly")
+	require.False(t, ok)
+	score, _ = getContent(logs[4], "score")
+	require.Equal(t, "+Inf", score)
+	_, ok = getContent(logs[4], "anomaly")
+	require.True(t, ok)
+}
+
+func TestProcess(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.KeyFields = []string{"host"}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	ctx := helper.NewObservePipelineConext(10)
+	events := make([]models.PipelineEvent, 0)
+	for _, v := range baseline(20) {
+		value, _ := strconv.ParseFloat(v, 64)
+		log := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+		log.Contents.Add("latency", v)
+		log.Contents.Add("host", "a")
+		events = append(events,
+			log,
+			models.NewSingleValueMetric("latency", models.MetricTypeGauge, models.NewTagsWithKeyValues("host", "b"), 0, value))
+	}
+	anomalousLog := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	anomalousLog.Contents.Add("latency", "150")
+	anomalousLog.Contents.Add("host", "a")
+	events = append(events,
+		anomalousLog,
+		models.NewSingleValueMetric("latency", models.MetricTypeGauge, models.NewTagsWithKeyValues("host", "b"), 0, 30),
+		models.NewSingleValueMetric("other", models.MetricTypeGauge, models.NewTagsWithKeyValues("host", "b"), 0, 30))
+	p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: events}, ctx)
+
+	result := ctx.Collector().ToArray()
+	require.Len(t, result, 1)
+	require.Len(t, result[0].Events, 43)
+	require.False(t, result[0].Events[39].(*models.Metric).GetTags().Contains("anomaly"))
+	require.Equal(t, "true", result[0].Events[40].(*models.Log).GetIndices().Get("anomaly"))
+	require.Equal(t, "true", result[0].Events[41].(*models.Metric).GetTags().Get("anomaly"))
+	require.False(t, result[0].Events[42].(*models.Metric).GetTags().Contains("anomaly"))
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anomaly
+
+import "math"
+
+const (
+	methodEWMA   = "ewma"
+	methodZScore = "zscore"
+)
+
+// statistics keeps the streaming mean and variance of a series of values.
+type statistics interface {
+	// score returns the number of standard deviations the value is away from the mean, and whether there are
+	// enough samples to tell.
+	score(value float64) (float64, bool)
+	update(value float64)
+}
+
+// ewmaStatistics weights the recent values exponentially more, so that the baseline follows the slow drifts.
+type ewmaStatistics struct {
+	alpha      float64
+	minSamples int64
+	count      int64
+	mean       float64
+	variance   float64
+}
+
+func (s *ewmaStatistics) score(value float64) (float64, bool) {
+	if s.count < s.minSamples {
+		return 0, false
+	}
+	return deviation(value, s.mean, s.variance), true
+}
+
+func (s *ewmaStatistics) update(value float64) {
+	s.count++
+	if s.count == 1 {
+		s.mean = value
+		return
+	}
+	diff := value - s.mean
+	incr := s.alpha * diff
+	s.mean += incr
+	s.variance = (1 - s.alpha) * (s.variance + diff*incr)
+}
+
+// zscoreStatistics weights all the values equally with the Welford's algorithm.
+type zscoreStatistics struct {
+	minSamples int64
+	count      int64
+	mean       float64
+	m2         float64
+}
+
+func (s *zscoreStatistics) score(value float64) (float64, bool) {
+	if s.count < s.minSamples || s.count < 2 {
+		return 0, false
+	}
+	return deviation(value, s.mean, s.m2/float64(s.count-1)), true
+}
+
+func (s *zscoreStatistics) update(value float64) {
+	s.count++
+	diff := value - s.mean
+	s.mean += diff / float64(s.count)
+	s.m2 += diff * (value - s.mean)
+}
+
+func deviation(value, mean, variance float64) float64 {
+	diff := value - mean
+	if diff == 0 {
+		return 0
+	}
+	stddev := math.Sqrt(variance)
+	if stddev == 0 {
+		return math.Copysign(math.Inf(1), diff)
+	}
+	return diff / stddev
+}
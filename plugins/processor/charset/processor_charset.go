@@ -0,0 +1,230 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package charset
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_charset"
+
+const (
+	policyReplace    = "replace"
+	policyDrop       = "drop"
+	policyKeepSource = "keep_source"
+
+	replacementChar = "\uFFFD"
+)
+
+var encodings = map[string]encoding.Encoding{
+	"gbk":        simplifiedchinese.GBK,
+	"gb2312":     simplifiedchinese.GBK,
+	"gb18030":    simplifiedchinese.GB18030,
+	"shift_jis":  japanese.ShiftJIS,
+	"shift-jis":  japanese.ShiftJIS,
+	"sjis":       japanese.ShiftJIS,
+	"latin1":     charmap.ISO8859_1,
+	"latin-1":    charmap.ISO8859_1,
+	"iso-8859-1": charmap.ISO8859_1,
+}
+
+var normForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// ProcessorCharset converts the fields from the source encoding to UTF-8, and optionally normalizes them.
+type ProcessorCharset struct {
+	SourceKeys        []string // The fields to convert, all fields are converted if empty.
+	SourceEncoding    string   // The encoding of the fields, e.g. gbk, gb18030, shift_jis, latin1 or utf-8.
+	InvalidBytePolicy string   // How to handle the invalid bytes, replace, drop or keep_source.
+	Normalization     string   // The unicode normalization form applied after the conversion, NFC, NFD, NFKC or NFKD.
+	NoKeyError        bool
+
+	decoder   *encoding.Decoder // nil for utf-8
+	normForm  *norm.Form
+	sourceSet map[string]struct{}
+	context   pipeline.Context
+}
+
+// Init ...
+func (p *ProcessorCharset) Init(context pipeline.Context) error {
+	p.context = context
+	name := strings.ToLower(strings.TrimSpace(p.SourceEncoding))
+	switch name {
+	case "", "utf-8", "utf8":
+	default:
+		enc, ok := encodings[name]
+		if !ok {
+			var err error
+			if enc, err = ianaindex.IANA.Encoding(p.SourceEncoding); err != nil || enc == nil {
+				return fmt.Errorf("unsupported SourceEncoding %v for plugin %v", p.SourceEncoding, pluginType)
+			}
+		}
+		p.decoder = enc.NewDecoder()
+	}
+	switch p.InvalidBytePolicy {
+	case policyReplace, policyDrop, policyKeepSource:
+	default:
+		return fmt.Errorf("unknown InvalidBytePolicy %v for plugin %v", p.InvalidBytePolicy, pluginType)
+	}
+	if p.Normalization != "" {
+		form, ok := normForms[strings.ToUpper(p.Normalization)]
+		if !ok {
+			return fmt.Errorf("unknown Normalization %v for plugin %v", p.Normalization, pluginType)
+		}
+		p.normForm = &form
+	}
+	if len(p.SourceKeys) > 0 {
+		p.sourceSet = make(map[string]struct{}, len(p.SourceKeys))
+		for _, key := range p.SourceKeys {
+			p.sourceSet[key] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Description ...
+func (*ProcessorCharset) Description() string {
+	return "charset processor to convert fields to UTF-8"
+}
+
+// ProcessLogs ...
+func (p *ProcessorCharset) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	for _, log := range logArray {
+		found := 0
+		for _, content := range log.Contents {
+			if !p.isSource(content.Key) {
+				continue
+			}
+			found++
+			content.Value = p.convert(content.Value)
+		}
+		p.checkFound(found)
+	}
+	return logArray
+}
+
+// Process ...
+func (p *ProcessorCharset) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	for _, event := range in.Events {
+		if event.GetType() != models.EventTypeLogging {
+			continue
+		}
+		contents := event.(*models.Log).GetIndices()
+		found := 0
+		for key, value := range contents.Iterator() {
+			if !p.isSource(key) {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				found++
+				contents.Add(key, p.convert(v))
+			case []byte:
+				found++
+				contents.Add(key, p.convert(string(v)))
+			}
+		}
+		p.checkFound(found)
+	}
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+func (p *ProcessorCharset) isSource(key string) bool {
+	if p.sourceSet == nil {
+		return true
+	}
+	_, ok := p.sourceSet[key]
+	return ok
+}
+
+func (p *ProcessorCharset) checkFound(found int) {
+	if p.NoKeyError && p.sourceSet != nil && found < len(p.sourceSet) {
+		logger.Warningf(p.context.GetRuntimeContext(), "CHARSET_FIND_ALARM", "cannot find all keys %v", p.SourceKeys)
+	}
+}
+
+// convert returns the value in UTF-8, the ascii values are returned as is since they are the same in all the encodings.
+func (p *ProcessorCharset) convert(value string) string {
+	if isASCII(value) {
+		return value
+	}
+	converted := value
+	invalid := false
+	if p.decoder == nil {
+		if !utf8.ValidString(value) {
+			invalid = true
+			converted = strings.ToValidUTF8(value, replacementChar)
+		}
+	} else {
+		result, err := p.decoder.String(value)
+		if err != nil {
+			invalid = true
+			converted = strings.ToValidUTF8(value, replacementChar)
+		} else {
+			converted = result
+			// the decoders replace the invalid bytes with U+FFFD, which is never produced from the valid bytes
+			// unless the value contains U+FFFD in UTF-8 bytes already.
+			invalid = strings.Contains(converted, replacementChar) && !strings.Contains(value, replacementChar)
+		}
+	}
+	if invalid {
+		switch p.InvalidBytePolicy {
+		case policyKeepSource:
+			return value
+		case policyDrop:
+			converted = strings.ReplaceAll(converted, replacementChar, "")
+		}
+	}
+	if p.normForm != nil {
+		converted = p.normForm.String(converted)
+	}
+	return converted
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorCharset{
+			SourceEncoding:    "utf-8",
+			InvalidBytePolicy: policyReplace,
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package charset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorCharset, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorCharset)
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func encode(t *testing.T, encoder interface{ String(string) (string, error) }, s string) string {
+	encoded, err := encoder.String(s)
+	require.NoError(t, err)
+	return encoded
+}
+
+func TestInit(t *testing.T) {
+	for _, setup := range []func(p *ProcessorCharset){
+		func(p *ProcessorCharset) { p.SourceEncoding = "ebcdic-unknown" },
+		func(p *ProcessorCharset) { p.InvalidBytePolicy = "ignore" },
+		func(p *ProcessorCharset) { p.Normalization = "NFX" },
+	} {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		setup(p)
+		require.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	}
+	// the IANA names are supported
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.SourceEncoding = "EUC-KR"
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestEncodings(t *testing.T) {
+	cases := []struct {
+		encoding string
+		value    string
+	}{
+		{"GBK", encode(t, simplifiedchinese.GBK.NewEncoder(), "中文日志")},
+		{"gb18030", encode(t, simplifiedchinese.GB18030.NewEncoder(), "中文日志𠀀")},
+		{"Shift_JIS", encode(t, japanese.ShiftJIS.NewEncoder(), "日本語ログ")},
+		{"latin1", encode(t, charmap.ISO8859_1.NewEncoder(), "café")},
+	}
+	expected := []string{"中文日志", "中文日志𠀀", "日本語ログ", "café"}
+	for i, c := range cases {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		p.SourceEncoding = c.encoding
+		require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+		logs := p.ProcessLogs([]*protocol.Log{{Contents: []*protocol.Log_Content{
+			{Key: "content", Value: c.value},
+			{Key: "ascii", Value: "plain text"},
+		}}})
+		require.Equal(t, expected[i], logs[0].Contents[0].Value, c.encoding)
+		require.Equal(t, "plain text", logs[0].Contents[1].Value)
+	}
+}
+
+func TestInvalidBytePolicy(t *testing.T) {
+	invalidGBK := encode(t, simplifiedchinese.GBK.NewEncoder(), "中文") + "\x81"
+	invalidUTF8 := "abc\xffdef"
+	cases := []struct {
+		encoding string
+		policy   string
+		value    string
+		expected string
+	}{
+		{"gbk", policyReplace, invalidGBK, "中文\uFFFD"},
+		{"gbk", policyDrop, invalidGBK, "中文"},
+		{"gbk", policyKeepSource, invalidGBK, invalidGBK},
+		{"utf-8", policyReplace, invalidUTF8, "abc\uFFFDdef"},
+		{"utf-8", policyDrop, invalidUTF8, "abcdef"},
+		{"utf-8", policyKeepSource, invalidUTF8, invalidUTF8},
+		{"utf-8", policyDrop, "valid \uFFFD", "valid \uFFFD"},
+	}
+	for _, c := range cases {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		p.SourceEncoding = c.encoding
+		p.InvalidBytePolicy = c.policy
+		require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+		require.Equal(t, c.expected, p.convert(c.value), c.encoding+" "+c.policy)
+	}
+}
+
+func TestSourceKeysAndNormalization(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.SourceKeys = []string{"msg"}
+	p.Normalization = "nfkc"
+	p.NoKeyError = true
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := p.ProcessLogs([]*protocol.Log{{Contents: []*protocol.Log_Content{
+		{Key: "msg", Value: "ｆｕｌｌｗｉｄｔｈ e\u0301"},
+		{Key: "other", Value: "ｆｕｌｌｗｉｄｔｈ"},
+	}}})
+	require.Equal(t, "fullwidth é", logs[0].Contents[0].Value)
+	require.Equal(t, "ｆｕｌｌｗｉｄｔｈ", logs[0].Contents[1].Value)
+}
+
+func TestProcess(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.SourceEncoding = "gbk"
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	gbk := encode(t, simplifiedchinese.GBK.NewEncoder(), "中文")
+	log := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	log.Contents.Add("str", gbk)
+	log.Contents.Add("bytes", []byte(gbk))
+	log.Contents.Add("number", 1)
+	ctx := helper.NewObservePipelineConext(10)
+	p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: []models.PipelineEvent{log}}, ctx)
+	result := ctx.Collector().ToArray()
+	require.Len(t, result, 1)
+	contents := result[0].Events[0].(*models.Log).GetIndices()
+	require.Equal(t, "中文", contents.Get("str"))
+	require.Equal(t, "中文", contents.Get("bytes"))
+	require.Equal(t, 1, contents.Get("number"))
+}
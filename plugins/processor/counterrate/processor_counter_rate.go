@@ -0,0 +1,247 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package counterrate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_counter_rate"
+
+const (
+	modeRate  = "rate"
+	modeDelta = "delta"
+)
+
+const (
+	metricNameKey     = "__name__"
+	metricLabelsKey   = "__labels__"
+	metricTimeNanoKey = "__time_nano__"
+	metricValueKey    = "__value__"
+)
+
+// ProcessorCounterRate converts the cumulative counters to the rates per second or the deltas between the samples
+// of each series. The first sample of a series, or the first one after the series is stale, is dropped as it has
+// no previous sample to compare with.
+type ProcessorCounterRate struct {
+	MetricNames  []string // The regexps of the metric names to convert. If empty, the Counter metrics are converted in v2, and the metrics named with the _total suffix in v1.
+	Mode         string   // The output, rate or delta.
+	NameSuffix   string   // The suffix appended to the names of the converted metrics.
+	StalenessSec int      // The series whose previous sample is older than this are restarted, 0 means never.
+	MaxSeries    int      // The max number of series, the least recently seen series is evicted when exceeded.
+
+	names   []*regexp.Regexp
+	samples *lru.Cache[string, sample]
+	context pipeline.Context
+}
+
+// sample is the previous cumulative value of a series, timestamp is in nanoseconds.
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+// Init ...
+func (p *ProcessorCounterRate) Init(context pipeline.Context) error {
+	p.context = context
+	switch p.Mode {
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("unknown Mode %v for plugin %v", p.Mode, pluginType)
+	}
+	for _, name := range p.MetricNames {
+		reg, err := regexp.Compile("^(?:" + name + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid MetricNames %v for plugin %v: %v", name, pluginType, err)
+		}
+		p.names = append(p.names, reg)
+	}
+	var err error
+	p.samples, err = lru.New[string, sample](p.MaxSeries)
+	return err
+}
+
+// Description ...
+func (*ProcessorCounterRate) Description() string {
+	return "counter rate processor to convert the cumulative counters to the rates or deltas"
+}
+
+func (p *ProcessorCounterRate) matchName(name string) bool {
+	for _, reg := range p.names {
+		if reg.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns the rate or delta of the series, and false for the first sample of the series.
+// A decreasing value is a counter reset, and the counter is considered restarted from zero. The samples
+// not newer than the previous one are dropped as well, without updating the series.
+func (p *ProcessorCounterRate) convert(series string, value float64, timestamp int64) (float64, bool) {
+	prev, exists := p.samples.Get(series)
+	if exists && timestamp <= prev.timestamp {
+		return 0, false
+	}
+	p.samples.Add(series, sample{value: value, timestamp: timestamp})
+	if !exists || (p.StalenessSec > 0 && timestamp-prev.timestamp > int64(p.StalenessSec)*1e9) {
+		return 0, false
+	}
+	delta := value - prev.value
+	if delta < 0 {
+		delta = value
+	}
+	if p.Mode == modeDelta {
+		return delta, true
+	}
+	return delta / (float64(timestamp-prev.timestamp) / 1e9), true
+}
+
+// ProcessLogs converts the metrics in the SLS metric format.
+func (p *ProcessorCounterRate) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	kept := logArray[:0]
+	for _, log := range logArray {
+		if p.processLog(log) {
+			kept = append(kept, log)
+		}
+	}
+	for i := len(kept); i < len(logArray); i++ {
+		logArray[i] = nil
+	}
+	return kept
+}
+
+// processLog returns false if the log should be dropped.
+func (p *ProcessorCounterRate) processLog(log *protocol.Log) bool {
+	var name, labels string
+	var nameContent, valueContent *protocol.Log_Content
+	timestamp := int64(log.Time) * 1e9
+	for _, content := range log.Contents {
+		switch content.Key {
+		case metricNameKey:
+			name = content.Value
+			nameContent = content
+		case metricLabelsKey:
+			labels = content.Value
+		case metricValueKey:
+			valueContent = content
+		case metricTimeNanoKey:
+			if t, err := strconv.ParseInt(content.Value, 10, 64); err == nil {
+				timestamp = t
+			}
+		}
+	}
+	if nameContent == nil || valueContent == nil {
+		return true
+	}
+	if len(p.names) > 0 && !p.matchName(name) || len(p.names) == 0 && !strings.HasSuffix(name, "_total") {
+		return true
+	}
+	value, err := strconv.ParseFloat(valueContent.Value, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return true
+	}
+	result, ok := p.convert(name+"\x00"+labels, value, timestamp)
+	if !ok {
+		return false
+	}
+	valueContent.Value = strconv.FormatFloat(result, 'g', -1, 64)
+	nameContent.Value += p.NameSuffix
+	return true
+}
+
+// Process ...
+func (p *ProcessorCounterRate) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	kept := in.Events[:0]
+	for _, event := range in.Events {
+		if metric, ok := event.(*models.Metric); ok && !p.processMetric(metric) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	for i := len(kept); i < len(in.Events); i++ {
+		in.Events[i] = nil
+	}
+	in.Events = kept
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+// processMetric returns false if the metric should be dropped. Each field of the multi values metrics is a
+// series, and the metric is dropped if any field has no previous sample.
+func (p *ProcessorCounterRate) processMetric(metric *models.Metric) bool {
+	if len(p.names) > 0 && !p.matchName(metric.GetName()) || len(p.names) == 0 && metric.GetMetricType() != models.MetricTypeCounter {
+		return true
+	}
+	var builder strings.Builder
+	builder.WriteString(metric.GetName())
+	if tags := metric.GetTags(); tags != nil {
+		for _, kv := range tags.SortTo(nil) {
+			builder.WriteByte('\x00')
+			builder.WriteString(kv.Key)
+			builder.WriteByte('=')
+			builder.WriteString(kv.Value)
+		}
+	}
+	series := builder.String()
+	timestamp := int64(metric.GetTimestamp())
+	value := metric.GetValue()
+	if value.IsSingleValue() {
+		result, ok := p.convert(series, value.GetSingleValue(), timestamp)
+		if !ok {
+			return false
+		}
+		metric.Value = &models.MetricSingleValue{Value: result}
+	} else {
+		values := value.GetMultiValues()
+		results := make(map[string]float64, values.Len())
+		converted := true
+		for field, v := range values.Iterator() {
+			result, ok := p.convert(series+"\x00"+field, v, timestamp)
+			converted = converted && ok
+			results[field] = result
+		}
+		if !converted {
+			return false
+		}
+		for field, result := range results {
+			values.Add(field, result)
+		}
+	}
+	metric.SetName(metric.GetName() + p.NameSuffix)
+	if p.Mode == modeRate {
+		metric.MetricType = models.MetricTypeGauge
+	}
+	return true
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorCounterRate{
+			Mode:         modeRate,
+			StalenessSec: 300,
+			MaxSeries:    100000,
+		}
+	}
+}
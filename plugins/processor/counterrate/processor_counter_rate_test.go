@@ -0,0 +1,141 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package counterrate
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorCounterRate, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorCounterRate)
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func newMetricLog(name, labels string, value float64, sec int64) *protocol.Log {
+	return &protocol.Log{Time: uint32(sec), Contents: []*protocol.Log_Content{
+		{Key: metricNameKey, Value: name},
+		{Key: metricLabelsKey, Value: labels},
+		{Key: metricTimeNanoKey, Value: strconv.FormatInt(sec*1e9, 10)},
+		{Key: metricValueKey, Value: strconv.FormatFloat(value, 'g', -1, 64)},
+	}}
+}
+
+func TestInit(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Mode = "sum"
+	assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	p, err = newProcessor()
+	require.NoError(t, err)
+	p.MetricNames = []string{"("}
+	assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestProcessLogsRate(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.NameSuffix = ":rate"
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := p.ProcessLogs([]*protocol.Log{
+		newMetricLog("requests_total", "host#$#a", 100, 10),
+		newMetricLog("requests_total", "host#$#b", 5, 10),
+		newMetricLog("cpu_usage", "host#$#a", 0.5, 10),
+	})
+	require.Len(t, logs, 1)
+	assert.Equal(t, "cpu_usage", logs[0].Contents[0].Value)
+
+	logs = p.ProcessLogs([]*protocol.Log{
+		newMetricLog("requests_total", "host#$#a", 160, 40),
+		newMetricLog("requests_total", "host#$#b", 2, 20),
+	})
+	require.Len(t, logs, 2)
+	assert.Equal(t, "requests_total:rate", logs[0].Contents[0].Value)
+	assert.Equal(t, "2", logs[0].Contents[3].Value)
+	// counter reset
+	assert.Equal(t, "0.2", logs[1].Contents[3].Value)
+}
+
+func TestProcessLogsStaleAndOutOfOrder(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Mode = modeDelta
+	p.StalenessSec = 60
+	p.MetricNames = []string{"bytes_.*"}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.Len(t, p.ProcessLogs([]*protocol.Log{newMetricLog("bytes_sent", "", 100, 10)}), 0)
+	require.Len(t, p.ProcessLogs([]*protocol.Log{newMetricLog("bytes_sent", "", 90, 5)}), 0)
+	logs := p.ProcessLogs([]*protocol.Log{newMetricLog("bytes_sent", "", 150, 20)})
+	require.Len(t, logs, 1)
+	assert.Equal(t, "50", logs[0].Contents[3].Value)
+	// stale, restarted
+	require.Len(t, p.ProcessLogs([]*protocol.Log{newMetricLog("bytes_sent", "", 400, 200)}), 0)
+	logs = p.ProcessLogs([]*protocol.Log{newMetricLog("bytes_sent", "", 450, 210)})
+	require.Len(t, logs, 1)
+	assert.Equal(t, "50", logs[0].Contents[3].Value)
+}
+
+func TestProcessMetrics(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	process := func(events ...models.PipelineEvent) []models.PipelineEvent {
+		ctx := helper.NewObservePipelineConext(10)
+		p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: events}, ctx)
+		var out []models.PipelineEvent
+		for _, group := range ctx.Collector().ToArray() {
+			out = append(out, group.Events...)
+		}
+		return out
+	}
+	counter := func(value float64, sec uint64) *models.Metric {
+		return models.NewSingleValueMetric("requests", models.MetricTypeCounter, models.NewTagsWithKeyValues("host", "a"), int64(sec*1e9), value)
+	}
+	gauge := models.NewSingleValueMetric("cpu", models.MetricTypeGauge, models.NewTags(), int64(1e10), 0.5)
+	events := process(counter(10, 10), gauge)
+	require.Len(t, events, 1)
+	assert.Equal(t, "cpu", events[0].GetName())
+
+	events = process(counter(30, 20))
+	require.Len(t, events, 1)
+	metric := events[0].(*models.Metric)
+	assert.Equal(t, models.MetricTypeGauge, metric.GetMetricType())
+	assert.Equal(t, 2.0, metric.GetValue().GetSingleValue())
+
+	multi := func(in, out float64, sec uint64) *models.Metric {
+		return models.NewMultiValuesMetric("net", models.MetricTypeCounter, models.NewTags(), int64(sec*1e9), models.NewMetricMultiValueWithMap(map[string]float64{"in": in, "out": out}).Values)
+	}
+	require.Len(t, process(multi(100, 10, 10)), 0)
+	events = process(multi(200, 5, 20))
+	require.Len(t, events, 1)
+	values := events[0].(*models.Metric).GetValue().GetMultiValues()
+	assert.Equal(t, 10.0, values.Get("in"))
+	assert.Equal(t, 0.5, values.Get("out"))
+}
@@ -16,11 +16,13 @@ package desensitize
 
 import (
 	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
 	"errors"
 	"fmt"
 
 	"github.com/dlclark/regexp2"
 
+	"github.com/alibaba/ilogtail/pkg/fips"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -55,11 +57,17 @@ func (p *ProcessorDesensitize) Init(context pipeline.Context) error {
 	}
 
 	// check Method
-	if p.Method != "const" && p.Method != "md5" {
-		err = errors.New("parameter Method should be \"const\" or \"md5\"")
+	if p.Method != "const" && p.Method != "md5" && p.Method != "sha256" {
+		err = errors.New("parameter Method should be \"const\", \"md5\" or \"sha256\"")
 		logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init processor_desensitize error", err)
 		return err
 	}
+	if p.Method != "const" {
+		if err = fips.CheckHash(p.Method); err != nil {
+			logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init processor_desensitize error", err)
+			return err
+		}
+	}
 
 	// check Method
 	if p.Method == "const" && p.ReplaceString == "" {
@@ -128,11 +136,7 @@ func (p *ProcessorDesensitize) desensitize(val string) string {
 		if p.Method == "const" {
 			return p.ReplaceString
 		}
-		if p.Method == "md5" {
-			has := md5.Sum([]byte(val)) //nolint:gosec
-			md5str := fmt.Sprintf("%x", has)
-			return md5str
-		}
+		return p.hash(val)
 	}
 
 	var pos = 0
@@ -143,9 +147,8 @@ func (p *ProcessorDesensitize) desensitize(val string) string {
 		pos = beginMatch.Index + beginMatch.Length
 		content, _ := p.regexContent.FindRunesMatchStartingAt(runeVal, pos)
 		if content != nil {
-			if p.Method == "md5" {
-				has := md5.Sum([]byte(content.String())) //nolint:gosec
-				p.ReplaceString = fmt.Sprintf("%x", has)
+			if p.Method != "const" {
+				p.ReplaceString = p.hash(content.String())
 				runeReplace = runes(p.ReplaceString)
 			}
 			runeVal = append(runeVal[:pos], append(runeReplace, runeVal[pos+content.Length:]...)...)
@@ -156,6 +159,16 @@ func (p *ProcessorDesensitize) desensitize(val string) string {
 	return string(runeVal)
 }
 
+func (p *ProcessorDesensitize) hash(val string) string {
+	switch p.Method {
+	case "md5":
+		return fmt.Sprintf("%x", md5.Sum([]byte(val))) //nolint:gosec
+	case "sha256":
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(val)))
+	}
+	return p.ReplaceString
+}
+
 func init() {
 	pipeline.Processors[pluginType] = func() pipeline.Processor {
 		return &ProcessorDesensitize{
@@ -21,6 +21,7 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/alibaba/ilogtail/pkg/flags"
 	"github.com/alibaba/ilogtail/pkg/protocol"
 	"github.com/alibaba/ilogtail/plugins/test/mock"
 )
@@ -88,13 +89,13 @@ func TestProcessorDesensitizeInit(t *testing.T) {
 		Convey("Test load fail with no Method error", func() {
 			processor.Method = ""
 			err := processor.Init(mock.NewEmptyContext("p", "l", "c"))
-			So(err, ShouldResemble, errors.New("parameter Method should be \"const\" or \"md5\""))
+			So(err, ShouldResemble, errors.New("parameter Method should be \"const\", \"md5\" or \"sha256\""))
 		})
 
 		Convey("Test load fail with wrong Method error", func() {
 			processor.Method = "Base64"
 			err := processor.Init(mock.NewEmptyContext("p", "l", "c"))
-			So(err, ShouldResemble, errors.New("parameter Method should be \"const\" or \"md5\""))
+			So(err, ShouldResemble, errors.New("parameter Method should be \"const\", \"md5\" or \"sha256\""))
 		})
 
 		Convey("Test load fail with wrong Match error", func() {
@@ -133,6 +134,15 @@ func TestProcessorDesensitizeInit(t *testing.T) {
 			err := processor.Init(mock.NewEmptyContext("p", "l", "c"))
 			So(err, ShouldResemble, errors.New("parameter ReplaceString should not be empty when Method is \"const\""))
 		})
+
+		Convey("Test load fail with md5 in FIPS mode", func() {
+			*flags.FIPSMode = true
+			defer func() { *flags.FIPSMode = false }()
+			processor.Method = "md5"
+			So(processor.Init(mock.NewEmptyContext("p", "l", "c")), ShouldNotBeNil)
+			processor.Method = "sha256"
+			So(processor.Init(mock.NewEmptyContext("p", "l", "c")), ShouldBeNil)
+		})
 	})
 }
 
@@ -158,6 +168,16 @@ func TestProcessorDesensitizeWork(t *testing.T) {
 			res := processor.desensitize(record)
 			So(res, ShouldEqual, "700085e3968c3efb83b54ba47dd1367d")
 		})
+
+		Convey("Test sha256", func() {
+			processor.Method = "sha256"
+			err := processor.Init(mock.NewEmptyContext("p", "l", "c"))
+			So(err, ShouldBeNil)
+
+			record := "[{'account':'1812213231432969','password':'04a23f38'}, {'account':'1812213685634','password':'123a'}]"
+			res := processor.desensitize(record)
+			So(res, ShouldEqual, "b3fbbda6b2be5b2ccceeb4b9b824f2a6139094d4c6a5fed02c618ff844906db1")
+		})
 	})
 
 	Convey("Test Match = regex.", t, func() {
@@ -18,8 +18,8 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/md5" //nolint:gosec
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -167,7 +167,7 @@ func (p *ProcessorEncrypt) parseKey() error {
 		}
 
 		logger.Infof(p.context.GetRuntimeContext(), "read key from file %v, hash: %v", p.EncryptionParameters.KeyFilePath,
-			hex.EncodeToString(md5.New().Sum([]byte(p.EncryptionParameters.Key)))) //nolint:gosec
+			fmt.Sprintf("%x", sha256.Sum256([]byte(p.EncryptionParameters.Key))))
 	}
 
 	// Decode from hex to bytes.
@@ -23,6 +23,11 @@ import (
 	"github.com/alibaba/ilogtail/pkg/protocol"
 )
 
+const (
+	dropReasonIncludeNotMatch = "include_not_match"
+	dropReasonExcludeMatch    = "exclude_match"
+)
+
 const pluginType = "processor_filter_key_regex"
 
 type ProcessorKeyFilter struct {
@@ -32,6 +37,7 @@ type ProcessorKeyFilter struct {
 	includeRegex []*regexp.Regexp
 	excludeRegex []*regexp.Regexp
 	filterMetric pipeline.CounterMetric
+	dropRecorder pipeline.DropRecorder
 	context      pipeline.Context
 }
 
@@ -70,7 +76,17 @@ func (*ProcessorKeyFilter) Description() string {
 	return "key regex filter for logtail, if key is unmatched, drop this log"
 }
 
+func (p *ProcessorKeyFilter) SetDropRecorder(recorder pipeline.DropRecorder) {
+	p.dropRecorder = recorder
+}
+
 func (p *ProcessorKeyFilter) IsLogMatch(log *protocol.Log) bool {
+	match, _ := p.matchLog(log)
+	return match
+}
+
+// matchLog returns whether the log should be kept, and the drop reason if not.
+func (p *ProcessorKeyFilter) matchLog(log *protocol.Log) (bool, string) {
 	if p.includeRegex != nil {
 	ForBlock:
 		for _, reg := range p.includeRegex {
@@ -79,7 +95,7 @@ func (p *ProcessorKeyFilter) IsLogMatch(log *protocol.Log) bool {
 					continue ForBlock
 				}
 			}
-			return false
+			return false, dropReasonIncludeNotMatch
 		}
 	}
 
@@ -88,25 +104,29 @@ func (p *ProcessorKeyFilter) IsLogMatch(log *protocol.Log) bool {
 			for _, reg := range p.excludeRegex {
 				// if match, return false
 				if reg.MatchString(cont.Key) {
-					return false
+					return false, dropReasonExcludeMatch
 				}
 			}
 		}
 	}
-	return true
+	return true, ""
 }
 
 func (p *ProcessorKeyFilter) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
 	totalLen := len(logArray)
 	nextIdx := 0
 	for idx := 0; idx < totalLen; idx++ {
-		if p.IsLogMatch(logArray[idx]) {
+		match, reason := p.matchLog(logArray[idx])
+		if match {
 			if idx != nextIdx {
 				logArray[nextIdx] = logArray[idx]
 			}
 			nextIdx++
 		} else {
 			p.filterMetric.Add(1)
+			if p.dropRecorder != nil {
+				p.dropRecorder.Drop(reason, 1)
+			}
 		}
 	}
 	logArray = logArray[:nextIdx]
@@ -23,6 +23,11 @@ import (
 	"github.com/alibaba/ilogtail/pkg/protocol"
 )
 
+const (
+	dropReasonIncludeNotMatch = "include_not_match"
+	dropReasonExcludeMatch    = "exclude_match"
+)
+
 const pluginType = "processor_filter_regex"
 
 // ProcessorRegexFilter is a processor plugin to filter log according to the value of field.
@@ -37,6 +42,7 @@ type ProcessorRegexFilter struct {
 	includeRegex map[string]*regexp.Regexp
 	excludeRegex map[string]*regexp.Regexp
 	filterMetric pipeline.CounterMetric
+	dropRecorder pipeline.DropRecorder
 	context      pipeline.Context
 }
 
@@ -74,7 +80,17 @@ func (*ProcessorRegexFilter) Description() string {
 	return "regex filter for logtail"
 }
 
+func (p *ProcessorRegexFilter) SetDropRecorder(recorder pipeline.DropRecorder) {
+	p.dropRecorder = recorder
+}
+
 func (p *ProcessorRegexFilter) IsLogMatch(log *protocol.Log) bool {
+	match, _ := p.matchLog(log)
+	return match
+}
+
+// matchLog returns whether the log should be kept, and the drop reason if not.
+func (p *ProcessorRegexFilter) matchLog(log *protocol.Log) (bool, string) {
 	if p.includeRegex != nil {
 		includeCount := 0
 		for _, cont := range log.Contents {
@@ -84,14 +100,14 @@ func (p *ProcessorRegexFilter) IsLogMatch(log *protocol.Log) bool {
 				} else {
 					// not match
 					// logger.Info("include not match", "kv", cont.GetKey(), cont.GetValue(), "match result", matchRst)
-					return false
+					return false, dropReasonIncludeNotMatch
 				}
 			}
 		}
 		// not match all
 		if includeCount < len(p.includeRegex) {
 			// logger.Info("include count not match", includeCount)
-			return false
+			return false, dropReasonIncludeNotMatch
 		}
 	}
 
@@ -101,26 +117,30 @@ func (p *ProcessorRegexFilter) IsLogMatch(log *protocol.Log) bool {
 				if reg.MatchString(cont.Value) {
 					// if match, return false
 					// logger.Info("exclude not match", *cont)
-					return false
+					return false, dropReasonExcludeMatch
 				}
 			}
 		}
 	}
 
-	return true
+	return true, ""
 }
 
 func (p *ProcessorRegexFilter) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
 	totalLen := len(logArray)
 	nextIdx := 0
 	for idx := 0; idx < totalLen; idx++ {
-		if p.IsLogMatch(logArray[idx]) {
+		match, reason := p.matchLog(logArray[idx])
+		if match {
 			if idx != nextIdx {
 				logArray[nextIdx] = logArray[idx]
 			}
 			nextIdx++
 		} else {
 			p.filterMetric.Add(1)
+			if p.dropRecorder != nil {
+				p.dropRecorder.Drop(reason, 1)
+			}
 		}
 	}
 	logArray = logArray[:nextIdx]
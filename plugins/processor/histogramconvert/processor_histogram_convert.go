@@ -0,0 +1,158 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogramconvert
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol/otlp"
+)
+
+const pluginType = "processor_histogram_convert"
+
+const (
+	targetNone        = ""
+	targetExplicit    = "explicit"
+	targetExponential = "exponential"
+	targetNative      = "native"
+	targetPrometheus  = "prometheus"
+)
+
+// ProcessorHistogramConvert converts the histogram metrics between the Prometheus classic buckets, the explicit
+// bucket histograms, the exponential histograms and the Prometheus native histograms.
+type ProcessorHistogramConvert struct {
+	MetricNames        []string  // The regexps of the histogram names to convert, all the histograms are converted if empty.
+	CollapsePrometheus bool      // Whether to collapse the Prometheus classic series _bucket, _count and _sum into the histograms first.
+	Target             string    // The target format, explicit, exponential, native or prometheus, not converted if empty.
+	Bounds             []float64 // The bucket bounds of the explicit target, and of the prometheus target for the exponential histograms.
+	Scale              int       // The scale of the exponential and native targets, the histograms with higher scales are downscaled.
+
+	names []*regexp.Regexp
+}
+
+// Init ...
+func (p *ProcessorHistogramConvert) Init(context pipeline.Context) error {
+	switch p.Target {
+	case targetNone, targetPrometheus:
+	case targetExplicit:
+		if len(p.Bounds) == 0 {
+			return fmt.Errorf("must specify Bounds for the explicit target of plugin %v", pluginType)
+		}
+	case targetExponential:
+		if p.Scale < otlp.ExponentialHistogramMinScale || p.Scale > otlp.ExponentialHistogramMaxScale {
+			return fmt.Errorf("invalid Scale %v for the exponential target of plugin %v", p.Scale, pluginType)
+		}
+	case targetNative:
+		if p.Scale < otlp.NativeHistogramMinScale || p.Scale > otlp.NativeHistogramMaxScale {
+			return fmt.Errorf("invalid Scale %v for the native target of plugin %v", p.Scale, pluginType)
+		}
+	default:
+		return fmt.Errorf("unknown Target %v for plugin %v", p.Target, pluginType)
+	}
+	for _, name := range p.MetricNames {
+		reg, err := regexp.Compile("^(?:" + name + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid MetricNames %v for plugin %v: %v", name, pluginType, err)
+		}
+		p.names = append(p.names, reg)
+	}
+	return nil
+}
+
+// Description ...
+func (*ProcessorHistogramConvert) Description() string {
+	return "histogram convert processor to convert the histograms between the formats"
+}
+
+func (p *ProcessorHistogramConvert) matchName(name string) bool {
+	if len(p.names) == 0 {
+		return true
+	}
+	for _, reg := range p.names {
+		if reg.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Process ...
+func (p *ProcessorHistogramConvert) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	events := in.Events
+	if p.CollapsePrometheus {
+		events = p.collapse(events)
+	}
+	if p.Target != targetNone {
+		converted := make([]models.PipelineEvent, 0, len(events))
+		for _, event := range events {
+			metric, ok := event.(*models.Metric)
+			if !ok || metric.GetMetricType() != models.MetricTypeHistogram || !metric.GetValue().IsMultiValues() || !p.matchName(metric.GetName()) {
+				converted = append(converted, event)
+				continue
+			}
+			converted = append(converted, p.convert(metric)...)
+		}
+		events = converted
+	}
+	in.Events = events
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+// convert converts the histogram to the target format, the prometheus target expands it to multiple metrics.
+func (p *ProcessorHistogramConvert) convert(metric *models.Metric) []models.PipelineEvent {
+	values := metric.GetValue().GetMultiValues()
+	exponential := metric.GetTags().Get(otlp.TagKeyMetricHistogramType) == otlp.HistogramTypeExponential
+	switch p.Target {
+	case targetExplicit:
+		if exponential {
+			values = otlp.ExponentialToExplicit(values, p.Bounds)
+		} else {
+			values = otlp.ReaggregateExplicit(values, p.Bounds)
+		}
+		setHistogram(metric, values, otlp.HistogramTypeExplicit)
+	case targetExponential, targetNative:
+		if exponential {
+			values = otlp.DownscaleExponential(values, int32(p.Scale))
+		} else {
+			values = otlp.ExplicitToExponential(values, int32(p.Scale))
+		}
+		setHistogram(metric, values, otlp.HistogramTypeExponential)
+	case targetPrometheus:
+		if exponential {
+			values = otlp.ExponentialToExplicit(values, p.Bounds)
+		}
+		return expand(metric, values)
+	}
+	return []models.PipelineEvent{metric}
+}
+
+func setHistogram(metric *models.Metric, values models.MetricFloatValues, histogramType string) {
+	metric.Value = &models.MetricMultiValue{Values: values}
+	if metric.Tags == nil {
+		metric.Tags = models.NewTags()
+	}
+	metric.Tags.Add(otlp.TagKeyMetricHistogramType, histogramType)
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorHistogramConvert{
+			Scale: 3,
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogramconvert
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol/otlp"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorHistogramConvert, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorHistogramConvert)
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func process(p *ProcessorHistogramConvert, events ...models.PipelineEvent) []models.PipelineEvent {
+	ctx := helper.NewObservePipelineConext(10)
+	p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: events}, ctx)
+	groups := ctx.Collector().ToArray()
+	if len(groups) == 0 {
+		return nil
+	}
+	return groups[0].Events
+}
+
+func newClassicSeries(name, le string, value float64) *models.Metric {
+	tags := models.NewTagsWithKeyValues("job", "api")
+	if le != "" {
+		tags.Add("le", le)
+	}
+	return models.NewSingleValueMetric(name, models.MetricTypeCounter, tags, 100, value)
+}
+
+func newExplicitHistogram(buckets map[string]float64, count, sum float64) *models.Metric {
+	values := models.NewMetricMultiValue()
+	for k, v := range buckets {
+		values.Add(k, v)
+	}
+	values.Add(otlp.FieldCount, count)
+	values.Add(otlp.FieldSum, sum)
+	tags := models.NewTagsWithKeyValues("job", "api", otlp.TagKeyMetricHistogramType, otlp.HistogramTypeExplicit)
+	return models.NewMultiValuesMetric("latency", models.MetricTypeHistogram, tags, 100, values.Values)
+}
+
+func TestInit(t *testing.T) {
+	for _, setup := range []func(p *ProcessorHistogramConvert){
+		func(p *ProcessorHistogramConvert) { p.Target = "unknown" },
+		func(p *ProcessorHistogramConvert) { p.Target = targetExplicit },
+		func(p *ProcessorHistogramConvert) { p.Target = targetNative; p.Scale = 9 },
+		func(p *ProcessorHistogramConvert) { p.Target = targetExponential; p.Scale = 21 },
+		func(p *ProcessorHistogramConvert) { p.MetricNames = []string{"("} },
+	} {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		setup(p)
+		assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	}
+}
+
+func TestCollapsePrometheus(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.CollapsePrometheus = true
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	events := process(p,
+		newClassicSeries("latency_bucket", "0.1", 2),
+		newClassicSeries("latency_bucket", "1", 5),
+		newClassicSeries("latency_bucket", "+Inf", 6),
+		newClassicSeries("latency_count", "", 6),
+		newClassicSeries("latency_sum", "", 3.5),
+		newClassicSeries("requests_total", "", 10),
+	)
+	require.Len(t, events, 2)
+	histogram := events[0].(*models.Metric)
+	assert.Equal(t, "latency", histogram.GetName())
+	assert.Equal(t, models.MetricTypeHistogram, histogram.GetMetricType())
+	assert.Equal(t, otlp.HistogramTypeExplicit, histogram.GetTags().Get(otlp.TagKeyMetricHistogramType))
+	assert.Equal(t, "api", histogram.GetTags().Get("job"))
+	assert.False(t, histogram.GetTags().Contains("le"))
+	values := histogram.GetValue().GetMultiValues()
+	assert.Equal(t, 6.0, values.Get(otlp.FieldCount))
+	assert.Equal(t, 3.5, values.Get(otlp.FieldSum))
+	buckets := otlp.ParseExplicitBuckets(values)
+	require.Len(t, buckets, 3)
+	assert.Equal(t, []float64{2, 3, 1}, []float64{buckets[0].Count, buckets[1].Count, buckets[2].Count})
+	assert.Equal(t, 1.0, buckets[1].Upper)
+	assert.True(t, math.IsInf(buckets[2].Upper, 1))
+	assert.Equal(t, "requests_total", events[1].GetName())
+}
+
+func TestExplicitToPrometheus(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Target = targetPrometheus
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	events := process(p, newExplicitHistogram(map[string]float64{
+		otlp.ComposeBucketFieldName(math.Inf(-1), 1, true): 1,
+		otlp.ComposeBucketFieldName(1, 5, true):            3,
+		otlp.ComposeBucketFieldName(5, math.Inf(1), true):  2,
+	}, 6, 12))
+	require.Len(t, events, 5)
+	expected := []struct {
+		name  string
+		le    string
+		value float64
+	}{
+		{"latency_bucket", "1", 1},
+		{"latency_bucket", "5", 4},
+		{"latency_bucket", "+Inf", 6},
+		{"latency_count", "", 6},
+		{"latency_sum", "", 12},
+	}
+	for i, e := range expected {
+		metric := events[i].(*models.Metric)
+		assert.Equal(t, e.name, metric.GetName())
+		assert.Equal(t, e.le, metric.GetTags().Get("le"))
+		assert.Equal(t, e.value, metric.GetValue().GetSingleValue())
+		assert.Equal(t, models.MetricTypeCounter, metric.GetMetricType())
+		assert.False(t, metric.GetTags().Contains(otlp.TagKeyMetricHistogramType))
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	toExponential, err := newProcessor()
+	require.NoError(t, err)
+	toExponential.Target = targetNative
+	toExponential.Scale = 0
+	require.NoError(t, toExponential.Init(mock.NewEmptyContext("p", "l", "c")))
+	events := process(toExponential, newExplicitHistogram(map[string]float64{
+		otlp.ComposeBucketFieldName(math.Inf(-1), 1, true): 1,
+		otlp.ComposeBucketFieldName(1, 4, true):            3,
+		otlp.ComposeBucketFieldName(4, math.Inf(1), true):  0,
+	}, 4, 8))
+	require.Len(t, events, 1)
+	histogram := events[0].(*models.Metric)
+	assert.Equal(t, otlp.HistogramTypeExponential, histogram.GetTags().Get(otlp.TagKeyMetricHistogramType))
+	assert.Equal(t, 4.0, histogram.GetValue().GetMultiValues().Get(otlp.FieldCount))
+
+	toExplicit, err := newProcessor()
+	require.NoError(t, err)
+	toExplicit.Target = targetExplicit
+	toExplicit.Bounds = []float64{1, 4}
+	require.NoError(t, toExplicit.Init(mock.NewEmptyContext("p", "l", "c")))
+	events = process(toExplicit, histogram)
+	require.Len(t, events, 1)
+	histogram = events[0].(*models.Metric)
+	assert.Equal(t, otlp.HistogramTypeExplicit, histogram.GetTags().Get(otlp.TagKeyMetricHistogramType))
+	var total float64
+	for _, bucket := range otlp.ParseExplicitBuckets(histogram.GetValue().GetMultiValues()) {
+		total += bucket.Count
+	}
+	assert.Equal(t, 4.0, total)
+}
+
+func TestMetricNames(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Target = targetPrometheus
+	p.MetricNames = []string{"other"}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	events := process(p, newExplicitHistogram(map[string]float64{otlp.ComposeBucketFieldName(math.Inf(-1), math.Inf(1), true): 1}, 1, 1))
+	require.Len(t, events, 1)
+	assert.Equal(t, "latency", events[0].GetName())
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package histogramconvert
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol/otlp"
+)
+
+const (
+	suffixBucket = "_bucket"
+	suffixCount  = "_count"
+	suffixSum    = "_sum"
+	labelLe      = "le"
+)
+
+// classicSeries is the series of a Prometheus classic histogram sample, the bucket counts are cumulative.
+type classicSeries struct {
+	metric   *models.Metric
+	buckets  map[float64]float64
+	count    float64
+	hasCount bool
+	sum      float64
+	hasSum   bool
+}
+
+// collapse collapses the Prometheus classic series with the same tags and timestamp into the histograms, which
+// take the place of the first series. The series without buckets are kept as is.
+func (p *ProcessorHistogramConvert) collapse(events []models.PipelineEvent) []models.PipelineEvent {
+	histograms := make(map[string]*classicSeries)
+	positions := make([]*classicSeries, len(events))
+	for i, event := range events {
+		metric, ok := event.(*models.Metric)
+		if !ok || !metric.GetValue().IsSingleValue() {
+			continue
+		}
+		name, suffix := splitClassicName(metric.GetName())
+		if suffix == "" || !p.matchName(name) {
+			continue
+		}
+		le := math.NaN()
+		if suffix == suffixBucket {
+			var err error
+			if le, err = strconv.ParseFloat(metric.GetTags().Get(labelLe), 64); err != nil {
+				continue
+			}
+		}
+		key := seriesKey(name, metric)
+		series, ok := histograms[key]
+		if !ok {
+			series = &classicSeries{buckets: make(map[float64]float64)}
+			histograms[key] = series
+		}
+		value := metric.GetValue().GetSingleValue()
+		switch suffix {
+		case suffixBucket:
+			series.buckets[le] = value
+			if series.metric == nil {
+				series.metric = metric
+			}
+		case suffixCount:
+			series.count = value
+			series.hasCount = true
+		case suffixSum:
+			series.sum = value
+			series.hasSum = true
+		}
+		positions[i] = series
+	}
+
+	result := make([]models.PipelineEvent, 0, len(events))
+	emitted := make(map[*classicSeries]bool)
+	for i, event := range events {
+		series := positions[i]
+		if series == nil || series.metric == nil {
+			result = append(result, event)
+			continue
+		}
+		if !emitted[series] {
+			emitted[series] = true
+			result = append(result, series.toHistogram())
+		}
+	}
+	return result
+}
+
+func splitClassicName(name string) (string, string) {
+	for _, suffix := range []string{suffixBucket, suffixCount, suffixSum} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix), suffix
+		}
+	}
+	return name, ""
+}
+
+// seriesKey is the name, the timestamp and the tags except le.
+func seriesKey(name string, metric *models.Metric) string {
+	var builder strings.Builder
+	builder.WriteString(name)
+	builder.WriteByte('\x00')
+	builder.WriteString(strconv.FormatUint(metric.GetTimestamp(), 10))
+	if tags := metric.GetTags(); tags != nil {
+		for _, kv := range tags.SortTo(nil) {
+			if kv.Key == labelLe {
+				continue
+			}
+			builder.WriteByte('\x00')
+			builder.WriteString(kv.Key)
+			builder.WriteByte('=')
+			builder.WriteString(kv.Value)
+		}
+	}
+	return builder.String()
+}
+
+func (s *classicSeries) toHistogram() *models.Metric {
+	les := make([]float64, 0, len(s.buckets))
+	for le := range s.buckets {
+		les = append(les, le)
+	}
+	sort.Float64s(les)
+	values := models.NewMetricMultiValue()
+	lower, cumulative := math.Inf(-1), 0.0
+	for _, le := range les {
+		values.Add(otlp.ComposeBucketFieldName(lower, le, true), s.buckets[le]-cumulative)
+		lower, cumulative = le, s.buckets[le]
+	}
+	if !math.IsInf(lower, 1) {
+		// the +Inf bucket is missing, the count of the histogram is the total
+		values.Add(otlp.ComposeBucketFieldName(lower, math.Inf(1), true), math.Max(s.count-cumulative, 0))
+	}
+	if !s.hasCount {
+		s.count = math.Max(s.count, cumulative)
+	}
+	values.Add(otlp.FieldCount, s.count)
+	if s.hasSum {
+		values.Add(otlp.FieldSum, s.sum)
+	}
+
+	name, _ := splitClassicName(s.metric.GetName())
+	tags := models.NewTags()
+	for k, v := range s.metric.GetTags().Iterator() {
+		if k != labelLe {
+			tags.Add(k, v)
+		}
+	}
+	tags.Add(otlp.TagKeyMetricHistogramType, otlp.HistogramTypeExplicit)
+	tags.Add(otlp.TagKeyMetricAggregationTemporality, pmetric.AggregationTemporalityCumulative.String())
+	histogram := models.NewMultiValuesMetric(name, models.MetricTypeHistogram, tags, int64(s.metric.GetTimestamp()), values.Values)
+	histogram.SetObservedTimestamp(s.metric.GetObservedTimestamp())
+	return histogram
+}
+
+// expand expands the explicit bucket histogram into the Prometheus classic series, the internal tags are removed.
+func expand(metric *models.Metric, values models.MetricFloatValues) []models.PipelineEvent {
+	name := metric.GetName()
+	timestamp := int64(metric.GetTimestamp())
+	baseTags := func() models.Tags {
+		tags := models.NewTags()
+		if metric.GetTags() != nil {
+			for k, v := range metric.GetTags().Iterator() {
+				if !otlp.IsInternalTag(k) && k != otlp.TagKeyMetricHistogramType {
+					tags.Add(k, v)
+				}
+			}
+		}
+		return tags
+	}
+	buckets := otlp.ParseExplicitBuckets(values)
+	events := make([]models.PipelineEvent, 0, len(buckets)+2)
+	cumulative := 0.0
+	for _, bucket := range buckets {
+		cumulative += bucket.Count
+		tags := baseTags()
+		tags.Add(labelLe, formatLe(bucket.Upper))
+		events = append(events, models.NewSingleValueMetric(name+suffixBucket, models.MetricTypeCounter, tags, timestamp, cumulative))
+	}
+	if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].Upper, 1) {
+		tags := baseTags()
+		tags.Add(labelLe, formatLe(math.Inf(1)))
+		events = append(events, models.NewSingleValueMetric(name+suffixBucket, models.MetricTypeCounter, tags, timestamp, values.Get(otlp.FieldCount)))
+	}
+	events = append(events, models.NewSingleValueMetric(name+suffixCount, models.MetricTypeCounter, baseTags(), timestamp, values.Get(otlp.FieldCount)))
+	if values.Contains(otlp.FieldSum) {
+		events = append(events, models.NewSingleValueMetric(name+suffixSum, models.MetricTypeCounter, baseTags(), timestamp, values.Get(otlp.FieldSum)))
+	}
+	return events
+}
+
+func formatLe(le float64) string {
+	if math.IsInf(le, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(le, 'g', -1, 64)
+}
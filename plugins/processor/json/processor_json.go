@@ -15,7 +15,9 @@
 package json
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/buger/jsonparser"
 
@@ -37,17 +39,47 @@ type ProcessorJSON struct {
 	UseSourceKeyAsPrefix   bool // Should SourceKey be used as prefix for all extracted keys.
 	IgnoreFirstConnector   bool // 是否忽略第一个Connector
 	ExpandArray            bool // 是否展开数组类型
+	// MaxDepth limits the nesting depth of the json, the top level object is depth 1, 0 means no limit.
+	// A json nested deeper is treated as a parse error.
+	MaxDepth int
+	// DuplicateKeyPolicy decides what to do when the expansion produces a key twice, one of first, last and error.
+	// Empty keeps all of them in v1 and the last one in v2.
+	DuplicateKeyPolicy string
+	// ParseNumber stores the json numbers as int64 or float64 in v2, v1 contents are always strings.
+	ParseNumber bool
+	// KeepLargeNumberAsString keeps the numbers which cannot be exactly stored as int64 or float64 as the original
+	// strings when ParseNumber is on, e.g. the long numeric ids.
+	KeepLargeNumberAsString bool
 
 	context pipeline.Context
 }
 
 const pluginType = "processor_json"
 
+const (
+	duplicateKeyFirst = "first"
+	duplicateKeyLast  = "last"
+	duplicateKeyError = "error"
+)
+
+// float64 keeps any decimal with no more than 15 significant digits exactly
+const maxFloatSignificantDigits = 15
+
+var (
+	errMaxDepthExceeded = errors.New("json exceeds max depth")
+	errDuplicateKey     = errors.New("json has duplicate key")
+)
+
 // Init called for init some system resources, like socket, mutex...
 func (p *ProcessorJSON) Init(context pipeline.Context) error {
 	if p.SourceKey == "" {
 		return fmt.Errorf("must specify SourceKey for plugin %v", pluginType)
 	}
+	switch p.DuplicateKeyPolicy {
+	case "", duplicateKeyFirst, duplicateKeyLast, duplicateKeyError:
+	default:
+		return fmt.Errorf("invalid DuplicateKeyPolicy %v for plugin %v, must be one of first, last and error", p.DuplicateKeyPolicy, pluginType)
+	}
 	p.context = context
 	return nil
 }
@@ -68,20 +100,10 @@ func (p *ProcessorJSON) processLog(log *protocol.Log) {
 	for idx := range log.Contents {
 		if log.Contents[idx].Key == p.SourceKey {
 			objectVal := log.Contents[idx].Value
-			param := ExpandParam{
-				sourceKey:            p.SourceKey,
-				log:                  log,
-				nowDepth:             0,
-				maxDepth:             p.ExpandDepth,
-				connector:            p.ExpandConnector,
-				prefix:               p.Prefix,
-				ignoreFirstConnector: p.IgnoreFirstConnector,
-				expandArray:          p.ExpandArray,
-			}
-			if p.UseSourceKeyAsPrefix {
-				param.preKey = p.SourceKey
-			}
-			err := jsonparser.ObjectEach([]byte(objectVal), param.ExpandJSONCallBack)
+			param := p.newExpandParam()
+			param.log = log
+			param.originLen = len(log.Contents)
+			err := param.expand([]byte(objectVal), p.MaxDepth)
 			if err != nil {
 				logger.Errorf(p.context.GetRuntimeContext(), "PROCESSOR_JSON_PARSER_ALARM", "parser json error %v", err)
 			}
@@ -97,6 +119,29 @@ func (p *ProcessorJSON) processLog(log *protocol.Log) {
 	}
 }
 
+func (p *ProcessorJSON) newExpandParam() *ExpandParam {
+	param := &ExpandParam{
+		sourceKey:               p.SourceKey,
+		nowDepth:                0,
+		maxDepth:                p.ExpandDepth,
+		connector:               p.ExpandConnector,
+		prefix:                  p.Prefix,
+		ignoreFirstConnector:    p.IgnoreFirstConnector,
+		expandArray:             p.ExpandArray,
+		duplicateKeyPolicy:      p.DuplicateKeyPolicy,
+		parseNumber:             p.ParseNumber,
+		keepLargeNumberAsString: p.KeepLargeNumberAsString,
+	}
+	if p.UseSourceKeyAsPrefix {
+		param.preKey = p.SourceKey
+	}
+	if p.DuplicateKeyPolicy != "" {
+		param.emitted = make(map[string]int)
+		param.previous = make(map[string]interface{})
+	}
+	return param
+}
+
 func (p *ProcessorJSON) shouldKeepSource(err error) bool {
 	return p.KeepSource || (p.KeepSourceIfParseError && err != nil)
 }
@@ -113,6 +158,8 @@ func init() {
 			KeepSourceIfParseError: true,
 			UseSourceKeyAsPrefix:   false,
 			ExpandArray:            false,
+
+			KeepLargeNumberAsString: true,
 		}
 	}
 }
@@ -129,6 +176,83 @@ type ExpandParam struct {
 	ignoreFirstConnector   bool
 	isSourceKeyOverwritten bool
 	expandArray            bool
+
+	duplicateKeyPolicy      string
+	parseNumber             bool
+	keepLargeNumberAsString bool
+	// emitted records the expanded keys with their index in the v1 contents, only used with a duplicate key policy
+	emitted map[string]int
+	// previous records the values of the v2 contents existing before the expansion overwrites them, restored if the
+	// expansion is rolled back, only used with a duplicate key policy
+	previous map[string]interface{}
+	// originLen is the length of the v1 contents before the expansion
+	originLen int
+	// sourceValue is the source value in v2, restored if the expansion is rolled back after overwriting it
+	sourceValue interface{}
+	err         error
+}
+
+// expand flattens the json object into the contents. If the json violates the max depth or the duplicate key
+// policy, nothing is added and the error is returned.
+func (p *ExpandParam) expand(data []byte, maxNestingDepth int) error {
+	if maxNestingDepth > 0 && exceedsDepth(data, maxNestingDepth) {
+		return errMaxDepthExceeded
+	}
+	err := jsonparser.ObjectEach(data, p.ExpandJSONCallBack)
+	if p.err != nil {
+		p.rollback()
+		return p.err
+	}
+	return err
+}
+
+func (p *ExpandParam) rollback() {
+	if p.log != nil {
+		p.log.Contents = p.log.Contents[:p.originLen]
+		return
+	}
+	for key := range p.emitted {
+		if value, ok := p.previous[key]; ok {
+			p.contents.Add(key, value)
+		} else {
+			p.contents.Delete(key)
+		}
+	}
+	if p.isSourceKeyOverwritten {
+		p.contents.Add(p.sourceKey, p.sourceValue)
+		p.isSourceKeyOverwritten = false
+	}
+}
+
+// exceedsDepth tells whether the objects and arrays in the json are nested deeper than maxDepth.
+// The brackets in strings are skipped, the syntax is left to the parser.
+func exceedsDepth(data []byte, maxDepth int) bool {
+	depth := 0
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
 }
 
 func (p *ExpandParam) getConnector(depth int) string {
@@ -147,11 +271,11 @@ func (p *ExpandParam) ExpandJSONCallBack(key []byte, value []byte, dataType json
 	case jsonparser.Array:
 		p.flattenArray(key, value)
 	default:
-		p.flattenValue(key, value)
+		p.flattenValue(key, value, dataType)
 	}
 
 	p.nowDepth--
-	return nil
+	return p.err
 }
 
 func (p *ExpandParam) flattenObject(key []byte, value []byte) {
@@ -184,15 +308,21 @@ func (p *ExpandParam) flattenArray(key []byte, value []byte) {
 		if dataType == jsonparser.Object {
 			p.flattenObject(newKey, val)
 		} else {
-			p.flattenValue(newKey, val)
+			p.flattenValue(newKey, val, dataType)
 		}
 		index++
 	})
 }
 
-func (p *ExpandParam) flattenValue(key []byte, value []byte) {
+func (p *ExpandParam) flattenValue(key []byte, value []byte, dataType jsonparser.ValueType) {
 	// If the current value is not a JSON object, nor a JSON array, add it directly to the result
 	newKey := p.preKey + p.getConnector(p.nowDepth) + (string)(key)
+	if p.parseNumber && p.log == nil && dataType == jsonparser.Number {
+		if number, ok := p.parseNumberValue(value); ok {
+			p.appendNewContentV2(newKey, number)
+			return
+		}
+	}
 	if strValue, err := jsonparser.ParseString(value); err == nil {
 		p.appendNewContent(newKey, strValue)
 	} else {
@@ -200,6 +330,69 @@ func (p *ExpandParam) flattenValue(key []byte, value []byte) {
 	}
 }
 
+// parseNumberValue converts the json number to int64 or float64, false is returned if it is kept as string to
+// avoid losing precision.
+func (p *ExpandParam) parseNumberValue(value []byte) (interface{}, bool) {
+	str := string(value)
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return i, true
+	}
+	if p.keepLargeNumberAsString && significantDigits(str) > maxFloatSignificantDigits {
+		return nil, false
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// significantDigits counts the significant digits of the mantissa of the json number.
+func significantDigits(number string) int {
+	first, last := -1, -1
+	pos := 0
+	for i := 0; i < len(number); i++ {
+		c := number[i]
+		if c == 'e' || c == 'E' {
+			break
+		}
+		if c < '0' || c > '9' {
+			continue
+		}
+		if c != '0' {
+			if first < 0 {
+				first = pos
+			}
+			last = pos
+		}
+		pos++
+	}
+	if first < 0 {
+		return 0
+	}
+	return last - first + 1
+}
+
+// checkDuplicateKey applies the duplicate key policy to the expanded key. The index of the v1 content to overwrite
+// is returned, or -1 if a new content should be added, false is returned if the content should be dropped.
+func (p *ExpandParam) checkDuplicateKey(key string) (int, bool) {
+	if p.err != nil {
+		return -1, false
+	}
+	idx, ok := p.emitted[key]
+	if !ok {
+		return -1, true
+	}
+	switch p.duplicateKeyPolicy {
+	case duplicateKeyFirst:
+		return -1, false
+	case duplicateKeyError:
+		p.err = fmt.Errorf("%w %v", errDuplicateKey, key)
+		return -1, false
+	default:
+		return idx, true
+	}
+}
+
 func (p *ExpandParam) appendNewContent(key string, value string) {
 	if p.log != nil {
 		p.appendNewContentV1(key, value)
@@ -210,28 +403,43 @@ func (p *ExpandParam) appendNewContent(key string, value string) {
 
 func (p *ExpandParam) appendNewContentV1(key string, value string) {
 	if len(p.prefix) > 0 {
-		newContent := &protocol.Log_Content{
-			Key:   p.prefix + key,
-			Value: value,
-		}
-		p.log.Contents = append(p.log.Contents, newContent)
-	} else {
-		newContent := &protocol.Log_Content{
-			Key:   key,
-			Value: value,
-		}
-		p.log.Contents = append(p.log.Contents, newContent)
+		key = p.prefix + key
+	}
+	idx, ok := p.checkDuplicateKey(key)
+	if !ok {
+		return
+	}
+	if idx >= 0 {
+		p.log.Contents[idx].Value = value
+		return
 	}
+	if p.emitted != nil {
+		p.emitted[key] = len(p.log.Contents)
+	}
+	p.log.Contents = append(p.log.Contents, &protocol.Log_Content{
+		Key:   key,
+		Value: value,
+	})
 }
 
-func (p *ExpandParam) appendNewContentV2(key string, value string) {
+func (p *ExpandParam) appendNewContentV2(key string, value interface{}) {
 	if len(p.prefix) > 0 {
 		key = p.prefix + key
 	}
-	p.contents.Add(key, value)
-	if key == p.sourceKey {
+	if _, ok := p.checkDuplicateKey(key); !ok {
+		return
+	}
+	if p.emitted != nil {
+		if _, ok := p.emitted[key]; !ok && p.contents.Contains(key) {
+			p.previous[key] = p.contents.Get(key)
+		}
+		p.emitted[key] = -1
+	}
+	if key == p.sourceKey && !p.isSourceKeyOverwritten {
+		p.sourceValue = p.contents.Get(key)
 		p.isSourceKeyOverwritten = true
 	}
+	p.contents.Add(key, value)
 }
 
 func (p *ProcessorJSON) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
@@ -263,20 +471,9 @@ func (p *ProcessorJSON) processEvent(event models.PipelineEvent) {
 		logger.Warningf(p.context.GetRuntimeContext(), "PROCESSOR_JSON_FIND_ALARM", "key %v is not string", p.SourceKey)
 		return
 	}
-	param := ExpandParam{
-		sourceKey:            p.SourceKey,
-		contents:             contents,
-		nowDepth:             0,
-		maxDepth:             p.ExpandDepth,
-		connector:            p.ExpandConnector,
-		prefix:               p.Prefix,
-		ignoreFirstConnector: p.IgnoreFirstConnector,
-		expandArray:          p.ExpandArray,
-	}
-	if p.UseSourceKeyAsPrefix {
-		param.preKey = p.SourceKey
-	}
-	err := jsonparser.ObjectEach(bytesVal, param.ExpandJSONCallBack)
+	param := p.newExpandParam()
+	param.contents = contents
+	err := param.expand(bytesVal, p.MaxDepth)
 	if err != nil {
 		logger.Errorf(p.context.GetRuntimeContext(), "PROCESSOR_JSON_PARSER_ALARM", "parser json error %v", err)
 	}
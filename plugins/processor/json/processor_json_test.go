@@ -401,3 +401,106 @@ func TestExpandArrayV2(t *testing.T) {
 	assert.Equal(t, "b", contents.Get("js_key-k6[1]-x"))
 	assert.False(t, contents.Contains("js_key-k7"))
 }
+
+func TestMaxDepth(t *testing.T) {
+	processor, err := newProcessor()
+	require.NoError(t, err)
+	processor.KeepSource = false
+	processor.MaxDepth = 4
+
+	log := &protocol.Log{Time: 0}
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: "s_key", Value: jsonVal})
+	processor.processLog(log)
+	require.Equal(t, 1, len(log.Contents))
+	assert.Equal(t, "s_key", log.Contents[0].Key)
+
+	// brackets in strings are not counted
+	processor.MaxDepth = 2
+	log = &protocol.Log{Time: 0}
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: "s_key", Value: `{"a":{"b":"[[{{\"}"}}`})
+	processor.processLog(log)
+	require.Equal(t, 1, len(log.Contents))
+	assert.Equal(t, "js_key-a-b", log.Contents[0].Key)
+	assert.Equal(t, `[[{{"}`, log.Contents[0].Value)
+}
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	const dupVal = `{"a":"1","b":"2","a":"3"}`
+	cases := []struct {
+		policy   string
+		expected []string
+	}{
+		{"", []string{"s_key", dupVal, "js_key-a", "1", "js_key-b", "2", "js_key-a", "3"}},
+		{"first", []string{"s_key", dupVal, "js_key-a", "1", "js_key-b", "2"}},
+		{"last", []string{"s_key", dupVal, "js_key-a", "3", "js_key-b", "2"}},
+		{"error", []string{"s_key", dupVal}},
+	}
+	for _, c := range cases {
+		processor, err := newProcessor()
+		require.NoError(t, err)
+		processor.DuplicateKeyPolicy = c.policy
+		require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+		log := &protocol.Log{Time: 0}
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: "s_key", Value: dupVal})
+		processor.processLog(log)
+		result := make([]string, 0, len(log.Contents)*2)
+		for _, content := range log.Contents {
+			result = append(result, content.Key, content.Value)
+		}
+		assert.Equal(t, c.expected, result, c.policy)
+	}
+
+	processor, _ := newProcessor()
+	processor.DuplicateKeyPolicy = "any"
+	assert.Error(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestDuplicateKeyPolicyErrorV2(t *testing.T) {
+	processor := &ProcessorJSON{
+		SourceKey:              "key",
+		KeepSourceIfParseError: true,
+		DuplicateKeyPolicy:     "error",
+	}
+	require.NoError(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	const dupVal = `{"key":"1","other":"2","key":"3"}`
+	log := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents := log.GetIndices()
+	contents.Add("key", dupVal)
+	processor.processEvent(log)
+	assert.Equal(t, 1, contents.Len())
+	assert.Equal(t, dupVal, contents.Get("key"))
+
+	// the existing contents overwritten by the expansion are restored
+	log = models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents = log.GetIndices()
+	contents.Add("a", "origin")
+	contents.Add("key", `{"a":1,"a":2}`)
+	processor.processEvent(log)
+	assert.Equal(t, 2, contents.Len())
+	assert.Equal(t, "origin", contents.Get("a"))
+	assert.Equal(t, `{"a":1,"a":2}`, contents.Get("key"))
+}
+
+func TestParseNumberV2(t *testing.T) {
+	processor, err := newProcessor()
+	require.NoError(t, err)
+	processor.ParseNumber = true
+	processor.KeepLargeNumberAsString = true
+	log := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents := log.GetIndices()
+	contents.Add("s_key", `{"int":-42,"float":1.25,"id":12345678901234567890,"decimal":0.12345678901234567,"exp":1.5e3,"str":"7"}`)
+	processor.processEvent(log)
+	assert.Equal(t, int64(-42), contents.Get("js_key-int"))
+	assert.Equal(t, 1.25, contents.Get("js_key-float"))
+	assert.Equal(t, "12345678901234567890", contents.Get("js_key-id"))
+	assert.Equal(t, "0.12345678901234567", contents.Get("js_key-decimal"))
+	assert.Equal(t, 1500.0, contents.Get("js_key-exp"))
+	assert.Equal(t, "7", contents.Get("js_key-str"))
+
+	processor.KeepLargeNumberAsString = false
+	log = models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents = log.GetIndices()
+	contents.Add("s_key", `{"id":12345678901234567890}`)
+	processor.processEvent(log)
+	assert.Equal(t, 12345678901234567890.0, contents.Get("js_key-id"))
+}
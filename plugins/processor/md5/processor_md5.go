@@ -18,6 +18,7 @@ import (
 	"crypto/md5" //nolint:gosec
 	"fmt"
 
+	"github.com/alibaba/ilogtail/pkg/fips"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -34,7 +35,7 @@ type ProcessorMD5 struct {
 // Init called for init some system resources, like socket, mutex...
 func (p *ProcessorMD5) Init(context pipeline.Context) error {
 	p.context = context
-	return nil
+	return fips.CheckHash("md5")
 }
 
 func (*ProcessorMD5) Description() string {
@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/alibaba/ilogtail/pkg/flags"
 	"github.com/alibaba/ilogtail/pkg/logger"
 	"github.com/alibaba/ilogtail/pkg/pipeline"
 	"github.com/alibaba/ilogtail/pkg/protocol"
@@ -53,6 +54,13 @@ func TestSourceKey(t *testing.T) {
 	assert.Equal(t, "202cb962ac59075b964b07152d234b70", log.Contents[1].Value)
 }
 
+func TestInitFIPSMode(t *testing.T) {
+	*flags.FIPSMode = true
+	defer func() { *flags.FIPSMode = false }()
+	_, err := newProcessor()
+	assert.Error(t, err)
+}
+
 func TestNoKeyError(t *testing.T) {
 	logger.ClearMemoryLog()
 	ctx := mock.NewEmptyContext("p", "l", "c")
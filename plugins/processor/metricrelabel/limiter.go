@@ -0,0 +1,75 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricrelabel
+
+import "time"
+
+// seriesLimiter tracks the active series of each metric. The inactive series are swept at most once per second
+// for each metric when the limit is reached, so a metric keeping overflowing is not swept for every sample.
+type seriesLimiter struct {
+	maxSeries int
+	ttl       time.Duration
+	metrics   map[string]*metricSeries
+}
+
+type metricSeries struct {
+	lastSeen  map[string]time.Time
+	lastSweep time.Time
+	lastAlarm time.Time
+}
+
+func newSeriesLimiter(maxSeries int, ttl time.Duration) *seriesLimiter {
+	return &seriesLimiter{
+		maxSeries: maxSeries,
+		ttl:       ttl,
+		metrics:   make(map[string]*metricSeries),
+	}
+}
+
+// admit returns true if the series is active or could be added under the limit.
+func (l *seriesLimiter) admit(metric, series string, now time.Time) bool {
+	m, ok := l.metrics[metric]
+	if !ok {
+		m = &metricSeries{lastSeen: make(map[string]time.Time)}
+		l.metrics[metric] = m
+	}
+	if _, ok = m.lastSeen[series]; ok {
+		m.lastSeen[series] = now
+		return true
+	}
+	if len(m.lastSeen) >= l.maxSeries && l.ttl > 0 && now.Sub(m.lastSweep) >= time.Second {
+		m.lastSweep = now
+		for s, seen := range m.lastSeen {
+			if now.Sub(seen) > l.ttl {
+				delete(m.lastSeen, s)
+			}
+		}
+	}
+	if len(m.lastSeen) >= l.maxSeries {
+		return false
+	}
+	m.lastSeen[series] = now
+	return true
+}
+
+// shouldAlarm returns true at most once per minute for each metric.
+func (l *seriesLimiter) shouldAlarm(metric string, now time.Time) bool {
+	m := l.metrics[metric]
+	if now.Sub(m.lastAlarm) < time.Minute {
+		return false
+	}
+	m.lastAlarm = now
+	return true
+}
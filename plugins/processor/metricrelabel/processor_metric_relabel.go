@@ -0,0 +1,266 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricrelabel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+)
+
+const pluginType = "processor_metric_relabel"
+
+const (
+	overflowDrop      = "drop"
+	overflowAggregate = "aggregate"
+)
+
+const (
+	metricNameKey   = "__name__"
+	metricLabelsKey = "__labels__"
+)
+
+// RelabelRule is a Prometheus relabel config, the metric name is the __name__ label.
+type RelabelRule struct {
+	SourceLabels []string // The labels whose values are concatenated with Separator and matched against Regex.
+	Separator    string   // Default is ;.
+	Regex        string   // The regexp fully matched against the concatenated value, default is (.*).
+	Modulus      uint64   // The modulus of the hashmod action.
+	TargetLabel  string   // The label written by the replace and hashmod actions.
+	Replacement  *string  // The replacement of the replace and labelmap actions, default is $1.
+	Action       string   // One of replace, keep, drop, hashmod, labelmap, labeldrop and labelkeep, default is replace.
+}
+
+// ProcessorMetricRelabel relabels the metrics with the Prometheus relabel rules, and limits the active series of each metric.
+type ProcessorMetricRelabel struct {
+	Rules              []RelabelRule
+	MaxSeriesPerMetric int    // The max active series of each metric after relabeling, 0 means no limit.
+	SeriesTTLSec       int    // The series not seen in the time are inactive, and no longer count towards the limit.
+	OverflowAction     string // The action on the samples of the new series beyond the limit, drop or aggregate.
+	OverflowLabel      string // The only label, with value true, of the series aggregating the samples beyond the limit.
+
+	configs []*relabel.Config
+	limiter *seriesLimiter
+	context pipeline.Context
+}
+
+// Init ...
+func (p *ProcessorMetricRelabel) Init(context pipeline.Context) error {
+	p.context = context
+	for i, rule := range p.Rules {
+		cfg, err := rule.toConfig()
+		if err != nil {
+			return fmt.Errorf("invalid Rules[%d] for plugin %v: %v", i, pluginType, err)
+		}
+		p.configs = append(p.configs, cfg)
+	}
+	switch p.OverflowAction {
+	case overflowDrop:
+	case overflowAggregate:
+		if !model.LabelName(p.OverflowLabel).IsValid() {
+			return fmt.Errorf("invalid OverflowLabel %q for plugin %v", p.OverflowLabel, pluginType)
+		}
+	default:
+		return fmt.Errorf("unknown OverflowAction %v for plugin %v", p.OverflowAction, pluginType)
+	}
+	if p.MaxSeriesPerMetric > 0 {
+		p.limiter = newSeriesLimiter(p.MaxSeriesPerMetric, time.Duration(p.SeriesTTLSec)*time.Second)
+	}
+	return nil
+}
+
+func (r *RelabelRule) toConfig() (*relabel.Config, error) {
+	cfg := relabel.DefaultRelabelConfig
+	if r.Action != "" {
+		cfg.Action = relabel.Action(strings.ToLower(r.Action))
+	}
+	for _, name := range r.SourceLabels {
+		cfg.SourceLabels = append(cfg.SourceLabels, model.LabelName(name))
+	}
+	if r.Separator != "" {
+		cfg.Separator = r.Separator
+	}
+	if r.Regex != "" {
+		regex, err := relabel.NewRegexp(r.Regex)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Regex = regex
+	}
+	if r.Replacement != nil {
+		cfg.Replacement = *r.Replacement
+	}
+	cfg.Modulus = r.Modulus
+	cfg.TargetLabel = r.TargetLabel
+	switch cfg.Action {
+	case relabel.Replace, relabel.Keep, relabel.Drop, relabel.LabelMap, relabel.LabelDrop, relabel.LabelKeep:
+	case relabel.HashMod:
+		if cfg.Modulus == 0 {
+			return nil, fmt.Errorf("hashmod action requires non-zero Modulus")
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	if (cfg.Action == relabel.Replace || cfg.Action == relabel.HashMod) && cfg.TargetLabel == "" {
+		return nil, fmt.Errorf("%s action requires TargetLabel", cfg.Action)
+	}
+	return &cfg, nil
+}
+
+// Description ...
+func (*ProcessorMetricRelabel) Description() string {
+	return "metric relabel processor to relabel the metrics and limit the series of each metric"
+}
+
+// relabel returns the relabeled labels, or nil if the metric should be dropped.
+func (p *ProcessorMetricRelabel) relabel(lset labels.Labels, now time.Time) labels.Labels {
+	lset = relabel.Process(lset, p.configs...)
+	name := lset.Get(metricNameKey)
+	if name == "" {
+		return nil
+	}
+	if p.limiter == nil || p.limiter.admit(name, lset.String(), now) {
+		return lset
+	}
+	if p.limiter.shouldAlarm(name, now) {
+		logger.Warningf(p.context.GetRuntimeContext(), "METRIC_RELABEL_ALARM", "series of metric %v exceed the limit %v, overflow action %v",
+			name, p.MaxSeriesPerMetric, p.OverflowAction)
+	}
+	if p.OverflowAction == overflowDrop {
+		return nil
+	}
+	return labels.FromStrings(metricNameKey, name, p.OverflowLabel, "true")
+}
+
+// ProcessLogs relabels the metrics in the SLS metric format.
+func (p *ProcessorMetricRelabel) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	now := time.Now()
+	kept := logArray[:0]
+	for _, log := range logArray {
+		if p.processLog(log, now) {
+			kept = append(kept, log)
+		}
+	}
+	for i := len(kept); i < len(logArray); i++ {
+		logArray[i] = nil
+	}
+	return kept
+}
+
+// processLog returns false if the log should be dropped, the logs not in the metric format are kept.
+func (p *ProcessorMetricRelabel) processLog(log *protocol.Log, now time.Time) bool {
+	var nameContent, labelsContent *protocol.Log_Content
+	for _, content := range log.Contents {
+		switch content.Key {
+		case metricNameKey:
+			nameContent = content
+		case metricLabelsKey:
+			labelsContent = content
+		}
+	}
+	if nameContent == nil {
+		return true
+	}
+	builder := labels.NewBuilder(nil).Set(metricNameKey, nameContent.Value)
+	if labelsContent != nil && labelsContent.Value != "" {
+		for _, pair := range strings.Split(labelsContent.Value, "|") {
+			if kv := strings.SplitN(pair, "#$#", 2); len(kv) == 2 {
+				builder.Set(kv[0], kv[1])
+			}
+		}
+	}
+	lset := p.relabel(builder.Labels(), now)
+	if lset == nil {
+		return false
+	}
+	var sb strings.Builder
+	for _, l := range lset {
+		if l.Name == metricNameKey {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(l.Name)
+		sb.WriteString("#$#")
+		sb.WriteString(l.Value)
+	}
+	nameContent.Value = lset.Get(metricNameKey)
+	if labelsContent != nil {
+		labelsContent.Value = sb.String()
+	} else if sb.Len() > 0 {
+		log.Contents = append(log.Contents, &protocol.Log_Content{Key: metricLabelsKey, Value: sb.String()})
+	}
+	return true
+}
+
+// Process ...
+func (p *ProcessorMetricRelabel) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	now := time.Now()
+	kept := in.Events[:0]
+	for _, event := range in.Events {
+		if metric, ok := event.(*models.Metric); ok && !p.processMetric(metric, now) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	for i := len(kept); i < len(in.Events); i++ {
+		in.Events[i] = nil
+	}
+	in.Events = kept
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+// processMetric returns false if the metric should be dropped.
+func (p *ProcessorMetricRelabel) processMetric(metric *models.Metric, now time.Time) bool {
+	builder := labels.NewBuilder(nil).Set(metricNameKey, metric.GetName())
+	if tags := metric.GetTags(); tags != nil {
+		for k, v := range tags.Iterator() {
+			builder.Set(k, v)
+		}
+	}
+	lset := p.relabel(builder.Labels(), now)
+	if lset == nil {
+		return false
+	}
+	tags := models.NewTags()
+	for _, l := range lset {
+		if l.Name != metricNameKey {
+			tags.Add(l.Name, l.Value)
+		}
+	}
+	metric.Name = lset.Get(metricNameKey)
+	metric.Tags = tags
+	return true
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorMetricRelabel{
+			SeriesTTLSec:   600,
+			OverflowAction: overflowDrop,
+			OverflowLabel:  "__overflow__",
+		}
+	}
+}
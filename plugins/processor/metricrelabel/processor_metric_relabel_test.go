@@ -0,0 +1,156 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricrelabel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorMetricRelabel, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorMetricRelabel)
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func newMetricLog(name, labels string) *protocol.Log {
+	return &protocol.Log{Contents: []*protocol.Log_Content{
+		{Key: metricNameKey, Value: name},
+		{Key: metricLabelsKey, Value: labels},
+		{Key: "__value__", Value: "1"},
+	}}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestInit(t *testing.T) {
+	for _, rule := range []RelabelRule{
+		{Action: "unknown"},
+		{Action: "replace"},
+		{Action: "hashmod", TargetLabel: "shard"},
+		{Action: "keep", Regex: "("},
+	} {
+		p, err := newProcessor()
+		require.NoError(t, err)
+		p.Rules = []RelabelRule{rule}
+		assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")), rule.Action)
+	}
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.OverflowAction = overflowAggregate
+	p.OverflowLabel = "bad-label"
+	assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestProcessLogs(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Rules = []RelabelRule{
+		{SourceLabels: []string{"__name__"}, Regex: "go_.*", Action: "drop"},
+		{SourceLabels: []string{"instance"}, Regex: "([^:]+):.*", TargetLabel: "host", Replacement: stringPtr("${1}")},
+		{Regex: "instance", Action: "labeldrop"},
+		{SourceLabels: []string{"host"}, Modulus: 4, TargetLabel: "shard", Action: "hashmod"},
+		{SourceLabels: []string{"path"}, TargetLabel: "path", Replacement: stringPtr("")},
+	}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := p.ProcessLogs([]*protocol.Log{
+		newMetricLog("go_goroutines", "instance#$#a:80"),
+		newMetricLog("http_requests_total", "instance#$#a:80|path#$#/api/1"),
+		{Contents: []*protocol.Log_Content{{Key: "content", Value: "not a metric"}}},
+	})
+	require.Len(t, logs, 2)
+	assert.Equal(t, "http_requests_total", logs[0].Contents[0].Value)
+	assert.Regexp(t, `^host#\$#a\|shard#\$#[0-3]$`, logs[0].Contents[1].Value)
+	assert.Equal(t, "not a metric", logs[1].Contents[0].Value)
+}
+
+func TestProcessMetricsKeepAndRename(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Rules = []RelabelRule{
+		{SourceLabels: []string{"__name__"}, Regex: "node_.*", Action: "keep"},
+		{SourceLabels: []string{"__name__"}, Regex: "node_(.*)", TargetLabel: "__name__", Replacement: stringPtr("host_${1}")},
+		{Regex: "label_(.+)", Replacement: stringPtr("${1}"), Action: "labelmap"},
+	}
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	ctx := helper.NewObservePipelineConext(10)
+	p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: []models.PipelineEvent{
+		models.NewSingleValueMetric("node_load1", models.MetricTypeGauge, models.NewTagsWithKeyValues("label_zone", "z1"), 0, 1.5),
+		models.NewSingleValueMetric("process_cpu", models.MetricTypeGauge, models.NewTags(), 0, 1),
+		models.NewLog("", []byte("log"), "", "", "", models.NewTags(), 0),
+	}}, ctx)
+	groups := ctx.Collector().ToArray()
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Events, 2)
+	metric := groups[0].Events[0].(*models.Metric)
+	assert.Equal(t, "host_load1", metric.GetName())
+	assert.Equal(t, "z1", metric.GetTags().Get("zone"))
+	assert.Equal(t, "z1", metric.GetTags().Get("label_zone"))
+	assert.Equal(t, models.EventTypeLogging, groups[0].Events[1].GetType())
+}
+
+func TestSeriesLimit(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.MaxSeriesPerMetric = 2
+	p.OverflowAction = overflowAggregate
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	logs := p.ProcessLogs([]*protocol.Log{
+		newMetricLog("requests", "user#$#1"),
+		newMetricLog("requests", "user#$#2"),
+		newMetricLog("requests", "user#$#3"),
+		newMetricLog("requests", "user#$#1"),
+		newMetricLog("errors", "user#$#3"),
+	})
+	require.Len(t, logs, 5)
+	assert.Equal(t, "user#$#2", logs[1].Contents[1].Value)
+	assert.Equal(t, "__overflow__#$#true", logs[2].Contents[1].Value)
+	assert.Equal(t, "user#$#1", logs[3].Contents[1].Value)
+	assert.Equal(t, "user#$#3", logs[4].Contents[1].Value)
+
+	p, err = newProcessor()
+	require.NoError(t, err)
+	p.MaxSeriesPerMetric = 1
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	require.Len(t, p.ProcessLogs([]*protocol.Log{newMetricLog("requests", "user#$#1"), newMetricLog("requests", "user#$#2")}), 1)
+}
+
+func TestSeriesLimiterExpire(t *testing.T) {
+	l := newSeriesLimiter(1, time.Minute)
+	now := time.Now()
+	assert.True(t, l.admit("m", "a", now))
+	assert.False(t, l.admit("m", "b", now.Add(30*time.Second)))
+	assert.True(t, l.admit("m", "b", now.Add(2*time.Minute)))
+	assert.False(t, l.admit("m", "a", now.Add(2*time.Minute)))
+	assert.True(t, l.shouldAlarm("m", now))
+	assert.False(t, l.shouldAlarm("m", now.Add(time.Second)))
+}
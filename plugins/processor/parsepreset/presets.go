@@ -0,0 +1,300 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsepreset
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	presetNginx  = "nginx"
+	presetEnvoy  = "envoy"
+	presetSyslog = "syslog"
+)
+
+// presetParser appends the fields parsed from the value to fields, false is returned if the value is not in the format.
+type presetParser func(value string, fields []field) ([]field, bool)
+
+var presetParsers = map[string]presetParser{
+	presetNginx:  parseNginx,
+	presetEnvoy:  parseEnvoy,
+	presetSyslog: parseSyslog,
+}
+
+// splitRequest splits the request line into the method, the uri and the protocol.
+func splitRequest(request string) (string, string, string, bool) {
+	first := strings.IndexByte(request, ' ')
+	last := strings.LastIndexByte(request, ' ')
+	if first <= 0 || last <= first+1 || last == len(request)-1 {
+		return "", "", "", false
+	}
+	return request[:first], request[first+1 : last], request[last+1:], true
+}
+
+// parseNginx parses the combined log format of nginx, with the optional quoted $http_x_forwarded_for of the default main format.
+//
+//	$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" ["$http_x_forwarded_for"]
+func parseNginx(value string, fields []field) ([]field, bool) {
+	c := scanner{s: value}
+	remoteAddr, ok := c.word()
+	if !ok || !c.expectString("- ") {
+		return fields, false
+	}
+	remoteUser, ok := c.word()
+	if !ok {
+		return fields, false
+	}
+	timeLocal, ok := c.bracketed('[', ']')
+	if !ok || !c.expect(' ') {
+		return fields, false
+	}
+	request, ok := c.quoted()
+	if !ok || !c.expect(' ') {
+		return fields, false
+	}
+	method, uri, protocol, ok := splitRequest(request)
+	if !ok {
+		return fields, false
+	}
+	status, ok := c.word()
+	if !ok || !isDigits(status) {
+		return fields, false
+	}
+	bodyBytesSent, ok := c.word()
+	if !ok || !isDigits(bodyBytesSent) {
+		return fields, false
+	}
+	referer, ok := c.quoted()
+	if !ok || !c.expect(' ') {
+		return fields, false
+	}
+	userAgent, ok := c.quoted()
+	if !ok {
+		return fields, false
+	}
+	forwardedFor := ""
+	hasForwardedFor := c.expect(' ')
+	if hasForwardedFor {
+		if forwardedFor, ok = c.quoted(); !ok {
+			return fields, false
+		}
+	}
+	if !c.eof() {
+		return fields, false
+	}
+	fields = append(fields,
+		field{"remote_addr", remoteAddr},
+		field{"remote_user", remoteUser},
+		field{"time_local", timeLocal},
+		field{"request_method", method},
+		field{"request_uri", uri},
+		field{"server_protocol", protocol},
+		field{"status", status},
+		field{"body_bytes_sent", bodyBytesSent},
+		field{"http_referer", referer},
+		field{"http_user_agent", userAgent},
+	)
+	if hasForwardedFor {
+		fields = append(fields, field{"http_x_forwarded_for", forwardedFor})
+	}
+	return fields, true
+}
+
+var envoyWordKeys = []string{"response_code", "response_flags", "bytes_received", "bytes_sent", "duration", "upstream_service_time"}
+
+var envoyQuotedKeys = []string{"x_forwarded_for", "user_agent", "request_id", "authority", "upstream_host"}
+
+// parseEnvoy parses the default access log format of envoy.
+//
+//	[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" %RESPONSE_CODE% %RESPONSE_FLAGS%
+//	%BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% "%REQ(X-FORWARDED-FOR)%"
+//	"%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"
+func parseEnvoy(value string, fields []field) ([]field, bool) {
+	c := scanner{s: value}
+	startTime, ok := c.bracketed('[', ']')
+	if !ok || !c.expect(' ') {
+		return fields, false
+	}
+	request, ok := c.quoted()
+	if !ok || !c.expect(' ') {
+		return fields, false
+	}
+	method, path, protocol, ok := splitRequest(request)
+	if !ok {
+		return fields, false
+	}
+	begin := len(fields)
+	fields = append(fields,
+		field{"start_time", startTime},
+		field{"method", method},
+		field{"path", path},
+		field{"protocol", protocol},
+	)
+	for _, key := range envoyWordKeys {
+		token, ok := c.word()
+		if !ok {
+			return fields[:begin], false
+		}
+		fields = append(fields, field{key, token})
+	}
+	if !isDigits(fields[begin+4].value) {
+		return fields[:begin], false
+	}
+	for i, key := range envoyQuotedKeys {
+		if i > 0 && !c.expect(' ') {
+			return fields[:begin], false
+		}
+		token, ok := c.quoted()
+		if !ok {
+			return fields[:begin], false
+		}
+		fields = append(fields, field{key, token})
+	}
+	if !c.eof() {
+		return fields[:begin], false
+	}
+	return fields, true
+}
+
+// parseSyslog parses the RFC5424 and RFC3164 syslog messages, the PRI part is optional for RFC3164,
+// as the messages written into the files by the syslog daemons usually have no PRI.
+func parseSyslog(value string, fields []field) ([]field, bool) {
+	c := scanner{s: value}
+	begin := len(fields)
+	if c.expect('<') {
+		pri, ok := c.until('>')
+		if !ok || len(pri) > 3 || !isDigits(pri) {
+			return fields, false
+		}
+		priority, _ := strconv.Atoi(pri)
+		if priority > 191 {
+			return fields, false
+		}
+		fields = append(fields,
+			field{"priority", pri},
+			field{"facility", strconv.Itoa(priority >> 3)},
+			field{"severity", strconv.Itoa(priority & 7)},
+		)
+		if c.expectString("1 ") {
+			return parseRFC5424(&c, fields, begin)
+		}
+	}
+	return parseRFC3164(&c, fields, begin)
+}
+
+// parseRFC5424 parses the part after the version.
+//
+//	TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(c *scanner, fields []field, begin int) ([]field, bool) {
+	fields = append(fields, field{"version", "1"})
+	for _, key := range []string{"timestamp", "hostname", "program", "pid", "msgid"} {
+		token, ok := c.word()
+		if !ok {
+			return fields[:begin], false
+		}
+		fields = append(fields, field{key, token})
+	}
+	start := c.pos
+	if !c.expect('-') {
+		// one or more SD-ELEMENTs, the quoted param values could contain the escaped ']'
+		for c.expect('[') {
+			inQuote := false
+			for ; !c.eof(); c.pos++ {
+				ch := c.s[c.pos]
+				if ch == '\\' && inQuote {
+					c.pos++
+				} else if ch == '"' {
+					inQuote = !inQuote
+				} else if ch == ']' && !inQuote {
+					break
+				}
+			}
+			if !c.expect(']') {
+				return fields[:begin], false
+			}
+		}
+		if c.pos == start {
+			return fields[:begin], false
+		}
+	}
+	fields = append(fields, field{"structured_data", c.s[start:c.pos]})
+	if c.eof() {
+		return fields, true
+	}
+	if !c.expect(' ') {
+		return fields[:begin], false
+	}
+	return append(fields, field{"message", c.rest()}), true
+}
+
+// parseRFC3164 parses the part after the PRI.
+//
+//	Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+func parseRFC3164(c *scanner, fields []field, begin int) ([]field, bool) {
+	if len(c.s)-c.pos < 16 {
+		return fields[:begin], false
+	}
+	timestamp := c.s[c.pos : c.pos+15]
+	if !isRFC3164Timestamp(timestamp) {
+		return fields[:begin], false
+	}
+	c.pos += 15
+	if !c.expect(' ') {
+		return fields[:begin], false
+	}
+	hostname, ok := c.word()
+	if !ok {
+		return fields[:begin], false
+	}
+	fields = append(fields, field{"timestamp", timestamp}, field{"hostname", hostname})
+	start := c.pos
+	for ; !c.eof(); c.pos++ {
+		ch := c.s[c.pos]
+		if ch == '[' || ch == ':' || ch == ' ' {
+			break
+		}
+	}
+	if c.pos == start {
+		return fields[:begin], false
+	}
+	fields = append(fields, field{"program", c.s[start:c.pos]})
+	if !c.eof() && c.s[c.pos] == '[' {
+		pid, ok := c.bracketed('[', ']')
+		if !ok {
+			return fields[:begin], false
+		}
+		fields = append(fields, field{"pid", pid})
+	}
+	if !c.expect(':') {
+		return fields[:begin], false
+	}
+	c.expect(' ')
+	return append(fields, field{"message", c.rest()}), true
+}
+
+var months = "JanFebMarAprMayJunJulAugSepOctNovDec"
+
+// isRFC3164Timestamp checks the "Mmm dd hh:mm:ss" timestamp, the day is padded by a space.
+func isRFC3164Timestamp(s string) bool {
+	monthIndex := strings.Index(months, s[:3])
+	if monthIndex < 0 || monthIndex%3 != 0 || s[3] != ' ' || s[6] != ' ' || s[9] != ':' || s[12] != ':' {
+		return false
+	}
+	if s[4] != ' ' && (s[4] < '0' || s[4] > '3') {
+		return false
+	}
+	return isDigits(s[5:6]) && isDigits(s[7:9]) && isDigits(s[10:12]) && isDigits(s[13:15])
+}
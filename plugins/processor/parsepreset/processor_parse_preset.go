@@ -0,0 +1,159 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsepreset
+
+import (
+	"fmt"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/pkg/util"
+)
+
+const pluginType = "processor_parse_preset"
+
+// ProcessorParsePreset parses the field specified by SourceKey with the hand-written parser of the Preset format,
+// which walks the value once without the regex, and is several times faster than the equivalent regex config.
+// Supported presets are nginx (combined and main), envoy (default access log) and syslog (RFC3164 and RFC5424).
+type ProcessorParsePreset struct {
+	Preset                 string
+	SourceKey              string
+	KeepSource             bool
+	KeepSourceIfParseError bool
+	NoKeyError             bool
+	NoMatchError           bool
+
+	context       pipeline.Context
+	logPairMetric pipeline.CounterMetric
+	parser        presetParser
+	fields        []field
+}
+
+// Init called for init some system resources, like socket, mutex...
+func (p *ProcessorParsePreset) Init(context pipeline.Context) error {
+	p.context = context
+	if p.SourceKey == "" {
+		return fmt.Errorf("must specify SourceKey for plugin %v", pluginType)
+	}
+	p.parser = presetParsers[p.Preset]
+	if p.parser == nil {
+		return fmt.Errorf("unknown preset %q for plugin %v, supported presets are %v, %v and %v", p.Preset, pluginType, presetNginx, presetEnvoy, presetSyslog)
+	}
+	p.fields = make([]field, 0, 16)
+	metricsRecord := p.context.GetMetricRecord()
+	p.logPairMetric = helper.NewAverageMetricAndRegister(metricsRecord, helper.PluginPairsPerLogTotal)
+	return nil
+}
+
+func (*ProcessorParsePreset) Description() string {
+	return "regex-free parser of the common log formats for logtail"
+}
+
+func (p *ProcessorParsePreset) ProcessLogs(logArray []*protocol.Log) []*protocol.Log {
+	for _, log := range logArray {
+		p.ProcessLog(log)
+	}
+	return logArray
+}
+
+func (p *ProcessorParsePreset) ProcessLog(log *protocol.Log) {
+	beginLen := len(log.Contents)
+	findKey := false
+	for i, cont := range log.Contents {
+		if cont.Key != p.SourceKey {
+			continue
+		}
+		findKey = true
+		parseResult := p.parse(cont.Value)
+		for _, f := range p.fields {
+			log.Contents = append(log.Contents, &protocol.Log_Content{Key: f.key, Value: f.value})
+		}
+		if !p.shouldKeepSource(parseResult) {
+			log.Contents = append(log.Contents[:i], log.Contents[i+1:]...)
+		}
+		break
+	}
+	if !findKey && p.NoKeyError {
+		logger.Warning(p.context.GetRuntimeContext(), "PRESET_FIND_ALARM", "cannot find key", p.SourceKey)
+	}
+	p.logPairMetric.Add(int64(len(log.Contents) - beginLen + 1))
+}
+
+func (p *ProcessorParsePreset) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	for _, event := range in.Events {
+		p.processEvent(event)
+	}
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+func (p *ProcessorParsePreset) processEvent(event models.PipelineEvent) {
+	if event.GetType() != models.EventTypeLogging {
+		return
+	}
+	contents := event.(*models.Log).GetIndices()
+	if !contents.Contains(p.SourceKey) {
+		if p.NoKeyError {
+			logger.Warning(p.context.GetRuntimeContext(), "PRESET_FIND_ALARM", "cannot find key", p.SourceKey)
+		}
+		return
+	}
+	var val string
+	switch v := contents.Get(p.SourceKey).(type) {
+	case string:
+		val = v
+	case []byte:
+		val = util.ZeroCopyBytesToString(v)
+	default:
+		logger.Warning(p.context.GetRuntimeContext(), "PRESET_FIND_ALARM", "key is not string", p.SourceKey)
+		return
+	}
+	parseResult := p.parse(val)
+	sourceKeyOverwritten := false
+	for _, f := range p.fields {
+		contents.Add(f.key, f.value)
+		sourceKeyOverwritten = sourceKeyOverwritten || f.key == p.SourceKey
+	}
+	if !p.shouldKeepSource(parseResult) && !sourceKeyOverwritten {
+		contents.Delete(p.SourceKey)
+	}
+}
+
+// parse fills p.fields with the fields parsed from the value, p.fields is left empty if the value is not in the preset format.
+func (p *ProcessorParsePreset) parse(val string) bool {
+	var ok bool
+	p.fields, ok = p.parser(val, p.fields[:0])
+	if !ok && p.NoMatchError {
+		logger.Warning(p.context.GetRuntimeContext(), "PRESET_UNMATCHED_ALARM", "preset", p.Preset, "unmatch this log content", util.CutString(val, 512))
+	}
+	return ok
+}
+
+func (p *ProcessorParsePreset) shouldKeepSource(parseResult bool) bool {
+	return p.KeepSource || (p.KeepSourceIfParseError && !parseResult)
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorParsePreset{
+			SourceKey:              "content",
+			NoKeyError:             false,
+			NoMatchError:           true,
+			KeepSourceIfParseError: true,
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsepreset
+
+import (
+	"regexp"
+	"testing"
+)
+
+func benchmarkPreset(b *testing.B, preset, log string) {
+	parser := presetParsers[preset]
+	fields := make([]field, 0, 16)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fields, _ = parser(log, fields[:0])
+	}
+}
+
+func benchmarkRegex(b *testing.B, re *regexp.Regexp, log string) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		re.FindStringSubmatchIndex(log)
+	}
+}
+
+// goos: linux
+// goarch: amd64
+// pkg: github.com/alibaba/ilogtail/plugins/processor/parsepreset
+// cpu: Intel(R) Xeon(R) Processor
+// BenchmarkNginx/preset         	 9057285	       133.5 ns/op	       0 B/op	       0 allocs/op
+// BenchmarkNginx/regex          	  220941	      7532 ns/op	     192 B/op	       1 allocs/op
+// BenchmarkEnvoy/preset         	 5703056	       247.4 ns/op	       0 B/op	       0 allocs/op
+// BenchmarkEnvoy/regex          	  128836	      9073 ns/op	     256 B/op	       1 allocs/op
+// BenchmarkSyslog/preset        	17894431	        66.16 ns/op	       0 B/op	       0 allocs/op
+// BenchmarkSyslog/regex         	 1000000	      1054 ns/op	     112 B/op	       1 allocs/op
+func BenchmarkNginx(b *testing.B) {
+	b.Run("preset", func(b *testing.B) { benchmarkPreset(b, presetNginx, nginxMainLog) })
+	b.Run("regex", func(b *testing.B) { benchmarkRegex(b, nginxRegex, nginxMainLog) })
+}
+
+func BenchmarkEnvoy(b *testing.B) {
+	b.Run("preset", func(b *testing.B) { benchmarkPreset(b, presetEnvoy, envoyLog) })
+	b.Run("regex", func(b *testing.B) { benchmarkRegex(b, envoyRegex, envoyLog) })
+}
+
+func BenchmarkSyslog(b *testing.B) {
+	b.Run("preset", func(b *testing.B) { benchmarkPreset(b, presetSyslog, syslogLog) })
+	b.Run("regex", func(b *testing.B) { benchmarkRegex(b, syslogRegex, syslogLog) })
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsepreset
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+const (
+	nginxLog     = `192.168.1.1 - - [10/Oct/2023:13:55:36 +0800] "GET /api/v1/users?id=1 HTTP/1.1" 200 612 "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36"`
+	nginxMainLog = `10.0.0.8 - admin [10/Oct/2023:13:55:37 +0800] "POST /login HTTP/2.0" 302 0 "-" "curl/8.0 \"beta\"" "203.0.113.5, 10.0.0.1"`
+	envoyLog     = `[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" 204 - 154 0 226 100 "10.0.35.28" "nsq2http" "cc21d9b0-cf5c-432b-8c7e-98aeb7988cd2" "locations" "tcp://10.0.2.1:80"`
+	envoyTCPLog  = `[2016-04-15T20:17:00.310Z] "- - -" 0 - 1024 2048 15 - "-" "-" "-" "-" "10.0.2.1:3306"`
+	syslogLog    = `<34>Oct  1 22:14:15 mymachine su[1234]: 'su root' failed for lonvick on /dev/pts/8`
+	syslogRFC    = `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="App\]"][x@1 a="b"] An application event log entry`
+)
+
+// the regexes equivalent to the presets, used by the tests and the benchmarks.
+var (
+	nginxRegex = regexp.MustCompile(`^(\S+) - (\S+) \[([^\]]*)\] "(\S+) (.+) (\S+)" (\d+) (\d+) "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)"(?: "((?:[^"\\]|\\.)*)")?$`)
+	nginxKeys  = []string{"remote_addr", "remote_user", "time_local", "request_method", "request_uri", "server_protocol", "status",
+		"body_bytes_sent", "http_referer", "http_user_agent", "http_x_forwarded_for"}
+	envoyRegex = regexp.MustCompile(`^\[([^\]]*)\] "(\S+) (.+) (\S+)" (\d+) (\S+) (\S+) (\S+) (\S+) (\S+) "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)"$`)
+	envoyKeys  = []string{"start_time", "method", "path", "protocol", "response_code", "response_flags", "bytes_received", "bytes_sent",
+		"duration", "upstream_service_time", "x_forwarded_for", "user_agent", "request_id", "authority", "upstream_host"}
+	syslogRegex = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\[: ]+)(?:\[([^\]]*)\])?: ?(.*)$`)
+	syslogKeys  = []string{"priority", "timestamp", "hostname", "program", "pid", "message"}
+)
+
+func newProcessor(preset string) (*ProcessorParsePreset, error) {
+	processor := &ProcessorParsePreset{
+		Preset:                 preset,
+		SourceKey:              "content",
+		NoMatchError:           true,
+		KeepSourceIfParseError: true,
+	}
+	return processor, processor.Init(mock.NewEmptyContext("p", "l", "c"))
+}
+
+func parseToMap(t *testing.T, preset, value string) (map[string]string, bool) {
+	fields, ok := presetParsers[preset](value, nil)
+	res := make(map[string]string, len(fields))
+	for _, f := range fields {
+		_, exists := res[f.key]
+		require.False(t, exists, f.key)
+		res[f.key] = f.value
+	}
+	return res, ok
+}
+
+func TestInitError(t *testing.T) {
+	_, err := newProcessor("apache")
+	require.Error(t, err)
+	processor := &ProcessorParsePreset{Preset: presetNginx}
+	require.Error(t, processor.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestNginx(t *testing.T) {
+	res, ok := parseToMap(t, presetNginx, nginxLog)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"remote_addr":     "192.168.1.1",
+		"remote_user":     "-",
+		"time_local":      "10/Oct/2023:13:55:36 +0800",
+		"request_method":  "GET",
+		"request_uri":     "/api/v1/users?id=1",
+		"server_protocol": "HTTP/1.1",
+		"status":          "200",
+		"body_bytes_sent": "612",
+		"http_referer":    "https://example.com/",
+		"http_user_agent": "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36",
+	}, res)
+
+	res, ok = parseToMap(t, presetNginx, nginxMainLog)
+	require.True(t, ok)
+	assert.Equal(t, "admin", res["remote_user"])
+	assert.Equal(t, `curl/8.0 \"beta\"`, res["http_user_agent"])
+	assert.Equal(t, "203.0.113.5, 10.0.0.1", res["http_x_forwarded_for"])
+
+	for _, invalid := range []string{
+		"",
+		`192.168.1.1 - - [10/Oct/2023:13:55:36 +0800] "-" 400 0 "-" "-"`,
+		`192.168.1.1 - - [10/Oct/2023:13:55:36 +0800] "GET / HTTP/1.1" 200 612 "-"`,
+		`192.168.1.1 - - [10/Oct/2023:13:55:36 +0800] "GET / HTTP/1.1" ok 612 "-" "-"`,
+		nginxLog + " trailing",
+	} {
+		res, ok = parseToMap(t, presetNginx, invalid)
+		assert.False(t, ok, invalid)
+		assert.Empty(t, res, invalid)
+	}
+}
+
+func TestEnvoy(t *testing.T) {
+	res, ok := parseToMap(t, presetEnvoy, envoyLog)
+	require.True(t, ok)
+	assert.Len(t, res, len(envoyKeys))
+	assert.Equal(t, "2016-04-15T20:17:00.310Z", res["start_time"])
+	assert.Equal(t, "/api/v1/locations", res["path"])
+	assert.Equal(t, "204", res["response_code"])
+	assert.Equal(t, "-", res["response_flags"])
+	assert.Equal(t, "100", res["upstream_service_time"])
+	assert.Equal(t, "tcp://10.0.2.1:80", res["upstream_host"])
+
+	res, ok = parseToMap(t, presetEnvoy, envoyTCPLog)
+	require.True(t, ok)
+	assert.Equal(t, "-", res["method"])
+	assert.Equal(t, "0", res["response_code"])
+
+	for _, invalid := range []string{
+		envoyLog[:len(envoyLog)-10],
+		`[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/2" - - 154 0 226 100 "-" "-" "-" "-" "-"`,
+	} {
+		res, ok = parseToMap(t, presetEnvoy, invalid)
+		assert.False(t, ok, invalid)
+		assert.Empty(t, res, invalid)
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	res, ok := parseToMap(t, presetSyslog, syslogLog)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"priority":  "34",
+		"facility":  "4",
+		"severity":  "2",
+		"timestamp": "Oct  1 22:14:15",
+		"hostname":  "mymachine",
+		"program":   "su",
+		"pid":       "1234",
+		"message":   "'su root' failed for lonvick on /dev/pts/8",
+	}, res)
+
+	res, ok = parseToMap(t, presetSyslog, "Oct 11 22:14:15 host kernel: [ 0.000000] Linux version")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"timestamp": "Oct 11 22:14:15",
+		"hostname":  "host",
+		"program":   "kernel",
+		"message":   "[ 0.000000] Linux version",
+	}, res)
+
+	res, ok = parseToMap(t, presetSyslog, syslogRFC)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"priority":        "165",
+		"facility":        "20",
+		"severity":        "5",
+		"version":         "1",
+		"timestamp":       "2003-10-11T22:14:15.003Z",
+		"hostname":        "mymachine.example.com",
+		"program":         "evntslog",
+		"pid":             "-",
+		"msgid":           "ID47",
+		"structured_data": `[exampleSDID@32473 iut="3" eventSource="App\]"][x@1 a="b"]`,
+		"message":         "An application event log entry",
+	}, res)
+
+	res, ok = parseToMap(t, presetSyslog, "<13>1 2003-10-11T22:14:15.003Z host app 12 - -")
+	require.True(t, ok)
+	assert.Equal(t, "-", res["structured_data"])
+	assert.NotContains(t, res, "message")
+
+	for _, invalid := range []string{
+		"",
+		"<192>Oct 11 22:14:15 host su: message",
+		"<34Oct 11 22:14:15 host su: message",
+		"Foo 11 22:14:15 host su: message",
+		"Oct 11 22:14:15 host su message",
+		"<13>1 2003-10-11T22:14:15.003Z host app 12 - [unclosed",
+	} {
+		res, ok = parseToMap(t, presetSyslog, invalid)
+		assert.False(t, ok, invalid)
+		assert.Empty(t, res, invalid)
+	}
+}
+
+// TestRegexEquivalence checks the presets extract the same values as the equivalent regex configs.
+func TestRegexEquivalence(t *testing.T) {
+	cases := []struct {
+		preset string
+		re     *regexp.Regexp
+		keys   []string
+		logs   []string
+	}{
+		{presetNginx, nginxRegex, nginxKeys, []string{nginxLog, nginxMainLog}},
+		{presetEnvoy, envoyRegex, envoyKeys, []string{envoyLog, envoyTCPLog}},
+		{presetSyslog, syslogRegex, syslogKeys, []string{syslogLog, "Oct 11 22:14:15 host kernel: [ 0.000000] Linux version"}},
+	}
+	for _, c := range cases {
+		for _, log := range c.logs {
+			res, ok := parseToMap(t, c.preset, log)
+			require.True(t, ok, log)
+			indexes := c.re.FindStringSubmatchIndex(log)
+			require.NotNil(t, indexes, log)
+			for i, key := range c.keys {
+				if indexes[i*2+2] < 0 {
+					assert.NotContains(t, res, key, log)
+					continue
+				}
+				assert.Equal(t, log[indexes[i*2+2]:indexes[i*2+3]], res[key], key)
+			}
+		}
+	}
+}
+
+func TestProcessLog(t *testing.T) {
+	processor, err := newProcessor(presetNginx)
+	require.NoError(t, err)
+	log := &protocol.Log{Time: 0}
+	log.Contents = append(log.Contents, &protocol.Log_Content{Key: "content", Value: nginxLog})
+	processor.ProcessLogs([]*protocol.Log{log})
+	require.Len(t, log.Contents, 10)
+	assert.Equal(t, "remote_addr", log.Contents[0].Key)
+	assert.Equal(t, "192.168.1.1", log.Contents[0].Value)
+
+	processor.KeepSource = true
+	log.Contents = []*protocol.Log_Content{{Key: "content", Value: nginxLog}}
+	processor.ProcessLog(log)
+	require.Len(t, log.Contents, 11)
+	assert.Equal(t, "content", log.Contents[0].Key)
+
+	processor.KeepSource = false
+	log.Contents = []*protocol.Log_Content{{Key: "content", Value: "not nginx"}}
+	processor.ProcessLog(log)
+	require.Len(t, log.Contents, 1)
+	assert.Equal(t, "not nginx", log.Contents[0].Value)
+
+	processor.KeepSourceIfParseError = false
+	processor.ProcessLog(log)
+	assert.Empty(t, log.Contents)
+}
+
+func TestProcessEvent(t *testing.T) {
+	processor, err := newProcessor(presetSyslog)
+	require.NoError(t, err)
+	log := models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents := log.GetIndices()
+	contents.Add("content", []byte(syslogLog))
+	processor.processEvent(log)
+	assert.False(t, contents.Contains("content"))
+	assert.Equal(t, "su", contents.Get("program"))
+	assert.Equal(t, "1234", contents.Get("pid"))
+
+	log = models.NewLog("", nil, "", "", "", models.NewTags(), 0)
+	contents = log.GetIndices()
+	contents.Add("content", "invalid")
+	processor.processEvent(log)
+	assert.Equal(t, "invalid", contents.Get("content"))
+	assert.Equal(t, 1, contents.Len())
+}
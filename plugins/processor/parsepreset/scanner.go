@@ -0,0 +1,131 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parsepreset
+
+// field is a parsed key value pair, the value shares the memory of the source.
+type field struct {
+	key   string
+	value string
+}
+
+// scanner walks the source once from left to right. Each preset is a fixed sequence of the scanner states,
+// a token until a delimiter, a bracketed or a quoted token, so that no backtracking happens as in the regex.
+type scanner struct {
+	s   string
+	pos int
+}
+
+func (c *scanner) eof() bool {
+	return c.pos >= len(c.s)
+}
+
+// expect consumes the byte b.
+func (c *scanner) expect(b byte) bool {
+	if c.pos < len(c.s) && c.s[c.pos] == b {
+		c.pos++
+		return true
+	}
+	return false
+}
+
+// expectString consumes the literal str.
+func (c *scanner) expectString(str string) bool {
+	if len(c.s)-c.pos >= len(str) && c.s[c.pos:c.pos+len(str)] == str {
+		c.pos += len(str)
+		return true
+	}
+	return false
+}
+
+// until returns the non-empty token before the delimiter d and consumes the delimiter.
+func (c *scanner) until(d byte) (string, bool) {
+	for i := c.pos; i < len(c.s); i++ {
+		if c.s[i] == d {
+			if i == c.pos {
+				return "", false
+			}
+			token := c.s[c.pos:i]
+			c.pos = i + 1
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// word returns the non-empty token before the next space or the end, the space is consumed.
+func (c *scanner) word() (string, bool) {
+	start := c.pos
+	for c.pos < len(c.s) && c.s[c.pos] != ' ' {
+		c.pos++
+	}
+	if c.pos == start {
+		return "", false
+	}
+	token := c.s[start:c.pos]
+	c.expect(' ')
+	return token, true
+}
+
+// bracketed returns the token enclosed by the open and close bytes.
+func (c *scanner) bracketed(open, close byte) (string, bool) {
+	if !c.expect(open) {
+		return "", false
+	}
+	for i := c.pos; i < len(c.s); i++ {
+		if c.s[i] == close {
+			token := c.s[c.pos:i]
+			c.pos = i + 1
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// quoted returns the token enclosed by the double quotes, the escaped quotes are kept as is.
+func (c *scanner) quoted() (string, bool) {
+	if !c.expect('"') {
+		return "", false
+	}
+	for i := c.pos; i < len(c.s); i++ {
+		switch c.s[i] {
+		case '\\':
+			i++
+		case '"':
+			token := c.s[c.pos:i]
+			c.pos = i + 1
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// rest returns the remaining source.
+func (c *scanner) rest() string {
+	token := c.s[c.pos:]
+	c.pos = len(c.s)
+	return token
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
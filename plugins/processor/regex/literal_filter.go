@@ -0,0 +1,86 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regex
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// literalFilter rejects the values which cannot match the regex by the literals the regex begins or ends with,
+// which is much cheaper than running the regex on them.
+type literalFilter struct {
+	prefix         string
+	prefixAnchored bool // the value must start with the prefix, otherwise contain it
+	suffix         string
+	suffixAnchored bool // the value must end with the suffix, otherwise contain it
+}
+
+// newLiteralFilter returns nil if the regex neither begins nor ends with a case-sensitive literal.
+func newLiteralFilter(expr string, fullMatch bool) (*literalFilter, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	re = re.Simplify()
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	f := &literalFilter{prefixAnchored: fullMatch, suffixAnchored: fullMatch}
+	begin, end := 0, len(subs)-1
+	for begin <= end && subs[begin].Op == syntax.OpBeginText {
+		f.prefixAnchored = true
+		begin++
+	}
+	for end >= begin && subs[end].Op == syntax.OpEndText {
+		f.suffixAnchored = true
+		end--
+	}
+	if begin <= end && isLiteral(subs[begin]) {
+		f.prefix = string(subs[begin].Rune)
+		begin++
+	}
+	if begin <= end && isLiteral(subs[end]) {
+		f.suffix = string(subs[end].Rune)
+	}
+	if f.prefix == "" && f.suffix == "" {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func isLiteral(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0
+}
+
+func (f *literalFilter) candidate(val string) bool {
+	if f.prefix != "" {
+		if f.prefixAnchored {
+			if !strings.HasPrefix(val, f.prefix) {
+				return false
+			}
+		} else if !strings.Contains(val, f.prefix) {
+			return false
+		}
+	}
+	if f.suffix != "" {
+		if f.suffixAnchored {
+			return strings.HasSuffix(val, f.suffix)
+		}
+		return strings.Contains(val, f.suffix)
+	}
+	return true
+}
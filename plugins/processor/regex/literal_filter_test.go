@@ -0,0 +1,73 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiteralFilter(t *testing.T) {
+	cases := []struct {
+		regex     string
+		fullMatch bool
+		filter    *literalFilter
+		matched   []string
+		unmatched []string
+	}{
+		{regex: `(\w+) (\d+)`},
+		{regex: `(?i)^abc\d+`},
+		{regex: `abc|def`},
+		{
+			regex:     `^GET (\S+)`,
+			filter:    &literalFilter{prefix: "GET ", prefixAnchored: true},
+			matched:   []string{"GET /"},
+			unmatched: []string{"POST /", " GET /"},
+		},
+		{
+			regex:     `(\S+) HTTP/1\.1`,
+			filter:    &literalFilter{suffix: " HTTP/1.1"},
+			matched:   []string{"GET / HTTP/1.1 200"},
+			unmatched: []string{"GET / HTTP/2"},
+		},
+		{
+			regex:     `\[(\w+)\] (.*) done`,
+			fullMatch: true,
+			filter:    &literalFilter{prefix: "[", prefixAnchored: true, suffix: " done", suffixAnchored: true},
+			matched:   []string{"[INFO] job done"},
+			unmatched: []string{" [INFO] job done", "[INFO] done job"},
+		},
+		{
+			regex:     `(?s)^abc$`,
+			filter:    &literalFilter{prefix: "abc", prefixAnchored: true, suffixAnchored: true},
+			matched:   []string{"abc"},
+			unmatched: []string{"ab"},
+		},
+	}
+	for _, tc := range cases {
+		filter, err := newLiteralFilter(tc.regex, tc.fullMatch)
+		require.NoError(t, err, tc.regex)
+		require.Equal(t, tc.filter, filter, tc.regex)
+		for _, val := range tc.matched {
+			require.True(t, filter.candidate(val), val)
+		}
+		for _, val := range tc.unmatched {
+			require.False(t, filter.candidate(val), val)
+		}
+	}
+	_, err := newLiteralFilter("(", false)
+	require.Error(t, err)
+}
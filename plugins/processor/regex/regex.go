@@ -16,6 +16,7 @@ package regex
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 
 	"github.com/alibaba/ilogtail/pkg/helper"
@@ -38,10 +39,15 @@ type ProcessorRegex struct {
 	KeepSource             bool
 	KeepSourceIfParseError bool
 	SourceKey              string
+	// PerformanceMode skips the values not containing the literals the regex begins or ends with before running the regex,
+	// and doesn't extract the groups whose keys are empty. When no group is extracted, only whether the value matches is tested.
+	PerformanceMode bool
 
 	context       pipeline.Context
 	logPairMetric pipeline.CounterMetric
 	re            *regexp.Regexp
+	filter        *literalFilter
+	matchOnly     bool
 }
 
 var errNoRegexKey = errors.New("no regex key error")
@@ -54,11 +60,31 @@ func (p *ProcessorRegex) Init(context pipeline.Context) error {
 	}
 	var err error
 	// `(?s)` change the meaning of `.` in Golang to match the every character, and the default meaning is not match a newline.
-	p.re, err = regexp.Compile("(?s)" + p.Regex)
+	expr := "(?s)" + p.Regex
+	if p.PerformanceMode && p.FullMatch {
+		// anchoring lets the regex give up early on the unmatched values.
+		expr = "(?s)^(?:" + p.Regex + ")$"
+	}
+	p.re, err = regexp.Compile(expr)
 	if err != nil {
 		logger.Error(p.context.GetRuntimeContext(), "PROCESSOR_INIT_ALARM", "init regex error", err, "regex", p.Regex)
 		return err
 	}
+	if p.PerformanceMode {
+		if p.re.NumSubexp() < len(p.Keys) {
+			return fmt.Errorf("the regex has %d groups, less than %d keys", p.re.NumSubexp(), len(p.Keys))
+		}
+		if p.filter, err = newLiteralFilter(expr, p.FullMatch); err != nil {
+			return err
+		}
+		p.matchOnly = true
+		for _, key := range p.Keys {
+			if key != "" {
+				p.matchOnly = false
+				break
+			}
+		}
+	}
 
 	metricsRecord := p.context.GetMetricRecord()
 	p.logPairMetric = helper.NewAverageMetricAndRegister(metricsRecord, helper.PluginPairsPerLogTotal)
@@ -103,11 +129,20 @@ func (p *ProcessorRegex) shouldKeepSource(parseResult bool) bool {
 }
 
 func (p *ProcessorRegex) processRegex(log *protocol.Log, val *string) bool {
+	if p.filter != nil && !p.filter.candidate(*val) {
+		p.alarmUnmatched(*val)
+		return false
+	}
+	if p.matchOnly {
+		if !p.re.MatchString(*val) {
+			p.alarmUnmatched(*val)
+			return false
+		}
+		return true
+	}
 	indexArray := p.re.FindStringSubmatchIndex(*val)
 	if len(indexArray) < 2 || (p.FullMatch && (indexArray[0] != 0 || indexArray[1] != len(*val))) {
-		if p.NoMatchError {
-			logger.Warning(p.context.GetRuntimeContext(), "REGEX_UNMATCHED_ALARM", "unmatch this log content", util.CutString(*val, 512))
-		}
+		p.alarmUnmatched(*val)
 		return false
 	}
 
@@ -119,6 +154,9 @@ func (p *ProcessorRegex) processRegex(log *protocol.Log, val *string) bool {
 		return false
 	}
 	for i := 0; i < len(p.Keys); i++ {
+		if p.PerformanceMode && p.Keys[i] == "" {
+			continue
+		}
 		leftIndex := indexArray[i<<1+2]
 		rightIndex := indexArray[i<<1+3]
 		if leftIndex >= 0 && rightIndex >= leftIndex {
@@ -128,6 +166,12 @@ func (p *ProcessorRegex) processRegex(log *protocol.Log, val *string) bool {
 	return true
 }
 
+func (p *ProcessorRegex) alarmUnmatched(val string) {
+	if p.NoMatchError {
+		logger.Warning(p.context.GetRuntimeContext(), "REGEX_UNMATCHED_ALARM", "unmatch this log content", util.CutString(val, 512))
+	}
+}
+
 func init() {
 	pipeline.Processors["processor_regex"] = func() pipeline.Processor {
 		return &ProcessorRegex{
@@ -18,6 +18,9 @@ import (
 	"regexp"
 	"strconv"
 	"testing"
+
+	"github.com/alibaba/ilogtail/pkg/protocol"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
 )
 
 type MockParam struct {
@@ -81,3 +84,30 @@ func mockDataSingleLine(num int, separator, separatorReg string) (string, string
 	pattern, log := mockData(num, separator, separatorReg)
 	return "(?s)" + pattern, log
 }
+
+func BenchmarkPerformanceMode(b *testing.B) {
+	values := []string{
+		`127.0.0.1 - - [27/Aug/2021:13:04:14 +0800] "GET /index.html HTTP/1.1" 200 612`,
+		`2021-08-27 13:04:14.920 77711773 [ThreadName] INFO  content detail`,
+	}
+	for _, performanceMode := range []bool{false, true} {
+		b.Run("performance_mode_"+strconv.FormatBool(performanceMode), func(b *testing.B) {
+			processor := &ProcessorRegex{
+				Regex:           `(\S+) - - \[([^\]]+)\] "(\w+) (\S+) ([^"]+)" (\d+) (\d+)`,
+				Keys:            []string{"ip", "", "method", "", "", "status"},
+				FullMatch:       true,
+				KeepSource:      true,
+				PerformanceMode: performanceMode,
+			}
+			if err := processor.Init(mock.NewEmptyContext("p", "l", "c")); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				log := &protocol.Log{Contents: []*protocol.Log_Content{{Key: "content", Value: values[i%len(values)]}}}
+				processor.ProcessLog(log)
+			}
+		})
+	}
+}
@@ -307,3 +307,53 @@ func (s *processorTestSuite) TestKeepSourceIfParseError(c *check.C) {
 		c.Assert(outLogs[0].Contents[0].GetValue(), check.Equals, sourceValue)
 	}
 }
+
+func (s *processorTestSuite) TestPerformanceMode(c *check.C) {
+	const accessLog = `127.0.0.1 - - [27/Aug/2021:13:04:14 +0800] "GET /index.html HTTP/1.1" 200 612`
+	processor, _ := s.processor.(*ProcessorRegex)
+	processor.Regex = `(\S+) - - \[([^\]]+)\] "(\w+) (\S+) ([^"]+)" (\d+) (\d+)`
+	processor.Keys = []string{"ip", "", "method", "", "", "status"}
+	processor.FullMatch = true
+	processor.PerformanceMode = true
+	processor.KeepSource = false
+	require.NoError(c, s.processor.Init(mock.NewEmptyContext("p", "l", "c")))
+
+	outLogs := s.processor.ProcessLogs([]*protocol.Log{test.CreateLogs("content", accessLog)})
+	c.Assert(len(outLogs[0].Contents), check.Equals, 3)
+	c.Assert(outLogs[0].Contents[0].GetKey(), check.Equals, "ip")
+	c.Assert(outLogs[0].Contents[0].GetValue(), check.Equals, "127.0.0.1")
+	c.Assert(outLogs[0].Contents[1].GetKey(), check.Equals, "method")
+	c.Assert(outLogs[0].Contents[1].GetValue(), check.Equals, "GET")
+	c.Assert(outLogs[0].Contents[2].GetKey(), check.Equals, "status")
+	c.Assert(outLogs[0].Contents[2].GetValue(), check.Equals, "200")
+
+	// the full match is required
+	outLogs = s.processor.ProcessLogs([]*protocol.Log{test.CreateLogs("content", accessLog+" extra")})
+	c.Assert(len(outLogs[0].Contents), check.Equals, 1)
+	c.Assert(outLogs[0].Contents[0].GetKey(), check.Equals, "content")
+
+	// more keys than groups
+	processor.Keys = []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	c.Assert(s.processor.Init(mock.NewEmptyContext("p", "l", "c")), check.NotNil)
+}
+
+func (s *processorTestSuite) TestPerformanceModeMatchOnly(c *check.C) {
+	processor, _ := s.processor.(*ProcessorRegex)
+	processor.Regex = `^\[(\w+)\] .*done$`
+	processor.Keys = []string{""}
+	processor.PerformanceMode = true
+	processor.KeepSource = false
+	processor.KeepSourceIfParseError = true
+	require.NoError(c, s.processor.Init(mock.NewEmptyContext("p", "l", "c")))
+	c.Assert(processor.matchOnly, check.IsTrue)
+	c.Assert(processor.filter, check.NotNil)
+
+	outLogs := s.processor.ProcessLogs([]*protocol.Log{
+		test.CreateLogs("content", "[INFO] job done"),
+		test.CreateLogs("content", "[INFO] job failed"),
+		test.CreateLogs("content", "INFO job done"),
+	})
+	c.Assert(len(outLogs[0].Contents), check.Equals, 0)
+	c.Assert(len(outLogs[1].Contents), check.Equals, 1)
+	c.Assert(len(outLogs[2].Contents), check.Equals, 1)
+}
@@ -0,0 +1,312 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package temporalityconvert
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol/otlp"
+)
+
+const pluginType = "processor_temporality_convert"
+
+var (
+	temporalityDelta      = pmetric.AggregationTemporalityDelta.String()
+	temporalityCumulative = pmetric.AggregationTemporalityCumulative.String()
+)
+
+const (
+	targetCumulative = "cumulative"
+	targetDelta      = "delta"
+)
+
+// ProcessorTemporalityConvert converts the aggregation temporality of the sums and histograms, between delta and
+// cumulative. The temporality is read from the otlp.metric.aggregation.temporality tag, or from the metric type
+// for the sums without the tag, Counter is cumulative and RateCounter is delta.
+//
+// To cumulative, the deltas of each series are accumulated since the first one. To delta, the difference between
+// the adjacent cumulative values is emitted, and the first value of a series is dropped. A decreasing monotonic
+// value or a changed start timestamp is a restart of the source, after which the cumulative value itself is the delta.
+type ProcessorTemporalityConvert struct {
+	MetricNames  []string // The regexps of the metric names to convert, all the sums and histograms are converted if empty.
+	Target       string   // The target temporality, cumulative or delta.
+	StalenessSec int      // The series whose previous point is older than this are restarted, 0 means never.
+	MaxSeries    int      // The max number of series, the least recently seen series is evicted when exceeded.
+
+	names  []*regexp.Regexp
+	series *lru.Cache[string, *seriesState]
+}
+
+// seriesState is the previous point of a series. The value is the accumulated delta to cumulative, or the
+// previous cumulative value to delta. The timestamps are in nanoseconds.
+type seriesState struct {
+	start     uint64
+	timestamp uint64
+	value     float64
+	values    models.MetricFloatValues
+}
+
+// Init ...
+func (p *ProcessorTemporalityConvert) Init(context pipeline.Context) error {
+	switch p.Target {
+	case targetCumulative, targetDelta:
+	default:
+		return fmt.Errorf("unknown Target %v for plugin %v", p.Target, pluginType)
+	}
+	for _, name := range p.MetricNames {
+		reg, err := regexp.Compile("^(?:" + name + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid MetricNames %v for plugin %v: %v", name, pluginType, err)
+		}
+		p.names = append(p.names, reg)
+	}
+	var err error
+	p.series, err = lru.New[string, *seriesState](p.MaxSeries)
+	return err
+}
+
+// Description ...
+func (*ProcessorTemporalityConvert) Description() string {
+	return "temporality convert processor to convert the sums and histograms between delta and cumulative"
+}
+
+func (p *ProcessorTemporalityConvert) matchName(name string) bool {
+	if len(p.names) == 0 {
+		return true
+	}
+	for _, reg := range p.names {
+		if reg.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Process ...
+func (p *ProcessorTemporalityConvert) Process(in *models.PipelineGroupEvents, context pipeline.PipelineContext) {
+	kept := in.Events[:0]
+	for _, event := range in.Events {
+		if metric, ok := event.(*models.Metric); ok && !p.processMetric(metric) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	for i := len(kept); i < len(in.Events); i++ {
+		in.Events[i] = nil
+	}
+	in.Events = kept
+	context.Collector().Collect(in.Group, in.Events...)
+}
+
+// temporality returns the temporality of the metric, or empty if it is neither a sum nor a histogram.
+func temporality(metric *models.Metric) string {
+	if temporality := tag(metric, otlp.TagKeyMetricAggregationTemporality); temporality == temporalityDelta || temporality == temporalityCumulative {
+		switch metric.GetMetricType() {
+		case models.MetricTypeCounter, models.MetricTypeRateCounter, models.MetricTypeHistogram:
+			return temporality
+		}
+		return ""
+	}
+	switch metric.GetMetricType() {
+	case models.MetricTypeCounter:
+		return temporalityCumulative
+	case models.MetricTypeRateCounter:
+		return temporalityDelta
+	}
+	return ""
+}
+
+// processMetric returns false if the metric should be dropped.
+func (p *ProcessorTemporalityConvert) processMetric(metric *models.Metric) bool {
+	from := temporality(metric)
+	if from == "" || (p.Target == targetCumulative) == (from == temporalityCumulative) || !p.matchName(metric.GetName()) {
+		return true
+	}
+	value := metric.GetValue()
+	histogram := metric.GetMetricType() == models.MetricTypeHistogram
+	if histogram != value.IsMultiValues() {
+		return true
+	}
+	if !histogram && (math.IsNaN(value.GetSingleValue()) || math.IsInf(value.GetSingleValue(), 0)) {
+		return true
+	}
+	var converted bool
+	if p.Target == targetCumulative {
+		converted = p.toCumulative(seriesKey(metric), metric)
+	} else {
+		converted = p.toDelta(seriesKey(metric), metric)
+	}
+	if !converted {
+		return false
+	}
+	if metric.Tags == nil {
+		metric.Tags = models.NewTags()
+	}
+	if p.Target == targetCumulative {
+		metric.Tags.Add(otlp.TagKeyMetricAggregationTemporality, temporalityCumulative)
+		if metric.MetricType == models.MetricTypeRateCounter {
+			metric.MetricType = models.MetricTypeCounter
+		}
+	} else {
+		metric.Tags.Add(otlp.TagKeyMetricAggregationTemporality, temporalityDelta)
+		if metric.MetricType == models.MetricTypeCounter {
+			metric.MetricType = models.MetricTypeRateCounter
+		}
+	}
+	return true
+}
+
+// toCumulative accumulates the delta into the series, and sets the start timestamp to the start of the series.
+// The points not newer than the previous one are dropped.
+func (p *ProcessorTemporalityConvert) toCumulative(key string, metric *models.Metric) bool {
+	timestamp := metric.GetTimestamp()
+	state, exists := p.series.Get(key)
+	if exists && timestamp <= state.timestamp {
+		return false
+	}
+	if exists && !p.stale(state, timestamp) {
+		state.timestamp = timestamp
+		if metric.GetMetricType() == models.MetricTypeHistogram {
+			values, ok := otlp.AddHistograms(state.values, metric.GetValue().GetMultiValues(), isExponential(metric))
+			if ok {
+				state.values = values
+				metric.Value = &models.MetricMultiValue{Values: values}
+				metric.SetObservedTimestamp(state.start)
+				return true
+			}
+			// the buckets are changed, restart the series
+		} else {
+			state.value += metric.GetValue().GetSingleValue()
+			metric.Value = &models.MetricSingleValue{Value: state.value}
+			metric.SetObservedTimestamp(state.start)
+			return true
+		}
+	}
+	start := metric.GetObservedTimestamp()
+	if start == 0 || start > timestamp {
+		start = timestamp
+	}
+	state = &seriesState{start: start, timestamp: timestamp}
+	if metric.GetMetricType() == models.MetricTypeHistogram {
+		state.values = copyValues(metric.GetValue().GetMultiValues())
+	} else {
+		state.value = metric.GetValue().GetSingleValue()
+	}
+	p.series.Add(key, state)
+	metric.SetObservedTimestamp(start)
+	return true
+}
+
+// toDelta replaces the cumulative value with the difference from the previous one, and sets the start timestamp
+// to the timestamp of the previous one. The first point of a series and the points not newer than the previous
+// one are dropped.
+func (p *ProcessorTemporalityConvert) toDelta(key string, metric *models.Metric) bool {
+	timestamp, start := metric.GetTimestamp(), metric.GetObservedTimestamp()
+	state, exists := p.series.Get(key)
+	if exists && timestamp <= state.timestamp {
+		return false
+	}
+	next := &seriesState{start: start, timestamp: timestamp}
+	if metric.GetMetricType() == models.MetricTypeHistogram {
+		next.values = copyValues(metric.GetValue().GetMultiValues())
+	} else {
+		next.value = metric.GetValue().GetSingleValue()
+	}
+	p.series.Add(key, next)
+	if !exists || p.stale(state, timestamp) {
+		return false
+	}
+	restarted := start != 0 && state.start != 0 && start != state.start
+	if metric.GetMetricType() == models.MetricTypeHistogram {
+		if !restarted {
+			if values, ok := otlp.SubtractHistograms(next.values, state.values, isExponential(metric)); ok {
+				metric.Value = &models.MetricMultiValue{Values: values}
+				metric.SetObservedTimestamp(state.timestamp)
+				return true
+			}
+		}
+		// the cumulative histogram is restarted, itself is the delta since its start
+		metric.Value = &models.MetricMultiValue{Values: copyValues(next.values)}
+	} else {
+		delta := next.value - state.value
+		if restarted || delta < 0 && tag(metric, otlp.TagKeyMetricIsMonotonic) != "false" {
+			delta = next.value
+		}
+		metric.Value = &models.MetricSingleValue{Value: delta}
+		if !restarted {
+			metric.SetObservedTimestamp(state.timestamp)
+			return true
+		}
+	}
+	if start == 0 {
+		metric.SetObservedTimestamp(state.timestamp)
+	}
+	return true
+}
+
+func (p *ProcessorTemporalityConvert) stale(state *seriesState, timestamp uint64) bool {
+	return p.StalenessSec > 0 && timestamp-state.timestamp > uint64(p.StalenessSec)*1e9
+}
+
+func tag(metric *models.Metric, key string) string {
+	if metric.Tags == nil {
+		return ""
+	}
+	return metric.Tags.Get(key)
+}
+
+func isExponential(metric *models.Metric) bool {
+	return tag(metric, otlp.TagKeyMetricHistogramType) == otlp.HistogramTypeExponential
+}
+
+func copyValues(values models.MetricFloatValues) models.MetricFloatValues {
+	return models.NewMetricMultiValueWithMap(values.Iterator()).Values
+}
+
+// seriesKey is the name and the tags except the temporality, which is changed by the conversion.
+func seriesKey(metric *models.Metric) string {
+	var builder strings.Builder
+	builder.WriteString(metric.GetName())
+	if tags := metric.GetTags(); tags != nil {
+		for _, kv := range tags.SortTo(nil) {
+			if kv.Key == otlp.TagKeyMetricAggregationTemporality {
+				continue
+			}
+			builder.WriteByte('\x00')
+			builder.WriteString(kv.Key)
+			builder.WriteByte('=')
+			builder.WriteString(kv.Value)
+		}
+	}
+	return builder.String()
+}
+
+func init() {
+	pipeline.Processors[pluginType] = func() pipeline.Processor {
+		return &ProcessorTemporalityConvert{
+			Target:       targetCumulative,
+			StalenessSec: 300,
+			MaxSeries:    100000,
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 iLogtail Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package temporalityconvert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alibaba/ilogtail/pkg/helper"
+	"github.com/alibaba/ilogtail/pkg/logger"
+	"github.com/alibaba/ilogtail/pkg/models"
+	"github.com/alibaba/ilogtail/pkg/pipeline"
+	"github.com/alibaba/ilogtail/pkg/protocol/otlp"
+	"github.com/alibaba/ilogtail/plugins/test/mock"
+)
+
+func init() {
+	logger.InitTestLogger(logger.OptionOpenMemoryReceiver)
+}
+
+func newProcessor() (*ProcessorTemporalityConvert, error) {
+	ctx := mock.NewEmptyContext("p", "l", "c")
+	processor := pipeline.Processors[pluginType]().(*ProcessorTemporalityConvert)
+	err := processor.Init(ctx)
+	return processor, err
+}
+
+func process(p *ProcessorTemporalityConvert, events ...models.PipelineEvent) []models.PipelineEvent {
+	ctx := helper.NewObservePipelineConext(10)
+	p.Process(&models.PipelineGroupEvents{Group: models.NewGroup(models.NewMetadata(), models.NewTags()), Events: events}, ctx)
+	groups := ctx.Collector().ToArray()
+	if len(groups) == 0 {
+		return nil
+	}
+	return groups[0].Events
+}
+
+func newSum(temporality string, value float64, sec, startSec int64) *models.Metric {
+	metricType := models.MetricTypeCounter
+	if temporality == temporalityDelta {
+		metricType = models.MetricTypeRateCounter
+	}
+	tags := models.NewTagsWithKeyValues("host", "a", otlp.TagKeyMetricAggregationTemporality, temporality, otlp.TagKeyMetricIsMonotonic, "true")
+	metric := models.NewSingleValueMetric("requests", metricType, tags, sec*1e9, value)
+	metric.SetObservedTimestamp(uint64(startSec * 1e9))
+	return metric
+}
+
+func singleValues(events []models.PipelineEvent) []float64 {
+	values := make([]float64, 0, len(events))
+	for _, event := range events {
+		values = append(values, event.(*models.Metric).GetValue().GetSingleValue())
+	}
+	return values
+}
+
+func TestInit(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Target = "unknown"
+	assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	p, err = newProcessor()
+	require.NoError(t, err)
+	p.MetricNames = []string{"("}
+	assert.Error(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+}
+
+func TestDeltaToCumulative(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	events := process(p,
+		newSum(temporalityDelta, 2, 10, 0),
+		newSum(temporalityDelta, 3, 20, 10),
+		newSum(temporalityDelta, 1, 15, 10),
+		newSum(temporalityDelta, 5, 30, 20),
+		newSum(temporalityCumulative, 7, 30, 0),
+	)
+	require.Len(t, events, 4)
+	assert.Equal(t, []float64{2, 5, 10, 7}, singleValues(events))
+	for _, event := range events[:3] {
+		metric := event.(*models.Metric)
+		assert.Equal(t, models.MetricTypeCounter, metric.GetMetricType())
+		assert.Equal(t, temporalityCumulative, metric.GetTags().Get(otlp.TagKeyMetricAggregationTemporality))
+		assert.Equal(t, uint64(10e9), metric.GetObservedTimestamp())
+	}
+
+	// the series is restarted after it is stale
+	events = process(p, newSum(temporalityDelta, 4, 1000, 990))
+	assert.Equal(t, []float64{4}, singleValues(events))
+	assert.Equal(t, uint64(990e9), events[0].(*models.Metric).GetObservedTimestamp())
+}
+
+func TestCumulativeToDelta(t *testing.T) {
+	p, err := newProcessor()
+	require.NoError(t, err)
+	p.Target = targetDelta
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	events := process(p,
+		newSum(temporalityCumulative, 10, 10, 5),
+		newSum(temporalityCumulative, 15, 20, 5),
+		newSum(temporalityCumulative, 4, 30, 5),
+		newSum(temporalityCumulative, 6, 40, 35),
+		models.NewSingleValueMetric("gauge", models.MetricTypeGauge, models.NewTags(), 40e9, 1),
+	)
+	require.Len(t, events, 4)
+	assert.Equal(t, []float64{5, 4, 6, 1}, singleValues(events))
+	first := events[0].(*models.Metric)
+	assert.Equal(t, models.MetricTypeRateCounter, first.GetMetricType())
+	assert.Equal(t, temporalityDelta, first.GetTags().Get(otlp.TagKeyMetricAggregationTemporality))
+	assert.Equal(t, uint64(10e9), first.GetObservedTimestamp())
+	// the start timestamp is changed, the source is restarted
+	assert.Equal(t, uint64(35e9), events[2].(*models.Metric).GetObservedTimestamp())
+}
+
+func TestHistogram(t *testing.T) {
+	newHistogram := func(temporality string, le1, inf float64, sec int64) *models.Metric {
+		values := models.NewMetricMultiValueWithMap(map[string]float64{
+			otlp.FieldCount: le1 + inf,
+			otlp.FieldSum:   le1 + 2*inf,
+			"(-Inf,1]":      le1,
+			"(1,+Inf]":      inf,
+		})
+		tags := models.NewTagsWithKeyValues(otlp.TagKeyMetricAggregationTemporality, temporality, otlp.TagKeyMetricHistogramType, otlp.HistogramTypeExplicit)
+		return models.NewMultiValuesMetric("latency", models.MetricTypeHistogram, tags, sec*1e9, values.Values)
+	}
+
+	p, err := newProcessor()
+	require.NoError(t, err)
+	events := process(p, newHistogram(temporalityDelta, 1, 2, 10), newHistogram(temporalityDelta, 2, 0, 20))
+	require.Len(t, events, 2)
+	values := events[1].(*models.Metric).GetValue().GetMultiValues()
+	assert.Equal(t, map[string]float64{otlp.FieldCount: 5, otlp.FieldSum: 7, "(-Inf,1]": 3, "(1,+Inf]": 2}, values.Iterator())
+
+	p, err = newProcessor()
+	require.NoError(t, err)
+	p.Target = targetDelta
+	require.NoError(t, p.Init(mock.NewEmptyContext("p", "l", "c")))
+	events = process(p,
+		newHistogram(temporalityCumulative, 1, 2, 10),
+		newHistogram(temporalityCumulative, 3, 2, 20),
+		newHistogram(temporalityCumulative, 1, 1, 30),
+	)
+	require.Len(t, events, 2)
+	values = events[0].(*models.Metric).GetValue().GetMultiValues()
+	assert.Equal(t, map[string]float64{otlp.FieldCount: 2, otlp.FieldSum: 2, "(-Inf,1]": 2, "(1,+Inf]": 0}, values.Iterator())
+	values = events[1].(*models.Metric).GetValue().GetMultiValues()
+	assert.Equal(t, map[string]float64{otlp.FieldCount: 2, otlp.FieldSum: 3, "(-Inf,1]": 1, "(1,+Inf]": 1}, values.Iterator())
+}
@@ -16,11 +16,13 @@ package test
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -193,6 +195,48 @@ func (m *MockMetricCollector) AddRawLogWithContext(log *protocol.Log, ctx map[st
 	m.Logs = append(m.Logs, log)
 }
 
+// MetricSample is the labels and value of a metric log created by helper.NewMetricLog.
+type MetricSample struct {
+	Labels string
+	Value  string
+}
+
+func parseMetricLog(log *protocol.Log) (name string, sample MetricSample) {
+	for _, c := range log.Contents {
+		switch c.Key {
+		case "__name__":
+			name = c.Value
+		case "__labels__":
+			sample.Labels = c.Value
+		case "__value__":
+			sample.Value = c.Value
+		}
+	}
+	return
+}
+
+// MetricLines formats the metric logs as sorted `name{labels} value` lines, so that the metric inputs
+// can assert the collected metrics as a whole.
+func MetricLines(logs []*protocol.Log) []string {
+	res := make([]string, 0, len(logs))
+	for _, log := range logs {
+		name, sample := parseMetricLog(log)
+		res = append(res, fmt.Sprintf("%s{%s} %s", name, sample.Labels, sample.Value))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// MetricSamples groups the samples of the metric logs by the metric name, in the order of the logs.
+func MetricSamples(logs []*protocol.Log) map[string][]MetricSample {
+	res := make(map[string][]MetricSample)
+	for _, log := range logs {
+		name, sample := parseMetricLog(log)
+		res[name] = append(res[name], sample)
+	}
+	return res
+}
+
 type portpair struct {
 	first string
 	last  string